@@ -73,6 +73,7 @@ func main() {
 				etcdsnapshotCommand,
 				etcdsnapshotCommand,
 				etcdsnapshotCommand,
+				etcdsnapshotCommand,
 				etcdsnapshotCommand),
 		),
 		cmds.NewSecretsEncryptCommand(secretsencryptCommand,
@@ -40,7 +40,8 @@ func main() {
 				etcdsnapshot.Delete,
 				etcdsnapshot.List,
 				etcdsnapshot.Prune,
-				etcdsnapshot.Save),
+				etcdsnapshot.Save,
+				etcdsnapshot.Decrypt),
 		),
 	}
 
@@ -50,7 +50,8 @@ func main() {
 				etcdsnapshot.Delete,
 				etcdsnapshot.List,
 				etcdsnapshot.Prune,
-				etcdsnapshot.Save),
+				etcdsnapshot.Save,
+				etcdsnapshot.Decrypt),
 		),
 		cmds.NewSecretsEncryptCommand(cli.ShowAppHelp,
 			cmds.NewSecretsEncryptSubcommands(
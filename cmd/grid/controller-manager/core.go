@@ -24,6 +24,7 @@ import (
 	"net/http"
 	"time"
 
+	appsclientset "github.com/bhojpur/dcp/pkg/appmanager/client/clientset/versioned"
 	"github.com/bhojpur/dcp/pkg/controller/certificates"
 	lifecyclecontroller "github.com/bhojpur/dcp/pkg/controller/nodelifecycle"
 )
@@ -46,6 +47,10 @@ func startNodeLifecycleController(ctx ControllerContext) (http.Handler, bool, er
 		ctx.ComponentConfig.NodeLifecycleController.LargeClusterSizeThreshold,
 		ctx.ComponentConfig.NodeLifecycleController.UnhealthyZoneThreshold,
 		*ctx.ComponentConfig.NodeLifecycleController.EnableTaintManager,
+		appsclientset.NewForConfigOrDie(ctx.ClientBuilder.ConfigOrDie("node-controller")),
+		ctx.ComponentConfig.NodePoolPartition.PoolPartitionThreshold,
+		ctx.ComponentConfig.NodePoolPartition.MinPoolPartitionSize,
+		ctx.ComponentConfig.NodePoolPartition.PoolPartitionWindow.Duration,
 	)
 	if err != nil {
 		return nil, true, err
@@ -56,7 +61,14 @@ func startNodeLifecycleController(ctx ControllerContext) (http.Handler, bool, er
 
 func startDcpCSRApproverController(ctx ControllerContext) (http.Handler, bool, error) {
 	clientSet := ctx.ClientBuilder.ClientOrDie("csr-controller")
-	csrApprover, err := certificates.NewCSRApprover(clientSet, ctx.InformerFactory)
+	var policy *certificates.CSRApprovalPolicy
+	if policyFile := ctx.ComponentConfig.CSRApprover.ApprovalPolicyFile; policyFile != "" {
+		var err error
+		if policy, err = certificates.LoadCSRApprovalPolicy(policyFile); err != nil {
+			return nil, false, err
+		}
+	}
+	csrApprover, err := certificates.NewCSRApprover(clientSet, ctx.InformerFactory, policy)
 	if err != nil {
 		return nil, false, err
 	}
@@ -0,0 +1,72 @@
+package options
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/pflag"
+
+	dcpctrlmgrconfig "github.com/bhojpur/dcp/pkg/controller/apis/config"
+)
+
+// CSRApproverOptions holds the CSRApprover options.
+type CSRApproverOptions struct {
+	*dcpctrlmgrconfig.CSRApproverConfiguration
+}
+
+// AddFlags adds flags related to CSRApprover for controller manager to the specified FlagSet.
+func (o *CSRApproverOptions) AddFlags(fs *pflag.FlagSet) {
+	if o == nil {
+		return
+	}
+
+	fs.StringVar(&o.ApprovalPolicyFile, "csr-approval-policy", o.ApprovalPolicyFile,
+		"Path to the approval policy of the Bhojpur DCP related CSRs, which checks the requesting node, "+
+			"the common name, the SANs and the rate of the requests. All the CSRs are approved if not set.")
+}
+
+// ApplyTo fills up CSRApprover config with options.
+func (o *CSRApproverOptions) ApplyTo(cfg *dcpctrlmgrconfig.CSRApproverConfiguration) error {
+	if o == nil {
+		return nil
+	}
+
+	cfg.ApprovalPolicyFile = o.ApprovalPolicyFile
+
+	return nil
+}
+
+// Validate checks validation of CSRApproverOptions.
+func (o *CSRApproverOptions) Validate() []error {
+	if o == nil {
+		return nil
+	}
+
+	errs := []error{}
+	if o.ApprovalPolicyFile != "" {
+		if _, err := os.Stat(o.ApprovalPolicyFile); err != nil {
+			errs = append(errs, fmt.Errorf("--csr-approval-policy %s is not accessible, %v", o.ApprovalPolicyFile, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,81 @@
+package options
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"fmt"
+
+	"github.com/spf13/pflag"
+
+	dcpctrlmgrconfig "github.com/bhojpur/dcp/pkg/controller/apis/config"
+)
+
+// NodePoolPartitionOptions holds the options of the NodePool partition detection.
+type NodePoolPartitionOptions struct {
+	*dcpctrlmgrconfig.NodePoolPartitionConfiguration
+}
+
+// AddFlags adds flags related to the NodePool partition detection for controller manager to the specified FlagSet.
+func (o *NodePoolPartitionOptions) AddFlags(fs *pflag.FlagSet) {
+	if o == nil {
+		return
+	}
+
+	fs.Float32Var(&o.PoolPartitionThreshold, "pool-partition-threshold", o.PoolPartitionThreshold,
+		"Fraction of Nodes in a NodePool which need to become unreachable together for the pool to be treated as partitioned, "+
+			"no pods are evicted from the nodes of a partitioned pool.")
+	fs.Int32Var(&o.MinPoolPartitionSize, "min-pool-partition-size", o.MinPoolPartitionSize,
+		"Number of Nodes from which a NodePool is checked for partition, the nodes of smaller pools are evicted one by one.")
+	fs.DurationVar(&o.PoolPartitionWindow.Duration, "pool-partition-window", o.PoolPartitionWindow.Duration,
+		"Max interval between the Nodes of a NodePool becoming unreachable for them to be treated as unreachable together.")
+}
+
+// ApplyTo fills up the NodePool partition config with options.
+func (o *NodePoolPartitionOptions) ApplyTo(cfg *dcpctrlmgrconfig.NodePoolPartitionConfiguration) error {
+	if o == nil {
+		return nil
+	}
+
+	cfg.PoolPartitionThreshold = o.PoolPartitionThreshold
+	cfg.MinPoolPartitionSize = o.MinPoolPartitionSize
+	cfg.PoolPartitionWindow = o.PoolPartitionWindow
+
+	return nil
+}
+
+// Validate checks validation of NodePoolPartitionOptions.
+func (o *NodePoolPartitionOptions) Validate() []error {
+	if o == nil {
+		return nil
+	}
+
+	errs := []error{}
+	if o.PoolPartitionThreshold <= 0 || o.PoolPartitionThreshold > 1 {
+		errs = append(errs, fmt.Errorf("--pool-partition-threshold %v must be in (0, 1]", o.PoolPartitionThreshold))
+	}
+	if o.MinPoolPartitionSize < 1 {
+		errs = append(errs, fmt.Errorf("--min-pool-partition-size %d must be positive", o.MinPoolPartitionSize))
+	}
+	if o.PoolPartitionWindow.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("--pool-partition-window %v must be positive", o.PoolPartitionWindow.Duration))
+	}
+	return errs
+}
@@ -45,6 +45,7 @@ import (
 	utilpointer "k8s.io/utils/pointer"
 
 	dcpcontrollerconfig "github.com/bhojpur/dcp/cmd/grid/controller-manager/config"
+	dcpctrlmgrconfig "github.com/bhojpur/dcp/pkg/controller/apis/config"
 	"github.com/bhojpur/dcp/pkg/projectinfo"
 )
 
@@ -57,6 +58,8 @@ const (
 type DcpControllerManagerOptions struct {
 	Generic                 *cmoptions.GenericControllerManagerConfigurationOptions
 	NodeLifecycleController *NodeLifecycleControllerOptions
+	NodePoolPartition       *NodePoolPartitionOptions
+	CSRApprover             *CSRApproverOptions
 	Master                  string
 	Kubeconfig              string
 	Version                 bool
@@ -94,6 +97,16 @@ func NewDcpControllerManagerOptions() (*DcpControllerManagerOptions, error) {
 				NodeStartupGracePeriod: metav1.Duration{Duration: 60 * time.Second},
 			},
 		},
+		NodePoolPartition: &NodePoolPartitionOptions{
+			NodePoolPartitionConfiguration: &dcpctrlmgrconfig.NodePoolPartitionConfiguration{
+				PoolPartitionThreshold: 0.55,
+				MinPoolPartitionSize:   2,
+				PoolPartitionWindow:    metav1.Duration{Duration: time.Minute},
+			},
+		},
+		CSRApprover: &CSRApproverOptions{
+			CSRApproverConfiguration: &dcpctrlmgrconfig.CSRApproverConfiguration{},
+		},
 	}
 
 	return &s, nil
@@ -104,6 +117,8 @@ func (s *DcpControllerManagerOptions) Flags(allControllers []string, disabledByD
 	fss := cliflag.NamedFlagSets{}
 	s.Generic.AddFlags(&fss, allControllers, disabledByDefaultControllers)
 	s.NodeLifecycleController.AddFlags(fss.FlagSet("nodelifecycle controller"))
+	s.NodePoolPartition.AddFlags(fss.FlagSet("nodelifecycle controller"))
+	s.CSRApprover.AddFlags(fss.FlagSet("csrapprover controller"))
 
 	fs := fss.FlagSet("misc")
 	fs.StringVar(&s.Master, "master", s.Master, "The address of the Kubernetes API server (overrides any value in kubeconfig).")
@@ -124,6 +139,14 @@ func (s *DcpControllerManagerOptions) ApplyTo(c *dcpcontrollerconfig.Config) err
 		return err
 	}
 
+	if err := s.NodePoolPartition.ApplyTo(&c.ComponentConfig.NodePoolPartition); err != nil {
+		return err
+	}
+
+	if err := s.CSRApprover.ApplyTo(&c.ComponentConfig.CSRApprover); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -133,6 +156,8 @@ func (s *DcpControllerManagerOptions) Validate(allControllers []string, disabled
 
 	errs = append(errs, s.Generic.Validate(allControllers, disabledByDefaultControllers)...)
 	errs = append(errs, s.NodeLifecycleController.Validate()...)
+	errs = append(errs, s.NodePoolPartition.Validate()...)
+	errs = append(errs, s.CSRApprover.Validate()...)
 
 	// TODO: validate component config, master and kubeconfig
 
@@ -0,0 +1,79 @@
+package config
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	dcpctlv1alpha1 "github.com/bhojpur/dcp/pkg/client/apis/dcpctl/v1alpha1"
+)
+
+// NewConfigCmd generates a new config command
+func NewConfigCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the configuration files of dcpctl",
+		Args:  cobra.NoArgs,
+	}
+	cmd.AddCommand(newPrintCmd(out))
+	return cmd
+}
+
+// newPrintCmd generates a new config print command
+func newPrintCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "print",
+		Short: "Print the configuration files of dcpctl",
+		Args:  cobra.NoArgs,
+	}
+	cmd.AddCommand(newPrintJoinDefaultsCmd(out))
+	return cmd
+}
+
+// newPrintJoinDefaultsCmd generates a new config print join-defaults command
+func newPrintJoinDefaultsCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "join-defaults",
+		Short: "Print the default JoinConfiguration used by 'dcpctl join --config'",
+		Long: "Print the default JoinConfiguration used by 'dcpctl join --config'. The token and " +
+			"apiServerEndpoint are examples, they must be replaced before the file is used.",
+		Run: func(cmd *cobra.Command, _ []string) {
+			if err := printJoinDefaults(out); err != nil {
+				klog.Fatalf("fail to print join defaults: %s", err)
+			}
+		},
+		Args: cobra.NoArgs,
+	}
+	return cmd
+}
+
+func printJoinDefaults(out io.Writer) error {
+	data, err := dcpctlv1alpha1.MarshalJoinConfiguration(dcpctlv1alpha1.DefaultJoinConfiguration())
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(out, string(data))
+	return err
+}
@@ -23,6 +23,7 @@ package convert
 import (
 	"context"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"strconv"
@@ -82,6 +83,13 @@ func NewConvertCmd() *cobra.Command {
 				os.Exit(1)
 			}
 			converter := NewClusterConverter(co)
+			if co.PrintPlan {
+				if err := converter.PrintPlan(os.Stdout); err != nil {
+					klog.Errorf("Fail to print the convert plan: %s", err)
+					os.Exit(1)
+				}
+				return
+			}
 			if err := converter.PreflightCheck(); err != nil {
 				klog.Errorf("Fail to run pre-flight checks: %s", err)
 				os.Exit(1)
@@ -140,6 +148,10 @@ func setFlags(cmd *cobra.Command) {
 		"enable-app-manager", "e", false,
 		"If set, appmanager will be deployed.",
 	)
+	cmd.Flags().Bool(
+		"plan", false,
+		"If set, print the conversion plan and the steps left from a previous run, without changing the cluster.",
+	)
 	cmd.Flags().String(
 		"system-architecture", "amd64",
 		"The system architecture of cloud nodes.",
@@ -196,6 +208,14 @@ func (c *ClusterConverter) RunConvert() (err error) {
 	}
 	edgeNodeNames := getEdgeNodeNames(nodeLst, c.CloudNodes)
 
+	state, err := c.loadState(nodeLst)
+	if err != nil {
+		return
+	}
+	if err = state.save(c.ClientSet); err != nil {
+		return
+	}
+
 	fmt.Println("[runConvert] Label all nodes with edgeworker label, annotate all nodes with autonomy annotation")
 	for _, node := range nodeLst.Items {
 		isEdge := strutil.IsInStringLst(edgeNodeNames, node.Name)
@@ -248,18 +268,13 @@ func (c *ClusterConverter) RunConvert() (err error) {
 
 	// disable node-controller
 	fmt.Println("[runConvert] Running disable-node-controller jobs to disable node-controller")
-	var kcmNodeNames []string
-	if kcmNodeNames, err = kubeutil.GetKubeControllerManagerHANodes(c.ClientSet); err != nil {
-		return
-	}
-
-	if err = kubeutil.RunServantJobs(c.ClientSet, c.WaitServantJobTimeout, func(nodeName string) (*batchv1.Job, error) {
+	if err = c.runServantJobs(state, stepDisableNodeController, func(nodeName string) (*batchv1.Job, error) {
 		ctx := map[string]string{
 			"node_servant_image": c.NodeServantImage,
 			"pod_manifest_path":  c.PodMainfestPath,
 		}
 		return kubeutil.RenderServantJob("disable", ctx, nodeName)
-	}, kcmNodeNames, os.Stderr); err != nil {
+	}, state.Plan.KubeControllerManagerNodes); err != nil {
 		return
 	}
 
@@ -276,35 +291,108 @@ func (c *ClusterConverter) RunConvert() (err error) {
 		"joinToken":          joinToken,
 		"kubeadm_conf_path":  c.KubeadmConfPath,
 		"working_mode":       string(util.WorkingModeEdge),
+		"provider":           string(c.Provider),
 	}
 	if c.EngineHealthCheckTimeout != defaultEngineHealthCheckTimeout {
 		convertCtx["dcpsvr_healthcheck_timeout"] = c.EngineHealthCheckTimeout.String()
 	}
 	if len(edgeNodeNames) != 0 {
 		convertCtx["working_mode"] = string(util.WorkingModeEdge)
-		if err = kubeutil.RunServantJobs(c.ClientSet, c.WaitServantJobTimeout, func(nodeName string) (*batchv1.Job, error) {
+		if err = c.runServantJobs(state, stepConvert, func(nodeName string) (*batchv1.Job, error) {
 			return nodeservant.RenderNodeServantJob("convert", convertCtx, nodeName)
-		}, edgeNodeNames, os.Stderr); err != nil {
+		}, edgeNodeNames); err != nil {
 			return
 		}
 	}
 
 	// deploy dcpsvr and reset the kubelet service on cloud nodes
 	convertCtx["working_mode"] = string(util.WorkingModeCloud)
-	if err = kubeutil.RunServantJobs(c.ClientSet, c.WaitServantJobTimeout, func(nodeName string) (*batchv1.Job, error) {
+	if err = c.runServantJobs(state, stepConvert, func(nodeName string) (*batchv1.Job, error) {
 		return nodeservant.RenderNodeServantJob("convert", convertCtx, nodeName)
-	}, c.CloudNodes, os.Stderr); err != nil {
+	}, c.CloudNodes); err != nil {
 		return
 	}
 
-	fmt.Println("[runConvert] If any job fails, you can get job information through 'kubectl get jobs -n kube-system' to debug.\n" +
-		"\tNote that before the next conversion, please delete all related jobs so as not to affect the conversion.")
+	state.printSummary(os.Stdout)
+	if failed := state.failedNodes(); len(failed) != 0 {
+		fmt.Println("[runConvert] You can get job information through 'kubectl get jobs -n kube-system' to debug.\n" +
+			"\tRun the same convert command again to retry the failed nodes, the succeeded ones are skipped.")
+		return fmt.Errorf("fail to convert %d nodes: %s", len(failed), strings.Join(failed, ", "))
+	}
 
 	return
 
 }
 
-func prepareEngineStart(cliSet *kubernetes.Clientset, kcfg string) (string, error) {
+// PrintPlan prints what the conversion changes in the cluster, taking the progress of a
+// previous run into account.
+func (c *ClusterConverter) PrintPlan(w io.Writer) error {
+	nodeLst, err := c.ClientSet.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	state, err := c.loadState(nodeLst)
+	if err != nil {
+		return err
+	}
+	state.printPlan(w)
+	return nil
+}
+
+// loadState returns the state of the conversion, resumed from the configmap/dcpctl-convert-state.
+func (c *ClusterConverter) loadState(nodeLst *v1.NodeList) (*convertState, error) {
+	kcmNodeNames, err := kubeutil.GetKubeControllerManagerHANodes(c.ClientSet)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := loadConvertState(c.ClientSet)
+	if err != nil {
+		return nil, err
+	}
+
+	components := []string{"controller-manager"}
+	if c.DeployTunnel {
+		components = append(components, "tunnel-server", "tunnel-agent")
+	}
+	if c.EnableAppManager {
+		components = append(components, "app-manager")
+	}
+	return newConvertState(convertPlan{
+		CloudNodes:                 c.CloudNodes,
+		EdgeNodes:                  getEdgeNodeNames(nodeLst, c.CloudNodes),
+		AutonomousNodes:            c.AutonomousNodes,
+		KubeControllerManagerNodes: kcmNodeNames,
+		Components:                 components,
+	}, previous), nil
+}
+
+// runServantJobs runs the servant jobs of a step on the nodes where it has not succeeded yet,
+// and saves the result of every job in the state as soon as it finishes.
+func (c *ClusterConverter) runServantJobs(state *convertState, step string,
+	getJob func(nodeName string) (*batchv1.Job, error), nodeNames []string) error {
+	pending := state.pendingNodes(step, nodeNames)
+	if skipped := len(nodeNames) - len(pending); skipped != 0 {
+		fmt.Printf("\t[INFO] skip %s on %d nodes converted by a previous run\n", step, skipped)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	_, err := kubeutil.RunServantJobsWithResults(c.ClientSet, c.WaitServantJobTimeout, getJob, pending,
+		func(res kubeutil.ServantJobResult) {
+			if res.Err != nil {
+				fmt.Fprintf(os.Stderr, "\t[ERROR] fail to run servant job(%s): %s\n", res.JobName, res.Err)
+			} else {
+				fmt.Fprintf(os.Stderr, "\t[INFO] servant job(%s) has succeeded\n", res.JobName)
+			}
+			state.setResult(step, res)
+			if err := state.save(c.ClientSet); err != nil {
+				klog.Error(err)
+			}
+		})
+	return err
+}
+
+func prepareEngineStart(cliSet kubernetes.Interface, kcfg string) (string, error) {
 	// prepare kube-public/cluster-info configmap before convert
 	if err := prepareClusterInfoConfigMap(cliSet, kcfg); err != nil {
 		return "", err
@@ -349,7 +437,7 @@ func (c *ClusterConverter) generatePreflightJobs() ([]*batchv1.Job, error) {
 }
 
 // prepareClusterInfoConfigMap will create cluster-info configmap in kube-public namespace if it does not exist
-func prepareClusterInfoConfigMap(client *kubernetes.Clientset, file string) error {
+func prepareClusterInfoConfigMap(client kubernetes.Interface, file string) error {
 	info, err := client.CoreV1().ConfigMaps(metav1.NamespacePublic).Get(context.Background(), bootstrapapi.ConfigMapClusterInfo, metav1.GetOptions{})
 	if err != nil && apierrors.IsNotFound(err) {
 		// Create the cluster-info ConfigMap with the associated RBAC rules
@@ -0,0 +1,88 @@
+package convert
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes/fake"
+	bootstrapapi "k8s.io/cluster-bootstrap/token/api"
+
+	kubeutil "github.com/bhojpur/dcp/pkg/client/util/kubernetes"
+)
+
+func newTestNode(name string) *v1.Node {
+	return &v1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:        name,
+		Labels:      map[string]string{v1.LabelHostname: name},
+		Annotations: map[string]string{},
+	}}
+}
+
+func TestRunConvertResume(t *testing.T) {
+	cliSet := fake.NewSimpleClientset(
+		newTestNode("master"),
+		newTestNode("edge-1"),
+		&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespacePublic, Name: bootstrapapi.ConfigMapClusterInfo}},
+	)
+
+	// the nodes have been converted by a previous run, which failed after deploying the components
+	state := newConvertState(convertPlan{
+		CloudNodes: []string{"master"},
+		EdgeNodes:  []string{"edge-1"},
+	}, nil)
+	state.setResult(stepConvert, kubeutil.ServantJobResult{NodeName: "master", JobName: "convert-master"})
+	state.setResult(stepConvert, kubeutil.ServantJobResult{NodeName: "edge-1", JobName: "convert-edge-1"})
+	if err := state.save(cliSet); err != nil {
+		t.Fatalf("fail to save the state: %v", err)
+	}
+
+	c := NewClusterConverter(&ConvertOptions{
+		CloudNodes:             []string{"master"},
+		IgnorePreflightErrors:  sets.NewString(),
+		DeployTunnel:           true,
+		ControllerManagerImage: "controller-manager:v1",
+		TunnelServerImage:      "tunnel-server:v1",
+		TunnelAgentImage:       "tunnel-agent:v1",
+		SystemArchitecture:     "amd64",
+		ClientSet:              cliSet,
+	})
+	if err := c.RunConvert(); err != nil {
+		t.Fatalf("fail to convert: %v", err)
+	}
+
+	c.ControllerManagerImage = "controller-manager:v2"
+	if err := c.RunConvert(); err != nil {
+		t.Fatalf("fail to convert again: %v", err)
+	}
+	dply, err := cliSet.AppsV1().Deployments(kubeutil.SystemNamespace).
+		Get(context.Background(), "controller-manager", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("fail to get the controller-manager: %v", err)
+	}
+	if image := dply.Spec.Template.Spec.Containers[0].Image; image != "controller-manager:v2" {
+		t.Fatalf("expect the controller-manager to be updated, but got image %s", image)
+	}
+}
@@ -50,6 +50,12 @@ const (
 	ProviderKubeadm Provider = "kubeadm"
 	// ProviderKind is used if the target kubernetes is run on kind
 	ProviderKind Provider = "kind"
+	// ProviderK3s is used if the nodes of the target kubernetes run the k3s agent
+	ProviderK3s Provider = "k3s"
+	// ProviderRKE2 is used if the nodes of the target kubernetes run the rke2 agent
+	ProviderRKE2 Provider = "rke2"
+	// ProviderDcp is used if the nodes of the target kubernetes run the Bhojpur DCP agent
+	ProviderDcp Provider = "dcp"
 
 	Amd64 string = "amd64"
 	Arm64 string = "arm64"
@@ -71,6 +77,8 @@ type ConvertOptions struct {
 	IgnorePreflightErrors    sets.String
 	DeployTunnel             bool
 	EnableAppManager         bool
+	// PrintPlan prints the conversion plan instead of converting the cluster.
+	PrintPlan bool
 
 	SystemArchitecture     string
 	EngineImage            string
@@ -81,7 +89,7 @@ type ConvertOptions struct {
 	AppManagerImage        string
 
 	PodMainfestPath     string
-	ClientSet           *kubernetes.Clientset
+	ClientSet           kubernetes.Interface
 	AppManagerClientSet dynamic.Interface
 }
 
@@ -163,6 +171,12 @@ func (co *ConvertOptions) Complete(flags *pflag.FlagSet) error {
 	}
 	co.EnableAppManager = eam
 
+	plan, err := flags.GetBool("plan")
+	if err != nil {
+		return err
+	}
+	co.PrintPlan = plan
+
 	sa, err := flags.GetString("system-architecture")
 	if err != nil {
 		return err
@@ -314,8 +328,9 @@ func ValidateNodeAutonomy(edgeNodeNames []string, autonomousNodeNames []string)
 
 func ValidateProvider(provider Provider) error {
 	if provider != ProviderMinikube && provider != ProviderACK &&
-		provider != ProviderKubeadm && provider != ProviderKind {
-		return fmt.Errorf("invalid --provider: %s, valid providers are: minikube, ack, kubeadm, kind",
+		provider != ProviderKubeadm && provider != ProviderKind &&
+		provider != ProviderK3s && provider != ProviderRKE2 && provider != ProviderDcp {
+		return fmt.Errorf("invalid --provider: %s, valid providers are: minikube, ack, kubeadm, kind, k3s, rke2, dcp",
 			provider)
 	}
 	return nil
@@ -0,0 +1,303 @@
+package convert
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/bhojpur/dcp/pkg/client/constants"
+	kubeutil "github.com/bhojpur/dcp/pkg/client/util/kubernetes"
+	"github.com/bhojpur/dcp/pkg/engine/util"
+)
+
+const (
+	// convertStateKey is the key of the convert state in the configmap/dcpctl-convert-state
+	convertStateKey = "state"
+
+	// stepDisableNodeController disables the node-controller on the kube-controller-manager nodes
+	stepDisableNodeController = "disable-node-controller"
+	// stepConvert deploys the dcpsvr and resets the kubelet service on every node
+	stepConvert = "convert"
+)
+
+// stepPhase is the phase of a conversion step of a node.
+type stepPhase string
+
+const (
+	stepPending   stepPhase = "Pending"
+	stepSucceeded stepPhase = "Succeeded"
+	stepFailed    stepPhase = "Failed"
+)
+
+// convertState is the plan of the conversion and the progress of every node. It is persisted
+// in the configmap/dcpctl-convert-state, so that an interrupted conversion is resumed by
+// running dcpctl convert again, skipping the steps which have already succeeded.
+type convertState struct {
+	Plan  convertPlan           `json:"plan"`
+	Nodes map[string]*nodeState `json:"nodes"`
+
+	// skipped holds the nodes whose steps all succeeded before this run.
+	skipped map[string]bool
+	// saved is true if the configmap exists, resourceVersion is its last seen resourceVersion.
+	saved           bool
+	resourceVersion string
+}
+
+// convertPlan describes what the conversion changes in the cluster.
+type convertPlan struct {
+	CloudNodes                 []string `json:"cloudNodes"`
+	EdgeNodes                  []string `json:"edgeNodes"`
+	AutonomousNodes            []string `json:"autonomousNodes,omitempty"`
+	KubeControllerManagerNodes []string `json:"kubeControllerManagerNodes,omitempty"`
+	Components                 []string `json:"components"`
+}
+
+// nodeState is the progress of the conversion of a node.
+type nodeState struct {
+	WorkingMode util.WorkingMode      `json:"workingMode"`
+	Steps       map[string]*stepState `json:"steps"`
+}
+
+// stepState is the result of the servant job of a conversion step.
+type stepState struct {
+	Phase      stepPhase   `json:"phase"`
+	Job        string      `json:"job,omitempty"`
+	Message    string      `json:"message,omitempty"`
+	Logs       string      `json:"logs,omitempty"`
+	UpdateTime metav1.Time `json:"updateTime,omitempty"`
+}
+
+// loadConvertState reads the state of the previous conversion, it returns nil if there is none.
+func loadConvertState(cliSet kubernetes.Interface) (*convertState, error) {
+	cm, err := cliSet.CoreV1().ConfigMaps(kubeutil.SystemNamespace).
+		Get(context.Background(), constants.DcpctlConvertStateConfigMapName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("fail to get configmap/%s: %v", constants.DcpctlConvertStateConfigMapName, err)
+	}
+	state := &convertState{}
+	if err := json.Unmarshal([]byte(cm.Data[convertStateKey]), state); err != nil {
+		return nil, fmt.Errorf("fail to parse configmap/%s: %v", constants.DcpctlConvertStateConfigMapName, err)
+	}
+	state.saved, state.resourceVersion = true, cm.ResourceVersion
+	return state, nil
+}
+
+// newConvertState returns the state of a conversion following the plan. The progress of the
+// nodes is resumed from the previous state, unless their working mode has changed.
+func newConvertState(plan convertPlan, previous *convertState) *convertState {
+	state := &convertState{
+		Plan:    plan,
+		Nodes:   make(map[string]*nodeState),
+		skipped: make(map[string]bool),
+	}
+	if previous != nil {
+		state.saved, state.resourceVersion = previous.saved, previous.resourceVersion
+	}
+
+	addNode := func(nodeName string, mode util.WorkingMode, steps ...string) {
+		ns := &nodeState{WorkingMode: mode, Steps: make(map[string]*stepState)}
+		var prev *nodeState
+		if previous != nil && previous.Nodes[nodeName] != nil && previous.Nodes[nodeName].WorkingMode == mode {
+			prev = previous.Nodes[nodeName]
+		}
+		done := true
+		for _, step := range steps {
+			ns.Steps[step] = &stepState{Phase: stepPending}
+			if prev != nil && prev.Steps[step] != nil {
+				ns.Steps[step] = prev.Steps[step]
+			}
+			done = done && ns.Steps[step].Phase == stepSucceeded
+		}
+		state.Nodes[nodeName] = ns
+		state.skipped[nodeName] = done
+	}
+	kcmNodes := make(map[string]bool)
+	for _, nodeName := range plan.KubeControllerManagerNodes {
+		kcmNodes[nodeName] = true
+	}
+	for _, nodeName := range plan.EdgeNodes {
+		if kcmNodes[nodeName] {
+			addNode(nodeName, util.WorkingModeEdge, stepDisableNodeController, stepConvert)
+		} else {
+			addNode(nodeName, util.WorkingModeEdge, stepConvert)
+		}
+	}
+	for _, nodeName := range plan.CloudNodes {
+		if kcmNodes[nodeName] {
+			addNode(nodeName, util.WorkingModeCloud, stepDisableNodeController, stepConvert)
+		} else {
+			addNode(nodeName, util.WorkingModeCloud, stepConvert)
+		}
+	}
+	return state
+}
+
+// save creates or updates the configmap/dcpctl-convert-state.
+func (s *convertState) save(cliSet kubernetes.Interface) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            constants.DcpctlConvertStateConfigMapName,
+			Namespace:       kubeutil.SystemNamespace,
+			ResourceVersion: s.resourceVersion,
+		},
+		Data: map[string]string{convertStateKey: string(data)},
+	}
+	if !s.saved {
+		cm, err = cliSet.CoreV1().ConfigMaps(kubeutil.SystemNamespace).Create(context.Background(), cm, metav1.CreateOptions{})
+	} else {
+		cm, err = cliSet.CoreV1().ConfigMaps(kubeutil.SystemNamespace).Update(context.Background(), cm, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("fail to save configmap/%s: %v", constants.DcpctlConvertStateConfigMapName, err)
+	}
+	s.saved, s.resourceVersion = true, cm.ResourceVersion
+	return nil
+}
+
+// pendingNodes returns the nodes whose step has not succeeded yet.
+func (s *convertState) pendingNodes(step string, nodeNames []string) []string {
+	var pending []string
+	for _, nodeName := range nodeNames {
+		ns := s.Nodes[nodeName]
+		if ns == nil || ns.Steps[step] == nil || ns.Steps[step].Phase != stepSucceeded {
+			pending = append(pending, nodeName)
+		}
+	}
+	return pending
+}
+
+// setResult records the result of the servant job of a step of a node.
+func (s *convertState) setResult(step string, res kubeutil.ServantJobResult) {
+	ns := s.Nodes[res.NodeName]
+	if ns == nil {
+		return
+	}
+	ss := &stepState{Phase: stepSucceeded, Job: res.JobName, UpdateTime: metav1.Now()}
+	if res.Err != nil {
+		ss.Phase = stepFailed
+		ss.Message = res.Err.Error()
+		ss.Logs = res.Logs
+	}
+	ns.Steps[step] = ss
+}
+
+// sortedNodeNames returns the names of the nodes of the state in order.
+func (s *convertState) sortedNodeNames() []string {
+	names := make([]string, 0, len(s.Nodes))
+	for name := range s.Nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// failedNodes returns the nodes having a failed step.
+func (s *convertState) failedNodes() []string {
+	var failed []string
+	for _, nodeName := range s.sortedNodeNames() {
+		for _, ss := range s.Nodes[nodeName].Steps {
+			if ss.Phase == stepFailed {
+				failed = append(failed, nodeName)
+				break
+			}
+		}
+	}
+	return failed
+}
+
+// printPlan prints the plan and what is going to be done for every node.
+func (s *convertState) printPlan(w io.Writer) {
+	fmt.Fprintf(w, "[plan] cloud nodes: %s\n", strings.Join(s.Plan.CloudNodes, ", "))
+	fmt.Fprintf(w, "[plan] edge nodes: %s\n", strings.Join(s.Plan.EdgeNodes, ", "))
+	fmt.Fprintf(w, "[plan] autonomous nodes: %s\n", strings.Join(s.Plan.AutonomousNodes, ", "))
+	fmt.Fprintf(w, "[plan] components to deploy: %s\n", strings.Join(s.Plan.Components, ", "))
+	for _, nodeName := range s.sortedNodeNames() {
+		ns := s.Nodes[nodeName]
+		var actions []string
+		for _, step := range []string{stepDisableNodeController, stepConvert} {
+			ss := ns.Steps[step]
+			switch {
+			case ss == nil:
+				continue
+			case ss.Phase == stepSucceeded:
+				actions = append(actions, step+" (done, skip)")
+			case ss.Phase == stepFailed:
+				actions = append(actions, step+" (failed, retry)")
+			default:
+				actions = append(actions, step)
+			}
+		}
+		fmt.Fprintf(w, "[plan] node %s (%s): %s\n", nodeName, ns.WorkingMode, strings.Join(actions, ", "))
+	}
+}
+
+// printSummary prints the succeeded, failed and skipped nodes, with the logs of the failed jobs.
+func (s *convertState) printSummary(w io.Writer) {
+	var succeeded, skipped []string
+	failed := s.failedNodes()
+	isFailed := make(map[string]bool, len(failed))
+	for _, nodeName := range failed {
+		isFailed[nodeName] = true
+	}
+	for _, nodeName := range s.sortedNodeNames() {
+		switch {
+		case isFailed[nodeName]:
+		case s.skipped[nodeName]:
+			skipped = append(skipped, nodeName)
+		default:
+			succeeded = append(succeeded, nodeName)
+		}
+	}
+
+	fmt.Fprintf(w, "[summary] succeeded nodes: %s\n", strings.Join(succeeded, ", "))
+	fmt.Fprintf(w, "[summary] skipped nodes (converted by a previous run): %s\n", strings.Join(skipped, ", "))
+	fmt.Fprintf(w, "[summary] failed nodes: %s\n", strings.Join(failed, ", "))
+	for _, nodeName := range failed {
+		for _, step := range []string{stepDisableNodeController, stepConvert} {
+			ss := s.Nodes[nodeName].Steps[step]
+			if ss == nil || ss.Phase != stepFailed {
+				continue
+			}
+			fmt.Fprintf(w, "\t%s: %s job(%s) failed: %s\n", nodeName, step, ss.Job, ss.Message)
+			for _, line := range strings.Split(ss.Logs, "\n") {
+				if line != "" {
+					fmt.Fprintf(w, "\t\t%s\n", line)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package convert
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+
+	kubeutil "github.com/bhojpur/dcp/pkg/client/util/kubernetes"
+	"github.com/bhojpur/dcp/pkg/engine/util"
+)
+
+func TestConvertStateResume(t *testing.T) {
+	cliSet := fake.NewSimpleClientset()
+	plan := convertPlan{
+		CloudNodes:                 []string{"master"},
+		EdgeNodes:                  []string{"edge-1", "edge-2", "edge-3"},
+		KubeControllerManagerNodes: []string{"master"},
+		Components:                 []string{"controller-manager"},
+	}
+
+	previous, err := loadConvertState(cliSet)
+	if err != nil || previous != nil {
+		t.Fatalf("expect no previous state, but got %v, %v", previous, err)
+	}
+	state := newConvertState(plan, previous)
+	if err := state.save(cliSet); err != nil {
+		t.Fatalf("fail to save the state: %v", err)
+	}
+	state.setResult(stepDisableNodeController, kubeutil.ServantJobResult{NodeName: "master", JobName: "disable-master"})
+	state.setResult(stepConvert, kubeutil.ServantJobResult{NodeName: "edge-1", JobName: "convert-edge-1"})
+	state.setResult(stepConvert, kubeutil.ServantJobResult{NodeName: "edge-2", JobName: "convert-edge-2",
+		Err: errors.New("wait for job to be complete timeout"), Logs: "line 1\nline 2"})
+	if err := state.save(cliSet); err != nil {
+		t.Fatalf("fail to save the state: %v", err)
+	}
+
+	// edge-3 becomes a cloud node in the next run
+	plan.CloudNodes = []string{"master", "edge-3"}
+	plan.EdgeNodes = []string{"edge-1", "edge-2"}
+	previous, err = loadConvertState(cliSet)
+	if err != nil {
+		t.Fatalf("fail to load the state: %v", err)
+	}
+	state = newConvertState(plan, previous)
+
+	if pending := state.pendingNodes(stepDisableNodeController, plan.KubeControllerManagerNodes); len(pending) != 0 {
+		t.Fatalf("expect no pending disable-node-controller, but got %v", pending)
+	}
+	if pending := state.pendingNodes(stepConvert, plan.EdgeNodes); !reflect.DeepEqual(pending, []string{"edge-2"}) {
+		t.Fatalf("expect edge-2 to be converted again, but got %v", pending)
+	}
+	if pending := state.pendingNodes(stepConvert, plan.CloudNodes); !reflect.DeepEqual(pending, []string{"master", "edge-3"}) {
+		t.Fatalf("expect master and edge-3 to be converted, but got %v", pending)
+	}
+	if state.Nodes["edge-3"].WorkingMode != util.WorkingModeCloud {
+		t.Fatalf("expect edge-3 to be a cloud node, but got %s", state.Nodes["edge-3"].WorkingMode)
+	}
+
+	var plainPlan bytes.Buffer
+	state.printPlan(&plainPlan)
+	for _, expect := range []string{
+		"node edge-1 (edge): convert (done, skip)",
+		"node edge-2 (edge): convert (failed, retry)",
+		"node master (cloud): disable-node-controller (done, skip), convert",
+	} {
+		if !strings.Contains(plainPlan.String(), expect) {
+			t.Fatalf("expect %q in the plan, but got:\n%s", expect, plainPlan.String())
+		}
+	}
+
+	state.setResult(stepConvert, kubeutil.ServantJobResult{NodeName: "master", JobName: "convert-master"})
+	state.setResult(stepConvert, kubeutil.ServantJobResult{NodeName: "edge-3", JobName: "convert-edge-3"})
+	var summary bytes.Buffer
+	state.printSummary(&summary)
+	for _, expect := range []string{
+		"succeeded nodes: edge-3, master\n",
+		"skipped nodes (converted by a previous run): edge-1\n",
+		"failed nodes: edge-2\n",
+		"\t\tline 2\n",
+	} {
+		if !strings.Contains(summary.String(), expect) {
+			t.Fatalf("expect %q in the summary, but got:\n%s", expect, summary.String())
+		}
+	}
+}
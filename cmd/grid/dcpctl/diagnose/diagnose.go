@@ -0,0 +1,339 @@
+package diagnose
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	"github.com/bhojpur/dcp/pkg/client/constants"
+	enutil "github.com/bhojpur/dcp/pkg/client/util/edgenode"
+	kubeutil "github.com/bhojpur/dcp/pkg/client/util/kubernetes"
+	nodeservant "github.com/bhojpur/dcp/pkg/node-servant"
+	"github.com/bhojpur/dcp/pkg/node-servant/diagnose"
+	"github.com/bhojpur/dcp/pkg/projectinfo"
+)
+
+const (
+	latestNodeServantImage = "bhojpur/node-servant:latest"
+
+	bundleTimeFormat = "20060102-150405"
+)
+
+// DiagnoseOptions has the information that required by diagnose operation
+type DiagnoseOptions struct {
+	NodeName              string
+	NodeServantImage      string
+	KubeadmConfPath       string
+	OutputDir             string
+	LogLines              int
+	WaitServantJobTimeout time.Duration
+
+	ClientSet kubernetes.Interface
+}
+
+// NewDiagnoseOptions creates a new DiagnoseOptions
+func NewDiagnoseOptions() *DiagnoseOptions {
+	return &DiagnoseOptions{}
+}
+
+// NewDiagnoseCmd generates a new diagnose command
+func NewDiagnoseCmd() *cobra.Command {
+	do := NewDiagnoseOptions()
+	cmd := &cobra.Command{
+		Use:   "diagnose NODE",
+		Short: "Collects the diagnostics of a node into a tarball and summarizes the problems detected",
+		Run: func(cmd *cobra.Command, args []string) {
+			do.NodeName = args[0]
+			if err := do.Complete(cmd.Flags()); err != nil {
+				klog.Errorf("Fail to complete the diagnose option: %s", err)
+				os.Exit(1)
+			}
+			if err := do.RunDiagnose(); err != nil {
+				klog.Errorf("Fail to diagnose the node: %s", err)
+				os.Exit(1)
+			}
+		},
+		Args: cobra.ExactArgs(1),
+	}
+
+	cmd.Flags().String("node-servant-image", latestNodeServantImage, "The node-servant image.")
+	cmd.Flags().String("kubeadm-conf-path", "",
+		"The path to kubelet service conf that is used by kubelet component to join the cluster on the node.")
+	cmd.Flags().StringP("output-dir", "o", ".", "The directory where the tarball is written.")
+	cmd.Flags().Int("log-lines", diagnose.DefaultLogLines, "The number of the last lines of dcpsvr logs collected.")
+	cmd.Flags().Duration("wait-servant-job-timeout", kubeutil.DefaultWaitServantJobTimeout,
+		"The timeout for servant-job run check.")
+
+	return cmd
+}
+
+// Complete completes all the required options
+func (do *DiagnoseOptions) Complete(flags *pflag.FlagSet) error {
+	nsi, err := flags.GetString("node-servant-image")
+	if err != nil {
+		return err
+	}
+	do.NodeServantImage = nsi
+
+	kcp, err := flags.GetString("kubeadm-conf-path")
+	if err != nil {
+		return err
+	}
+	do.KubeadmConfPath = kcp
+
+	od, err := flags.GetString("output-dir")
+	if err != nil {
+		return err
+	}
+	do.OutputDir = od
+
+	ll, err := flags.GetInt("log-lines")
+	if err != nil {
+		return err
+	}
+	do.LogLines = ll
+
+	waitServantJobTimeout, err := flags.GetDuration("wait-servant-job-timeout")
+	if err != nil {
+		return err
+	}
+	do.WaitServantJobTimeout = waitServantJobTimeout
+
+	do.ClientSet, err = kubeutil.GenClientSet(flags)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// RunDiagnose collects the diagnostics of the node from the cluster and from a node-servant
+// job running on the node, writes them into a timestamped tarball and prints the summary.
+// Secrets are redacted on the node, before they leave it.
+func (do *DiagnoseOptions) RunDiagnose() error {
+	node, err := do.ClientSet.CoreV1().Nodes().Get(context.Background(), do.NodeName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+
+	fmt.Printf("[diagnose] Collecting the status of node %s in cluster\n", do.NodeName)
+	files, problems := do.collectClusterFiles(node)
+
+	fmt.Printf("[diagnose] Running node-servant-diagnose job on node %s. Job running may take a minute\n", do.NodeName)
+	nodeFiles, nodeProblems := do.collectNodeFiles()
+	files = append(files, nodeFiles...)
+	problems = append(problems, nodeProblems...)
+
+	summary := buildSummary(node, now, problems)
+	files = append(files, diagnose.File{Name: "summary.txt", Content: summary})
+
+	name := fmt.Sprintf("dcp-diagnose-%s-%s", do.NodeName, now.Format(bundleTimeFormat))
+	path := filepath.Join(do.OutputDir, name+".tar.gz")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := diagnose.WriteTarball(f, name, files, now); err != nil {
+		return fmt.Errorf("fail to write %s: %v", path, err)
+	}
+
+	fmt.Println()
+	os.Stdout.Write(summary)
+	fmt.Printf("\n[diagnose] The diagnostics are written to %s\n", path)
+	return nil
+}
+
+// collectClusterFiles collects the node object and the pods of dcpsvr and tunnel-agent on the node
+func (do *DiagnoseOptions) collectClusterFiles(node *v1.Node) ([]diagnose.File, []string) {
+	var files []diagnose.File
+	problems := checkNode(node)
+
+	nodeCopy := node.DeepCopy()
+	nodeCopy.ManagedFields = nil
+	if content, err := yaml.Marshal(nodeCopy); err == nil {
+		files = append(files, diagnose.File{Name: "cluster/node.yaml", Content: content})
+	}
+
+	podLst, err := do.ClientSet.CoreV1().Pods(kubeutil.SystemNamespace).List(context.Background(), metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", node.Name).String(),
+	})
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("pods on the node can not be listed: %v", err))
+		return files, problems
+	}
+	pods, podProblems := checkComponentPods(node, podLst.Items)
+	files = append(files, diagnose.File{Name: "cluster/pods.txt", Content: pods})
+	return files, append(problems, podProblems...)
+}
+
+// collectNodeFiles runs the node-servant diagnose job on the node and extracts the files it collects
+func (do *DiagnoseOptions) collectNodeFiles() ([]diagnose.File, []string) {
+	ctx := map[string]string{
+		"node_servant_image": do.NodeServantImage,
+		"kubeadm_conf_path":  do.KubeadmConfPath,
+		"log_lines":          strconv.Itoa(do.LogLines),
+	}
+	job, err := nodeservant.RenderNodeServantJob("diagnose", ctx, do.NodeName)
+	if err != nil {
+		return nil, []string{fmt.Sprintf("node-servant-diagnose job can not be rendered: %v", err)}
+	}
+
+	logs, err := kubeutil.RunJobAndGetLogs(do.ClientSet, job, do.WaitServantJobTimeout, kubeutil.CheckServantJobPeriod)
+	if err != nil {
+		problem := fmt.Sprintf("node-servant-diagnose job(%s) failed, the diagnostics on the node are not collected: %v", job.Name, err)
+		logs = kubeutil.GetJobLogs(do.ClientSet, job, 0)
+		return []diagnose.File{{Name: "node/job.log", Content: diagnose.Redact([]byte(logs))}}, []string{problem}
+	}
+
+	bundle, err := diagnose.ReadBundle(logs)
+	if err != nil {
+		return nil, []string{fmt.Sprintf("diagnostics of node-servant-diagnose job(%s) can not be read: %v", job.Name, err)}
+	}
+	var files []diagnose.File
+	var problems []string
+	for _, f := range bundle {
+		if f.Name == diagnose.ProblemsFileName {
+			problems = append(problems, splitLines(f.Content)...)
+			continue
+		}
+		files = append(files, diagnose.File{Name: "node/" + f.Name, Content: f.Content})
+	}
+	return files, problems
+}
+
+// checkNode returns the problems of the node object
+func checkNode(node *v1.Node) []string {
+	var problems []string
+	if _, ok := node.Labels[projectinfo.GetEdgeWorkerLabelKey()]; !ok {
+		problems = append(problems, fmt.Sprintf("node is not converted, label %s is not found",
+			projectinfo.GetEdgeWorkerLabelKey()))
+	}
+
+	foundReady := false
+	for _, cond := range node.Status.Conditions {
+		if cond.Type != v1.NodeReady {
+			continue
+		}
+		foundReady = true
+		if cond.Status != v1.ConditionTrue {
+			problems = append(problems, fmt.Sprintf("node is not ready since %s, %s: %s",
+				cond.LastTransitionTime.Format(time.RFC3339), cond.Reason, cond.Message))
+		}
+	}
+	if !foundReady {
+		problems = append(problems, "node has no Ready condition")
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == v1.TaintNodeUnreachable {
+			problems = append(problems, fmt.Sprintf("node is tainted with %s:%s", taint.Key, taint.Effect))
+		}
+	}
+	return problems
+}
+
+// checkComponentPods lists the dcpsvr and tunnel-agent pods on the node, and returns
+// the problems of them.
+func checkComponentPods(node *v1.Node, pods []v1.Pod) ([]byte, []string) {
+	var buf bytes.Buffer
+	var problems []string
+	foundEngine := false
+	for _, pod := range pods {
+		component := pod.Labels["k8s-app"]
+		if component != constants.TunnelAgentComponentName && component != enutil.EngineComponentName {
+			continue
+		}
+		if component == enutil.EngineComponentName {
+			foundEngine = true
+		}
+
+		ready, restarts := false, int32(0)
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == v1.PodReady {
+				ready = cond.Status == v1.ConditionTrue
+			}
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			restarts += cs.RestartCount
+		}
+		fmt.Fprintf(&buf, "%s phase=%s ready=%t restarts=%d\n", pod.Name, pod.Status.Phase, ready, restarts)
+		if !ready {
+			problems = append(problems, fmt.Sprintf("pod %s is not ready, phase %s", pod.Name, pod.Status.Phase))
+		}
+	}
+	if _, converted := node.Labels[projectinfo.GetEdgeWorkerLabelKey()]; converted && !foundEngine {
+		problems = append(problems, "mirror pod of dcpsvr is not found in cluster")
+	}
+	if buf.Len() == 0 {
+		buf.WriteString("no pod of dcpsvr or tunnel-agent is found on the node\n")
+	}
+	return buf.Bytes(), problems
+}
+
+// buildSummary returns the human-readable summary of the diagnostics
+func buildSummary(node *v1.Node, at time.Time, problems []string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Node:       %s\n", node.Name)
+	fmt.Fprintf(&buf, "Collected:  %s\n", at.Format(time.RFC3339))
+	fmt.Fprintf(&buf, "Edge node:  %s\n", valueOrUnset(node.Labels, projectinfo.GetEdgeWorkerLabelKey()))
+	fmt.Fprintf(&buf, "Autonomy:   %s\n", valueOrUnset(node.Annotations, constants.AnnotationAutonomy))
+	if len(problems) == 0 {
+		buf.WriteString("\nNo problem is detected.\n")
+		return buf.Bytes()
+	}
+	fmt.Fprintf(&buf, "\n%d problems are detected:\n", len(problems))
+	for _, p := range problems {
+		fmt.Fprintf(&buf, "  - %s\n", p)
+	}
+	return buf.Bytes()
+}
+
+func valueOrUnset(m map[string]string, key string) string {
+	if val, ok := m[key]; ok {
+		return val
+	}
+	return "<unset>"
+}
+
+func splitLines(content []byte) []string {
+	var lines []string
+	for _, line := range bytes.Split(bytes.TrimSpace(content), []byte("\n")) {
+		if len(line) != 0 {
+			lines = append(lines, string(line))
+		}
+	}
+	return lines
+}
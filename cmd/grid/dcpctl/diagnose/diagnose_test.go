@@ -0,0 +1,114 @@
+package diagnose
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bhojpur/dcp/pkg/projectinfo"
+)
+
+func TestCheckNode(t *testing.T) {
+	converted := map[string]string{projectinfo.GetEdgeWorkerLabelKey(): "true"}
+	tests := []struct {
+		name   string
+		node   *v1.Node
+		expect []string
+	}{
+		{
+			name: "ready converted node",
+			node: &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "edge-1", Labels: converted},
+				Status: v1.NodeStatus{Conditions: []v1.NodeCondition{
+					{Type: v1.NodeReady, Status: v1.ConditionTrue},
+				}},
+			},
+		},
+		{
+			name: "unconverted node without ready condition",
+			node: &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "edge-1"}},
+			expect: []string{
+				"node is not converted, label " + projectinfo.GetEdgeWorkerLabelKey() + " is not found",
+				"node has no Ready condition",
+			},
+		},
+		{
+			name: "unreachable node",
+			node: &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "edge-1", Labels: converted},
+				Spec: v1.NodeSpec{Taints: []v1.Taint{
+					{Key: v1.TaintNodeUnreachable, Effect: v1.TaintEffectNoExecute},
+				}},
+				Status: v1.NodeStatus{Conditions: []v1.NodeCondition{
+					{Type: v1.NodeReady, Status: v1.ConditionUnknown, Reason: "NodeStatusUnknown", Message: "Kubelet stopped posting node status."},
+				}},
+			},
+			expect: []string{
+				"node is not ready since 0001-01-01T00:00:00Z, NodeStatusUnknown: Kubelet stopped posting node status.",
+				"node is tainted with node.kubernetes.io/unreachable:NoExecute",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		st := tt
+		tf := func(t *testing.T) {
+			t.Parallel()
+			if got := checkNode(st.node); !reflect.DeepEqual(got, st.expect) {
+				t.Fatalf("expect problems %v, but got %v", st.expect, got)
+			}
+		}
+		t.Run(st.name, tf)
+	}
+}
+
+func TestCheckComponentPods(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:   "edge-1",
+		Labels: map[string]string{projectinfo.GetEdgeWorkerLabelKey(): "true"},
+	}}
+	pods := []v1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "tunnel-agent-x7k2p", Labels: map[string]string{"k8s-app": "tunnel-agent"}},
+			Status: v1.PodStatus{
+				Phase:             v1.PodRunning,
+				ContainerStatuses: []v1.ContainerStatus{{RestartCount: 3}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "kube-proxy-9xq8z", Labels: map[string]string{"k8s-app": "kube-proxy"}},
+		},
+	}
+
+	content, problems := checkComponentPods(node, pods)
+	if expect := "tunnel-agent-x7k2p phase=Running ready=false restarts=3\n"; string(content) != expect {
+		t.Fatalf("expect pods %q, but got %q", expect, content)
+	}
+	if len(problems) != 2 || !strings.HasPrefix(problems[0], "pod tunnel-agent-x7k2p is not ready") ||
+		problems[1] != "mirror pod of dcpsvr is not found in cluster" {
+		t.Fatalf("unexpected problems %v", problems)
+	}
+}
@@ -27,7 +27,6 @@ import (
 	"strings"
 
 	"github.com/lithammer/dedent"
-	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	flag "github.com/spf13/pflag"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -37,6 +36,8 @@ import (
 
 	"github.com/bhojpur/dcp/cmd/grid/dcpctl/join/joindata"
 	dcpphase "github.com/bhojpur/dcp/cmd/grid/dcpctl/join/phases"
+	appsv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+	dcpctlv1alpha1 "github.com/bhojpur/dcp/pkg/client/apis/dcpctl/v1alpha1"
 	dcpconstants "github.com/bhojpur/dcp/pkg/client/constants"
 	"github.com/bhojpur/dcp/pkg/client/kubernetes/kubeadm/app/cmd/options"
 	"github.com/bhojpur/dcp/pkg/client/kubernetes/kubeadm/app/cmd/phases/workflow"
@@ -58,6 +59,7 @@ var (
 )
 
 type joinOptions struct {
+	cfgPath                  string
 	token                    string
 	nodeType                 string
 	nodeName                 string
@@ -120,6 +122,11 @@ func NewCmdJoin(out io.Writer, joinOptions *joinOptions) *cobra.Command {
 
 // addJoinConfigFlags adds join flags bound to the config to the specified flagset
 func addJoinConfigFlags(flagSet *flag.FlagSet, joinOptions *joinOptions) {
+	flagSet.StringVar(
+		&joinOptions.cfgPath, options.CfgPath, joinOptions.cfgPath,
+		"Path to a JoinConfiguration file, the flags set explicitly override the values in it. "+
+			"Run 'dcpctl config print join-defaults' for an example.",
+	)
 	flagSet.StringVar(
 		&joinOptions.token, options.TokenStr, "",
 		"Use this token for both discovery-token and tls-bootstrap-token when those values are not provided.",
@@ -183,6 +190,7 @@ type joinData struct {
 	kubernetesVersion        string
 	caCertHashes             sets.String
 	nodeLabels               map[string]string
+	engineExtraArgs          map[string]string
 	kubernetesResourceServer string
 }
 
@@ -190,39 +198,12 @@ type joinData struct {
 // This func takes care of validating joinOptions passed to the command, and then it converts
 // options into the internal JoinData type that is used as input all the phases in the kubeadm join workflow
 func newJoinData(cmd *cobra.Command, args []string, opt *joinOptions, out io.Writer) (*joinData, error) {
-	// if an APIServerEndpoint from which to retrieve cluster information was not provided, unset the Discovery.BootstrapToken object
-	var apiServerEndpoint string
-	if len(args) == 0 {
-		return nil, errors.New("apiServer endpoint is empty")
-	} else {
-		if len(args) > 1 {
-			klog.Warningf("[preflight] WARNING: More than one API server endpoint supplied on command line %v. Using the first one.", args)
-		}
-		apiServerEndpoint = args[0]
-	}
-
-	if len(opt.token) == 0 {
-		return nil, errors.New("join token is empty, so unable to bootstrap worker node.")
-	}
-
-	if opt.nodeType != dcpconstants.EdgeNode && opt.nodeType != dcpconstants.CloudNode {
-		return nil, errors.Errorf("node type(%s) is invalid, only \"edge and cloud\" are supported", opt.nodeType)
-	}
-
-	if opt.unsafeSkipCAVerification && len(opt.caCertHashes) != 0 {
-		return nil, errors.Errorf("when --discovery-token-ca-cert-hash is specified, --discovery-token-unsafe-skip-ca-verification should be false.")
-	} else if len(opt.caCertHashes) == 0 && !opt.unsafeSkipCAVerification {
-		return nil, errors.Errorf("when --discovery-token-ca-cert-hash is not specified, --discovery-token-unsafe-skip-ca-verification should be true")
-	}
-
-	ignoreErrors := sets.String{}
-	for i := range opt.ignorePreflightErrors {
-		ignoreErrors.Insert(opt.ignorePreflightErrors[i])
+	joinCfg, err := newJoinConfiguration(cmd.Flags(), args, opt)
+	if err != nil {
+		return nil, err
 	}
 
-	// Either use the config file if specified, or convert public kubeadm API to the internal JoinConfiguration
-	// and validates JoinConfiguration
-	name := opt.nodeName
+	name := joinCfg.NodeRegistration.Name
 	if name == "" {
 		klog.V(1).Infoln("[preflight] found NodeName empty; using OS hostname as NodeName")
 		hostname, err := os.Hostname()
@@ -232,36 +213,32 @@ func newJoinData(cmd *cobra.Command, args []string, opt *joinOptions, out io.Wri
 		name = hostname
 	}
 
+	organizations := strings.Join(joinCfg.NodeRegistration.Organizations, ",")
 	data := &joinData{
-		apiServerEndpoint:     apiServerEndpoint,
-		token:                 opt.token,
+		apiServerEndpoint:     joinCfg.APIServerEndpoint,
+		token:                 joinCfg.Discovery.Token,
 		tlsBootstrapCfg:       nil,
-		ignorePreflightErrors: ignoreErrors,
-		pauseImage:            opt.pauseImage,
-		engineImage:           opt.engineImage,
-		caCertHashes:          sets.NewString(opt.caCertHashes...),
-		organizations:         opt.organizations,
+		ignorePreflightErrors: sets.NewString(joinCfg.IgnorePreflightErrors...),
+		pauseImage:            joinCfg.PauseImage,
+		engineImage:           joinCfg.Engine.Image,
+		caCertHashes:          sets.NewString(joinCfg.Discovery.CACertHashes...),
+		organizations:         organizations,
 		nodeLabels:            make(map[string]string),
+		engineExtraArgs:       joinCfg.Engine.ExtraArgs,
 		joinNodeData: &joindata.NodeRegistration{
 			Name:          name,
-			WorkingMode:   opt.nodeType,
-			CRISocket:     opt.criSocket,
-			Organizations: opt.organizations,
+			WorkingMode:   joinCfg.NodeRegistration.WorkingMode,
+			CRISocket:     joinCfg.NodeRegistration.CRISocket,
+			Organizations: organizations,
+			Taints:        joinCfg.NodeRegistration.Taints,
 		},
-		kubernetesResourceServer: opt.kubernetesResourceServer,
+		kubernetesResourceServer: joinCfg.KubernetesResourceServer,
 	}
-
-	// parse node labels
-	if len(opt.nodeLabels) != 0 {
-		parts := strings.Split(opt.nodeLabels, ",")
-		for i := range parts {
-			kv := strings.Split(parts[i], "=")
-			if len(kv) != 2 {
-				klog.Warningf("node labels(%s) format is invalid, expect k1=v1,k2=v2", parts[i])
-				continue
-			}
-			data.nodeLabels[kv[0]] = kv[1]
-		}
+	for k, v := range joinCfg.NodeRegistration.Labels {
+		data.nodeLabels[k] = v
+	}
+	if joinCfg.NodeRegistration.NodePool != "" {
+		data.nodeLabels[appsv1alpha1.LabelDesiredNodePool] = joinCfg.NodeRegistration.NodePool
 	}
 
 	// get tls bootstrap config
@@ -291,6 +268,93 @@ func newJoinData(cmd *cobra.Command, args []string, opt *joinOptions, out io.Wri
 	return data, nil
 }
 
+// newJoinConfiguration loads the JoinConfiguration from the file set by --config, the flags set
+// explicitly and the api-server-endpoint in args override the values in it. The returned
+// configuration is defaulted and validated.
+func newJoinConfiguration(flagSet *flag.FlagSet, args []string, opt *joinOptions) (*dcpctlv1alpha1.JoinConfiguration, error) {
+	cfg := &dcpctlv1alpha1.JoinConfiguration{}
+	if opt.cfgPath != "" {
+		loaded, err := dcpctlv1alpha1.LoadJoinConfiguration(opt.cfgPath)
+		if err != nil {
+			return nil, err
+		}
+		cfg = loaded
+	}
+
+	if len(args) > 1 {
+		klog.Warningf("[preflight] WARNING: More than one API server endpoint supplied on command line %v. Using the first one.", args)
+	}
+	if len(args) != 0 {
+		cfg.APIServerEndpoint = args[0]
+	}
+
+	// the default values of the flags are the same as the defaults of JoinConfiguration,
+	// so only the flags set explicitly are applied.
+	if flagSet.Changed(options.TokenStr) {
+		cfg.Discovery.Token = opt.token
+	}
+	if flagSet.Changed(options.TokenDiscoveryCAHash) {
+		cfg.Discovery.CACertHashes = opt.caCertHashes
+	}
+	if flagSet.Changed(options.TokenDiscoverySkipCAHash) {
+		cfg.Discovery.UnsafeSkipCAVerification = opt.unsafeSkipCAVerification
+	}
+	if flagSet.Changed(options.NodeType) {
+		cfg.NodeRegistration.WorkingMode = opt.nodeType
+	}
+	if flagSet.Changed(options.NodeName) {
+		cfg.NodeRegistration.Name = opt.nodeName
+	}
+	if flagSet.Changed(options.NodeCRISocket) {
+		cfg.NodeRegistration.CRISocket = opt.criSocket
+	}
+	if flagSet.Changed(options.Organizations) {
+		cfg.NodeRegistration.Organizations = nil
+		if opt.organizations != "" {
+			cfg.NodeRegistration.Organizations = strings.Split(opt.organizations, ",")
+		}
+	}
+	if flagSet.Changed(options.NodeLabels) {
+		cfg.NodeRegistration.Labels = parseNodeLabels(opt.nodeLabels)
+	}
+	if flagSet.Changed(options.PauseImage) {
+		cfg.PauseImage = opt.pauseImage
+	}
+	if flagSet.Changed(options.EngineImage) {
+		cfg.Engine.Image = opt.engineImage
+	}
+	if flagSet.Changed(options.IgnorePreflightErrors) {
+		cfg.IgnorePreflightErrors = opt.ignorePreflightErrors
+	}
+	if flagSet.Changed(options.KubernetesResourceServer) {
+		cfg.KubernetesResourceServer = opt.kubernetesResourceServer
+	}
+
+	dcpctlv1alpha1.SetDefaults_JoinConfiguration(cfg)
+	if err := dcpctlv1alpha1.ValidateJoinConfiguration(cfg).ToAggregate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// parseNodeLabels parses the node labels in the format of k1=v1,k2=v2
+func parseNodeLabels(nodeLabels string) map[string]string {
+	labels := make(map[string]string)
+	if len(nodeLabels) == 0 {
+		return labels
+	}
+	parts := strings.Split(nodeLabels, ",")
+	for i := range parts {
+		kv := strings.Split(parts[i], "=")
+		if len(kv) != 2 {
+			klog.Warningf("node labels(%s) format is invalid, expect k1=v1,k2=v2", parts[i])
+			continue
+		}
+		labels[kv[0]] = kv[1]
+	}
+	return labels
+}
+
 // ServerAddr returns the public address of kube-apiserver.
 func (j *joinData) ServerAddr() string {
 	return j.apiServerEndpoint
@@ -343,6 +407,11 @@ func (j *joinData) NodeLabels() map[string]string {
 	return j.nodeLabels
 }
 
+// EngineExtraArgs returns the extra args of the Bhojpur DCP server engine.
+func (j *joinData) EngineExtraArgs() map[string]string {
+	return j.engineExtraArgs
+}
+
 func (j *joinData) KubernetesResourceServer() string {
 	return j.kubernetesResourceServer
 }
@@ -21,6 +21,7 @@ package joindata
 // THE SOFTWARE.
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	clientset "k8s.io/client-go/kubernetes"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
@@ -31,6 +32,7 @@ type NodeRegistration struct {
 	CRISocket     string
 	WorkingMode   string
 	Organizations string
+	Taints        []corev1.Taint
 }
 
 type DcpJoinData interface {
@@ -44,6 +46,7 @@ type DcpJoinData interface {
 	NodeRegistration() *NodeRegistration
 	CaCertHashes() sets.String
 	NodeLabels() map[string]string
+	EngineExtraArgs() map[string]string
 	IgnorePreflightErrors() sets.String
 	KubernetesResourceServer() string
 }
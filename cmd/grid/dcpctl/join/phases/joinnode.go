@@ -25,6 +25,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -55,6 +57,7 @@ func NewEdgeNodePhase() workflow.Phase {
 		Short: "Join node",
 		Run:   runJoinNode,
 		InheritFlags: []string{
+			options.CfgPath,
 			options.TokenStr,
 			options.NodeCRISocket,
 			options.NodeName,
@@ -178,6 +181,7 @@ func addEngineStaticYaml(data joindata.DcpJoinData, podManifestPath string) erro
 		"joinToken":            data.JoinToken(),
 		"workingMode":          data.NodeRegistration().WorkingMode,
 		"organizations":        data.NodeRegistration().Organizations,
+		"extraArgs":            constructEngineExtraArgs(data.EngineExtraArgs()),
 	}
 
 	engineTemplate, err := templates.SubsituteTemplate(edgenode.EngineTemplate, ctx)
@@ -191,3 +195,14 @@ func addEngineStaticYaml(data joindata.DcpJoinData, podManifestPath string) erro
 	klog.Info("[join-node] Add hub agent static yaml is ok")
 	return nil
 }
+
+// constructEngineExtraArgs make up the extra args of Bhojpur DCP server engine as the
+// items of the command list in the static yaml, sorted by the flag name.
+func constructEngineExtraArgs(extraArgs map[string]string) string {
+	args := make([]string, 0, len(extraArgs))
+	for k, v := range extraArgs {
+		args = append(args, fmt.Sprintf("    - %q", fmt.Sprintf("--%s=%s", k, v)))
+	}
+	sort.Strings(args)
+	return strings.Join(args, "\n")
+}
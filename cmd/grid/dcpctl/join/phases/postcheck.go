@@ -48,6 +48,7 @@ func NewPostcheckPhase() workflow.Phase {
 		Short: "postcheck",
 		Run:   runPostCheck,
 		InheritFlags: []string{
+			options.CfgPath,
 			options.TokenStr,
 		},
 	}
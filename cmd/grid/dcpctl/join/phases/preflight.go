@@ -39,6 +39,7 @@ func NewPreflightPhase() workflow.Phase {
 		Long:  "Run pre-flight checks for kubeadm join.",
 		Run:   runPreflight,
 		InheritFlags: []string{
+			options.CfgPath,
 			options.TokenStr,
 			options.NodeCRISocket,
 			options.NodeName,
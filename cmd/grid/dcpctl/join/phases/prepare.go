@@ -42,6 +42,7 @@ func NewPreparePhase() workflow.Phase {
 		Short: "Initialize system environment.",
 		Run:   runPrepare,
 		InheritFlags: []string{
+			options.CfgPath,
 			options.TokenStr,
 		},
 	}
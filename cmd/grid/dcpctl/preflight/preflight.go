@@ -0,0 +1,186 @@
+package preflight
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	"github.com/bhojpur/dcp/cmd/grid/dcpctl/convert"
+	kubeutil "github.com/bhojpur/dcp/pkg/client/util/kubernetes"
+	"github.com/bhojpur/dcp/pkg/preflight"
+)
+
+// PreflightOptions has the information that required by preflight operation
+type PreflightOptions struct {
+	Output                string
+	IgnorePreflightErrors sets.String
+	SystemCheckOptions    *preflight.SystemCheckOptions
+}
+
+// NewPreflightOptions creates a new PreflightOptions
+func NewPreflightOptions() *PreflightOptions {
+	return &PreflightOptions{
+		Output:                preflight.OutputText,
+		IgnorePreflightErrors: sets.NewString(),
+		SystemCheckOptions:    preflight.NewSystemCheckOptions(),
+	}
+}
+
+// NewPreflightCmd generates a new preflight command
+func NewPreflightCmd() *cobra.Command {
+	po := NewPreflightOptions()
+	cmd := &cobra.Command{
+		Use:   "preflight",
+		Short: "Runs the system preflight checks on the node and reports the results",
+		Run: func(cmd *cobra.Command, _ []string) {
+			if err := po.Complete(cmd.Flags()); err != nil {
+				klog.Errorf("Fail to complete the preflight option: %s", err)
+				os.Exit(1)
+			}
+			if err := po.Validate(); err != nil {
+				klog.Errorf("Fail to validate preflight option: %s", err)
+				os.Exit(1)
+			}
+			passed, err := po.RunPreflight(os.Stdout)
+			if err != nil {
+				klog.Errorf("Fail to run the preflight checks: %s", err)
+				os.Exit(1)
+			}
+			if !passed {
+				os.Exit(1)
+			}
+		},
+		Args: cobra.NoArgs,
+	}
+
+	defaults := po.SystemCheckOptions
+	cmd.Flags().String("output", po.Output, "The format of the report, text, json or yaml.")
+	cmd.Flags().String("ignore-preflight-errors", "",
+		"A list of checks whose errors will be shown as warnings. Example: 'sysctls,clockskew'. Value 'all' ignores errors from all checks.")
+	cmd.Flags().StringSlice("kernel-modules", defaults.KernelModules, "The kernel modules that must be loaded.")
+	cmd.Flags().StringToString("sysctls", defaults.Sysctls, "The kernel parameters and their required values.")
+	cmd.Flags().Int("cgroup-version", defaults.CgroupVersion, "The required cgroup version, 0 accepts both v1 and v2.")
+	cmd.Flags().String("engine-cache-dir", defaults.EngineCacheDir, "The cache dir of dcpsvr on the node.")
+	cmd.Flags().String("min-engine-cache-free", defaults.MinEngineCacheFree.String(),
+		"The min free disk space of the filesystem of the cache dir of dcpsvr.")
+	cmd.Flags().Duration("max-clock-skew", defaults.MaxClockSkew, "The max clock skew against the apiserver.")
+	cmd.Flags().String("tunnel-server-address", "", "The tunnel-server address to be resolved, the check is skipped if empty.")
+
+	return cmd
+}
+
+// Complete completes all the required options
+func (po *PreflightOptions) Complete(flags *pflag.FlagSet) error {
+	output, err := flags.GetString("output")
+	if err != nil {
+		return err
+	}
+	po.Output = output
+
+	ipStr, err := flags.GetString("ignore-preflight-errors")
+	if err != nil {
+		return err
+	}
+	if ipStr != "" {
+		ipStr = strings.ToLower(ipStr)
+		po.IgnorePreflightErrors = sets.NewString(strings.Split(ipStr, ",")...)
+	}
+
+	o := po.SystemCheckOptions
+	if o.KernelModules, err = flags.GetStringSlice("kernel-modules"); err != nil {
+		return err
+	}
+	if o.Sysctls, err = flags.GetStringToString("sysctls"); err != nil {
+		return err
+	}
+	if o.CgroupVersion, err = flags.GetInt("cgroup-version"); err != nil {
+		return err
+	}
+	if o.EngineCacheDir, err = flags.GetString("engine-cache-dir"); err != nil {
+		return err
+	}
+	minFree, err := flags.GetString("min-engine-cache-free")
+	if err != nil {
+		return err
+	}
+	if o.MinEngineCacheFree, err = resource.ParseQuantity(minFree); err != nil {
+		return fmt.Errorf("invalid --min-engine-cache-free %s: %v", minFree, err)
+	}
+	if o.MaxClockSkew, err = flags.GetDuration("max-clock-skew"); err != nil {
+		return err
+	}
+	if o.TunnelServerAddress, err = flags.GetString("tunnel-server-address"); err != nil {
+		return err
+	}
+
+	// the apiserver is only required by the clock skew check
+	cliSet, err := kubeutil.GenClientSet(flags)
+	if err != nil {
+		klog.Warningf("the clock skew check is skipped, fail to create the clientset: %v", err)
+	} else {
+		o.ClientSet = cliSet
+	}
+	return nil
+}
+
+// Validate makes sure provided values for PreflightOptions are valid
+func (po *PreflightOptions) Validate() error {
+	switch po.Output {
+	case preflight.OutputText, preflight.OutputJSON, preflight.OutputYAML:
+	default:
+		return fmt.Errorf("invalid --output %s, only text, json and yaml are supported", po.Output)
+	}
+	if err := convert.ValidateIgnorePreflightErrors(po.IgnorePreflightErrors); err != nil {
+		return err
+	}
+
+	o := po.SystemCheckOptions
+	if o.CgroupVersion < 0 || o.CgroupVersion > 2 {
+		return fmt.Errorf("invalid --cgroup-version %d, only 0, 1 and 2 are supported", o.CgroupVersion)
+	}
+	if o.MaxClockSkew <= 0 {
+		return fmt.Errorf("invalid --max-clock-skew %s, it should be positive", o.MaxClockSkew)
+	}
+	if o.MinEngineCacheFree.Sign() < 0 {
+		return fmt.Errorf("invalid --min-engine-cache-free %s, it should not be negative", o.MinEngineCacheFree.String())
+	}
+	return nil
+}
+
+// RunPreflight runs the system checks, writes the report to w and returns whether all the checks passed
+func (po *PreflightOptions) RunPreflight(w io.Writer) (bool, error) {
+	checks := preflight.SystemCheckers(po.SystemCheckOptions)
+	klog.V(1).Infof("running %d preflight checks", len(checks))
+	report := preflight.RunChecksWithReport(checks, po.IgnorePreflightErrors)
+	if err := report.Write(w, po.Output); err != nil {
+		return false, err
+	}
+	return report.Passed, nil
+}
@@ -36,6 +36,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 
+	"github.com/bhojpur/dcp/cmd/grid/dcpctl/convert"
 	"github.com/bhojpur/dcp/pkg/client/constants"
 	"github.com/bhojpur/dcp/pkg/client/lock"
 	enutil "github.com/bhojpur/dcp/pkg/client/util/edgenode"
@@ -53,6 +54,7 @@ type RevertOptions struct {
 	NodeServantImage      string
 	PodMainfestPath       string
 	KubeadmConfPath       string
+	Provider              convert.Provider
 	AppManagerClientSet   dynamic.Interface
 }
 
@@ -86,6 +88,8 @@ func NewRevertCmd() *cobra.Command {
 	cmd.Flags().String("kubeadm-conf-path",
 		"/etc/systemd/system/kubelet.service.d/10-kubeadm.conf",
 		"The path to kubelet service conf that is used by kubelet component to join the cluster on the edge node.")
+	cmd.Flags().StringP("provider", "p", "minikube",
+		"The provider of the original Kubernetes cluster.")
 	cmd.Flags().Duration("wait-servant-job-timeout", kubeutil.DefaultWaitServantJobTimeout,
 		"The timeout for servant-job run check.")
 	return cmd
@@ -105,6 +109,15 @@ func (ro *RevertOptions) Complete(flags *pflag.FlagSet) error {
 	}
 	ro.KubeadmConfPath = kcp
 
+	pStr, err := flags.GetString("provider")
+	if err != nil {
+		return err
+	}
+	ro.Provider = convert.Provider(pStr)
+	if err := convert.ValidateProvider(ro.Provider); err != nil {
+		return err
+	}
+
 	ro.PodMainfestPath = enutil.GetPodManifestPath()
 
 	waitServantJobTimeout, err := flags.GetDuration("wait-servant-job-timeout")
@@ -235,6 +248,7 @@ func (ro *RevertOptions) RunRevert() (err error) {
 		ctx := map[string]string{
 			"node_servant_image": ro.NodeServantImage,
 			"kubeadm_conf_path":  ro.KubeadmConfPath,
+			"provider":           string(ro.Provider),
 		}
 		return nodeservant.RenderNodeServantJob("revert", ctx, nodeName)
 	}, nodeNames, os.Stderr); err != nil {
@@ -270,6 +284,13 @@ func (ro *RevertOptions) RunRevert() (err error) {
 		}
 	}
 
+	// 10. remove the state of the conversion, so that the next conversion starts from scratch
+	if err = ro.clientSet.CoreV1().ConfigMaps(kubeutil.SystemNamespace).
+		Delete(context.Background(), constants.DcpctlConvertStateConfigMapName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("fail to remove configmap/%s: %s", constants.DcpctlConvertStateConfigMapName, err)
+	}
+	klog.Infof("configmap/%s is removed", constants.DcpctlConvertStateConfigMapName)
+
 	return
 }
 
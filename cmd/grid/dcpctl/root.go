@@ -30,12 +30,16 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/bhojpur/dcp/cmd/grid/dcpctl/clusterinfo"
+	"github.com/bhojpur/dcp/cmd/grid/dcpctl/config"
 	"github.com/bhojpur/dcp/cmd/grid/dcpctl/convert"
 	"github.com/bhojpur/dcp/cmd/grid/dcpctl/dcpinit"
+	"github.com/bhojpur/dcp/cmd/grid/dcpctl/diagnose"
 	"github.com/bhojpur/dcp/cmd/grid/dcpctl/join"
 	"github.com/bhojpur/dcp/cmd/grid/dcpctl/markautonomous"
+	"github.com/bhojpur/dcp/cmd/grid/dcpctl/preflight"
 	"github.com/bhojpur/dcp/cmd/grid/dcpctl/reset"
 	"github.com/bhojpur/dcp/cmd/grid/dcpctl/revert"
+	"github.com/bhojpur/dcp/cmd/grid/dcpctl/upgrade"
 	"github.com/bhojpur/dcp/pkg/projectinfo"
 )
 
@@ -53,10 +57,14 @@ func NewClientCommand() *cobra.Command {
 	cmds.PersistentFlags().String("kubeconfig", "", "The path to the kubeconfig file")
 	cmds.AddCommand(convert.NewConvertCmd())
 	cmds.AddCommand(revert.NewRevertCmd())
+	cmds.AddCommand(upgrade.NewUpgradeCmd())
 	cmds.AddCommand(markautonomous.NewMarkAutonomousCmd())
 	cmds.AddCommand(clusterinfo.NewClusterInfoCmd())
+	cmds.AddCommand(diagnose.NewDiagnoseCmd())
+	cmds.AddCommand(preflight.NewPreflightCmd())
 	cmds.AddCommand(dcpinit.NewCmdInit())
 	cmds.AddCommand(join.NewCmdJoin(os.Stdout, nil))
+	cmds.AddCommand(config.NewConfigCmd(os.Stdout))
 	cmds.AddCommand(reset.NewCmdReset(os.Stdin, os.Stdout, nil))
 
 	klog.InitFlags(nil)
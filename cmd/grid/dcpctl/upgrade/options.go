@@ -0,0 +1,213 @@
+package upgrade
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/bhojpur/dcp/cmd/grid/dcpctl/convert"
+	kubeutil "github.com/bhojpur/dcp/pkg/client/util/kubernetes"
+)
+
+// UpgradeOptions has the information that required by upgrade operation
+type UpgradeOptions struct {
+	// Nodes stores the names of the converted nodes whose dcpsvr is going to be upgraded.
+	// If empty, all converted nodes will be upgraded.
+	Nodes                    []string
+	BatchSize                int
+	TunnelServerAddress      string
+	KubeConfigPath           string
+	KubeadmConfPath          string
+	Provider                 convert.Provider
+	EngineHealthCheckTimeout time.Duration
+	WaitServantJobTimeout    time.Duration
+	// RollbackOnFailure restores the components and the dcpsvr of the upgraded nodes
+	// once a batch fails.
+	RollbackOnFailure bool
+
+	SystemArchitecture     string
+	EngineImage            string
+	ControllerManagerImage string
+	NodeServantImage       string
+	TunnelServerImage      string
+	TunnelAgentImage       string
+	AppManagerImage        string
+
+	ClientSet kubernetes.Interface
+}
+
+// NewUpgradeOptions creates a new UpgradeOptions
+func NewUpgradeOptions() *UpgradeOptions {
+	return &UpgradeOptions{
+		Nodes: []string{},
+	}
+}
+
+// Complete completes all the required options
+func (uo *UpgradeOptions) Complete(flags *pflag.FlagSet) error {
+	nStr, err := flags.GetString("nodes")
+	if err != nil {
+		return err
+	}
+	if nStr != "" {
+		uo.Nodes = strings.Split(nStr, ",")
+	}
+
+	bs, err := flags.GetInt("batch-size")
+	if err != nil {
+		return err
+	}
+	uo.BatchSize = bs
+
+	ytsa, err := flags.GetString("tunnel-server-address")
+	if err != nil {
+		return err
+	}
+	uo.TunnelServerAddress = ytsa
+
+	kcp, err := flags.GetString("kubeadm-conf-path")
+	if err != nil {
+		return err
+	}
+	uo.KubeadmConfPath = kcp
+
+	pStr, err := flags.GetString("provider")
+	if err != nil {
+		return err
+	}
+	uo.Provider = convert.Provider(pStr)
+
+	engineHealthCheckTimeout, err := flags.GetDuration("dcpsvr-healthcheck-timeout")
+	if err != nil {
+		return err
+	}
+	uo.EngineHealthCheckTimeout = engineHealthCheckTimeout
+
+	waitServantJobTimeout, err := flags.GetDuration("wait-servant-job-timeout")
+	if err != nil {
+		return err
+	}
+	uo.WaitServantJobTimeout = waitServantJobTimeout
+
+	rb, err := flags.GetBool("rollback-on-failure")
+	if err != nil {
+		return err
+	}
+	uo.RollbackOnFailure = rb
+
+	sa, err := flags.GetString("system-architecture")
+	if err != nil {
+		return err
+	}
+	uo.SystemArchitecture = sa
+
+	yhi, err := flags.GetString("dcpsvr-image")
+	if err != nil {
+		return err
+	}
+	uo.EngineImage = yhi
+
+	ycmi, err := flags.GetString("controller-manager-image")
+	if err != nil {
+		return err
+	}
+	uo.ControllerManagerImage = ycmi
+
+	nsi, err := flags.GetString("node-servant-image")
+	if err != nil {
+		return err
+	}
+	uo.NodeServantImage = nsi
+
+	ytsi, err := flags.GetString("tunnel-server-image")
+	if err != nil {
+		return err
+	}
+	uo.TunnelServerImage = ytsi
+
+	ytai, err := flags.GetString("tunnel-agent-image")
+	if err != nil {
+		return err
+	}
+	uo.TunnelAgentImage = ytai
+
+	yami, err := flags.GetString("app-manager-image")
+	if err != nil {
+		return err
+	}
+	uo.AppManagerImage = yami
+
+	// prepare path of cluster kubeconfig file
+	uo.KubeConfigPath, err = kubeutil.PrepareKubeConfigPath(flags)
+	if err != nil {
+		return err
+	}
+
+	// parse kubeconfig and generate the clientset
+	uo.ClientSet, err = kubeutil.GenClientSet(flags)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Validate makes sure provided values for UpgradeOptions are valid
+func (uo *UpgradeOptions) Validate() error {
+	if err := convert.ValidateKubeConfig(uo.KubeConfigPath); err != nil {
+		return err
+	}
+	if err := kubeutil.ValidateServerVersion(uo.ClientSet); err != nil {
+		return err
+	}
+	if err := ValidateBatchSize(uo.BatchSize); err != nil {
+		return err
+	}
+	if err := convert.ValidateProvider(uo.Provider); err != nil {
+		return err
+	}
+	if err := convert.ValidateTunnelServerAddress(uo.TunnelServerAddress); err != nil {
+		return err
+	}
+	if err := convert.ValidateEngineHealthCheckTimeout(uo.EngineHealthCheckTimeout); err != nil {
+		return err
+	}
+	if err := convert.ValidateWaitServantJobTimeout(uo.WaitServantJobTimeout); err != nil {
+		return err
+	}
+	if err := convert.ValidateSystemArchitecture(uo.SystemArchitecture); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func ValidateBatchSize(size int) error {
+	if size <= 0 {
+		return fmt.Errorf("invalid --batch-size: %d, it must be greater than 0", size)
+	}
+	return nil
+}
@@ -0,0 +1,457 @@
+package upgrade
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/klog/v2"
+
+	"github.com/bhojpur/dcp/pkg/client/constants"
+	"github.com/bhojpur/dcp/pkg/client/lock"
+	kubeutil "github.com/bhojpur/dcp/pkg/client/util/kubernetes"
+	"github.com/bhojpur/dcp/pkg/engine/util"
+	nodeservant "github.com/bhojpur/dcp/pkg/node-servant"
+	"github.com/bhojpur/dcp/pkg/projectinfo"
+)
+
+const (
+	// defaultEngineHealthCheckTimeout defines the default timeout for Bhojpur DCP engine health check phase
+	defaultEngineHealthCheckTimeout = 2 * time.Minute
+
+	latestEngineImage            = "bhojpur/dcpsvr:latest"
+	latestControllerManagerImage = "bhojpur/controller-manager:latest"
+	latestNodeServantImage       = "bhojpur/node-servant:latest"
+	latestTunnelServerImage      = "bhojpur/tunnel-server:latest"
+	latestTunnelAgentImage       = "bhojpur/tunnel-agent:latest"
+	versionedAppManagerImage     = "bhojpur/app-manager:v0.4.0"
+)
+
+// upgradeNode is a converted node and the working mode of its dcpsvr
+type upgradeNode struct {
+	name        string
+	workingMode util.WorkingMode
+}
+
+// ClusterUpgrader do the cluster upgrade job.
+// The components running in cluster are upgraded first, then the dcpsvr
+// is upgraded node by node in batches.
+type ClusterUpgrader struct {
+	UpgradeOptions
+
+	// the workloads before the upgrade, kept to roll back
+	previousDeployments []*appsv1.Deployment
+	previousDaemonSets  []*appsv1.DaemonSet
+	// upgradeID marks the dcpsvr backups written by this upgrade, so that the rollback
+	// does not restore the ones of a previous upgrade
+	upgradeID string
+}
+
+// NewUpgradeCmd generates a new upgrade command
+func NewUpgradeCmd() *cobra.Command {
+	uo := NewUpgradeOptions()
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Upgrades the Bhojpur DCP components of a converted cluster",
+		Run: func(cmd *cobra.Command, _ []string) {
+			if err := uo.Complete(cmd.Flags()); err != nil {
+				klog.Errorf("Fail to complete the upgrade option: %s", err)
+				os.Exit(1)
+			}
+			if err := uo.Validate(); err != nil {
+				klog.Errorf("Fail to validate upgrade option: %s", err)
+				os.Exit(1)
+			}
+			upgrader := NewClusterUpgrader(uo)
+			if err := upgrader.RunUpgrade(); err != nil {
+				klog.Errorf("Fail to upgrade the cluster: %s", err)
+				os.Exit(1)
+			}
+		},
+		Args: cobra.NoArgs,
+	}
+
+	setFlags(cmd)
+	return cmd
+}
+
+// setFlags sets flags.
+func setFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP(
+		"nodes", "n", "",
+		"The list of converted nodes whose dcpsvr will be upgraded. If not set, all converted nodes will be upgraded."+
+			"(e.g. -n node1,node2)",
+	)
+	cmd.Flags().Int(
+		"batch-size", 1,
+		"The number of nodes upgraded at the same time.",
+	)
+	cmd.Flags().String(
+		"tunnel-server-address", "",
+		"The tunnel-server address, shall be the same as the one used to convert the cluster. If not set, the one of the tunnel in cluster is kept.",
+	)
+	cmd.Flags().String(
+		"kubeadm-conf-path", "",
+		"The path to kubelet service conf that is used by kubelet component to join the cluster on the edge node.",
+	)
+	cmd.Flags().StringP(
+		"provider", "p", "minikube",
+		"The provider of the original Kubernetes cluster, shall be the same as the one used to convert the cluster.",
+	)
+	cmd.Flags().Duration(
+		"dcpsvr-healthcheck-timeout", defaultEngineHealthCheckTimeout,
+		"The timeout for Bhojpur DCP engine health check.",
+	)
+	cmd.Flags().Duration(
+		"wait-servant-job-timeout", kubeutil.DefaultWaitServantJobTimeout,
+		"The timeout for servant-job run check.")
+	cmd.Flags().Bool(
+		"rollback-on-failure", false,
+		"If set, the components and the dcpsvr of the upgraded nodes are rolled back when the upgrade of a batch fails.",
+	)
+	cmd.Flags().String(
+		"system-architecture", "amd64",
+		"The system architecture of cloud nodes.",
+	)
+
+	cmd.Flags().String("dcpsvr-image", latestEngineImage, "The Bhojpur DCP server engine image.")
+	cmd.Flags().String("controller-manager-image", latestControllerManagerImage, "The controller-manager image.")
+	cmd.Flags().String("node-servant-image", latestNodeServantImage, "The node-servant image.")
+	cmd.Flags().String("tunnel-server-image", latestTunnelServerImage, "The tunnel-server image.")
+	cmd.Flags().String("tunnel-agent-image", latestTunnelAgentImage, "The tunnel-agent image.")
+	cmd.Flags().String("app-manager-image", versionedAppManagerImage, "The app-manager image.")
+}
+
+func NewClusterUpgrader(uo *UpgradeOptions) *ClusterUpgrader {
+	return &ClusterUpgrader{
+		UpgradeOptions: *uo,
+	}
+}
+
+// RunUpgrade performs the upgrade
+func (u *ClusterUpgrader) RunUpgrade() (err error) {
+	if err = lock.AcquireLock(u.ClientSet); err != nil {
+		return
+	}
+	defer func() {
+		if releaseLockErr := lock.ReleaseLock(u.ClientSet); releaseLockErr != nil {
+			klog.Error(releaseLockErr)
+		}
+	}()
+
+	nodeLst, err := u.ClientSet.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return
+	}
+	nodes, err := getUpgradeNodes(nodeLst, u.Nodes)
+	if err != nil {
+		return
+	}
+	u.upgradeID = rand.String(8)
+
+	fmt.Println("[upgrade] Upgrading controller-manager, tunnel-server, tunnel-agent and app-manager")
+	if err = u.upgradeComponents(); err != nil {
+		if u.RollbackOnFailure {
+			u.rollback(nil)
+		}
+		return
+	}
+
+	joinToken, err := kubeutil.GetOrCreateJoinTokenString(u.ClientSet)
+	if err != nil || joinToken == "" {
+		return fmt.Errorf("fail to get join token: %v", err)
+	}
+	upgradeCtx := map[string]string{
+		"node_servant_image": u.NodeServantImage,
+		"dcpsvr_image":       u.EngineImage,
+		"joinToken":          joinToken,
+		"kubeadm_conf_path":  u.KubeadmConfPath,
+		"provider":           string(u.Provider),
+		"upgrade_id":         u.upgradeID,
+	}
+	if u.EngineHealthCheckTimeout != defaultEngineHealthCheckTimeout {
+		upgradeCtx["dcpsvr_healthcheck_timeout"] = u.EngineHealthCheckTimeout.String()
+	}
+	workingModes := make(map[string]util.WorkingMode, len(nodes))
+	for _, node := range nodes {
+		workingModes[node.name] = node.workingMode
+	}
+
+	batches := splitIntoBatches(nodes, u.BatchSize)
+	var attempted []string
+	for i, batch := range batches {
+		fmt.Printf("[upgrade] Running node-servant-upgrade jobs to upgrade the dcpsvr on batch %d/%d: %s\n",
+			i+1, len(batches), strings.Join(batch, ", "))
+		attempted = append(attempted, batch...)
+		var failed []string
+		failed, err = u.runServantJobs(func(nodeName string) (*batchv1.Job, error) {
+			upgradeCtx["working_mode"] = string(workingModes[nodeName])
+			return nodeservant.RenderNodeServantJob("upgrade", upgradeCtx, nodeName)
+		}, batch)
+		if err != nil {
+			return
+		}
+		if len(failed) != 0 {
+			err = fmt.Errorf("fail to upgrade the dcpsvr on %d nodes: %s", len(failed), strings.Join(failed, ", "))
+			break
+		}
+	}
+
+	if err != nil {
+		fmt.Println("[upgrade] You can get job information through 'kubectl get jobs -n kube-system' to debug.")
+		if u.RollbackOnFailure {
+			u.rollback(attempted)
+		} else {
+			fmt.Println("\tThe upgrade is stopped, the nodes of the following batches are not upgraded. " +
+				"Run the same upgrade command again to retry, or with --rollback-on-failure to roll back.")
+		}
+		return
+	}
+
+	fmt.Printf("[upgrade] The components and the dcpsvr on %d nodes are upgraded\n", len(nodes))
+	return
+}
+
+// upgradeComponents renders the workloads of the components with the new images
+// and updates the ones running in cluster. Components not deployed are skipped.
+func (u *ClusterUpgrader) upgradeComponents() error {
+	certIP, tunnelServerAddress, err := u.tunnelAddresses()
+	if err != nil {
+		return err
+	}
+
+	deployments := []struct {
+		name string
+		tmpl string
+		ctx  map[string]string
+	}{
+		{
+			name: constants.ControllerManager,
+			tmpl: constants.ControllerManagerDeployment,
+			ctx: map[string]string{
+				"image":         u.ControllerManagerImage,
+				"edgeNodeLabel": projectinfo.GetEdgeWorkerLabelKey()},
+		},
+		{
+			name: constants.TunnelServer,
+			tmpl: constants.TunnelServerDeployment,
+			ctx: map[string]string{
+				"image":           u.TunnelServerImage,
+				"arch":            u.SystemArchitecture,
+				"certIP":          certIP,
+				"edgeWorkerLabel": projectinfo.GetEdgeWorkerLabelKey()},
+		},
+		{
+			name: constants.AppManager,
+			tmpl: constants.AppManagerDeployment,
+			ctx: map[string]string{
+				"image":           u.AppManagerImage,
+				"arch":            u.SystemArchitecture,
+				"edgeWorkerLabel": projectinfo.GetEdgeWorkerLabelKey()},
+		},
+	}
+	for _, d := range deployments {
+		previous, err := kubeutil.UpdateDeployFromYaml(u.ClientSet, kubeutil.SystemNamespace, d.tmpl, d.ctx)
+		if err != nil {
+			return err
+		}
+		if previous == nil {
+			fmt.Printf("\t[INFO] %s is not deployed, skip to upgrade it\n", d.name)
+			continue
+		}
+		u.previousDeployments = append(u.previousDeployments, previous)
+		fmt.Printf("\t[INFO] %s is upgraded\n", d.name)
+	}
+
+	previous, err := kubeutil.UpdateDaemonSetFromYaml(u.ClientSet, constants.TunnelAgentDaemonSet,
+		map[string]string{
+			"image":               u.TunnelAgentImage,
+			"edgeWorkerLabel":     projectinfo.GetEdgeWorkerLabelKey(),
+			"tunnelServerAddress": tunnelServerAddress})
+	if err != nil {
+		return err
+	}
+	if previous == nil {
+		fmt.Printf("\t[INFO] %s is not deployed, skip to upgrade it\n", constants.TunnelAgent)
+		return nil
+	}
+	u.previousDaemonSets = append(u.previousDaemonSets, previous)
+	fmt.Printf("\t[INFO] %s is upgraded\n", constants.TunnelAgent)
+	return nil
+}
+
+// tunnelAddresses returns the cert ips of the tunnel-server and the tunnel-server address
+// of the tunnel-agent. Unless --tunnel-server-address is set, they are kept from the
+// workloads in cluster, as their pod templates are replaced by the upgrade.
+func (u *ClusterUpgrader) tunnelAddresses() (certIP, tunnelServerAddress string, err error) {
+	if u.TunnelServerAddress != "" {
+		certIP, _, _ = net.SplitHostPort(u.TunnelServerAddress)
+		return certIP, u.TunnelServerAddress, nil
+	}
+
+	dply, err := u.ClientSet.AppsV1().Deployments(kubeutil.SystemNamespace).
+		Get(context.Background(), constants.TunnelServer, metav1.GetOptions{})
+	if err == nil {
+		certIP = containerArg(&dply.Spec.Template.Spec, "--cert-ips")
+	} else if !apierrors.IsNotFound(err) {
+		return "", "", fmt.Errorf("fail to get the deployment/%s: %v", constants.TunnelServer, err)
+	}
+	ds, err := u.ClientSet.AppsV1().DaemonSets(kubeutil.SystemNamespace).
+		Get(context.Background(), constants.TunnelAgent, metav1.GetOptions{})
+	if err == nil {
+		tunnelServerAddress = containerArg(&ds.Spec.Template.Spec, "--tunnelserver-addr")
+	} else if !apierrors.IsNotFound(err) {
+		return "", "", fmt.Errorf("fail to get the daemonset/%s: %v", constants.TunnelAgent, err)
+	}
+	return certIP, tunnelServerAddress, nil
+}
+
+// containerArg returns the value of the flag in the args of the containers of the pod.
+func containerArg(spec *v1.PodSpec, flag string) string {
+	for _, c := range spec.Containers {
+		for _, arg := range append(c.Command, c.Args...) {
+			if strings.HasPrefix(arg, flag+"=") {
+				return strings.TrimPrefix(arg, flag+"=")
+			}
+		}
+	}
+	return ""
+}
+
+// rollback restores the dcpsvr on the given nodes and the components upgraded so far.
+// Errors are reported, but do not stop the rollback of the others.
+func (u *ClusterUpgrader) rollback(nodeNames []string) {
+	if len(nodeNames) != 0 {
+		fmt.Printf("[rollback] Running node-servant-upgrade-rollback jobs to restore the dcpsvr on %d nodes\n", len(nodeNames))
+		rollbackCtx := map[string]string{
+			"node_servant_image": u.NodeServantImage,
+			"provider":           string(u.Provider),
+			"upgrade_id":         u.upgradeID,
+		}
+		if u.EngineHealthCheckTimeout != defaultEngineHealthCheckTimeout {
+			rollbackCtx["dcpsvr_healthcheck_timeout"] = u.EngineHealthCheckTimeout.String()
+		}
+		failed, err := u.runServantJobs(func(nodeName string) (*batchv1.Job, error) {
+			return nodeservant.RenderNodeServantJob("upgrade-rollback", rollbackCtx, nodeName)
+		}, nodeNames)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\t[ERROR] fail to roll back the dcpsvr: %s\n", err)
+		} else if len(failed) != 0 {
+			fmt.Fprintf(os.Stderr, "\t[ERROR] fail to roll back the dcpsvr on %d nodes: %s\n", len(failed), strings.Join(failed, ", "))
+		}
+	}
+
+	fmt.Println("[rollback] Restoring the upgraded components")
+	for i := len(u.previousDaemonSets) - 1; i >= 0; i-- {
+		if err := kubeutil.RestoreDaemonSet(u.ClientSet, u.previousDaemonSets[i]); err != nil {
+			fmt.Fprintf(os.Stderr, "\t[ERROR] %s\n", err)
+		}
+	}
+	for i := len(u.previousDeployments) - 1; i >= 0; i-- {
+		if err := kubeutil.RestoreDeployment(u.ClientSet, u.previousDeployments[i]); err != nil {
+			fmt.Fprintf(os.Stderr, "\t[ERROR] %s\n", err)
+		}
+	}
+}
+
+// runServantJobs runs the servant jobs on the nodes and returns the nodes where the job failed.
+func (u *ClusterUpgrader) runServantJobs(getJob func(nodeName string) (*batchv1.Job, error),
+	nodeNames []string) ([]string, error) {
+	results, err := kubeutil.RunServantJobsWithResults(u.ClientSet, u.WaitServantJobTimeout, getJob, nodeNames,
+		func(res kubeutil.ServantJobResult) {
+			if res.Err != nil {
+				fmt.Fprintf(os.Stderr, "\t[ERROR] fail to run servant job(%s): %s\n", res.JobName, res.Err)
+			} else {
+				fmt.Fprintf(os.Stderr, "\t[INFO] servant job(%s) has succeeded\n", res.JobName)
+			}
+		})
+	if err != nil {
+		return nil, err
+	}
+	var failed []string
+	for _, res := range results {
+		if res.Err != nil {
+			failed = append(failed, res.NodeName)
+		}
+	}
+	return failed, nil
+}
+
+// getUpgradeNodes returns the converted nodes, which are labeled with the edgeworker label
+// by the conversion. If nodeNames is not empty, only these nodes are returned.
+func getUpgradeNodes(nodeLst *v1.NodeList, nodeNames []string) ([]upgradeNode, error) {
+	converted := make(map[string]upgradeNode)
+	var nodes []upgradeNode
+	for _, node := range nodeLst.Items {
+		val, ok := node.Labels[projectinfo.GetEdgeWorkerLabelKey()]
+		if !ok {
+			continue
+		}
+		n := upgradeNode{name: node.GetName(), workingMode: util.WorkingModeCloud}
+		if val == "true" {
+			n.workingMode = util.WorkingModeEdge
+		}
+		converted[n.name] = n
+		nodes = append(nodes, n)
+	}
+
+	if len(nodeNames) == 0 {
+		return nodes, nil
+	}
+	nodes = make([]upgradeNode, 0, len(nodeNames))
+	for _, name := range nodeNames {
+		n, ok := converted[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid --nodes: node %s does not exist or is not converted", name)
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+// splitIntoBatches splits the names of the nodes into batches of at most size nodes.
+func splitIntoBatches(nodes []upgradeNode, size int) [][]string {
+	var batches [][]string
+	for start := 0; start < len(nodes); start += size {
+		end := start + size
+		if end > len(nodes) {
+			end = len(nodes)
+		}
+		batch := make([]string, 0, end-start)
+		for _, n := range nodes[start:end] {
+			batch = append(batch, n.name)
+		}
+		batches = append(batches, batch)
+	}
+	return batches
+}
@@ -0,0 +1,186 @@
+package upgrade
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/bhojpur/dcp/pkg/client/constants"
+	kubeutil "github.com/bhojpur/dcp/pkg/client/util/kubernetes"
+	"github.com/bhojpur/dcp/pkg/engine/util"
+	"github.com/bhojpur/dcp/pkg/projectinfo"
+)
+
+func newNode(name string, labels map[string]string) v1.Node {
+	return v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func TestGetUpgradeNodes(t *testing.T) {
+	edgeLabel := projectinfo.GetEdgeWorkerLabelKey()
+	nodeLst := &v1.NodeList{Items: []v1.Node{
+		newNode("master", map[string]string{edgeLabel: "false"}),
+		newNode("edge-1", map[string]string{edgeLabel: "true"}),
+		newNode("edge-2", map[string]string{edgeLabel: "true"}),
+		newNode("unconverted", nil),
+	}}
+
+	tests := []struct {
+		name      string
+		nodeNames []string
+		expect    []upgradeNode
+		expectErr bool
+	}{
+		{
+			name: "all converted nodes",
+			expect: []upgradeNode{
+				{name: "master", workingMode: util.WorkingModeCloud},
+				{name: "edge-1", workingMode: util.WorkingModeEdge},
+				{name: "edge-2", workingMode: util.WorkingModeEdge},
+			},
+		},
+		{
+			name:      "selected nodes",
+			nodeNames: []string{"edge-2", "master"},
+			expect: []upgradeNode{
+				{name: "edge-2", workingMode: util.WorkingModeEdge},
+				{name: "master", workingMode: util.WorkingModeCloud},
+			},
+		},
+		{
+			name:      "unconverted node",
+			nodeNames: []string{"edge-1", "unconverted"},
+			expectErr: true,
+		},
+		{
+			name:      "missing node",
+			nodeNames: []string{"edge-3"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		st := tt
+		tf := func(t *testing.T) {
+			t.Parallel()
+			nodes, err := getUpgradeNodes(nodeLst, st.nodeNames)
+			if st.expectErr {
+				if err == nil {
+					t.Fatalf("expect an error, but got nodes %v", nodes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fail to get the nodes: %v", err)
+			}
+			if !reflect.DeepEqual(nodes, st.expect) {
+				t.Fatalf("expect nodes %v, but got %v", st.expect, nodes)
+			}
+		}
+		t.Run(st.name, tf)
+	}
+}
+
+func TestSplitIntoBatches(t *testing.T) {
+	nodes := []upgradeNode{{name: "n1"}, {name: "n2"}, {name: "n3"}, {name: "n4"}, {name: "n5"}}
+
+	tests := []struct {
+		name   string
+		size   int
+		expect [][]string
+	}{
+		{
+			name:   "one node per batch",
+			size:   1,
+			expect: [][]string{{"n1"}, {"n2"}, {"n3"}, {"n4"}, {"n5"}},
+		},
+		{
+			name:   "last batch is smaller",
+			size:   2,
+			expect: [][]string{{"n1", "n2"}, {"n3", "n4"}, {"n5"}},
+		},
+		{
+			name:   "batch larger than the nodes",
+			size:   10,
+			expect: [][]string{{"n1", "n2", "n3", "n4", "n5"}},
+		},
+	}
+
+	for _, tt := range tests {
+		st := tt
+		tf := func(t *testing.T) {
+			t.Parallel()
+			if batches := splitIntoBatches(nodes, st.size); !reflect.DeepEqual(batches, st.expect) {
+				t.Fatalf("expect batches %v, but got %v", st.expect, batches)
+			}
+		}
+		t.Run(st.name, tf)
+	}
+}
+
+func TestUpgradeComponentsKeepsTunnelAddresses(t *testing.T) {
+	podSpec := func(args ...string) v1.PodTemplateSpec {
+		return v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "tunnel", Args: args}}}}
+	}
+	cliSet := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: kubeutil.SystemNamespace, Name: constants.TunnelServer},
+			Spec:       appsv1.DeploymentSpec{Template: podSpec("--bind-address=$(NODE_IP)", "--cert-ips=10.0.0.1")},
+		},
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: kubeutil.SystemNamespace, Name: constants.TunnelAgent},
+			Spec:       appsv1.DaemonSetSpec{Template: podSpec("--node-name=$(NODE_NAME)", "--tunnelserver-addr=10.0.0.1:10262")},
+		},
+	)
+	u := &ClusterUpgrader{UpgradeOptions: UpgradeOptions{
+		SystemArchitecture: "amd64",
+		TunnelServerImage:  "tunnel-server:v2",
+		TunnelAgentImage:   "tunnel-agent:v2",
+		ClientSet:          cliSet,
+	}}
+	if err := u.upgradeComponents(); err != nil {
+		t.Fatalf("fail to upgrade the components: %v", err)
+	}
+
+	dply, err := cliSet.AppsV1().Deployments(kubeutil.SystemNamespace).Get(context.Background(), constants.TunnelServer, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("fail to get the tunnel-server: %v", err)
+	}
+	if certIP := containerArg(&dply.Spec.Template.Spec, "--cert-ips"); certIP != "10.0.0.1" {
+		t.Errorf("expect the cert ips of the tunnel-server to be kept, but got %q", certIP)
+	}
+	ds, err := cliSet.AppsV1().DaemonSets(kubeutil.SystemNamespace).Get(context.Background(), constants.TunnelAgent, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("fail to get the tunnel-agent: %v", err)
+	}
+	if addr := containerArg(&ds.Spec.Template.Spec, "--tunnelserver-addr"); addr != "10.0.0.1:10262" {
+		t.Errorf("expect the tunnel-server address of the tunnel-agent to be kept, but got %q", addr)
+	}
+	if image := ds.Spec.Template.Spec.Containers[0].Image; image != "tunnel-agent:v2" {
+		t.Errorf("expect the tunnel-agent to be upgraded, but got image %s", image)
+	}
+}
@@ -29,8 +29,10 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/bhojpur/dcp/cmd/grid/node-servant/convert"
+	"github.com/bhojpur/dcp/cmd/grid/node-servant/diagnose"
 	preflightconvert "github.com/bhojpur/dcp/cmd/grid/node-servant/preflight-convert"
 	"github.com/bhojpur/dcp/cmd/grid/node-servant/revert"
+	"github.com/bhojpur/dcp/cmd/grid/node-servant/upgrade"
 	"github.com/bhojpur/dcp/pkg/projectinfo"
 )
 
@@ -43,13 +45,15 @@ func main() {
 	version := fmt.Sprintf("%#v", projectinfo.Get())
 	rootCmd := &cobra.Command{
 		Use:     "node-servant",
-		Short:   "node-servant do preflight-convert/convert/revert specific node",
+		Short:   "node-servant do preflight-convert/convert/revert/upgrade/diagnose specific node",
 		Version: version,
 	}
 	rootCmd.PersistentFlags().String("kubeconfig", "", "The path to the kubeconfig file")
 	rootCmd.AddCommand(convert.NewConvertCmd())
 	rootCmd.AddCommand(revert.NewRevertCmd())
 	rootCmd.AddCommand(preflightconvert.NewxPreflightConvertCmd())
+	rootCmd.AddCommand(upgrade.NewUpgradeCmd())
+	rootCmd.AddCommand(diagnose.NewDiagnoseCmd())
 
 	if err := rootCmd.Execute(); err != nil { // run command
 		os.Exit(1)
@@ -68,6 +68,8 @@ func setFlags(cmd *cobra.Command) {
 		"The path to kubelet service conf that is used by kubelet component to join the cluster on the work node."+
 			"Support multiple values, will search in order until get the file.(e.g -k kbcfg1,kbcfg2)",
 	)
+	cmd.Flags().String("provider", "kubeadm",
+		"The provider of the node, which decides where the kubelet args and kubeconfig are kept. (e.g. kubeadm, k3s, rke2, dcp)")
 	cmd.Flags().String("join-token", "", "The token used by Bhojpur DCP engine for joining the cluster.")
 	cmd.Flags().String("working-mode", "edge", "The node type cloud/edge, effect Bhojpur DCP engine workingMode.")
 }
@@ -0,0 +1,63 @@
+package diagnose
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	"github.com/bhojpur/dcp/pkg/node-servant/diagnose"
+)
+
+// NewDiagnoseCmd generates a new diagnose command
+func NewDiagnoseCmd() *cobra.Command {
+	o := diagnose.NewDiagnoseOptions()
+	cmd := &cobra.Command{
+		Use:   "diagnose",
+		Short: "Collects the diagnostics of the node, and prints them as a bundle",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := o.Complete(cmd.Flags()); err != nil {
+				klog.Fatalf("fail to complete the diagnose option: %s", err)
+			}
+
+			d := diagnose.NewDiagnoserWithOptions(o)
+			if err := d.Do(os.Stdout); err != nil {
+				klog.Fatalf("fail to diagnose the node: %s", err)
+			}
+			klog.Info("diagnose success")
+		},
+		Args: cobra.NoArgs,
+	}
+	setFlags(cmd)
+
+	return cmd
+}
+
+// setFlags sets flags.
+func setFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("log-lines", diagnose.DefaultLogLines, "The number of the last lines of dcpsvr logs collected.")
+	cmd.Flags().StringP("kubeadm-conf-path", "k", "",
+		"The path to kubelet service conf that is used by kubelet component to join the cluster on the work node."+
+			"Support multiple values, will search in order until get the file.(e.g -k kbcfg1,kbcfg2)",
+	)
+}
@@ -55,4 +55,6 @@ func NewRevertCmd() *cobra.Command {
 func setFlags(cmd *cobra.Command) {
 	cmd.Flags().String("kubeadm-conf-path", "",
 		"The path to kubelet service conf that is used by kubelet component to join the cluster on the edge node.")
+	cmd.Flags().String("provider", "kubeadm",
+		"The provider of the node, which decides where the kubelet args and kubeconfig are kept. (e.g. kubeadm, k3s, rke2, dcp)")
 }
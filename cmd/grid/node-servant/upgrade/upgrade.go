@@ -0,0 +1,78 @@
+package upgrade
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+
+	nodeupgrader "github.com/bhojpur/dcp/pkg/node-servant/upgrade"
+)
+
+const (
+	// defaultEngineHealthCheckTimeout defines the default timeout for Engine health check phase
+	defaultEngineHealthCheckTimeout = 2 * time.Minute
+)
+
+// NewUpgradeCmd generates a new upgrade command
+func NewUpgradeCmd() *cobra.Command {
+	o := nodeupgrader.NewUpgradeOptions()
+	cmd := &cobra.Command{
+		Use:   "upgrade --dcpsvr-image",
+		Short: "Upgrades or rolls back the Bhojpur DCP server engine on the node",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := o.Complete(cmd.Flags()); err != nil {
+				klog.Fatalf("fail to complete the upgrade option: %s", err)
+			}
+
+			upgrader := nodeupgrader.NewUpgraderWithOptions(o)
+			if err := upgrader.Do(); err != nil {
+				klog.Fatalf("fail to upgrade the Bhojpur DCP node: %s", err)
+			}
+			klog.Info("upgrade success")
+		},
+		Args: cobra.NoArgs,
+	}
+	setFlags(cmd)
+
+	return cmd
+}
+
+// setFlags sets flags.
+func setFlags(cmd *cobra.Command) {
+	cmd.Flags().String("dcpsvr-image", "bhojpur/dcpsvr:latest",
+		"The Bhojpur DCP Engine image to upgrade to.")
+	cmd.Flags().Duration("dcpsvr-healthcheck-timeout", defaultEngineHealthCheckTimeout,
+		"The timeout for Bhojpur DCP engine health check.")
+	cmd.Flags().StringP("kubeadm-conf-path", "k", "",
+		"The path to kubelet service conf that is used by kubelet component to join the cluster on the work node."+
+			"Support multiple values, will search in order until get the file.(e.g -k kbcfg1,kbcfg2)",
+	)
+	cmd.Flags().String("provider", "kubeadm",
+		"The provider of the node, which decides where the kubelet args and kubeconfig are kept. (e.g. kubeadm, k3s, rke2, dcp)")
+	cmd.Flags().String("join-token", "", "The token used by Bhojpur DCP engine for joining the cluster.")
+	cmd.Flags().String("working-mode", "edge", "The node type cloud/edge, effect Bhojpur DCP engine workingMode.")
+	cmd.Flags().String("upgrade-id", "",
+		"The id of the upgrade, a rollback only restores the Bhojpur DCP engine kept by the upgrade with the same id.")
+	cmd.Flags().Bool("rollback", false, "If set, restore the Bhojpur DCP engine kept by the last upgrade.")
+}
@@ -35,6 +35,7 @@ type Config struct {
 	NodeIP           string
 	TunnelServerAddr string
 	Client           kubernetes.Interface
+	BootstrapClient  kubernetes.Interface
 	AgentIdentifiers string
 	AgentMetaAddr    string
 	CertDir          string
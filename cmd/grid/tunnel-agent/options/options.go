@@ -51,6 +51,7 @@ type AgentOptions struct {
 	MetaHost         string
 	MetaPort         string
 	CertDir          string
+	JoinToken        string
 }
 
 // NewAgentOptions creates a new AgentOptions with a default config.
@@ -98,6 +99,7 @@ func (o *AgentOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.MetaHost, "meta-host", o.MetaHost, "The ip address on which listen for --meta-port port.")
 	fs.StringVar(&o.MetaPort, "meta-port", o.MetaPort, "The port on which to serve HTTP requests like profling, metrics")
 	fs.StringVar(&o.CertDir, "cert-dir", o.CertDir, "The directory of certificate stored at.")
+	fs.StringVar(&o.JoinToken, "join-token", o.JoinToken, fmt.Sprintf("The bootstrap token used to request a new certificate when the certificate is rejected by %s.", projectinfo.GetServerName()))
 }
 
 // agentIdentifiersIsValid verify agent identifiers are valid or not.
@@ -148,6 +150,14 @@ func (o *AgentOptions) Config() (*config.Config, error) {
 		klog.Infof("neither --kube-config nor --apiserver-addr is set, will use %s as the kubeconfig", kubeConfig)
 	}
 
+	if o.JoinToken != "" {
+		klog.Infof("create the bootstrap clientset based on the join token.")
+		c.BootstrapClient, err = kubeutil.CreateClientSetBootstrapToken(kubeConfig, o.ApiserverAddr, o.JoinToken)
+		if err != nil {
+			return c, err
+		}
+	}
+
 	if kubeConfig != "" {
 		klog.Infof("create the clientset based on the kubeconfig(%s).", kubeConfig)
 		c.Client, err = kubeutil.CreateClientSetKubeConfig(kubeConfig)
@@ -22,10 +22,9 @@ package cmd
 
 import (
 	"fmt"
-	"time"
 
 	"github.com/spf13/cobra"
-	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/util/certificate"
 	"k8s.io/klog/v2"
 
@@ -77,7 +76,6 @@ func Run(cfg *config.CompletedConfig, stopCh <-chan struct{}) error {
 	var (
 		tunnelServerAddr string
 		err              error
-		agentCertMgr     certificate.Manager
 	)
 
 	// 1. get the address of the tunnel-server
@@ -89,39 +87,26 @@ func Run(cfg *config.CompletedConfig, stopCh <-chan struct{}) error {
 	}
 	klog.Infof("%s address: %s", projectinfo.GetServerName(), tunnelServerAddr)
 
-	// 2. create a certificate manager
-	agentCertMgr, err =
-		certmanager.NewTunnelAgentCertManager(cfg.Client, cfg.CertDir)
-	if err != nil {
-		return err
-	}
-	agentCertMgr.Start()
-
-	// 2.1. waiting for the certificate is generated
-	_ = wait.PollUntil(5*time.Second, func() (bool, error) {
-		if agentCertMgr.Current() != nil {
-			return true, nil
-		}
-		klog.Infof("certificate %s not signed, waiting...",
-			projectinfo.GetAgentName())
-		return false, nil
-	}, stopCh)
-	klog.Infof("certificate %s ok", projectinfo.GetAgentName())
-
-	// 3. generate a TLS configuration for securing the connection to server
-	tlsCfg, err := certmanager.GenTLSConfigUseCertMgrAndCA(agentCertMgr,
-		tunnelServerAddr, constants.TunnelCAFile)
-	if err != nil {
-		return err
-	}
-
-	// 4. start the tunnel-agent
-	ta := agent.NewTunnelAgent(tlsCfg, tunnelServerAddr, cfg.NodeName, cfg.AgentIdentifiers)
-	ta.Run(stopCh)
+	// 2. create an identity manager, which requests the certificate,
+	// connects to the tunnel-server and rotates the certificate
+	im := agent.NewIdentityManager(&agent.IdentityConfig{
+		NodeName:         cfg.NodeName,
+		TunnelServerAddr: tunnelServerAddr,
+		AgentIdentifiers: cfg.AgentIdentifiers,
+		CAFile:           constants.TunnelCAFile,
+		Client:           cfg.Client,
+		BootstrapClient:  cfg.BootstrapClient,
+		NewCertManager: func(client kubernetes.Interface) (certificate.Manager, error) {
+			return certmanager.NewTunnelAgentCertManager(client, cfg.CertDir)
+		},
+		ResetCert: func() error {
+			return certmanager.ResetTunnelAgentCert(cfg.CertDir)
+		},
+	})
 
-	// 5. start meta server
+	// 3. start meta server
 	util.RunMetaServer(cfg.AgentMetaAddr)
 
-	<-stopCh
-	return nil
+	// 4. start the tunnel-agent
+	return im.Run(stopCh)
 }
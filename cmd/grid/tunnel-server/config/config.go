@@ -38,7 +38,9 @@ type Config struct {
 	EnableIptables              bool
 	EnableDNSController         bool
 	IptablesSyncPeriod          int
+	ForwardRulesBackend         string
 	DNSSyncPeriod               int
+	DNSServerAddr               string
 	CertDNSNames                []string
 	CertIPs                     []net.IP
 	CertDir                     string
@@ -46,12 +48,17 @@ type Config struct {
 	ListenAddrForMaster         string
 	ListenInsecureAddrForMaster string
 	ListenMetaAddr              string
+	ListenAddrForPeer           string
+	PeerAdvertiseAddr           string
 	RootCert                    *x509.CertPool
 	Client                      kubernetes.Interface
 	SharedInformerFactory       informers.SharedInformerFactory
 	ServerCount                 int
 	ProxyStrategy               string
 	InterceptorServerUDSFile    string
+	EnablePeerForwarding        bool
+	PeerToken                   string
+	ReplicaID                   string
 }
 
 type completedConfig struct {
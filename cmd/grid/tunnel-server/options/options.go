@@ -23,6 +23,7 @@ package options
 import (
 	"fmt"
 	"net"
+	"os"
 	"strings"
 	"time"
 
@@ -35,6 +36,7 @@ import (
 	"github.com/bhojpur/dcp/pkg/projectinfo"
 	"github.com/bhojpur/dcp/pkg/tunnel/constants"
 	kubeutil "github.com/bhojpur/dcp/pkg/tunnel/kubernetes"
+	"github.com/bhojpur/dcp/pkg/tunnel/trafficforward/iptables"
 	"github.com/bhojpur/dcp/pkg/utils/certmanager"
 )
 
@@ -51,13 +53,20 @@ type ServerOptions struct {
 	EnableDNSController    bool
 	EgressSelectorEnabled  bool
 	IptablesSyncPeriod     int
+	ForwardRulesBackend    string
 	DNSSyncPeriod          int
+	DNSServerAddr          string
 	TunnelAgentConnectPort string
 	SecurePort             string
 	InsecurePort           string
 	MetaPort               string
 	ServerCount            int
 	ProxyStrategy          string
+	EnablePeerForwarding   bool
+	PeerPort               string
+	PeerAdvertiseAddr      string
+	PeerToken              string
+	ReplicaID              string
 }
 
 // NewServerOptions creates a new ServerOptions
@@ -68,6 +77,7 @@ func NewServerOptions() *ServerOptions {
 		EnableIptables:         true,
 		EnableDNSController:    true,
 		IptablesSyncPeriod:     60,
+		ForwardRulesBackend:    string(iptables.BackendAuto),
 		DNSSyncPeriod:          1800,
 		ServerCount:            1,
 		TunnelAgentConnectPort: constants.TunnelServerAgentPort,
@@ -75,6 +85,12 @@ func NewServerOptions() *ServerOptions {
 		InsecurePort:           constants.TunnelServerMasterInsecurePort,
 		MetaPort:               constants.TunnelServerMetaPort,
 		ProxyStrategy:          string(server.ProxyStrategyDestHost),
+		PeerPort:               constants.TunnelServerPeerPort,
+		PeerAdvertiseAddr:      os.Getenv(constants.TunnelServerPodIPEnv),
+		ReplicaID:              os.Getenv(constants.TunnelServerPodNameEnv),
+	}
+	if o.ReplicaID == "" {
+		o.ReplicaID, _ = os.Hostname()
 	}
 	return o
 }
@@ -85,6 +101,28 @@ func (o *ServerOptions) Validate() error {
 		return fmt.Errorf("%s's bind address can't be empty",
 			projectinfo.GetServerName())
 	}
+	if len(o.DNSServerAddr) != 0 {
+		if _, _, err := net.SplitHostPort(o.DNSServerAddr); err != nil {
+			return fmt.Errorf("invalid dns server address %q, %v", o.DNSServerAddr, err)
+		}
+	}
+	if o.EnablePeerForwarding {
+		if len(o.PeerToken) == 0 {
+			return fmt.Errorf("--peer-token is required when peer forwarding is enabled")
+		}
+		if len(o.PeerAdvertiseAddr) == 0 {
+			return fmt.Errorf("--peer-advertise-address or $%s is required when peer forwarding is enabled", constants.TunnelServerPodIPEnv)
+		}
+		if len(o.ReplicaID) == 0 {
+			return fmt.Errorf("--replica-id or $%s is required when peer forwarding is enabled", constants.TunnelServerPodNameEnv)
+		}
+	}
+	switch iptables.Backend(o.ForwardRulesBackend) {
+	case iptables.BackendAuto, iptables.BackendIptables, iptables.BackendNftables:
+	default:
+		return fmt.Errorf("invalid forward rules backend %q, must be one of auto, iptables or nftables",
+			o.ForwardRulesBackend)
+	}
 	return nil
 }
 
@@ -101,9 +139,16 @@ func (o *ServerOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.BoolVar(&o.EnableDNSController, "enable-dns-controller", o.EnableDNSController, "If allow DNS controller to set the dns rules.")
 	fs.BoolVar(&o.EgressSelectorEnabled, "egress-selector-enable", o.EgressSelectorEnabled, "If the apiserver egress selector has been enabled.")
 	fs.IntVar(&o.IptablesSyncPeriod, "iptables-sync-period", o.IptablesSyncPeriod, "The synchronization period of the iptable manager.")
+	fs.StringVar(&o.ForwardRulesBackend, "forward-rules-backend", o.ForwardRulesBackend, "The backend used to maintain the dnat rules, one of auto, iptables or nftables. auto selects nftables when iptables is missing or is the nf_tables shim.")
 	fs.IntVar(&o.DNSSyncPeriod, "dns-sync-period", o.DNSSyncPeriod, "The synchronization period of the DNS controller.")
+	fs.StringVar(&o.DNSServerAddr, "dns-server-addr", o.DNSServerAddr, "The address on which the embedded DNS server answers A/AAAA/PTR queries for node hostnames, e.g. 0.0.0.0:10253. It can be used as a CoreDNS forward target. Empty disables the server.")
 	fs.IntVar(&o.ServerCount, "server-count", o.ServerCount, "The number of proxy server instances, should be 1 unless it is an HA server.")
 	fs.StringVar(&o.ProxyStrategy, "proxy-strategy", o.ProxyStrategy, "The strategy of proxying requests from tunnel server to agent.")
+	fs.BoolVar(&o.EnablePeerForwarding, "enable-peer-forwarding", o.EnablePeerForwarding, "If forward requests to the replica holding the agent tunnel, so agents only need to connect to one of the tunnel server replicas.")
+	fs.StringVar(&o.PeerPort, "peer-port", o.PeerPort, "The port on which to serve connections from other tunnel server replicas")
+	fs.StringVar(&o.PeerAdvertiseAddr, "peer-advertise-address", o.PeerAdvertiseAddr, fmt.Sprintf("The ip address that other replicas use to connect to this replica, defaults to $%s.", constants.TunnelServerPodIPEnv))
+	fs.StringVar(&o.PeerToken, "peer-token", o.PeerToken, "The token shared by all tunnel server replicas to authenticate peer connections.")
+	fs.StringVar(&o.ReplicaID, "replica-id", o.ReplicaID, fmt.Sprintf("The unique id of this replica in the replica registry, defaults to $%s or the hostname.", constants.TunnelServerPodNameEnv))
 	fs.StringVar(&o.TunnelAgentConnectPort, "tunnel-agent-connect-port", o.TunnelAgentConnectPort, "The port on which to serve tcp packets from tunnel agent")
 	fs.StringVar(&o.SecurePort, "secure-port", o.SecurePort, "The port on which to serve HTTPS requests from cloud clients like prometheus")
 	fs.StringVar(&o.InsecurePort, "insecure-port", o.InsecurePort, "The port on which to serve HTTP requests from cloud clients like metrics-server")
@@ -117,12 +162,17 @@ func (o *ServerOptions) Config() (*config.Config, error) {
 		EnableIptables:        o.EnableIptables,
 		EnableDNSController:   o.EnableDNSController,
 		IptablesSyncPeriod:    o.IptablesSyncPeriod,
+		ForwardRulesBackend:   o.ForwardRulesBackend,
 		DNSSyncPeriod:         o.DNSSyncPeriod,
+		DNSServerAddr:         o.DNSServerAddr,
 		CertDNSNames:          make([]string, 0),
 		CertIPs:               make([]net.IP, 0),
 		CertDir:               o.CertDir,
 		ServerCount:           o.ServerCount,
 		ProxyStrategy:         o.ProxyStrategy,
+		EnablePeerForwarding:  o.EnablePeerForwarding,
+		PeerToken:             o.PeerToken,
+		ReplicaID:             o.ReplicaID,
 	}
 
 	if o.CertDNSNames != "" {
@@ -144,6 +194,10 @@ func (o *ServerOptions) Config() (*config.Config, error) {
 	cfg.ListenAddrForMaster = net.JoinHostPort(o.BindAddr, o.SecurePort)
 	cfg.ListenInsecureAddrForMaster = net.JoinHostPort(o.InsecureBindAddr, o.InsecurePort)
 	cfg.ListenMetaAddr = net.JoinHostPort(o.InsecureBindAddr, o.MetaPort)
+	cfg.ListenAddrForPeer = net.JoinHostPort(o.BindAddr, o.PeerPort)
+	if o.PeerAdvertiseAddr != "" {
+		cfg.PeerAdvertiseAddr = net.JoinHostPort(o.PeerAdvertiseAddr, o.PeerPort)
+	}
 	cfg.RootCert, err = certmanager.GenRootCertPool(o.KubeConfig, constants.TunnelCAFile)
 	if err != nil {
 		return nil, fmt.Errorf("fail to generate the rootCertPool: %s", err)
@@ -159,6 +213,11 @@ func (o *ServerOptions) Config() (*config.Config, error) {
 	}
 	cfg.SharedInformerFactory = informers.NewSharedInformerFactory(cfg.Client, 24*time.Hour)
 
-	klog.Infof("Bhojpur DCP tunnel server config: %#+v", cfg)
+	// don't leak the peer token into the logs
+	logCfg := *cfg
+	if logCfg.PeerToken != "" {
+		logCfg.PeerToken = "******"
+	}
+	klog.Infof("Bhojpur DCP tunnel server config: %#+v", &logCfg)
 	return cfg, nil
 }
@@ -89,7 +89,8 @@ func Run(cfg *config.CompletedConfig, stopCh <-chan struct{}) error {
 			cfg.SharedInformerFactory,
 			cfg.ListenInsecureAddrForMaster,
 			cfg.ListenAddrForMaster,
-			cfg.DNSSyncPeriod)
+			cfg.DNSSyncPeriod,
+			cfg.DNSServerAddr)
 		if err != nil {
 			return fmt.Errorf("fail to create a new dnsController, %v", err)
 		}
@@ -97,13 +98,14 @@ func Run(cfg *config.CompletedConfig, stopCh <-chan struct{}) error {
 	}
 	// 1. start the IP table manager
 	if cfg.EnableIptables {
-		iptablesMgr := iptables.NewIptablesManager(cfg.Client,
+		iptablesMgr, err := iptables.NewManager(iptables.Backend(cfg.ForwardRulesBackend),
+			cfg.Client,
 			cfg.SharedInformerFactory.Core().V1().Nodes(),
 			cfg.ListenAddrForMaster,
 			cfg.ListenInsecureAddrForMaster,
 			cfg.IptablesSyncPeriod)
-		if iptablesMgr == nil {
-			return fmt.Errorf("fail to create a new IptableManager")
+		if err != nil {
+			return fmt.Errorf("fail to create a new IptableManager, %v", err)
 		}
 		wg.Add(1)
 		go iptablesMgr.Run(stopCh, &wg)
@@ -145,6 +147,16 @@ func Run(cfg *config.CompletedConfig, stopCh <-chan struct{}) error {
 	}
 
 	// 6. start the server
+	var peerCfg *server.PeerConfig
+	if cfg.EnablePeerForwarding {
+		peerCfg = &server.PeerConfig{
+			ReplicaID:     cfg.ReplicaID,
+			ListenAddr:    cfg.ListenAddrForPeer,
+			AdvertiseAddr: cfg.PeerAdvertiseAddr,
+			Token:         cfg.PeerToken,
+			Client:        cfg.Client,
+		}
+	}
 	ts := server.NewTunnelServer(
 		cfg.EgressSelectorEnabled,
 		cfg.InterceptorServerUDSFile,
@@ -154,7 +166,9 @@ func Run(cfg *config.CompletedConfig, stopCh <-chan struct{}) error {
 		cfg.ServerCount,
 		tlsCfg,
 		wrappers,
-		cfg.ProxyStrategy)
+		cfg.ProxyStrategy,
+		peerCfg,
+		stopCh)
 	if err := ts.Run(); err != nil {
 		return err
 	}
@@ -23,6 +23,7 @@ package v1alpha1
 import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // DcpAppDaemonConditionType indicates valid conditions type of a DcpAppDaemon.
@@ -37,6 +38,16 @@ const (
 	WorkLoadFailure DcpAppDaemonConditionType = "WorkLoadFailure"
 )
 
+// OverridePatchType is the format of the patch of a NodePoolOverride.
+type OverridePatchType string
+
+const (
+	// StrategicMergeOverridePatchType patches the workload with a strategic merge patch.
+	StrategicMergeOverridePatchType OverridePatchType = "StrategicMerge"
+	// JSONOverridePatchType patches the workload with a JSON patch (RFC 6902).
+	JSONOverridePatchType OverridePatchType = "JSON"
+)
+
 // DcpAppDaemonSpec defines the desired state of DcpAppDaemon.
 type DcpAppDaemonSpec struct {
 	// Selector is a label query over pods that should match the replica count.
@@ -55,6 +66,42 @@ type DcpAppDaemonSpec struct {
 	// If unspecified, defaults to 10.
 	// +optional
 	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// Overrides customize the workload of the nodepools they match. Every matching
+	// override is applied, in order, on top of the WorkloadTemplate.
+	// +optional
+	Overrides []NodePoolOverride `json:"overrides,omitempty"`
+
+	// DisruptionBudget, if set, creates a PodDisruptionBudget for the pods of every nodepool.
+	// +optional
+	DisruptionBudget *PoolDisruptionBudget `json:"disruptionBudget,omitempty"`
+
+	// TopologySpread, if set, spreads the pods of every nodepool across the topology domains
+	// of the nodes of the nodepool.
+	// +optional
+	TopologySpread *PoolTopologySpread `json:"topologySpread,omitempty"`
+}
+
+// NodePoolOverride is a patch of the workload of some nodepools.
+type NodePoolOverride struct {
+	// Name identifies the override in the annotations of the workloads and in the status.
+	Name string `json:"name"`
+
+	// NodePools lists the names of the nodepools the override applies to.
+	// +optional
+	NodePools []string `json:"nodepools,omitempty"`
+
+	// NodePoolSelector is a label query over the nodepools the override applies to.
+	// A nodepool matches the override if it is listed in NodePools or matches NodePoolSelector.
+	// +optional
+	NodePoolSelector *metav1.LabelSelector `json:"nodepoolSelector,omitempty"`
+
+	// PatchType is StrategicMerge or JSON. Defaults to StrategicMerge.
+	// +optional
+	PatchType OverridePatchType `json:"patchType,omitempty"`
+
+	// Patch is applied to the whole workload, e.g. the Deployment, built for the nodepool.
+	Patch runtime.RawExtension `json:"patch"`
 }
 
 // DcpAppDaemonStatus defines the observed state of DcpAppDaemon.
@@ -82,6 +129,20 @@ type DcpAppDaemonStatus struct {
 
 	// NodePools indicates the list of node pools selected by DcpAppDaemon
 	NodePools []string `json:"nodepools,omitempty"`
+
+	// AppliedOverrides records the overrides applied to the workload of every nodepool
+	// which matches at least one of them.
+	// +optional
+	AppliedOverrides []AppliedNodePoolOverride `json:"appliedOverrides,omitempty"`
+}
+
+// AppliedNodePoolOverride records the overrides applied to the workload of a nodepool.
+type AppliedNodePoolOverride struct {
+	// NodePool is the name of the nodepool.
+	NodePool string `json:"nodepool"`
+
+	// Overrides are the names of the overrides applied, in order.
+	Overrides []string `json:"overrides"`
 }
 
 // DcpAppDaemonCondition describes current state of a DcpAppDaemon.
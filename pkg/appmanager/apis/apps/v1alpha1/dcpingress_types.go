@@ -21,6 +21,7 @@ package v1alpha1
 // THE SOFTWARE.
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -28,8 +29,10 @@ import (
 const (
 	// DefaultIngressControllerReplicasPerPool defines the default ingress controller replicas per pool
 	DefaultIngressControllerReplicasPerPool int32 = 1
-	// NginxIngressControllerVersion defines the nginx ingress controller version
+	// NginxIngressControllerVersion defines the default nginx ingress controller version
 	NginxIngressControllerVersion = "0.48.1"
+	// NginxIngressControllerImageRepository defines the image repository of the nginx ingress controller
+	NginxIngressControllerImageRepository = "k8s.gcr.io/ingress-nginx/controller"
 	// SingletonDcpIngressInstanceName defines the singleton instance name of DcpIngress
 	SingletonDcpIngressInstanceName = "ingress-singleton"
 	// DcpIngressFinalizer is used to cleanup ingress resources when singleton DcpIngress CR is deleted
@@ -43,12 +46,70 @@ const (
 	IngressFailure IngressNotReadyType = "Failure"
 )
 
+// IngressControllerSpec defines how the ingress controller of a pool is deployed.
+type IngressControllerSpec struct {
+	// Image of the ingress controller. If not specified, the image is made of
+	// NginxIngressControllerImageRepository and Version.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Version of the nginx ingress controller, e.g. 0.48.1. It is reported in the
+	// status of the pool.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// IngressClassName is the ingress class served by the ingress controller.
+	// Defaults to the pool name.
+	// +optional
+	IngressClassName string `json:"ingressClassName,omitempty"`
+
+	// ServiceType is the type of the Service exposing the ingress controller,
+	// one of ClusterIP, NodePort and LoadBalancer. Defaults to NodePort.
+	// +optional
+	ServiceType corev1.ServiceType `json:"serviceType,omitempty"`
+
+	// ServiceAnnotations are added to the Service exposing the ingress controller,
+	// e.g. to configure a cloud load balancer.
+	// +optional
+	ServiceAnnotations map[string]string `json:"serviceAnnotations,omitempty"`
+
+	// HostNetwork runs the ingress controller in the host network of the nodes.
+	// +optional
+	HostNetwork *bool `json:"hostNetwork,omitempty"`
+
+	// Resources of the ingress controller container.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// NodeSelector is added to the node selector of the pool when placing the
+	// ingress controller.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations of the ingress controller. All taints are tolerated by default.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Config is put into the ConfigMap of the ingress controller, see
+	// https://kubernetes.github.io/ingress-nginx/user-guide/nginx-configuration/configmap/
+	// +optional
+	Config map[string]string `json:"config,omitempty"`
+}
+
 // IngressPool defines the details of a Pool for ingress
 type IngressPool struct {
 	// Indicates the pool name.
 	Name string `json:"name"`
 
-	// Pool specific configuration will be supported in future.
+	// Indicates the number of the ingress controllers to be deployed in the pool.
+	// Overrides the replicas of the DcpIngress.
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Controller overrides the ingress controller settings of the DcpIngress for the pool.
+	// Only the specified fields are overridden; maps are merged.
+	// +optional
+	Controller *IngressControllerSpec `json:"controller,omitempty"`
 }
 
 // IngressNotReadyConditionInfo defines the details info of an ingress not ready Pool
@@ -84,6 +145,32 @@ type DcpIngressSpec struct {
 	// Indicates all the nodepools on which to enable ingress.
 	// +optional
 	Pools []IngressPool `json:"pools,omitempty"`
+
+	// Controller defines the ingress controller settings of all the pools.
+	// +optional
+	Controller IngressControllerSpec `json:"controller,omitempty"`
+}
+
+// IngressPoolStatus defines the observed state of the ingress controller of a pool
+type IngressPoolStatus struct {
+	// Indicates the pool name.
+	Name string `json:"name"`
+
+	// Indicates the nginx ingress controller version deployed in the pool.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Indicates the ingress class served in the pool.
+	// +optional
+	IngressClassName string `json:"ingressClassName,omitempty"`
+
+	// Indicates the number of the ingress controllers deployed in the pool.
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// Indicates the number of the ready ingress controllers in the pool.
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
 }
 
 // DcpIngressCondition describes current state of a DcpIngress
@@ -116,6 +203,10 @@ type DcpIngressStatus struct {
 	// Total number of unready pools on which ingress is enabling or enable failed.
 	// +optional
 	UnreadyNum int32 `json:"unreadyNum"`
+
+	// Indicates the ingress controller deployed in each pool.
+	// +optional
+	Pools []IngressPoolStatus `json:"pools,omitempty"`
 }
 
 // +kubebuilder:object:root=true
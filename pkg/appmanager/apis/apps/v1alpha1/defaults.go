@@ -47,6 +47,11 @@ func SetDefaultsDcpAppDaemon(obj *DcpAppDaemon) {
 		SetDefaultPodSpec(&obj.Spec.WorkloadTemplate.DeploymentTemplate.Spec.Template.Spec)
 	}
 
+	for i := range obj.Spec.Overrides {
+		if obj.Spec.Overrides[i].PatchType == "" {
+			obj.Spec.Overrides[i].PatchType = StrategicMergeOverridePatchType
+		}
+	}
 }
 
 // SetDefaults_UnitedDeployment set default values for UnitedDeployment.
@@ -69,6 +74,22 @@ func SetDefaultsUnitedDeployment(obj *UnitedDeployment) {
 	if obj.Spec.WorkloadTemplate.DeploymentTemplate != nil {
 		SetDefaultPodSpec(&obj.Spec.WorkloadTemplate.DeploymentTemplate.Spec.Template.Spec)
 	}
+	if obj.Spec.WorkloadTemplate.DaemonSetTemplate != nil {
+		SetDefaultPodSpec(&obj.Spec.WorkloadTemplate.DaemonSetTemplate.Spec.Template.Spec)
+	}
+
+	if obj.Spec.UpdateStrategy.Type == "" {
+		obj.Spec.UpdateStrategy.Type = AllAtOnceUpdateStrategyType
+	}
+	if obj.Spec.UpdateStrategy.Staged != nil && obj.Spec.UpdateStrategy.Staged.HealthGate != nil {
+		gate := obj.Spec.UpdateStrategy.Staged.HealthGate
+		if gate.MinReadyPercent == nil {
+			gate.MinReadyPercent = utilpointer.Int32Ptr(100)
+		}
+		if gate.ProgressDeadlineSeconds == nil {
+			gate.ProgressDeadlineSeconds = utilpointer.Int32Ptr(600)
+		}
+	}
 
 }
 
@@ -154,14 +175,14 @@ func SetDefaultPodSpec(in *corev1.PodSpec) {
 		v1.SetDefaults_ResourceList(&a.Resources.Requests)
 		if a.LivenessProbe != nil {
 			v1.SetDefaults_Probe(a.LivenessProbe)
-			if a.LivenessProbe.Handler.HTTPGet != nil {
-				v1.SetDefaults_HTTPGetAction(a.LivenessProbe.Handler.HTTPGet)
+			if a.LivenessProbe.ProbeHandler.HTTPGet != nil {
+				v1.SetDefaults_HTTPGetAction(a.LivenessProbe.ProbeHandler.HTTPGet)
 			}
 		}
 		if a.ReadinessProbe != nil {
 			v1.SetDefaults_Probe(a.ReadinessProbe)
-			if a.ReadinessProbe.Handler.HTTPGet != nil {
-				v1.SetDefaults_HTTPGetAction(a.ReadinessProbe.Handler.HTTPGet)
+			if a.ReadinessProbe.ProbeHandler.HTTPGet != nil {
+				v1.SetDefaults_HTTPGetAction(a.ReadinessProbe.ProbeHandler.HTTPGet)
 			}
 		}
 		if a.Lifecycle != nil {
@@ -200,14 +221,14 @@ func SetDefaultPodSpec(in *corev1.PodSpec) {
 		v1.SetDefaults_ResourceList(&a.Resources.Requests)
 		if a.LivenessProbe != nil {
 			v1.SetDefaults_Probe(a.LivenessProbe)
-			if a.LivenessProbe.Handler.HTTPGet != nil {
-				v1.SetDefaults_HTTPGetAction(a.LivenessProbe.Handler.HTTPGet)
+			if a.LivenessProbe.ProbeHandler.HTTPGet != nil {
+				v1.SetDefaults_HTTPGetAction(a.LivenessProbe.ProbeHandler.HTTPGet)
 			}
 		}
 		if a.ReadinessProbe != nil {
 			v1.SetDefaults_Probe(a.ReadinessProbe)
-			if a.ReadinessProbe.Handler.HTTPGet != nil {
-				v1.SetDefaults_HTTPGetAction(a.ReadinessProbe.Handler.HTTPGet)
+			if a.ReadinessProbe.ProbeHandler.HTTPGet != nil {
+				v1.SetDefaults_HTTPGetAction(a.ReadinessProbe.ProbeHandler.HTTPGet)
 			}
 		}
 		if a.Lifecycle != nil {
@@ -23,6 +23,7 @@ package v1alpha1
 import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 type NodePoolType string
@@ -32,12 +33,31 @@ const (
 	Cloud NodePoolType = "Cloud"
 )
 
+// NodePoolConditionType indicates valid conditions type of a NodePool.
+type NodePoolConditionType string
+
+const (
+	// NodePoolAutonomyCondition means the autonomy annotation is set on all the nodes of the pool.
+	NodePoolAutonomyCondition NodePoolConditionType = "Autonomy"
+	// NodePoolMaintenanceCondition reports the progress of the cordon and drain of the nodes of the pool.
+	NodePoolMaintenanceCondition NodePoolConditionType = "Maintenance"
+	// NodePoolPartitionedCondition means most nodes of the pool became unreachable together, and the
+	// node lifecycle controller stops evicting the pods from the nodes of the pool.
+	NodePoolPartitionedCondition NodePoolConditionType = "PoolPartitioned"
+)
+
 // NodePoolSpec defines the desired state of NodePool
 type NodePoolSpec struct {
 	// The type of the NodePool
 	// +optional
 	Type NodePoolType `json:"type,omitempty"`
 
+	// Parent is the name of the NodePool this pool belongs to, e.g. the site of a rack.
+	// Nodes of the pool get the Labels, Annotations and Taints of all of its ancestors,
+	// merged from the root down so that those of a pool override those of its ancestors.
+	// +optional
+	Parent string `json:"parent,omitempty"`
+
 	// A label query over nodes to consider for adding to the pool
 	// +optional
 	Selector *metav1.LabelSelector `json:"selector,omitempty"`
@@ -55,6 +75,38 @@ type NodePoolSpec struct {
 	// If specified, the Taints will be added to all nodes.
 	// +optional
 	Taints []v1.Taint `json:"taints,omitempty"`
+
+	// Autonomy puts all nodes of the pool into autonomy, so that their pods are
+	// not evicted while the nodes are disconnected from the cloud.
+	// +optional
+	Autonomy bool `json:"autonomy,omitempty"`
+
+	// Maintenance, if specified, cordons and drains the nodes of the pool and of its
+	// descendant pools, which follow the maintenance of their nearest ancestor having one.
+	// Removing it uncordons the nodes again.
+	// +optional
+	Maintenance *NodePoolMaintenance `json:"maintenance,omitempty"`
+}
+
+// NodePoolMaintenance defines how the nodes of a pool are drained.
+type NodePoolMaintenance struct {
+	// MaxUnavailable is the number or percentage of nodes of the pool being drained
+	// at the same time. Nodes are drained unready first, then by name. Defaults to 1.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+
+	// GracePeriodSeconds overrides the termination grace period of the evicted pods.
+	// +optional
+	GracePeriodSeconds *int64 `json:"gracePeriodSeconds,omitempty"`
+
+	// DeleteEmptyDirData allows evicting pods using emptyDir volumes.
+	// +optional
+	DeleteEmptyDirData bool `json:"deleteEmptyDirData,omitempty"`
+
+	// Force also deletes the pods not managed by a controller, which are lost. Otherwise
+	// such pods block the drain of their node and are reported in a MaintenanceFailed event.
+	// +optional
+	Force bool `json:"force,omitempty"`
 }
 
 // NodePoolStatus defines the observed state of NodePool
@@ -70,6 +122,51 @@ type NodePoolStatus struct {
 	// The list of nodes' names in the pool
 	// +optional
 	Nodes []string `json:"nodes,omitempty"`
+
+	// Total number of ready nodes in the descendant pools.
+	// +optional
+	DescendantReadyNodeNum int32 `json:"descendantReadyNodeNum,omitempty"`
+
+	// Total number of unready nodes in the descendant pools.
+	// +optional
+	DescendantUnreadyNodeNum int32 `json:"descendantUnreadyNodeNum,omitempty"`
+
+	// Represents the latest available observations of the autonomy and maintenance of the pool.
+	// +optional
+	Conditions []NodePoolCondition `json:"conditions,omitempty"`
+
+	// Allocatable is the sum of the allocatable resources of the nodes in the pool,
+	// including extended resources.
+	// +optional
+	Allocatable v1.ResourceList `json:"allocatable,omitempty"`
+
+	// Requested is the sum of the resource requests of the pods scheduled to the
+	// nodes in the pool which are not terminated.
+	// +optional
+	Requested v1.ResourceList `json:"requested,omitempty"`
+
+	// NodeConditions is the number of nodes in the pool with each node condition
+	// other than Ready set to True, e.g. DiskPressure.
+	// +optional
+	NodeConditions map[v1.NodeConditionType]int32 `json:"nodeConditions,omitempty"`
+}
+
+// NodePoolCondition describes current state of a NodePool.
+type NodePoolCondition struct {
+	// Type of NodePool condition.
+	Type NodePoolConditionType `json:"type,omitempty"`
+
+	// Status of the condition, one of True, False, Unknown.
+	Status v1.ConditionStatus `json:"status,omitempty"`
+
+	// Last time the condition transitioned from one status to another.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// The reason for the condition's last transition.
+	Reason string `json:"reason,omitempty"`
+
+	// A human readable message indicating details about the transition.
+	Message string `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -77,6 +174,11 @@ type NodePoolStatus struct {
 // +kubebuilder:printcolumn:name="Type",type="string",JSONPath=".spec.type",description="The type of nodepool"
 // +kubebuilder:printcolumn:name="ReadyNodes",type="integer",JSONPath=".status.readyNodeNum",description="The number of ready nodes in the pool"
 // +kubebuilder:printcolumn:name="NotReadyNodes",type="integer",JSONPath=".status.unreadyNodeNum"
+// +kubebuilder:printcolumn:name="CPU",type="string",JSONPath=".status.allocatable.cpu",description="The allocatable CPU of the pool"
+// +kubebuilder:printcolumn:name="Memory",type="string",JSONPath=".status.allocatable.memory",description="The allocatable memory of the pool"
+// +kubebuilder:printcolumn:name="RequestedCPU",type="string",JSONPath=".status.requested.cpu",description="The CPU requested by the pods in the pool",priority=1
+// +kubebuilder:printcolumn:name="RequestedMemory",type="string",JSONPath=".status.requested.memory",description="The memory requested by the pods in the pool",priority=1
+// +kubebuilder:printcolumn:name="Parent",type="string",JSONPath=".spec.parent",description="The parent nodepool",priority=1
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 // +kubebuilder:subresource:status
 // +genclient:nonNamespaced
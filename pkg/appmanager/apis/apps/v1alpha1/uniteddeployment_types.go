@@ -25,6 +25,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 type TemplateType string
@@ -32,6 +33,7 @@ type TemplateType string
 const (
 	StatefulSetTemplateType TemplateType = "StatefulSet"
 	DeploymentTemplateType  TemplateType = "Deployment"
+	DaemonSetTemplateType   TemplateType = "DaemonSet"
 )
 
 // UnitedDeploymentConditionType indicates valid conditions type of a UnitedDeployment.
@@ -44,10 +46,36 @@ const (
 	PoolUpdated UnitedDeploymentConditionType = "PoolUpdated"
 	// PoolFailure is added to a UnitedDeployment when one of its pools has failure during its own reconciling.
 	PoolFailure UnitedDeploymentConditionType = "PoolFailure"
+	// RolloutPaused means the rollout of the updated revision is held before its next stage.
+	RolloutPaused UnitedDeploymentConditionType = "RolloutPaused"
+	// RolloutRolledBack means a health gate failed and the failing pools were rolled back to the current revision.
+	RolloutRolledBack UnitedDeploymentConditionType = "RolloutRolledBack"
+	// PoolsRebalanced means the replicas of pools without ready nodes were moved to other pools.
+	PoolsRebalanced UnitedDeploymentConditionType = "PoolsRebalanced"
+	// ReplicasInsufficient means the replicas of the UnitedDeployment are fewer than the fixed
+	// replicas and the MinReplicas of its pools, so some pools get less than their MinReplicas.
+	ReplicasInsufficient UnitedDeploymentConditionType = "ReplicasInsufficient"
+)
+
+// UnitedDeploymentUpdateStrategyType defines how a new revision is rolled out to the pools.
+type UnitedDeploymentUpdateStrategyType string
+
+const (
+	// AllAtOnceUpdateStrategyType pushes a new revision to every pool at the same time.
+	AllAtOnceUpdateStrategyType UnitedDeploymentUpdateStrategyType = "AllAtOnce"
+	// StagedUpdateStrategyType pushes a new revision stage by stage, gating every stage on the health of its pools.
+	StagedUpdateStrategyType UnitedDeploymentUpdateStrategyType = "Staged"
 )
 
 // UnitedDeploymentSpec defines the desired state of UnitedDeployment.
 type UnitedDeploymentSpec struct {
+	// Replicas is the total number of desired replicas of all the pools.
+	// If specified, the replicas of the pools which do not set Replicas are computed
+	// from their Weight, MinReplicas and MaxReplicas, moving replicas away from the pools
+	// whose NodePool has no ready nodes. If unspecified, every pool uses its own Replicas.
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
 	// Selector is a label query over pods that should match the replica count.
 	// It must match the pod template's labels.
 	Selector *metav1.LabelSelector `json:"selector"`
@@ -64,11 +92,121 @@ type UnitedDeploymentSpec struct {
 	// If unspecified, defaults to 10.
 	// +optional
 	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// UpdateStrategy indicates how a new revision is rolled out to the pools.
+	// +optional
+	UpdateStrategy UnitedDeploymentUpdateStrategy `json:"updateStrategy,omitempty"`
+
+	// DisruptionBudget, if set, creates a PodDisruptionBudget for the pods of every pool.
+	// +optional
+	DisruptionBudget *PoolDisruptionBudget `json:"disruptionBudget,omitempty"`
+
+	// TopologySpread, if set, spreads the pods of every pool across the topology domains
+	// of the nodes of the pool. It does not apply to the pools of a DaemonSetTemplate.
+	// +optional
+	TopologySpread *PoolTopologySpread `json:"topologySpread,omitempty"`
+}
+
+// PoolDisruptionBudget describes the PodDisruptionBudget created for each pool.
+// Only one of MinAvailable and MaxUnavailable can be set.
+type PoolDisruptionBudget struct {
+	// MinAvailable is the number or percentage of the pods of a pool that must stay available.
+	// An absolute number is capped to the replicas of the pool.
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+
+	// MaxUnavailable is the number or percentage of the pods of a pool that can be unavailable.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// PoolTopologySpread describes the topology spread constraint added to the pods of each pool.
+// The constraint only counts the pods of the same pool, so that the pools are spread
+// independently of each other. It replaces a constraint of the template on the same key.
+type PoolTopologySpread struct {
+	// TopologyKey is the node label whose values are the domains the pods are spread across,
+	// e.g. kubernetes.io/hostname.
+	TopologyKey string `json:"topologyKey"`
+
+	// MaxSkew is the maximum difference of the number of pods of a pool between two domains.
+	// Defaults to 1.
+	// +optional
+	MaxSkew int32 `json:"maxSkew,omitempty"`
+
+	// WhenUnsatisfiable is what the scheduler does with a pod which does not satisfy the spread,
+	// either DoNotSchedule or ScheduleAnyway. Defaults to ScheduleAnyway.
+	// +optional
+	WhenUnsatisfiable corev1.UnsatisfiableConstraintAction `json:"whenUnsatisfiable,omitempty"`
+}
+
+// UnitedDeploymentUpdateStrategy defines the rollout of a new revision across pools.
+type UnitedDeploymentUpdateStrategy struct {
+	// Type of the update strategy, AllAtOnce or Staged. Defaults to AllAtOnce.
+	// +optional
+	Type UnitedDeploymentUpdateStrategyType `json:"type,omitempty"`
+
+	// Paused holds the rollout before its next stage. Pools of a stage that is
+	// already in progress keep converging and are still checked by the health gate.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// Staged describes the stages of a Staged update.
+	// +optional
+	Staged *StagedUpdateStrategy `json:"staged,omitempty"`
+}
+
+// StagedUpdateStrategy defines the stages a new revision goes through.
+type StagedUpdateStrategy struct {
+	// Stages are rolled out in order, the next one starting once every pool of the
+	// previous one passed the health gate. Pools which are not listed, or whose last
+	// stage keeps a partition, are fully updated in a final stage.
+	// +optional
+	Stages []UpdateStage `json:"stages,omitempty"`
+
+	// HealthGate decides whether the pools of a stage are healthy enough to go on.
+	// If unspecified, a stage goes on once all of its replicas are ready and never rolls back.
+	// +optional
+	HealthGate *UpdateHealthGate `json:"healthGate,omitempty"`
+}
+
+// UpdateStage is a batch of pools updated together.
+type UpdateStage struct {
+	// Pools updated in this stage.
+	Pools []UpdateStagePool `json:"pools"`
+}
+
+// UpdateStagePool defines how far a pool is updated in a stage.
+type UpdateStagePool struct {
+	// Name of the pool in Topology.
+	Name string `json:"name"`
+
+	// Partition is the number of replicas of the pool kept at the current revision in this
+	// stage, which makes the stage a canary for the pool. Only StatefulSet pools support it.
+	// +optional
+	Partition *int32 `json:"partition,omitempty"`
+}
+
+// UpdateHealthGate defines when the pools of a stage are considered healthy.
+type UpdateHealthGate struct {
+	// MinReadyPercent is the percentage of the pool replicas which must be ready.
+	// Defaults to 100.
+	// +optional
+	MinReadyPercent *int32 `json:"minReadyPercent,omitempty"`
+
+	// BakeSeconds is how long the pool must keep MinReadyPercent before the stage passes.
+	// Defaults to 0.
+	// +optional
+	BakeSeconds int32 `json:"bakeSeconds,omitempty"`
+
+	// ProgressDeadlineSeconds is how long a pool may take to pass the gate before
+	// it is rolled back to the current revision. Defaults to 600.
+	// +optional
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
 }
 
 // WorkloadTemplate defines the pool template under the UnitedDeployment.
 // UnitedDeployment will provision every pool based on one workload templates in WorkloadTemplate.
-// WorkloadTemplate now support statefulset and deployment
+// WorkloadTemplate now support statefulset, deployment and daemonset
 // Only one of its members may be specified.
 type WorkloadTemplate struct {
 	// StatefulSet template
@@ -78,6 +216,11 @@ type WorkloadTemplate struct {
 	// Deployment template
 	// +optional
 	DeploymentTemplate *DeploymentTemplateSpec `json:"deploymentTemplate,omitempty"`
+
+	// DaemonSet template, which runs one pod on every node of a pool.
+	// The Replicas of the pools are ignored.
+	// +optional
+	DaemonSetTemplate *DaemonSetTemplateSpec `json:"daemonSetTemplate,omitempty"`
 }
 
 // StatefulSetTemplateSpec defines the pool template of StatefulSet.
@@ -92,6 +235,12 @@ type DeploymentTemplateSpec struct {
 	Spec              appsv1.DeploymentSpec `json:"spec"`
 }
 
+// DaemonSetTemplateSpec defines the pool template of DaemonSet.
+type DaemonSetTemplateSpec struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              appsv1.DaemonSetSpec `json:"spec"`
+}
+
 // Topology defines the spread detail of each pool under UnitedDeployment.
 // A UnitedDeployment manages multiple homogeneous workloads which are called pool.
 // Each of pools under the UnitedDeployment is described in Topology.
@@ -122,9 +271,24 @@ type Pool struct {
 	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
 
 	// Indicates the number of the pod to be created under this pool.
-	// +required
+	// Required unless the UnitedDeployment sets Replicas, in which case a pool setting it
+	// keeps this fixed number and the rest of the replicas go to the other pools.
+	// +optional
 	Replicas *int32 `json:"replicas,omitempty"`
 
+	// Weight is the share of the UnitedDeployment Replicas this pool gets relative
+	// to the other weighted pools. Defaults to 1, 0 gives the pool only its MinReplicas.
+	// +optional
+	Weight *int32 `json:"weight,omitempty"`
+
+	// MinReplicas is the lower bound of the replicas computed for this pool.
+	// +optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+
+	// MaxReplicas is the upper bound of the replicas computed for this pool.
+	// +optional
+	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
+
 	// Indicates the patch for the templateSpec
 	// Now support strategic merge path :https://kubernetes.io/docs/tasks/manage-kubernetes-objects/update-api-object-kubectl-patch/#notes-on-the-strategic-merge-patch
 	// Patch takes precedence over Replicas fields
@@ -164,8 +328,59 @@ type UnitedDeploymentStatus struct {
 	// Replicas is the most recently observed number of replicas.
 	Replicas int32 `json:"replicas"`
 
+	// Selector is the label selector of the pods in string form, used by the scale subresource.
+	// +optional
+	Selector string `json:"selector,omitempty"`
+
 	// TemplateType indicates the type of PoolTemplate
 	TemplateType TemplateType `json:"templateType"`
+
+	// UpdateStatus records the progress of the rollout of the updated revision.
+	// +optional
+	UpdateStatus *UnitedDeploymentUpdateStatus `json:"updateStatus,omitempty"`
+}
+
+// UnitedDeploymentUpdateStatus describes the rollout of a revision across pools.
+type UnitedDeploymentUpdateStatus struct {
+	// UpdatedRevision is the revision being rolled out.
+	UpdatedRevision string `json:"updatedRevision"`
+
+	// CurrentStage is the index of the stage in progress.
+	CurrentStage int32 `json:"currentStage"`
+
+	// RolledBack is set once a health gate failed. The rollout of UpdatedRevision
+	// does not go on until the template changes.
+	// +optional
+	RolledBack bool `json:"rolledBack,omitempty"`
+
+	// Pools records the rollout progress of each pool.
+	// +optional
+	Pools []PoolUpdateStatus `json:"pools,omitempty"`
+}
+
+// PoolUpdateStatus describes the rollout progress of a pool.
+type PoolUpdateStatus struct {
+	// Name of the pool.
+	Name string `json:"name"`
+
+	// Revision the pool is updated to.
+	Revision string `json:"revision"`
+
+	// Partition is the number of replicas of the pool kept at the previous revision.
+	// +optional
+	Partition int32 `json:"partition,omitempty"`
+
+	// LastUpdateTime is the last time the pool was moved to Revision and Partition.
+	// +optional
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+
+	// HealthySince is the time since when the pool has been passing the health gate.
+	// +optional
+	HealthySince *metav1.Time `json:"healthySince,omitempty"`
+
+	// Conditions of the pool rollout.
+	// +optional
+	Conditions []UnitedDeploymentCondition `json:"conditions,omitempty"`
 }
 
 // UnitedDeploymentCondition describes current state of a UnitedDeployment.
@@ -189,6 +404,7 @@ type UnitedDeploymentCondition struct {
 // +genclient
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
 // +kubebuilder:resource:shortName=ud
 // +kubebuilder:printcolumn:name="READY",type="integer",JSONPath=".status.readyReplicas",description="The number of pods ready."
 // +kubebuilder:printcolumn:name="WorkloadTemplate",type="string",JSONPath=".status.templateType",description="The WorkloadTemplate Type."
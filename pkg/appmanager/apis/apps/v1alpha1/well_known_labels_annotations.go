@@ -35,6 +35,9 @@ const (
 	AnnotationPatchKey = "apps.bhojpur.net/patch"
 
 	AnnotationRefNodePool = "apps.bhojpur.net/ref-nodepool"
+
+	// AnnotationNodePoolOverrides records the names of the DcpAppDaemon overrides applied to a workload
+	AnnotationNodePoolOverrides = "apps.bhojpur.net/nodepool-overrides"
 )
 
 // NodePool related labels and annotations
@@ -52,6 +55,21 @@ const (
 
 	AnnotationPrevAttrs = "nodepool.bhojpur.net/previous-attributes"
 
+	// AnnotationPoolAutonomy records that the autonomy annotation of a node was set by its
+	// nodepool, so that the nodepool only removes the autonomy it set
+	AnnotationPoolAutonomy = "nodepool.bhojpur.net/autonomy"
+
+	// AnnotationMaintenance records the progress of the maintenance of a node by its nodepool,
+	// either MaintenanceDraining or MaintenanceDrained
+	AnnotationMaintenance = "nodepool.bhojpur.net/maintenance"
+
+	MaintenanceDraining = "draining"
+	MaintenanceDrained  = "drained"
+
+	// AnnotationMaintenanceUnschedulable records that a node was already cordoned before the
+	// maintenance of its nodepool, so that it stays cordoned once the maintenance is over
+	AnnotationMaintenanceUnschedulable = "nodepool.bhojpur.net/maintenance-unschedulable"
+
 	// DefaultCloudNodePoolName defines the name of the default cloud nodepool
 	DefaultCloudNodePoolName = "default-nodepool"
 
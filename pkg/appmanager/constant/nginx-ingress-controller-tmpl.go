@@ -156,6 +156,19 @@ metadata:
   name: ingress-nginx-controller
   namespace: ingress-nginx
 data:
+`
+	NginxIngressControllerNodePoolConfigMap = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  labels:
+    app.kubernetes.io/name: ingress-nginx
+    app.kubernetes.io/instance: ingress-nginx
+    app.kubernetes.io/component: controller
+    dcpingress.io/nodepool: {{.nodepool_name}}
+  name: {{.nodepool_name}}-ingress-nginx-controller
+  namespace: ingress-nginx
+data:
 `
 	NginxIngressControllerClusterRoleBinding = `
 # Source: ingress-nginx/templates/clusterrolebinding.yaml
@@ -389,8 +402,8 @@ spec:
           args:
             - /nginx-ingress-controller
             - --election-id=ingress-controller-leader-edge
-            - --ingress-class={{.nodepool_name}}
-            - --configmap=$(POD_NAMESPACE)/ingress-nginx-controller
+            - --ingress-class={{.ingress_class}}
+            - --configmap=$(POD_NAMESPACE)/{{.nodepool_name}}-ingress-nginx-controller
           securityContext:
             capabilities:
               drop:
@@ -496,7 +509,7 @@ spec:
           args:
             - /nginx-ingress-controller
             - --election-id=ingress-controller-leader-webhook
-            - --ingress-class={{.nodepool_name}}
+            - --ingress-class={{.ingress_class}}
             - --update-status=false
             - --configmap=$(POD_NAMESPACE)/ingress-nginx-controller
             - --validating-webhook=:8443
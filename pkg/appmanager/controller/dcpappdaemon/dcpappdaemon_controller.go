@@ -28,6 +28,7 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -58,9 +59,10 @@ const (
 	eventTypeRevisionProvision  = "RevisionProvision"
 	eventTypeTemplateController = "TemplateController"
 
-	eventTypeWorkloadsCreated = "CreateWorkload"
-	eventTypeWorkloadsUpdated = "UpdateWorkload"
-	eventTypeWorkloadsDeleted = "DeleteWorkload"
+	eventTypeWorkloadsCreated         = "CreateWorkload"
+	eventTypeWorkloadsUpdated         = "UpdateWorkload"
+	eventTypeWorkloadsDeleted         = "DeleteWorkload"
+	eventTypeDisruptionBudgetsUpdated = "UpdateDisruptionBudget"
 )
 
 func init() {
@@ -91,6 +93,15 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// Watch for changes to PodDisruptionBudget
+	err = c.Watch(&source.Kind{Type: &policyv1.PodDisruptionBudget{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &unitv1alpha1.DcpAppDaemon{},
+	})
+	if err != nil {
+		return err
+	}
+
 	// Watch for changes to NodePool
 	err = c.Watch(&source.Kind{Type: &unitv1alpha1.NodePool{}}, &EnqueueAppDaemonForNodePool{client: mgr.GetClient()})
 	if err != nil {
@@ -126,6 +137,7 @@ func newReconciler(mgr manager.Manager) reconcile.Reconciler {
 
 // +kubebuilder:rbac:groups=apps.bhojpur.net,resources=dcpappdaemons,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps.bhojpur.net,resources=dcpappdaemons/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile reads that state of the cluster for a DcpAppDaemon object and makes changes based on the state read
 // and what is in the DcpAppDaemon.Spec
@@ -185,6 +197,11 @@ func (r *ReconcileAppDaemon) Reconcile(_ context.Context, request reconcile.Requ
 		return reconcile.Result{}, nil
 	}
 
+	if err := r.manageDisruptionBudgets(instance, currentNPToWorkload, allNameToNodePools); err != nil {
+		klog.Errorf("DcpAppDaemon[%s/%s] Fail to manage PodDisruptionBudgets, error: %s", instance.Namespace, instance.Name, err)
+		r.recorder.Event(instance.DeepCopy(), corev1.EventTypeWarning, fmt.Sprintf("Failed%s", eventTypeDisruptionBudgetsUpdated), err.Error())
+	}
+
 	return r.updateStatus(instance, newStatus, oldStatus, currentRevision, collisionCount, templateType)
 }
 
@@ -203,6 +220,7 @@ func (r *ReconcileAppDaemon) updateAppDaemon(yad *unitv1alpha1.DcpAppDaemon, old
 		oldStatus.TemplateType == newStatus.TemplateType &&
 		yad.Generation == newStatus.ObservedGeneration &&
 		reflect.DeepEqual(oldStatus.NodePools, newStatus.NodePools) &&
+		reflect.DeepEqual(oldStatus.AppliedOverrides, newStatus.AppliedOverrides) &&
 		reflect.DeepEqual(oldStatus.Conditions, newStatus.Conditions) {
 		klog.Infof("DcpAppDaemon[%s/%s] oldStatus==newStatus, no need to update status", yad.GetNamespace(), yad.GetName())
 		return yad, nil
@@ -261,6 +279,7 @@ func (r *ReconcileAppDaemon) manageWorkloads(instance *unitv1alpha1.DcpAppDaemon
 		nps = append(nps, np)
 	}
 	newStatus.NodePools = nps
+	newStatus.AppliedOverrides = getAppliedOverrides(instance, allNameToNodePools)
 
 	needDeleted, needUpdate, needCreate := r.classifyWorkloads(instance, currentNodepoolToWorkload, allNameToNodePools, expectedRevision)
 	provision, err := r.manageWorkloadsProvision(instance, allNameToNodePools, expectedRevision, templateType, needDeleted, needCreate)
@@ -368,6 +387,11 @@ func (r *ReconcileAppDaemon) classifyWorkloads(instance *unitv1alpha1.DcpAppDaem
 				match = false
 			}
 
+			// judge the overrides matching the nodepool, whose labels may have changed
+			if load.GetOverrides() != workloadcontroller.OverrideNames(workloadcontroller.MatchedOverrides(instance, np)) {
+				match = false
+			}
+
 			if !match {
 				klog.V(4).Infof("DcpAppDaemon[%s/%s] need update [%s/%s/%s]", instance.GetNamespace(),
 					instance.GetName(), load.GetKind(), load.Namespace, load.Name)
@@ -0,0 +1,59 @@
+package dcpappdaemon
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	unitv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+	"github.com/bhojpur/dcp/pkg/appmanager/controller/dcpappdaemon/workloadcontroller"
+	kubeutil "github.com/bhojpur/dcp/pkg/appmanager/util/kubernetes"
+)
+
+// manageDisruptionBudgets creates or updates a PodDisruptionBudget for the workload of every
+// nodepool of the DcpAppDaemon, and deletes the budgets of the nodepools which are gone.
+func (r *ReconcileAppDaemon) manageDisruptionBudgets(instance *unitv1alpha1.DcpAppDaemon,
+	nodepoolToWorkload map[string]*workloadcontroller.Workload, allNameToNodePools map[string]unitv1alpha1.NodePool) error {
+	keep := sets.NewString()
+	if instance.Spec.DisruptionBudget != nil {
+		for np, workload := range nodepoolToWorkload {
+			if _, ok := allNameToNodePools[np]; !ok {
+				continue
+			}
+			var replicas int32 = 1
+			if deploy, ok := workload.Spec.Ref.(*appsv1.Deployment); ok && deploy.Spec.Replicas != nil {
+				replicas = *deploy.Spec.Replicas
+			}
+			selector := instance.Spec.Selector.DeepCopy()
+			if selector.MatchLabels == nil {
+				selector.MatchLabels = map[string]string{}
+			}
+			selector.MatchLabels[unitv1alpha1.PoolNameLabelKey] = np
+			pdb := kubeutil.NewPoolDisruptionBudget(workload.Spec.Ref, selector, instance.Spec.DisruptionBudget, replicas)
+			if err := kubeutil.ApplyPoolDisruptionBudget(r.Client, r.scheme, instance, pdb); err != nil {
+				return err
+			}
+			keep.Insert(pdb.Name)
+		}
+	}
+	return kubeutil.CleanupPoolDisruptionBudgets(r.Client, instance, instance.Spec.Selector, keep)
+}
@@ -280,6 +280,10 @@ func getAppDaemonPatch(ud *appsalphav1.DcpAppDaemon) ([]byte, error) {
 	template := spec["workloadTemplate"].(map[string]interface{})
 	specCopy["workloadTemplate"] = template
 	template["$patch"] = "replace"
+	// overrides are part of the workloads, so a change of them is a new revision
+	if overrides, ok := spec["overrides"]; ok {
+		specCopy["overrides"] = overrides
+	}
 	objCopy["spec"] = specCopy
 	patch, err := json.Marshal(objCopy)
 	return patch, err
@@ -21,7 +21,10 @@ package dcpappdaemon
 // THE SOFTWARE.
 
 import (
+	"sort"
+
 	unitv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+	"github.com/bhojpur/dcp/pkg/appmanager/controller/dcpappdaemon/workloadcontroller"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/kubernetes/pkg/apis/core/v1/helper"
@@ -85,3 +88,23 @@ func filterOutCondition(conditions []unitv1alpha1.DcpAppDaemonCondition, condTyp
 	}
 	return newConditions
 }
+
+// getAppliedOverrides returns the overrides applied to the workload of every nodepool, sorted by nodepool name.
+func getAppliedOverrides(instance *unitv1alpha1.DcpAppDaemon, allNameToNodePools map[string]unitv1alpha1.NodePool) []unitv1alpha1.AppliedNodePoolOverride {
+	var applied []unitv1alpha1.AppliedNodePoolOverride
+	for name, np := range allNameToNodePools {
+		overrides := workloadcontroller.MatchedOverrides(instance, np)
+		if len(overrides) == 0 {
+			continue
+		}
+		names := make([]string, 0, len(overrides))
+		for _, o := range overrides {
+			names = append(names, o.Name)
+		}
+		applied = append(applied, unitv1alpha1.AppliedNodePoolOverride{NodePool: name, Overrides: names})
+	}
+	sort.Slice(applied, func(i, j int) bool {
+		return applied[i].NodePool < applied[j].NodePool
+	})
+	return applied
+}
@@ -33,6 +33,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	"github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+	kubeutil "github.com/bhojpur/dcp/pkg/appmanager/util/kubernetes"
 	"github.com/bhojpur/dcp/pkg/appmanager/util/refmanager"
 )
 
@@ -68,11 +69,6 @@ func (a *DeploymentControllor) applyTemplate(scheme *runtime.Scheme, yad *v1alph
 	for k, v := range yad.Spec.WorkloadTemplate.DeploymentTemplate.Labels {
 		set.Labels[k] = v
 	}
-	for k, v := range yad.Spec.Selector.MatchLabels {
-		set.Labels[k] = v
-	}
-	set.Labels[v1alpha1.ControllerRevisionHashLabelKey] = revision
-	set.Labels[v1alpha1.PoolNameLabelKey] = nodepool.GetName()
 
 	if set.Annotations == nil {
 		set.Annotations = map[string]string{}
@@ -80,37 +76,82 @@ func (a *DeploymentControllor) applyTemplate(scheme *runtime.Scheme, yad *v1alph
 	for k, v := range yad.Spec.WorkloadTemplate.DeploymentTemplate.Annotations {
 		set.Annotations[k] = v
 	}
-	set.Annotations[v1alpha1.AnnotationRefNodePool] = nodepool.GetName()
 
 	set.Namespace = yad.GetNamespace()
 	set.GenerateName = getWorkloadPrefix(yad.GetName(), nodepool.GetName())
 
 	set.Spec = *yad.Spec.WorkloadTemplate.DeploymentTemplate.Spec.DeepCopy()
-	set.Spec.Selector.MatchLabels[v1alpha1.PoolNameLabelKey] = nodepool.GetName()
 
 	// set RequiredDuringSchedulingIgnoredDuringExecution nil
 	if set.Spec.Template.Spec.Affinity != nil && set.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
 		set.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = nil
 	}
 
-	if set.Spec.Template.Labels == nil {
-		set.Spec.Template.Labels = map[string]string{}
-	}
-	set.Spec.Template.Labels[v1alpha1.PoolNameLabelKey] = nodepool.GetName()
-	set.Spec.Template.Labels[v1alpha1.ControllerRevisionHashLabelKey] = revision
-
 	// use nodeSelector
 	set.Spec.Template.Spec.NodeSelector = CreateNodeSelectorByNodepoolName(nodepool.GetName())
 
 	// toleration
 	set.Spec.Template.Spec.Tolerations = TaintsToTolerations(nodepool.Spec.Taints)
 
+	setPoolOwnedFields(yad, nodepool, revision, set)
+	kubeutil.SetPoolTopologySpread(&set.Spec.Template.Spec, yad.Spec.TopologySpread, set.Spec.Selector)
+
+	// overrides of the nodepool
+	patched := &appsv1.Deployment{}
+	if err := applyOverrides(MatchedOverrides(yad, nodepool), set, patched); err != nil {
+		return err
+	}
+	patched.DeepCopyInto(set)
+	setPoolOwnedFields(yad, nodepool, revision, set)
+
 	if err := controllerutil.SetControllerReference(yad, set, scheme); err != nil {
 		return err
 	}
 	return nil
 }
 
+// setPoolOwnedFields sets the labels, annotation, selector and nodeSelector which tie the Deployment and its pods
+// to the nodepool. It runs again after the overrides so that a patch can not take the workload out of the pool.
+func setPoolOwnedFields(yad *v1alpha1.DcpAppDaemon, nodepool v1alpha1.NodePool, revision string, set *appsv1.Deployment) {
+	if set.Labels == nil {
+		set.Labels = map[string]string{}
+	}
+	for k, v := range yad.Spec.Selector.MatchLabels {
+		set.Labels[k] = v
+	}
+	set.Labels[v1alpha1.ControllerRevisionHashLabelKey] = revision
+	set.Labels[v1alpha1.PoolNameLabelKey] = nodepool.GetName()
+
+	if set.Annotations == nil {
+		set.Annotations = map[string]string{}
+	}
+	set.Annotations[v1alpha1.AnnotationRefNodePool] = nodepool.GetName()
+
+	set.Spec.Selector = yad.Spec.WorkloadTemplate.DeploymentTemplate.Spec.Selector.DeepCopy()
+	if set.Spec.Selector == nil {
+		set.Spec.Selector = &metav1.LabelSelector{}
+	}
+	if set.Spec.Selector.MatchLabels == nil {
+		set.Spec.Selector.MatchLabels = map[string]string{}
+	}
+	set.Spec.Selector.MatchLabels[v1alpha1.PoolNameLabelKey] = nodepool.GetName()
+
+	if set.Spec.Template.Labels == nil {
+		set.Spec.Template.Labels = map[string]string{}
+	}
+	for k, v := range set.Spec.Selector.MatchLabels {
+		set.Spec.Template.Labels[k] = v
+	}
+	set.Spec.Template.Labels[v1alpha1.ControllerRevisionHashLabelKey] = revision
+
+	if set.Spec.Template.Spec.NodeSelector == nil {
+		set.Spec.Template.Spec.NodeSelector = map[string]string{}
+	}
+	for k, v := range CreateNodeSelectorByNodepoolName(nodepool.GetName()) {
+		set.Spec.Template.Spec.NodeSelector[k] = v
+	}
+}
+
 func (d *DeploymentControllor) ObjectKey(load *Workload) client.ObjectKey {
 	return types.NamespacedName{
 		Namespace: load.Namespace,
@@ -0,0 +1,112 @@
+package workloadcontroller
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+
+	"github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+)
+
+// MatchedOverrides returns the overrides of the DcpAppDaemon which apply to the nodepool, in order.
+func MatchedOverrides(yad *v1alpha1.DcpAppDaemon, nodepool v1alpha1.NodePool) []v1alpha1.NodePoolOverride {
+	var matched []v1alpha1.NodePoolOverride
+	for _, o := range yad.Spec.Overrides {
+		if overrideMatchNodePool(o, nodepool) {
+			matched = append(matched, o)
+		}
+	}
+	return matched
+}
+
+func overrideMatchNodePool(o v1alpha1.NodePoolOverride, nodepool v1alpha1.NodePool) bool {
+	for _, name := range o.NodePools {
+		if name == nodepool.GetName() {
+			return true
+		}
+	}
+	if o.NodePoolSelector == nil {
+		return false
+	}
+	selector, err := metav1.LabelSelectorAsSelector(o.NodePoolSelector)
+	if err != nil || selector.Empty() {
+		return false
+	}
+	return selector.Matches(labels.Set(nodepool.GetLabels()))
+}
+
+// OverrideNames returns the names of the overrides as recorded in the workload annotations.
+func OverrideNames(overrides []v1alpha1.NodePoolOverride) string {
+	names := make([]string, 0, len(overrides))
+	for _, o := range overrides {
+		names = append(names, o.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+// applyOverrides patches set with the overrides in order into patched, which must be an empty
+// object of the same type, and records the names of the overrides in the annotations of patched.
+func applyOverrides(overrides []v1alpha1.NodePoolOverride, set, patched metav1.Object) error {
+	data, err := json.Marshal(set)
+	if err != nil {
+		return err
+	}
+	for _, o := range overrides {
+		switch o.PatchType {
+		case v1alpha1.JSONOverridePatchType:
+			patch, err := jsonpatch.DecodePatch(o.Patch.Raw)
+			if err != nil {
+				return fmt.Errorf("fail to decode json patch of override %s: %v", o.Name, err)
+			}
+			if data, err = patch.Apply(data); err != nil {
+				return fmt.Errorf("fail to apply json patch of override %s: %v", o.Name, err)
+			}
+		case v1alpha1.StrategicMergeOverridePatchType, "":
+			if data, err = strategicpatch.StrategicMergePatch(data, o.Patch.Raw, patched); err != nil {
+				return fmt.Errorf("fail to apply strategic merge patch of override %s: %v", o.Name, err)
+			}
+		default:
+			return fmt.Errorf("unsupported patch type %s of override %s", o.PatchType, o.Name)
+		}
+	}
+	if err := json.Unmarshal(data, patched); err != nil {
+		return err
+	}
+
+	annotations := patched.GetAnnotations()
+	if len(overrides) == 0 {
+		delete(annotations, v1alpha1.AnnotationNodePoolOverrides)
+		return nil
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[v1alpha1.AnnotationNodePoolOverrides] = OverrideNames(overrides)
+	patched.SetAnnotations(annotations)
+	return nil
+}
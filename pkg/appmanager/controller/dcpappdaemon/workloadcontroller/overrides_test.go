@@ -0,0 +1,211 @@
+package workloadcontroller
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+)
+
+func TestMatchedOverrides(t *testing.T) {
+	yad := &v1alpha1.DcpAppDaemon{
+		Spec: v1alpha1.DcpAppDaemonSpec{
+			Overrides: []v1alpha1.NodePoolOverride{
+				{Name: "by-name", NodePools: []string{"hangzhou"}},
+				{Name: "by-label", NodePoolSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"site": "factory"}}},
+				{Name: "empty-selector", NodePoolSelector: &metav1.LabelSelector{}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		nodepool v1alpha1.NodePool
+		expect   string
+	}{
+		{
+			name:     "no match",
+			nodepool: v1alpha1.NodePool{ObjectMeta: metav1.ObjectMeta{Name: "beijing"}},
+		},
+		{
+			name:     "match by name",
+			nodepool: v1alpha1.NodePool{ObjectMeta: metav1.ObjectMeta{Name: "hangzhou"}},
+			expect:   "by-name",
+		},
+		{
+			name: "match by name and labels",
+			nodepool: v1alpha1.NodePool{ObjectMeta: metav1.ObjectMeta{Name: "hangzhou",
+				Labels: map[string]string{"site": "factory"}}},
+			expect: "by-name,by-label",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := OverrideNames(MatchedOverrides(yad, tt.nodepool)); got != tt.expect {
+				t.Fatalf("expected overrides %q, got %q", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestApplyOverrides(t *testing.T) {
+	set := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "app",
+			Annotations: map[string]string{v1alpha1.AnnotationNodePoolOverrides: "stale"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "app", Image: "registry.example.com/app:1.0", Env: []corev1.EnvVar{{Name: "SITE", Value: "default"}}},
+						{Name: "sidecar", Image: "registry.example.com/sidecar:1.0"},
+					},
+				},
+			},
+		},
+	}
+	overrides := []v1alpha1.NodePoolOverride{
+		{
+			Name:      "mirror",
+			PatchType: v1alpha1.StrategicMergeOverridePatchType,
+			Patch:     runtime.RawExtension{Raw: []byte(`{"spec":{"template":{"spec":{"containers":[{"name":"app","image":"mirror.local/app:1.0"}]}}}}`)},
+		},
+		{
+			Name:      "env",
+			PatchType: v1alpha1.JSONOverridePatchType,
+			Patch:     runtime.RawExtension{Raw: []byte(`[{"op":"replace","path":"/spec/template/spec/containers/0/env/0/value","value":"factory"}]`)},
+		},
+	}
+
+	patched := &appsv1.Deployment{}
+	if err := applyOverrides(overrides, set, patched); err != nil {
+		t.Fatalf("fail to apply overrides: %v", err)
+	}
+	containers := patched.Spec.Template.Spec.Containers
+	if len(containers) != 2 || containers[0].Image != "mirror.local/app:1.0" || containers[1].Image != "registry.example.com/sidecar:1.0" {
+		t.Fatalf("unexpected containers %v", containers)
+	}
+	if containers[0].Env[0].Value != "factory" {
+		t.Fatalf("expected env SITE=factory, got %v", containers[0].Env)
+	}
+	if got := patched.Annotations[v1alpha1.AnnotationNodePoolOverrides]; got != "mirror,env" {
+		t.Fatalf("expected overrides annotation mirror,env, got %q", got)
+	}
+
+	patched = &appsv1.Deployment{}
+	if err := applyOverrides(nil, set, patched); err != nil {
+		t.Fatalf("fail to apply no overrides: %v", err)
+	}
+	if _, ok := patched.Annotations[v1alpha1.AnnotationNodePoolOverrides]; ok {
+		t.Fatalf("expected the overrides annotation to be removed")
+	}
+	if patched.Spec.Template.Spec.Containers[0].Image != "registry.example.com/app:1.0" {
+		t.Fatalf("unexpected image %s", patched.Spec.Template.Spec.Containers[0].Image)
+	}
+}
+
+func TestApplyTemplateKeepsPoolOwnedFields(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("fail to add scheme: %v", err)
+	}
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}}
+	yad := &v1alpha1.DcpAppDaemon{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "default", UID: "uid"},
+		Spec: v1alpha1.DcpAppDaemonSpec{
+			Selector: selector,
+			WorkloadTemplate: v1alpha1.WorkloadTemplate{
+				DeploymentTemplate: &v1alpha1.DeploymentTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "demo"}},
+					Spec: appsv1.DeploymentSpec{
+						Selector: selector.DeepCopy(),
+						Template: corev1.PodTemplateSpec{
+							ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "demo"}},
+							Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:1.0"}}},
+						},
+					},
+				},
+			},
+			Overrides: []v1alpha1.NodePoolOverride{
+				{
+					Name:      "hijack",
+					NodePools: []string{"hangzhou"},
+					PatchType: v1alpha1.StrategicMergeOverridePatchType,
+					Patch: runtime.RawExtension{Raw: []byte(`{"metadata":{"labels":{"` + v1alpha1.PoolNameLabelKey + `":"beijing"}},` +
+						`"spec":{"selector":{"matchLabels":{"app":"other"}},"template":{"metadata":{"labels":{"app":"other"}},` +
+						`"spec":{"nodeSelector":{"` + v1alpha1.LabelCurrentNodePool + `":"beijing","disk":"ssd"}}}}}`)},
+				},
+				{
+					Name:      "drop",
+					NodePools: []string{"hangzhou"},
+					PatchType: v1alpha1.JSONOverridePatchType,
+					Patch: runtime.RawExtension{Raw: []byte(`[{"op":"remove","path":"/spec/template/metadata/labels/` +
+						jsonPointerEscape(v1alpha1.PoolNameLabelKey) + `"}]`)},
+				},
+			},
+		},
+	}
+	nodepool := v1alpha1.NodePool{ObjectMeta: metav1.ObjectMeta{Name: "hangzhou"}}
+
+	d := &DeploymentControllor{Scheme: scheme}
+	set := &appsv1.Deployment{}
+	if err := d.applyTemplate(scheme, yad, nodepool, "rev", set); err != nil {
+		t.Fatalf("fail to apply template: %v", err)
+	}
+
+	if got := set.Labels[v1alpha1.PoolNameLabelKey]; got != "hangzhou" {
+		t.Fatalf("expected pool label hangzhou, got %q", got)
+	}
+	if got := set.Annotations[v1alpha1.AnnotationRefNodePool]; got != "hangzhou" {
+		t.Fatalf("expected nodepool annotation hangzhou, got %q", got)
+	}
+	expectSelector := map[string]string{"app": "demo", v1alpha1.PoolNameLabelKey: "hangzhou"}
+	if !reflect.DeepEqual(set.Spec.Selector.MatchLabels, expectSelector) {
+		t.Fatalf("expected selector %v, got %v", expectSelector, set.Spec.Selector.MatchLabels)
+	}
+	podSelector, err := metav1.LabelSelectorAsSelector(set.Spec.Selector)
+	if err != nil {
+		t.Fatalf("fail to convert selector: %v", err)
+	}
+	if !podSelector.Matches(labels.Set(set.Spec.Template.Labels)) {
+		t.Fatalf("selector %v does not match pod labels %v", set.Spec.Selector, set.Spec.Template.Labels)
+	}
+	if got := set.Spec.Template.Spec.NodeSelector[v1alpha1.LabelCurrentNodePool]; got != "hangzhou" {
+		t.Fatalf("expected nodeSelector of nodepool hangzhou, got %q", got)
+	}
+	if got := set.Spec.Template.Spec.NodeSelector["disk"]; got != "ssd" {
+		t.Fatalf("expected the extra nodeSelector disk=ssd to be kept, got %q", got)
+	}
+}
+
+func jsonPointerEscape(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
@@ -54,6 +54,11 @@ func (w *Workload) GetNodePoolName() string {
 	return w.Spec.Ref.GetAnnotations()[unitv1alpha1.AnnotationRefNodePool]
 }
 
+// GetOverrides returns the names of the DcpAppDaemon overrides applied to the workload.
+func (w *Workload) GetOverrides() string {
+	return w.Spec.Ref.GetAnnotations()[unitv1alpha1.AnnotationNodePoolOverrides]
+}
+
 func (w *Workload) GetToleration() []corev1.Toleration {
 	return w.Spec.Toleration
 }
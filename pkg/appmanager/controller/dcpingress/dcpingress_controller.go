@@ -47,7 +47,7 @@ import (
 
 const (
 	controllerName         = "ingress-controller"
-	ingressDeploymentLabel = "ingress.io/nodepool"
+	ingressDeploymentLabel = "dcpingress.io/nodepool"
 )
 
 const updateRetries = 5
@@ -140,8 +140,8 @@ func (r *IngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	if !instance.ObjectMeta.DeletionTimestamp.IsZero() {
 		return r.cleanupIngressResources(instance)
 	}
-	// Set the default version at current stage
-	instance.Status.Version = appsv1alpha1.NginxIngressControllerVersion
+	// Set the version of the ingress controller shared by the pools
+	instance.Status.Version = getIngressControllerVersion(&instance.Spec.Controller)
 
 	var desiredPoolNames, currentPoolNames []string
 	desiredPoolNames = getDesiredPoolNames(instance)
@@ -158,8 +158,8 @@ func (r *IngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			}
 		}
 		for _, pool := range addedPools {
-			replicas := instance.Spec.Replicas
-			if err := dcpapputil.CreateNginxIngressSpecificResource(r.Client, pool, replicas, ownerRef); err != nil {
+			config := getPoolIngressConfig(instance, pool)
+			if err := dcpapputil.CreateNginxIngressSpecificResource(r.Client, pool, config, ownerRef); err != nil {
 				return ctrl.Result{}, err
 			}
 			notReadyPool := appsv1alpha1.IngressNotReadyPool{Name: pool, Info: nil}
@@ -196,15 +196,16 @@ func (r *IngressReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	}
 	if unchangedPools != nil {
 		klog.V(4).Infof("unchanged pool list is %s", unchangedPools)
-		desiredReplicas := instance.Spec.Replicas
-		currentReplicas := instance.Status.Replicas
-		if desiredReplicas != currentReplicas {
+		if instance.Spec.Replicas != instance.Status.Replicas {
 			klog.V(4).Infof("Per-Pool ingress controller replicas is changed!")
 			isIngressCRChanged = true
-			for _, pool := range unchangedPools {
-				if err := dcpapputil.ScaleNginxIngressControllerDeploymment(r.Client, pool, desiredReplicas); err != nil {
-					return ctrl.Result{}, err
-				}
+		}
+		ownerRef := prepareDeploymentOwnerReferences(instance)
+		for _, pool := range unchangedPools {
+			// the resources of the pool follow any change of its ingress controller settings
+			config := getPoolIngressConfig(instance, pool)
+			if err := dcpapputil.ApplyNginxIngressPoolResource(r.Client, pool, config, ownerRef); err != nil {
+				return ctrl.Result{}, err
 			}
 		}
 	}
@@ -295,18 +296,19 @@ func removePoolfromCondition(ying *appsv1alpha1.DcpIngress, poolname string) boo
 
 func (r *IngressReconciler) updateStatus(ying *appsv1alpha1.DcpIngress, ingressCRChanged bool) error {
 	ying.Status.Replicas = ying.Spec.Replicas
+	deployments, err := r.getAllDeployments(ying)
+	if err != nil {
+		klog.V(4).Infof("Get all the ingress controller deployments err: %v", err)
+		return err
+	}
+	ying.Status.Pools = getPoolStatuses(ying, deployments)
 	if !ingressCRChanged {
-		deployments, err := r.getAllDeployments(ying)
-		if err != nil {
-			klog.V(4).Infof("Get all the ingress controller deployments err: %v", err)
-			return err
-		}
 		ying.Status.Conditions.IngressReadyPools = nil
 		ying.Status.Conditions.IngressNotReadyPools = nil
 		ying.Status.ReadyNum = 0
 		for _, dply := range deployments {
 			pool := dply.ObjectMeta.GetLabels()[ingressDeploymentLabel]
-			if dply.Status.ReadyReplicas == ying.Spec.Replicas {
+			if dply.Status.ReadyReplicas == getPoolIngressConfig(ying, pool).Replicas {
 				klog.V(4).Infof("Ingress on pool %s is ready!", pool)
 				ying.Status.ReadyNum += 1
 				ying.Status.Conditions.IngressReadyPools = append(ying.Status.Conditions.IngressReadyPools, pool)
@@ -335,6 +337,27 @@ func (r *IngressReconciler) updateStatus(ying *appsv1alpha1.DcpIngress, ingressC
 	return updateErr
 }
 
+// getPoolStatuses reports the ingress controller deployed in each pool.
+func getPoolStatuses(ying *appsv1alpha1.DcpIngress, deployments []*appsv1.Deployment) []appsv1alpha1.IngressPoolStatus {
+	readyReplicas := make(map[string]int32, len(deployments))
+	for _, dply := range deployments {
+		readyReplicas[dply.ObjectMeta.GetLabels()[ingressDeploymentLabel]] = dply.Status.ReadyReplicas
+	}
+	var statuses []appsv1alpha1.IngressPoolStatus
+	for i := range ying.Spec.Pools {
+		pool := &ying.Spec.Pools[i]
+		config := getPoolIngressConfig(ying, pool.Name)
+		statuses = append(statuses, appsv1alpha1.IngressPoolStatus{
+			Name:             pool.Name,
+			Version:          getIngressControllerVersion(getPoolControllerSpec(ying, pool)),
+			IngressClassName: config.IngressClass,
+			Replicas:         config.Replicas,
+			ReadyReplicas:    readyReplicas[pool.Name],
+		})
+	}
+	return statuses
+}
+
 func (r *IngressReconciler) cleanupIngressResources(instance *appsv1alpha1.DcpIngress) (ctrl.Result, error) {
 	pools := getDesiredPoolNames(instance)
 	if pools != nil {
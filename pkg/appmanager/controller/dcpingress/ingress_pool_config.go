@@ -0,0 +1,143 @@
+package dcpingress
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"strings"
+
+	appsv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+	dcpapputil "github.com/bhojpur/dcp/pkg/appmanager/util/kubernetes"
+)
+
+// getIngressPool returns the spec of the named pool, or nil if ingress is not enabled on it.
+func getIngressPool(ying *appsv1alpha1.DcpIngress, poolname string) *appsv1alpha1.IngressPool {
+	for i := range ying.Spec.Pools {
+		if ying.Spec.Pools[i].Name == poolname {
+			return &ying.Spec.Pools[i]
+		}
+	}
+	return nil
+}
+
+// getPoolControllerSpec returns the ingress controller settings of the DcpIngress
+// overridden by those of the pool.
+func getPoolControllerSpec(ying *appsv1alpha1.DcpIngress, pool *appsv1alpha1.IngressPool) *appsv1alpha1.IngressControllerSpec {
+	spec := ying.Spec.Controller.DeepCopy()
+	if pool == nil || pool.Controller == nil {
+		return spec
+	}
+	override := pool.Controller
+	if override.Image != "" {
+		spec.Image = override.Image
+	}
+	if override.Version != "" {
+		spec.Version = override.Version
+	}
+	if override.IngressClassName != "" {
+		spec.IngressClassName = override.IngressClassName
+	}
+	if override.ServiceType != "" {
+		spec.ServiceType = override.ServiceType
+	}
+	spec.ServiceAnnotations = mergeStringMap(spec.ServiceAnnotations, override.ServiceAnnotations)
+	if override.HostNetwork != nil {
+		hostNetwork := *override.HostNetwork
+		spec.HostNetwork = &hostNetwork
+	}
+	if override.Resources != nil {
+		spec.Resources = override.Resources.DeepCopy()
+	}
+	spec.NodeSelector = mergeStringMap(spec.NodeSelector, override.NodeSelector)
+	if override.Tolerations != nil {
+		spec.Tolerations = override.Tolerations
+	}
+	spec.Config = mergeStringMap(spec.Config, override.Config)
+	return spec
+}
+
+// getPoolIngressConfig returns the settings of the ingress controller to be deployed in the pool.
+func getPoolIngressConfig(ying *appsv1alpha1.DcpIngress, poolname string) *dcpapputil.NginxIngressPoolConfig {
+	pool := getIngressPool(ying, poolname)
+	spec := getPoolControllerSpec(ying, pool)
+	config := &dcpapputil.NginxIngressPoolConfig{
+		Replicas:           ying.Spec.Replicas,
+		Image:              getIngressControllerImage(spec),
+		IngressClass:       spec.IngressClassName,
+		ServiceType:        spec.ServiceType,
+		ServiceAnnotations: spec.ServiceAnnotations,
+		HostNetwork:        spec.HostNetwork != nil && *spec.HostNetwork,
+		Resources:          spec.Resources,
+		NodeSelector:       spec.NodeSelector,
+		Tolerations:        spec.Tolerations,
+		Config:             spec.Config,
+	}
+	if pool != nil && pool.Replicas != nil {
+		config.Replicas = *pool.Replicas
+	}
+	if config.IngressClass == "" {
+		config.IngressClass = poolname
+	}
+	return config
+}
+
+// getIngressControllerImage returns the image of the ingress controller, empty for
+// the default one of the template.
+func getIngressControllerImage(spec *appsv1alpha1.IngressControllerSpec) string {
+	if spec.Image != "" {
+		return spec.Image
+	}
+	version := strings.TrimPrefix(spec.Version, "v")
+	if version == "" || version == appsv1alpha1.NginxIngressControllerVersion {
+		return ""
+	}
+	return appsv1alpha1.NginxIngressControllerImageRepository + ":v" + version
+}
+
+// getIngressControllerVersion returns the version of the ingress controller, which
+// is taken from the image tag if only the image is specified.
+func getIngressControllerVersion(spec *appsv1alpha1.IngressControllerSpec) string {
+	if spec.Version != "" {
+		return strings.TrimPrefix(spec.Version, "v")
+	}
+	if spec.Image == "" {
+		return appsv1alpha1.NginxIngressControllerVersion
+	}
+	image := strings.SplitN(spec.Image, "@", 2)[0]
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return strings.TrimPrefix(image[i+1:], "v")
+	}
+	return ""
+}
+
+// mergeStringMap returns the union of both maps, the values of override winning.
+func mergeStringMap(base, override map[string]string) map[string]string {
+	if len(override) == 0 {
+		return base
+	}
+	merged := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
@@ -0,0 +1,134 @@
+package dcpingress
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+)
+
+func TestGetPoolIngressConfig(t *testing.T) {
+	hostNetwork := true
+	replicas := int32(3)
+	ying := &appsv1alpha1.DcpIngress{
+		Spec: appsv1alpha1.DcpIngressSpec{
+			Replicas: 1,
+			Controller: appsv1alpha1.IngressControllerSpec{
+				Version:            "1.1.0",
+				ServiceType:        corev1.ServiceTypeNodePort,
+				ServiceAnnotations: map[string]string{"a": "1"},
+				Config:             map[string]string{"use-gzip": "true"},
+			},
+			Pools: []appsv1alpha1.IngressPool{
+				{Name: "beijing"},
+				{
+					Name:     "hangzhou",
+					Replicas: &replicas,
+					Controller: &appsv1alpha1.IngressControllerSpec{
+						Image:              "registry.local/ingress-nginx/controller:v1.2.0",
+						IngressClassName:   "edge",
+						ServiceType:        corev1.ServiceTypeLoadBalancer,
+						ServiceAnnotations: map[string]string{"b": "2"},
+						HostNetwork:        &hostNetwork,
+						Config:             map[string]string{"use-gzip": "false"},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		pool    string
+		version string
+		check   func(t *testing.T, image, class string, replicas int32, svcType corev1.ServiceType, hostNetwork bool, annotations, config map[string]string)
+	}{
+		{
+			pool:    "beijing",
+			version: "1.1.0",
+			check: func(t *testing.T, image, class string, replicas int32, svcType corev1.ServiceType, hostNetwork bool, annotations, config map[string]string) {
+				if image != appsv1alpha1.NginxIngressControllerImageRepository+":v1.1.0" || class != "beijing" || replicas != 1 ||
+					svcType != corev1.ServiceTypeNodePort || hostNetwork {
+					t.Fatalf("unexpected config of pool beijing: %s %s %d %s %v", image, class, replicas, svcType, hostNetwork)
+				}
+				if !reflect.DeepEqual(annotations, map[string]string{"a": "1"}) || config["use-gzip"] != "true" {
+					t.Fatalf("unexpected maps of pool beijing: %v %v", annotations, config)
+				}
+			},
+		},
+		{
+			pool:    "hangzhou",
+			version: "1.1.0",
+			check: func(t *testing.T, image, class string, replicas int32, svcType corev1.ServiceType, hostNetwork bool, annotations, config map[string]string) {
+				if image != "registry.local/ingress-nginx/controller:v1.2.0" || class != "edge" || replicas != 3 ||
+					svcType != corev1.ServiceTypeLoadBalancer || !hostNetwork {
+					t.Fatalf("unexpected config of pool hangzhou: %s %s %d %s %v", image, class, replicas, svcType, hostNetwork)
+				}
+				if !reflect.DeepEqual(annotations, map[string]string{"a": "1", "b": "2"}) || config["use-gzip"] != "false" {
+					t.Fatalf("unexpected maps of pool hangzhou: %v %v", annotations, config)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		st := tt
+		tf := func(t *testing.T) {
+			config := getPoolIngressConfig(ying, st.pool)
+			st.check(t, config.Image, config.IngressClass, config.Replicas, config.ServiceType,
+				config.HostNetwork, config.ServiceAnnotations, config.Config)
+			if version := getIngressControllerVersion(getPoolControllerSpec(ying, getIngressPool(ying, st.pool))); version != st.version {
+				t.Fatalf("expect version %s, but got %s", st.version, version)
+			}
+		}
+		t.Run(st.pool, tf)
+	}
+
+	// the settings of the DcpIngress are not modified by the overrides of a pool
+	if len(ying.Spec.Controller.ServiceAnnotations) != 1 || ying.Spec.Controller.Config["use-gzip"] != "true" {
+		t.Fatalf("expect the settings of the DcpIngress to be kept, but got %v", ying.Spec.Controller)
+	}
+}
+
+func TestGetIngressControllerVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		spec   appsv1alpha1.IngressControllerSpec
+		expect string
+	}{
+		{"default", appsv1alpha1.IngressControllerSpec{}, appsv1alpha1.NginxIngressControllerVersion},
+		{"version", appsv1alpha1.IngressControllerSpec{Version: "v1.1.0"}, "1.1.0"},
+		{"image tag", appsv1alpha1.IngressControllerSpec{Image: "registry.local:5000/controller:v1.2.0@sha256:abc"}, "1.2.0"},
+		{"image without tag", appsv1alpha1.IngressControllerSpec{Image: "registry.local:5000/controller"}, ""},
+	}
+	for _, tt := range tests {
+		st := tt
+		tf := func(t *testing.T) {
+			t.Parallel()
+			if version := getIngressControllerVersion(&st.spec); version != st.expect {
+				t.Fatalf("expect version %q, but got %q", st.expect, version)
+			}
+		}
+		t.Run(st.name, tf)
+	}
+}
@@ -33,6 +33,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog"
@@ -40,12 +41,12 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
-	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	appsv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+	extclient "github.com/bhojpur/dcp/pkg/appmanager/client"
 	"github.com/bhojpur/dcp/pkg/appmanager/constant"
 	"github.com/bhojpur/dcp/pkg/appmanager/util/gate"
 )
@@ -61,6 +62,8 @@ type NodePoolReconciler struct {
 
 	recorder          record.EventRecorder
 	createDefaultPool bool
+	// kubeClient evicts the pods of the nodes under maintenance
+	kubeClient kubernetes.Interface
 }
 
 type NodePoolRelatedAttributes struct {
@@ -86,12 +89,16 @@ func Add(mgr manager.Manager, ctx context.Context) error {
 
 // newReconciler returns a new reconcile.Reconciler
 func newReconciler(mgr manager.Manager, createDefaultPool bool) reconcile.Reconciler {
-	return &NodePoolReconciler{
+	npr := &NodePoolReconciler{
 		Client:            mgr.GetClient(),
 		Scheme:            mgr.GetScheme(),
 		recorder:          mgr.GetEventRecorderFor(controllerName),
 		createDefaultPool: createDefaultPool,
 	}
+	if genericClient := extclient.GetGenericClient(); genericClient != nil {
+		npr.kubeClient = genericClient.KubeClient
+	}
+	return npr
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
@@ -110,10 +117,10 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return errors.New("fail to assert interface to NodePoolReconciler")
 	}
 
-	// Watch for changes to NodePool
+	// Watch for changes to NodePool, and the pools above and below it
 	err = c.Watch(&source.Kind{
 		Type: &appsv1alpha1.NodePool{}},
-		&handler.EnqueueRequestForObject{})
+		&EnqueueNodePoolHierarchy{client: mgr.GetClient()})
 	if err != nil {
 		return err
 	}
@@ -126,6 +133,14 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// Watch for changes to Pod, whose requests are summarized in the pool status
+	err = c.Watch(&source.Kind{
+		Type: &corev1.Pod{}},
+		&EnqueueNodePoolForPod{client: mgr.GetClient()})
+	if err != nil {
+		return err
+	}
+
 	if npr.createDefaultPool {
 		// register a node controller with the underlying informer of the manager
 		go createDefaultNodePool(mgr.GetClient())
@@ -172,6 +187,7 @@ func createDefaultNodePool(client client.Client) {
 // +kubebuilder:rbac:groups=apps.bhojpur.net,resources=nodepools/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=core,resources=pods/eviction,verbs=create
 
 func (r *NodePoolReconciler) Reconcile(_ context.Context, req ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
@@ -181,6 +197,27 @@ func (r *NodePoolReconciler) Reconcile(_ context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	pools, err := listNodePools(r.Client)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	attrs := NodePoolRelatedAttributes{
+		Labels:      nodePool.Spec.Labels,
+		Annotations: nodePool.Spec.Annotations,
+		Taints:      nodePool.Spec.Taints,
+	}
+	autonomy := nodePool.Spec.Autonomy
+	maintenance := nodePool.Spec.Maintenance
+	if chain, err := getNodePoolChain(pools, &nodePool); err != nil {
+		// only the attributes of the pool itself are applied until the cycle is broken
+		klog.Errorf("fail to get the ancestors of nodepool %s: %v", nodePool.GetName(), err)
+		r.recorder.Event(&nodePool, corev1.EventTypeWarning, "InvalidParent", err.Error())
+	} else {
+		attrs = mergeNodePoolChainAttrs(chain)
+		autonomy = chainAutonomy(chain)
+		maintenance = chainMaintenance(chain)
+	}
+
 	var desiredNodeList corev1.NodeList
 	if err := r.List(ctx, &desiredNodeList, client.MatchingLabels(map[string]string{
 		appsv1alpha1.LabelDesiredNodePool: nodePool.GetName(),
@@ -229,23 +266,22 @@ func (r *NodePoolReconciler) Reconcile(_ context.Context, req ctrl.Request) (ctr
 
 	// 2. handle the event of adding node to the pool and the event of
 	// updating node pool attributes
-	for _, node := range desiredNodeList.Items {
+	for i := range desiredNodeList.Items {
+		node := &desiredNodeList.Items[i]
 		nodes = append(nodes, node.GetName())
-		if isNodeReady(node) {
+		if isNodeReady(*node) {
 			readyNode += 1
 		} else {
 			notReadyNode += 1
 		}
 
-		attrUpdated, err := conciliatePoolRelatedAttrs(&node,
-			NodePoolRelatedAttributes{
-				Labels:      nodePool.Spec.Labels,
-				Annotations: nodePool.Spec.Annotations,
-				Taints:      nodePool.Spec.Taints,
-			})
+		attrUpdated, err := conciliatePoolRelatedAttrs(node, attrs)
 		if err != nil {
 			return ctrl.Result{}, err
 		}
+		if conciliateAutonomy(node, autonomy) {
+			attrUpdated = true
+		}
 		var ownerLabelUpdated bool
 		if node.Labels[appsv1alpha1.LabelCurrentNodePool] != nodePool.GetName() {
 			ownerLabelUpdated = true
@@ -256,15 +292,45 @@ func (r *NodePoolReconciler) Reconcile(_ context.Context, req ctrl.Request) (ctr
 		}
 
 		if attrUpdated || ownerLabelUpdated {
-			if err := r.Update(ctx, &node); err != nil {
+			if err := r.Update(ctx, node); err != nil {
 				klog.Errorf("Update Node %s error %v", node.Name, err)
 				return ctrl.Result{}, err
 			}
 		}
 	}
 
-	// 3. always update the node pool status if necessary
-	return conciliateNodePoolStatus(r.Client, readyNode, notReadyNode, nodes, &nodePool)
+	// 3. cordon and drain the nodes if the pool or one of its ancestors is under maintenance
+	newStatus := nodePool.Status.DeepCopy()
+	maintenanceCond, requeue, err := r.conciliateMaintenance(ctx, &nodePool, maintenance, desiredNodeList.Items)
+	if err != nil {
+		klog.Errorf("fail to conciliate the maintenance of nodepool %s: %v", nodePool.GetName(), err)
+		r.recorder.Event(&nodePool, corev1.EventTypeWarning, "MaintenanceFailed", err.Error())
+		requeue = true
+	}
+	setNodePoolCondition(newStatus, appsv1alpha1.NodePoolMaintenanceCondition, maintenanceCond)
+
+	var autonomyCond *appsv1alpha1.NodePoolCondition
+	if autonomy {
+		autonomyCond = autonomyCondition(desiredNodeList.Items)
+	}
+	setNodePoolCondition(newStatus, appsv1alpha1.NodePoolAutonomyCondition, autonomyCond)
+	conditionsUpdated := !reflect.DeepEqual(newStatus.Conditions, nodePool.Status.Conditions)
+	nodePool.Status.Conditions = newStatus.Conditions
+
+	// 4. sum up the capacity and the usage of the nodes
+	resources := summarizeNodes(desiredNodeList.Items)
+	if resources.requested, err = getRequestedResources(ctx, r.Client, desiredNodeList.Items); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// 5. always update the node pool status if necessary
+	descendantReadyNode, descendantNotReadyNode := countDescendantNodes(pools, nodePool.GetName())
+	result, err := conciliateNodePoolStatus(r.Client, readyNode, notReadyNode,
+		descendantReadyNode, descendantNotReadyNode, nodes, resources, conditionsUpdated, &nodePool)
+	if requeue {
+		result.RequeueAfter = maintenanceRequeuePeriod
+	}
+	return result, err
 }
 
 // removePoolRelatedAttrs removes attributes(label/annotation/taint) that
@@ -272,6 +338,9 @@ func (r *NodePoolReconciler) Reconcile(_ context.Context, req ctrl.Request) (ctr
 func removePoolRelatedAttrs(node *corev1.Node) error {
 	var npra NodePoolRelatedAttributes
 
+	// the maintenance of the pool does not apply to the node any more
+	endMaintenance(node)
+
 	if _, exist := node.Annotations[appsv1alpha1.AnnotationPrevAttrs]; !exist {
 		return nil
 	}
@@ -303,6 +372,7 @@ func removePoolRelatedAttrs(node *corev1.Node) error {
 	}
 	delete(node.Annotations, appsv1alpha1.AnnotationPrevAttrs)
 	delete(node.Labels, appsv1alpha1.LabelCurrentNodePool)
+	conciliateAutonomy(node, false)
 
 	return nil
 }
@@ -399,10 +469,14 @@ func conciliateTaints(node *corev1.Node, oldTaints, newTaints []corev1.Taint) {
 // conciliateNodePoolStatus will update the nodepool status
 func conciliateNodePoolStatus(cli client.Client,
 	readyNode,
-	notReadyNode int32,
+	notReadyNode,
+	descendantReadyNode,
+	descendantNotReadyNode int32,
 	nodes []string,
+	resources nodePoolResources,
+	conditionsUpdated bool,
 	nodePool *appsv1alpha1.NodePool) (ctrl.Result, error) {
-	var updateNodePool bool
+	updateNodePool := conditionsUpdated
 	if readyNode != nodePool.Status.ReadyNodeNum {
 		nodePool.Status.ReadyNodeNum = readyNode
 		updateNodePool = true
@@ -413,6 +487,31 @@ func conciliateNodePoolStatus(cli client.Client,
 		updateNodePool = true
 	}
 
+	if descendantReadyNode != nodePool.Status.DescendantReadyNodeNum {
+		nodePool.Status.DescendantReadyNodeNum = descendantReadyNode
+		updateNodePool = true
+	}
+
+	if descendantNotReadyNode != nodePool.Status.DescendantUnreadyNodeNum {
+		nodePool.Status.DescendantUnreadyNodeNum = descendantNotReadyNode
+		updateNodePool = true
+	}
+
+	if !resourceListEqual(resources.allocatable, nodePool.Status.Allocatable) {
+		nodePool.Status.Allocatable = resources.allocatable
+		updateNodePool = true
+	}
+
+	if !resourceListEqual(resources.requested, nodePool.Status.Requested) {
+		nodePool.Status.Requested = resources.requested
+		updateNodePool = true
+	}
+
+	if !reflect.DeepEqual(resources.nodeConditions, nodePool.Status.NodeConditions) {
+		nodePool.Status.NodeConditions = resources.nodeConditions
+		updateNodePool = true
+	}
+
 	// update the node list on demand
 	sort.Strings(nodes)
 	sort.Strings(nodePool.Status.Nodes)
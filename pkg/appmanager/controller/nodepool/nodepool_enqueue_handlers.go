@@ -21,11 +21,13 @@ package nodepool
 // THE SOFTWARE.
 
 import (
+	"context"
 	"reflect"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 
 	appsv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
@@ -102,6 +104,15 @@ func (e *EnqueueNodePoolForNode) Update(evt event.UpdateEvent,
 		return
 	}
 
+	if !resourceListEqual(newNode.Status.Allocatable, oldNode.Status.Allocatable) ||
+		!reflect.DeepEqual(nodeConditionStatuses(newNode), nodeConditionStatuses(oldNode)) {
+		// the capacity or the conditions summarized by the pool are changed
+		klog.V(5).Infof("node capacity or conditions have been changed,"+
+			" will enqueue pool(%s) for node(%s)", newNp, newNode.GetName())
+		addNodePoolToWorkQueue(newNp, q)
+		return
+	}
+
 	if !reflect.DeepEqual(newNode.Labels, oldNode.Labels) ||
 		!reflect.DeepEqual(newNode.Annotations, oldNode.Annotations) ||
 		!reflect.DeepEqual(newNode.Spec.Taints, oldNode.Spec.Taints) {
@@ -140,3 +151,77 @@ func (e *EnqueueNodePoolForNode) Generic(evt event.GenericEvent,
 	q workqueue.RateLimitingInterface) {
 	return
 }
+
+// nodeConditionStatuses returns the status of each condition of the node,
+// ignoring the heartbeats.
+func nodeConditionStatuses(node *corev1.Node) map[corev1.NodeConditionType]corev1.ConditionStatus {
+	statuses := make(map[corev1.NodeConditionType]corev1.ConditionStatus, len(node.Status.Conditions))
+	for _, cond := range node.Status.Conditions {
+		statuses[cond.Type] = cond.Status
+	}
+	return statuses
+}
+
+// EnqueueNodePoolForPod enqueues the nodepool of the node a pod is scheduled to,
+// as the pod requests are summarized in the status of the pool.
+type EnqueueNodePoolForPod struct {
+	client client.Client
+}
+
+// Create implements EventHandler
+func (e *EnqueueNodePoolForPod) Create(evt event.CreateEvent,
+	q workqueue.RateLimitingInterface) {
+	if pod, ok := evt.Object.(*corev1.Pod); ok {
+		e.enqueueNodePool(pod.Spec.NodeName, q)
+	}
+}
+
+// Update implements EventHandler
+func (e *EnqueueNodePoolForPod) Update(evt event.UpdateEvent,
+	q workqueue.RateLimitingInterface) {
+	newPod, ok := evt.ObjectNew.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	oldPod, ok := evt.ObjectOld.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	if newPod.Spec.NodeName == oldPod.Spec.NodeName &&
+		isPodTerminated(newPod) == isPodTerminated(oldPod) {
+		return
+	}
+	if oldPod.Spec.NodeName != newPod.Spec.NodeName {
+		e.enqueueNodePool(oldPod.Spec.NodeName, q)
+	}
+	e.enqueueNodePool(newPod.Spec.NodeName, q)
+}
+
+// Delete implements EventHandler
+func (e *EnqueueNodePoolForPod) Delete(evt event.DeleteEvent,
+	q workqueue.RateLimitingInterface) {
+	if pod, ok := evt.Object.(*corev1.Pod); ok {
+		e.enqueueNodePool(pod.Spec.NodeName, q)
+	}
+}
+
+// Generic implements EventHandler
+func (e *EnqueueNodePoolForPod) Generic(evt event.GenericEvent,
+	q workqueue.RateLimitingInterface) {
+	return
+}
+
+func (e *EnqueueNodePoolForPod) enqueueNodePool(nodeName string,
+	q workqueue.RateLimitingInterface) {
+	if nodeName == "" {
+		return
+	}
+	var node corev1.Node
+	if err := e.client.Get(context.TODO(), client.ObjectKey{Name: nodeName}, &node); err != nil {
+		klog.V(5).Infof("fail to get node(%s) of pod: %v", nodeName, err)
+		return
+	}
+	if np := node.Labels[appsv1alpha1.LabelCurrentNodePool]; np != "" {
+		addNodePoolToWorkQueue(np, q)
+	}
+}
@@ -0,0 +1,197 @@
+package nodepool
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	appsv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+)
+
+// getNodePoolChain returns the ancestors of the nodepool followed by the nodepool itself,
+// from the root down. A missing parent ends the chain, a cycle is an error.
+func getNodePoolChain(pools map[string]*appsv1alpha1.NodePool, np *appsv1alpha1.NodePool) ([]*appsv1alpha1.NodePool, error) {
+	chain := []*appsv1alpha1.NodePool{np}
+	visited := map[string]bool{np.GetName(): true}
+	for parent := np.Spec.Parent; parent != ""; {
+		if visited[parent] {
+			return nil, fmt.Errorf("nodepool %s is in a cycle of parents through %s", np.GetName(), parent)
+		}
+		visited[parent] = true
+		p, ok := pools[parent]
+		if !ok {
+			klog.V(4).Infof("parent %s of nodepool %s does not exist", parent, np.GetName())
+			break
+		}
+		chain = append([]*appsv1alpha1.NodePool{p}, chain...)
+		parent = p.Spec.Parent
+	}
+	return chain, nil
+}
+
+// mergeNodePoolChainAttrs merges the attributes of the chain from the root down, the
+// labels, annotations and taints of a pool overriding those of its ancestors.
+func mergeNodePoolChainAttrs(chain []*appsv1alpha1.NodePool) NodePoolRelatedAttributes {
+	if len(chain) == 1 {
+		return NodePoolRelatedAttributes{
+			Labels:      chain[0].Spec.Labels,
+			Annotations: chain[0].Spec.Annotations,
+			Taints:      chain[0].Spec.Taints,
+		}
+	}
+
+	var npra NodePoolRelatedAttributes
+	for _, np := range chain {
+		if len(np.Spec.Labels) != 0 {
+			npra.Labels = mergeMap(npra.Labels, np.Spec.Labels)
+		}
+		if len(np.Spec.Annotations) != 0 {
+			npra.Annotations = mergeMap(npra.Annotations, np.Spec.Annotations)
+		}
+		for _, t := range np.Spec.Taints {
+			if i, exist := containTaint(t, npra.Taints); exist {
+				npra.Taints[i] = t
+				continue
+			}
+			npra.Taints = append(npra.Taints, t)
+		}
+	}
+	return npra
+}
+
+// getDescendants returns the names of all the nodepools below the nodepool.
+func getDescendants(pools map[string]*appsv1alpha1.NodePool, name string) []string {
+	children := map[string][]string{}
+	for _, np := range pools {
+		if np.Spec.Parent != "" {
+			children[np.Spec.Parent] = append(children[np.Spec.Parent], np.GetName())
+		}
+	}
+
+	var descendants []string
+	visited := map[string]bool{name: true}
+	queue := []string{name}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, child := range children[cur] {
+			if visited[child] {
+				continue
+			}
+			visited[child] = true
+			descendants = append(descendants, child)
+			queue = append(queue, child)
+		}
+	}
+	return descendants
+}
+
+// countDescendantNodes sums the ready and unready nodes of the descendants of the nodepool.
+func countDescendantNodes(pools map[string]*appsv1alpha1.NodePool, name string) (ready, notReady int32) {
+	for _, d := range getDescendants(pools, name) {
+		ready += pools[d].Status.ReadyNodeNum
+		notReady += pools[d].Status.UnreadyNodeNum
+	}
+	return ready, notReady
+}
+
+// listNodePools returns all the nodepools by name.
+func listNodePools(cli client.Client) (map[string]*appsv1alpha1.NodePool, error) {
+	var npList appsv1alpha1.NodePoolList
+	if err := cli.List(context.TODO(), &npList); err != nil {
+		return nil, err
+	}
+	pools := make(map[string]*appsv1alpha1.NodePool, len(npList.Items))
+	for i := range npList.Items {
+		pools[npList.Items[i].GetName()] = &npList.Items[i]
+	}
+	return pools, nil
+}
+
+// EnqueueNodePoolHierarchy enqueues a changed nodepool with its ancestors, whose status
+// aggregates it, and its descendants, whose nodes inherit its attributes.
+type EnqueueNodePoolHierarchy struct {
+	client client.Client
+}
+
+// Create implements EventHandler
+func (e *EnqueueNodePoolHierarchy) Create(evt event.CreateEvent,
+	q workqueue.RateLimitingInterface) {
+	e.enqueueHierarchy(evt.Object.(*appsv1alpha1.NodePool), q)
+}
+
+// Update implements EventHandler
+func (e *EnqueueNodePoolHierarchy) Update(evt event.UpdateEvent,
+	q workqueue.RateLimitingInterface) {
+	if oldNp, ok := evt.ObjectOld.(*appsv1alpha1.NodePool); ok && oldNp.Spec.Parent != "" {
+		// the old ancestors lose the nodes of the pool if its parent changed
+		e.enqueueHierarchy(oldNp, q)
+	}
+	e.enqueueHierarchy(evt.ObjectNew.(*appsv1alpha1.NodePool), q)
+}
+
+// Delete implements EventHandler
+func (e *EnqueueNodePoolHierarchy) Delete(evt event.DeleteEvent,
+	q workqueue.RateLimitingInterface) {
+	e.enqueueHierarchy(evt.Object.(*appsv1alpha1.NodePool), q)
+}
+
+// Generic implements EventHandler
+func (e *EnqueueNodePoolHierarchy) Generic(evt event.GenericEvent,
+	q workqueue.RateLimitingInterface) {
+	return
+}
+
+func (e *EnqueueNodePoolHierarchy) enqueueHierarchy(np *appsv1alpha1.NodePool,
+	q workqueue.RateLimitingInterface) {
+	addNodePoolToWorkQueue(np.GetName(), q)
+	if e.client == nil {
+		return
+	}
+
+	pools, err := listNodePools(e.client)
+	if err != nil {
+		klog.Errorf("fail to list nodepools to enqueue the hierarchy of %s: %v", np.GetName(), err)
+		return
+	}
+	visited := map[string]bool{np.GetName(): true}
+	for parent := np.Spec.Parent; parent != "" && !visited[parent]; {
+		visited[parent] = true
+		addNodePoolToWorkQueue(parent, q)
+		p, ok := pools[parent]
+		if !ok {
+			break
+		}
+		parent = p.Spec.Parent
+	}
+	for _, d := range getDescendants(pools, np.GetName()) {
+		addNodePoolToWorkQueue(d, q)
+	}
+}
+
+var _ handler.EventHandler = &EnqueueNodePoolHierarchy{}
@@ -0,0 +1,151 @@
+package nodepool
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+)
+
+func newTestNodePool(name, parent string, labels map[string]string, taints []corev1.Taint, ready, notReady int32) *appsv1alpha1.NodePool {
+	return &appsv1alpha1.NodePool{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: appsv1alpha1.NodePoolSpec{
+			Parent: parent,
+			Labels: labels,
+			Taints: taints,
+		},
+		Status: appsv1alpha1.NodePoolStatus{
+			ReadyNodeNum:   ready,
+			UnreadyNodeNum: notReady,
+		},
+	}
+}
+
+func newTestNodePools(pools ...*appsv1alpha1.NodePool) map[string]*appsv1alpha1.NodePool {
+	m := make(map[string]*appsv1alpha1.NodePool)
+	for _, np := range pools {
+		m[np.Name] = np
+	}
+	return m
+}
+
+func TestMergeNodePoolChainAttrs(t *testing.T) {
+	region := newTestNodePool("region", "", map[string]string{"region": "east", "tier": "region"},
+		[]corev1.Taint{{Key: "edge", Value: "region", Effect: corev1.TaintEffectNoSchedule}}, 0, 0)
+	site := newTestNodePool("site", "region", map[string]string{"site": "factory", "tier": "site"},
+		[]corev1.Taint{{Key: "edge", Value: "site", Effect: corev1.TaintEffectNoSchedule}}, 0, 0)
+	rack := newTestNodePool("rack", "site", map[string]string{"rack": "r1"},
+		[]corev1.Taint{{Key: "gpu", Effect: corev1.TaintEffectNoExecute}}, 0, 0)
+	orphan := newTestNodePool("orphan", "missing", map[string]string{"orphan": "true"}, nil, 0, 0)
+	pools := newTestNodePools(region, site, rack, orphan)
+
+	tests := []struct {
+		name   string
+		pool   *appsv1alpha1.NodePool
+		expect NodePoolRelatedAttributes
+	}{
+		{
+			"root pool",
+			region,
+			NodePoolRelatedAttributes{
+				Labels: map[string]string{"region": "east", "tier": "region"},
+				Taints: []corev1.Taint{{Key: "edge", Value: "region", Effect: corev1.TaintEffectNoSchedule}},
+			},
+		},
+		{
+			"child overrides its ancestors",
+			rack,
+			NodePoolRelatedAttributes{
+				Labels: map[string]string{"region": "east", "site": "factory", "rack": "r1", "tier": "site"},
+				Taints: []corev1.Taint{
+					{Key: "edge", Value: "site", Effect: corev1.TaintEffectNoSchedule},
+					{Key: "gpu", Effect: corev1.TaintEffectNoExecute},
+				},
+			},
+		},
+		{
+			"missing parent",
+			orphan,
+			NodePoolRelatedAttributes{
+				Labels: map[string]string{"orphan": "true"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		st := tt
+		tf := func(t *testing.T) {
+			t.Parallel()
+			t.Logf("\tTestCase: %s", st.name)
+			chain, err := getNodePoolChain(pools, st.pool)
+			if err != nil {
+				t.Fatalf("\t%s\tunexpected error %v", failed, err)
+			}
+			get := mergeNodePoolChainAttrs(chain)
+			if !reflect.DeepEqual(get, st.expect) {
+				t.Fatalf("\t%s\texpect %v, but get %v", failed, st.expect, get)
+			}
+			t.Logf("\t%s\texpect %v, get %v", succeed, st.expect, get)
+		}
+		t.Run(st.name, tf)
+	}
+}
+
+func TestGetNodePoolChainCycle(t *testing.T) {
+	a := newTestNodePool("a", "c", nil, nil, 0, 0)
+	b := newTestNodePool("b", "a", nil, nil, 0, 0)
+	c := newTestNodePool("c", "b", nil, nil, 0, 0)
+	pools := newTestNodePools(a, b, c)
+	if _, err := getNodePoolChain(pools, b); err == nil {
+		t.Fatalf("\t%s\texpect an error for the cycle of parents", failed)
+	}
+}
+
+func TestCountDescendantNodes(t *testing.T) {
+	pools := newTestNodePools(
+		newTestNodePool("region", "", nil, nil, 1, 0),
+		newTestNodePool("site-a", "region", nil, nil, 2, 1),
+		newTestNodePool("site-b", "region", nil, nil, 3, 0),
+		newTestNodePool("rack", "site-a", nil, nil, 4, 2),
+		newTestNodePool("other", "", nil, nil, 5, 5),
+	)
+
+	descendants := getDescendants(pools, "region")
+	sort.Strings(descendants)
+	if expect := []string{"rack", "site-a", "site-b"}; !reflect.DeepEqual(descendants, expect) {
+		t.Fatalf("\t%s\texpect descendants %v, but get %v", failed, expect, descendants)
+	}
+
+	ready, notReady := countDescendantNodes(pools, "region")
+	if ready != 9 || notReady != 3 {
+		t.Fatalf("\t%s\texpect 9 ready and 3 unready nodes, but get %d and %d", failed, ready, notReady)
+	}
+	ready, notReady = countDescendantNodes(pools, "rack")
+	if ready != 0 || notReady != 0 {
+		t.Fatalf("\t%s\texpect no descendant nodes, but get %d and %d", failed, ready, notReady)
+	}
+}
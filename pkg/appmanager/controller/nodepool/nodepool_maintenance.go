@@ -0,0 +1,334 @@
+package nodepool
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog"
+	"k8s.io/kubectl/pkg/drain"
+
+	appsv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+	"github.com/bhojpur/dcp/pkg/projectinfo"
+)
+
+// maintenanceRequeuePeriod is how often the drain of the nodes under maintenance is checked.
+const maintenanceRequeuePeriod = 10 * time.Second
+
+// chainAutonomy tells if any pool of the chain puts its nodes into autonomy.
+func chainAutonomy(chain []*appsv1alpha1.NodePool) bool {
+	for _, np := range chain {
+		if np.Spec.Autonomy {
+			return true
+		}
+	}
+	return false
+}
+
+// chainMaintenance returns the maintenance of the nearest pool of the chain having one,
+// the pool itself first, or nil if none of them is under maintenance.
+func chainMaintenance(chain []*appsv1alpha1.NodePool) *appsv1alpha1.NodePoolMaintenance {
+	for i := len(chain) - 1; i >= 0; i-- {
+		if chain[i].Spec.Maintenance != nil {
+			return chain[i].Spec.Maintenance
+		}
+	}
+	return nil
+}
+
+// endMaintenance removes the maintenance annotations of the node and restores the
+// Unschedulable it had before the maintenance. It tells whether the node was in
+// maintenance, and whether it was cordoned before.
+func endMaintenance(node *corev1.Node) (ended, wasUnschedulable bool) {
+	if _, exist := node.Annotations[appsv1alpha1.AnnotationMaintenance]; !exist {
+		return false, false
+	}
+	_, wasUnschedulable = node.Annotations[appsv1alpha1.AnnotationMaintenanceUnschedulable]
+	delete(node.Annotations, appsv1alpha1.AnnotationMaintenance)
+	delete(node.Annotations, appsv1alpha1.AnnotationMaintenanceUnschedulable)
+	node.Spec.Unschedulable = wasUnschedulable
+	return true, wasUnschedulable
+}
+
+// conciliateAutonomy sets the autonomy annotation on the node if the pool is in autonomy, or
+// removes it once the pool leaves autonomy. An annotation the pool did not set, e.g. by
+// dcpctl markautonomous, is left as it is. It tells whether the node is updated.
+func conciliateAutonomy(node *corev1.Node, autonomy bool) bool {
+	autonomyAnnotation := projectinfo.GetAutonomyAnnotation()
+	_, owned := node.Annotations[appsv1alpha1.AnnotationPoolAutonomy]
+	if !autonomy {
+		if !owned {
+			return false
+		}
+		delete(node.Annotations, appsv1alpha1.AnnotationPoolAutonomy)
+		if node.Annotations[autonomyAnnotation] == "true" {
+			delete(node.Annotations, autonomyAnnotation)
+		}
+		return true
+	}
+
+	if node.Annotations[autonomyAnnotation] == "true" {
+		return false
+	}
+	if node.Annotations == nil {
+		node.Annotations = make(map[string]string)
+	}
+	node.Annotations[autonomyAnnotation] = "true"
+	node.Annotations[appsv1alpha1.AnnotationPoolAutonomy] = "true"
+	return true
+}
+
+// autonomyCondition reports how many nodes of the pool are in autonomy.
+func autonomyCondition(nodes []corev1.Node) *appsv1alpha1.NodePoolCondition {
+	var autonomous int
+	for _, node := range nodes {
+		if node.Annotations[projectinfo.GetAutonomyAnnotation()] == "true" {
+			autonomous++
+		}
+	}
+	if autonomous == len(nodes) {
+		return newNodePoolCondition(appsv1alpha1.NodePoolAutonomyCondition, corev1.ConditionTrue, "Enabled",
+			fmt.Sprintf("all %d nodes are in autonomy", len(nodes)))
+	}
+	return newNodePoolCondition(appsv1alpha1.NodePoolAutonomyCondition, corev1.ConditionFalse, "Enabling",
+		fmt.Sprintf("%d/%d nodes are in autonomy", autonomous, len(nodes)))
+}
+
+// sortNodesForMaintenance orders the nodes to drain, the unready ones first as they
+// do not serve anything, then by name.
+func sortNodesForMaintenance(nodes []corev1.Node) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		iReady, jReady := isNodeReady(nodes[i]), isNodeReady(nodes[j])
+		if iReady != jReady {
+			return !iReady
+		}
+		return nodes[i].Name < nodes[j].Name
+	})
+}
+
+// getMaxUnavailable returns the number of nodes which may be drained at the same time, at least 1.
+func getMaxUnavailable(maintenance *appsv1alpha1.NodePoolMaintenance, total int) (int, error) {
+	maxUnavailable := intstr.FromInt(1)
+	if maintenance.MaxUnavailable != nil {
+		maxUnavailable = *maintenance.MaxUnavailable
+	}
+	n, err := intstr.GetScaledValueFromIntOrPercent(&maxUnavailable, total, true)
+	if err != nil {
+		return 0, err
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n, nil
+}
+
+// conciliateMaintenance cordons and drains the nodes of the pool under the maintenance, at most
+// maxUnavailable at a time, or uncordons the nodes it drained once the maintenance is removed,
+// except those which were already cordoned before.
+// It returns the maintenance condition, nil if the pool is not under maintenance, and
+// whether the drain has to be checked again.
+func (r *NodePoolReconciler) conciliateMaintenance(ctx context.Context, nodePool *appsv1alpha1.NodePool,
+	maintenance *appsv1alpha1.NodePoolMaintenance, nodes []corev1.Node) (*appsv1alpha1.NodePoolCondition, bool, error) {
+	if maintenance == nil {
+		var errs []error
+		for i := range nodes {
+			node := &nodes[i]
+			ended, wasUnschedulable := endMaintenance(node)
+			if !ended {
+				continue
+			}
+			if err := r.Update(ctx, node); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if wasUnschedulable {
+				klog.Infof("node %s of nodepool %s stays cordoned as it was before the maintenance", node.Name, nodePool.Name)
+				continue
+			}
+			klog.Infof("node %s of nodepool %s is uncordoned as the maintenance is over", node.Name, nodePool.Name)
+		}
+		return nil, false, utilerrors.NewAggregate(errs)
+	}
+
+	maxUnavailable, err := getMaxUnavailable(maintenance, len(nodes))
+	if err != nil {
+		return nil, false, err
+	}
+
+	sortNodesForMaintenance(nodes)
+	var draining, drained int
+	for _, node := range nodes {
+		if node.Annotations[appsv1alpha1.AnnotationMaintenance] == appsv1alpha1.MaintenanceDraining {
+			draining++
+		}
+	}
+
+	var errs []error
+	for i := range nodes {
+		node := &nodes[i]
+		switch node.Annotations[appsv1alpha1.AnnotationMaintenance] {
+		case appsv1alpha1.MaintenanceDrained:
+			drained++
+			continue
+		case appsv1alpha1.MaintenanceDraining:
+		default:
+			if draining >= maxUnavailable {
+				continue
+			}
+			if node.Annotations == nil {
+				node.Annotations = make(map[string]string)
+			}
+			node.Annotations[appsv1alpha1.AnnotationMaintenance] = appsv1alpha1.MaintenanceDraining
+			if node.Spec.Unschedulable {
+				node.Annotations[appsv1alpha1.AnnotationMaintenanceUnschedulable] = "true"
+			}
+			node.Spec.Unschedulable = true
+			if err := r.Update(ctx, node); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			draining++
+			klog.Infof("node %s of nodepool %s is cordoned for maintenance", node.Name, nodePool.Name)
+		}
+
+		done, err := r.drainNode(ctx, node, maintenance)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("fail to drain node %s: %v", node.Name, err))
+			continue
+		}
+		if !done {
+			continue
+		}
+		node.Annotations[appsv1alpha1.AnnotationMaintenance] = appsv1alpha1.MaintenanceDrained
+		if err := r.Update(ctx, node); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		draining--
+		drained++
+		klog.Infof("node %s of nodepool %s is drained", node.Name, nodePool.Name)
+	}
+
+	if drained == len(nodes) {
+		return newNodePoolCondition(appsv1alpha1.NodePoolMaintenanceCondition, corev1.ConditionTrue, "Drained",
+			fmt.Sprintf("all %d nodes are drained", len(nodes))), false, utilerrors.NewAggregate(errs)
+	}
+	return newNodePoolCondition(appsv1alpha1.NodePoolMaintenanceCondition, corev1.ConditionTrue, "Draining",
+		fmt.Sprintf("%d/%d nodes are drained, %d are draining", drained, len(nodes), draining)), true, utilerrors.NewAggregate(errs)
+}
+
+// drainNode evicts the pods of the cordoned node, and tells whether none is left.
+// Evictions refused by a PodDisruptionBudget are retried on the next check. Pods not
+// managed by a controller are only deleted if the maintenance forces it.
+func (r *NodePoolReconciler) drainNode(ctx context.Context, node *corev1.Node,
+	maintenance *appsv1alpha1.NodePoolMaintenance) (bool, error) {
+	if r.kubeClient == nil {
+		return false, fmt.Errorf("no clientset to evict pods")
+	}
+	helper := &drain.Helper{
+		Ctx:                 ctx,
+		Client:              r.kubeClient,
+		Force:               maintenance.Force,
+		GracePeriodSeconds:  -1,
+		IgnoreAllDaemonSets: true,
+		DeleteEmptyDirData:  maintenance.DeleteEmptyDirData,
+		Out:                 ioutil.Discard,
+		ErrOut:              ioutil.Discard,
+	}
+	if maintenance.GracePeriodSeconds != nil {
+		helper.GracePeriodSeconds = int(*maintenance.GracePeriodSeconds)
+	}
+
+	list, errs := helper.GetPodsForDeletion(node.Name)
+	if len(errs) > 0 {
+		return false, utilerrors.NewAggregate(errs)
+	}
+	pods := list.Pods()
+	if len(pods) == 0 {
+		return true, nil
+	}
+
+	evictionGroupVersion, err := drain.CheckEvictionSupport(r.kubeClient)
+	if err != nil {
+		return false, err
+	}
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if evictionGroupVersion.Empty() {
+			err = helper.DeletePod(pod)
+		} else {
+			err = helper.EvictPod(pod, evictionGroupVersion)
+		}
+		if err != nil && !apierrors.IsNotFound(err) && !apierrors.IsTooManyRequests(err) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// newNodePoolCondition creates a new NodePool condition.
+func newNodePoolCondition(condType appsv1alpha1.NodePoolConditionType, status corev1.ConditionStatus,
+	reason, message string) *appsv1alpha1.NodePoolCondition {
+	return &appsv1alpha1.NodePoolCondition{
+		Type:               condType,
+		Status:             status,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	}
+}
+
+// setNodePoolCondition sets the condition of the given type, or removes it if condition is nil.
+// The transition time is kept if the status does not change.
+func setNodePoolCondition(status *appsv1alpha1.NodePoolStatus, condType appsv1alpha1.NodePoolConditionType,
+	condition *appsv1alpha1.NodePoolCondition) {
+	var conditions []appsv1alpha1.NodePoolCondition
+	for _, c := range status.Conditions {
+		if c.Type != condType {
+			conditions = append(conditions, c)
+			continue
+		}
+		if condition == nil {
+			continue
+		}
+		if c.Status == condition.Status && c.Reason == condition.Reason && c.Message == condition.Message {
+			return
+		}
+		if c.Status == condition.Status {
+			condition.LastTransitionTime = c.LastTransitionTime
+		}
+	}
+	if condition != nil {
+		conditions = append(conditions, *condition)
+	}
+	status.Conditions = conditions
+}
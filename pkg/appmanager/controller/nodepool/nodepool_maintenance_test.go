@@ -0,0 +1,325 @@
+package nodepool
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	appsv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+	"github.com/bhojpur/dcp/pkg/projectinfo"
+)
+
+func newTestNode(name string, ready bool, annotations map[string]string) corev1.Node {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+		},
+	}
+}
+
+func TestSortNodesForMaintenance(t *testing.T) {
+	nodes := []corev1.Node{
+		newTestNode("c", true, nil),
+		newTestNode("b", false, nil),
+		newTestNode("a", true, nil),
+		newTestNode("d", false, nil),
+	}
+	sortNodesForMaintenance(nodes)
+
+	expect := []string{"b", "d", "a", "c"}
+	for i := range nodes {
+		if nodes[i].Name != expect[i] {
+			t.Fatalf("expect node %s at %d, but got %s", expect[i], i, nodes[i].Name)
+		}
+	}
+}
+
+func TestGetMaxUnavailable(t *testing.T) {
+	percent := intstr.FromString("30%")
+	zero := intstr.FromInt(0)
+	three := intstr.FromInt(3)
+	invalid := intstr.FromString("thirty")
+
+	tests := []struct {
+		name           string
+		maxUnavailable *intstr.IntOrString
+		total          int
+		expect         int
+		expectErr      bool
+	}{
+		{"default", nil, 10, 1, false},
+		{"number", &three, 10, 3, false},
+		{"percent rounded up", &percent, 10, 3, false},
+		{"percent of a small pool", &percent, 2, 1, false},
+		{"at least one", &zero, 10, 1, false},
+		{"invalid", &invalid, 10, 0, true},
+	}
+	for _, tt := range tests {
+		st := tt
+		tf := func(t *testing.T) {
+			t.Parallel()
+			n, err := getMaxUnavailable(&appsv1alpha1.NodePoolMaintenance{MaxUnavailable: st.maxUnavailable}, st.total)
+			if (err != nil) != st.expectErr {
+				t.Fatalf("expect error %v, but got %v", st.expectErr, err)
+			}
+			if n != st.expect {
+				t.Fatalf("expect %d nodes, but got %d", st.expect, n)
+			}
+		}
+		t.Run(st.name, tf)
+	}
+}
+
+func TestChainMaintenance(t *testing.T) {
+	site := &appsv1alpha1.NodePool{ObjectMeta: metav1.ObjectMeta{Name: "site"},
+		Spec: appsv1alpha1.NodePoolSpec{Maintenance: &appsv1alpha1.NodePoolMaintenance{DeleteEmptyDirData: true}}}
+	room := &appsv1alpha1.NodePool{ObjectMeta: metav1.ObjectMeta{Name: "room"},
+		Spec: appsv1alpha1.NodePoolSpec{Parent: "site"}}
+	rack := &appsv1alpha1.NodePool{ObjectMeta: metav1.ObjectMeta{Name: "rack"},
+		Spec: appsv1alpha1.NodePoolSpec{Parent: "room", Maintenance: &appsv1alpha1.NodePoolMaintenance{Force: true}}}
+
+	if m := chainMaintenance([]*appsv1alpha1.NodePool{room}); m != nil {
+		t.Fatalf("expect no maintenance, but got %v", m)
+	}
+	if m := chainMaintenance([]*appsv1alpha1.NodePool{site, room}); m != site.Spec.Maintenance {
+		t.Fatalf("expect the maintenance of the site to be inherited, but got %v", m)
+	}
+	if m := chainMaintenance([]*appsv1alpha1.NodePool{site, room, rack}); m != rack.Spec.Maintenance {
+		t.Fatalf("expect the maintenance of the rack itself, but got %v", m)
+	}
+}
+
+func TestConciliateAutonomy(t *testing.T) {
+	autonomy := projectinfo.GetAutonomyAnnotation()
+	pooled := newTestNode("pooled", true, map[string]string{"foo": "bar"})
+	marked := newTestNode("marked", true, map[string]string{autonomy: "true"})
+
+	if !conciliateAutonomy(&pooled, true) || conciliateAutonomy(&marked, true) {
+		t.Fatalf("expect only the node without autonomy to be updated")
+	}
+	if pooled.Annotations[autonomy] != "true" || pooled.Annotations[appsv1alpha1.AnnotationPoolAutonomy] != "true" {
+		t.Fatalf("expect the pool to put the node into autonomy, but got %v", pooled.Annotations)
+	}
+	if pooled.Annotations["foo"] != "bar" {
+		t.Fatalf("expect the annotations of the node to be kept, but got %v", pooled.Annotations)
+	}
+	if conciliateAutonomy(&pooled, true) {
+		t.Fatalf("expect no update while the pool stays in autonomy")
+	}
+
+	if !conciliateAutonomy(&pooled, false) || conciliateAutonomy(&marked, false) {
+		t.Fatalf("expect only the node put into autonomy by the pool to be updated")
+	}
+	if _, exist := pooled.Annotations[autonomy]; exist {
+		t.Fatalf("expect the autonomy set by the pool to be removed, but got %v", pooled.Annotations)
+	}
+	if _, exist := pooled.Annotations[appsv1alpha1.AnnotationPoolAutonomy]; exist {
+		t.Fatalf("expect the pool autonomy record to be removed, but got %v", pooled.Annotations)
+	}
+	if marked.Annotations[autonomy] != "true" {
+		t.Fatalf("expect the autonomy marked on the node to be kept, but got %v", marked.Annotations)
+	}
+}
+
+func TestRemovePoolRelatedAttrsEndsMaintenance(t *testing.T) {
+	prevAttrs := `{"labels":{"pool":"edge"}}`
+	draining := newTestNode("draining", true, map[string]string{
+		appsv1alpha1.AnnotationPrevAttrs:   prevAttrs,
+		appsv1alpha1.AnnotationMaintenance: appsv1alpha1.MaintenanceDraining,
+	})
+	draining.Spec.Unschedulable = true
+	cordoned := newTestNode("cordoned", true, map[string]string{
+		appsv1alpha1.AnnotationPrevAttrs:                prevAttrs,
+		appsv1alpha1.AnnotationMaintenance:              appsv1alpha1.MaintenanceDrained,
+		appsv1alpha1.AnnotationMaintenanceUnschedulable: "true",
+	})
+	cordoned.Spec.Unschedulable = true
+
+	for _, node := range []*corev1.Node{&draining, &cordoned} {
+		if err := removePoolRelatedAttrs(node); err != nil {
+			t.Fatalf("failed to remove the pool attributes of node %s: %v", node.Name, err)
+		}
+		for _, key := range []string{appsv1alpha1.AnnotationMaintenance, appsv1alpha1.AnnotationMaintenanceUnschedulable} {
+			if _, exist := node.Annotations[key]; exist {
+				t.Fatalf("expect annotation %s of node %s to be removed, but got %v", key, node.Name, node.Annotations)
+			}
+		}
+	}
+	if draining.Spec.Unschedulable {
+		t.Fatalf("expect the node cordoned by the maintenance to be uncordoned after leaving the pool")
+	}
+	if !cordoned.Spec.Unschedulable {
+		t.Fatalf("expect the node cordoned before the maintenance to stay cordoned after leaving the pool")
+	}
+}
+
+func TestSetNodePoolCondition(t *testing.T) {
+	status := &appsv1alpha1.NodePoolStatus{}
+	setNodePoolCondition(status, appsv1alpha1.NodePoolMaintenanceCondition,
+		newNodePoolCondition(appsv1alpha1.NodePoolMaintenanceCondition, corev1.ConditionTrue, "Draining", "0/2"))
+	transition := status.Conditions[0].LastTransitionTime
+
+	setNodePoolCondition(status, appsv1alpha1.NodePoolMaintenanceCondition,
+		newNodePoolCondition(appsv1alpha1.NodePoolMaintenanceCondition, corev1.ConditionTrue, "Drained", "2/2"))
+	if len(status.Conditions) != 1 || status.Conditions[0].Reason != "Drained" {
+		t.Fatalf("expect the condition to be updated, but got %v", status.Conditions)
+	}
+	if !status.Conditions[0].LastTransitionTime.Equal(&transition) {
+		t.Fatalf("expect the transition time to be kept while the status does not change")
+	}
+
+	setNodePoolCondition(status, appsv1alpha1.NodePoolMaintenanceCondition, nil)
+	if len(status.Conditions) != 0 {
+		t.Fatalf("expect the condition to be removed, but got %v", status.Conditions)
+	}
+}
+
+func TestConciliateMaintenance(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = appsv1alpha1.AddToScheme(scheme)
+
+	nodes := []corev1.Node{
+		newTestNode("a", true, nil),
+		newTestNode("b", true, nil),
+		newTestNode("c", false, nil),
+	}
+	// node b was cordoned before the maintenance
+	nodes[1].Spec.Unschedulable = true
+	objs := make([]client.Object, 0, len(nodes))
+	for i := range nodes {
+		objs = append(objs, nodes[i].DeepCopy())
+	}
+	kubeClient := kubefake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "c"},
+	})
+	// the pods are deleted as the eviction subresource is not served
+	kubeClient.Resources = []*metav1.APIResourceList{{GroupVersion: "v1"}}
+	r := &NodePoolReconciler{
+		Client:     fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+		kubeClient: kubeClient,
+	}
+	np := &appsv1alpha1.NodePool{
+		ObjectMeta: metav1.ObjectMeta{Name: "pool"},
+		Spec: appsv1alpha1.NodePoolSpec{
+			// the pod on node c is not managed by a controller
+			Maintenance: &appsv1alpha1.NodePoolMaintenance{Force: true},
+		},
+	}
+
+	var nodeList corev1.NodeList
+	if err := r.List(context.TODO(), &nodeList); err != nil {
+		t.Fatalf("fail to list nodes: %v", err)
+	}
+	cond, requeue, err := r.conciliateMaintenance(context.TODO(), np, np.Spec.Maintenance, nodeList.Items)
+	if err != nil {
+		t.Fatalf("fail to conciliate the maintenance: %v", err)
+	}
+	if !requeue || cond.Reason != "Draining" {
+		t.Fatalf("expect the pool to be draining, but got %v", cond)
+	}
+	// the unready node is drained first, one node at a time
+	for name, expect := range map[string]string{"c": appsv1alpha1.MaintenanceDraining, "a": "", "b": ""} {
+		node := &corev1.Node{}
+		if err := r.Get(context.TODO(), client.ObjectKey{Name: name}, node); err != nil {
+			t.Fatalf("fail to get node %s: %v", name, err)
+		}
+		if node.Annotations[appsv1alpha1.AnnotationMaintenance] != expect || node.Spec.Unschedulable != (expect != "" || name == "b") {
+			t.Fatalf("expect node %s to be %q, but got %v", name, expect, node)
+		}
+	}
+
+	if err := r.List(context.TODO(), &nodeList); err != nil {
+		t.Fatalf("fail to list nodes: %v", err)
+	}
+	cond, requeue, err = r.conciliateMaintenance(context.TODO(), np, np.Spec.Maintenance, nodeList.Items)
+	if err != nil {
+		t.Fatalf("fail to conciliate the maintenance: %v", err)
+	}
+	if requeue || cond.Reason != "Drained" {
+		t.Fatalf("expect the pool to be drained, but got %v", cond)
+	}
+
+	np.Spec.Maintenance = nil
+	if err := r.List(context.TODO(), &nodeList); err != nil {
+		t.Fatalf("fail to list nodes: %v", err)
+	}
+	if cond, _, err = r.conciliateMaintenance(context.TODO(), np, np.Spec.Maintenance, nodeList.Items); err != nil || cond != nil {
+		t.Fatalf("expect no condition after the maintenance, but got %v, %v", cond, err)
+	}
+	if err := r.List(context.TODO(), &nodeList); err != nil {
+		t.Fatalf("fail to list nodes: %v", err)
+	}
+	for _, node := range nodeList.Items {
+		if node.Spec.Unschedulable != (node.Name == "b") {
+			t.Fatalf("expect node %s to be unschedulable %v as before the maintenance", node.Name, node.Name == "b")
+		}
+		if _, exist := node.Annotations[appsv1alpha1.AnnotationMaintenance]; exist {
+			t.Fatalf("expect the maintenance annotation to be removed from node %s", node.Name)
+		}
+		if _, exist := node.Annotations[appsv1alpha1.AnnotationMaintenanceUnschedulable]; exist {
+			t.Fatalf("expect the unschedulable record to be removed from node %s", node.Name)
+		}
+	}
+}
+
+func TestDrainNodeWithUnmanagedPod(t *testing.T) {
+	node := newTestNode("a", true, nil)
+	kubeClient := kubefake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "a"},
+	})
+	kubeClient.Resources = []*metav1.APIResourceList{{GroupVersion: "v1"}}
+	r := &NodePoolReconciler{kubeClient: kubeClient}
+
+	done, err := r.drainNode(context.TODO(), &node, &appsv1alpha1.NodePoolMaintenance{})
+	if done || err == nil || !strings.Contains(err.Error(), "default/pod") {
+		t.Fatalf("expect the unmanaged pod to be reported, but got %v, %v", done, err)
+	}
+	if _, err := kubeClient.CoreV1().Pods("default").Get(context.TODO(), "pod", metav1.GetOptions{}); err != nil {
+		t.Fatalf("expect the unmanaged pod to be kept, but got %v", err)
+	}
+
+	if _, err := r.drainNode(context.TODO(), &node, &appsv1alpha1.NodePoolMaintenance{Force: true}); err != nil {
+		t.Fatalf("fail to drain node: %v", err)
+	}
+	if _, err := kubeClient.CoreV1().Pods("default").Get(context.TODO(), "pod", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Fatalf("expect the unmanaged pod to be deleted by force, but got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package nodepool
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	podutil "k8s.io/kubernetes/pkg/api/v1/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bhojpur/dcp/pkg/appmanager/util/fieldindex"
+)
+
+// nodePoolResources summarizes the capacity and the usage of the nodes in a pool.
+type nodePoolResources struct {
+	allocatable    corev1.ResourceList
+	requested      corev1.ResourceList
+	nodeConditions map[corev1.NodeConditionType]int32
+}
+
+// summarizeNodes sums up the allocatable resources of the nodes and counts the
+// nodes with each condition other than Ready set to True.
+func summarizeNodes(nodes []corev1.Node) nodePoolResources {
+	var npr nodePoolResources
+	for _, node := range nodes {
+		npr.allocatable = addResourceList(npr.allocatable, node.Status.Allocatable)
+		for _, cond := range node.Status.Conditions {
+			if cond.Type == corev1.NodeReady {
+				continue
+			}
+			if npr.nodeConditions == nil {
+				npr.nodeConditions = make(map[corev1.NodeConditionType]int32)
+			}
+			if cond.Status == corev1.ConditionTrue {
+				npr.nodeConditions[cond.Type]++
+			} else if _, exist := npr.nodeConditions[cond.Type]; !exist {
+				npr.nodeConditions[cond.Type] = 0
+			}
+		}
+	}
+	return npr
+}
+
+// getRequestedResources sums up the resource requests of the pods scheduled to
+// the nodes, skipping the terminated ones.
+func getRequestedResources(ctx context.Context, cli client.Client, nodes []corev1.Node) (corev1.ResourceList, error) {
+	var requested corev1.ResourceList
+	for _, node := range nodes {
+		var podList corev1.PodList
+		if err := cli.List(ctx, &podList, client.MatchingFields{
+			fieldindex.IndexNameForPodNodeName: node.GetName(),
+		}); err != nil {
+			return nil, err
+		}
+		for i := range podList.Items {
+			pod := &podList.Items[i]
+			if pod.Spec.NodeName != node.GetName() || isPodTerminated(pod) {
+				continue
+			}
+			reqs, _ := podutil.PodRequestsAndLimits(pod)
+			requested = addResourceList(requested, reqs)
+		}
+	}
+	return requested, nil
+}
+
+// addResourceList adds the quantities of newList to list, and returns list.
+func addResourceList(list, newList corev1.ResourceList) corev1.ResourceList {
+	for name, quantity := range newList {
+		if list == nil {
+			list = make(corev1.ResourceList)
+		}
+		if value, ok := list[name]; ok {
+			value.Add(quantity)
+			list[name] = value
+		} else {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+	return list
+}
+
+// resourceListEqual checks if both lists have the same quantities, regardless
+// of their format.
+func resourceListEqual(a, b corev1.ResourceList) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, qa := range a {
+		qb, ok := b[name]
+		if !ok || qa.Cmp(qb) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// isPodTerminated checks if the pod no longer holds its requested resources.
+func isPodTerminated(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
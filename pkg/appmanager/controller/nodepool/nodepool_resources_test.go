@@ -0,0 +1,116 @@
+package nodepool
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestResourceNode(name, cpu, memory string, conditions ...corev1.NodeCondition) corev1.Node {
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+			},
+			Conditions: conditions,
+		},
+	}
+}
+
+func newTestResourcePod(name, nodeName, cpu string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+			Containers: []corev1.Container{{
+				Name: "main",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)},
+				},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func TestSummarizeNodes(t *testing.T) {
+	nodes := []corev1.Node{
+		newTestResourceNode("a", "2", "4Gi",
+			corev1.NodeCondition{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+			corev1.NodeCondition{Type: corev1.NodeDiskPressure, Status: corev1.ConditionTrue}),
+		newTestResourceNode("b", "1500m", "2Gi",
+			corev1.NodeCondition{Type: corev1.NodeDiskPressure, Status: corev1.ConditionFalse},
+			corev1.NodeCondition{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse}),
+	}
+	nodes[1].Status.Allocatable["nvidia.com/gpu"] = resource.MustParse("1")
+
+	npr := summarizeNodes(nodes)
+	expect := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("3500m"),
+		corev1.ResourceMemory: resource.MustParse("6Gi"),
+		"nvidia.com/gpu":      resource.MustParse("1"),
+	}
+	if !resourceListEqual(npr.allocatable, expect) {
+		t.Fatalf("expect allocatable %v, but got %v", expect, npr.allocatable)
+	}
+	if npr.nodeConditions[corev1.NodeDiskPressure] != 1 {
+		t.Fatalf("expect 1 node with DiskPressure, but got %v", npr.nodeConditions)
+	}
+	if count, exist := npr.nodeConditions[corev1.NodeMemoryPressure]; !exist || count != 0 {
+		t.Fatalf("expect 0 node with MemoryPressure, but got %v", npr.nodeConditions)
+	}
+	if _, exist := npr.nodeConditions[corev1.NodeReady]; exist {
+		t.Fatalf("expect the Ready condition not to be counted, but got %v", npr.nodeConditions)
+	}
+}
+
+func TestGetRequestedResources(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newTestResourcePod("running", "a", "500m", corev1.PodRunning),
+		newTestResourcePod("pending", "b", "250m", corev1.PodPending),
+		newTestResourcePod("succeeded", "a", "1", corev1.PodSucceeded),
+		newTestResourcePod("other-pool", "c", "1", corev1.PodRunning),
+		newTestResourcePod("unscheduled", "", "1", corev1.PodPending),
+	).Build()
+
+	nodes := []corev1.Node{newTestResourceNode("a", "2", "4Gi"), newTestResourceNode("b", "2", "4Gi")}
+	requested, err := getRequestedResources(context.TODO(), cli, nodes)
+	if err != nil {
+		t.Fatalf("fail to get the requested resources: %v", err)
+	}
+	expect := corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("750m")}
+	if !resourceListEqual(requested, expect) {
+		t.Fatalf("expect requested %v, but got %v", expect, requested)
+	}
+}
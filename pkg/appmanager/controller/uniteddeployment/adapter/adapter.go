@@ -48,6 +48,9 @@ type Adapter interface {
 }
 
 type ReplicasInfo struct {
-	Replicas      int32
-	ReadyReplicas int32
+	Replicas        int32
+	ReadyReplicas   int32
+	UpdatedReplicas int32
+	// Partition is the number of replicas kept at the previous revision.
+	Partition int32
 }
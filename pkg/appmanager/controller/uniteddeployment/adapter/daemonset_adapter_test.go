@@ -0,0 +1,114 @@
+package adapter
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	unitv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+)
+
+func TestDaemonSetApplyPoolTemplate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := unitv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("fail to build scheme: %v", err)
+	}
+	replicas := int32(5)
+	ud := &unitv1alpha1.UnitedDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default", UID: "uid"},
+		Spec: unitv1alpha1.UnitedDeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "agent"}},
+			WorkloadTemplate: unitv1alpha1.WorkloadTemplate{
+				DaemonSetTemplate: &unitv1alpha1.DaemonSetTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "agent"}},
+					Spec: appsv1.DaemonSetSpec{
+						Template: corev1.PodTemplateSpec{
+							ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "agent"}},
+							Spec: corev1.PodSpec{
+								Containers: []corev1.Container{{Name: "agent", Image: "agent:1.0"}},
+							},
+						},
+					},
+				},
+			},
+			Topology: unitv1alpha1.Topology{
+				Pools: []unitv1alpha1.Pool{
+					{
+						Name: "hangzhou",
+						NodeSelectorTerm: corev1.NodeSelectorTerm{
+							MatchExpressions: []corev1.NodeSelectorRequirement{{
+								Key:      unitv1alpha1.LabelCurrentNodePool,
+								Operator: corev1.NodeSelectorOpIn,
+								Values:   []string{"hangzhou"},
+							}},
+						},
+						Tolerations: []corev1.Toleration{{Key: "edge", Operator: corev1.TolerationOpExists}},
+						Replicas:    &replicas,
+						Patch:       &runtime.RawExtension{Raw: []byte(`{"spec":{"template":{"spec":{"containers":[{"name":"agent","image":"agent:1.1"}]}}}}`)},
+					},
+				},
+			},
+		},
+	}
+
+	a := &DaemonSetAdapter{Scheme: scheme}
+	set := a.NewResourceObject().(*appsv1.DaemonSet)
+	if err := a.ApplyPoolTemplate(ud, "hangzhou", "v1", replicas, set); err != nil {
+		t.Fatalf("fail to apply pool template: %v", err)
+	}
+
+	if set.Labels[unitv1alpha1.PoolNameLabelKey] != "hangzhou" || set.Labels[unitv1alpha1.ControllerRevisionHashLabelKey] != "v1" {
+		t.Fatalf("unexpected labels %v", set.Labels)
+	}
+	if set.Spec.Selector.MatchLabels[unitv1alpha1.PoolNameLabelKey] != "hangzhou" {
+		t.Fatalf("unexpected selector %v", set.Spec.Selector)
+	}
+	if len(set.OwnerReferences) != 1 || set.OwnerReferences[0].Name != "agent" {
+		t.Fatalf("unexpected owner references %v", set.OwnerReferences)
+	}
+	terms := set.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) != 1 || len(terms[0].MatchExpressions) != 1 || terms[0].MatchExpressions[0].Values[0] != "hangzhou" {
+		t.Fatalf("unexpected node selector terms %v", terms)
+	}
+	if len(set.Spec.Template.Spec.Tolerations) != 1 {
+		t.Fatalf("unexpected tolerations %v", set.Spec.Template.Spec.Tolerations)
+	}
+	if image := set.Spec.Template.Spec.Containers[0].Image; image != "agent:1.1" {
+		t.Fatalf("expected the pool patch to set image agent:1.1, got %s", image)
+	}
+	if set.Annotations[unitv1alpha1.AnnotationPatchKey] == "" {
+		t.Fatalf("expected the patch annotation to be recorded")
+	}
+
+	set.Status = appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, NumberReady: 2, UpdatedNumberScheduled: 1}
+	info, err := a.GetDetails(set)
+	if err != nil {
+		t.Fatalf("fail to get details: %v", err)
+	}
+	if info.Replicas != 3 || info.ReadyReplicas != 2 || info.UpdatedReplicas != 1 {
+		t.Fatalf("unexpected replicas info %+v", info)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"k8s.io/klog"
 
 	alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+	kubeutil "github.com/bhojpur/dcp/pkg/appmanager/util/kubernetes"
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -65,8 +66,9 @@ func (a *DeploymentAdapter) GetDetails(obj metav1.Object) (ReplicasInfo, error)
 		specReplicas = *set.Spec.Replicas
 	}
 	replicasInfo := ReplicasInfo{
-		Replicas:      specReplicas,
-		ReadyReplicas: set.Status.ReadyReplicas,
+		Replicas:        specReplicas,
+		ReadyReplicas:   set.Status.ReadyReplicas,
+		UpdatedReplicas: set.Status.UpdatedReplicas,
 	}
 	return replicasInfo, nil
 }
@@ -141,6 +143,7 @@ func (a *DeploymentAdapter) ApplyPoolTemplate(ud *alpha1.UnitedDeployment, poolN
 	set.Spec.ProgressDeadlineSeconds = ud.Spec.WorkloadTemplate.DeploymentTemplate.Spec.ProgressDeadlineSeconds
 
 	attachNodeAffinityAndTolerations(&set.Spec.Template.Spec, poolConfig)
+	kubeutil.SetPoolTopologySpread(&set.Spec.Template.Spec, ud.Spec.TopologySpread, selectors)
 
 	if !PoolHasPatch(poolConfig, set) {
 		klog.Infof("Deployment[%s/%s-] has no patches, do not need strategicmerge", set.Namespace,
@@ -35,6 +35,7 @@ import (
 
 	alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
 	clientutil "github.com/bhojpur/dcp/pkg/appmanager/controller/util"
+	kubeutil "github.com/bhojpur/dcp/pkg/appmanager/util/kubernetes"
 	"github.com/bhojpur/dcp/pkg/appmanager/util/refmanager"
 )
 
@@ -69,9 +70,15 @@ func (a *StatefulSetAdapter) GetDetails(obj metav1.Object) (ReplicasInfo, error)
 	if set.Spec.Replicas != nil {
 		specReplicas = *set.Spec.Replicas
 	}
+	var partition int32
+	if set.Spec.UpdateStrategy.RollingUpdate != nil && set.Spec.UpdateStrategy.RollingUpdate.Partition != nil {
+		partition = *set.Spec.UpdateStrategy.RollingUpdate.Partition
+	}
 	replicasInfo := ReplicasInfo{
-		Replicas:      specReplicas,
-		ReadyReplicas: set.Status.ReadyReplicas,
+		Replicas:        specReplicas,
+		ReadyReplicas:   set.Status.ReadyReplicas,
+		UpdatedReplicas: set.Status.UpdatedReplicas,
+		Partition:       partition,
 	}
 
 	return replicasInfo, nil
@@ -147,6 +154,7 @@ func (a *StatefulSetAdapter) ApplyPoolTemplate(ud *alpha1.UnitedDeployment, pool
 	set.Spec.VolumeClaimTemplates = ud.Spec.WorkloadTemplate.StatefulSetTemplate.Spec.VolumeClaimTemplates
 
 	attachNodeAffinityAndTolerations(&set.Spec.Template.Spec, poolConfig)
+	kubeutil.SetPoolTopologySpread(&set.Spec.Template.Spec, ud.Spec.TopologySpread, selectors)
 
 	if !PoolHasPatch(poolConfig, set) {
 		klog.Infof("StatefulSet[%s/%s-] has no patches, do not need strategicmerge", set.Namespace,
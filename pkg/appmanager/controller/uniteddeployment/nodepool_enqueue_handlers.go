@@ -0,0 +1,90 @@
+package uniteddeployment
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	unitv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+)
+
+// EnqueueUnitedDeploymentForNodePool enqueues the UnitedDeployments sharing their Replicas
+// across a NodePool whenever the NodePool gains or loses all of its ready nodes.
+type EnqueueUnitedDeploymentForNodePool struct {
+	client client.Client
+}
+
+func (e *EnqueueUnitedDeploymentForNodePool) Create(event event.CreateEvent, limitingInterface workqueue.RateLimitingInterface) {
+	e.addUnitedDeploymentsToWorkQueue(event.Object.GetName(), limitingInterface)
+}
+
+func (e *EnqueueUnitedDeploymentForNodePool) Update(event event.UpdateEvent, limitingInterface workqueue.RateLimitingInterface) {
+	oldNp, ok := event.ObjectOld.(*unitv1alpha1.NodePool)
+	if !ok {
+		return
+	}
+	newNp, ok := event.ObjectNew.(*unitv1alpha1.NodePool)
+	if !ok {
+		return
+	}
+	if (oldNp.Status.ReadyNodeNum == 0) == (newNp.Status.ReadyNodeNum == 0) {
+		return
+	}
+	e.addUnitedDeploymentsToWorkQueue(newNp.GetName(), limitingInterface)
+}
+
+func (e *EnqueueUnitedDeploymentForNodePool) Delete(event event.DeleteEvent, limitingInterface workqueue.RateLimitingInterface) {
+	e.addUnitedDeploymentsToWorkQueue(event.Object.GetName(), limitingInterface)
+}
+
+func (e *EnqueueUnitedDeploymentForNodePool) Generic(event event.GenericEvent, limitingInterface workqueue.RateLimitingInterface) {
+	return
+}
+
+func (e *EnqueueUnitedDeploymentForNodePool) addUnitedDeploymentsToWorkQueue(poolName string, limitingInterface workqueue.RateLimitingInterface) {
+	uds := &unitv1alpha1.UnitedDeploymentList{}
+	if err := e.client.List(context.TODO(), uds); err != nil {
+		return
+	}
+
+	for _, ud := range uds.Items {
+		if ud.Spec.Replicas == nil {
+			continue
+		}
+		for _, pool := range ud.Spec.Topology.Pools {
+			if pool.Name == poolName {
+				limitingInterface.Add(reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: ud.GetName(), Namespace: ud.GetNamespace()},
+				})
+				break
+			}
+		}
+	}
+}
+
+var _ handler.EventHandler = &EnqueueUnitedDeploymentForNodePool{}
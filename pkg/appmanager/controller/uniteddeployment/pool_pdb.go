@@ -0,0 +1,53 @@
+package uniteddeployment
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	unitv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+	kubeutil "github.com/bhojpur/dcp/pkg/appmanager/util/kubernetes"
+)
+
+// manageDisruptionBudgets creates or updates a PodDisruptionBudget for every existing pool
+// of the UnitedDeployment, and deletes the budgets of the pools which are gone.
+func (r *ReconcileUnitedDeployment) manageDisruptionBudgets(ud *unitv1alpha1.UnitedDeployment, nameToPool map[string]*Pool,
+	nextPatches map[string]UnitedDeploymentPatches, poolType unitv1alpha1.TemplateType) error {
+	keep := sets.NewString()
+	if ud.Spec.DisruptionBudget != nil {
+		for poolName, patch := range nextPatches {
+			pool, ok := nameToPool[poolName]
+			if !ok {
+				continue
+			}
+			replicas := patch.Replicas
+			if poolType == unitv1alpha1.DaemonSetTemplateType {
+				replicas = pool.Status.Replicas
+			}
+			pdb := kubeutil.NewPoolDisruptionBudget(pool.Spec.PoolRef, getPoolSelector(ud, poolName), ud.Spec.DisruptionBudget, replicas)
+			if err := kubeutil.ApplyPoolDisruptionBudget(r.Client, r.scheme, ud, pdb); err != nil {
+				return err
+			}
+			keep.Insert(pdb.Name)
+		}
+	}
+	return kubeutil.CleanupPoolDisruptionBudgets(r.Client, ud, ud.Spec.Selector, keep)
+}
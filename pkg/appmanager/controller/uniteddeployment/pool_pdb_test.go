@@ -0,0 +1,112 @@
+package uniteddeployment
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	unitv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+)
+
+func TestManageDisruptionBudgets(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = unitv1alpha1.AddToScheme(scheme)
+
+	minAvailable := intstr.FromInt(3)
+	ud := &unitv1alpha1.UnitedDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "ud", Namespace: "default", UID: "ud-uid"},
+		Spec: unitv1alpha1.UnitedDeploymentSpec{
+			Selector:         &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}},
+			DisruptionBudget: &unitv1alpha1.PoolDisruptionBudget{MinAvailable: &minAvailable},
+		},
+	}
+	stale := &policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{
+		Name:      "ud-gone-abcde",
+		Namespace: "default",
+		Labels:    map[string]string{"app": "demo", unitv1alpha1.PoolNameLabelKey: "gone"},
+	}}
+	if err := controllerutil.SetControllerReference(ud, stale, scheme); err != nil {
+		t.Fatalf("failed to set the owner of the stale budget: %v", err)
+	}
+
+	r := &ReconcileUnitedDeployment{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(stale).Build(),
+		scheme: scheme,
+	}
+	nameToPool := map[string]*Pool{
+		"beijing":  {Name: "beijing", Namespace: "default", Spec: PoolSpec{PoolRef: &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "ud-beijing-abcde", Namespace: "default"}}}},
+		"hangzhou": {Name: "hangzhou", Namespace: "default", Spec: PoolSpec{PoolRef: &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "ud-hangzhou-abcde", Namespace: "default"}}}},
+	}
+	nextPatches := map[string]UnitedDeploymentPatches{
+		"beijing":  {Replicas: 2},
+		"hangzhou": {Replicas: 5},
+		"shanghai": {Replicas: 1},
+	}
+
+	if err := r.manageDisruptionBudgets(ud, nameToPool, nextPatches, unitv1alpha1.DeploymentTemplateType); err != nil {
+		t.Fatalf("failed to manage the budgets: %v", err)
+	}
+
+	expect := map[string]int{"ud-beijing-abcde": 2, "ud-hangzhou-abcde": 3}
+	pdbList := &policyv1.PodDisruptionBudgetList{}
+	if err := r.List(context.TODO(), pdbList, client.InNamespace("default")); err != nil {
+		t.Fatalf("failed to list the budgets: %v", err)
+	}
+	if len(pdbList.Items) != len(expect) {
+		t.Fatalf("expect %d budgets, but got %d", len(expect), len(pdbList.Items))
+	}
+	for _, pdb := range pdbList.Items {
+		if !metav1.IsControlledBy(&pdb, ud) {
+			t.Fatalf("budget %s is not controlled by the UnitedDeployment", pdb.Name)
+		}
+		if pdb.Spec.MinAvailable == nil || pdb.Spec.MinAvailable.IntValue() != expect[pdb.Name] {
+			t.Fatalf("expect minAvailable %d of budget %s, but got %v", expect[pdb.Name], pdb.Name, pdb.Spec.MinAvailable)
+		}
+		if pdb.Spec.Selector.MatchLabels[unitv1alpha1.PoolNameLabelKey] != pdb.Labels[unitv1alpha1.PoolNameLabelKey] {
+			t.Fatalf("budget %s does not select the pods of its pool", pdb.Name)
+		}
+	}
+
+	ud.Spec.DisruptionBudget = nil
+	if err := r.manageDisruptionBudgets(ud, nameToPool, nextPatches, unitv1alpha1.DeploymentTemplateType); err != nil {
+		t.Fatalf("failed to manage the budgets: %v", err)
+	}
+	for name := range expect {
+		err := r.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: name}, &policyv1.PodDisruptionBudget{})
+		if !apierrors.IsNotFound(err) {
+			t.Fatalf("expect budget %s to be deleted, but got %v", name, err)
+		}
+	}
+}
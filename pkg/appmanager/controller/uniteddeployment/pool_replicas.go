@@ -0,0 +1,223 @@
+package uniteddeployment
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	unitv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+)
+
+// getUnavailablePools returns the pools whose NodePool exists but has no ready nodes.
+// Pools without a NodePool of the same name are always considered available.
+func getUnavailablePools(c client.Client, ud *unitv1alpha1.UnitedDeployment) (sets.String, error) {
+	unavailable := sets.String{}
+	for _, pool := range ud.Spec.Topology.Pools {
+		np := &unitv1alpha1.NodePool{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Name: pool.Name}, np); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		if np.Status.ReadyNodeNum == 0 {
+			unavailable.Insert(pool.Name)
+		}
+	}
+	return unavailable, nil
+}
+
+// weightedPool is a pool sharing the UnitedDeployment replicas by weight.
+type weightedPool struct {
+	name     string
+	weight   int64
+	min      int32
+	max      int32
+	replicas int32
+}
+
+// allocatePoolReplicas computes the replicas of every pool from the total replicas of the
+// UnitedDeployment. Pools setting Replicas keep them, the rest is shared between the other pools
+// in proportion to their weight, within their MinReplicas and MaxReplicas. Unavailable pools only
+// get their MinReplicas unless every weighted pool is unavailable. It returns nil if the
+// UnitedDeployment does not set Replicas.
+func allocatePoolReplicas(ud *unitv1alpha1.UnitedDeployment, unavailable sets.String) map[string]int32 {
+	if ud.Spec.Replicas == nil {
+		return nil
+	}
+
+	allocated := map[string]int32{}
+	remaining := *ud.Spec.Replicas
+	var weighted []*weightedPool
+	for _, pool := range ud.Spec.Topology.Pools {
+		if pool.Replicas != nil {
+			allocated[pool.Name] = *pool.Replicas
+			remaining -= *pool.Replicas
+			continue
+		}
+		wp := &weightedPool{name: pool.Name, weight: 1, max: -1}
+		if pool.Weight != nil {
+			wp.weight = int64(*pool.Weight)
+		}
+		if pool.MinReplicas != nil {
+			wp.min = *pool.MinReplicas
+		}
+		if pool.MaxReplicas != nil {
+			wp.max = *pool.MaxReplicas
+		}
+		weighted = append(weighted, wp)
+	}
+
+	// the replicas of unavailable pools only move when there is somewhere to move them to
+	available := 0
+	for _, wp := range weighted {
+		if !unavailable.Has(wp.name) {
+			available++
+		}
+	}
+	if available > 0 {
+		for _, wp := range weighted {
+			if unavailable.Has(wp.name) {
+				wp.weight = 0
+			}
+		}
+	}
+
+	// every pool gets its lower bound first, in the order of the topology
+	for _, wp := range weighted {
+		wp.replicas = wp.min
+		if remaining < wp.min {
+			wp.replicas = maxInt32(remaining, 0)
+		}
+		remaining -= wp.replicas
+	}
+
+	for remaining > 0 {
+		var growable []*weightedPool
+		var totalWeight int64
+		for _, wp := range weighted {
+			if wp.weight > 0 && (wp.max < 0 || wp.replicas < wp.max) {
+				growable = append(growable, wp)
+				totalWeight += wp.weight
+			}
+		}
+		if len(growable) == 0 {
+			break
+		}
+		remaining = distributeByWeight(growable, totalWeight, remaining)
+	}
+
+	for _, wp := range weighted {
+		allocated[wp.name] = wp.replicas
+	}
+	return allocated
+}
+
+// distributeByWeight hands out replicas to the pools by the largest remainder method,
+// capping every pool at its MaxReplicas, and returns the replicas left over by the caps.
+func distributeByWeight(pools []*weightedPool, totalWeight int64, replicas int32) int32 {
+	type share struct {
+		pool      *weightedPool
+		remainder int64
+	}
+	shares := make([]share, 0, len(pools))
+	var given int32
+	for _, wp := range pools {
+		quota := int64(replicas) * wp.weight
+		n := int32(quota / totalWeight)
+		given += n
+		shares = append(shares, share{pool: wp, remainder: quota % totalWeight})
+		wp.replicas += n
+	}
+	sort.SliceStable(shares, func(i, j int) bool {
+		return shares[i].remainder > shares[j].remainder
+	})
+	for i := 0; given < replicas; i++ {
+		shares[i].pool.replicas++
+		given++
+	}
+
+	var left int32
+	for _, wp := range pools {
+		if wp.max >= 0 && wp.replicas > wp.max {
+			left += wp.replicas - wp.max
+			wp.replicas = wp.max
+		}
+	}
+	return left
+}
+
+func maxInt32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// requiredPoolReplicas returns the replicas the pools need at least: the fixed Replicas of
+// some pools plus the MinReplicas of the others.
+func requiredPoolReplicas(ud *unitv1alpha1.UnitedDeployment) int32 {
+	var required int32
+	for _, pool := range ud.Spec.Topology.Pools {
+		if pool.Replicas != nil {
+			required += *pool.Replicas
+		} else if pool.MinReplicas != nil {
+			required += *pool.MinReplicas
+		}
+	}
+	return required
+}
+
+// setReplicasInsufficientCondition records that the replicas of the UnitedDeployment, which
+// may have been scaled after admission, can not satisfy the fixed replicas and the MinReplicas
+// of its pools.
+func setReplicasInsufficientCondition(status *unitv1alpha1.UnitedDeploymentStatus, ud *unitv1alpha1.UnitedDeployment, poolReplicas map[string]int32) {
+	if poolReplicas == nil {
+		RemoveUnitedDeploymentCondition(status, unitv1alpha1.ReplicasInsufficient)
+		return
+	}
+	required := requiredPoolReplicas(ud)
+	if required <= *ud.Spec.Replicas {
+		RemoveUnitedDeploymentCondition(status, unitv1alpha1.ReplicasInsufficient)
+		return
+	}
+	SetUnitedDeploymentCondition(status, NewUnitedDeploymentCondition(unitv1alpha1.ReplicasInsufficient, corev1.ConditionTrue,
+		"BelowPoolMinimum", fmt.Sprintf("replicas %d are fewer than the %d required by the fixed replicas and minReplicas of the pools",
+			*ud.Spec.Replicas, required)))
+}
+
+// setPoolsRebalancedCondition records which unavailable pools had their replicas moved to other pools.
+func setPoolsRebalancedCondition(status *unitv1alpha1.UnitedDeploymentStatus, poolReplicas map[string]int32, unavailable sets.String) {
+	if poolReplicas == nil || unavailable.Len() == 0 {
+		RemoveUnitedDeploymentCondition(status, unitv1alpha1.PoolsRebalanced)
+		return
+	}
+	SetUnitedDeploymentCondition(status, NewUnitedDeploymentCondition(unitv1alpha1.PoolsRebalanced, corev1.ConditionTrue,
+		"NoReadyNodes", fmt.Sprintf("NodePools %v have no ready nodes", unavailable.List())))
+}
@@ -0,0 +1,161 @@
+package uniteddeployment
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	utilpointer "k8s.io/utils/pointer"
+
+	unitv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+)
+
+func TestAllocatePoolReplicas(t *testing.T) {
+	tests := []struct {
+		name        string
+		replicas    *int32
+		pools       []unitv1alpha1.Pool
+		unavailable []string
+		expect      map[string]int32
+	}{
+		{
+			name:  "replicas unset",
+			pools: []unitv1alpha1.Pool{{Name: "a", Replicas: utilpointer.Int32Ptr(2)}},
+		},
+		{
+			name:     "even weights",
+			replicas: utilpointer.Int32Ptr(7),
+			pools:    []unitv1alpha1.Pool{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+			expect:   map[string]int32{"a": 3, "b": 2, "c": 2},
+		},
+		{
+			name:     "weights and fixed replicas",
+			replicas: utilpointer.Int32Ptr(10),
+			pools: []unitv1alpha1.Pool{
+				{Name: "a", Replicas: utilpointer.Int32Ptr(2)},
+				{Name: "b", Weight: utilpointer.Int32Ptr(3)},
+				{Name: "c", Weight: utilpointer.Int32Ptr(1)},
+			},
+			expect: map[string]int32{"a": 2, "b": 6, "c": 2},
+		},
+		{
+			name:     "min and max replicas",
+			replicas: utilpointer.Int32Ptr(10),
+			pools: []unitv1alpha1.Pool{
+				{Name: "a", MaxReplicas: utilpointer.Int32Ptr(2)},
+				{Name: "b", Weight: utilpointer.Int32Ptr(0), MinReplicas: utilpointer.Int32Ptr(3)},
+				{Name: "c"},
+			},
+			expect: map[string]int32{"a": 2, "b": 3, "c": 5},
+		},
+		{
+			name:     "all pools at max",
+			replicas: utilpointer.Int32Ptr(10),
+			pools: []unitv1alpha1.Pool{
+				{Name: "a", MaxReplicas: utilpointer.Int32Ptr(2)},
+				{Name: "b", MaxReplicas: utilpointer.Int32Ptr(3)},
+			},
+			expect: map[string]int32{"a": 2, "b": 3},
+		},
+		{
+			name:     "rebalance unavailable pool",
+			replicas: utilpointer.Int32Ptr(6),
+			pools: []unitv1alpha1.Pool{
+				{Name: "a", MinReplicas: utilpointer.Int32Ptr(1)},
+				{Name: "b"},
+				{Name: "c"},
+			},
+			unavailable: []string{"a"},
+			expect:      map[string]int32{"a": 1, "b": 3, "c": 2},
+		},
+		{
+			name:        "every pool unavailable",
+			replicas:    utilpointer.Int32Ptr(4),
+			pools:       []unitv1alpha1.Pool{{Name: "a"}, {Name: "b"}},
+			unavailable: []string{"a", "b"},
+			expect:      map[string]int32{"a": 2, "b": 2},
+		},
+		{
+			name:     "min replicas exceed replicas",
+			replicas: utilpointer.Int32Ptr(3),
+			pools: []unitv1alpha1.Pool{
+				{Name: "a", MinReplicas: utilpointer.Int32Ptr(2)},
+				{Name: "b", MinReplicas: utilpointer.Int32Ptr(2)},
+			},
+			expect: map[string]int32{"a": 2, "b": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ud := &unitv1alpha1.UnitedDeployment{
+				Spec: unitv1alpha1.UnitedDeploymentSpec{
+					Replicas: tt.replicas,
+					Topology: unitv1alpha1.Topology{Pools: tt.pools},
+				},
+			}
+			got := allocatePoolReplicas(ud, sets.NewString(tt.unavailable...))
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Fatalf("expected pool replicas %v, got %v", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestGetNextPatchesPoolReplicas(t *testing.T) {
+	ud := &unitv1alpha1.UnitedDeployment{
+		Spec: unitv1alpha1.UnitedDeploymentSpec{
+			Topology: unitv1alpha1.Topology{Pools: []unitv1alpha1.Pool{
+				{Name: "a", Replicas: utilpointer.Int32Ptr(2)},
+				{Name: "b", Replicas: utilpointer.Int32Ptr(2)},
+			}},
+		},
+	}
+	next := GetNextPatches(ud, map[string]int32{"b": 5})
+	if next["a"].Replicas != 2 || next["b"].Replicas != 5 {
+		t.Fatalf("unexpected next patches %v", next)
+	}
+}
+
+func TestSetReplicasInsufficientCondition(t *testing.T) {
+	ud := &unitv1alpha1.UnitedDeployment{
+		Spec: unitv1alpha1.UnitedDeploymentSpec{
+			Replicas: utilpointer.Int32Ptr(3),
+			Topology: unitv1alpha1.Topology{Pools: []unitv1alpha1.Pool{
+				{Name: "a", Replicas: utilpointer.Int32Ptr(3)},
+				{Name: "b", MinReplicas: utilpointer.Int32Ptr(1)},
+			}},
+		},
+	}
+	status := &unitv1alpha1.UnitedDeploymentStatus{}
+	setReplicasInsufficientCondition(status, ud, allocatePoolReplicas(ud, sets.NewString()))
+	if GetUnitedDeploymentCondition(*status, unitv1alpha1.ReplicasInsufficient) == nil {
+		t.Fatalf("expected condition %s, got %v", unitv1alpha1.ReplicasInsufficient, status.Conditions)
+	}
+
+	ud.Spec.Replicas = utilpointer.Int32Ptr(4)
+	setReplicasInsufficientCondition(status, ud, allocatePoolReplicas(ud, sets.NewString()))
+	if c := GetUnitedDeploymentCondition(*status, unitv1alpha1.ReplicasInsufficient); c != nil {
+		t.Fatalf("expected no condition %s, got %v", unitv1alpha1.ReplicasInsufficient, c)
+	}
+}
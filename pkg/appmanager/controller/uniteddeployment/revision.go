@@ -30,6 +30,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"k8s.io/klog"
 	"k8s.io/kubernetes/pkg/controller/history"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -234,6 +235,8 @@ func (r *ReconcileUnitedDeployment) newRevision(ud *appsalphav1.UnitedDeployment
 		selectedLabels = ud.Spec.WorkloadTemplate.StatefulSetTemplate.Labels
 	case ud.Spec.WorkloadTemplate.DeploymentTemplate != nil:
 		selectedLabels = ud.Spec.WorkloadTemplate.DeploymentTemplate.Labels
+	case ud.Spec.WorkloadTemplate.DaemonSetTemplate != nil:
+		selectedLabels = ud.Spec.WorkloadTemplate.DaemonSetTemplate.Labels
 	default:
 		klog.Errorf("UnitedDeployment(%s/%s) need specific WorkloadTemplate", ud.GetNamespace(), ud.GetName())
 		return nil, fmt.Errorf("UnitedDeployment(%s/%s) need specific WorkloadTemplate", ud.GetNamespace(), ud.GetName())
@@ -253,6 +256,25 @@ func (r *ReconcileUnitedDeployment) newRevision(ud *appsalphav1.UnitedDeployment
 	return cr, nil
 }
 
+// applyRevision returns a new UnitedDeployment constructed by restoring the workload template
+// saved in revision onto ud. The rest of ud, such as its topology, is kept.
+func applyRevision(ud *appsalphav1.UnitedDeployment, revision *apps.ControllerRevision) (*appsalphav1.UnitedDeployment, error) {
+	clone := ud.DeepCopy()
+	original, err := json.Marshal(clone)
+	if err != nil {
+		return nil, err
+	}
+	patched, err := strategicpatch.StrategicMergePatch(original, revision.Data.Raw, clone)
+	if err != nil {
+		return nil, err
+	}
+	restored := &appsalphav1.UnitedDeployment{}
+	if err = json.Unmarshal(patched, restored); err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
 // nextRevision finds the next valid revision number based on revisions. If the length of revisions
 // is 0 this is 1. Otherwise, it is 1 greater than the largest revision's Revision. This method
 // assumes that revisions has been sorted by Revision.
@@ -28,8 +28,11 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -56,11 +59,13 @@ var (
 const (
 	controllerName = "uniteddeployment-controller"
 
-	eventTypeRevisionProvision  = "RevisionProvision"
-	eventTypeFindPools          = "FindPools"
-	eventTypeDupPoolsDelete     = "DeleteDuplicatedPools"
-	eventTypePoolsUpdate        = "UpdatePool"
-	eventTypeTemplateController = "TemplateController"
+	eventTypeRevisionProvision       = "RevisionProvision"
+	eventTypeFindPools               = "FindPools"
+	eventTypeDupPoolsDelete          = "DeleteDuplicatedPools"
+	eventTypePoolsUpdate             = "UpdatePool"
+	eventTypePoolsRollback           = "RollbackPool"
+	eventTypeTemplateController      = "TemplateController"
+	eventTypeDisruptionBudgetsUpdate = "UpdateDisruptionBudget"
 
 	slowStartInitialBatchSize = 1
 )
@@ -86,6 +91,8 @@ func newReconciler(mgr manager.Manager) reconcile.Reconciler {
 				adapter: &adapter.StatefulSetAdapter{Client: mgr.GetClient(), Scheme: mgr.GetScheme()}},
 			unitv1alpha1.DeploymentTemplateType: &PoolControl{Client: mgr.GetClient(), scheme: mgr.GetScheme(),
 				adapter: &adapter.DeploymentAdapter{Client: mgr.GetClient(), Scheme: mgr.GetScheme()}},
+			unitv1alpha1.DaemonSetTemplateType: &PoolControl{Client: mgr.GetClient(), scheme: mgr.GetScheme(),
+				adapter: &adapter.DaemonSetAdapter{Client: mgr.GetClient(), Scheme: mgr.GetScheme()}},
 		},
 	}
 }
@@ -120,6 +127,27 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	err = c.Watch(&source.Kind{Type: &appsv1.DaemonSet{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &unitv1alpha1.UnitedDeployment{},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = c.Watch(&source.Kind{Type: &policyv1.PodDisruptionBudget{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &unitv1alpha1.UnitedDeployment{},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = c.Watch(&source.Kind{Type: &unitv1alpha1.NodePool{}}, &EnqueueUnitedDeploymentForNodePool{client: mgr.GetClient()})
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -136,10 +164,14 @@ type ReconcileUnitedDeployment struct {
 
 // +kubebuilder:rbac:groups=apps.bhojpur.net,resources=uniteddeployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps.bhojpur.net,resources=uniteddeployments/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=apps.bhojpur.net,resources=nodepools,verbs=get;list;watch
 // +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=statefulsets/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=deployments/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=daemonsets/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=controllerrevisions,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
@@ -187,20 +219,37 @@ func (r *ReconcileUnitedDeployment) Reconcile(_ context.Context, request reconci
 		return reconcile.Result{}, nil
 	}
 
-	nextPatches := GetNextPatches(instance)
+	var poolReplicas map[string]int32
+	var unavailable sets.String
+	if instance.Spec.Replicas != nil && poolType != unitv1alpha1.DaemonSetTemplateType {
+		unavailable, err = getUnavailablePools(r.Client, instance)
+		if err != nil {
+			klog.Errorf("Fail to get NodePools of UnitedDeployment %s/%s: %s", instance.Namespace, instance.Name, err)
+			return reconcile.Result{}, err
+		}
+		poolReplicas = allocatePoolReplicas(instance, unavailable)
+	}
+
+	nextPatches := GetNextPatches(instance, poolReplicas)
 	klog.V(4).Infof("Get UnitedDeployment %s/%s next Patches %v", instance.Namespace, instance.Name, nextPatches)
 
-	expectedRevision := currentRevision
-	if updatedRevision != nil {
-		expectedRevision = updatedRevision
-	}
-	newStatus, err := r.managePools(instance, nameToPool, nextPatches, expectedRevision, poolType)
+	newStatus, requeueAfter, err := r.managePools(instance, nameToPool, nextPatches, currentRevision, updatedRevision, poolType)
 	if err != nil {
 		klog.Errorf("Fail to update UnitedDeployment %s/%s: %s", instance.Namespace, instance.Name, err)
 		r.recorder.Event(instance.DeepCopy(), corev1.EventTypeWarning, fmt.Sprintf("Failed%s", eventTypePoolsUpdate), err.Error())
 	}
+	if err := r.manageDisruptionBudgets(instance, nameToPool, nextPatches, poolType); err != nil {
+		klog.Errorf("Fail to manage PodDisruptionBudgets of UnitedDeployment %s/%s: %s", instance.Namespace, instance.Name, err)
+		r.recorder.Event(instance.DeepCopy(), corev1.EventTypeWarning, fmt.Sprintf("Failed%s", eventTypeDisruptionBudgetsUpdate), err.Error())
+	}
+	setPoolsRebalancedCondition(newStatus, poolReplicas, unavailable)
+	setReplicasInsufficientCondition(newStatus, instance, poolReplicas)
 
-	return r.updateStatus(instance, newStatus, oldStatus, nameToPool, currentRevision, collisionCount, control)
+	result, err := r.updateStatus(instance, newStatus, oldStatus, nameToPool, currentRevision, collisionCount, control)
+	if err == nil && requeueAfter > 0 {
+		result.RequeueAfter = requeueAfter
+	}
+	return result, err
 }
 
 func (r *ReconcileUnitedDeployment) getNameToPool(instance *unitv1alpha1.UnitedDeployment, control ControlInterface) (map[string]*Pool, error) {
@@ -253,10 +302,12 @@ func (r *ReconcileUnitedDeployment) getPoolControls(instance *unitv1alpha1.Unite
 		return r.poolControls[unitv1alpha1.StatefulSetTemplateType], unitv1alpha1.StatefulSetTemplateType, nil
 	case instance.Spec.WorkloadTemplate.DeploymentTemplate != nil:
 		return r.poolControls[unitv1alpha1.DeploymentTemplateType], unitv1alpha1.DeploymentTemplateType, nil
+	case instance.Spec.WorkloadTemplate.DaemonSetTemplate != nil:
+		return r.poolControls[unitv1alpha1.DaemonSetTemplateType], unitv1alpha1.DaemonSetTemplateType, nil
 	default:
 		klog.Errorf("The appropriate WorkloadTemplate was not found")
-		return nil, "", fmt.Errorf("The appropriate WorkloadTemplate was not found, Now Support(%s/%s)",
-			unitv1alpha1.StatefulSetTemplateType, unitv1alpha1.DeploymentTemplateType)
+		return nil, "", fmt.Errorf("The appropriate WorkloadTemplate was not found, Now Support(%s/%s/%s)",
+			unitv1alpha1.StatefulSetTemplateType, unitv1alpha1.DeploymentTemplateType, unitv1alpha1.DaemonSetTemplateType)
 	}
 }
 
@@ -302,6 +353,9 @@ func (r *ReconcileUnitedDeployment) calculateStatus(instance *unitv1alpha1.Unite
 	}
 
 	newStatus.TemplateType = getPoolTemplateType(instance)
+	if selector, err := metav1.LabelSelectorAsSelector(instance.Spec.Selector); err == nil {
+		newStatus.Selector = selector.String()
+	}
 
 	var poolFailure *string
 	for _, pool := range nameToPool {
@@ -328,6 +382,8 @@ func getPoolTemplateType(obj *unitv1alpha1.UnitedDeployment) (templateType unitv
 		templateType = unitv1alpha1.StatefulSetTemplateType
 	case template.DeploymentTemplate != nil:
 		templateType = unitv1alpha1.DeploymentTemplateType
+	case template.DaemonSetTemplate != nil:
+		templateType = unitv1alpha1.DaemonSetTemplateType
 	default:
 		klog.Warning("UnitedDeployment.Spec.WorkloadTemplate exist wrong template")
 	}
@@ -339,9 +395,11 @@ func (r *ReconcileUnitedDeployment) updateUnitedDeployment(ud *unitv1alpha1.Unit
 		oldStatus.CollisionCount == newStatus.CollisionCount &&
 		oldStatus.Replicas == newStatus.Replicas &&
 		oldStatus.ReadyReplicas == newStatus.ReadyReplicas &&
+		oldStatus.Selector == newStatus.Selector &&
 		ud.Generation == newStatus.ObservedGeneration &&
 		reflect.DeepEqual(oldStatus.PoolReplicas, newStatus.PoolReplicas) &&
-		reflect.DeepEqual(oldStatus.Conditions, newStatus.Conditions) {
+		reflect.DeepEqual(oldStatus.Conditions, newStatus.Conditions) &&
+		reflect.DeepEqual(oldStatus.UpdateStatus, newStatus.UpdateStatus) {
 		return ud, nil
 	}
 
@@ -48,6 +48,16 @@ func getPoolNameFrom(metaObj metav1.Object) (string, error) {
 	return name, nil
 }
 
+// getPoolSelector returns the pod selector of the workload of a pool.
+func getPoolSelector(ud *unitv1alpha1.UnitedDeployment, poolName string) *metav1.LabelSelector {
+	selector := ud.Spec.Selector.DeepCopy()
+	if selector.MatchLabels == nil {
+		selector.MatchLabels = map[string]string{}
+	}
+	selector.MatchLabels[unitv1alpha1.PoolNameLabelKey] = poolName
+	return selector
+}
+
 // NewUnitedDeploymentCondition creates a new UnitedDeployment condition.
 func NewUnitedDeploymentCondition(condType unitv1alpha1.UnitedDeploymentConditionType, status corev1.ConditionStatus, reason, message string) *unitv1alpha1.UnitedDeploymentCondition {
 	return &unitv1alpha1.UnitedDeploymentCondition{
@@ -85,6 +95,20 @@ func SetUnitedDeploymentCondition(status *unitv1alpha1.UnitedDeploymentStatus, c
 	status.Conditions = append(newConditions, *condition)
 }
 
+// setPoolUpdateCondition updates the rollout conditions of a pool the same way SetUnitedDeploymentCondition does.
+func setPoolUpdateCondition(status *unitv1alpha1.PoolUpdateStatus, condition *unitv1alpha1.UnitedDeploymentCondition) {
+	for _, c := range status.Conditions {
+		if c.Type != condition.Type || c.Status != condition.Status {
+			continue
+		}
+		if c.Reason == condition.Reason {
+			return
+		}
+		condition.LastTransitionTime = c.LastTransitionTime
+	}
+	status.Conditions = append(filterOutCondition(status.Conditions, condition.Type), *condition)
+}
+
 // RemoveUnitedDeploymentCondition removes the UnitedDeployment condition with the provided type.
 func RemoveUnitedDeploymentCondition(status *unitv1alpha1.UnitedDeploymentStatus, condType unitv1alpha1.UnitedDeploymentConditionType) {
 	status.Conditions = filterOutCondition(status.Conditions, condType)
@@ -101,11 +125,15 @@ func filterOutCondition(conditions []unitv1alpha1.UnitedDeploymentCondition, con
 	return newConditions
 }
 
-func GetNextPatches(ud *unitv1alpha1.UnitedDeployment) map[string]UnitedDeploymentPatches {
+// GetNextPatches returns the replicas and patch of every pool. poolReplicas, if not nil,
+// holds the replicas computed from the UnitedDeployment Replicas and takes precedence.
+func GetNextPatches(ud *unitv1alpha1.UnitedDeployment, poolReplicas map[string]int32) map[string]UnitedDeploymentPatches {
 	next := make(map[string]UnitedDeploymentPatches)
 	for _, pool := range ud.Spec.Topology.Pools {
 		t := UnitedDeploymentPatches{}
-		if pool.Replicas != nil {
+		if replicas, ok := poolReplicas[pool.Name]; ok {
+			t.Replicas = replicas
+		} else if pool.Replicas != nil {
 			t.Replicas = *pool.Replicas
 		}
 		if pool.Patch != nil {
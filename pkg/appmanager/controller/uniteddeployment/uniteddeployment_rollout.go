@@ -0,0 +1,342 @@
+package uniteddeployment
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	unitv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+)
+
+const (
+	// reasons of the PoolUpdated condition recorded for every pool of a rollout
+	poolUpdatePending    = "Pending"
+	poolUpdateUpdating   = "Updating"
+	poolUpdateBaking     = "Baking"
+	poolUpdateUpdated    = "Updated"
+	poolUpdateRolledBack = "RolledBack"
+
+	defaultMinReadyPercent = 100
+)
+
+// poolTarget is the revision, and the partition of it, a pool is expected to run.
+type poolTarget struct {
+	Revision  string
+	Partition int32
+}
+
+// stagePool is a pool of a rollout stage.
+type stagePool struct {
+	name      string
+	partition int32
+}
+
+// rolloutPlan is the outcome of planRollout.
+type rolloutPlan struct {
+	// targets of every pool in the topology
+	targets map[string]poolTarget
+	// requeueAfter is the time after which the rollout should be checked again, zero if it need not.
+	requeueAfter time.Duration
+	// rolledBack are the pools rolled back by this plan.
+	rolledBack []string
+}
+
+func (p *rolloutPlan) requeue(d time.Duration) {
+	if d > 0 && (p.requeueAfter == 0 || d < p.requeueAfter) {
+		p.requeueAfter = d
+	}
+}
+
+// rolloutStages returns the pool batches the updated revision goes through. The last stage
+// fully updates every pool which was not listed, or whose last listed stage kept a partition.
+func rolloutStages(ud *unitv1alpha1.UnitedDeployment) [][]stagePool {
+	inTopology := map[string]bool{}
+	for _, pool := range ud.Spec.Topology.Pools {
+		inTopology[pool.Name] = true
+	}
+
+	var stages [][]stagePool
+	lastPartition := map[string]int32{}
+	strategy := ud.Spec.UpdateStrategy
+	if strategy.Type == unitv1alpha1.StagedUpdateStrategyType && strategy.Staged != nil {
+		for _, stage := range strategy.Staged.Stages {
+			var batch []stagePool
+			for _, pool := range stage.Pools {
+				if !inTopology[pool.Name] {
+					continue
+				}
+				var partition int32
+				// only StatefulSet pools are able to keep a part of their replicas at the previous revision
+				if pool.Partition != nil && ud.Spec.WorkloadTemplate.StatefulSetTemplate != nil {
+					partition = *pool.Partition
+				}
+				batch = append(batch, stagePool{name: pool.Name, partition: partition})
+				lastPartition[pool.Name] = partition
+			}
+			if len(batch) > 0 {
+				stages = append(stages, batch)
+			}
+		}
+	}
+
+	var final []stagePool
+	for _, pool := range ud.Spec.Topology.Pools {
+		if partition, listed := lastPartition[pool.Name]; !listed || partition > 0 {
+			final = append(final, stagePool{name: pool.Name})
+		}
+	}
+	if len(final) > 0 {
+		stages = append(stages, final)
+	}
+	return stages
+}
+
+// planRollout moves the rollout of updatedRevision forward, recording its progress in status, and
+// returns the revision every pool is expected to run. Pools the rollout has not reached yet, and
+// pools rolled back after failing the health gate, are kept at currentRevision.
+func planRollout(ud *unitv1alpha1.UnitedDeployment, nameToPool map[string]*Pool, currentRevision, updatedRevision string,
+	status *unitv1alpha1.UnitedDeploymentStatus, now time.Time) *rolloutPlan {
+
+	plan := &rolloutPlan{targets: map[string]poolTarget{}}
+	if currentRevision == updatedRevision {
+		if status.UpdateStatus != nil && status.UpdateStatus.UpdatedRevision != updatedRevision {
+			status.UpdateStatus = nil
+		}
+		RemoveUnitedDeploymentCondition(status, unitv1alpha1.RolloutPaused)
+		RemoveUnitedDeploymentCondition(status, unitv1alpha1.RolloutRolledBack)
+		for _, pool := range ud.Spec.Topology.Pools {
+			plan.targets[pool.Name] = poolTarget{Revision: updatedRevision}
+		}
+		return plan
+	}
+
+	st := status.UpdateStatus
+	if st == nil || st.UpdatedRevision != updatedRevision {
+		st = &unitv1alpha1.UnitedDeploymentUpdateStatus{UpdatedRevision: updatedRevision}
+		status.UpdateStatus = st
+		RemoveUnitedDeploymentCondition(status, unitv1alpha1.RolloutRolledBack)
+	}
+
+	// keep a record for every pool of the topology, in the topology order
+	pools := make([]unitv1alpha1.PoolUpdateStatus, 0, len(ud.Spec.Topology.Pools))
+	for _, pool := range ud.Spec.Topology.Pools {
+		ps := unitv1alpha1.PoolUpdateStatus{Name: pool.Name, Revision: currentRevision}
+		for i := range st.Pools {
+			if st.Pools[i].Name == pool.Name {
+				ps = st.Pools[i]
+				break
+			}
+		}
+		if len(ps.Conditions) == 0 {
+			setPoolUpdateCondition(&ps, NewUnitedDeploymentCondition(unitv1alpha1.PoolUpdated, corev1.ConditionFalse, poolUpdatePending, ""))
+		}
+		pools = append(pools, ps)
+	}
+	st.Pools = pools
+	records := map[string]*unitv1alpha1.PoolUpdateStatus{}
+	for i := range st.Pools {
+		records[st.Pools[i].Name] = &st.Pools[i]
+	}
+
+	stages := rolloutStages(ud)
+	var gate *unitv1alpha1.UpdateHealthGate
+	if ud.Spec.UpdateStrategy.Type == unitv1alpha1.StagedUpdateStrategyType && ud.Spec.UpdateStrategy.Staged != nil {
+		gate = ud.Spec.UpdateStrategy.Staged.HealthGate
+	}
+
+	paused := false
+	for !st.RolledBack && int(st.CurrentStage) < len(stages) {
+		stage := stages[st.CurrentStage]
+		if !stageStarted(stage, records, updatedRevision) {
+			if ud.Spec.UpdateStrategy.Paused {
+				paused = true
+				SetUnitedDeploymentCondition(status, NewUnitedDeploymentCondition(unitv1alpha1.RolloutPaused, corev1.ConditionTrue, "Paused",
+					fmt.Sprintf("rollout of revision %s is paused before stage %d", updatedRevision, st.CurrentStage)))
+				break
+			}
+			for _, sp := range stage {
+				ps := records[sp.name]
+				if ps.Revision == updatedRevision && ps.Partition == sp.partition {
+					continue
+				}
+				ps.Revision = updatedRevision
+				ps.Partition = sp.partition
+				ps.LastUpdateTime = &metav1.Time{Time: now}
+				ps.HealthySince = nil
+				setPoolUpdateCondition(ps, NewUnitedDeploymentCondition(unitv1alpha1.PoolUpdated, corev1.ConditionFalse, poolUpdateUpdating, ""))
+			}
+		}
+
+		var failed []string
+		passed := true
+		for _, sp := range stage {
+			ok, fail := checkHealthGate(gate, nameToPool[sp.name], records[sp.name], now, plan)
+			passed = passed && ok
+			if fail {
+				failed = append(failed, sp.name)
+			}
+		}
+
+		if len(failed) > 0 {
+			for _, name := range failed {
+				ps := records[name]
+				ps.Revision = currentRevision
+				ps.Partition = 0
+				ps.LastUpdateTime = &metav1.Time{Time: now}
+				ps.HealthySince = nil
+				setPoolUpdateCondition(ps, NewUnitedDeploymentCondition(unitv1alpha1.PoolUpdated, corev1.ConditionFalse, poolUpdateRolledBack,
+					fmt.Sprintf("health gate failed for revision %s", updatedRevision)))
+			}
+			st.RolledBack = true
+			plan.rolledBack = failed
+			SetUnitedDeploymentCondition(status, NewUnitedDeploymentCondition(unitv1alpha1.RolloutRolledBack, corev1.ConditionTrue, "HealthGateFailed",
+				fmt.Sprintf("pools %s failed the health gate of revision %s and were rolled back to revision %s",
+					strings.Join(failed, ","), updatedRevision, currentRevision)))
+			break
+		}
+		if !passed {
+			break
+		}
+		st.CurrentStage++
+	}
+
+	if !paused {
+		RemoveUnitedDeploymentCondition(status, unitv1alpha1.RolloutPaused)
+	}
+	if !st.RolledBack && int(st.CurrentStage) >= len(stages) {
+		status.CurrentRevision = updatedRevision
+	}
+
+	for name, ps := range records {
+		plan.targets[name] = poolTarget{Revision: ps.Revision, Partition: ps.Partition}
+	}
+	return plan
+}
+
+// stageStarted checks whether every pool of the stage has been moved to the updated revision.
+func stageStarted(stage []stagePool, records map[string]*unitv1alpha1.PoolUpdateStatus, updatedRevision string) bool {
+	for _, sp := range stage {
+		ps := records[sp.name]
+		if ps.Revision != updatedRevision || ps.Partition != sp.partition {
+			return false
+		}
+	}
+	return true
+}
+
+// checkHealthGate checks a pool of the stage in progress against the health gate. It returns
+// whether the pool passed, and whether it failed to pass within the progress deadline.
+func checkHealthGate(gate *unitv1alpha1.UpdateHealthGate, pool *Pool, ps *unitv1alpha1.PoolUpdateStatus,
+	now time.Time, plan *rolloutPlan) (passed, failed bool) {
+
+	minReadyPercent := int32(defaultMinReadyPercent)
+	var bake, deadline time.Duration
+	if gate != nil {
+		if gate.MinReadyPercent != nil {
+			minReadyPercent = *gate.MinReadyPercent
+		}
+		bake = time.Duration(gate.BakeSeconds) * time.Second
+		deadline = 600 * time.Second
+		if gate.ProgressDeadlineSeconds != nil {
+			deadline = time.Duration(*gate.ProgressDeadlineSeconds) * time.Second
+		}
+	}
+
+	if !isPoolHealthy(pool, ps, minReadyPercent) {
+		ps.HealthySince = nil
+		setPoolUpdateCondition(ps, NewUnitedDeploymentCondition(unitv1alpha1.PoolUpdated, corev1.ConditionFalse, poolUpdateUpdating, ""))
+		if deadline == 0 || ps.LastUpdateTime == nil {
+			return false, false
+		}
+		remaining := deadline - now.Sub(ps.LastUpdateTime.Time)
+		if remaining <= 0 {
+			return false, true
+		}
+		plan.requeue(remaining)
+		return false, false
+	}
+
+	if ps.HealthySince == nil {
+		ps.HealthySince = &metav1.Time{Time: now}
+	}
+	if remaining := bake - now.Sub(ps.HealthySince.Time); remaining > 0 {
+		setPoolUpdateCondition(ps, NewUnitedDeploymentCondition(unitv1alpha1.PoolUpdated, corev1.ConditionFalse, poolUpdateBaking, ""))
+		plan.requeue(remaining)
+		return false, false
+	}
+	setPoolUpdateCondition(ps, NewUnitedDeploymentCondition(unitv1alpha1.PoolUpdated, corev1.ConditionTrue, poolUpdateUpdated, ""))
+	return true, false
+}
+
+// isPoolHealthy checks whether the pool workload runs the revision recorded for it, with enough of its replicas ready.
+func isPoolHealthy(pool *Pool, ps *unitv1alpha1.PoolUpdateStatus, minReadyPercent int32) bool {
+	if pool == nil || pool.Spec.PoolRef == nil {
+		return false
+	}
+	if pool.Spec.PoolRef.GetLabels()[unitv1alpha1.ControllerRevisionHashLabelKey] != ps.Revision ||
+		pool.Status.Partition != ps.Partition ||
+		pool.Status.ObservedGeneration < pool.Spec.PoolRef.GetGeneration() {
+		return false
+	}
+
+	expectUpdated := pool.Status.Replicas - ps.Partition
+	if expectUpdated < 0 {
+		expectUpdated = 0
+	}
+	if pool.Status.UpdatedReplicas < expectUpdated {
+		return false
+	}
+	return pool.Status.ReadyReplicas*100 >= minReadyPercent*pool.Status.Replicas
+}
+
+// revisionedUnitedDeployment returns the UnitedDeployment a pool is provisioned from to run target.
+func revisionedUnitedDeployment(ud *unitv1alpha1.UnitedDeployment, currentRevision, updatedRevision *appsv1.ControllerRevision,
+	target poolTarget) (*unitv1alpha1.UnitedDeployment, error) {
+
+	obj := ud
+	if target.Revision != updatedRevision.Name {
+		if target.Revision != currentRevision.Name {
+			return nil, fmt.Errorf("unknown revision %s", target.Revision)
+		}
+		restored, err := applyRevision(ud, currentRevision)
+		if err != nil {
+			return nil, fmt.Errorf("fail to restore revision %s: %v", currentRevision.Name, err)
+		}
+		obj = restored
+	}
+
+	if target.Partition > 0 && obj.Spec.WorkloadTemplate.StatefulSetTemplate != nil {
+		if obj == ud {
+			obj = ud.DeepCopy()
+		}
+		partition := target.Partition
+		strategy := &obj.Spec.WorkloadTemplate.StatefulSetTemplate.Spec.UpdateStrategy
+		strategy.Type = appsv1.RollingUpdateStatefulSetStrategyType
+		strategy.RollingUpdate = &appsv1.RollingUpdateStatefulSetStrategy{Partition: &partition}
+	}
+	return obj, nil
+}
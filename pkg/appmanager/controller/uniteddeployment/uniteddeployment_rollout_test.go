@@ -0,0 +1,311 @@
+package uniteddeployment
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilpointer "k8s.io/utils/pointer"
+
+	unitv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+	"github.com/bhojpur/dcp/pkg/appmanager/controller/uniteddeployment/adapter"
+)
+
+func newRolloutUnitedDeployment(strategy unitv1alpha1.UnitedDeploymentUpdateStrategy, pools ...string) *unitv1alpha1.UnitedDeployment {
+	ud := &unitv1alpha1.UnitedDeployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec: unitv1alpha1.UnitedDeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"name": "foo"}},
+			WorkloadTemplate: unitv1alpha1.WorkloadTemplate{
+				StatefulSetTemplate: &unitv1alpha1.StatefulSetTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"name": "foo"}},
+					Spec: appsv1.StatefulSetSpec{
+						Template: corev1.PodTemplateSpec{
+							ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"name": "foo"}},
+							Spec: corev1.PodSpec{
+								Containers: []corev1.Container{{Name: "container-a", Image: "nginx:1.0"}},
+							},
+						},
+					},
+				},
+			},
+			UpdateStrategy: strategy,
+		},
+		Status: unitv1alpha1.UnitedDeploymentStatus{CurrentRevision: "v1"},
+	}
+	for _, name := range pools {
+		ud.Spec.Topology.Pools = append(ud.Spec.Topology.Pools, unitv1alpha1.Pool{Name: name, Replicas: utilpointer.Int32Ptr(3)})
+	}
+	return ud
+}
+
+// newRolloutPool returns a pool running revision, with updated of its 3 replicas at it and ready of them ready.
+func newRolloutPool(name, revision string, partition, updated, ready int32) *Pool {
+	set := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       name,
+			Generation: 2,
+			Labels:     map[string]string{unitv1alpha1.ControllerRevisionHashLabelKey: revision},
+		},
+	}
+	return &Pool{
+		Name: name,
+		Spec: PoolSpec{PoolRef: set},
+		Status: PoolStatus{
+			ObservedGeneration: 2,
+			ReplicasInfo: adapter.ReplicasInfo{
+				Replicas:        3,
+				ReadyReplicas:   ready,
+				UpdatedReplicas: updated,
+				Partition:       partition,
+			},
+		},
+	}
+}
+
+func expectTargets(t *testing.T, plan *rolloutPlan, expected map[string]poolTarget) {
+	t.Helper()
+	if len(plan.targets) != len(expected) {
+		t.Fatalf("expected targets %v, got %v", expected, plan.targets)
+	}
+	for name, target := range expected {
+		if plan.targets[name] != target {
+			t.Fatalf("expected pool %s target %v, got %v", name, target, plan.targets[name])
+		}
+	}
+}
+
+func poolUpdateReason(status *unitv1alpha1.UnitedDeploymentStatus, name string) string {
+	for _, ps := range status.UpdateStatus.Pools {
+		if ps.Name == name && len(ps.Conditions) > 0 {
+			return ps.Conditions[0].Reason
+		}
+	}
+	return ""
+}
+
+func TestPlanRolloutAllAtOnce(t *testing.T) {
+	now := time.Now()
+	ud := newRolloutUnitedDeployment(unitv1alpha1.UnitedDeploymentUpdateStrategy{}, "a", "b")
+	status := ud.Status.DeepCopy()
+
+	plan := planRollout(ud, nil, "v1", "v2", status, now)
+	expectTargets(t, plan, map[string]poolTarget{"a": {Revision: "v2"}, "b": {Revision: "v2"}})
+	if status.CurrentRevision != "v1" {
+		t.Fatalf("expected current revision v1 before pools are ready, got %s", status.CurrentRevision)
+	}
+
+	nameToPool := map[string]*Pool{
+		"a": newRolloutPool("a", "v2", 0, 3, 3),
+		"b": newRolloutPool("b", "v2", 0, 3, 3),
+	}
+	planRollout(ud, nameToPool, "v1", "v2", status, now)
+	if status.CurrentRevision != "v2" {
+		t.Fatalf("expected current revision v2 once pools are ready, got %s", status.CurrentRevision)
+	}
+
+	// a finished rollout keeps pools at the current revision
+	plan = planRollout(ud, nameToPool, "v2", "v2", status, now)
+	expectTargets(t, plan, map[string]poolTarget{"a": {Revision: "v2"}, "b": {Revision: "v2"}})
+}
+
+func TestPlanRolloutStagedCanary(t *testing.T) {
+	now := time.Now()
+	ud := newRolloutUnitedDeployment(unitv1alpha1.UnitedDeploymentUpdateStrategy{
+		Type: unitv1alpha1.StagedUpdateStrategyType,
+		Staged: &unitv1alpha1.StagedUpdateStrategy{
+			Stages: []unitv1alpha1.UpdateStage{
+				{Pools: []unitv1alpha1.UpdateStagePool{{Name: "a", Partition: utilpointer.Int32Ptr(2)}}},
+				{Pools: []unitv1alpha1.UpdateStagePool{{Name: "b"}}},
+			},
+			HealthGate: &unitv1alpha1.UpdateHealthGate{BakeSeconds: 60},
+		},
+	}, "a", "b", "c")
+	status := ud.Status.DeepCopy()
+
+	// the canary stage only reaches a
+	plan := planRollout(ud, nil, "v1", "v2", status, now)
+	expectTargets(t, plan, map[string]poolTarget{"a": {Revision: "v2", Partition: 2}, "b": {Revision: "v1"}, "c": {Revision: "v1"}})
+	if reason := poolUpdateReason(status, "b"); reason != poolUpdatePending {
+		t.Fatalf("expected pool b %s, got %s", poolUpdatePending, reason)
+	}
+
+	// the canary is healthy, but still baking
+	nameToPool := map[string]*Pool{
+		"a": newRolloutPool("a", "v2", 2, 1, 3),
+		"b": newRolloutPool("b", "v1", 0, 3, 3),
+		"c": newRolloutPool("c", "v1", 0, 3, 3),
+	}
+	plan = planRollout(ud, nameToPool, "v1", "v2", status, now)
+	if plan.requeueAfter != 60*time.Second {
+		t.Fatalf("expected to requeue after the bake time, got %v", plan.requeueAfter)
+	}
+	if reason := poolUpdateReason(status, "a"); reason != poolUpdateBaking {
+		t.Fatalf("expected pool a %s, got %s", poolUpdateBaking, reason)
+	}
+
+	// once baked, the next stage starts
+	plan = planRollout(ud, nameToPool, "v1", "v2", status, now.Add(61*time.Second))
+	expectTargets(t, plan, map[string]poolTarget{"a": {Revision: "v2", Partition: 2}, "b": {Revision: "v2"}, "c": {Revision: "v1"}})
+	if status.UpdateStatus.CurrentStage != 1 {
+		t.Fatalf("expected stage 1, got %d", status.UpdateStatus.CurrentStage)
+	}
+
+	// the final stage updates the rest of the canary and the unlisted pools
+	nameToPool["b"] = newRolloutPool("b", "v2", 0, 3, 3)
+	plan = planRollout(ud, nameToPool, "v1", "v2", status, now.Add(62*time.Second))
+	plan = planRollout(ud, nameToPool, "v1", "v2", status, now.Add(123*time.Second))
+	expectTargets(t, plan, map[string]poolTarget{"a": {Revision: "v2"}, "b": {Revision: "v2"}, "c": {Revision: "v2"}})
+	if status.CurrentRevision != "v1" {
+		t.Fatalf("expected current revision v1 before the last stage passes, got %s", status.CurrentRevision)
+	}
+}
+
+func TestPlanRolloutPaused(t *testing.T) {
+	now := time.Now()
+	ud := newRolloutUnitedDeployment(unitv1alpha1.UnitedDeploymentUpdateStrategy{
+		Type:   unitv1alpha1.StagedUpdateStrategyType,
+		Paused: true,
+		Staged: &unitv1alpha1.StagedUpdateStrategy{
+			Stages: []unitv1alpha1.UpdateStage{{Pools: []unitv1alpha1.UpdateStagePool{{Name: "a"}}}},
+		},
+	}, "a", "b")
+	status := ud.Status.DeepCopy()
+
+	plan := planRollout(ud, nil, "v1", "v2", status, now)
+	expectTargets(t, plan, map[string]poolTarget{"a": {Revision: "v1"}, "b": {Revision: "v1"}})
+	if cond := GetUnitedDeploymentCondition(*status, unitv1alpha1.RolloutPaused); cond == nil || cond.Status != corev1.ConditionTrue {
+		t.Fatalf("expected condition %s, got %v", unitv1alpha1.RolloutPaused, status.Conditions)
+	}
+
+	ud.Spec.UpdateStrategy.Paused = false
+	plan = planRollout(ud, nil, "v1", "v2", status, now)
+	expectTargets(t, plan, map[string]poolTarget{"a": {Revision: "v2"}, "b": {Revision: "v1"}})
+	if cond := GetUnitedDeploymentCondition(*status, unitv1alpha1.RolloutPaused); cond != nil {
+		t.Fatalf("expected condition %s to be removed, got %v", unitv1alpha1.RolloutPaused, cond)
+	}
+
+	// pausing holds the next stage, but not the one in progress
+	ud.Spec.UpdateStrategy.Paused = true
+	nameToPool := map[string]*Pool{"a": newRolloutPool("a", "v2", 0, 3, 3)}
+	plan = planRollout(ud, nameToPool, "v1", "v2", status, now)
+	expectTargets(t, plan, map[string]poolTarget{"a": {Revision: "v2"}, "b": {Revision: "v1"}})
+	if status.UpdateStatus.CurrentStage != 1 {
+		t.Fatalf("expected stage 1, got %d", status.UpdateStatus.CurrentStage)
+	}
+}
+
+func TestPlanRolloutRollback(t *testing.T) {
+	now := time.Now()
+	ud := newRolloutUnitedDeployment(unitv1alpha1.UnitedDeploymentUpdateStrategy{
+		Type: unitv1alpha1.StagedUpdateStrategyType,
+		Staged: &unitv1alpha1.StagedUpdateStrategy{
+			Stages: []unitv1alpha1.UpdateStage{{Pools: []unitv1alpha1.UpdateStagePool{{Name: "a"}, {Name: "b"}}}},
+			HealthGate: &unitv1alpha1.UpdateHealthGate{
+				MinReadyPercent:         utilpointer.Int32Ptr(60),
+				ProgressDeadlineSeconds: utilpointer.Int32Ptr(300),
+			},
+		},
+	}, "a", "b", "c")
+	status := ud.Status.DeepCopy()
+	planRollout(ud, nil, "v1", "v2", status, now)
+
+	// b has 2 of 3 replicas ready, which passes a 60% gate, a has 1
+	nameToPool := map[string]*Pool{
+		"a": newRolloutPool("a", "v2", 0, 3, 1),
+		"b": newRolloutPool("b", "v2", 0, 3, 2),
+	}
+	plan := planRollout(ud, nameToPool, "v1", "v2", status, now.Add(time.Minute))
+	if plan.requeueAfter != 4*time.Minute {
+		t.Fatalf("expected to requeue at the progress deadline, got %v", plan.requeueAfter)
+	}
+
+	plan = planRollout(ud, nameToPool, "v1", "v2", status, now.Add(6*time.Minute))
+	expectTargets(t, plan, map[string]poolTarget{"a": {Revision: "v1"}, "b": {Revision: "v2"}, "c": {Revision: "v1"}})
+	if len(plan.rolledBack) != 1 || plan.rolledBack[0] != "a" {
+		t.Fatalf("expected pool a to be rolled back, got %v", plan.rolledBack)
+	}
+	if !status.UpdateStatus.RolledBack || status.CurrentRevision != "v1" {
+		t.Fatalf("expected the rollout to stop at revision v1, got %+v", status)
+	}
+	if cond := GetUnitedDeploymentCondition(*status, unitv1alpha1.RolloutRolledBack); cond == nil || cond.Status != corev1.ConditionTrue {
+		t.Fatalf("expected condition %s, got %v", unitv1alpha1.RolloutRolledBack, status.Conditions)
+	}
+	if reason := poolUpdateReason(status, "a"); reason != poolUpdateRolledBack {
+		t.Fatalf("expected pool a %s, got %s", poolUpdateRolledBack, reason)
+	}
+
+	// the rollout does not go on while the revision stays the same
+	nameToPool["a"] = newRolloutPool("a", "v2", 0, 3, 3)
+	plan = planRollout(ud, nameToPool, "v1", "v2", status, now.Add(7*time.Minute))
+	expectTargets(t, plan, map[string]poolTarget{"a": {Revision: "v1"}, "b": {Revision: "v2"}, "c": {Revision: "v1"}})
+
+	// a new revision starts over
+	plan = planRollout(ud, nameToPool, "v1", "v3", status, now.Add(8*time.Minute))
+	expectTargets(t, plan, map[string]poolTarget{"a": {Revision: "v3"}, "b": {Revision: "v3"}, "c": {Revision: "v1"}})
+	if status.UpdateStatus.RolledBack || GetUnitedDeploymentCondition(*status, unitv1alpha1.RolloutRolledBack) != nil {
+		t.Fatalf("expected the rollback to be cleared, got %+v", status)
+	}
+}
+
+func TestRevisionedUnitedDeployment(t *testing.T) {
+	ud := newRolloutUnitedDeployment(unitv1alpha1.UnitedDeploymentUpdateStrategy{}, "a")
+	patch, err := getUnitedDeploymentPatch(ud)
+	if err != nil {
+		t.Fatalf("fail to get patch: %v", err)
+	}
+	current := &appsv1.ControllerRevision{ObjectMeta: metav1.ObjectMeta{Name: "v1"}, Data: runtime.RawExtension{Raw: patch}}
+	updated := &appsv1.ControllerRevision{ObjectMeta: metav1.ObjectMeta{Name: "v2"}}
+
+	ud.Spec.WorkloadTemplate.StatefulSetTemplate.Spec.Template.Spec.Containers[0].Image = "nginx:2.0"
+	ud.Spec.Topology.Pools[0].Replicas = utilpointer.Int32Ptr(5)
+
+	obj, err := revisionedUnitedDeployment(ud, current, updated, poolTarget{Revision: "v1"})
+	if err != nil {
+		t.Fatalf("fail to restore revision: %v", err)
+	}
+	if image := obj.Spec.WorkloadTemplate.StatefulSetTemplate.Spec.Template.Spec.Containers[0].Image; image != "nginx:1.0" {
+		t.Fatalf("expected image nginx:1.0, got %s", image)
+	}
+	if replicas := *obj.Spec.Topology.Pools[0].Replicas; replicas != 5 {
+		t.Fatalf("expected the topology to be kept, got %d replicas", replicas)
+	}
+
+	obj, err = revisionedUnitedDeployment(ud, current, updated, poolTarget{Revision: "v2", Partition: 2})
+	if err != nil {
+		t.Fatalf("fail to get revision: %v", err)
+	}
+	if obj == ud || ud.Spec.WorkloadTemplate.StatefulSetTemplate.Spec.UpdateStrategy.RollingUpdate != nil {
+		t.Fatalf("expected the partition not to be set on the UnitedDeployment itself")
+	}
+	if partition := obj.Spec.WorkloadTemplate.StatefulSetTemplate.Spec.UpdateStrategy.RollingUpdate.Partition; *partition != 2 {
+		t.Fatalf("expected partition 2, got %d", *partition)
+	}
+
+	if _, err = revisionedUnitedDeployment(ud, current, updated, poolTarget{Revision: "v0"}); err == nil {
+		t.Fatalf("expected an error for an unknown revision")
+	}
+}
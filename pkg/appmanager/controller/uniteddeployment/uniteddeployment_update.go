@@ -22,6 +22,7 @@ package uniteddeployment
 
 import (
 	"fmt"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -36,25 +37,42 @@ import (
 
 func (r *ReconcileUnitedDeployment) managePools(ud *unitv1alpha1.UnitedDeployment,
 	nameToPool map[string]*Pool, nextPatches map[string]UnitedDeploymentPatches,
-	expectedRevision *appsv1.ControllerRevision,
-	poolType unitv1alpha1.TemplateType) (newStatus *unitv1alpha1.UnitedDeploymentStatus, updateErr error) {
+	currentRevision, updatedRevision *appsv1.ControllerRevision,
+	poolType unitv1alpha1.TemplateType) (newStatus *unitv1alpha1.UnitedDeploymentStatus, requeueAfter time.Duration, updateErr error) {
 
 	newStatus = ud.Status.DeepCopy()
-	exists, provisioned, err := r.managePoolProvision(ud, nameToPool, nextPatches, expectedRevision, poolType)
+	plan := planRollout(ud, nameToPool, currentRevision.Name, updatedRevision.Name, newStatus, time.Now())
+	requeueAfter = plan.requeueAfter
+	if len(plan.rolledBack) > 0 {
+		r.recorder.Eventf(ud.DeepCopy(), corev1.EventTypeWarning, eventTypePoolsRollback,
+			"Roll back Pool %v to revision %s: health gate of revision %s failed", plan.rolledBack, currentRevision.Name, updatedRevision.Name)
+	}
+	poolTemplate := func(poolName string) (*unitv1alpha1.UnitedDeployment, poolTarget, error) {
+		target := plan.targets[poolName]
+		obj, err := revisionedUnitedDeployment(ud, currentRevision, updatedRevision, target)
+		return obj, target, err
+	}
+
+	exists, provisioned, err := r.managePoolProvision(ud, nameToPool, nextPatches, poolTemplate, poolType)
 	if err != nil {
 		SetUnitedDeploymentCondition(newStatus, NewUnitedDeploymentCondition(unitv1alpha1.PoolProvisioned, corev1.ConditionFalse, "Error", err.Error()))
-		return newStatus, fmt.Errorf("fail to manage Pool provision: %s", err)
+		return newStatus, requeueAfter, fmt.Errorf("fail to manage Pool provision: %s", err)
 	}
 
 	if provisioned {
 		SetUnitedDeploymentCondition(newStatus, NewUnitedDeploymentCondition(unitv1alpha1.PoolProvisioned, corev1.ConditionTrue, "", ""))
 	}
 
+	// the replicas of a DaemonSet pool follow its nodes, not the pool Replicas
+	ignoreReplicas := poolType == unitv1alpha1.DaemonSetTemplateType
+
 	var needUpdate []string
 	for _, name := range exists.List() {
 		pool := nameToPool[name]
-		if r.poolControls[poolType].IsExpected(pool, expectedRevision.Name) ||
-			pool.Status.ReplicasInfo.Replicas != nextPatches[name].Replicas ||
+		target := plan.targets[name]
+		if r.poolControls[poolType].IsExpected(pool, target.Revision) ||
+			pool.Status.Partition != target.Partition ||
+			(!ignoreReplicas && pool.Status.ReplicasInfo.Replicas != nextPatches[name].Replicas) ||
 			pool.Status.PatchInfo != nextPatches[name].Patch {
 			needUpdate = append(needUpdate, name)
 		}
@@ -66,10 +84,15 @@ func (r *ReconcileUnitedDeployment) managePools(ud *unitv1alpha1.UnitedDeploymen
 			pool := nameToPool[cell]
 			replicas := nextPatches[cell].Replicas
 
-			klog.Infof("UnitedDeployment %s/%s needs to update Pool (%s) %s/%s with revision %s, replicas %d ",
-				ud.Namespace, ud.Name, poolType, pool.Namespace, pool.Name, expectedRevision.Name, replicas)
+			obj, target, err := poolTemplate(cell)
+			if err != nil {
+				return err
+			}
+
+			klog.Infof("UnitedDeployment %s/%s needs to update Pool (%s) %s/%s with revision %s, partition %d, replicas %d ",
+				ud.Namespace, ud.Name, poolType, pool.Namespace, pool.Name, target.Revision, target.Partition, replicas)
 
-			updatePoolErr := r.poolControls[poolType].UpdatePool(pool, ud, expectedRevision.Name, replicas)
+			updatePoolErr := r.poolControls[poolType].UpdatePool(pool, obj, target.Revision, replicas)
 			if updatePoolErr != nil {
 				r.recorder.Event(ud.DeepCopy(), corev1.EventTypeWarning, fmt.Sprintf("Failed%s", eventTypePoolsUpdate), fmt.Sprintf("Error updating PodSet (%s) %s when updating: %s", poolType, pool.Name, updatePoolErr))
 			}
@@ -87,7 +110,8 @@ func (r *ReconcileUnitedDeployment) managePools(ud *unitv1alpha1.UnitedDeploymen
 
 func (r *ReconcileUnitedDeployment) managePoolProvision(ud *unitv1alpha1.UnitedDeployment,
 	nameToPool map[string]*Pool, nextPatches map[string]UnitedDeploymentPatches,
-	expectedRevision *appsv1.ControllerRevision, workloadType unitv1alpha1.TemplateType) (sets.String, bool, error) {
+	poolTemplate func(poolName string) (*unitv1alpha1.UnitedDeployment, poolTarget, error),
+	workloadType unitv1alpha1.TemplateType) (sets.String, bool, error) {
 	expectedPools := sets.String{}
 	gotPools := sets.String{}
 
@@ -118,8 +142,6 @@ func (r *ReconcileUnitedDeployment) managePoolProvision(ud *unitv1alpha1.UnitedD
 		deletes = append(deletes, gotPool)
 	}
 
-	revision := expectedRevision.Name
-
 	var errs []error
 	// manage creating
 	if len(creates) > 0 {
@@ -136,7 +158,11 @@ func (r *ReconcileUnitedDeployment) managePoolProvision(ud *unitv1alpha1.UnitedD
 			poolName := createdPools[idx]
 
 			replicas := nextPatches[poolName].Replicas
-			err := r.poolControls[workloadType].CreatePool(ud, poolName, revision, replicas)
+			obj, target, err := poolTemplate(poolName)
+			if err != nil {
+				return fmt.Errorf("fail to create Pool (%s) %s: %s", workloadType, poolName, err.Error())
+			}
+			err = r.poolControls[workloadType].CreatePool(obj, poolName, target.Revision, replicas)
 			if err != nil {
 				if !errors.IsTimeout(err) {
 					return fmt.Errorf("fail to create Pool (%s) %s: %s", workloadType, poolName, err.Error())
@@ -21,12 +21,34 @@ package kubernetes
 // THE SOFTWARE.
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/bhojpur/dcp/pkg/appmanager/constant"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
+const nginxIngressControllerContainer = "controller"
+
+// NginxIngressPoolConfig defines the settings of the nginx ingress controller of a pool.
+type NginxIngressPoolConfig struct {
+	Replicas           int32
+	Image              string
+	IngressClass       string
+	ServiceType        corev1.ServiceType
+	ServiceAnnotations map[string]string
+	HostNetwork        bool
+	Resources          *corev1.ResourceRequirements
+	NodeSelector       map[string]string
+	Tolerations        []corev1.Toleration
+	Config             map[string]string
+}
+
 func CreateNginxIngressCommonResource(client client.Client) error {
 	// 1. Create Namespace
 	if err := CreateNamespaceFromYaml(client, constant.NginxIngressControllerNamespace); err != nil {
@@ -163,34 +185,14 @@ func DeleteNginxIngressCommonResource(client client.Client) error {
 	return nil
 }
 
-func CreateNginxIngressSpecificResource(client client.Client, poolname string, replicas int32, ownerRef *metav1.OwnerReference) error {
-	// 1. Create Deployment
-	if err := CreateDeployFromYaml(client,
-		constant.NginxIngressControllerNodePoolDeployment,
-		replicas,
-		ownerRef,
-		map[string]string{
-			"nodepool_name": poolname}); err != nil {
-		klog.Errorf("%v", err)
-		return err
-	}
-	if err := CreateDeployFromYaml(client,
-		constant.NginxIngressAdmissionWebhookDeployment,
-		1,
-		nil,
-		map[string]string{
-			"nodepool_name": poolname}); err != nil {
-		klog.Errorf("%v", err)
-		return err
-	}
-	// 2. Create Service
-	if err := CreateServiceFromYaml(client,
-		constant.NginxIngressControllerService,
-		map[string]string{
-			"nodepool_name": poolname}); err != nil {
+func CreateNginxIngressSpecificResource(client client.Client, poolname string, config *NginxIngressPoolConfig, ownerRef *metav1.OwnerReference) error {
+	// 1. Create the ConfigMap, Deployment and Service of the ingress controller, and the
+	// admission webhook Deployment
+	if err := ApplyNginxIngressPoolResource(client, poolname, config, ownerRef); err != nil {
 		klog.Errorf("%v", err)
 		return err
 	}
+	// 2. Create the admission webhook Service
 	if err := CreateServiceFromYaml(client,
 		constant.NginxIngressAdmissionWebhookService,
 		map[string]string{
@@ -282,17 +284,215 @@ func DeleteNginxIngressSpecificResource(client client.Client, poolname string, c
 		klog.Errorf("%v", err)
 		return err
 	}
+	// 6. Delete ConfigMap
+	if err := DeleteConfigMapFromYaml(client,
+		nginxIngressPoolConfigMap(poolname)); err != nil {
+		klog.Errorf("%v", err)
+		return err
+	}
 	return nil
 }
 
-func ScaleNginxIngressControllerDeploymment(client client.Client, poolname string, replicas int32) error {
-	if err := UpdateDeployFromYaml(client,
-		constant.NginxIngressControllerNodePoolDeployment,
-		&replicas,
-		map[string]string{
-			"nodepool_name": poolname}); err != nil {
-		klog.Errorf("%v", err)
+// ApplyNginxIngressPoolResource creates or updates the ConfigMap, Deployment and Service
+// of the nginx ingress controller of the pool and its admission webhook Deployment
+// according to the config.
+func ApplyNginxIngressPoolResource(c client.Client, poolname string, config *NginxIngressPoolConfig, ownerRef *metav1.OwnerReference) error {
+	ingressClass := config.IngressClass
+	if ingressClass == "" {
+		ingressClass = poolname
+	}
+	tmplCtx := map[string]string{"nodepool_name": poolname, "ingress_class": ingressClass}
+
+	// 1. Apply ConfigMap
+	obj, err := YamlToObject([]byte(nginxIngressPoolConfigMap(poolname)))
+	if err != nil {
 		return err
 	}
+	desiredCm, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return fmt.Errorf("fail to assert configmap")
+	}
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: desiredCm.Name, Namespace: desiredCm.Namespace}}
+	op, err := controllerutil.CreateOrUpdate(context.Background(), c, cm, func() error {
+		cm.Labels = desiredCm.Labels
+		cm.Data = config.Config
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("fail to apply the configmap/%s: %v", cm.Name, err)
+	}
+	klog.V(4).Infof("configmap/%s is %s", cm.Name, op)
+
+	// 2. Apply Deployment
+	dp, err := SubsituteTemplate(constant.NginxIngressControllerNodePoolDeployment, tmplCtx)
+	if err != nil {
+		return err
+	}
+	if obj, err = YamlToObject([]byte(dp)); err != nil {
+		return err
+	}
+	desiredDply, ok := obj.(*appsv1.Deployment)
+	if !ok {
+		return fmt.Errorf("fail to assert deployment")
+	}
+	setNginxIngressControllerPodSpec(desiredDply, config)
+	dply := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: desiredDply.Name, Namespace: desiredDply.Namespace}}
+	op, err = controllerutil.CreateOrUpdate(context.Background(), c, dply, func() error {
+		if dply.CreationTimestamp.IsZero() {
+			dply.Labels = desiredDply.Labels
+			if ownerRef != nil {
+				dply.OwnerReferences = []metav1.OwnerReference{*ownerRef}
+			}
+			desiredDply.Spec.DeepCopyInto(&dply.Spec)
+			return nil
+		}
+		dply.Spec.Replicas = desiredDply.Spec.Replicas
+		spec, desiredSpec := &dply.Spec.Template.Spec, &desiredDply.Spec.Template.Spec
+		spec.HostNetwork = desiredSpec.HostNetwork
+		spec.DNSPolicy = desiredSpec.DNSPolicy
+		spec.NodeSelector = desiredSpec.NodeSelector
+		spec.Tolerations = desiredSpec.Tolerations
+		container := getContainer(spec, nginxIngressControllerContainer)
+		desiredContainer := getContainer(desiredSpec, nginxIngressControllerContainer)
+		if container == nil {
+			spec.Containers = desiredSpec.Containers
+			return nil
+		}
+		container.Image = desiredContainer.Image
+		container.Args = desiredContainer.Args
+		container.Resources = desiredContainer.Resources
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("fail to apply the deployment/%s: %v", dply.Name, err)
+	}
+	klog.V(4).Infof("deployment/%s is %s", dply.Name, op)
+
+	// 3. Apply Service
+	sv, err := SubsituteTemplate(constant.NginxIngressControllerService, tmplCtx)
+	if err != nil {
+		return err
+	}
+	if obj, err = YamlToObject([]byte(sv)); err != nil {
+		return err
+	}
+	desiredSvc, ok := obj.(*corev1.Service)
+	if !ok {
+		return fmt.Errorf("fail to assert service")
+	}
+	if config.ServiceType != "" {
+		desiredSvc.Spec.Type = config.ServiceType
+	}
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: desiredSvc.Name, Namespace: desiredSvc.Namespace}}
+	op, err = controllerutil.CreateOrUpdate(context.Background(), c, svc, func() error {
+		if svc.CreationTimestamp.IsZero() {
+			svc.Labels = desiredSvc.Labels
+			desiredSvc.Spec.DeepCopyInto(&svc.Spec)
+		}
+		// keep the annotations written by others, e.g. the cloud provider
+		if svc.Annotations == nil && len(config.ServiceAnnotations) != 0 {
+			svc.Annotations = map[string]string{}
+		}
+		for k, v := range config.ServiceAnnotations {
+			svc.Annotations[k] = v
+		}
+		if svc.Spec.Type != desiredSvc.Spec.Type {
+			svc.Spec.Type = desiredSvc.Spec.Type
+			if svc.Spec.Type == corev1.ServiceTypeClusterIP {
+				// node ports are only allowed for NodePort and LoadBalancer services
+				for i := range svc.Spec.Ports {
+					svc.Spec.Ports[i].NodePort = 0
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("fail to apply the service/%s: %v", svc.Name, err)
+	}
+	klog.V(4).Infof("service/%s is %s", svc.Name, op)
+
+	// 4. Apply the admission webhook Deployment, which validates the ingresses of the same class
+	wh, err := SubsituteTemplate(constant.NginxIngressAdmissionWebhookDeployment, tmplCtx)
+	if err != nil {
+		return err
+	}
+	if obj, err = YamlToObject([]byte(wh)); err != nil {
+		return err
+	}
+	desiredWebhook, ok := obj.(*appsv1.Deployment)
+	if !ok {
+		return fmt.Errorf("fail to assert deployment")
+	}
+	replicas := int32(1)
+	desiredWebhook.Spec.Replicas = &replicas
+	webhook := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: desiredWebhook.Name, Namespace: desiredWebhook.Namespace}}
+	op, err = controllerutil.CreateOrUpdate(context.Background(), c, webhook, func() error {
+		if webhook.CreationTimestamp.IsZero() {
+			webhook.Labels = desiredWebhook.Labels
+			desiredWebhook.Spec.DeepCopyInto(&webhook.Spec)
+			return nil
+		}
+		container := getContainer(&webhook.Spec.Template.Spec, nginxIngressControllerContainer)
+		desiredContainer := getContainer(&desiredWebhook.Spec.Template.Spec, nginxIngressControllerContainer)
+		if container == nil {
+			webhook.Spec.Template.Spec.Containers = desiredWebhook.Spec.Template.Spec.Containers
+			return nil
+		}
+		container.Args = desiredContainer.Args
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("fail to apply the deployment/%s: %v", webhook.Name, err)
+	}
+	klog.V(4).Infof("deployment/%s is %s", webhook.Name, op)
+	return nil
+}
+
+// setNginxIngressControllerPodSpec applies the config to the ingress controller deployment
+// rendered from the template.
+func setNginxIngressControllerPodSpec(dply *appsv1.Deployment, config *NginxIngressPoolConfig) {
+	replicas := config.Replicas
+	dply.Spec.Replicas = &replicas
+	spec := &dply.Spec.Template.Spec
+	if config.HostNetwork {
+		spec.HostNetwork = true
+		spec.DNSPolicy = corev1.DNSClusterFirstWithHostNet
+	}
+	for k, v := range config.NodeSelector {
+		if spec.NodeSelector == nil {
+			spec.NodeSelector = make(map[string]string)
+		}
+		spec.NodeSelector[k] = v
+	}
+	if config.Tolerations != nil {
+		spec.Tolerations = config.Tolerations
+	}
+	container := getContainer(spec, nginxIngressControllerContainer)
+	if container == nil {
+		return
+	}
+	if config.Image != "" {
+		container.Image = config.Image
+	}
+	if config.Resources != nil {
+		container.Resources = *config.Resources
+	}
+}
+
+// getContainer returns the container with the given name in the pod spec, or nil.
+func getContainer(spec *corev1.PodSpec, name string) *corev1.Container {
+	for i := range spec.Containers {
+		if spec.Containers[i].Name == name {
+			return &spec.Containers[i]
+		}
+	}
 	return nil
 }
+
+// nginxIngressPoolConfigMap renders the ConfigMap template of the ingress controller of the pool.
+func nginxIngressPoolConfigMap(poolname string) string {
+	cm, _ := SubsituteTemplate(constant.NginxIngressControllerNodePoolConfigMap,
+		map[string]string{"nodepool_name": poolname})
+	return cm
+}
@@ -0,0 +1,97 @@
+package kubernetes
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestApplyNginxIngressPoolResourceIngressClass(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	deployments := []string{"hangzhou-ingress-nginx-controller", "hangzhou-ingress-nginx-admission-webhook"}
+	for _, class := range []string{"", "edge"} {
+		if err := ApplyNginxIngressPoolResource(c, "hangzhou", &NginxIngressPoolConfig{Replicas: 1, IngressClass: class}, nil); err != nil {
+			t.Fatalf("fail to apply the ingress resources of class %q: %v", class, err)
+		}
+		expect := "--ingress-class=" + class
+		if class == "" {
+			expect = "--ingress-class=hangzhou"
+		}
+		for _, name := range deployments {
+			dply := &appsv1.Deployment{}
+			if err := c.Get(context.TODO(), client.ObjectKey{Namespace: "ingress-nginx", Name: name}, dply); err != nil {
+				t.Fatalf("fail to get deployment %s: %v", name, err)
+			}
+			container := getContainer(&dply.Spec.Template.Spec, nginxIngressControllerContainer)
+			var found bool
+			for _, arg := range container.Args {
+				if arg == expect {
+					found = true
+				}
+			}
+			if !found {
+				t.Fatalf("expect deployment %s to have the arg %s, but got %v", name, expect, container.Args)
+			}
+		}
+	}
+}
+
+func TestApplyNginxIngressPoolResourceKeepsServiceAnnotations(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	if err := ApplyNginxIngressPoolResource(c, "hangzhou", &NginxIngressPoolConfig{Replicas: 1}, nil); err != nil {
+		t.Fatalf("fail to apply the ingress resources: %v", err)
+	}
+	key := client.ObjectKey{Namespace: "ingress-nginx", Name: "hangzhou-ingress-nginx-controller"}
+	svc := &corev1.Service{}
+	if err := c.Get(context.TODO(), key, svc); err != nil {
+		t.Fatalf("fail to get service %s: %v", key.Name, err)
+	}
+	// e.g. written by the cloud provider
+	svc.Annotations = map[string]string{"service.beta.kubernetes.io/lb-id": "lb-1"}
+	if err := c.Update(context.TODO(), svc); err != nil {
+		t.Fatalf("fail to update service %s: %v", key.Name, err)
+	}
+
+	config := &NginxIngressPoolConfig{Replicas: 1, ServiceAnnotations: map[string]string{"foo": "bar"}}
+	if err := ApplyNginxIngressPoolResource(c, "hangzhou", config, nil); err != nil {
+		t.Fatalf("fail to apply the ingress resources: %v", err)
+	}
+	if err := c.Get(context.TODO(), key, svc); err != nil {
+		t.Fatalf("fail to get service %s: %v", key.Name, err)
+	}
+	if svc.Annotations["service.beta.kubernetes.io/lb-id"] != "lb-1" || svc.Annotations["foo"] != "bar" {
+		t.Fatalf("expect the service annotations to be merged, but got %v", svc.Annotations)
+	}
+}
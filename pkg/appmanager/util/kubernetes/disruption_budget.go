@@ -0,0 +1,115 @@
+package kubernetes
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"fmt"
+
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	appsv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+)
+
+// NewPoolDisruptionBudget returns the PodDisruptionBudget protecting the pods of the workload
+// of a pool. It is named after the workload and labeled with the pod selector of the pool.
+// An absolute MinAvailable is capped to the replicas of the pool.
+func NewPoolDisruptionBudget(workload metav1.Object, poolSelector *metav1.LabelSelector,
+	budget *appsv1alpha1.PoolDisruptionBudget, replicas int32) *policyv1.PodDisruptionBudget {
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      workload.GetName(),
+			Namespace: workload.GetNamespace(),
+			Labels:    make(map[string]string, len(poolSelector.MatchLabels)),
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: poolSelector.DeepCopy(),
+		},
+	}
+	for k, v := range poolSelector.MatchLabels {
+		pdb.Labels[k] = v
+	}
+	if budget.MaxUnavailable != nil {
+		maxUnavailable := *budget.MaxUnavailable
+		pdb.Spec.MaxUnavailable = &maxUnavailable
+	}
+	if budget.MinAvailable != nil {
+		minAvailable := *budget.MinAvailable
+		if minAvailable.Type == intstr.Int && minAvailable.IntVal > replicas {
+			minAvailable = intstr.FromInt(int(replicas))
+		}
+		pdb.Spec.MinAvailable = &minAvailable
+	}
+	return pdb
+}
+
+// ApplyPoolDisruptionBudget creates or updates the PodDisruptionBudget of a pool, controlled
+// by the owner of the pool workloads.
+func ApplyPoolDisruptionBudget(c client.Client, scheme *runtime.Scheme, owner metav1.Object,
+	desired *policyv1.PodDisruptionBudget) error {
+	pdb := &policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: desired.Name, Namespace: desired.Namespace}}
+	op, err := controllerutil.CreateOrUpdate(context.TODO(), c, pdb, func() error {
+		pdb.Labels = desired.Labels
+		pdb.Spec.Selector = desired.Spec.Selector
+		pdb.Spec.MinAvailable = desired.Spec.MinAvailable
+		pdb.Spec.MaxUnavailable = desired.Spec.MaxUnavailable
+		return controllerutil.SetControllerReference(owner, pdb, scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("fail to apply the poddisruptionbudget/%s: %v", desired.Name, err)
+	}
+	klog.V(4).Infof("poddisruptionbudget %s/%s is %s", pdb.Namespace, pdb.Name, op)
+	return nil
+}
+
+// CleanupPoolDisruptionBudgets deletes the PodDisruptionBudgets controlled by the owner which
+// are not in keep, e.g. those of the deleted pools.
+func CleanupPoolDisruptionBudgets(c client.Client, owner metav1.Object, selector *metav1.LabelSelector,
+	keep sets.String) error {
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return err
+	}
+	pdbList := &policyv1.PodDisruptionBudgetList{}
+	if err := c.List(context.TODO(), pdbList, client.InNamespace(owner.GetNamespace()),
+		client.MatchingLabelsSelector{Selector: labelSelector}); err != nil {
+		return err
+	}
+	for i := range pdbList.Items {
+		pdb := &pdbList.Items[i]
+		if !metav1.IsControlledBy(pdb, owner) || keep.Has(pdb.Name) {
+			continue
+		}
+		if err := c.Delete(context.TODO(), pdb); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("fail to delete the poddisruptionbudget/%s: %v", pdb.Name, err)
+		}
+		klog.V(4).Infof("poddisruptionbudget %s/%s is deleted", pdb.Namespace, pdb.Name)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package kubernetes
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+)
+
+// SetPoolTopologySpread adds the topology spread constraint of a pool to the pod spec of its
+// workload, counting only the pods matched by the pod selector of the pool. A constraint of
+// the template on the same topology key is replaced. Nothing is done if spread is nil.
+func SetPoolTopologySpread(podSpec *corev1.PodSpec, spread *appsv1alpha1.PoolTopologySpread,
+	poolSelector *metav1.LabelSelector) {
+	if spread == nil {
+		return
+	}
+	constraint := corev1.TopologySpreadConstraint{
+		MaxSkew:           spread.MaxSkew,
+		TopologyKey:       spread.TopologyKey,
+		WhenUnsatisfiable: spread.WhenUnsatisfiable,
+		LabelSelector:     poolSelector.DeepCopy(),
+	}
+	if constraint.MaxSkew == 0 {
+		constraint.MaxSkew = 1
+	}
+	if constraint.WhenUnsatisfiable == "" {
+		constraint.WhenUnsatisfiable = corev1.ScheduleAnyway
+	}
+
+	constraints := make([]corev1.TopologySpreadConstraint, 0, len(podSpec.TopologySpreadConstraints)+1)
+	for _, c := range podSpec.TopologySpreadConstraints {
+		if c.TopologyKey != spread.TopologyKey {
+			constraints = append(constraints, c)
+		}
+	}
+	podSpec.TopologySpreadConstraints = append(constraints, constraint)
+}
@@ -0,0 +1,61 @@
+package kubernetes
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	appsv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+)
+
+func TestSetPoolTopologySpread(t *testing.T) {
+	poolSelector := &metav1.LabelSelector{MatchLabels: map[string]string{
+		"app": "demo", appsv1alpha1.PoolNameLabelKey: "hangzhou"}}
+	zone := corev1.TopologySpreadConstraint{MaxSkew: 2, TopologyKey: "topology.kubernetes.io/zone",
+		WhenUnsatisfiable: corev1.DoNotSchedule}
+	podSpec := &corev1.PodSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+		zone,
+		{MaxSkew: 1, TopologyKey: corev1.LabelHostname, WhenUnsatisfiable: corev1.DoNotSchedule,
+			LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "demo"}}},
+	}}
+
+	SetPoolTopologySpread(podSpec, nil, poolSelector)
+	if len(podSpec.TopologySpreadConstraints) != 2 {
+		t.Fatalf("expect the constraints to be kept without spread, but got %v", podSpec.TopologySpreadConstraints)
+	}
+
+	SetPoolTopologySpread(podSpec, &appsv1alpha1.PoolTopologySpread{TopologyKey: corev1.LabelHostname}, poolSelector)
+	expect := []corev1.TopologySpreadConstraint{
+		zone,
+		{MaxSkew: 1, TopologyKey: corev1.LabelHostname, WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector: poolSelector},
+	}
+	if !reflect.DeepEqual(podSpec.TopologySpreadConstraints, expect) {
+		t.Fatalf("expect constraints %v, but got %v", expect, podSpec.TopologySpreadConstraints)
+	}
+	if podSpec.TopologySpreadConstraints[1].LabelSelector == poolSelector {
+		t.Fatalf("expect the pool selector to be copied")
+	}
+}
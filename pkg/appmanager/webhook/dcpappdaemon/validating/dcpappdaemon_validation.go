@@ -21,8 +21,10 @@ package validating
 // THE SOFTWARE.
 
 import (
+	"encoding/json"
 	"fmt"
 
+	jsonpatch "github.com/evanphx/json-patch"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
@@ -30,6 +32,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	unversionedvalidation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	appsvalidation "k8s.io/kubernetes/pkg/apis/apps/validation"
 	"k8s.io/kubernetes/pkg/apis/core"
@@ -67,6 +70,58 @@ func validateAppDaemonSpec(c client.Client, spec *unitv1alpha1.DcpAppDaemonSpec,
 		allErrs = append(allErrs, validateWorkLoadTemplate(&(spec.WorkloadTemplate), selector, fldPath.Child("template"))...)
 	}
 
+	allErrs = append(allErrs, validateOverrides(spec.Overrides, fldPath.Child("overrides"))...)
+	allErrs = append(allErrs, validateDisruptionBudget(spec.DisruptionBudget, fldPath.Child("disruptionBudget"))...)
+	allErrs = append(allErrs, validateTopologySpread(spec.TopologySpread, fldPath.Child("topologySpread"))...)
+
+	return allErrs
+}
+
+func validateOverrides(overrides []unitv1alpha1.NodePoolOverride, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	names := sets.String{}
+	for i, o := range overrides {
+		idxPath := fldPath.Index(i)
+		if len(o.Name) == 0 {
+			allErrs = append(allErrs, field.Required(idxPath.Child("name"), ""))
+		} else {
+			for _, msg := range apimachineryvalidation.NameIsDNSLabel(o.Name, false) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), o.Name, msg))
+			}
+			if names.Has(o.Name) {
+				allErrs = append(allErrs, field.Duplicate(idxPath.Child("name"), o.Name))
+			}
+			names.Insert(o.Name)
+		}
+
+		if len(o.NodePools) == 0 && o.NodePoolSelector == nil {
+			allErrs = append(allErrs, field.Required(idxPath, "should provide nodepools or nodepoolSelector"))
+		}
+		if o.NodePoolSelector != nil {
+			allErrs = append(allErrs, unversionedvalidation.ValidateLabelSelector(o.NodePoolSelector, idxPath.Child("nodepoolSelector"))...)
+		}
+
+		if len(o.Patch.Raw) == 0 {
+			allErrs = append(allErrs, field.Required(idxPath.Child("patch"), ""))
+			continue
+		}
+		switch o.PatchType {
+		case unitv1alpha1.JSONOverridePatchType:
+			if _, err := jsonpatch.DecodePatch(o.Patch.Raw); err != nil {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("patch"), string(o.Patch.Raw), fmt.Sprintf("invalid json patch: %v", err)))
+			}
+		case unitv1alpha1.StrategicMergeOverridePatchType, "":
+			patch := map[string]interface{}{}
+			if err := json.Unmarshal(o.Patch.Raw, &patch); err != nil {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("patch"), string(o.Patch.Raw), fmt.Sprintf("invalid strategic merge patch: %v", err)))
+			}
+		default:
+			allErrs = append(allErrs, field.NotSupported(idxPath.Child("patchType"), o.PatchType,
+				[]string{string(unitv1alpha1.StrategicMergeOverridePatchType), string(unitv1alpha1.JSONOverridePatchType)}))
+		}
+	}
+
 	return allErrs
 }
 
@@ -88,6 +143,10 @@ func validateWorkLoadTemplate(template *unitv1alpha1.WorkloadTemplate, selector
 		allErrs = append(allErrs, field.Invalid(fldPath, template, "should provide only one of (statefulSetTemplate/deploymentTemplate)"))
 	}
 
+	if template.DaemonSetTemplate != nil {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("daemonSetTemplate"), "daemonSetTemplate is not supported by DcpAppDaemon"))
+	}
+
 	if template.StatefulSetTemplate != nil {
 		labels := labels.Set(template.StatefulSetTemplate.Labels)
 		if !selector.Matches(labels) {
@@ -100,7 +159,7 @@ func validateWorkLoadTemplate(template *unitv1alpha1.WorkloadTemplate, selector
 			allErrs = append(allErrs, field.Invalid(fldPath.Root(), sstemplate, fmt.Sprintf("Convert_v1_PodTemplateSpec_To_core_PodTemplateSpec failed: %v", err)))
 			return allErrs
 		}
-		allErrs = append(allErrs, appsvalidation.ValidatePodTemplateSpecForStatefulSet(coreTemplate, selector, fldPath.Child("statefulSetTemplate", "spec", "template"))...)
+		allErrs = append(allErrs, appsvalidation.ValidatePodTemplateSpecForStatefulSet(coreTemplate, selector, fldPath.Child("statefulSetTemplate", "spec", "template"), apivalidation.PodValidationOptions{})...)
 	}
 
 	if template.DeploymentTemplate != nil {
@@ -118,7 +177,7 @@ func validateWorkLoadTemplate(template *unitv1alpha1.WorkloadTemplate, selector
 		}
 		allErrs = append(allErrs, validatePodTemplateSpec(coreTemplate, selector, fldPath.Child("deploymentTemplate", "spec", "template"))...)
 		allErrs = append(allErrs, apivalidation.ValidatePodTemplateSpec(coreTemplate,
-			fldPath.Child("deploymentTemplate", "spec", "template"))...)
+			fldPath.Child("deploymentTemplate", "spec", "template"), apivalidation.PodValidationOptions{})...)
 	}
 
 	return allErrs
@@ -139,6 +198,53 @@ func convertPodTemplateSpec(template *v1.PodTemplateSpec) (*core.PodTemplateSpec
 	return coreTemplate, nil
 }
 
+// validateDisruptionBudget checks that one and only one of minAvailable and maxUnavailable is set.
+func validateDisruptionBudget(budget *unitv1alpha1.PoolDisruptionBudget, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if budget == nil {
+		return allErrs
+	}
+
+	if budget.MinAvailable != nil && budget.MaxUnavailable != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath, budget, "minAvailable and maxUnavailable cannot be both set"))
+	} else if budget.MinAvailable == nil && budget.MaxUnavailable == nil {
+		allErrs = append(allErrs, field.Required(fldPath, "one of minAvailable and maxUnavailable must be set"))
+	}
+	if budget.MinAvailable != nil {
+		allErrs = append(allErrs, appsvalidation.ValidatePositiveIntOrPercent(*budget.MinAvailable, fldPath.Child("minAvailable"))...)
+		allErrs = append(allErrs, appsvalidation.IsNotMoreThan100Percent(*budget.MinAvailable, fldPath.Child("minAvailable"))...)
+	}
+	if budget.MaxUnavailable != nil {
+		allErrs = append(allErrs, appsvalidation.ValidatePositiveIntOrPercent(*budget.MaxUnavailable, fldPath.Child("maxUnavailable"))...)
+		allErrs = append(allErrs, appsvalidation.IsNotMoreThan100Percent(*budget.MaxUnavailable, fldPath.Child("maxUnavailable"))...)
+	}
+	return allErrs
+}
+
+// validateTopologySpread checks the topology key, the skew and the action of the pool topology spread.
+func validateTopologySpread(spread *unitv1alpha1.PoolTopologySpread, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if spread == nil {
+		return allErrs
+	}
+
+	if spread.TopologyKey == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("topologyKey"), ""))
+	} else {
+		allErrs = append(allErrs, unversionedvalidation.ValidateLabelName(spread.TopologyKey, fldPath.Child("topologyKey"))...)
+	}
+	if spread.MaxSkew < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxSkew"), spread.MaxSkew, "must be greater than or equal to 0"))
+	}
+	switch spread.WhenUnsatisfiable {
+	case "", v1.DoNotSchedule, v1.ScheduleAnyway:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("whenUnsatisfiable"), spread.WhenUnsatisfiable,
+			[]string{string(v1.DoNotSchedule), string(v1.ScheduleAnyway)}))
+	}
+	return allErrs
+}
+
 func validateAppDaemonSpecUpdate(spec, oldSpec *unitv1alpha1.DcpAppDaemonSpec, fldPath *field.Path) field.ErrorList {
 	allErrs := field.ErrorList{}
 	allErrs = append(allErrs, validateWorkloadTemplateUpdate(&spec.WorkloadTemplate, &oldSpec.WorkloadTemplate, fldPath.Child("template"))...)
@@ -23,6 +23,7 @@ package validating
 import (
 	"context"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/klog"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -32,6 +33,9 @@ import (
 
 // validateIngressSpec validates the Bhojpur DCP ingress spec.
 func validateIngressSpec(c client.Client, spec *appsv1alpha1.DcpIngressSpec) field.ErrorList {
+	if allErrs := validateIngressControllerSettings(spec); len(allErrs) > 0 {
+		return allErrs
+	}
 	if len(spec.Pools) > 0 {
 		var err error
 		var errmsg string
@@ -72,6 +76,46 @@ func validateIngressSpec(c client.Client, spec *appsv1alpha1.DcpIngressSpec) fie
 	return nil
 }
 
+// validateIngressControllerSettings validates the ingress controller settings of the
+// DcpIngress and of each pool.
+func validateIngressControllerSettings(spec *appsv1alpha1.DcpIngressSpec) field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	if spec.Replicas < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("ingress_controller_replicas_per_pool"),
+			spec.Replicas, "must be greater than or equal to 0"))
+	}
+	allErrs = append(allErrs, validateIngressControllerSpec(&spec.Controller, specPath.Child("controller"))...)
+
+	pools := make(map[string]struct{}, len(spec.Pools))
+	for i, pool := range spec.Pools {
+		poolPath := specPath.Child("pools").Index(i)
+		if _, exist := pools[pool.Name]; exist {
+			allErrs = append(allErrs, field.Duplicate(poolPath.Child("name"), pool.Name))
+		}
+		pools[pool.Name] = struct{}{}
+		if pool.Replicas != nil && *pool.Replicas < 0 {
+			allErrs = append(allErrs, field.Invalid(poolPath.Child("replicas"),
+				*pool.Replicas, "must be greater than or equal to 0"))
+		}
+		if pool.Controller != nil {
+			allErrs = append(allErrs, validateIngressControllerSpec(pool.Controller, poolPath.Child("controller"))...)
+		}
+	}
+	return allErrs
+}
+
+func validateIngressControllerSpec(spec *appsv1alpha1.IngressControllerSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	switch spec.ServiceType {
+	case "", corev1.ServiceTypeClusterIP, corev1.ServiceTypeNodePort, corev1.ServiceTypeLoadBalancer:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("serviceType"), spec.ServiceType,
+			[]string{string(corev1.ServiceTypeClusterIP), string(corev1.ServiceTypeNodePort), string(corev1.ServiceTypeLoadBalancer)}))
+	}
+	return allErrs
+}
+
 func validateIngressSpecUpdate(c client.Client, spec, oldSpec *appsv1alpha1.DcpIngressSpec) field.ErrorList {
 	return validateIngressSpec(c, spec)
 }
@@ -63,6 +63,10 @@ func (h *NodePoolCreateUpdateHandler) Handle(ctx context.Context, req admission.
 			return admission.Errored(http.StatusUnprocessableEntity,
 				allErrs.ToAggregate())
 		}
+		if allErrs := validateNodePoolParent(h.Client, &np); len(allErrs) > 0 {
+			return admission.Errored(http.StatusUnprocessableEntity,
+				allErrs.ToAggregate())
+		}
 	case admissionv1.Update:
 		klog.V(4).Info("capture the nodepool update request")
 		err := h.Decoder.Decode(req, &np)
@@ -79,6 +83,10 @@ func (h *NodePoolCreateUpdateHandler) Handle(ctx context.Context, req admission.
 			return admission.Errored(http.StatusUnprocessableEntity,
 				allErrs.ToAggregate())
 		}
+		if allErrs := validateNodePoolParent(h.Client, &np); len(allErrs) > 0 {
+			return admission.Errored(http.StatusUnprocessableEntity,
+				allErrs.ToAggregate())
+		}
 	case admissionv1.Delete:
 		klog.V(4).Info("capture the nodepool deletion request")
 		err := h.Decoder.DecodeRaw(req.OldObject, &np)
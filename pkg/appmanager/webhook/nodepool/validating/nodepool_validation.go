@@ -26,6 +26,7 @@ import (
 	"fmt"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	apivalidation "k8s.io/apimachinery/pkg/api/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -59,6 +60,40 @@ func validateNodePoolSpec(spec *appsv1alpha1.NodePoolSpec) field.ErrorList {
 	return nil
 }
 
+// validateNodePoolParent rejects a parent which is the nodepool itself or one of its
+// descendants, as the parents of the nodepools would form a cycle.
+func validateNodePoolParent(cli client.Client, np *appsv1alpha1.NodePool) field.ErrorList {
+	parentPath := field.NewPath("spec").Child("parent")
+	if np.Spec.Parent == "" {
+		return nil
+	}
+	if msgs := apivalidation.NameIsDNSSubdomain(np.Spec.Parent, false); len(msgs) > 0 {
+		return field.ErrorList([]*field.Error{field.Invalid(parentPath, np.Spec.Parent, msgs[0])})
+	}
+
+	visited := map[string]bool{np.Name: true}
+	for parent := np.Spec.Parent; parent != ""; {
+		if visited[parent] {
+			return field.ErrorList([]*field.Error{
+				field.Invalid(parentPath, np.Spec.Parent,
+					fmt.Sprintf("parent forms a cycle through nodepool %s", parent))})
+		}
+		visited[parent] = true
+
+		p := appsv1alpha1.NodePool{}
+		if err := cli.Get(context.TODO(), client.ObjectKey{Name: parent}, &p); err != nil {
+			if apierrors.IsNotFound(err) {
+				// a missing ancestor ends the chain
+				return nil
+			}
+			return field.ErrorList([]*field.Error{
+				field.InternalError(parentPath, fmt.Errorf("fail to get nodepool %s: %v", parent, err))})
+		}
+		parent = p.Spec.Parent
+	}
+	return nil
+}
+
 // validateNodePoolSpecUpdate tests if required fields in the NodePool spec are set.
 func validateNodePoolSpecUpdate(spec, oldSpec *appsv1alpha1.NodePoolSpec) field.ErrorList {
 	if allErrs := validateNodePoolSpec(spec); allErrs != nil {
@@ -95,5 +130,19 @@ func validateNodePoolDeletion(cli client.Client, np *appsv1alpha1.NodePool) fiel
 			field.Forbidden(field.NewPath("metadata").Child("name"),
 				"cannot remove nonempty pool, please drain the pool before deleting")})
 	}
+
+	pools := appsv1alpha1.NodePoolList{}
+	if err := cli.List(context.TODO(), &pools); err != nil {
+		return field.ErrorList([]*field.Error{
+			field.Forbidden(field.NewPath("metadata").Child("name"),
+				"fail to get the child pools of the pool")})
+	}
+	for _, p := range pools.Items {
+		if p.Spec.Parent == np.Name {
+			return field.ErrorList([]*field.Error{
+				field.Forbidden(field.NewPath("metadata").Child("name"),
+					fmt.Sprintf("cannot remove pool with child pool %s, please move or remove the child pools before deleting", p.Name))})
+		}
+	}
 	return nil
 }
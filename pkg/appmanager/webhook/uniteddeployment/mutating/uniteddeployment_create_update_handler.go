@@ -67,6 +67,7 @@ func (h *UnitedDeploymentCreateUpdateHandler) Handle(ctx context.Context, req ad
 
 	statefulSetTemp := obj.Spec.WorkloadTemplate.StatefulSetTemplate
 	deployTem := obj.Spec.WorkloadTemplate.DeploymentTemplate
+	daemonSetTem := obj.Spec.WorkloadTemplate.DaemonSetTemplate
 
 	if statefulSetTemp != nil {
 		statefulSetTemp.Spec.Selector = obj.Spec.Selector
@@ -74,6 +75,9 @@ func (h *UnitedDeploymentCreateUpdateHandler) Handle(ctx context.Context, req ad
 	if deployTem != nil {
 		deployTem.Spec.Selector = obj.Spec.Selector
 	}
+	if daemonSetTem != nil {
+		daemonSetTem.Spec.Selector = obj.Spec.Selector
+	}
 
 	marshalled, err := json.Marshal(obj)
 	if err != nil {
@@ -103,6 +103,178 @@ func validateUnitedDeploymentSpec(c client.Client, spec *unitv1alpha1.UnitedDepl
 
 	}
 
+	allErrs = append(allErrs, validatePoolReplicas(spec, fldPath)...)
+	allErrs = append(allErrs, validateUpdateStrategy(spec, poolNames, fldPath.Child("updateStrategy"))...)
+	allErrs = append(allErrs, validateDisruptionBudget(spec.DisruptionBudget, fldPath.Child("disruptionBudget"))...)
+	allErrs = append(allErrs, validateTopologySpread(spec.TopologySpread, fldPath.Child("topologySpread"))...)
+
+	return allErrs
+}
+
+func validatePoolReplicas(spec *unitv1alpha1.UnitedDeploymentSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if spec.Replicas != nil {
+		allErrs = append(allErrs, apivalidation.ValidateNonnegativeField(int64(*spec.Replicas), fldPath.Child("replicas"))...)
+		if spec.WorkloadTemplate.DaemonSetTemplate != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("replicas"), *spec.Replicas,
+				"replicas will not be used by a daemonSetTemplate"))
+		}
+	}
+
+	var required int32
+	for i, pool := range spec.Topology.Pools {
+		poolPath := fldPath.Child("topology", "pools").Index(i)
+		if pool.Replicas != nil {
+			required += *pool.Replicas
+		} else if pool.MinReplicas != nil {
+			required += *pool.MinReplicas
+		}
+		if pool.Replicas != nil {
+			allErrs = append(allErrs, apivalidation.ValidateNonnegativeField(int64(*pool.Replicas), poolPath.Child("replicas"))...)
+		}
+		if pool.Weight == nil && pool.MinReplicas == nil && pool.MaxReplicas == nil {
+			continue
+		}
+		if spec.Replicas == nil {
+			allErrs = append(allErrs, field.Forbidden(poolPath, "weight, minReplicas and maxReplicas require the replicas of the UnitedDeployment"))
+			continue
+		}
+		if pool.Replicas != nil {
+			allErrs = append(allErrs, field.Forbidden(poolPath, "weight, minReplicas and maxReplicas can not be set with the replicas of the pool"))
+			continue
+		}
+		if pool.Weight != nil {
+			allErrs = append(allErrs, apivalidation.ValidateNonnegativeField(int64(*pool.Weight), poolPath.Child("weight"))...)
+		}
+		if pool.MinReplicas != nil {
+			allErrs = append(allErrs, apivalidation.ValidateNonnegativeField(int64(*pool.MinReplicas), poolPath.Child("minReplicas"))...)
+		}
+		if pool.MaxReplicas != nil {
+			allErrs = append(allErrs, apivalidation.ValidateNonnegativeField(int64(*pool.MaxReplicas), poolPath.Child("maxReplicas"))...)
+			if pool.MinReplicas != nil && *pool.MinReplicas > *pool.MaxReplicas {
+				allErrs = append(allErrs, field.Invalid(poolPath.Child("minReplicas"), *pool.MinReplicas,
+					"must be less than or equal to maxReplicas"))
+			}
+		}
+	}
+
+	// the weighted pools would quietly get less than their minReplicas
+	if spec.Replicas != nil && *spec.Replicas < required {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("replicas"), *spec.Replicas,
+			fmt.Sprintf("must be at least %d, the sum of the replicas and minReplicas of the pools", required)))
+	}
+
+	return allErrs
+}
+
+func validateUpdateStrategy(spec *unitv1alpha1.UnitedDeploymentSpec, poolNames sets.String, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	strategy := &spec.UpdateStrategy
+
+	switch strategy.Type {
+	case "", unitv1alpha1.AllAtOnceUpdateStrategyType, unitv1alpha1.StagedUpdateStrategyType:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("type"), strategy.Type,
+			[]string{string(unitv1alpha1.AllAtOnceUpdateStrategyType), string(unitv1alpha1.StagedUpdateStrategyType)}))
+	}
+
+	if strategy.Staged == nil {
+		return allErrs
+	}
+	if strategy.Type != unitv1alpha1.StagedUpdateStrategyType {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("staged"), strategy.Staged,
+			fmt.Sprintf("only allowed for type %s", unitv1alpha1.StagedUpdateStrategyType)))
+	}
+
+	template := spec.WorkloadTemplate.StatefulSetTemplate
+	for i, stage := range strategy.Staged.Stages {
+		stagePath := fldPath.Child("staged", "stages").Index(i)
+		if len(stage.Pools) == 0 {
+			allErrs = append(allErrs, field.Required(stagePath.Child("pools"), ""))
+		}
+		stagePools := sets.String{}
+		for j, pool := range stage.Pools {
+			poolPath := stagePath.Child("pools").Index(j)
+			if !poolNames.Has(pool.Name) {
+				allErrs = append(allErrs, field.NotFound(poolPath.Child("name"), pool.Name))
+			}
+			if stagePools.Has(pool.Name) {
+				allErrs = append(allErrs, field.Duplicate(poolPath.Child("name"), pool.Name))
+			}
+			stagePools.Insert(pool.Name)
+
+			if pool.Partition == nil {
+				continue
+			}
+			allErrs = append(allErrs, apivalidation.ValidateNonnegativeField(int64(*pool.Partition), poolPath.Child("partition"))...)
+			if template == nil {
+				allErrs = append(allErrs, field.Invalid(poolPath.Child("partition"), *pool.Partition, "only allowed for statefulSetTemplate"))
+			} else if template.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
+				allErrs = append(allErrs, field.Invalid(poolPath.Child("partition"), *pool.Partition,
+					fmt.Sprintf("not allowed with statefulSetTemplate updateStrategy %s", appsv1.OnDeleteStatefulSetStrategyType)))
+			}
+		}
+	}
+
+	if gate := strategy.Staged.HealthGate; gate != nil {
+		gatePath := fldPath.Child("staged", "healthGate")
+		if gate.MinReadyPercent != nil && (*gate.MinReadyPercent < 0 || *gate.MinReadyPercent > 100) {
+			allErrs = append(allErrs, field.Invalid(gatePath.Child("minReadyPercent"), *gate.MinReadyPercent, "must be between 0 and 100"))
+		}
+		allErrs = append(allErrs, apivalidation.ValidateNonnegativeField(int64(gate.BakeSeconds), gatePath.Child("bakeSeconds"))...)
+		if gate.ProgressDeadlineSeconds != nil && *gate.ProgressDeadlineSeconds <= gate.BakeSeconds {
+			allErrs = append(allErrs, field.Invalid(gatePath.Child("progressDeadlineSeconds"), *gate.ProgressDeadlineSeconds, "must be greater than bakeSeconds"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateDisruptionBudget checks that one and only one of minAvailable and maxUnavailable is set.
+func validateDisruptionBudget(budget *unitv1alpha1.PoolDisruptionBudget, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if budget == nil {
+		return allErrs
+	}
+
+	if budget.MinAvailable != nil && budget.MaxUnavailable != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath, budget, "minAvailable and maxUnavailable cannot be both set"))
+	} else if budget.MinAvailable == nil && budget.MaxUnavailable == nil {
+		allErrs = append(allErrs, field.Required(fldPath, "one of minAvailable and maxUnavailable must be set"))
+	}
+	if budget.MinAvailable != nil {
+		allErrs = append(allErrs, appsvalidation.ValidatePositiveIntOrPercent(*budget.MinAvailable, fldPath.Child("minAvailable"))...)
+		allErrs = append(allErrs, appsvalidation.IsNotMoreThan100Percent(*budget.MinAvailable, fldPath.Child("minAvailable"))...)
+	}
+	if budget.MaxUnavailable != nil {
+		allErrs = append(allErrs, appsvalidation.ValidatePositiveIntOrPercent(*budget.MaxUnavailable, fldPath.Child("maxUnavailable"))...)
+		allErrs = append(allErrs, appsvalidation.IsNotMoreThan100Percent(*budget.MaxUnavailable, fldPath.Child("maxUnavailable"))...)
+	}
+	return allErrs
+}
+
+// validateTopologySpread checks the topology key, the skew and the action of the pool topology spread.
+func validateTopologySpread(spread *unitv1alpha1.PoolTopologySpread, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if spread == nil {
+		return allErrs
+	}
+
+	if spread.TopologyKey == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("topologyKey"), ""))
+	} else {
+		allErrs = append(allErrs, unversionedvalidation.ValidateLabelName(spread.TopologyKey, fldPath.Child("topologyKey"))...)
+	}
+	if spread.MaxSkew < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("maxSkew"), spread.MaxSkew, "must be greater than or equal to 0"))
+	}
+	switch spread.WhenUnsatisfiable {
+	case "", v1.DoNotSchedule, v1.ScheduleAnyway:
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("whenUnsatisfiable"), spread.WhenUnsatisfiable,
+			[]string{string(v1.DoNotSchedule), string(v1.ScheduleAnyway)}))
+	}
 	return allErrs
 }
 
@@ -177,6 +349,10 @@ func validateWorkloadTemplateUpdate(template, oldTemplate *unitv1alpha1.Workload
 		allErrs = append(allErrs, validateDeploymentUpdate(template.DeploymentTemplate, oldTemplate.DeploymentTemplate,
 			fldPath.Child("deploymentTemplate"))...)
 	}
+	if template.DaemonSetTemplate != nil && oldTemplate.DaemonSetTemplate != nil {
+		allErrs = append(allErrs, validateDaemonSetUpdate(template.DaemonSetTemplate, oldTemplate.DaemonSetTemplate,
+			fldPath.Child("daemonSetTemplate"))...)
+	}
 	return allErrs
 }
 
@@ -192,6 +368,9 @@ func validatePoolTemplate(template *unitv1alpha1.WorkloadTemplate, spec *unitv1a
 	if template.DeploymentTemplate != nil {
 		templateCount++
 	}
+	if template.DaemonSetTemplate != nil {
+		templateCount++
+	}
 
 	if templateCount < 1 {
 		allErrs = append(allErrs, field.Required(fldPath, "should provide one of (statefulSetTemplate/deploymentTemplate/daemonSetTemplate)"))
@@ -211,7 +390,7 @@ func validatePoolTemplate(template *unitv1alpha1.WorkloadTemplate, spec *unitv1a
 			allErrs = append(allErrs, field.Invalid(fldPath.Root(), sstemplate, fmt.Sprintf("Convert_v1_PodTemplateSpec_To_core_PodTemplateSpec failed: %v", err)))
 			return allErrs
 		}
-		allErrs = append(allErrs, appsvalidation.ValidatePodTemplateSpecForStatefulSet(coreTemplate, selector, fldPath.Child("statefulSetTemplate", "spec", "template"))...)
+		allErrs = append(allErrs, appsvalidation.ValidatePodTemplateSpecForStatefulSet(coreTemplate, selector, fldPath.Child("statefulSetTemplate", "spec", "template"), apivalidation.PodValidationOptions{})...)
 	}
 
 	if template.DeploymentTemplate != nil {
@@ -229,7 +408,28 @@ func validatePoolTemplate(template *unitv1alpha1.WorkloadTemplate, spec *unitv1a
 		}
 		allErrs = append(allErrs, validatePodTemplateSpec(coreTemplate, selector, fldPath.Child("deploymentTemplate", "spec", "template"))...)
 		allErrs = append(allErrs, apivalidation.ValidatePodTemplateSpec(coreTemplate,
-			fldPath.Child("deploymentTemplate", "spec", "template"))...)
+			fldPath.Child("deploymentTemplate", "spec", "template"), apivalidation.PodValidationOptions{})...)
+	}
+
+	if template.DaemonSetTemplate != nil {
+		labels := labels.Set(template.DaemonSetTemplate.Labels)
+		if !selector.Matches(labels) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("daemonSetTemplate", "metadata", "labels"),
+				template.DaemonSetTemplate.Labels, "`selector` does not match template `labels`"))
+		}
+		template := template.DaemonSetTemplate.Spec.Template
+		coreTemplate, err := convertPodTemplateSpec(&template)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Root(), template, fmt.Sprintf("Convert_v1_PodTemplateSpec_To_core_PodTemplateSpec failed: %v", err)))
+			return allErrs
+		}
+		allErrs = append(allErrs, validatePodTemplateSpec(coreTemplate, selector, fldPath.Child("daemonSetTemplate", "spec", "template"))...)
+		allErrs = append(allErrs, apivalidation.ValidatePodTemplateSpec(coreTemplate,
+			fldPath.Child("daemonSetTemplate", "spec", "template"), apivalidation.PodValidationOptions{})...)
+		if coreTemplate.Spec.RestartPolicy != core.RestartPolicyAlways {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("daemonSetTemplate", "spec", "template", "spec", "restartPolicy"),
+				coreTemplate.Spec.RestartPolicy, []string{string(core.RestartPolicyAlways)}))
+		}
 	}
 
 	return allErrs
@@ -301,6 +501,29 @@ func validateDeploymentUpdate(deployment, oldDeployment *unitv1alpha1.Deployment
 
 }
 
+func validateDaemonSetUpdate(daemonSet, oldDaemonSet *unitv1alpha1.DaemonSetTemplateSpec,
+	fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	restoreTemplate := daemonSet.Spec.Template
+	daemonSet.Spec.Template = oldDaemonSet.Spec.Template
+
+	restoreStrategy := daemonSet.Spec.UpdateStrategy
+	daemonSet.Spec.UpdateStrategy = oldDaemonSet.Spec.UpdateStrategy
+
+	restoreMinReadySeconds := daemonSet.Spec.MinReadySeconds
+	daemonSet.Spec.MinReadySeconds = oldDaemonSet.Spec.MinReadySeconds
+
+	if !apiequality.Semantic.DeepEqual(daemonSet.Spec, oldDaemonSet.Spec) {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("spec"),
+			"updates to daemonSetTemplate spec for fields other than 'template', 'updateStrategy' and 'minReadySeconds' are forbidden"))
+	}
+	daemonSet.Spec.Template = restoreTemplate
+	daemonSet.Spec.UpdateStrategy = restoreStrategy
+	daemonSet.Spec.MinReadySeconds = restoreMinReadySeconds
+
+	return allErrs
+}
+
 func validateStatefulSetUpdate(statefulSet, oldStatefulSet *unitv1alpha1.StatefulSetTemplateSpec, fldPath *field.Path) field.ErrorList {
 	allErrs := field.ErrorList{}
 	restoreReplicas := statefulSet.Spec.Replicas
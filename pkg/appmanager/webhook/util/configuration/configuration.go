@@ -38,6 +38,9 @@ const (
 	validatingWebhookConfigurationName = "app-validating-webhook-configuration"
 )
 
+// Ensure updates the webhook configurations from their templates with the given caBundle.
+// If caBundle is empty, e.g. the issuer of the serving cert does not provide its CA, the caBundle
+// of every webhook is kept as is, so that the one injected by cert-manager is not overwritten.
 func Ensure(c client.Client, handlers map[string]webhookutil.Handler, caBundle []byte) error {
 	mutatingConfig := &v1beta1.MutatingWebhookConfiguration{}
 	if err := c.Get(context.TODO(), types.NamespacedName{Name: mutatingWebhookConfigurationName}, mutatingConfig); err != nil {
@@ -57,10 +60,17 @@ func Ensure(c client.Client, handlers map[string]webhookutil.Handler, caBundle [
 		return err
 	}
 
+	mutatingCABundles := make(map[string][]byte, len(mutatingConfig.Webhooks))
+	for _, wh := range mutatingConfig.Webhooks {
+		mutatingCABundles[wh.Name] = wh.ClientConfig.CABundle
+	}
 	var mutatingWHs []v1beta1.MutatingWebhook
 	for i := range mutatingTemplate {
 		wh := &mutatingTemplate[i]
 		wh.ClientConfig.CABundle = caBundle
+		if len(caBundle) == 0 {
+			wh.ClientConfig.CABundle = mutatingCABundles[wh.Name]
+		}
 		path, err := getPath(&wh.ClientConfig)
 		if err != nil {
 			return err
@@ -80,10 +90,17 @@ func Ensure(c client.Client, handlers map[string]webhookutil.Handler, caBundle [
 	}
 	mutatingConfig.Webhooks = mutatingWHs
 
+	validatingCABundles := make(map[string][]byte, len(validatingConfig.Webhooks))
+	for _, wh := range validatingConfig.Webhooks {
+		validatingCABundles[wh.Name] = wh.ClientConfig.CABundle
+	}
 	var validatingWHs []v1beta1.ValidatingWebhook
 	for i := range validatingTemplate {
 		wh := &validatingTemplate[i]
 		wh.ClientConfig.CABundle = caBundle
+		if len(caBundle) == 0 {
+			wh.ClientConfig.CABundle = validatingCABundles[wh.Name]
+		}
 		path, err := getPath(&wh.ClientConfig)
 		if err != nil {
 			return err
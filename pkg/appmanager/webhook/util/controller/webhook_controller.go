@@ -52,8 +52,9 @@ var (
 	mutatingWebhookConfigurationName   = "app-mutating-webhook-configuration"
 	validatingWebhookConfigurationName = "app-validating-webhook-configuration"
 
-	namespace  = webhookutil.GetNamespace()
-	secretName = webhookutil.GetSecretName()
+	namespace    = webhookutil.GetNamespace()
+	secretName   = webhookutil.GetSecretName()
+	caSecretName = webhookutil.GetCASecretName()
 
 	uninit   = make(chan struct{})
 	onceInit = sync.Once{}
@@ -104,15 +105,15 @@ func New(cli client.Client, handlers map[string]webhookutil.Handler) (*Controlle
 	secretInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			secret := obj.(*v1.Secret)
-			if secret.Name == secretName {
-				klog.Infof("Secret %s/%s added", secret.GetNamespace(), secretName)
+			if secret.Name == secretName || secret.Name == caSecretName {
+				klog.Infof("Secret %s/%s added", secret.GetNamespace(), secret.Name)
 				c.queue.Add("")
 			}
 		},
 		UpdateFunc: func(old, cur interface{}) {
 			secret := cur.(*v1.Secret)
-			if secret.Name == secretName {
-				klog.Infof("Secret %s/%s updated", secret.GetNamespace(), secretName)
+			if secret.Name == secretName || secret.Name == caSecretName {
+				klog.Infof("Secret %s/%s updated", secret.GetNamespace(), secret.Name)
 				c.queue.Add("")
 			}
 		},
@@ -203,18 +204,37 @@ func (c *Controller) sync() error {
 
 	var dnsName string
 	var certWriter writer.CertWriter
+	var certGenerator generator.CertGenerator
 	var err error
 
-	if dnsName = webhookutil.GetHost(); len(dnsName) > 0 {
+	certSource := webhookutil.GetCertSource()
+	if certSource == webhookutil.CertSourceExternalCA {
+		if certGenerator, err = c.getExternalCAGenerator(); err != nil {
+			return err
+		}
+	}
+
+	if dnsName = webhookutil.GetHost(); len(dnsName) == 0 {
+		dnsName = generator.ServiceToCommonName(webhookutil.GetNamespace(), webhookutil.GetServiceName())
+	}
+	switch {
+	case certSource == webhookutil.CertSourceCertManager:
+		certWriter, err = writer.NewCertManagerCertWriter(writer.CertManagerCertWriterOptions{
+			Client: c.runtimeClient,
+			Secret: &types.NamespacedName{Namespace: webhookutil.GetNamespace(), Name: webhookutil.GetSecretName()},
+		})
+		klog.Infof("Use cert-manager Cert Writer")
+	case len(webhookutil.GetHost()) > 0:
 		certWriter, err = writer.NewFSCertWriter(writer.FSCertWriterOptions{
-			Path: webhookutil.GetCertDir(),
+			CertGenerator: certGenerator,
+			Path:          webhookutil.GetCertDir(),
 		})
 		klog.Infof("Use Fs Cert Writer")
-	} else {
-		dnsName = generator.ServiceToCommonName(webhookutil.GetNamespace(), webhookutil.GetServiceName())
+	default:
 		certWriter, err = writer.NewSecretCertWriter(writer.SecretCertWriterOptions{
-			Client: c.runtimeClient,
-			Secret: &types.NamespacedName{Namespace: webhookutil.GetNamespace(), Name: webhookutil.GetSecretName()},
+			Client:        c.runtimeClient,
+			CertGenerator: certGenerator,
+			Secret:        &types.NamespacedName{Namespace: webhookutil.GetNamespace(), Name: webhookutil.GetSecretName()},
 		})
 		klog.Infof("Use Secret Cert Writer")
 	}
@@ -239,3 +259,21 @@ func (c *Controller) sync() error {
 	})
 	return nil
 }
+
+// getExternalCAGenerator returns the generator signing with the CA of the CA secret,
+// given as ca-key.pem and ca-cert.pem, or as tls.key and tls.crt.
+func (c *Controller) getExternalCAGenerator() (generator.CertGenerator, error) {
+	secret, err := c.secretLister.Get(caSecretName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CA secret %s/%s: %v", namespace, caSecretName, err)
+	}
+	caKey, caCert := secret.Data[writer.CAKeyName], secret.Data[writer.CACertName]
+	if len(caKey) == 0 || len(caCert) == 0 {
+		caKey, caCert = secret.Data[v1.TLSPrivateKeyKey], secret.Data[v1.TLSCertKey]
+	}
+	certGenerator, err := generator.NewCASignedCertGenerator(caKey, caCert)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA secret %s/%s: %v", namespace, caSecretName, err)
+	}
+	return certGenerator, nil
+}
@@ -0,0 +1,107 @@
+package generator
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+
+	"k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+)
+
+// CASignedCertGenerator generates serving certs signed by a CA provided by the user,
+// e.g. the intermediate CA of the cluster PKI. The CA is never replaced by the generator.
+type CASignedCertGenerator struct {
+	caKey  crypto.Signer
+	caCert *x509.Certificate
+	// caCertPEM is the PEM encoded CA certificate, possibly with its chain.
+	caCertPEM []byte
+}
+
+var _ CertGenerator = &CASignedCertGenerator{}
+
+// NewCASignedCertGenerator returns a CASignedCertGenerator signing with the PEM encoded CA
+// private key and certificate. The first certificate of caCert must be the one of caKey.
+func NewCASignedCertGenerator(caKey, caCert []byte) (*CASignedCertGenerator, error) {
+	key, err := keyutil.ParsePrivateKeyPEM(caKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the CA private key: %v", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("the CA private key can not sign certificates")
+	}
+	certs, err := cert.ParseCertsPEM(caCert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the CA cert: %v", err)
+	}
+	if !certs[0].IsCA {
+		return nil, errors.New("the CA cert is not a certificate authority")
+	}
+	if time.Now().After(certs[0].NotAfter) {
+		return nil, fmt.Errorf("the CA cert expired at %s", certs[0].NotAfter)
+	}
+	if !signerMatchesCert(signer, certs[0]) {
+		return nil, errors.New("the CA private key does not match the CA cert")
+	}
+	return &CASignedCertGenerator{
+		caKey:     signer,
+		caCert:    certs[0],
+		caCertPEM: caCert,
+	}, nil
+}
+
+// SetCA is a no-op, the CA is set by NewCASignedCertGenerator.
+func (cp *CASignedCertGenerator) SetCA(_, _ []byte) {}
+
+// CACert returns the PEM encoded CA certificate.
+func (cp *CASignedCertGenerator) CACert() []byte {
+	return cp.caCertPEM
+}
+
+// Generate returns a serving cert signed by the CA. The CA private key is not part of the
+// returned Artifacts, so it is never written next to the serving cert.
+func (cp *CASignedCertGenerator) Generate(commonName string) (*Artifacts, error) {
+	key, signedCert, err := newServingCert(commonName, cp.caCert, cp.caKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Artifacts{
+		Key:    EncodePrivateKeyPEM(key),
+		Cert:   EncodeCertPEM(signedCert),
+		CACert: cp.caCertPEM,
+	}, nil
+}
+
+func signerMatchesCert(signer crypto.Signer, c *x509.Certificate) bool {
+	type publicKey interface {
+		Equal(crypto.PublicKey) bool
+	}
+	pub, ok := signer.Public().(publicKey)
+	if !ok {
+		return false
+	}
+	return pub.Equal(c.PublicKey)
+}
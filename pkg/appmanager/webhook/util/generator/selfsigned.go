@@ -85,6 +85,20 @@ func (cp *SelfSignedCertGenerator) Generate(commonName string) (*Artifacts, erro
 		}
 	}
 
+	key, signedCert, err := newServingCert(commonName, signingCert, signingKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Artifacts{
+		Key:    EncodePrivateKeyPEM(key),
+		Cert:   EncodeCertPEM(signedCert),
+		CAKey:  EncodePrivateKeyPEM(signingKey),
+		CACert: EncodeCertPEM(signingCert),
+	}, nil
+}
+
+// newServingCert creates a private key and a serving cert for commonName signed by the CA.
+func newServingCert(commonName string, caCert *x509.Certificate, caKey crypto.Signer) (*rsa.PrivateKey, *x509.Certificate, error) {
 	hostIP := net.ParseIP(commonName)
 	var altIPs []net.IP
 	DNSNames := []string{"localhost"}
@@ -96,7 +110,7 @@ func (cp *SelfSignedCertGenerator) Generate(commonName string) (*Artifacts, erro
 
 	key, err := NewPrivateKey()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create the private key: %v", err)
+		return nil, nil, fmt.Errorf("failed to create the private key: %v", err)
 	}
 	signedCert, err := NewSignedCert(
 		cert.Config{
@@ -104,17 +118,12 @@ func (cp *SelfSignedCertGenerator) Generate(commonName string) (*Artifacts, erro
 			Usages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
 			AltNames:   cert.AltNames{IPs: altIPs, DNSNames: DNSNames},
 		},
-		key, signingCert, signingKey,
+		key, caCert, caKey,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create the cert: %v", err)
+		return nil, nil, fmt.Errorf("failed to create the cert: %v", err)
 	}
-	return &Artifacts{
-		Key:    EncodePrivateKeyPEM(key),
-		Cert:   EncodeCertPEM(signedCert),
-		CAKey:  EncodePrivateKeyPEM(signingKey),
-		CACert: EncodeCertPEM(signingCert),
-	}, nil
+	return key, signedCert, nil
 }
 
 func (cp *SelfSignedCertGenerator) validCACert() (bool, *rsa.PrivateKey, *x509.Certificate) {
@@ -161,6 +170,11 @@ func NewSignedCert(cfg cert.Config, key crypto.Signer, caCert *x509.Certificate,
 		return nil, errors.New("must specify at least one ExtKeyUsage")
 	}
 
+	// The cert can not outlive the CA which signs it.
+	notAfter := time.Now().Add(time.Hour * 24 * 365 * 10).UTC()
+	if notAfter.After(caCert.NotAfter) {
+		notAfter = caCert.NotAfter
+	}
 	certTmpl := x509.Certificate{
 		Subject: pkix.Name{
 			CommonName:   cfg.CommonName,
@@ -170,7 +184,7 @@ func NewSignedCert(cfg cert.Config, key crypto.Signer, caCert *x509.Certificate,
 		IPAddresses:  cfg.AltNames.IPs,
 		SerialNumber: serial,
 		NotBefore:    caCert.NotBefore,
-		NotAfter:     time.Now().Add(time.Hour * 24 * 365 * 10).UTC(),
+		NotAfter:     notAfter,
 		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
 		ExtKeyUsage:  cfg.Usages,
 	}
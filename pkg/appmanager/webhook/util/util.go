@@ -27,6 +27,15 @@ import (
 	"k8s.io/klog"
 )
 
+const (
+	// CertSourceSelfSigned generates a self-signed CA and the serving cert signed by it.
+	CertSourceSelfSigned = "self-signed"
+	// CertSourceCertManager uses the serving cert issued by cert-manager in the webhook secret.
+	CertSourceCertManager = "cert-manager"
+	// CertSourceExternalCA generates the serving cert signed by the CA of the CA secret.
+	CertSourceExternalCA = "external-ca"
+)
+
 func GetHost() string {
 	return os.Getenv("WEBHOOK_HOST")
 }
@@ -70,3 +79,23 @@ func GetCertDir() string {
 	}
 	return "/tmp/app-webhook-certs"
 }
+
+func GetCertSource() string {
+	switch source := os.Getenv("WEBHOOK_CERT_SOURCE"); source {
+	case CertSourceCertManager, CertSourceExternalCA:
+		return source
+	case "", CertSourceSelfSigned:
+		return CertSourceSelfSigned
+	default:
+		klog.Fatalf("unsupported WEBHOOK_CERT_SOURCE=%v in env, must be one of %s, %s and %s",
+			source, CertSourceSelfSigned, CertSourceCertManager, CertSourceExternalCA)
+		return ""
+	}
+}
+
+func GetCASecretName() string {
+	if name := os.Getenv("WEBHOOK_CA_SECRET_NAME"); len(name) > 0 {
+		return name
+	}
+	return "app-webhook-ca"
+}
@@ -0,0 +1,126 @@
+package writer
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/cert"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/bhojpur/dcp/pkg/appmanager/webhook/util/generator"
+)
+
+const (
+	// CertManagerCAName is the name of the CA certificate in the secrets issued by cert-manager
+	CertManagerCAName = "ca.crt"
+)
+
+// certManagerCertWriter reads the certificate issued by cert-manager from the secret
+// of a Certificate resource. It never writes the secret, renewals are done by cert-manager.
+type certManagerCertWriter struct {
+	*CertManagerCertWriterOptions
+}
+
+// CertManagerCertWriterOptions is options for constructing a certManagerCertWriter.
+type CertManagerCertWriterOptions struct {
+	// Client talks to a kubernetes cluster for reading the secret.
+	Client client.Client
+	// Secret points the secretName of the cert-manager Certificate.
+	Secret *types.NamespacedName
+}
+
+var _ CertWriter = &certManagerCertWriter{}
+
+func (ops *CertManagerCertWriterOptions) validate() error {
+	if ops.Client == nil {
+		return errors.New("client must be set in CertManagerCertWriterOptions")
+	}
+	if ops.Secret == nil {
+		return errors.New("secret must be set in CertManagerCertWriterOptions")
+	}
+	return nil
+}
+
+// NewCertManagerCertWriter constructs a CertWriter that uses the certificate issued by cert-manager.
+func NewCertManagerCertWriter(ops CertManagerCertWriterOptions) (CertWriter, error) {
+	if err := ops.validate(); err != nil {
+		return nil, err
+	}
+	return &certManagerCertWriter{
+		CertManagerCertWriterOptions: &ops,
+	}, nil
+}
+
+// EnsureCert returns the certificate issued by cert-manager, or an error if it is not issued
+// yet or not valid for dnsName. The CACert of the returned certs is empty if the issuer does
+// not provide its CA, the caBundle of the webhooks is then expected to be injected by cert-manager.
+func (c *certManagerCertWriter) EnsureCert(dnsName string) (*generator.Artifacts, bool, error) {
+	secret := &corev1.Secret{}
+	if err := c.Client.Get(context.TODO(), *c.Secret, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, false, fmt.Errorf("secret %s is not issued by cert-manager yet", c.Secret.String())
+		}
+		return nil, false, err
+	}
+
+	certs := &generator.Artifacts{
+		Cert:            secret.Data[corev1.TLSCertKey],
+		Key:             secret.Data[corev1.TLSPrivateKeyKey],
+		CACert:          secret.Data[CertManagerCAName],
+		ResourceVersion: secret.ResourceVersion,
+	}
+	if len(certs.CACert) > 0 {
+		if !validCert(certs, dnsName, time.Now()) {
+			return nil, false, fmt.Errorf("cert of secret %s is invalid for %s", c.Secret.String(), dnsName)
+		}
+		return certs, false, nil
+	}
+	if err := verifyServingCert(certs, dnsName); err != nil {
+		return nil, false, fmt.Errorf("cert of secret %s is invalid: %v", c.Secret.String(), err)
+	}
+	return certs, false, nil
+}
+
+// verifyServingCert checks the cert without its CA: the key pair, the DNS name and the validity period.
+func verifyServingCert(certs *generator.Artifacts, dnsName string) error {
+	if _, err := tls.X509KeyPair(certs.Cert, certs.Key); err != nil {
+		return err
+	}
+	servingCerts, err := cert.ParseCertsPEM(certs.Cert)
+	if err != nil {
+		return err
+	}
+	if err := servingCerts[0].VerifyHostname(dnsName); err != nil {
+		return err
+	}
+	if now := time.Now(); now.Before(servingCerts[0].NotBefore) || now.After(servingCerts[0].NotAfter) {
+		return fmt.Errorf("cert is only valid from %s to %s", servingCerts[0].NotBefore, servingCerts[0].NotAfter)
+	}
+	return nil
+}
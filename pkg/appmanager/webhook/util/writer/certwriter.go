@@ -21,12 +21,14 @@ package writer
 // THE SOFTWARE.
 
 import (
+	"bytes"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
 	"time"
 
+	"k8s.io/client-go/util/cert"
 	"k8s.io/klog"
 
 	"github.com/bhojpur/dcp/pkg/appmanager/webhook/util/generator"
@@ -51,9 +53,16 @@ type CertWriter interface {
 	EnsureCert(dnsName string) (*generator.Artifacts, bool, error)
 }
 
+// fixedCA is implemented by the generators which always sign with the same CA,
+// e.g. generator.CASignedCertGenerator.
+type fixedCA interface {
+	CACert() []byte
+}
+
 // handleCommon ensures the given webhook has a proper certificate.
-// It uses the given certReadWriter to read and (or) write the certificate.
-func handleCommon(dnsName string, ch certReadWriter) (*generator.Artifacts, bool, error) {
+// It uses the given certReadWriter to read and (or) write the certificate,
+// and regenerates it if the generator signs with another CA.
+func handleCommon(dnsName string, ch certReadWriter, gen generator.CertGenerator) (*generator.Artifacts, bool, error) {
 	if len(dnsName) == 0 {
 		return nil, false, errors.New("dnsName should not be empty")
 	}
@@ -66,7 +75,20 @@ func handleCommon(dnsName string, ch certReadWriter) (*generator.Artifacts, bool
 		return nil, changed, err
 	}
 	// Recreate the cert if it's invalid.
-	valid := validCert(certs, dnsName)
+	at := time.Now().AddDate(0, 6, 0)
+	ca, isFixedCA := gen.(fixedCA)
+	if isFixedCA {
+		// a cert never outlives its CA, which the generator can not renew
+		if caExpiry, ok := caNotAfter(ca.CACert()); ok && caExpiry.Before(at) {
+			klog.Warningf("the CA of the webhook cert expires at %s, renew the CA to extend the cert", caExpiry)
+			at = caExpiry
+		}
+	}
+	valid := validCert(certs, dnsName, at)
+	if isFixedCA && valid && !bytes.Equal(certs.CACert, ca.CACert()) {
+		klog.Info("cert is signed by another CA, regenerating a new one")
+		valid = false
+	}
 	if !valid {
 		klog.Info("cert is invalid or expiring, regenerating a new one")
 		certs, err = ch.overwrite(certs.ResourceVersion)
@@ -78,6 +100,21 @@ func handleCommon(dnsName string, ch certReadWriter) (*generator.Artifacts, bool
 	return certs, changed, nil
 }
 
+// caNotAfter returns the earliest expiry of the PEM encoded CA certificates.
+func caNotAfter(caCert []byte) (time.Time, bool) {
+	cas, err := cert.ParseCertsPEM(caCert)
+	if err != nil {
+		return time.Time{}, false
+	}
+	notAfter := cas[0].NotAfter
+	for _, ca := range cas[1:] {
+		if ca.NotAfter.Before(notAfter) {
+			notAfter = ca.NotAfter
+		}
+	}
+	return notAfter, true
+}
+
 func createIfNotExists(ch certReadWriter) (*generator.Artifacts, bool, error) {
 	// Try to read first
 	certs, err := ch.read()
@@ -106,7 +143,8 @@ type certReadWriter interface {
 	overwrite(resourceVersion string) (*generator.Artifacts, error)
 }
 
-func validCert(certs *generator.Artifacts, dnsName string) bool {
+// validCert checks the cert is signed by the CA of certs, is for dnsName and is still valid at the given time.
+func validCert(certs *generator.Artifacts, dnsName string, at time.Time) bool {
 	if certs == nil || certs.Cert == nil || certs.Key == nil || certs.CACert == nil {
 		klog.Errorf("valid cert error is null")
 		return false
@@ -138,7 +176,7 @@ func validCert(certs *generator.Artifacts, dnsName string) bool {
 	ops := x509.VerifyOptions{
 		DNSName:     dnsName,
 		Roots:       pool,
-		CurrentTime: time.Now().AddDate(0, 6, 0),
+		CurrentTime: at,
 	}
 	_, err = cert.Verify(ops)
 	if err != nil {
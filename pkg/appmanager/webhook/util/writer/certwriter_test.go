@@ -0,0 +1,222 @@
+package writer
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/cert"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/bhojpur/dcp/pkg/appmanager/webhook/util/generator"
+)
+
+const testDNSName = "app-webhook-service.kube-system.svc"
+
+func newTestCA(t *testing.T, commonName string) *generator.CASignedCertGenerator {
+	key, err := generator.NewPrivateKey()
+	if err != nil {
+		t.Fatalf("failed to create the CA key: %v", err)
+	}
+	caCert, err := cert.NewSelfSignedCACert(cert.Config{CommonName: commonName}, key)
+	if err != nil {
+		t.Fatalf("failed to create the CA cert: %v", err)
+	}
+	gen, err := generator.NewCASignedCertGenerator(generator.EncodePrivateKeyPEM(key), generator.EncodeCertPEM(caCert))
+	if err != nil {
+		t.Fatalf("failed to create the generator: %v", err)
+	}
+	return gen
+}
+
+// newShortLivedTestCA returns a generator signing with a CA which expires in validFor.
+func newShortLivedTestCA(t *testing.T, commonName string, validFor time.Duration) *generator.CASignedCertGenerator {
+	key, err := generator.NewPrivateKey()
+	if err != nil {
+		t.Fatalf("failed to create the CA key: %v", err)
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Hour).UTC(),
+		NotAfter:              now.Add(validFor).UTC(),
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		t.Fatalf("failed to create the CA cert: %v", err)
+	}
+	caCert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse the CA cert: %v", err)
+	}
+	gen, err := generator.NewCASignedCertGenerator(generator.EncodePrivateKeyPEM(key), generator.EncodeCertPEM(caCert))
+	if err != nil {
+		t.Fatalf("failed to create the generator: %v", err)
+	}
+	return gen
+}
+
+func TestSecretCertWriterWithShortLivedCA(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	cli := fake.NewClientBuilder().WithScheme(scheme).Build()
+	secret := &types.NamespacedName{Namespace: "kube-system", Name: "app-webhook-certs"}
+	ca := newShortLivedTestCA(t, "short-lived-ca", 30*24*time.Hour)
+
+	w, err := NewSecretCertWriter(SecretCertWriterOptions{Client: cli, CertGenerator: ca, Secret: secret})
+	if err != nil {
+		t.Fatalf("failed to create the writer: %v", err)
+	}
+	certs, changed, err := w.EnsureCert(testDNSName)
+	if err != nil || !changed {
+		t.Fatalf("expect the cert to be created, but got %v, %v", changed, err)
+	}
+	again, changed, err := w.EnsureCert(testDNSName)
+	if err != nil {
+		t.Fatalf("failed to ensure the cert: %v", err)
+	}
+	if changed || !bytes.Equal(again.Cert, certs.Cert) {
+		t.Fatalf("expect the cert capped to the expiry of the CA to be kept")
+	}
+}
+
+func TestSecretCertWriterWithExternalCA(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	cli := fake.NewClientBuilder().WithScheme(scheme).Build()
+	secret := &types.NamespacedName{Namespace: "kube-system", Name: "app-webhook-certs"}
+
+	ensure := func(gen generator.CertGenerator) *generator.Artifacts {
+		w, err := NewSecretCertWriter(SecretCertWriterOptions{Client: cli, CertGenerator: gen, Secret: secret})
+		if err != nil {
+			t.Fatalf("failed to create the writer: %v", err)
+		}
+		certs, _, err := w.EnsureCert(testDNSName)
+		if err != nil {
+			t.Fatalf("failed to ensure the cert: %v", err)
+		}
+		return certs
+	}
+
+	ca := newTestCA(t, "pki-ca")
+	certs := ensure(ca)
+	if !bytes.Equal(certs.CACert, ca.CACert()) || len(certs.CAKey) != 0 {
+		t.Fatalf("expect the cert signed by the external CA without its key")
+	}
+	if again := ensure(ca); !bytes.Equal(again.Cert, certs.Cert) {
+		t.Fatalf("expect the valid cert to be kept")
+	}
+
+	rotated := newTestCA(t, "pki-ca-2")
+	certs = ensure(rotated)
+	if !bytes.Equal(certs.CACert, rotated.CACert()) || !validCert(certs, testDNSName, time.Now()) {
+		t.Fatalf("expect the cert to be regenerated with the rotated CA")
+	}
+}
+
+func TestCertManagerCertWriter(t *testing.T) {
+	ca := newTestCA(t, "cert-manager-ca")
+	issued, err := ca.Generate(testDNSName)
+	if err != nil {
+		t.Fatalf("failed to generate the cert: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		data      map[string][]byte
+		dnsName   string
+		expectErr bool
+	}{
+		{
+			name:      "not issued",
+			dnsName:   testDNSName,
+			expectErr: true,
+		},
+		{
+			name:    "issued with CA",
+			data:    map[string][]byte{corev1.TLSCertKey: issued.Cert, corev1.TLSPrivateKeyKey: issued.Key, CertManagerCAName: issued.CACert},
+			dnsName: testDNSName,
+		},
+		{
+			name:    "issued without CA",
+			data:    map[string][]byte{corev1.TLSCertKey: issued.Cert, corev1.TLSPrivateKeyKey: issued.Key},
+			dnsName: testDNSName,
+		},
+		{
+			name:      "issued for another name",
+			data:      map[string][]byte{corev1.TLSCertKey: issued.Cert, corev1.TLSPrivateKeyKey: issued.Key},
+			dnsName:   "other.kube-system.svc",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		st := tt
+		tf := func(t *testing.T) {
+			t.Parallel()
+			scheme := runtime.NewScheme()
+			_ = clientgoscheme.AddToScheme(scheme)
+			builder := fake.NewClientBuilder().WithScheme(scheme)
+			if st.data != nil {
+				builder = builder.WithObjects(&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "app-webhook-certs"},
+					Data:       st.data,
+				})
+			}
+			w, err := NewCertManagerCertWriter(CertManagerCertWriterOptions{
+				Client: builder.Build(),
+				Secret: &types.NamespacedName{Namespace: "kube-system", Name: "app-webhook-certs"},
+			})
+			if err != nil {
+				t.Fatalf("failed to create the writer: %v", err)
+			}
+			certs, _, err := w.EnsureCert(st.dnsName)
+			if st.expectErr {
+				if err == nil {
+					t.Fatalf("expect an error, but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to ensure the cert: %v", err)
+			}
+			if !bytes.Equal(certs.CACert, st.data[CertManagerCAName]) {
+				t.Fatalf("expect the CA of the secret, but got %s", certs.CACert)
+			}
+		}
+		t.Run(st.name, tf)
+	}
+}
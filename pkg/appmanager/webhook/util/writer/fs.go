@@ -79,7 +79,7 @@ func NewFSCertWriter(ops FSCertWriterOptions) (CertWriter, error) {
 func (f *fsCertWriter) EnsureCert(dnsName string) (*generator.Artifacts, bool, error) {
 	// create or refresh cert and write it to fs
 	f.dnsName = dnsName
-	return handleCommon(f.dnsName, f)
+	return handleCommon(f.dnsName, f, f.CertGenerator)
 }
 
 func (f *fsCertWriter) write() (*generator.Artifacts, error) {
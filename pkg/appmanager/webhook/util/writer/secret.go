@@ -86,7 +86,7 @@ func NewSecretCertWriter(ops SecretCertWriterOptions) (CertWriter, error) {
 func (s *secretCertWriter) EnsureCert(dnsName string) (*generator.Artifacts, bool, error) {
 	// Create or refresh the certs based on clientConfig
 	s.dnsName = dnsName
-	return handleCommon(s.dnsName, s)
+	return handleCommon(s.dnsName, s, s.CertGenerator)
 }
 
 var _ certReadWriter = &secretCertWriter{}
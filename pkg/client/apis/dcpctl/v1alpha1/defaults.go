@@ -0,0 +1,62 @@
+package v1alpha1
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"fmt"
+	"path"
+
+	dcpconstants "github.com/bhojpur/dcp/pkg/client/constants"
+	"github.com/bhojpur/dcp/pkg/client/kubernetes/kubeadm/app/constants"
+)
+
+// SetDefaults_JoinConfiguration sets the default values of the unset fields in JoinConfiguration
+func SetDefaults_JoinConfiguration(obj *JoinConfiguration) {
+	obj.APIVersion = SchemeGroupVersion.String()
+	obj.Kind = JoinConfigurationKind
+
+	if obj.NodeRegistration.WorkingMode == "" {
+		obj.NodeRegistration.WorkingMode = dcpconstants.EdgeNode
+	}
+	if obj.NodeRegistration.CRISocket == "" {
+		obj.NodeRegistration.CRISocket = constants.DefaultDockerCRISocket
+	}
+
+	registry := obj.ImageRegistry
+	if obj.Engine.Image == "" {
+		if registry == "" {
+			registry = dcpconstants.DefaultDcpImageRegistry
+		}
+		obj.Engine.Image = fmt.Sprintf("%s/%s:%s", registry, dcpconstants.Engine, dcpconstants.DefaultDcpVersion)
+	}
+	if obj.PauseImage == "" {
+		if obj.ImageRegistry == "" {
+			obj.PauseImage = dcpconstants.PauseImagePath
+		} else {
+			// keep the name and tag of the default pause image
+			obj.PauseImage = fmt.Sprintf("%s/%s", obj.ImageRegistry, path.Base(dcpconstants.PauseImagePath))
+		}
+	}
+
+	if obj.KubernetesResourceServer == "" {
+		obj.KubernetesResourceServer = dcpconstants.DefaultKubernetesResourceServer
+	}
+}
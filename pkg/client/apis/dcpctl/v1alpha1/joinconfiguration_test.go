@@ -0,0 +1,238 @@
+package v1alpha1
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	dcpconstants "github.com/bhojpur/dcp/pkg/client/constants"
+)
+
+const testCACertHash = "sha256:7e7f4c8d8ea09b4a3d5a7ab4ee1e2ae2b1a3a7a7c6ee6c7de2bf1e2c1e4c8a52"
+
+func TestDecodeJoinConfiguration(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			data: `apiVersion: dcpctl.bhojpur.net/v1alpha1
+kind: JoinConfiguration
+apiServerEndpoint: 1.2.3.4:6443
+discovery:
+  token: abcdef.0123456789abcdef
+  caCertHashes:
+  - ` + testCACertHash + `
+nodeRegistration:
+  name: edge-1
+  nodePool: hangzhou
+  labels:
+    foo: bar
+  taints:
+  - key: dedicated
+    value: edge
+    effect: NoSchedule
+engine:
+  extraArgs:
+    disabled-resource-filters: servicetopology
+imageRegistry: registry.example.com/dcp
+`,
+		},
+		{
+			name:    "unknown field",
+			data:    "apiVersion: dcpctl.bhojpur.net/v1alpha1\nkind: JoinConfiguration\nnodeRegistraton: {}\n",
+			wantErr: "unknown field",
+		},
+		{
+			name:    "wrong kind",
+			data:    "apiVersion: dcpctl.bhojpur.net/v1alpha1\nkind: InitConfiguration\n",
+			wantErr: "unsupported apiVersion",
+		},
+		{
+			name:    "missing apiVersion",
+			data:    "kind: JoinConfiguration\n",
+			wantErr: "unsupported apiVersion",
+		},
+	}
+
+	for _, tt := range tests {
+		st := tt
+		tf := func(t *testing.T) {
+			t.Parallel()
+			cfg, err := DecodeJoinConfiguration([]byte(st.data))
+			if st.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), st.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", st.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("could not decode: %v", err)
+			}
+			SetDefaults_JoinConfiguration(cfg)
+			if errs := ValidateJoinConfiguration(cfg); len(errs) != 0 {
+				t.Fatalf("expected a valid configuration, got %v", errs.ToAggregate())
+			}
+			if cfg.Engine.Image != "registry.example.com/dcp/dcpsvr:latest" {
+				t.Errorf("expected the engine image to use the registry override, got %s", cfg.Engine.Image)
+			}
+			if cfg.PauseImage != "registry.example.com/dcp/pause:3.2" {
+				t.Errorf("expected the pause image to use the registry override, got %s", cfg.PauseImage)
+			}
+			if cfg.NodeRegistration.WorkingMode != dcpconstants.EdgeNode {
+				t.Errorf("expected working mode to be defaulted to edge, got %s", cfg.NodeRegistration.WorkingMode)
+			}
+		}
+		t.Run(st.name, tf)
+	}
+}
+
+func TestValidateJoinConfiguration(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(cfg *JoinConfiguration)
+		// wantErr is the field expected in the errors, empty means valid
+		wantErr string
+	}{
+		{
+			name:   "defaults",
+			mutate: func(cfg *JoinConfiguration) {},
+		},
+		{
+			name:    "missing endpoint",
+			mutate:  func(cfg *JoinConfiguration) { cfg.APIServerEndpoint = "" },
+			wantErr: "apiServerEndpoint",
+		},
+		{
+			name:    "endpoint without port",
+			mutate:  func(cfg *JoinConfiguration) { cfg.APIServerEndpoint = "1.2.3.4" },
+			wantErr: "apiServerEndpoint",
+		},
+		{
+			name:    "invalid token",
+			mutate:  func(cfg *JoinConfiguration) { cfg.Discovery.Token = "abcdef" },
+			wantErr: "discovery.token",
+		},
+		{
+			name: "ca pin and skip verification",
+			mutate: func(cfg *JoinConfiguration) {
+				cfg.Discovery.CACertHashes = []string{testCACertHash}
+			},
+			wantErr: "discovery.unsafeSkipCAVerification",
+		},
+		{
+			name: "missing ca pin",
+			mutate: func(cfg *JoinConfiguration) {
+				cfg.Discovery.UnsafeSkipCAVerification = false
+			},
+			wantErr: "discovery.caCertHashes",
+		},
+		{
+			name: "invalid ca pin",
+			mutate: func(cfg *JoinConfiguration) {
+				cfg.Discovery.UnsafeSkipCAVerification = false
+				cfg.Discovery.CACertHashes = []string{"md5:1234"}
+			},
+			wantErr: "discovery.caCertHashes",
+		},
+		{
+			name:    "invalid working mode",
+			mutate:  func(cfg *JoinConfiguration) { cfg.NodeRegistration.WorkingMode = "fog" },
+			wantErr: "nodeRegistration.workingMode",
+		},
+		{
+			name:    "invalid nodepool",
+			mutate:  func(cfg *JoinConfiguration) { cfg.NodeRegistration.NodePool = "Hang_Zhou" },
+			wantErr: "nodeRegistration.nodePool",
+		},
+		{
+			name: "invalid label",
+			mutate: func(cfg *JoinConfiguration) {
+				cfg.NodeRegistration.Labels = map[string]string{"foo/bar/baz": "v"}
+			},
+			wantErr: "nodeRegistration.labels",
+		},
+		{
+			name: "invalid taint effect",
+			mutate: func(cfg *JoinConfiguration) {
+				cfg.NodeRegistration.Taints = []corev1.Taint{{Key: "dedicated", Value: "edge", Effect: "NoWay"}}
+			},
+			wantErr: "nodeRegistration.taints[0].effect",
+		},
+		{
+			name: "reserved engine arg",
+			mutate: func(cfg *JoinConfiguration) {
+				cfg.Engine.ExtraArgs = map[string]string{"join-token": "abcdef.0123456789abcdef"}
+			},
+			wantErr: "engine.extraArgs[join-token]",
+		},
+		{
+			name: "engine arg with dashes",
+			mutate: func(cfg *JoinConfiguration) {
+				cfg.Engine.ExtraArgs = map[string]string{"--v": "4"}
+			},
+			wantErr: "engine.extraArgs[--v]",
+		},
+	}
+
+	for _, tt := range tests {
+		st := tt
+		tf := func(t *testing.T) {
+			t.Parallel()
+			cfg := DefaultJoinConfiguration()
+			st.mutate(cfg)
+			errs := ValidateJoinConfiguration(cfg)
+			if st.wantErr == "" {
+				if len(errs) != 0 {
+					t.Fatalf("expected no error, got %v", errs.ToAggregate())
+				}
+				return
+			}
+			if len(errs) == 0 || !strings.Contains(errs.ToAggregate().Error(), st.wantErr) {
+				t.Fatalf("expected error of %s, got %v", st.wantErr, errs.ToAggregate())
+			}
+		}
+		t.Run(st.name, tf)
+	}
+}
+
+func TestDefaultJoinConfigurationRoundTrip(t *testing.T) {
+	data, err := MarshalJoinConfiguration(DefaultJoinConfiguration())
+	if err != nil {
+		t.Fatalf("could not marshal the default configuration: %v", err)
+	}
+	cfg, err := DecodeJoinConfiguration(data)
+	if err != nil {
+		t.Fatalf("could not decode the printed defaults:\n%s\nerror: %v", data, err)
+	}
+	SetDefaults_JoinConfiguration(cfg)
+	if errs := ValidateJoinConfiguration(cfg); len(errs) != 0 {
+		t.Fatalf("expected the printed defaults to be valid, got %v", errs.ToAggregate())
+	}
+	if cfg.PauseImage != dcpconstants.PauseImagePath {
+		t.Errorf("expected pause image %s, got %s", dcpconstants.PauseImagePath, cfg.PauseImage)
+	}
+}
@@ -0,0 +1,81 @@
+package v1alpha1
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// placeholderToken and placeholderAPIServerEndpoint are printed as the example values of
+	// the required fields by dcpctl config print join-defaults
+	placeholderToken             = "abcdef.0123456789abcdef"
+	placeholderAPIServerEndpoint = "kube-apiserver:6443"
+)
+
+// LoadJoinConfiguration reads the JoinConfiguration from file, the returned configuration is not defaulted
+func LoadJoinConfiguration(file string) (*JoinConfiguration, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read join configuration %s: %v", file, err)
+	}
+	cfg, err := DecodeJoinConfiguration(data)
+	if err != nil {
+		return nil, fmt.Errorf("fail to decode join configuration %s: %v", file, err)
+	}
+	return cfg, nil
+}
+
+// DecodeJoinConfiguration decodes the JoinConfiguration in yaml or json format,
+// the unknown fields are rejected to catch the typos in the file.
+func DecodeJoinConfiguration(data []byte) (*JoinConfiguration, error) {
+	cfg := &JoinConfiguration{}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, err
+	}
+	if cfg.APIVersion != SchemeGroupVersion.String() || cfg.Kind != JoinConfigurationKind {
+		return nil, fmt.Errorf("unsupported apiVersion %q and kind %q, expect apiVersion %q and kind %q",
+			cfg.APIVersion, cfg.Kind, SchemeGroupVersion.String(), JoinConfigurationKind)
+	}
+	return cfg, nil
+}
+
+// DefaultJoinConfiguration returns a defaulted JoinConfiguration with the example
+// values of the required fields, it can be used as the template of a config file.
+func DefaultJoinConfiguration() *JoinConfiguration {
+	cfg := &JoinConfiguration{
+		APIServerEndpoint: placeholderAPIServerEndpoint,
+		Discovery: Discovery{
+			Token:                    placeholderToken,
+			UnsafeSkipCAVerification: true,
+		},
+	}
+	SetDefaults_JoinConfiguration(cfg)
+	return cfg
+}
+
+// MarshalJoinConfiguration encodes the JoinConfiguration in yaml format
+func MarshalJoinConfiguration(cfg *JoinConfiguration) ([]byte, error) {
+	return yaml.Marshal(cfg)
+}
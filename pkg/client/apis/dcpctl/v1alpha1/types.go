@@ -0,0 +1,115 @@
+package v1alpha1
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// GroupName is the group name of the dcpctl configuration files
+	GroupName = "dcpctl.bhojpur.net"
+	// JoinConfigurationKind is the kind of JoinConfiguration
+	JoinConfigurationKind = "JoinConfiguration"
+)
+
+// SchemeGroupVersion is the group version of the dcpctl configuration files
+var SchemeGroupVersion = schema.GroupVersion{Group: GroupName, Version: "v1alpha1"}
+
+// JoinConfiguration contains the settings used by dcpctl join to join a node
+// into the Bhojpur DCP cluster, it is read from the file set by --config.
+type JoinConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// APIServerEndpoint is the address(host:port) of kube-apiserver to join,
+	// the endpoint in the command args overrides it.
+	APIServerEndpoint string `json:"apiServerEndpoint,omitempty"`
+
+	// Discovery specifies how the node discovers and trusts the cluster
+	Discovery Discovery `json:"discovery"`
+
+	// NodeRegistration holds the settings of the node object registered by kubelet
+	NodeRegistration NodeRegistrationOptions `json:"nodeRegistration"`
+
+	// Engine holds the settings of the Bhojpur DCP server engine on the node
+	Engine EngineOptions `json:"engine"`
+
+	// ImageRegistry overrides the registry of the default pause and engine images,
+	// an image set explicitly is never changed.
+	ImageRegistry string `json:"imageRegistry,omitempty"`
+
+	// PauseImage is the image of the pause container
+	PauseImage string `json:"pauseImage,omitempty"`
+
+	// KubernetesResourceServer is the address for downloading k8s node resources
+	KubernetesResourceServer string `json:"kubernetesResourceServer,omitempty"`
+
+	// IgnorePreflightErrors is a list of checks whose errors will be shown as warnings,
+	// value 'all' ignores errors from all checks.
+	IgnorePreflightErrors []string `json:"ignorePreflightErrors,omitempty"`
+}
+
+// Discovery specifies how the node discovers and trusts the cluster
+type Discovery struct {
+	// Token is used for both discovery and tls bootstrap
+	Token string `json:"token,omitempty"`
+
+	// CACertHashes pins the public key of the root CA, in the format "<type>:<value>"
+	CACertHashes []string `json:"caCertHashes,omitempty"`
+
+	// UnsafeSkipCAVerification allows joining without CACertHashes pinning
+	UnsafeSkipCAVerification bool `json:"unsafeSkipCAVerification,omitempty"`
+}
+
+// NodeRegistrationOptions holds the settings of the node object registered by kubelet
+type NodeRegistrationOptions struct {
+	// Name is the name of the node, the hostname is used if it is empty
+	Name string `json:"name,omitempty"`
+
+	// CRISocket is the path to the CRI socket to connect
+	CRISocket string `json:"criSocket,omitempty"`
+
+	// WorkingMode is the node type, edge or cloud
+	WorkingMode string `json:"workingMode,omitempty"`
+
+	// NodePool is the name of the nodepool the node wants to join
+	NodePool string `json:"nodePool,omitempty"`
+
+	// Labels are added to the node when it is registered
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Taints are added to the node when it is registered
+	Taints []corev1.Taint `json:"taints,omitempty"`
+
+	// Organizations are added into the client certificate of the engine
+	Organizations []string `json:"organizations,omitempty"`
+}
+
+// EngineOptions holds the settings of the Bhojpur DCP server engine on the node
+type EngineOptions struct {
+	// Image is the image of the Bhojpur DCP server engine
+	Image string `json:"image,omitempty"`
+
+	// ExtraArgs are passed to the engine as --key=value flags
+	ExtraArgs map[string]string `json:"extraArgs,omitempty"`
+}
@@ -0,0 +1,141 @@
+package v1alpha1
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"net"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	bootstraputil "k8s.io/cluster-bootstrap/token/util"
+
+	dcpconstants "github.com/bhojpur/dcp/pkg/client/constants"
+	"github.com/bhojpur/dcp/pkg/client/kubernetes/kubeadm/app/util/pubkeypin"
+)
+
+// reservedEngineArgs are set by dcpctl join and can't be overridden by Engine.ExtraArgs
+var reservedEngineArgs = sets.NewString("server-addr", "node-name", "join-token", "working-mode", "hub-cert-organizations")
+
+// ValidateJoinConfiguration validates a defaulted JoinConfiguration
+func ValidateJoinConfiguration(c *JoinConfiguration) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if c.APIVersion != SchemeGroupVersion.String() {
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("apiVersion"), c.APIVersion, []string{SchemeGroupVersion.String()}))
+	}
+	if c.Kind != JoinConfigurationKind {
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("kind"), c.Kind, []string{JoinConfigurationKind}))
+	}
+
+	if c.APIServerEndpoint == "" {
+		allErrs = append(allErrs, field.Required(field.NewPath("apiServerEndpoint"), "the endpoint of kube-apiserver is required"))
+	} else if _, _, err := net.SplitHostPort(c.APIServerEndpoint); err != nil {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("apiServerEndpoint"), c.APIServerEndpoint, err.Error()))
+	}
+
+	allErrs = append(allErrs, validateDiscovery(&c.Discovery, field.NewPath("discovery"))...)
+	allErrs = append(allErrs, validateNodeRegistration(&c.NodeRegistration, field.NewPath("nodeRegistration"))...)
+	allErrs = append(allErrs, validateEngineOptions(&c.Engine, field.NewPath("engine"))...)
+
+	return allErrs
+}
+
+func validateDiscovery(d *Discovery, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if d.Token == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("token"), "the token is required to bootstrap the node"))
+	} else if !bootstraputil.IsValidBootstrapToken(d.Token) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("token"), d.Token, "the token must be of form '[a-z0-9]{6}.[a-z0-9]{16}'"))
+	}
+
+	if d.UnsafeSkipCAVerification && len(d.CACertHashes) != 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("unsafeSkipCAVerification"), d.UnsafeSkipCAVerification,
+			"CA verification can't be skipped when caCertHashes is specified"))
+	} else if !d.UnsafeSkipCAVerification && len(d.CACertHashes) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("caCertHashes"),
+			"caCertHashes is required unless unsafeSkipCAVerification is true"))
+	}
+	if err := pubkeypin.NewSet().Allow(d.CACertHashes...); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("caCertHashes"), d.CACertHashes, err.Error()))
+	}
+
+	return allErrs
+}
+
+func validateNodeRegistration(n *NodeRegistrationOptions, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if n.Name != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(n.Name) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("name"), n.Name, msg))
+		}
+	}
+	if n.WorkingMode != dcpconstants.EdgeNode && n.WorkingMode != dcpconstants.CloudNode {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("workingMode"), n.WorkingMode,
+			[]string{dcpconstants.EdgeNode, dcpconstants.CloudNode}))
+	}
+	if n.NodePool != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(n.NodePool) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("nodePool"), n.NodePool, msg))
+		}
+	}
+	allErrs = append(allErrs, metav1validation.ValidateLabels(n.Labels, fldPath.Child("labels"))...)
+
+	effects := sets.NewString(string(corev1.TaintEffectNoSchedule), string(corev1.TaintEffectPreferNoSchedule),
+		string(corev1.TaintEffectNoExecute))
+	for i, taint := range n.Taints {
+		idxPath := fldPath.Child("taints").Index(i)
+		for _, msg := range validation.IsQualifiedName(taint.Key) {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("key"), taint.Key, msg))
+		}
+		if taint.Value != "" {
+			for _, msg := range validation.IsValidLabelValue(taint.Value) {
+				allErrs = append(allErrs, field.Invalid(idxPath.Child("value"), taint.Value, msg))
+			}
+		}
+		if !effects.Has(string(taint.Effect)) {
+			allErrs = append(allErrs, field.NotSupported(idxPath.Child("effect"), taint.Effect, effects.List()))
+		}
+	}
+
+	return allErrs
+}
+
+func validateEngineOptions(e *EngineOptions, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for arg := range e.ExtraArgs {
+		argPath := fldPath.Child("extraArgs").Key(arg)
+		switch {
+		case arg == "" || strings.HasPrefix(arg, "-"):
+			allErrs = append(allErrs, field.Invalid(argPath, arg, "the arg must be a flag name without the leading dashes"))
+		case reservedEngineArgs.Has(arg):
+			allErrs = append(allErrs, field.Forbidden(argPath, "the arg is set by dcpctl join"))
+		}
+	}
+
+	return allErrs
+}
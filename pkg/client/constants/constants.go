@@ -34,6 +34,8 @@ var (
 
 const (
 	DcpctlLockConfigMapName = "dcpctl-lock"
+	// DcpctlConvertStateConfigMapName is the configmap holding the plan and the progress of dcpctl convert
+	DcpctlConvertStateConfigMapName = "dcpctl-convert-state"
 
 	TunnelServerComponentName   = "tunnel-server"
 	TunnelServerSvcName         = "x-tunnel-server-svc"
@@ -194,6 +196,18 @@ rules:
   verbs:
   - list
   - watch
+- apiGroups:
+  - apps.bhojpur.net
+  resources:
+  - nodepools
+  verbs:
+  - get
+- apiGroups:
+  - apps.bhojpur.net
+  resources:
+  - nodepools/status
+  verbs:
+  - update
 - apiGroups:
     - certificates.k8s.io
   resources:
@@ -186,6 +186,10 @@ spec:
     singular: uniteddeployment
   scope: Namespaced
   subresources:
+    scale:
+      labelSelectorPath: .status.selector
+      specReplicasPath: .spec.replicas
+      statusReplicasPath: .status.replicas
     status: {}
   validation:
     openAPIV3Schema:
@@ -202,6 +206,10 @@ spec:
         spec:
           description: UnitedDeploymentSpec defines the desired state of UnitedDeployment.
           properties:
+            replicas:
+              description: Replicas is the total number of desired replicas of all the pools. If specified, the replicas of the pools which do not set Replicas are computed from their Weight, MinReplicas and MaxReplicas, moving replicas away from the pools whose NodePool has no ready nodes. If unspecified, every pool uses its own Replicas.
+              format: int32
+              type: integer
             revisionHistoryLimit:
               description: Indicates the number of histories to be conserved. If unspecified, defaults to 10.
               format: int32
@@ -244,6 +252,14 @@ spec:
                   items:
                     description: Pool defines the detail of a pool.
                     properties:
+                      maxReplicas:
+                        description: MaxReplicas is the upper bound of the replicas computed for this pool.
+                        format: int32
+                        type: integer
+                      minReplicas:
+                        description: MinReplicas is the lower bound of the replicas computed for this pool.
+                        format: int32
+                        type: integer
                       name:
                         description: Indicates pool name as a DNS_LABEL, which will be used to generate pool workload name prefix in the format '<deployment-name>-<pool-name>-'. Name should be unique between all of the pools under one UnitedDeployment. Name is NodePool Name
                         type: string
@@ -254,7 +270,7 @@ spec:
                         description: Indicates the patch for the templateSpec Now support strategic merge path :https://kubernetes.io/docs/tasks/manage-kubernetes-objects/update-api-object-kubectl-patch/#notes-on-the-strategic-merge-patch Patch takes precedence over Replicas fields If the Patch also modifies the Replicas, use the Replicas value in the Patch
                         type: object
                       replicas:
-                        description: Indicates the number of the pod to be created under this pool.
+                        description: Indicates the number of the pod to be created under this pool. Required unless the UnitedDeployment sets Replicas, in which case a pool setting it keeps this fixed number and the rest of the replicas go to the other pools.
                         format: int32
                         type: integer
                       tolerations:
@@ -263,6 +279,10 @@ spec:
                           description: The pod this Toleration is attached to tolerates any taint that matches the triple <key,value,effect> using the matching operator <operator>.
                           type: object
                         type: array
+                      weight:
+                        description: Weight is the share of the UnitedDeployment Replicas this pool gets relative to the other weighted pools. Defaults to 1, 0 gives the pool only its MinReplicas.
+                        format: int32
+                        type: integer
                     required:
                     - name
                     type: object
@@ -348,6 +368,9 @@ spec:
               description: Replicas is the most recently observed number of replicas.
               format: int32
               type: integer
+            selector:
+              description: Selector is the label selector of the pods in string form, used by the scale subresource.
+              type: string
             templateType:
               description: TemplateType indicates the type of PoolTemplate
               type: string
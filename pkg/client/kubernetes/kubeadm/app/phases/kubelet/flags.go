@@ -28,6 +28,7 @@ import (
 	"strings"
 
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
 
 	"github.com/bhojpur/dcp/cmd/grid/dcpctl/join/joindata"
@@ -73,6 +74,9 @@ func buildKubeletArgMapCommon(data joindata.DcpJoinData) map[string]string {
 	}
 
 	kubeletFlags["node-labels"] = constructNodeLabels(data.NodeLabels(), nodeReg.WorkingMode, projectinfo.GetEdgeWorkerLabelKey())
+	if len(nodeReg.Taints) != 0 {
+		kubeletFlags["register-with-taints"] = constructNodeTaints(nodeReg.Taints)
+	}
 
 	kubeletFlags["rotate-certificates"] = "false"
 
@@ -103,6 +107,15 @@ func constructNodeLabels(nodeLabels map[string]string, workingMode, edgeWorkerLa
 	return labelsStr
 }
 
+// constructNodeTaints make up node taints string in the format of key=value:effect
+func constructNodeTaints(taints []corev1.Taint) string {
+	taintStrs := make([]string, 0, len(taints))
+	for i := range taints {
+		taintStrs = append(taintStrs, taints[i].ToString())
+	}
+	return strings.Join(taintStrs, ",")
+}
+
 // writeKubeletFlagBytesToDisk writes a byte slice down to disk at the specific location of the kubelet flag overrides file
 func writeKubeletFlagBytesToDisk(b []byte, kubeletDir string) error {
 	kubeletEnvFilePath := filepath.Join(kubeletDir, constants.KubeletEnvFileName)
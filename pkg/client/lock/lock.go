@@ -48,7 +48,7 @@ var (
 )
 
 // AcquireLock tries to acquire the lock lock configmap/dcpctl-lock
-func AcquireLock(cli *kubernetes.Clientset) error {
+func AcquireLock(cli kubernetes.Interface) error {
 	lockCm, err := cli.CoreV1().ConfigMaps("kube-system").
 		Get(context.Background(), constants.DcpctlLockConfigMapName, metav1.GetOptions{})
 	if err != nil {
@@ -129,7 +129,7 @@ func acquireLockAndUpdateCm(cli kubernetes.Interface, lockCm *v1.ConfigMap) erro
 }
 
 // ReleaseLock releases the lock configmap/dcpctl-lock
-func ReleaseLock(cli *kubernetes.Clientset) error {
+func ReleaseLock(cli kubernetes.Interface) error {
 	lockCm, err := cli.CoreV1().ConfigMaps("kube-system").
 		Get(context.Background(), constants.DcpctlLockConfigMapName, metav1.GetOptions{})
 	if err != nil {
@@ -173,7 +173,7 @@ func ReleaseLock(cli *kubernetes.Clientset) error {
 
 // DeleteLock should only be called when you've achieved the lock.
 // It will delete the dcpctl-lock configmap.
-func DeleteLock(cli *kubernetes.Clientset) error {
+func DeleteLock(cli kubernetes.Interface) error {
 	if err := cli.CoreV1().ConfigMaps("kube-system").
 		Delete(context.Background(), constants.DcpctlLockConfigMapName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
 		klog.Error("fail to delete the dcpctl lock", err)
@@ -101,6 +101,9 @@ spec:
       {{if .organizations }}
     - --hub-cert-organizations={{.organizations}}
       {{end}}
+      {{if .extraArgs }}
+{{.extraArgs}}
+      {{end}}
     livenessProbe:
       httpGet:
         host: 127.0.0.1
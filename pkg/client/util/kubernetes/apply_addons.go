@@ -34,7 +34,7 @@ import (
 	"github.com/bhojpur/dcp/pkg/projectinfo"
 )
 
-func DeployControllerManager(client *kubernetes.Clientset, dcpControllerManagerImage string) error {
+func DeployControllerManager(client kubernetes.Interface, dcpControllerManagerImage string) error {
 	if err := CreateServiceAccountFromYaml(client,
 		SystemNamespace, constants.ControllerManagerServiceAccount); err != nil {
 		return err
@@ -62,7 +62,7 @@ func DeployControllerManager(client *kubernetes.Clientset, dcpControllerManagerI
 }
 
 func DeployAppManager(
-	client *kubernetes.Clientset,
+	client kubernetes.Interface,
 	dcpappmanagerImage string,
 	dcpAppManagerClient dynamic.Interface,
 	systemArchitecture string) error {
@@ -141,7 +141,7 @@ func DeployAppManager(
 }
 
 func DeployTunnelServer(
-	client *kubernetes.Clientset,
+	client kubernetes.Interface,
 	certIP string,
 	dcptunnelServerImage string,
 	systemArchitecture string) error {
@@ -198,7 +198,7 @@ func DeployTunnelServer(
 }
 
 func DeployTunnelAgent(
-	client *kubernetes.Clientset,
+	client kubernetes.Interface,
 	tunnelServerAddress string,
 	dcptunnelAgentImage string) error {
 	// 1. Deploy the tunnel-agent DaemonSet
@@ -214,7 +214,7 @@ func DeployTunnelAgent(
 }
 
 // DeployEngineSetting deploy clusterrole, clusterrolebinding for Bhojpur DCP server engine static pod.
-func DeployEngineSetting(client *kubernetes.Clientset) error {
+func DeployEngineSetting(client kubernetes.Interface) error {
 	// 1. create the ClusterRole
 	if err := CreateClusterRoleFromYaml(client, edgenode.EngineClusterRole); err != nil {
 		return err
@@ -236,7 +236,7 @@ func DeployEngineSetting(client *kubernetes.Clientset) error {
 }
 
 // DeleteEngineSetting rm settings for Bhojpur DCP server engine pod
-func DeleteEngineSetting(client *kubernetes.Clientset) error {
+func DeleteEngineSetting(client kubernetes.Interface) error {
 
 	// 1. delete the ClusterRoleBinding
 	if err := client.RbacV1().ClusterRoleBindings().
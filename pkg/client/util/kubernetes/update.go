@@ -0,0 +1,148 @@
+package kubernetes
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	tmplutil "github.com/bhojpur/dcp/pkg/client/util/templates"
+)
+
+// UpdateDeployFromYaml renders the Deployment from the yaml template and replaces the pod
+// template of the one in cluster with it, the replicas set by users are kept. It returns the
+// Deployment before the update, or nil if the Deployment is not in cluster.
+func UpdateDeployFromYaml(cliSet kubernetes.Interface, ns, dplyTmpl string, ctx interface{}) (*appsv1.Deployment, error) {
+	ycmdp, err := tmplutil.SubsituteTemplate(dplyTmpl, ctx)
+	if err != nil {
+		return nil, err
+	}
+	dpObj, err := YamlToObject([]byte(ycmdp))
+	if err != nil {
+		return nil, err
+	}
+	dply, ok := dpObj.(*appsv1.Deployment)
+	if !ok {
+		return nil, errors.New("fail to assert Deployment")
+	}
+
+	var previous *appsv1.Deployment
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cur, err := cliSet.AppsV1().Deployments(ns).Get(context.Background(), dply.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		previous = cur.DeepCopy()
+		cur.Spec.Template = dply.Spec.Template
+		_, err = cliSet.AppsV1().Deployments(ns).Update(context.Background(), cur, metav1.UpdateOptions{})
+		return err
+	})
+	if apierrors.IsNotFound(err) {
+		klog.V(4).Infof("the deployment/%s is not in cluster, skip to update it", dply.Name)
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("fail to update the deployment/%s: %v", dply.Name, err)
+	}
+	klog.V(4).Infof("the deployment/%s is updated", dply.Name)
+	return previous, nil
+}
+
+// RestoreDeployment restores the pod template of the Deployment returned by UpdateDeployFromYaml.
+func RestoreDeployment(cliSet kubernetes.Interface, previous *appsv1.Deployment) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cur, err := cliSet.AppsV1().Deployments(previous.Namespace).Get(context.Background(), previous.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		cur.Spec.Template = previous.Spec.Template
+		_, err = cliSet.AppsV1().Deployments(previous.Namespace).Update(context.Background(), cur, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("fail to restore the deployment/%s: %v", previous.Name, err)
+	}
+	klog.V(4).Infof("the deployment/%s is restored", previous.Name)
+	return nil
+}
+
+// UpdateDaemonSetFromYaml renders the DaemonSet from the yaml template and replaces the pod
+// template of the one in cluster with it. It returns the DaemonSet before the update, or nil
+// if the DaemonSet is not in cluster.
+func UpdateDaemonSetFromYaml(cliSet kubernetes.Interface, dsTmpl string, ctx interface{}) (*appsv1.DaemonSet, error) {
+	ytadstmp, err := tmplutil.SubsituteTemplate(dsTmpl, ctx)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := YamlToObject([]byte(ytadstmp))
+	if err != nil {
+		return nil, err
+	}
+	ds, ok := obj.(*appsv1.DaemonSet)
+	if !ok {
+		return nil, errors.New("fail to assert DaemonSet")
+	}
+
+	var previous *appsv1.DaemonSet
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cur, err := cliSet.AppsV1().DaemonSets(SystemNamespace).Get(context.Background(), ds.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		previous = cur.DeepCopy()
+		cur.Spec.Template = ds.Spec.Template
+		_, err = cliSet.AppsV1().DaemonSets(SystemNamespace).Update(context.Background(), cur, metav1.UpdateOptions{})
+		return err
+	})
+	if apierrors.IsNotFound(err) {
+		klog.V(4).Infof("the daemonset/%s is not in cluster, skip to update it", ds.Name)
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("fail to update the daemonset/%s: %v", ds.Name, err)
+	}
+	klog.V(4).Infof("daemonset/%s is updated", ds.Name)
+	return previous, nil
+}
+
+// RestoreDaemonSet restores the pod template of the DaemonSet returned by UpdateDaemonSetFromYaml.
+func RestoreDaemonSet(cliSet kubernetes.Interface, previous *appsv1.DaemonSet) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cur, err := cliSet.AppsV1().DaemonSets(previous.Namespace).Get(context.Background(), previous.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		cur.Spec.Template = previous.Spec.Template
+		_, err = cliSet.AppsV1().DaemonSets(previous.Namespace).Update(context.Background(), cur, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("fail to restore the daemonset/%s: %v", previous.Name, err)
+	}
+	klog.V(4).Infof("daemonset/%s is restored", previous.Name)
+	return nil
+}
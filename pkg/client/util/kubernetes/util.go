@@ -52,8 +52,8 @@ import (
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+	"k8s.io/apimachinery/pkg/util/wait"
 	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
-	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -86,6 +86,8 @@ const (
 	SystemNamespace                 = "kube-system"
 	// DefaultWaitServantJobTimeout specifies the timeout value of waiting for the ServantJob to be succeeded
 	DefaultWaitServantJobTimeout = time.Minute * 5
+	// ServantJobLogLines is the number of lines of logs kept for a failed ServantJob
+	ServantJobLogLines = 10
 )
 
 var (
@@ -121,7 +123,7 @@ func processCreateErr(kind string, name string, err error) error {
 }
 
 // CreateServiceAccountFromYaml creates the ServiceAccount from the yaml template.
-func CreateServiceAccountFromYaml(cliSet *kubernetes.Clientset, ns, saTmpl string) error {
+func CreateServiceAccountFromYaml(cliSet kubernetes.Interface, ns, saTmpl string) error {
 	obj, err := YamlToObject([]byte(saTmpl))
 	if err != nil {
 		return err
@@ -135,7 +137,7 @@ func CreateServiceAccountFromYaml(cliSet *kubernetes.Clientset, ns, saTmpl strin
 }
 
 // CreateClusterRoleFromYaml creates the ClusterRole from the yaml template.
-func CreateClusterRoleFromYaml(cliSet *kubernetes.Clientset, crTmpl string) error {
+func CreateClusterRoleFromYaml(cliSet kubernetes.Interface, crTmpl string) error {
 	obj, err := YamlToObject([]byte(crTmpl))
 	if err != nil {
 		return err
@@ -149,7 +151,7 @@ func CreateClusterRoleFromYaml(cliSet *kubernetes.Clientset, crTmpl string) erro
 }
 
 // CreateClusterRoleBindingFromYaml creates the ClusterRoleBinding from the yaml template.
-func CreateClusterRoleBindingFromYaml(cliSet *kubernetes.Clientset, crbTmpl string) error {
+func CreateClusterRoleBindingFromYaml(cliSet kubernetes.Interface, crbTmpl string) error {
 	obj, err := YamlToObject([]byte(crbTmpl))
 	if err != nil {
 		return err
@@ -163,7 +165,7 @@ func CreateClusterRoleBindingFromYaml(cliSet *kubernetes.Clientset, crbTmpl stri
 }
 
 // CreateConfigMapFromYaml creates the ConfigMap from the yaml template.
-func CreateConfigMapFromYaml(cliSet *kubernetes.Clientset, ns, cmTmpl string) error {
+func CreateConfigMapFromYaml(cliSet kubernetes.Interface, ns, cmTmpl string) error {
 	obj, err := YamlToObject([]byte(cmTmpl))
 	if err != nil {
 		return err
@@ -176,8 +178,9 @@ func CreateConfigMapFromYaml(cliSet *kubernetes.Clientset, ns, cmTmpl string) er
 	return processCreateErr("configmap", cm.Name, err)
 }
 
-// CreateDeployFromYaml creates the Deployment from the yaml template.
-func CreateDeployFromYaml(cliSet *kubernetes.Clientset, ns, dplyTmpl string, ctx interface{}) error {
+// CreateDeployFromYaml creates the Deployment from the yaml template, or updates the
+// Deployment left by a previous run to the rendered labels and spec.
+func CreateDeployFromYaml(cliSet kubernetes.Interface, ns, dplyTmpl string, ctx interface{}) error {
 	ycmdp, err := tmplutil.SubsituteTemplate(dplyTmpl, ctx)
 	if err != nil {
 		return err
@@ -190,15 +193,31 @@ func CreateDeployFromYaml(cliSet *kubernetes.Clientset, ns, dplyTmpl string, ctx
 	if !ok {
 		return errors.New("fail to assert Deployment")
 	}
-	if _, err = cliSet.AppsV1().Deployments(ns).Create(context.Background(), dply, metav1.CreateOptions{}); err != nil {
+	_, err = cliSet.AppsV1().Deployments(ns).Create(context.Background(), dply, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		var cur *appsv1.Deployment
+		cur, err = cliSet.AppsV1().Deployments(ns).Get(context.Background(), dply.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("fail to get the deployment/%s: %v", dply.Name, err)
+		}
+		cur.Labels = dply.Labels
+		cur.Spec = dply.Spec
+		if _, err = cliSet.AppsV1().Deployments(ns).Update(context.Background(), cur, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("fail to update the deployment/%s: %v", dply.Name, err)
+		}
+		klog.V(4).Infof("the deployment/%s is updated", dply.Name)
+		return nil
+	}
+	if err != nil {
 		return err
 	}
 	klog.V(4).Infof("the deployment/%s is deployed", dply.Name)
 	return nil
 }
 
-// CreateDaemonSetFromYaml creates the DaemonSet from the yaml template.
-func CreateDaemonSetFromYaml(cliSet *kubernetes.Clientset, dsTmpl string, ctx interface{}) error {
+// CreateDaemonSetFromYaml creates the DaemonSet from the yaml template, or updates the
+// DaemonSet left by a previous run to the rendered labels and spec.
+func CreateDaemonSetFromYaml(cliSet kubernetes.Interface, dsTmpl string, ctx interface{}) error {
 	var ytadstmp string
 	var err error
 	if ctx != nil {
@@ -219,6 +238,20 @@ func CreateDaemonSetFromYaml(cliSet *kubernetes.Clientset, dsTmpl string, ctx in
 		return fmt.Errorf("fail to assert daemonset: %v", err)
 	}
 	_, err = cliSet.AppsV1().DaemonSets(SystemNamespace).Create(context.Background(), ds, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		var cur *appsv1.DaemonSet
+		cur, err = cliSet.AppsV1().DaemonSets(SystemNamespace).Get(context.Background(), ds.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("fail to get the daemonset/%s: %v", ds.Name, err)
+		}
+		cur.Labels = ds.Labels
+		cur.Spec = ds.Spec
+		if _, err = cliSet.AppsV1().DaemonSets(SystemNamespace).Update(context.Background(), cur, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("fail to update the daemonset/%s: %v", ds.Name, err)
+		}
+		klog.V(4).Infof("daemonset/%s is updated", ds.Name)
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("fail to create the daemonset/%s: %v", ds.Name, err)
 	}
@@ -227,7 +260,7 @@ func CreateDaemonSetFromYaml(cliSet *kubernetes.Clientset, dsTmpl string, ctx in
 }
 
 // CreateServiceFromYaml creates the Service from the yaml template.
-func CreateServiceFromYaml(cliSet *kubernetes.Clientset, svcTmpl string) error {
+func CreateServiceFromYaml(cliSet kubernetes.Interface, svcTmpl string) error {
 	obj, err := YamlToObject([]byte(svcTmpl))
 	if err != nil {
 		return err
@@ -242,7 +275,7 @@ func CreateServiceFromYaml(cliSet *kubernetes.Clientset, svcTmpl string) error {
 
 //add by yanyhui at 20210611
 // CreateRoleFromYaml creates the ClusterRole from the yaml template.
-func CreateRoleFromYaml(cliSet *kubernetes.Clientset, ns, crTmpl string) error {
+func CreateRoleFromYaml(cliSet kubernetes.Interface, ns, crTmpl string) error {
 	obj, err := YamlToObject([]byte(crTmpl))
 	if err != nil {
 		return err
@@ -256,7 +289,7 @@ func CreateRoleFromYaml(cliSet *kubernetes.Clientset, ns, crTmpl string) error {
 }
 
 // CreateRoleBindingFromYaml creates the ClusterRoleBinding from the yaml template.
-func CreateRoleBindingFromYaml(cliSet *kubernetes.Clientset, ns, crbTmpl string) error {
+func CreateRoleBindingFromYaml(cliSet kubernetes.Interface, ns, crbTmpl string) error {
 	obj, err := YamlToObject([]byte(crbTmpl))
 	if err != nil {
 		return err
@@ -270,7 +303,7 @@ func CreateRoleBindingFromYaml(cliSet *kubernetes.Clientset, ns, crbTmpl string)
 }
 
 // CreateSecretFromYaml creates the Secret from the yaml template.
-func CreateSecretFromYaml(cliSet *kubernetes.Clientset, ns, saTmpl string) error {
+func CreateSecretFromYaml(cliSet kubernetes.Interface, ns, saTmpl string) error {
 	obj, err := YamlToObject([]byte(saTmpl))
 	if err != nil {
 		return err
@@ -285,7 +318,7 @@ func CreateSecretFromYaml(cliSet *kubernetes.Clientset, ns, saTmpl string) error
 }
 
 // CreateMutatingWebhookConfigurationFromYaml creates the Service from the yaml template.
-func CreateMutatingWebhookConfigurationFromYaml(cliSet *kubernetes.Clientset, svcTmpl string) error {
+func CreateMutatingWebhookConfigurationFromYaml(cliSet kubernetes.Interface, svcTmpl string) error {
 	obj, err := YamlToObject([]byte(svcTmpl))
 	if err != nil {
 		return err
@@ -299,7 +332,7 @@ func CreateMutatingWebhookConfigurationFromYaml(cliSet *kubernetes.Clientset, sv
 }
 
 // CreateValidatingWebhookConfigurationFromYaml creates the Service from the yaml template.
-func CreateValidatingWebhookConfigurationFromYaml(cliSet *kubernetes.Clientset, svcTmpl string) error {
+func CreateValidatingWebhookConfigurationFromYaml(cliSet kubernetes.Interface, svcTmpl string) error {
 	obj, err := YamlToObject([]byte(svcTmpl))
 	if err != nil {
 		return err
@@ -312,7 +345,7 @@ func CreateValidatingWebhookConfigurationFromYaml(cliSet *kubernetes.Clientset,
 	return processCreateErr("validatingwebhookconfiguration", vw.Name, err)
 }
 
-func CreateCRDFromYaml(clientset *kubernetes.Clientset, dcpAppManagerClient dynamic.Interface, nameSpace string, filebytes []byte) error {
+func CreateCRDFromYaml(clientset kubernetes.Interface, dcpAppManagerClient dynamic.Interface, nameSpace string, filebytes []byte) error {
 	var err error
 	decoder := yamlutil.NewYAMLOrJSONDecoder(bytes.NewReader(filebytes), 10000)
 	var rawObj k8sruntime.RawExtension
@@ -359,7 +392,7 @@ func CreateCRDFromYaml(clientset *kubernetes.Clientset, dcpAppManagerClient dyna
 	return nil
 }
 
-func DeleteCRDResource(clientset *kubernetes.Clientset, dcpAppManagerClientSet dynamic.Interface, res string, name string, filebytes []byte) error {
+func DeleteCRDResource(clientset kubernetes.Interface, dcpAppManagerClientSet dynamic.Interface, res string, name string, filebytes []byte) error {
 	var err error
 	decoder := yamlutil.NewYAMLOrJSONDecoder(bytes.NewReader(filebytes), 10000)
 	var rawObj k8sruntime.RawExtension
@@ -418,7 +451,7 @@ func YamlToObject(yamlContent []byte) (k8sruntime.Object, error) {
 }
 
 // LabelNode add a new label (<key>=<val>) to the given node
-func LabelNode(cliSet *kubernetes.Clientset, node *corev1.Node, key, val string) (*corev1.Node, error) {
+func LabelNode(cliSet kubernetes.Interface, node *corev1.Node, key, val string) (*corev1.Node, error) {
 	node.Labels[key] = val
 	newNode, err := cliSet.CoreV1().Nodes().Update(context.Background(), node, metav1.UpdateOptions{})
 	if err != nil {
@@ -428,7 +461,7 @@ func LabelNode(cliSet *kubernetes.Clientset, node *corev1.Node, key, val string)
 }
 
 // AnnotateNode add a new annotation (<key>=<val>) to the given node
-func AnnotateNode(cliSet *kubernetes.Clientset, node *corev1.Node, key, val string) (*corev1.Node, error) {
+func AnnotateNode(cliSet kubernetes.Interface, node *corev1.Node, key, val string) (*corev1.Node, error) {
 	node.Annotations[key] = val
 	newNode, err := cliSet.CoreV1().Nodes().Update(context.Background(), node, metav1.UpdateOptions{})
 	if err != nil {
@@ -437,7 +470,7 @@ func AnnotateNode(cliSet *kubernetes.Clientset, node *corev1.Node, key, val stri
 	return newNode, nil
 }
 
-func AddEdgeWorkerLableAndAutonomyAnnotation(cliSet *kubernetes.Clientset, node *corev1.Node, lVal, aVal string) (*corev1.Node, error) {
+func AddEdgeWorkerLableAndAutonomyAnnotation(cliSet kubernetes.Interface, node *corev1.Node, lVal, aVal string) (*corev1.Node, error) {
 	node.Labels[projectinfo.GetEdgeWorkerLabelKey()] = lVal
 	node.Annotations[projectinfo.GetAutonomyAnnotation()] = aVal
 	newNode, err := cliSet.CoreV1().Nodes().Update(context.Background(), node, metav1.UpdateOptions{})
@@ -447,12 +480,37 @@ func AddEdgeWorkerLableAndAutonomyAnnotation(cliSet *kubernetes.Clientset, node
 	return newNode, nil
 }
 
-// RunJobAndCleanup runs the job, wait for it to be complete, and delete it
-func RunJobAndCleanup(cliSet *kubernetes.Clientset, job *batchv1.Job, timeout, period time.Duration) error {
-	job, err := cliSet.BatchV1().Jobs(job.GetNamespace()).Create(context.Background(), job, metav1.CreateOptions{})
+// RunJobAndCleanup runs the job, wait for it to be complete, and delete it.
+// A job of the same name left by a previous run is waited for if it is still running,
+// and recreated if it has failed.
+func RunJobAndCleanup(cliSet kubernetes.Interface, job *batchv1.Job, timeout, period time.Duration) error {
+	job, err := createOrAdoptJob(cliSet, job, timeout, period)
 	if err != nil {
 		return err
 	}
+	if err := waitJobComplete(cliSet, job, timeout, period); err != nil {
+		return err
+	}
+	return deleteSucceededJob(cliSet, job)
+}
+
+// RunJobAndGetLogs runs the job, wait for it to be complete, and returns the whole logs of
+// its latest pod. The job is deleted after the logs are read, a failed job is preserved for
+// diagnosis.
+func RunJobAndGetLogs(cliSet kubernetes.Interface, job *batchv1.Job, timeout, period time.Duration) (string, error) {
+	job, err := createOrAdoptJob(cliSet, job, timeout, period)
+	if err != nil {
+		return "", err
+	}
+	if err := waitJobComplete(cliSet, job, timeout, period); err != nil {
+		return "", err
+	}
+	logs := GetJobLogs(cliSet, job, 0)
+	return logs, deleteSucceededJob(cliSet, job)
+}
+
+// waitJobComplete waits for the job to be succeeded, and returns an error if it fails.
+func waitJobComplete(cliSet kubernetes.Interface, job *batchv1.Job, timeout, period time.Duration) error {
 	waitJobTimeout := time.After(timeout)
 	for {
 		select {
@@ -466,15 +524,10 @@ func RunJobAndCleanup(cliSet *kubernetes.Clientset, job *batchv1.Job, timeout, p
 					job.GetName(), err)
 				return err
 			}
-			if job.Status.Succeeded == *job.Spec.Completions {
-				if err := cliSet.BatchV1().Jobs(job.GetNamespace()).
-					Delete(context.Background(), job.GetName(), metav1.DeleteOptions{
-						PropagationPolicy: &PropagationPolicy,
-					}); err != nil {
-					klog.Errorf("fail to delete succeeded servant job(%s): %s",
-						job.GetName(), err)
-					return err
-				}
+			if cond := getJobFailedCondition(job); cond != nil {
+				return fmt.Errorf("job failed: %s", cond.Message)
+			}
+			if job.Spec.Completions != nil && job.Status.Succeeded == *job.Spec.Completions {
 				return nil
 			}
 			continue
@@ -482,6 +535,87 @@ func RunJobAndCleanup(cliSet *kubernetes.Clientset, job *batchv1.Job, timeout, p
 	}
 }
 
+func deleteSucceededJob(cliSet kubernetes.Interface, job *batchv1.Job) error {
+	if err := cliSet.BatchV1().Jobs(job.GetNamespace()).
+		Delete(context.Background(), job.GetName(), metav1.DeleteOptions{
+			PropagationPolicy: &PropagationPolicy,
+		}); err != nil {
+		klog.Errorf("fail to delete succeeded servant job(%s): %s",
+			job.GetName(), err)
+		return err
+	}
+	return nil
+}
+
+// createOrAdoptJob creates the job, or returns the job of the same name if it has not failed.
+// A failed job is deleted and created again.
+func createOrAdoptJob(cliSet kubernetes.Interface, job *batchv1.Job, timeout, period time.Duration) (*batchv1.Job, error) {
+	created, err := cliSet.BatchV1().Jobs(job.GetNamespace()).Create(context.Background(), job, metav1.CreateOptions{})
+	if err == nil || !apierrors.IsAlreadyExists(err) {
+		return created, err
+	}
+	existing, err := cliSet.BatchV1().Jobs(job.GetNamespace()).Get(context.Background(), job.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if getJobFailedCondition(existing) == nil {
+		klog.Infof("job(%s) already exists, wait for it to be complete", job.GetName())
+		return existing, nil
+	}
+
+	klog.Infof("job(%s) failed in a previous run, recreate it", job.GetName())
+	if err := cliSet.BatchV1().Jobs(job.GetNamespace()).Delete(context.Background(), job.GetName(), metav1.DeleteOptions{
+		PropagationPolicy: &PropagationPolicy,
+	}); err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	err = wait.PollImmediate(period, timeout, func() (bool, error) {
+		created, err = cliSet.BatchV1().Jobs(job.GetNamespace()).Create(context.Background(), job, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+	return created, err
+}
+
+func getJobFailedCondition(job *batchv1.Job) *batchv1.JobCondition {
+	for i := range job.Status.Conditions {
+		if job.Status.Conditions[i].Type == batchv1.JobFailed && job.Status.Conditions[i].Status == corev1.ConditionTrue {
+			return &job.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// GetJobLogs returns the last lines of the logs of the latest pod of the job, all of them
+// if lines is not positive, or an empty string if they can not be read.
+func GetJobLogs(cliSet kubernetes.Interface, job *batchv1.Job, lines int64) string {
+	podLst, err := cliSet.CoreV1().Pods(job.GetNamespace()).List(context.Background(), metav1.ListOptions{
+		LabelSelector: "job-name=" + job.GetName(),
+	})
+	if err != nil || len(podLst.Items) == 0 {
+		return ""
+	}
+	latest := &podLst.Items[0]
+	for i := range podLst.Items {
+		if latest.CreationTimestamp.Before(&podLst.Items[i].CreationTimestamp) {
+			latest = &podLst.Items[i]
+		}
+	}
+	logOptions := &corev1.PodLogOptions{}
+	if lines > 0 {
+		logOptions.TailLines = &lines
+	}
+	logs, err := cliSet.CoreV1().Pods(latest.Namespace).
+		GetLogs(latest.Name, logOptions).DoRaw(context.Background())
+	if err != nil {
+		klog.V(4).Infof("fail to get logs of pod(%s): %s", latest.Name, err)
+		return ""
+	}
+	return strings.TrimSpace(string(logs))
+}
+
 // RenderServantJob renders servant job for a specified node.
 func RenderServantJob(action string, tmplCtx map[string]string, nodeName string) (*batchv1.Job, error) {
 	var jobTemplate string
@@ -511,52 +645,87 @@ func RenderServantJob(action string, tmplCtx map[string]string, nodeName string)
 	return srvJob, nil
 }
 
+// ServantJobResult is the result of the servant job run on a node.
+type ServantJobResult struct {
+	NodeName string
+	JobName  string
+	// Err is nil if the job succeeded.
+	Err error
+	// Logs holds the last lines of the logs of a failed job.
+	Logs string
+}
+
 // RunServantJobs launch servant jobs on specified nodes and wait all jobs to finish.
 // Succeed jobs will be deleted when finished. Failed jobs are preserved for diagnosis.
 func RunServantJobs(
-	cliSet *kubernetes.Clientset,
+	cliSet kubernetes.Interface,
 	waitServantJobTimeout time.Duration,
 	getJob func(nodeName string) (*batchv1.Job, error),
 	nodeNames []string, ww io.Writer) error {
+	results, err := RunServantJobsWithResults(cliSet, waitServantJobTimeout, getJob, nodeNames, nil)
+	if err != nil {
+		return err
+	}
+	for _, res := range results {
+		if res.Err != nil {
+			io.WriteString(ww, fmt.Sprintf("\t[ERROR] fail to run servant job(%s): %s\n", res.JobName, res.Err))
+		} else {
+			io.WriteString(ww, fmt.Sprintf("\t[INFO] servant job(%s) has succeeded\n", res.JobName))
+		}
+	}
+	return nil
+}
+
+// RunServantJobsWithResults launch servant jobs on specified nodes, wait all jobs to finish and
+// return the results sorted by node name. onResult, if not nil, is called with the result of
+// each job as soon as it finishes, one at a time.
+func RunServantJobsWithResults(
+	cliSet kubernetes.Interface,
+	waitServantJobTimeout time.Duration,
+	getJob func(nodeName string) (*batchv1.Job, error),
+	nodeNames []string, onResult func(ServantJobResult)) ([]ServantJobResult, error) {
 	var wg sync.WaitGroup
+	var mu sync.Mutex
 
 	jobByNodeName := make(map[string]*batchv1.Job)
 	for _, nodeName := range nodeNames {
 		job, err := getJob(nodeName)
 		if err != nil {
-			return fmt.Errorf("fail to get job for node %s: %s", nodeName, err)
+			return nil, fmt.Errorf("fail to get job for node %s: %s", nodeName, err)
 		}
 		jobByNodeName[nodeName] = job
 	}
 
-	res := make(chan string, len(nodeNames))
+	results := make([]ServantJobResult, 0, len(nodeNames))
 	for _, nodeName := range nodeNames {
 		wg.Add(1)
+		res := ServantJobResult{NodeName: nodeName, JobName: jobByNodeName[nodeName].GetName()}
 		job := jobByNodeName[nodeName]
 		go func() {
 			defer wg.Done()
 			if err := RunJobAndCleanup(cliSet, job,
 				waitServantJobTimeout, CheckServantJobPeriod); err != nil {
-				msg := fmt.Sprintf("\t[ERROR] fail to run servant job(%s): %s\n", job.GetName(), err)
-				res <- msg
-			} else {
-				msg := fmt.Sprintf("\t[INFO] servant job(%s) has succeeded\n", job.GetName())
-				res <- msg
+				res.Err = err
+				res.Logs = GetJobLogs(cliSet, job, ServantJobLogLines)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			results = append(results, res)
+			if onResult != nil {
+				onResult(res)
 			}
 		}()
 	}
 	wg.Wait()
-	close(res)
-	for m := range res {
-		io.WriteString(ww, m)
-	}
-	return nil
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].NodeName < results[j].NodeName
+	})
+	return results, nil
 }
 
 // ValidateServerVersion checks if the target server's version is supported
-func ValidateServerVersion(cliSet *kubernetes.Clientset) error {
-	serverVersion, err := discovery.
-		NewDiscoveryClient(cliSet.RESTClient()).ServerVersion()
+func ValidateServerVersion(cliSet kubernetes.Interface) error {
+	serverVersion, err := cliSet.Discovery().ServerVersion()
 	if err != nil {
 		return err
 	}
@@ -624,7 +793,7 @@ func PrepareKubeConfigPath(flags *pflag.FlagSet) (string, error) {
 	return kbCfgPath, nil
 }
 
-func GetOrCreateJoinTokenString(cliSet *kubernetes.Clientset) (string, error) {
+func GetOrCreateJoinTokenString(cliSet kubernetes.Interface) (string, error) {
 	tokenSelector := fields.SelectorFromSet(
 		map[string]string{
 			// TODO: We hard-code "type" here until `field_constants.go` that is
@@ -699,7 +868,7 @@ func usagesAndGroupsAreValid(token *kubeadmapi.BootstrapToken) bool {
 }
 
 // find kube-controller-manager deployed through static file
-func GetKubeControllerManagerHANodes(cliSet *kubernetes.Clientset) ([]string, error) {
+func GetKubeControllerManagerHANodes(cliSet kubernetes.Interface) ([]string, error) {
 	var kcmNodeNames []string
 	podLst, err := cliSet.CoreV1().Pods(SystemNamespace).List(context.Background(), metav1.ListOptions{})
 	if err != nil {
@@ -21,9 +21,12 @@ package kubernetes
 // THE SOFTWARE.
 
 import (
+	"context"
 	"testing"
 
 	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 const testDeployment = `
@@ -50,6 +53,27 @@ spec:
         - containerPort: 80
 `
 
+const testDeploymentTmpl = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx-deployment
+  namespace: kube-system
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: nginx
+  template:
+    metadata:
+      labels:
+        app: nginx
+    spec:
+      containers:
+      - name: nginx
+        image: {{.image}}
+`
+
 func TestYamlToObject(t *testing.T) {
 	obj, err := YamlToObject([]byte(testDeployment))
 	if err != nil {
@@ -77,3 +101,47 @@ func TestYamlToObject(t *testing.T) {
 		t.Fatalf("YamlToObj failed: want 3 get %d", *nd.Spec.Replicas)
 	}
 }
+
+func TestUpdateDeployFromYaml(t *testing.T) {
+	cliSet := fake.NewSimpleClientset()
+
+	previous, err := UpdateDeployFromYaml(cliSet, SystemNamespace, testDeploymentTmpl,
+		map[string]string{"image": "nginx:1.21"})
+	if err != nil || previous != nil {
+		t.Fatalf("UpdateDeployFromYaml of a missing deployment: want nil, nil get %v, %v", previous, err)
+	}
+
+	obj, err := YamlToObject([]byte(testDeployment))
+	if err != nil {
+		t.Fatalf("YamlToObj failed: %s", err)
+	}
+	dply := obj.(*appsv1.Deployment)
+	dply.Namespace = SystemNamespace
+	if _, err := cliSet.AppsV1().Deployments(SystemNamespace).Create(context.Background(), dply, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("fail to create the deployment: %s", err)
+	}
+
+	previous, err = UpdateDeployFromYaml(cliSet, SystemNamespace, testDeploymentTmpl,
+		map[string]string{"image": "nginx:1.21"})
+	if err != nil {
+		t.Fatalf("UpdateDeployFromYaml failed: %s", err)
+	}
+	if image := previous.Spec.Template.Spec.Containers[0].Image; image != "nginx:latest" {
+		t.Fatalf("UpdateDeployFromYaml returns the previous image %s, want nginx:latest", image)
+	}
+	cur, _ := cliSet.AppsV1().Deployments(SystemNamespace).Get(context.Background(), dply.Name, metav1.GetOptions{})
+	if image := cur.Spec.Template.Spec.Containers[0].Image; image != "nginx:1.21" {
+		t.Fatalf("UpdateDeployFromYaml: want image nginx:1.21 get %s", image)
+	}
+	if *cur.Spec.Replicas != 3 {
+		t.Fatalf("UpdateDeployFromYaml: want replicas 3 kept get %d", *cur.Spec.Replicas)
+	}
+
+	if err := RestoreDeployment(cliSet, previous); err != nil {
+		t.Fatalf("RestoreDeployment failed: %s", err)
+	}
+	cur, _ = cliSet.AppsV1().Deployments(SystemNamespace).Get(context.Background(), dply.Name, metav1.GetOptions{})
+	if image := cur.Spec.Template.Spec.Containers[0].Image; image != "nginx:latest" {
+		t.Fatalf("RestoreDeployment: want image nginx:latest get %s", image)
+	}
+}
@@ -587,6 +587,7 @@ func get(ctx context.Context, envInfo *cmds.Agent, proxy proxy.Proxy) (*config.N
 	nodeConfig.AgentConfig.PodManifests = filepath.Join(envInfo.DataDir, "agent", DefaultPodManifestPath)
 	nodeConfig.AgentConfig.ProtectKernelDefaults = envInfo.ProtectKernelDefaults
 	nodeConfig.AgentConfig.DisableServiceLB = envInfo.DisableServiceLB
+	nodeConfig.AgentConfig.TunnelTrafficPolicy = envInfo.TunnelTrafficPolicy
 
 	if err := validateNetworkConfig(nodeConfig); err != nil {
 		return nil, err
@@ -73,6 +73,11 @@ func Setup(ctx context.Context, config *config.Node, proxy proxy.Proxy) error {
 		return err
 	}
 
+	policy, err := remotedialer.LoadTrafficPolicy(config.AgentConfig.TunnelTrafficPolicy)
+	if err != nil {
+		return err
+	}
+
 	// Do an immediate fill of proxy addresses from the server endpoint list, before going into the
 	// watch loop. This will fail on the first server, as the apiserver won't be started yet - but
 	// that's fine because the local server is already seeded into the proxy address list.
@@ -90,7 +95,7 @@ func Setup(ctx context.Context, config *config.Node, proxy proxy.Proxy) error {
 	wg := &sync.WaitGroup{}
 	for _, address := range proxy.SupervisorAddresses() {
 		if _, ok := disconnect[address]; !ok {
-			disconnect[address] = connect(ctx, wg, address, tlsConfig)
+			disconnect[address] = connect(ctx, wg, address, tlsConfig, policy)
 		}
 	}
 
@@ -139,7 +144,7 @@ func Setup(ctx context.Context, config *config.Node, proxy proxy.Proxy) error {
 				for _, address := range proxy.SupervisorAddresses() {
 					validEndpoint[address] = true
 					if _, ok := disconnect[address]; !ok {
-						disconnect[address] = connect(ctx, nil, address, tlsConfig)
+						disconnect[address] = connect(ctx, nil, address, tlsConfig, policy)
 					}
 				}
 
@@ -170,7 +175,7 @@ func Setup(ctx context.Context, config *config.Node, proxy proxy.Proxy) error {
 	return nil
 }
 
-func connect(rootCtx context.Context, waitGroup *sync.WaitGroup, address string, tlsConfig *tls.Config) context.CancelFunc {
+func connect(rootCtx context.Context, waitGroup *sync.WaitGroup, address string, tlsConfig *tls.Config, policy *remotedialer.TrafficPolicy) context.CancelFunc {
 	wsURL := fmt.Sprintf("wss://%s/v1-"+version.Program+"/connect", address)
 	ws := &websocket.Dialer{
 		TLSClientConfig: tlsConfig,
@@ -185,10 +190,10 @@ func connect(rootCtx context.Context, waitGroup *sync.WaitGroup, address string,
 
 	go func() {
 		for {
-			remotedialer.ClientConnect(ctx, wsURL, nil, ws, func(proto, address string) bool {
+			remotedialer.ClientConnectWithPolicy(ctx, wsURL, nil, ws, func(proto, address string) bool {
 				host, port, err := net.SplitHostPort(address)
 				return err == nil && proto == "tcp" && ports[port] && host == "127.0.0.1"
-			}, func(_ context.Context, session *remotedialer.Session) error {
+			}, policy, func(_ context.Context, session *remotedialer.Session) error {
 				if waitGroup != nil {
 					once.Do(waitGroup.Done)
 				}
@@ -67,6 +67,7 @@ type Agent struct {
 	Taints                   cli.StringSlice
 	ImageCredProvBinDir      string
 	ImageCredProvConfig      string
+	TunnelTrafficPolicy      string
 	AgentReady               chan<- struct{}
 	AgentShared
 }
@@ -202,6 +203,11 @@ var (
 		Usage:       "(agent/node) Kernel tuning behavior. If set, error if kernel tunables are different than kubelet defaults.",
 		Destination: &AgentConfig.ProtectKernelDefaults,
 	}
+	TunnelTrafficPolicyFlag = cli.StringFlag{
+		Name:        "tunnel-traffic-policy",
+		Usage:       "(agent/networking) File with bandwidth limits and priority classes for tunneled traffic",
+		Destination: &AgentConfig.TunnelTrafficPolicy,
+	}
 	SELinuxFlag = cli.BoolFlag{
 		Name:        "selinux",
 		Usage:       "(agent/node) Enable SELinux in containerd",
@@ -282,6 +288,7 @@ func NewAgentCommand(action func(ctx *cli.Context) error) cli.Command {
 			ExtraKubeletArgs,
 			ExtraKubeProxyArgs,
 			ProtectKernelDefaultsFlag,
+			TunnelTrafficPolicyFlag,
 			cli.BoolFlag{
 				Name:        "rootless",
 				Usage:       "(experimental) Run rootless",
@@ -57,6 +57,16 @@ var EtcdSnapshotFlags = []cli.Flag{
 		Usage:       "(db) Compress etcd snapshot",
 		Destination: &ServerConfig.EtcdSnapshotCompress,
 	},
+	&cli.BoolFlag{
+		Name:        "snapshot-encrypt,etcd-snapshot-encrypt",
+		Usage:       "(db) Encrypt etcd snapshot with the active key of the snapshot encryption keyring",
+		Destination: &ServerConfig.EtcdSnapshotEncrypt,
+	},
+	&cli.StringFlag{
+		Name:        "encryption-keyring,etcd-snapshot-encryption-keyring",
+		Usage:       "(db) Path to the etcd snapshot encryption keyring, one '<key-id> base64:<32 byte key>' or '<key-id> passphrase:<passphrase>' per line, active key first",
+		Destination: &ServerConfig.EtcdSnapshotKeyring,
+	},
 	&cli.BoolFlag{
 		Name:        "s3,etcd-s3",
 		Usage:       "(db) Enable backup to S3",
@@ -131,7 +141,7 @@ func NewEtcdSnapshotCommand(action func(*cli.Context) error, subcommands []cli.C
 	}
 }
 
-func NewEtcdSnapshotSubcommands(delete, list, prune, save func(ctx *cli.Context) error) []cli.Command {
+func NewEtcdSnapshotSubcommands(delete, list, prune, save, decrypt func(ctx *cli.Context) error) []cli.Command {
 	return []cli.Command{
 		{
 			Name:            "delete",
@@ -175,5 +185,13 @@ func NewEtcdSnapshotSubcommands(delete, list, prune, save func(ctx *cli.Context)
 			Action:          save,
 			Flags:           EtcdSnapshotFlags,
 		},
+		{
+			Name:            "decrypt",
+			Usage:           "Decrypt an encrypted snapshot file: decrypt <snapshot> [<output>]",
+			SkipFlagParsing: false,
+			SkipArgReorder:  true,
+			Action:          decrypt,
+			Flags:           EtcdSnapshotFlags,
+		},
 	}
 }
@@ -86,11 +86,22 @@ func NewSecretsEncryptSubcommands(status, enable, disable, prepare, rotate, reen
 			SkipFlagParsing: false,
 			SkipArgReorder:  true,
 			Action:          prepare,
-			Flags: append(EncryptFlags, &cli.BoolFlag{
-				Name:        "f,force",
-				Usage:       "Force preparation.",
-				Destination: &ServerConfig.EncryptForce,
-			}),
+			Flags: append(EncryptFlags,
+				&cli.BoolFlag{
+					Name:        "f,force",
+					Usage:       "Force preparation.",
+					Destination: &ServerConfig.EncryptForce,
+				},
+				&cli.StringFlag{
+					Name:        "provider",
+					Usage:       "Provider of the new key (valid items: aescbc, aesgcm, secretbox, kms). Default: the server's secrets-encryption-provider",
+					Destination: &ServerConfig.EncryptProvider,
+				},
+				&cli.StringSliceFlag{
+					Name:  "resources",
+					Usage: "Resources to encrypt once reencrypt has completed, must include secrets. Default: unchanged",
+					Value: &ServerConfig.EncryptResources,
+				}),
 		},
 		{
 			Name:            "rotate",
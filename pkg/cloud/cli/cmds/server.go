@@ -98,6 +98,10 @@ type Server struct {
 	EncryptForce             bool
 	EncryptOutput            string
 	EncryptSkip              bool
+	EncryptProvider          string
+	EncryptKMSEndpoint       string
+	EncryptKMSTimeout        time.Duration
+	EncryptResources         cli.StringSlice
 	SystemDefaultRegistry    string
 	StartupHooks             []StartupHook
 	EtcdSnapshotName         string
@@ -107,6 +111,8 @@ type Server struct {
 	EtcdSnapshotCron         string
 	EtcdSnapshotRetention    int
 	EtcdSnapshotCompress     bool
+	EtcdSnapshotEncrypt      bool
+	EtcdSnapshotKeyring      string
 	EtcdListFormat           string
 	EtcdS3                   bool
 	EtcdS3Endpoint           string
@@ -323,6 +329,16 @@ var ServerFlags = []cli.Flag{
 		Usage:       "(db) Compress etcd snapshot",
 		Destination: &ServerConfig.EtcdSnapshotCompress,
 	},
+	&cli.BoolFlag{
+		Name:        "etcd-snapshot-encrypt",
+		Usage:       "(db) Encrypt etcd snapshots with the active key of the snapshot encryption keyring",
+		Destination: &ServerConfig.EtcdSnapshotEncrypt,
+	},
+	&cli.StringFlag{
+		Name:        "etcd-snapshot-encryption-keyring",
+		Usage:       "(db) Path to the etcd snapshot encryption keyring, one '<key-id> base64:<32 byte key>' or '<key-id> passphrase:<passphrase>' per line, active key first",
+		Destination: &ServerConfig.EtcdSnapshotKeyring,
+	},
 	&cli.BoolFlag{
 		Name:        "etcd-s3",
 		Usage:       "(db) Enable backup to S3",
@@ -455,6 +471,7 @@ var ServerFlags = []cli.Flag{
 	ExtraKubeletArgs,
 	ExtraKubeProxyArgs,
 	ProtectKernelDefaultsFlag,
+	TunnelTrafficPolicyFlag,
 	cli.BoolFlag{
 		Name:        "rootless",
 		Usage:       "(experimental) Run rootless",
@@ -500,6 +517,28 @@ var ServerFlags = []cli.Flag{
 		Usage:       "(experimental) Enable Secret encryption at rest",
 		Destination: &ServerConfig.EncryptSecrets,
 	},
+	cli.StringFlag{
+		Name:        "secrets-encryption-provider",
+		Usage:       "(experimental) Provider used for new secrets encryption keys (valid items: aescbc, aesgcm, secretbox, kms)",
+		Value:       "aescbc",
+		Destination: &ServerConfig.EncryptProvider,
+	},
+	cli.StringFlag{
+		Name:        "secrets-encryption-kms-endpoint",
+		Usage:       "(experimental) Endpoint of the KMS plugin used by the kms provider, in the form unix:///path/to/socket",
+		Destination: &ServerConfig.EncryptKMSEndpoint,
+	},
+	cli.DurationFlag{
+		Name:        "secrets-encryption-kms-timeout",
+		Usage:       "(experimental) Timeout for calls to the KMS plugin",
+		Value:       3 * time.Second,
+		Destination: &ServerConfig.EncryptKMSTimeout,
+	},
+	cli.StringSliceFlag{
+		Name:  "secrets-encryption-resources",
+		Usage: "(experimental) Resources to encrypt at rest when the encryption configuration is first created, must include secrets (default: secrets)",
+		Value: &ServerConfig.EncryptResources,
+	},
 	cli.StringFlag{
 		Name:        "system-default-registry",
 		Usage:       "(image) Private registry to be used for all system images",
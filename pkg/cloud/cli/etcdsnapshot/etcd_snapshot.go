@@ -34,6 +34,7 @@ import (
 	"github.com/bhojpur/dcp/pkg/cloud/cluster"
 	"github.com/bhojpur/dcp/pkg/cloud/daemons/config"
 	"github.com/bhojpur/dcp/pkg/cloud/etcd"
+	"github.com/bhojpur/dcp/pkg/cloud/etcd/snapshotencrypt"
 	"github.com/bhojpur/dcp/pkg/cloud/server"
 	util2 "github.com/bhojpur/dcp/pkg/cloud/util"
 	"github.com/erikdubbelboer/gspt"
@@ -68,6 +69,8 @@ func commandSetup(app *cli.Context, cfg *cmds.Server, sc *server.Config) error {
 	sc.ControlConfig.EtcdSnapshotName = cfg.EtcdSnapshotName
 	sc.ControlConfig.EtcdSnapshotDir = cfg.EtcdSnapshotDir
 	sc.ControlConfig.EtcdSnapshotCompress = cfg.EtcdSnapshotCompress
+	sc.ControlConfig.EtcdSnapshotEncrypt = cfg.EtcdSnapshotEncrypt
+	sc.ControlConfig.EtcdSnapshotKeyring = cfg.EtcdSnapshotKeyring
 	sc.ControlConfig.EtcdListFormat = strings.ToLower(cfg.EtcdListFormat)
 	sc.ControlConfig.EtcdS3 = cfg.EtcdS3
 	sc.ControlConfig.EtcdS3Endpoint = cfg.EtcdS3Endpoint
@@ -253,6 +256,47 @@ func list(app *cli.Context, cfg *cmds.Server) error {
 	return nil
 }
 
+// Decrypt writes the plaintext of an encrypted snapshot, so that it can be
+// inspected or restored with other tools.
+func Decrypt(app *cli.Context) error {
+	if err := cmds.InitLogging(); err != nil {
+		return err
+	}
+	return decrypt(app, &cmds.ServerConfig)
+}
+
+func decrypt(app *cli.Context, cfg *cmds.Server) error {
+	args := app.Args()
+	if len(args) == 0 || len(args) > 2 {
+		return errors.New("expected a snapshot and an optional output path")
+	}
+	if cfg.EtcdSnapshotKeyring == "" {
+		return errors.New("--etcd-snapshot-encryption-keyring is required to decrypt snapshots")
+	}
+	keyring, err := snapshotencrypt.LoadKeyring(cfg.EtcdSnapshotKeyring)
+	if err != nil {
+		return err
+	}
+
+	src := args[0]
+	dst := strings.TrimSuffix(src, snapshotencrypt.Extension)
+	if len(args) == 2 {
+		dst = args[1]
+	} else if dst == src {
+		return fmt.Errorf("snapshot %s does not end in %s, an output path is required", src, snapshotencrypt.Extension)
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return fmt.Errorf("output %s already exists", dst)
+	}
+
+	keyID, err := keyring.DecryptFile(src, dst)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Decrypted %s with key %s to %s\n", src, keyID, dst)
+	return nil
+}
+
 func Prune(app *cli.Context) error {
 	if err := cmds.InitLogging(); err != nil {
 		return err
@@ -33,6 +33,7 @@ import (
 	"github.com/bhojpur/dcp/pkg/cloud/cli/cmds"
 	"github.com/bhojpur/dcp/pkg/cloud/clientaccess"
 	"github.com/bhojpur/dcp/pkg/cloud/secretencrypt"
+	"github.com/bhojpur/dcp/pkg/cloud/secretencrypt/providers"
 	"github.com/bhojpur/dcp/pkg/cloud/server"
 	"github.com/bhojpur/dcp/pkg/cloud/version"
 	"github.com/erikdubbelboer/gspt"
@@ -139,6 +140,12 @@ func Status(app *cli.Context) error {
 		statusOutput += "Encryption Status: Disabled\n"
 	}
 	statusOutput += fmt.Sprintln("Current Rotation Stage:", status.Stage)
+	if len(status.Resources) > 0 {
+		statusOutput += fmt.Sprintln("Encrypted Resources:", strings.Join(status.Resources, ", "))
+	}
+	if len(status.DecryptingResources) > 0 {
+		statusOutput += fmt.Sprintln("Resources Decrypted On Reencrypt:", strings.Join(status.DecryptingResources, ", "))
+	}
 
 	if status.HashMatch {
 		statusOutput += fmt.Sprintln("Server Encryption Hashes: All hashes match")
@@ -152,16 +159,35 @@ func Status(app *cli.Context) error {
 	fmt.Fprintf(w, "Active\tKey Type\tName\n")
 	fmt.Fprintf(w, "------\t--------\t----\n")
 	if status.ActiveKey != "" {
-		fmt.Fprintf(w, " *\t%s\t%s\n", "AES-CBC", status.ActiveKey)
+		fmt.Fprintf(w, " *\t%s\t%s\n", keyTypeName(status.ActiveKeyType), status.ActiveKey)
 	}
-	for _, k := range status.InactiveKeys {
-		fmt.Fprintf(w, "\t%s\t%s\n", "AES-CBC", k)
+	for i, k := range status.InactiveKeys {
+		var keyType string
+		if i < len(status.InactiveKeyTypes) {
+			keyType = status.InactiveKeyTypes[i]
+		}
+		fmt.Fprintf(w, "\t%s\t%s\n", keyTypeName(keyType), k)
 	}
 	w.Flush()
 	fmt.Println(statusOutput + tabBuffer.String())
 	return nil
 }
 
+// keyTypeName returns the display name of a key type. Servers that predate
+// other providers do not report the type of their AES-CBC keys.
+func keyTypeName(keyType string) string {
+	switch keyType {
+	case providers.AESGCM:
+		return "AES-GCM"
+	case providers.Secretbox:
+		return "Secretbox"
+	case providers.KMS:
+		return "KMS"
+	default:
+		return "AES-CBC"
+	}
+}
+
 func Prepare(app *cli.Context) error {
 	var err error
 	if err = cmds.InitLogging(); err != nil {
@@ -171,9 +197,22 @@ func Prepare(app *cli.Context) error {
 	if err != nil {
 		return err
 	}
+	if cmds.ServerConfig.EncryptProvider != "" {
+		if err := providers.ValidateType(cmds.ServerConfig.EncryptProvider); err != nil {
+			return err
+		}
+	}
+	resources := []string(cmds.ServerConfig.EncryptResources)
+	if len(resources) > 0 {
+		if err := providers.ValidateResources(resources); err != nil {
+			return err
+		}
+	}
 	b, err := json.Marshal(server.EncryptionRequest{
-		Stage: pointer.StringPtr(secretsencrypt.EncryptionPrepare),
-		Force: cmds.ServerConfig.EncryptForce,
+		Stage:     pointer.StringPtr(secretsencrypt.EncryptionPrepare),
+		Force:     cmds.ServerConfig.EncryptForce,
+		Provider:  cmds.ServerConfig.EncryptProvider,
+		Resources: resources,
 	})
 	if err != nil {
 		return err
@@ -165,10 +165,21 @@ func run(app *cli.Context, cfg *cmds.Server, leaderControllers server.CustomCont
 	serverConfig.ControlConfig.DisableControllerManager = cfg.DisableControllerManager
 	serverConfig.ControlConfig.ClusterInit = cfg.ClusterInit
 	serverConfig.ControlConfig.EncryptSecrets = cfg.EncryptSecrets
+	serverConfig.ControlConfig.EncryptProvider = cfg.EncryptProvider
+	serverConfig.ControlConfig.EncryptKMSEndpoint = cfg.EncryptKMSEndpoint
+	serverConfig.ControlConfig.EncryptKMSTimeout = cfg.EncryptKMSTimeout
+	serverConfig.ControlConfig.EncryptResources = cfg.EncryptResources
+	serverConfig.ControlConfig.TunnelTrafficPolicy = cmds.AgentConfig.TunnelTrafficPolicy
 	serverConfig.ControlConfig.EtcdExposeMetrics = cfg.EtcdExposeMetrics
 	serverConfig.ControlConfig.EtcdDisableSnapshots = cfg.EtcdDisableSnapshots
+	// The keyring is also needed to restore encrypted snapshots
+	serverConfig.ControlConfig.EtcdSnapshotKeyring = cfg.EtcdSnapshotKeyring
 
 	if !cfg.EtcdDisableSnapshots {
+		if cfg.EtcdSnapshotEncrypt && cfg.EtcdSnapshotKeyring == "" {
+			return errors.New("invalid flag use; --etcd-snapshot-encryption-keyring required with --etcd-snapshot-encrypt")
+		}
+		serverConfig.ControlConfig.EtcdSnapshotEncrypt = cfg.EtcdSnapshotEncrypt
 		serverConfig.ControlConfig.EtcdSnapshotName = cfg.EtcdSnapshotName
 		serverConfig.ControlConfig.EtcdSnapshotCron = cfg.EtcdSnapshotCron
 		serverConfig.ControlConfig.EtcdSnapshotDir = cfg.EtcdSnapshotDir
@@ -122,6 +122,7 @@ type Agent struct {
 	Rootless                bool
 	ProtectKernelDefaults   bool
 	DisableServiceLB        bool
+	TunnelTrafficPolicy     string
 	EnableIPv6              bool
 }
 
@@ -184,6 +185,10 @@ type Control struct {
 	EncryptSecrets           bool
 	EncryptForce             bool
 	EncryptSkip              bool
+	EncryptProvider          string
+	EncryptKMSEndpoint       string
+	EncryptKMSTimeout        time.Duration
+	EncryptResources         []string
 	TLSMinVersion            uint16
 	TLSCipherSuites          []uint16
 	EtcdSnapshotName         string
@@ -193,6 +198,8 @@ type Control struct {
 	EtcdSnapshotCron         string
 	EtcdSnapshotRetention    int
 	EtcdSnapshotCompress     bool
+	EtcdSnapshotEncrypt      bool
+	EtcdSnapshotKeyring      string
 	EtcdListFormat           string
 	EtcdS3                   bool
 	EtcdS3Endpoint           string
@@ -206,6 +213,7 @@ type Control struct {
 	EtcdS3Timeout            time.Duration
 	EtcdS3Insecure           bool
 	ServerNodeName           string
+	TunnelTrafficPolicy      string
 
 	BindAddress string
 	SANs        []string
@@ -21,11 +21,10 @@ package deps
 // THE SOFTWARE.
 
 import (
+	"context"
 	"crypto"
-	cryptorand "crypto/rand"
 	"crypto/sha256"
 	"crypto/x509"
-	b64 "encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -42,17 +41,15 @@ import (
 	"github.com/bhojpur/dcp/pkg/cloud/daemons/config"
 	certutil "github.com/bhojpur/dcp/pkg/cloud/dynamiclistener/cert"
 	"github.com/bhojpur/dcp/pkg/cloud/passwd"
+	"github.com/bhojpur/dcp/pkg/cloud/secretencrypt/providers"
 	"github.com/bhojpur/dcp/pkg/cloud/token"
 	"github.com/bhojpur/dcp/pkg/cloud/version"
 	"github.com/sirupsen/logrus"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
-	apiserverconfigv1 "k8s.io/apiserver/pkg/apis/config/v1"
 )
 
 const (
 	ipsecTokenSize = 48
-	aescbcKeySize  = 32
 
 	RequestHeaderCN = "system:auth-proxy"
 )
@@ -701,40 +698,36 @@ func genEncryptionConfigAndState(controlConfig *config.Control) error {
 		return nil
 	}
 
-	aescbcKey := make([]byte, aescbcKeySize, aescbcKeySize)
-	_, err := cryptorand.Read(aescbcKey)
+	providerType := controlConfig.EncryptProvider
+	if providerType == "" {
+		providerType = providers.AESCBC
+	}
+	key, err := providers.NewKey(providerType, providers.KMSOptions{
+		Endpoint: controlConfig.EncryptKMSEndpoint,
+		Timeout:  controlConfig.EncryptKMSTimeout,
+	})
 	if err != nil {
 		return err
 	}
-	encodedKey := b64.StdEncoding.EncodeToString(aescbcKey)
+	key.Name = providerType + "key"
+	if key.KMS != nil {
+		key.KMS.Name = key.Name
+		if err := providers.CheckKMSPlugin(context.Background(), key.KMS); err != nil {
+			return err
+		}
+	}
 
-	encConfig := apiserverconfigv1.EncryptionConfiguration{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "EncryptionConfiguration",
-			APIVersion: "apiserver.config.k8s.io/v1",
-		},
-		Resources: []apiserverconfigv1.ResourceConfiguration{
-			{
-				Resources: []string{"secrets"},
-				Providers: []apiserverconfigv1.ProviderConfiguration{
-					{
-						AESCBC: &apiserverconfigv1.AESConfiguration{
-							Keys: []apiserverconfigv1.Key{
-								{
-									Name:   "aescbckey",
-									Secret: encodedKey,
-								},
-							},
-						},
-					},
-					{
-						Identity: &apiserverconfigv1.IdentityConfiguration{},
-					},
-				},
-			},
-		},
+	resources := controlConfig.EncryptResources
+	if len(resources) == 0 {
+		resources = []string{providers.DefaultResource}
 	}
-	b, err := json.Marshal(encConfig)
+	encConfig, err := providers.NewConfig(resources, key)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Encrypting %v at rest with %s key %s", resources, providerType, key.Name)
+
+	b, err := json.Marshal(encConfig.EncryptionConfiguration())
 	if err != nil {
 		return err
 	}
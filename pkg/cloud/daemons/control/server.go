@@ -60,7 +60,11 @@ func Server(ctx context.Context, cfg *config.Control) error {
 		return errors.Wrap(err, "preparing server")
 	}
 
-	cfg.Runtime.Tunnel = setupTunnel()
+	tunnel, err := setupTunnel(cfg)
+	if err != nil {
+		return errors.Wrap(err, "setting up tunnel server")
+	}
+	cfg.Runtime.Tunnel = tunnel
 	proxyutil.DisableProxyHostnameCheck = true
 
 	basicAuth, err := basicAuthenticator(cfg.Runtime.PasswdFile)
@@ -27,6 +27,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bhojpur/dcp/pkg/cloud/daemons/config"
 	"github.com/bhojpur/dcp/pkg/tunnel/remotedialer"
 	"github.com/bhojpur/host/pkg/common/kv"
 	utilnet "k8s.io/apimachinery/pkg/util/net"
@@ -34,10 +35,15 @@ import (
 	"k8s.io/kubernetes/cmd/kube-apiserver/app"
 )
 
-func setupTunnel() http.Handler {
+func setupTunnel(cfg *config.Control) (http.Handler, error) {
+	policy, err := remotedialer.LoadTrafficPolicy(cfg.TunnelTrafficPolicy)
+	if err != nil {
+		return nil, err
+	}
 	tunnelServer := remotedialer.New(authorizer, remotedialer.DefaultErrorWriter)
+	tunnelServer.TrafficPolicy = policy
 	setupProxyDialer(tunnelServer)
-	return tunnelServer
+	return tunnelServer, nil
 }
 
 func setupProxyDialer(tunnelServer *remotedialer.Server) {
@@ -45,6 +45,7 @@ import (
 	"github.com/bhojpur/dcp/pkg/cloud/daemons/control/deps"
 	"github.com/bhojpur/dcp/pkg/cloud/daemons/executor"
 	certutil "github.com/bhojpur/dcp/pkg/cloud/dynamiclistener/cert"
+	"github.com/bhojpur/dcp/pkg/cloud/etcd/snapshotencrypt"
 	"github.com/bhojpur/dcp/pkg/cloud/statebase/client"
 	endpoint2 "github.com/bhojpur/dcp/pkg/cloud/statebase/endpoint"
 	"github.com/bhojpur/dcp/pkg/cloud/version"
@@ -1224,6 +1225,51 @@ func (e *ETCD) decompressSnapshot(snapshotDir, snapshotFile string) (string, err
 	return decompressed.Name(), nil
 }
 
+// snapshotKeyring loads the snapshot encryption keyring. It is read for every
+// operation, so keys that are added to the file take effect immediately.
+func (e *ETCD) snapshotKeyring() (*snapshotencrypt.Keyring, error) {
+	if e.config.EtcdSnapshotKeyring == "" {
+		return nil, errors.New("no etcd snapshot encryption keyring was specified")
+	}
+	return snapshotencrypt.LoadKeyring(e.config.EtcdSnapshotKeyring)
+}
+
+// encryptSnapshot encrypts the given snapshot with the active keyring key and
+// provides the caller with the full path to the encrypted snapshot and the ID
+// of the key.
+func (e *ETCD) encryptSnapshot(snapshotPath string) (string, string, error) {
+	logrus.Info("Encrypting etcd snapshot file: " + snapshotPath)
+
+	keyring, err := e.snapshotKeyring()
+	if err != nil {
+		return "", "", err
+	}
+	encryptedPath := snapshotPath + snapshotencrypt.Extension
+	keyID, err := keyring.EncryptFile(snapshotPath, encryptedPath)
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to encrypt etcd snapshot")
+	}
+	return encryptedPath, keyID, nil
+}
+
+// decryptSnapshot decrypts the given snapshot into the snapshot dir and
+// provides the caller with the full path to the decrypted snapshot.
+func (e *ETCD) decryptSnapshot(snapshotDir, snapshotFile string) (string, error) {
+	logrus.Info("Decrypting etcd snapshot file: " + snapshotFile)
+
+	keyring, err := e.snapshotKeyring()
+	if err != nil {
+		return "", err
+	}
+	decryptedPath := filepath.Join(snapshotDir, "decrypted-"+strings.TrimSuffix(filepath.Base(snapshotFile), snapshotencrypt.Extension))
+	keyID, err := keyring.DecryptFile(snapshotFile, decryptedPath)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decrypt etcd snapshot")
+	}
+	logrus.Infof("Decrypted etcd snapshot with key %s", keyID)
+	return decryptedPath, nil
+}
+
 // Snapshot attempts to save a new snapshot to the configured directory, and then clean up any old and failed
 // snapshots in excess of the retention limits. This method is used in the internal cron snapshot
 // system as well as used to do on-demand snapshots.
@@ -1310,6 +1356,21 @@ func (e *ETCD) Snapshot(ctx context.Context, config *config.Control) error {
 		logrus.Info("Compressed snapshot: " + snapshotPath)
 	}
 
+	// Encrypt before anything is uploaded, so that only ciphertext leaves the node
+	var keyID string
+	if e.config.EtcdSnapshotEncrypt && sf == nil {
+		encryptedPath, id, err := e.encryptSnapshot(snapshotPath)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(snapshotPath); err != nil {
+			return err
+		}
+		snapshotPath = encryptedPath
+		keyID = id
+		logrus.Infof("Encrypted snapshot with key %s: %s", keyID, snapshotPath)
+	}
+
 	// If the snapshot attempt was successful, sf will be nil as we did not set it.
 	if sf == nil {
 		f, err := os.Stat(snapshotPath)
@@ -1324,9 +1385,10 @@ func (e *ETCD) Snapshot(ctx context.Context, config *config.Control) error {
 			CreatedAt: &metav1.Time{
 				Time: f.ModTime(),
 			},
-			Status:     successfulSnapshotStatus,
-			Size:       f.Size(),
-			Compressed: e.config.EtcdSnapshotCompress,
+			Status:          successfulSnapshotStatus,
+			Size:            f.Size(),
+			Compressed:      e.config.EtcdSnapshotCompress,
+			EncryptionKeyID: keyID,
 		}
 
 		if err := e.addSnapshotData(*sf); err != nil {
@@ -1370,6 +1432,7 @@ func (e *ETCD) Snapshot(ctx context.Context, config *config.Control) error {
 				if err != nil {
 					return err
 				}
+				sf.EncryptionKeyID = keyID
 				logrus.Infof("S3 upload complete for %s", snapshotName)
 				if err := e.s3.snapshotRetention(ctx); err != nil {
 					return errors.Wrap(err, "failed to apply s3 snapshot retention policy")
@@ -1416,6 +1479,9 @@ type snapshotFile struct {
 	Status     snapshotStatus `json:"status,omitempty"`
 	S3         *s3Config      `json:"s3Config,omitempty"`
 	Compressed bool           `json:"compressed"`
+	// EncryptionKeyID is the ID of the keyring key the snapshot was
+	// encrypted with, empty for snapshots that are not encrypted.
+	EncryptionKeyID string `json:"encryptionKeyID,omitempty"`
 }
 
 // listLocalSnapshots provides a list of the currently stored
@@ -1446,6 +1512,11 @@ func (e *ETCD) listLocalSnapshots() (map[string]snapshotFile, error) {
 			Size:   f.Size(),
 			Status: successfulSnapshotStatus,
 		}
+		if strings.HasSuffix(f.Name(), snapshotencrypt.Extension) {
+			if keyID, err := snapshotencrypt.KeyID(filepath.Join(snapshotDir, f.Name())); err == nil {
+				sf.EncryptionKeyID = keyID
+			}
+		}
 		sfKey := generateSnapshotConfigMapKey(sf)
 		snapshots[sfKey] = sf
 	}
@@ -1879,21 +1950,33 @@ func (e *ETCD) Restore(ctx context.Context) error {
 		return err
 	}
 
-	var restorePath string
-	if strings.HasSuffix(e.config.ClusterResetRestorePath, compressedExtension) {
+	restorePath := e.config.ClusterResetRestorePath
+	encrypted, err := snapshotencrypt.IsEncrypted(restorePath)
+	if err != nil {
+		return err
+	}
+	if encrypted || strings.HasSuffix(restorePath, compressedExtension) {
 		snapshotDir, err := snapshotDir(e.config, true)
 		if err != nil {
 			return errors.Wrap(err, "failed to get the snapshot dir")
 		}
 
-		decompressSnapshot, err := e.decompressSnapshot(snapshotDir, e.config.ClusterResetRestorePath)
-		if err != nil {
-			return err
+		if encrypted {
+			decryptedSnapshot, err := e.decryptSnapshot(snapshotDir, restorePath)
+			if err != nil {
+				return err
+			}
+			defer os.Remove(decryptedSnapshot)
+			restorePath = decryptedSnapshot
 		}
 
-		restorePath = decompressSnapshot
-	} else {
-		restorePath = e.config.ClusterResetRestorePath
+		if strings.HasSuffix(restorePath, compressedExtension) {
+			decompressSnapshot, err := e.decompressSnapshot(snapshotDir, restorePath)
+			if err != nil {
+				return err
+			}
+			restorePath = decompressSnapshot
+		}
 	}
 
 	// move the data directory to a temp path
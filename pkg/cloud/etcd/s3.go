@@ -37,6 +37,7 @@ import (
 	"time"
 
 	"github.com/bhojpur/dcp/pkg/cloud/daemons/config"
+	"github.com/bhojpur/dcp/pkg/cloud/etcd/snapshotencrypt"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/pkg/errors"
@@ -126,8 +127,12 @@ func (s *S3) upload(ctx context.Context, snapshot, extraMetadata string, now tim
 
 	toCtx, cancel := context.WithTimeout(ctx, s.config.EtcdS3Timeout)
 	defer cancel()
+	contentType := "application/zip"
+	if strings.HasSuffix(snapshot, snapshotencrypt.Extension) {
+		contentType = "application/octet-stream"
+	}
 	opts := minio.PutObjectOptions{
-		ContentType: "application/zip",
+		ContentType: contentType,
 		NumThreads:  2,
 	}
 	uploadInfo, err := s.client.FPutObject(toCtx, s.config.EtcdS3BucketName, snapshotFileName, snapshot, opts)
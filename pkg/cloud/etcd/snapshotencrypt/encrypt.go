@@ -0,0 +1,339 @@
+package snapshotencrypt
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Extension is appended to the name of encrypted snapshot files.
+const Extension = ".enc"
+
+const (
+	magic = "dcp-snapshot-encryption/v1\n"
+
+	kdfScrypt  = "scrypt"
+	scryptN    = 1 << 15
+	maxScryptN = 1 << 20
+	scryptR    = 8
+	scryptP    = 1
+	saltSize   = 16
+
+	// Snapshots are encrypted in chunks so that they never have to be held
+	// in memory. Each chunk is sealed with a nonce holding its index and a
+	// flag for the final chunk, so reordered or truncated files fail to
+	// decrypt.
+	chunkSize = 64 * 1024
+)
+
+// ErrNotEncrypted is returned when decrypting data without the header
+// written by Encrypt.
+var ErrNotEncrypted = errors.New("snapshot is not encrypted")
+
+// header is written as a single JSON line after the magic. The data key is
+// random for every snapshot and is stored wrapped with the keyring key.
+type header struct {
+	KeyID      string `json:"keyID"`
+	KDF        string `json:"kdf,omitempty"`
+	Salt       []byte `json:"salt,omitempty"`
+	N          int    `json:"n,omitempty"`
+	R          int    `json:"r,omitempty"`
+	P          int    `json:"p,omitempty"`
+	WrappedKey []byte `json:"wrappedKey"`
+}
+
+// Encrypt writes src to dst encrypted with the active key and returns the ID
+// of that key.
+func (k *Keyring) Encrypt(dst io.Writer, src io.Reader) (string, error) {
+	key := k.keys[0]
+	h := header{KeyID: key.ID}
+	if key.passphrase != "" {
+		h.KDF, h.N, h.R, h.P = kdfScrypt, scryptN, scryptR, scryptP
+		h.Salt = make([]byte, saltSize)
+		if _, err := rand.Read(h.Salt); err != nil {
+			return "", err
+		}
+	}
+	kek, err := key.derive(h)
+	if err != nil {
+		return "", err
+	}
+
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	if h.WrappedKey, err = seal(kek, dataKey, []byte(magic+key.ID)); err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(h)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.WriteString(dst, magic); err != nil {
+		return "", err
+	}
+	if _, err := dst.Write(append(b, '\n')); err != nil {
+		return "", err
+	}
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return "", err
+	}
+	in := bufio.NewReaderSize(src, chunkSize+1)
+	buf := make([]byte, chunkSize)
+	for counter := uint64(0); ; counter++ {
+		n, err := io.ReadFull(in, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return "", err
+		}
+		last := err != nil
+		if !last {
+			if _, err := in.Peek(1); err == io.EOF {
+				last = true
+			} else if err != nil {
+				return "", err
+			}
+		}
+		if _, err := dst.Write(aead.Seal(nil, chunkNonce(counter, last), buf[:n], nil)); err != nil {
+			return "", err
+		}
+		if last {
+			return key.ID, nil
+		}
+	}
+}
+
+// Decrypt writes the plaintext of src to dst, using whichever keyring key
+// the snapshot was encrypted with, and returns the ID of that key.
+func (k *Keyring) Decrypt(dst io.Writer, src io.Reader) (string, error) {
+	in := bufio.NewReaderSize(src, chunkSize+1)
+	h, err := readHeader(in)
+	if err != nil {
+		return "", err
+	}
+	key, ok := k.key(h.KeyID)
+	if !ok {
+		return h.KeyID, fmt.Errorf("snapshot is encrypted with key %s, which is not in the keyring", h.KeyID)
+	}
+	kek, err := key.derive(*h)
+	if err != nil {
+		return h.KeyID, err
+	}
+	dataKey, err := open(kek, h.WrappedKey, []byte(magic+h.KeyID))
+	if err != nil {
+		return h.KeyID, fmt.Errorf("failed to unwrap snapshot data key with key %s: %v", h.KeyID, err)
+	}
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return h.KeyID, err
+	}
+	buf := make([]byte, chunkSize+aead.Overhead())
+	for counter := uint64(0); ; counter++ {
+		n, err := io.ReadFull(in, buf)
+		if err == io.EOF {
+			return h.KeyID, fmt.Errorf("encrypted snapshot is truncated")
+		} else if err != nil && err != io.ErrUnexpectedEOF {
+			return h.KeyID, err
+		}
+		last := err != nil
+		if !last {
+			if _, err := in.Peek(1); err == io.EOF {
+				last = true
+			} else if err != nil {
+				return h.KeyID, err
+			}
+		}
+		plain, err := aead.Open(nil, chunkNonce(counter, last), buf[:n], nil)
+		if err != nil {
+			return h.KeyID, fmt.Errorf("failed to decrypt snapshot, the file is corrupted or truncated: %v", err)
+		}
+		if _, err := dst.Write(plain); err != nil {
+			return h.KeyID, err
+		}
+		if last {
+			return h.KeyID, nil
+		}
+	}
+}
+
+// EncryptFile encrypts the file at src into dst, which is created with
+// mode 0600.
+func (k *Keyring) EncryptFile(src, dst string) (string, error) {
+	return transformFile(src, dst, k.Encrypt)
+}
+
+// DecryptFile decrypts the file at src into dst, which is created with
+// mode 0600.
+func (k *Keyring) DecryptFile(src, dst string) (string, error) {
+	return transformFile(src, dst, k.Decrypt)
+}
+
+func transformFile(src, dst string, transform func(io.Writer, io.Reader) (string, error)) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+	w := bufio.NewWriter(out)
+	keyID, err := transform(w, in)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return keyID, err
+	}
+	return keyID, nil
+}
+
+// KeyID returns the ID of the key a snapshot file was encrypted with, or
+// ErrNotEncrypted if the file is not encrypted. No key is needed.
+func KeyID(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h, err := readHeader(bufio.NewReader(f))
+	if err != nil {
+		return "", err
+	}
+	return h.KeyID, nil
+}
+
+// IsEncrypted reports whether the file at path is an encrypted snapshot.
+func IsEncrypted(path string) (bool, error) {
+	_, err := KeyID(path)
+	if errors.Is(err, ErrNotEncrypted) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func readHeader(in *bufio.Reader) (*header, error) {
+	prefix, err := in.Peek(len(magic))
+	if err != nil || !bytes.Equal(prefix, []byte(magic)) {
+		if err == nil || err == io.EOF {
+			return nil, ErrNotEncrypted
+		}
+		return nil, err
+	}
+	in.Discard(len(magic))
+	line, err := in.ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encrypted snapshot header: %v", err)
+	}
+	h := &header{}
+	if err := json.Unmarshal(line, h); err != nil {
+		return nil, fmt.Errorf("invalid encrypted snapshot header: %v", err)
+	}
+	if h.KeyID == "" || len(h.WrappedKey) == 0 {
+		return nil, fmt.Errorf("invalid encrypted snapshot header: missing key")
+	}
+	return h, nil
+}
+
+// derive returns the key encryption key, deriving it from the passphrase with
+// the parameters recorded in the header.
+func (k Key) derive(h header) ([]byte, error) {
+	if k.passphrase == "" {
+		if h.KDF != "" {
+			return nil, fmt.Errorf("snapshot expects a passphrase for key %s", k.ID)
+		}
+		return k.secret, nil
+	}
+	if h.KDF != kdfScrypt {
+		return nil, fmt.Errorf("snapshot expects a base64 key for key %s", k.ID)
+	}
+	// The parameters are read from the file, bound them before doing the work
+	if h.N > maxScryptN || h.R > scryptR || h.P > scryptP {
+		return nil, fmt.Errorf("unsupported scrypt parameters in snapshot header")
+	}
+	return scrypt.Key([]byte(k.passphrase), h.Salt, h.N, h.R, h.P, keySize)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(key, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("wrapped key too short")
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+// chunkNonce builds the 12 byte GCM nonce from the chunk index and the
+// final chunk flag. Data keys are never reused, so the nonce only has to be
+// unique within a snapshot.
+func chunkNonce(counter uint64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
@@ -0,0 +1,151 @@
+package snapshotencrypt
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	keySize             = 32
+	minPassphraseLength = 8
+
+	keyPrefix        = "base64:"
+	passphrasePrefix = "passphrase:"
+)
+
+var keyIDRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Key is a key encryption key from the keyring. Either secret or passphrase
+// is set.
+type Key struct {
+	ID         string
+	secret     []byte
+	passphrase string
+}
+
+// Keyring holds the keys used to protect snapshots. The first key encrypts
+// new snapshots, the others are kept to decrypt older snapshots after the key
+// has been rotated.
+type Keyring struct {
+	keys []Key
+}
+
+// LoadKeyring reads a keyring file. Each line holds a key ID and either a
+// base64 encoded 32 byte key or a passphrase:
+//
+//	# comments and blank lines are ignored
+//	2022-06 base64:<output of head -c 32 /dev/urandom | base64>
+//	2022-01 passphrase:correct horse battery staple
+//
+// To rotate keys, add a new key as the first line and keep the old lines
+// until the snapshots encrypted with them have expired.
+func LoadKeyring(path string) (*Keyring, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	keyring, err := ParseKeyring(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot encryption keyring %s: %v", path, err)
+	}
+	return keyring, nil
+}
+
+// ParseKeyring parses keyring entries as described for LoadKeyring.
+func ParseKeyring(r io.Reader) (*Keyring, error) {
+	keyring := &Keyring{}
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.SplitN(text, " ", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a key ID followed by a key", line)
+		}
+		key, err := parseKey(fields[0], strings.TrimSpace(fields[1]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if seen[key.ID] {
+			return nil, fmt.Errorf("line %d: duplicate key ID %s", line, key.ID)
+		}
+		seen[key.ID] = true
+		keyring.keys = append(keyring.keys, key)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(keyring.keys) == 0 {
+		return nil, fmt.Errorf("no keys found")
+	}
+	return keyring, nil
+}
+
+func parseKey(id, value string) (Key, error) {
+	if !keyIDRegexp.MatchString(id) {
+		return Key{}, fmt.Errorf("invalid key ID %q", id)
+	}
+	switch {
+	case strings.HasPrefix(value, keyPrefix):
+		secret, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, keyPrefix))
+		if err != nil {
+			return Key{}, fmt.Errorf("key %s is not valid base64: %v", id, err)
+		}
+		if len(secret) != keySize {
+			return Key{}, fmt.Errorf("key %s must be %d bytes, got %d", id, keySize, len(secret))
+		}
+		return Key{ID: id, secret: secret}, nil
+	case strings.HasPrefix(value, passphrasePrefix):
+		passphrase := strings.TrimPrefix(value, passphrasePrefix)
+		if len(passphrase) < minPassphraseLength {
+			return Key{}, fmt.Errorf("passphrase for key %s must be at least %d characters", id, minPassphraseLength)
+		}
+		return Key{ID: id, passphrase: passphrase}, nil
+	default:
+		return Key{}, fmt.Errorf("key %s must start with %s or %s", id, keyPrefix, passphrasePrefix)
+	}
+}
+
+// ActiveKeyID returns the ID of the key used to encrypt new snapshots.
+func (k *Keyring) ActiveKeyID() string {
+	return k.keys[0].ID
+}
+
+func (k *Keyring) key(id string) (Key, bool) {
+	for _, key := range k.keys {
+		if key.ID == id {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
@@ -0,0 +1,234 @@
+package snapshotencrypt
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newKeyring(t *testing.T, lines ...string) *Keyring {
+	t.Helper()
+	keyring, err := ParseKeyring(strings.NewReader(strings.Join(lines, "\n")))
+	if err != nil {
+		t.Fatalf("ParseKeyring error: %v", err)
+	}
+	return keyring
+}
+
+func randomKey(t *testing.T) string {
+	t.Helper()
+	b := make([]byte, keySize)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return "base64:" + base64.StdEncoding.EncodeToString(b)
+}
+
+func randomData(t *testing.T, size int) []byte {
+	t.Helper()
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestParseKeyring(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{name: "key and passphrase", input: "# keys\n\nnew " + randomKey(t) + "\nold passphrase:correct horse battery\n"},
+		{name: "empty", input: "# nothing\n", wantErr: true},
+		{name: "missing key", input: "new\n", wantErr: true},
+		{name: "short key", input: "new base64:" + base64.StdEncoding.EncodeToString([]byte("short")), wantErr: true},
+		{name: "bad base64", input: "new base64:%%%", wantErr: true},
+		{name: "short passphrase", input: "new passphrase:short", wantErr: true},
+		{name: "unknown type", input: "new plain:secret", wantErr: true},
+		{name: "invalid id", input: "-new passphrase:correct horse battery", wantErr: true},
+		{name: "duplicate id", input: "new passphrase:correct horse battery\nnew passphrase:another passphrase", wantErr: true},
+	}
+	for _, tt := range tests {
+		st := tt
+		tf := func(t *testing.T) {
+			t.Parallel()
+			if _, err := ParseKeyring(strings.NewReader(st.input)); (err != nil) != st.wantErr {
+				t.Errorf("ParseKeyring() error = %v, wantErr %v", err, st.wantErr)
+			}
+		}
+		t.Run(st.name, tf)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	sizes := []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3*chunkSize + 17}
+	keys := map[string]string{
+		"key":        randomKey(t),
+		"passphrase": "passphrase:correct horse battery staple",
+	}
+	for name, key := range keys {
+		keyring := newKeyring(t, "active "+key)
+		for _, size := range sizes {
+			plain := randomData(t, size)
+			var encrypted bytes.Buffer
+			keyID, err := keyring.Encrypt(&encrypted, bytes.NewReader(plain))
+			if err != nil {
+				t.Fatalf("%s/%d: Encrypt error: %v", name, size, err)
+			}
+			if keyID != "active" {
+				t.Errorf("%s/%d: Encrypt key ID = %s, want active", name, size, keyID)
+			}
+			if bytes.Contains(encrypted.Bytes(), plain) && size > 0 {
+				t.Errorf("%s/%d: encrypted output contains the plaintext", name, size)
+			}
+			var decrypted bytes.Buffer
+			if _, err := keyring.Decrypt(&decrypted, bytes.NewReader(encrypted.Bytes())); err != nil {
+				t.Fatalf("%s/%d: Decrypt error: %v", name, size, err)
+			}
+			if !bytes.Equal(decrypted.Bytes(), plain) {
+				t.Errorf("%s/%d: decrypted data does not match", name, size)
+			}
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey := randomKey(t)
+	oldKeyring := newKeyring(t, "2022-01 "+oldKey)
+	plain := randomData(t, 2*chunkSize)
+	var encrypted bytes.Buffer
+	if _, err := oldKeyring.Encrypt(&encrypted, bytes.NewReader(plain)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A rotated keyring encrypts with the new key and still reads old snapshots
+	rotated := newKeyring(t, "2022-06 passphrase:correct horse battery staple", "2022-01 "+oldKey)
+	if rotated.ActiveKeyID() != "2022-06" {
+		t.Errorf("ActiveKeyID() = %s, want 2022-06", rotated.ActiveKeyID())
+	}
+	var decrypted bytes.Buffer
+	keyID, err := rotated.Decrypt(&decrypted, bytes.NewReader(encrypted.Bytes()))
+	if err != nil {
+		t.Fatalf("Decrypt with rotated keyring error: %v", err)
+	}
+	if keyID != "2022-01" || !bytes.Equal(decrypted.Bytes(), plain) {
+		t.Errorf("unexpected decrypt result with key %s", keyID)
+	}
+
+	// Once the old key is dropped, old snapshots report the missing key
+	dropped := newKeyring(t, "2022-06 passphrase:correct horse battery staple")
+	keyID, err = dropped.Decrypt(ioutil.Discard, bytes.NewReader(encrypted.Bytes()))
+	if err == nil || keyID != "2022-01" {
+		t.Errorf("expected missing key error for 2022-01, got key %s, error %v", keyID, err)
+	}
+
+	// The same ID with different key material must not decrypt
+	wrong := newKeyring(t, "2022-01 "+randomKey(t))
+	if _, err := wrong.Decrypt(ioutil.Discard, bytes.NewReader(encrypted.Bytes())); err == nil {
+		t.Errorf("expected error decrypting with the wrong key")
+	}
+}
+
+func TestTampering(t *testing.T) {
+	keyring := newKeyring(t, "active "+randomKey(t))
+	var encrypted bytes.Buffer
+	if _, err := keyring.Encrypt(&encrypted, bytes.NewReader(randomData(t, 2*chunkSize))); err != nil {
+		t.Fatal(err)
+	}
+	data := encrypted.Bytes()
+	overhead := 16
+
+	tests := map[string][]byte{
+		"truncated at chunk boundary": data[:len(data)-(chunkSize+overhead)],
+		"truncated mid chunk":         data[:len(data)-10],
+		"flipped bit":                 append(append([]byte{}, data[:len(data)-1]...), data[len(data)-1]^1),
+		"header only":                 data[:bytes.IndexByte(data[len(magic):], '\n')+len(magic)+1],
+	}
+	for name, tampered := range tests {
+		if _, err := keyring.Decrypt(ioutil.Discard, bytes.NewReader(tampered)); err == nil {
+			t.Errorf("%s: expected decrypt to fail", name)
+		}
+	}
+
+	if _, err := keyring.Decrypt(ioutil.Discard, strings.NewReader("plain etcd snapshot")); !errors.Is(err, ErrNotEncrypted) {
+		t.Errorf("expected ErrNotEncrypted, got %v", err)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	keyringPath := filepath.Join(dir, "keyring")
+	if err := ioutil.WriteFile(keyringPath, []byte("active "+randomKey(t)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := LoadKeyring(keyringPath)
+	if err != nil {
+		t.Fatalf("LoadKeyring error: %v", err)
+	}
+
+	plain := randomData(t, chunkSize+5)
+	plainPath := filepath.Join(dir, "snapshot.zip")
+	encryptedPath := plainPath + Extension
+	decryptedPath := filepath.Join(dir, "decrypted.zip")
+	if err := ioutil.WriteFile(plainPath, plain, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if encrypted, err := IsEncrypted(plainPath); err != nil || encrypted {
+		t.Errorf("IsEncrypted(plain) = %v, %v", encrypted, err)
+	}
+	if _, err := keyring.EncryptFile(plainPath, encryptedPath); err != nil {
+		t.Fatalf("EncryptFile error: %v", err)
+	}
+	if encrypted, err := IsEncrypted(encryptedPath); err != nil || !encrypted {
+		t.Errorf("IsEncrypted(encrypted) = %v, %v", encrypted, err)
+	}
+	if keyID, err := KeyID(encryptedPath); err != nil || keyID != "active" {
+		t.Errorf("KeyID() = %s, %v", keyID, err)
+	}
+	if info, err := os.Stat(encryptedPath); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("unexpected encrypted file mode: %v, %v", info, err)
+	}
+
+	if _, err := keyring.DecryptFile(encryptedPath, decryptedPath); err != nil {
+		t.Fatalf("DecryptFile error: %v", err)
+	}
+	if decrypted, err := ioutil.ReadFile(decryptedPath); err != nil || !bytes.Equal(decrypted, plain) {
+		t.Errorf("decrypted file does not match: %v", err)
+	}
+
+	// Failed decryption must not leave partial plaintext behind
+	if _, err := keyring.DecryptFile(plainPath, filepath.Join(dir, "failed")); err == nil {
+		t.Errorf("expected error decrypting a plain file")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "failed")); !os.IsNotExist(err) {
+		t.Errorf("expected failed output to be removed, got %v", err)
+	}
+}
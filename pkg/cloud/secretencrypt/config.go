@@ -28,11 +28,11 @@ import (
 	"io/ioutil"
 
 	"github.com/bhojpur/dcp/pkg/cloud/daemons/config"
+	"github.com/bhojpur/dcp/pkg/cloud/secretencrypt/providers"
 	"github.com/bhojpur/dcp/pkg/cloud/version"
 	corev1 "k8s.io/api/core/v1"
 
 	"github.com/sirupsen/logrus"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	apiserverconfigv1 "k8s.io/apiserver/pkg/apis/config/v1"
 )
 
@@ -48,81 +48,49 @@ const (
 var EncryptionHashAnnotation = version.Program + ".io/encryption-config-hash"
 
 func GetEncryptionProviders(runtime *config.ControlRuntime) ([]apiserverconfigv1.ProviderConfiguration, error) {
-	curEncryptionByte, err := ioutil.ReadFile(runtime.EncryptionConfig)
+	curEncryption, err := getEncryptionConfiguration(runtime)
 	if err != nil {
 		return nil, err
 	}
-
-	curEncryption := apiserverconfigv1.EncryptionConfiguration{}
-	if err = json.Unmarshal(curEncryptionByte, &curEncryption); err != nil {
-		return nil, err
-	}
 	return curEncryption.Resources[0].Providers, nil
 }
 
-func GetEncryptionKeys(runtime *config.ControlRuntime) ([]apiserverconfigv1.Key, error) {
-
-	providers, err := GetEncryptionProviders(runtime)
+func getEncryptionConfiguration(runtime *config.ControlRuntime) (*apiserverconfigv1.EncryptionConfiguration, error) {
+	curEncryptionByte, err := ioutil.ReadFile(runtime.EncryptionConfig)
 	if err != nil {
 		return nil, err
 	}
-	if len(providers) > 2 {
-		return nil, fmt.Errorf("more than 2 providers (%d) found in secrets encryption", len(providers))
-	}
 
-	var curKeys []apiserverconfigv1.Key
-	for _, p := range providers {
-		if p.AESCBC != nil {
-			curKeys = append(curKeys, p.AESCBC.Keys...)
-		}
-		if p.AESGCM != nil || p.KMS != nil || p.Secretbox != nil {
-			return nil, fmt.Errorf("non-standard encryption keys found")
-		}
+	curEncryption := &apiserverconfigv1.EncryptionConfiguration{}
+	if err = json.Unmarshal(curEncryptionByte, curEncryption); err != nil {
+		return nil, err
+	}
+	if len(curEncryption.Resources) == 0 {
+		return nil, fmt.Errorf("no resources found in secrets encryption")
 	}
-	return curKeys, nil
+	return curEncryption, nil
 }
 
-func WriteEncryptionConfig(runtime *config.ControlRuntime, keys []apiserverconfigv1.Key, enable bool) error {
-
-	// Placing the identity provider first disables encryption
-	var providers []apiserverconfigv1.ProviderConfiguration
-	if enable {
-		providers = []apiserverconfigv1.ProviderConfiguration{
-			{
-				AESCBC: &apiserverconfigv1.AESConfiguration{
-					Keys: keys,
-				},
-			},
-			{
-				Identity: &apiserverconfigv1.IdentityConfiguration{},
-			},
-		}
-	} else {
-		providers = []apiserverconfigv1.ProviderConfiguration{
-			{
-				Identity: &apiserverconfigv1.IdentityConfiguration{},
-			},
-			{
-				AESCBC: &apiserverconfigv1.AESConfiguration{
-					Keys: keys,
-				},
-			},
-		}
+// GetEncryptionConfig reads the encryption configuration in the flattened form
+// used by the secrets-encrypt stages.
+func GetEncryptionConfig(runtime *config.ControlRuntime) (*providers.Config, error) {
+	curEncryption, err := getEncryptionConfiguration(runtime)
+	if err != nil {
+		return nil, err
 	}
+	return providers.Parse(curEncryption)
+}
 
-	encConfig := apiserverconfigv1.EncryptionConfiguration{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "EncryptionConfiguration",
-			APIVersion: "apiserver.config.k8s.io/v1",
-		},
-		Resources: []apiserverconfigv1.ResourceConfiguration{
-			{
-				Resources: []string{"secrets"},
-				Providers: providers,
-			},
-		},
+func GetEncryptionKeys(runtime *config.ControlRuntime) ([]providers.Key, error) {
+	cfg, err := GetEncryptionConfig(runtime)
+	if err != nil {
+		return nil, err
 	}
-	jsonfile, err := json.Marshal(encConfig)
+	return cfg.Keys, nil
+}
+
+func WriteEncryptionConfig(runtime *config.ControlRuntime, cfg *providers.Config) error {
+	jsonfile, err := json.Marshal(cfg.EncryptionConfiguration())
 	if err != nil {
 		return err
 	}
@@ -146,12 +114,21 @@ func GenReencryptHash(runtime *config.ControlRuntime, keyName string) (string, e
 	if err != nil {
 		return "", err
 	}
+	// KMS keys are identified by their plugin endpoint instead of a secret
+	var hashKeys []apiserverconfigv1.Key
+	for _, k := range keys {
+		if k.KMS != nil {
+			hashKeys = append(hashKeys, apiserverconfigv1.Key{Name: k.Name, Secret: k.KMS.Endpoint})
+		} else {
+			hashKeys = append(hashKeys, apiserverconfigv1.Key{Name: k.Name, Secret: k.Secret})
+		}
+	}
 	newKey := apiserverconfigv1.Key{
 		Name:   keyName,
 		Secret: "12345",
 	}
-	keys = append(keys, newKey)
-	b, err := json.Marshal(keys)
+	hashKeys = append(hashKeys, newKey)
+	b, err := json.Marshal(hashKeys)
 	if err != nil {
 		return "", err
 	}
@@ -27,24 +27,32 @@ import (
 
 	"github.com/bhojpur/dcp/pkg/cloud/cluster"
 	"github.com/bhojpur/dcp/pkg/cloud/daemons/config"
+	"github.com/bhojpur/dcp/pkg/cloud/secretencrypt/providers"
 	"github.com/bhojpur/dcp/pkg/cloud/util"
 	coreclient "github.com/bhojpur/host/pkg/generated/controllers/core/v1"
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
-
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/pager"
 	"k8s.io/client-go/tools/record"
 )
 
 const (
-	controllerAgentName        string = "reencrypt-controller"
-	secretsUpdateStartEvent    string = "SecretsUpdateStart"
-	secretsProgressEvent       string = "SecretsProgress"
-	secretsUpdateCompleteEvent string = "SecretsUpdateComplete"
-	secretsUpdateErrorEvent    string = "SecretsUpdateError"
+	controllerAgentName          string = "reencrypt-controller"
+	secretsUpdateStartEvent      string = "SecretsUpdateStart"
+	secretsProgressEvent         string = "SecretsProgress"
+	secretsUpdateCompleteEvent   string = "SecretsUpdateComplete"
+	secretsUpdateErrorEvent      string = "SecretsUpdateError"
+	resourcesProgressEvent       string = "ResourcesProgress"
+	resourcesUpdateCompleteEvent string = "ResourcesUpdateComplete"
 )
 
 type handler struct {
@@ -52,6 +60,8 @@ type handler struct {
 	controlConfig *config.Control
 	nodes         coreclient.NodeController
 	secrets       coreclient.SecretController
+	k8s           kubernetes.Interface
+	dynamic       dynamic.Interface
 	recorder      record.EventRecorder
 }
 
@@ -62,11 +72,23 @@ func Register(
 	nodes coreclient.NodeController,
 	secrets coreclient.SecretController,
 ) error {
+	// Resources other than secrets are reencrypted through the dynamic client
+	restConfig, err := clientcmd.BuildConfigFromFlags("", controlConfig.Runtime.KubeConfigAdmin)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return err
+	}
+
 	h := &handler{
 		ctx:           ctx,
 		controlConfig: controlConfig,
 		nodes:         nodes,
 		secrets:       secrets,
+		k8s:           k8s,
+		dynamic:       dynamicClient,
 		recorder:      util.BuildControllerEventRecorder(k8s, controllerAgentName),
 	}
 
@@ -105,7 +127,12 @@ func (h *handler) onChangeNode(key string, node *corev1.Node) (*corev1.Node, err
 		return node, err
 	}
 
-	if err := h.updateSecrets(node); err != nil {
+	encConfig, err := GetEncryptionConfig(h.controlConfig.Runtime)
+	if err != nil {
+		h.recorder.Event(node, corev1.EventTypeWarning, secretsUpdateErrorEvent, err.Error())
+		return node, err
+	}
+	if err := h.updateResources(node, encConfig.AllResources()); err != nil {
 		h.recorder.Event(node, corev1.EventTypeWarning, secretsUpdateErrorEvent, err.Error())
 		return node, err
 	}
@@ -119,23 +146,17 @@ func (h *handler) onChangeNode(key string, node *corev1.Node) (*corev1.Node, err
 		return node, nil
 	}
 
-	// Remove last key
-	curKeys, err := GetEncryptionKeys(h.controlConfig.Runtime)
+	// Remove last key, along with the resources that are no longer encrypted
+	removedKey, err := encConfig.FinishReencrypt()
 	if err != nil {
 		h.recorder.Event(node, corev1.EventTypeWarning, secretsUpdateErrorEvent, err.Error())
 		return node, err
 	}
-
-	curKeys = curKeys[:len(curKeys)-1]
-	if err = WriteEncryptionConfig(h.controlConfig.Runtime, curKeys, true); err != nil {
-		h.recorder.Event(node, corev1.EventTypeWarning, secretsUpdateErrorEvent, err.Error())
-		return node, err
-	}
-	logrus.Infoln("Removed key: ", curKeys[len(curKeys)-1])
-	if err != nil {
+	if err = WriteEncryptionConfig(h.controlConfig.Runtime, encConfig); err != nil {
 		h.recorder.Event(node, corev1.EventTypeWarning, secretsUpdateErrorEvent, err.Error())
 		return node, err
 	}
+	logrus.Infoln("Removed key: ", removedKey.Name)
 	if err := WriteEncryptionHashAnnotation(h.controlConfig.Runtime, node, EncryptionReencryptFinished); err != nil {
 		h.recorder.Event(node, corev1.EventTypeWarning, secretsUpdateErrorEvent, err.Error())
 		return node, err
@@ -214,3 +235,55 @@ func (h *handler) updateSecrets(node *corev1.Node) error {
 	h.recorder.Eventf(node, corev1.EventTypeNormal, secretsUpdateCompleteEvent, "completed reencrypt of %d secrets", i)
 	return nil
 }
+
+// updateResources rewrites every object of the given resources, so that the
+// apiserver stores them with the active provider.
+func (h *handler) updateResources(node *corev1.Node, resources []string) error {
+	var mapper *restmapper.DeferredDiscoveryRESTMapper
+	for _, resource := range resources {
+		if resource == providers.DefaultResource {
+			if err := h.updateSecrets(node); err != nil {
+				return err
+			}
+			continue
+		}
+		if mapper == nil {
+			mapper = restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(h.k8s.Discovery()))
+		}
+		gvr, err := mapper.ResourceFor(schema.ParseGroupResource(resource).WithVersion(""))
+		if err != nil {
+			return fmt.Errorf("failed to find resource %s: %v", resource, err)
+		}
+		if err := h.updateResource(node, resource, gvr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *handler) updateResource(node *corev1.Node, resource string, gvr schema.GroupVersionResource) error {
+	client := h.dynamic.Resource(gvr)
+	resourcePager := pager.New(pager.SimplePageFunc(func(opts metav1.ListOptions) (runtime.Object, error) {
+		return client.List(h.ctx, opts)
+	}))
+	i := 0
+	err := resourcePager.EachListItem(h.ctx, metav1.ListOptions{}, func(obj runtime.Object) error {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return nil
+		}
+		if _, err := client.Namespace(u.GetNamespace()).Update(h.ctx, u, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to reencrypt %s %s/%s: %v", resource, u.GetNamespace(), u.GetName(), err)
+		}
+		if i != 0 && i%10 == 0 {
+			h.recorder.Eventf(node, corev1.EventTypeNormal, resourcesProgressEvent, "reencrypted %d %s", i, resource)
+		}
+		i++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	h.recorder.Eventf(node, corev1.EventTypeNormal, resourcesUpdateCompleteEvent, "completed reencrypt of %d %s", i, resource)
+	return nil
+}
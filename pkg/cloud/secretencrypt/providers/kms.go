@@ -0,0 +1,107 @@
+package providers
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"google.golang.org/grpc"
+	apiserverconfigv1 "k8s.io/apiserver/pkg/apis/config/v1"
+	kmsapi "k8s.io/apiserver/pkg/storage/value/encrypt/envelope/v1beta1"
+)
+
+// kmsAPIVersion is the KMS plugin API implemented by the bundled apiserver.
+const kmsAPIVersion = "v1beta1"
+
+var kmsProbe = []byte("secrets-encryption-kms-probe")
+
+// CheckKMSPlugin verifies that the KMS plugin behind a key is reachable, speaks
+// the API version expected by the apiserver and round trips data. The apiserver
+// refuses to start when a configured plugin is unavailable, so this is checked
+// before a KMS key is written to the encryption configuration.
+func CheckKMSPlugin(ctx context.Context, kms *apiserverconfigv1.KMSConfiguration) error {
+	addr, err := parseKMSEndpoint(kms.Endpoint)
+	if err != nil {
+		return err
+	}
+	timeout := DefaultKMSTimeout
+	if kms.Timeout != nil && kms.Timeout.Duration > 0 {
+		timeout = kms.Timeout.Duration
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, addr,
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", addr)
+		}))
+	if err != nil {
+		return fmt.Errorf("failed to connect to KMS plugin at %s: %v", kms.Endpoint, err)
+	}
+	defer conn.Close()
+	client := kmsapi.NewKeyManagementServiceClient(conn)
+
+	version, err := client.Version(ctx, &kmsapi.VersionRequest{Version: kmsAPIVersion})
+	if err != nil {
+		return fmt.Errorf("failed to get version from KMS plugin at %s: %v", kms.Endpoint, err)
+	}
+	if version.Version != kmsAPIVersion {
+		return fmt.Errorf("KMS plugin at %s implements API version %s, expected %s", kms.Endpoint, version.Version, kmsAPIVersion)
+	}
+	encrypted, err := client.Encrypt(ctx, &kmsapi.EncryptRequest{Version: kmsAPIVersion, Plain: kmsProbe})
+	if err != nil {
+		return fmt.Errorf("KMS plugin at %s failed to encrypt: %v", kms.Endpoint, err)
+	}
+	decrypted, err := client.Decrypt(ctx, &kmsapi.DecryptRequest{Version: kmsAPIVersion, Cipher: encrypted.Cipher})
+	if err != nil {
+		return fmt.Errorf("KMS plugin at %s failed to decrypt: %v", kms.Endpoint, err)
+	}
+	if !bytes.Equal(decrypted.Plain, kmsProbe) {
+		return fmt.Errorf("KMS plugin at %s did not return the original data on decrypt", kms.Endpoint)
+	}
+	return nil
+}
+
+// parseKMSEndpoint accepts the same unix:// endpoints as the apiserver,
+// including abstract sockets in the form unix:///@name.
+func parseKMSEndpoint(endpoint string) (string, error) {
+	if endpoint == "" {
+		return "", fmt.Errorf("KMS plugin endpoint must not be empty")
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid KMS plugin endpoint %q: %v", endpoint, err)
+	}
+	if u.Scheme != "unix" {
+		return "", fmt.Errorf("unsupported scheme %q for KMS plugin endpoint, only unix is supported", u.Scheme)
+	}
+	if strings.HasPrefix(u.Path, "/@") {
+		return strings.TrimPrefix(u.Path, "/"), nil
+	}
+	return u.Path, nil
+}
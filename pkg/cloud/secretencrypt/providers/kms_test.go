@@ -0,0 +1,88 @@
+package providers
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apiserverconfigv1 "k8s.io/apiserver/pkg/apis/config/v1"
+	kmstesting "k8s.io/apiserver/pkg/storage/value/encrypt/envelope/testing"
+)
+
+func startKMSPlugin(t *testing.T) (*kmstesting.Base64Plugin, string) {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "kms.sock")
+	plugin, err := kmstesting.NewBase64Plugin(socket)
+	if err != nil {
+		t.Fatalf("failed to create fake KMS plugin: %v", err)
+	}
+	if err := plugin.Start(); err != nil {
+		t.Fatalf("failed to start fake KMS plugin: %v", err)
+	}
+	t.Cleanup(plugin.CleanUp)
+	return plugin, "unix://" + socket
+}
+
+func TestCheckKMSPlugin(t *testing.T) {
+	plugin, endpoint := startKMSPlugin(t)
+	kms := &apiserverconfigv1.KMSConfiguration{
+		Name:     "kmskey",
+		Endpoint: endpoint,
+		Timeout:  &metav1.Duration{Duration: time.Second},
+	}
+	if err := CheckKMSPlugin(context.Background(), kms); err != nil {
+		t.Fatalf("CheckKMSPlugin error: %v", err)
+	}
+	if string(plugin.LastEncryptRequest()) != string(kmsProbe) {
+		t.Errorf("plugin did not receive the probe, got %q", plugin.LastEncryptRequest())
+	}
+
+	plugin.SetVersion("v2alpha1")
+	if err := CheckKMSPlugin(context.Background(), kms); err == nil {
+		t.Errorf("expected error for unsupported plugin version")
+	}
+	plugin.SetVersion(kmsAPIVersion)
+
+	plugin.EnterFailedState()
+	if err := CheckKMSPlugin(context.Background(), kms); err == nil {
+		t.Errorf("expected error for failed plugin")
+	}
+	plugin.ExitFailedState()
+}
+
+func TestCheckKMSPluginUnavailable(t *testing.T) {
+	kms := &apiserverconfigv1.KMSConfiguration{
+		Name:     "kmskey",
+		Endpoint: "unix://" + filepath.Join(t.TempDir(), "missing.sock"),
+		Timeout:  &metav1.Duration{Duration: 200 * time.Millisecond},
+	}
+	if err := CheckKMSPlugin(context.Background(), kms); err == nil {
+		t.Errorf("expected error for missing plugin socket")
+	}
+	kms.Endpoint = "tcp://127.0.0.1:9000"
+	if err := CheckKMSPlugin(context.Background(), kms); err == nil {
+		t.Errorf("expected error for non-unix endpoint")
+	}
+}
@@ -0,0 +1,344 @@
+package providers
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apiserverconfigv1 "k8s.io/apiserver/pkg/apis/config/v1"
+)
+
+// Provider types that may be used for secrets encryption keys. AES-GCM keys
+// must be rotated before about 200k writes have been made with them, so
+// AES-CBC remains the default.
+const (
+	AESCBC    = "aescbc"
+	AESGCM    = "aesgcm"
+	Secretbox = "secretbox"
+	KMS       = "kms"
+)
+
+const (
+	keySize = 32
+
+	// DefaultResource is always encrypted while secrets encryption is enabled
+	DefaultResource = "secrets"
+
+	// DefaultKMSTimeout matches the timeout applied by the apiserver when a
+	// KMS provider does not set one.
+	DefaultKMSTimeout = 3 * time.Second
+)
+
+// Types lists the supported provider types in order of preference.
+var Types = []string{AESCBC, AESGCM, Secretbox, KMS}
+
+var resourceRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+// Key is a single encryption key, independent of the provider that holds it.
+// KMS keys carry the plugin configuration instead of a secret.
+type Key struct {
+	Type   string
+	Name   string
+	Secret string
+	KMS    *apiserverconfigv1.KMSConfiguration
+}
+
+// KMSOptions configures the KMS plugin used for new KMS keys.
+type KMSOptions struct {
+	Endpoint string
+	Timeout  time.Duration
+}
+
+// Config is the flattened form of the EncryptionConfiguration managed by the
+// secrets-encrypt stages. Keys are ordered; when encryption is enabled the
+// first key encrypts new writes and the others are only used for reads.
+type Config struct {
+	Enabled bool
+	// Resources are encrypted with the keys.
+	Resources []string
+	// Decrypting are resources that were removed from the encrypted set and
+	// are stored in plaintext again once they have been reencrypted.
+	Decrypting []string
+	Keys       []Key
+}
+
+// ValidateType returns an error if the provider type is not supported.
+func ValidateType(providerType string) error {
+	for _, t := range Types {
+		if providerType == t {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported secrets encryption provider %q, must be one of %v", providerType, Types)
+}
+
+// ValidateResources checks a list of resources as accepted by the apiserver
+// EncryptionConfiguration, in the form resource or resource.group.
+func ValidateResources(resources []string) error {
+	seen := map[string]bool{}
+	for _, r := range resources {
+		if !resourceRegexp.MatchString(r) {
+			return fmt.Errorf("invalid resource %q, must be in the form resource or resource.group", r)
+		}
+		if seen[r] {
+			return fmt.Errorf("resource %q listed more than once", r)
+		}
+		seen[r] = true
+	}
+	if !seen[DefaultResource] {
+		return fmt.Errorf("resource list %v must include %s", resources, DefaultResource)
+	}
+	return nil
+}
+
+// NewKey generates a key of the given provider type. KMS keys only reference
+// the plugin, the data encryption keys are managed by the apiserver.
+func NewKey(providerType string, kms KMSOptions) (Key, error) {
+	if err := ValidateType(providerType); err != nil {
+		return Key{}, err
+	}
+	key := Key{
+		Type: providerType,
+		Name: providerType + "key-" + time.Now().Format(time.RFC3339),
+	}
+	if providerType == KMS {
+		if kms.Endpoint == "" {
+			return Key{}, fmt.Errorf("an endpoint is required for the %s provider", KMS)
+		}
+		key.KMS = &apiserverconfigv1.KMSConfiguration{
+			Name:     key.Name,
+			Endpoint: kms.Endpoint,
+		}
+		if kms.Timeout > 0 {
+			key.KMS.Timeout = &metav1.Duration{Duration: kms.Timeout}
+		}
+		return key, nil
+	}
+
+	secret := make([]byte, keySize)
+	if _, err := rand.Read(secret); err != nil {
+		return Key{}, err
+	}
+	key.Secret = base64.StdEncoding.EncodeToString(secret)
+	return key, nil
+}
+
+// NewConfig returns an enabled configuration with a single key.
+func NewConfig(resources []string, key Key) (*Config, error) {
+	if err := ValidateResources(resources); err != nil {
+		return nil, err
+	}
+	return &Config{
+		Enabled:   true,
+		Resources: resources,
+		Keys:      []Key{key},
+	}, nil
+}
+
+// Parse flattens an EncryptionConfiguration written by NewConfig and the
+// secrets-encrypt stages. Any other layout is rejected.
+func Parse(encConfig *apiserverconfigv1.EncryptionConfiguration) (*Config, error) {
+	if len(encConfig.Resources) == 0 || len(encConfig.Resources) > 2 {
+		return nil, fmt.Errorf("unexpected number of resource configurations (%d) found in secrets encryption", len(encConfig.Resources))
+	}
+	encrypted := encConfig.Resources[0]
+	if len(encrypted.Providers) == 0 {
+		return nil, fmt.Errorf("no providers found in secrets encryption")
+	}
+	keys, err := flatten(encrypted.Providers)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{
+		Enabled:   encrypted.Providers[0].Identity == nil,
+		Resources: encrypted.Resources,
+		Keys:      keys,
+	}
+	if len(encConfig.Resources) == 2 {
+		decrypting := encConfig.Resources[1]
+		if len(decrypting.Providers) == 0 || decrypting.Providers[0].Identity == nil {
+			return nil, fmt.Errorf("unexpected providers found for resources %v", decrypting.Resources)
+		}
+		cfg.Decrypting = decrypting.Resources
+	}
+	return cfg, nil
+}
+
+func flatten(providers []apiserverconfigv1.ProviderConfiguration) ([]Key, error) {
+	var keys []Key
+	identities := 0
+	for _, p := range providers {
+		switch {
+		case p.Identity != nil:
+			identities++
+		case p.AESCBC != nil:
+			keys = appendKeys(keys, AESCBC, p.AESCBC.Keys)
+		case p.AESGCM != nil:
+			keys = appendKeys(keys, AESGCM, p.AESGCM.Keys)
+		case p.Secretbox != nil:
+			keys = appendKeys(keys, Secretbox, p.Secretbox.Keys)
+		case p.KMS != nil:
+			keys = append(keys, Key{Type: KMS, Name: p.KMS.Name, KMS: p.KMS})
+		default:
+			return nil, fmt.Errorf("unknown provider found in secrets encryption")
+		}
+	}
+	if identities != 1 {
+		return nil, fmt.Errorf("expected exactly one identity provider in secrets encryption, found %d", identities)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no encryption keys found in secrets encryption")
+	}
+	return keys, nil
+}
+
+func appendKeys(keys []Key, providerType string, providerKeys []apiserverconfigv1.Key) []Key {
+	for _, k := range providerKeys {
+		keys = append(keys, Key{Type: providerType, Name: k.Name, Secret: k.Secret})
+	}
+	return keys
+}
+
+// providers groups consecutive keys of the same type into one provider, so
+// that a configuration holding only AES-CBC keys keeps its original layout.
+func (c *Config) providers() []apiserverconfigv1.ProviderConfiguration {
+	var providers []apiserverconfigv1.ProviderConfiguration
+	for i := 0; i < len(c.Keys); {
+		k := c.Keys[i]
+		if k.Type == KMS {
+			providers = append(providers, apiserverconfigv1.ProviderConfiguration{KMS: k.KMS})
+			i++
+			continue
+		}
+		var keys []apiserverconfigv1.Key
+		for ; i < len(c.Keys) && c.Keys[i].Type == k.Type; i++ {
+			keys = append(keys, apiserverconfigv1.Key{Name: c.Keys[i].Name, Secret: c.Keys[i].Secret})
+		}
+		p := apiserverconfigv1.ProviderConfiguration{}
+		switch k.Type {
+		case AESCBC:
+			p.AESCBC = &apiserverconfigv1.AESConfiguration{Keys: keys}
+		case AESGCM:
+			p.AESGCM = &apiserverconfigv1.AESConfiguration{Keys: keys}
+		case Secretbox:
+			p.Secretbox = &apiserverconfigv1.SecretboxConfiguration{Keys: keys}
+		}
+		providers = append(providers, p)
+	}
+	return providers
+}
+
+// EncryptionConfiguration returns the configuration to pass to the apiserver.
+// Placing the identity provider first disables encryption.
+func (c *Config) EncryptionConfiguration() *apiserverconfigv1.EncryptionConfiguration {
+	identity := []apiserverconfigv1.ProviderConfiguration{{Identity: &apiserverconfigv1.IdentityConfiguration{}}}
+	var providers []apiserverconfigv1.ProviderConfiguration
+	if c.Enabled {
+		providers = append(c.providers(), identity...)
+	} else {
+		providers = append(identity, c.providers()...)
+	}
+
+	encConfig := &apiserverconfigv1.EncryptionConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "EncryptionConfiguration",
+			APIVersion: "apiserver.config.k8s.io/v1",
+		},
+		Resources: []apiserverconfigv1.ResourceConfiguration{
+			{
+				Resources: c.Resources,
+				Providers: providers,
+			},
+		},
+	}
+	// Resources that are no longer encrypted are written in plaintext but can
+	// still be read with any of the keys until they have been reencrypted.
+	if len(c.Decrypting) > 0 {
+		encConfig.Resources = append(encConfig.Resources, apiserverconfigv1.ResourceConfiguration{
+			Resources: c.Decrypting,
+			Providers: append(identity, c.providers()...),
+		})
+	}
+	return encConfig
+}
+
+// AllResources returns every resource that must be rewritten on reencrypt.
+func (c *Config) AllResources() []string {
+	return append(append([]string{}, c.Resources...), c.Decrypting...)
+}
+
+// SetResources replaces the encrypted resources. Resources that are dropped
+// move to Decrypting until the next reencrypt finishes.
+func (c *Config) SetResources(resources []string) error {
+	if err := ValidateResources(resources); err != nil {
+		return err
+	}
+	keep := map[string]bool{}
+	for _, r := range resources {
+		keep[r] = true
+	}
+	var decrypting []string
+	for _, r := range c.AllResources() {
+		if !keep[r] {
+			decrypting = append(decrypting, r)
+		}
+	}
+	c.Resources = resources
+	c.Decrypting = decrypting
+	return nil
+}
+
+// AddKey appends a key that is used for reads only until it is rotated in.
+func (c *Config) AddKey(key Key) error {
+	for _, k := range c.Keys {
+		if k.Name == key.Name {
+			return fmt.Errorf("secrets encryption key %s already exists", key.Name)
+		}
+	}
+	c.Keys = append(c.Keys, key)
+	return nil
+}
+
+// Rotate right rotates the keys, making the last added key the active one.
+func (c *Config) Rotate() {
+	if len(c.Keys) < 2 {
+		return
+	}
+	c.Keys = append(c.Keys[len(c.Keys)-1:], c.Keys[:len(c.Keys)-1]...)
+}
+
+// FinishReencrypt removes the last key, which no longer protects any data
+// once all resources have been rewritten, and stops tracking decrypted
+// resources. The removed key is returned.
+func (c *Config) FinishReencrypt() (Key, error) {
+	if len(c.Keys) < 2 {
+		return Key{}, fmt.Errorf("cannot remove the only secrets encryption key")
+	}
+	removed := c.Keys[len(c.Keys)-1]
+	c.Keys = c.Keys[:len(c.Keys)-1]
+	c.Decrypting = nil
+	return removed, nil
+}
@@ -0,0 +1,266 @@
+package providers
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	apiserverconfigv1 "k8s.io/apiserver/pkg/apis/config/v1"
+)
+
+func mustNewKey(t *testing.T, providerType string) Key {
+	t.Helper()
+	key, err := NewKey(providerType, KMSOptions{Endpoint: "unix:///run/kms.sock"})
+	if err != nil {
+		t.Fatalf("NewKey(%s) error: %v", providerType, err)
+	}
+	// Key names only have second resolution
+	key.Name += "-" + t.Name()
+	if key.KMS != nil {
+		key.KMS.Name = key.Name
+	}
+	return key
+}
+
+func roundTrip(t *testing.T, cfg *Config) *Config {
+	t.Helper()
+	b, err := json.Marshal(cfg.EncryptionConfiguration())
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	encConfig := &apiserverconfigv1.EncryptionConfiguration{}
+	if err := json.Unmarshal(b, encConfig); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	parsed, err := Parse(encConfig)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	return parsed
+}
+
+func TestNewKey(t *testing.T) {
+	for _, providerType := range []string{AESCBC, AESGCM, Secretbox} {
+		key := mustNewKey(t, providerType)
+		if key.Type != providerType || key.Secret == "" || key.KMS != nil {
+			t.Errorf("unexpected %s key: %+v", providerType, key)
+		}
+	}
+
+	key := mustNewKey(t, KMS)
+	if key.Secret != "" || key.KMS == nil || key.KMS.Endpoint != "unix:///run/kms.sock" {
+		t.Errorf("unexpected kms key: %+v", key)
+	}
+	if _, err := NewKey(KMS, KMSOptions{}); err == nil {
+		t.Errorf("expected error for kms key without endpoint")
+	}
+	if _, err := NewKey("aesctr", KMSOptions{}); err == nil {
+		t.Errorf("expected error for unsupported provider")
+	}
+}
+
+func TestValidateResources(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources []string
+		wantErr   bool
+	}{
+		{name: "secrets only", resources: []string{"secrets"}},
+		{name: "with group", resources: []string{"secrets", "configmaps", "widgets.example.com"}},
+		{name: "missing secrets", resources: []string{"configmaps"}, wantErr: true},
+		{name: "empty", wantErr: true},
+		{name: "duplicate", resources: []string{"secrets", "secrets"}, wantErr: true},
+		{name: "wildcard", resources: []string{"secrets", "*.*"}, wantErr: true},
+		{name: "upper case", resources: []string{"secrets", "ConfigMaps"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		st := tt
+		tf := func(t *testing.T) {
+			t.Parallel()
+			if err := ValidateResources(st.resources); (err != nil) != st.wantErr {
+				t.Errorf("ValidateResources(%v) error = %v, wantErr %v", st.resources, err, st.wantErr)
+			}
+		}
+		t.Run(st.name, tf)
+	}
+}
+
+func TestLegacyLayout(t *testing.T) {
+	legacy := []byte(`{"kind":"EncryptionConfiguration","apiVersion":"apiserver.config.k8s.io/v1","resources":[{"resources":["secrets"],"providers":[{"aescbc":{"keys":[{"name":"aescbckey","secret":"c2VjcmV0"}]}},{"identity":{}}]}]}`)
+	encConfig := &apiserverconfigv1.EncryptionConfiguration{}
+	if err := json.Unmarshal(legacy, encConfig); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Parse(encConfig)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if !cfg.Enabled || len(cfg.Keys) != 1 || cfg.Keys[0].Type != AESCBC || cfg.Keys[0].Name != "aescbckey" {
+		t.Fatalf("unexpected config: %+v", cfg)
+	}
+	b, err := json.Marshal(cfg.EncryptionConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The file hash is tracked in node annotations, so the layout must not change
+	if string(b) != string(legacy) {
+		t.Errorf("legacy layout changed:\n got %s\nwant %s", b, legacy)
+	}
+}
+
+func TestProviderChange(t *testing.T) {
+	oldKey := mustNewKey(t, AESCBC)
+	cfg, err := NewConfig([]string{"secrets"}, oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// prepare
+	newKey := mustNewKey(t, KMS)
+	if err := cfg.AddKey(newKey); err != nil {
+		t.Fatal(err)
+	}
+	cfg = roundTrip(t, cfg)
+	providers := cfg.EncryptionConfiguration().Resources[0].Providers
+	if len(providers) != 3 || providers[0].AESCBC == nil || providers[1].KMS == nil || providers[2].Identity == nil {
+		t.Fatalf("unexpected providers after prepare: %+v", providers)
+	}
+
+	// rotate
+	cfg.Rotate()
+	cfg = roundTrip(t, cfg)
+	providers = cfg.EncryptionConfiguration().Resources[0].Providers
+	if providers[0].KMS == nil || providers[0].KMS.Name != newKey.Name || providers[1].AESCBC == nil {
+		t.Fatalf("unexpected providers after rotate: %+v", providers)
+	}
+
+	// reencrypt
+	removed, err := cfg.FinishReencrypt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed.Name != oldKey.Name {
+		t.Errorf("removed key %s, want %s", removed.Name, oldKey.Name)
+	}
+	cfg = roundTrip(t, cfg)
+	if !reflect.DeepEqual(cfg.Keys, []Key{newKey}) {
+		t.Errorf("unexpected keys after reencrypt: %+v", cfg.Keys)
+	}
+	if _, err := cfg.FinishReencrypt(); err == nil {
+		t.Errorf("expected error removing the only key")
+	}
+}
+
+func TestGroupsKeysByType(t *testing.T) {
+	cfg, err := NewConfig([]string{"secrets"}, mustNewKey(t, Secretbox))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := mustNewKey(t, Secretbox)
+	second.Name += "-second"
+	cfg.AddKey(second)
+	cfg.AddKey(mustNewKey(t, AESGCM))
+	if err := cfg.AddKey(second); err == nil {
+		t.Errorf("expected error adding a duplicate key")
+	}
+
+	providers := cfg.EncryptionConfiguration().Resources[0].Providers
+	if len(providers) != 3 || providers[0].Secretbox == nil || len(providers[0].Secretbox.Keys) != 2 || providers[1].AESGCM == nil {
+		t.Fatalf("unexpected providers: %+v", providers)
+	}
+	if parsed := roundTrip(t, cfg); !reflect.DeepEqual(parsed.Keys, cfg.Keys) {
+		t.Errorf("keys changed on round trip:\n got %+v\nwant %+v", parsed.Keys, cfg.Keys)
+	}
+}
+
+func TestSetResources(t *testing.T) {
+	cfg, err := NewConfig([]string{"secrets", "configmaps"}, mustNewKey(t, AESCBC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Enabled = false
+	if err := cfg.SetResources([]string{"secrets", "widgets.example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg.Decrypting, []string{"configmaps"}) {
+		t.Errorf("Decrypting = %v, want [configmaps]", cfg.Decrypting)
+	}
+	if want := []string{"secrets", "widgets.example.com", "configmaps"}; !reflect.DeepEqual(cfg.AllResources(), want) {
+		t.Errorf("AllResources() = %v, want %v", cfg.AllResources(), want)
+	}
+
+	cfg = roundTrip(t, cfg)
+	encConfig := cfg.EncryptionConfiguration()
+	if len(encConfig.Resources) != 2 || encConfig.Resources[1].Providers[0].Identity == nil || encConfig.Resources[1].Providers[1].AESCBC == nil {
+		t.Fatalf("unexpected resources: %+v", encConfig.Resources)
+	}
+	if cfg.Enabled {
+		t.Errorf("expected encryption to stay disabled")
+	}
+
+	// Adding the resource back stops decrypting it
+	if err := cfg.SetResources([]string{"secrets", "configmaps"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg.Decrypting, []string{"widgets.example.com"}) {
+		t.Errorf("Decrypting = %v, want [widgets.example.com]", cfg.Decrypting)
+	}
+
+	cfg.AddKey(mustNewKey(t, AESGCM))
+	cfg.FinishReencrypt()
+	if len(cfg.Decrypting) != 0 || len(cfg.EncryptionConfiguration().Resources) != 1 {
+		t.Errorf("expected decrypted resources to be dropped after reencrypt: %+v", cfg)
+	}
+}
+
+func TestParseRejectsUnknownLayouts(t *testing.T) {
+	key := apiserverconfigv1.Key{Name: "k", Secret: "c2VjcmV0"}
+	identity := apiserverconfigv1.ProviderConfiguration{Identity: &apiserverconfigv1.IdentityConfiguration{}}
+	aescbc := apiserverconfigv1.ProviderConfiguration{AESCBC: &apiserverconfigv1.AESConfiguration{Keys: []apiserverconfigv1.Key{key}}}
+	tests := []struct {
+		name      string
+		resources []apiserverconfigv1.ResourceConfiguration
+	}{
+		{name: "no resources"},
+		{name: "no identity", resources: []apiserverconfigv1.ResourceConfiguration{
+			{Resources: []string{"secrets"}, Providers: []apiserverconfigv1.ProviderConfiguration{aescbc}},
+		}},
+		{name: "no keys", resources: []apiserverconfigv1.ResourceConfiguration{
+			{Resources: []string{"secrets"}, Providers: []apiserverconfigv1.ProviderConfiguration{identity}},
+		}},
+		{name: "encrypted second entry", resources: []apiserverconfigv1.ResourceConfiguration{
+			{Resources: []string{"secrets"}, Providers: []apiserverconfigv1.ProviderConfiguration{aescbc, identity}},
+			{Resources: []string{"configmaps"}, Providers: []apiserverconfigv1.ProviderConfiguration{aescbc, identity}},
+		}},
+	}
+	for _, tt := range tests {
+		st := tt
+		tf := func(t *testing.T) {
+			t.Parallel()
+			if _, err := Parse(&apiserverconfigv1.EncryptionConfiguration{Resources: st.resources}); err == nil {
+				t.Errorf("expected Parse to fail")
+			}
+		}
+		t.Run(st.name, tf)
+	}
+}
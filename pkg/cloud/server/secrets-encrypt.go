@@ -23,7 +23,6 @@ package server
 import (
 	"context"
 	"crypto/rand"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -31,35 +30,38 @@ import (
 	"net/http"
 	"os"
 	"strings"
-	"time"
 
 	"github.com/bhojpur/dcp/pkg/cloud/cluster"
 	"github.com/bhojpur/dcp/pkg/cloud/daemons/config"
 	secretsencrypt "github.com/bhojpur/dcp/pkg/cloud/secretencrypt"
+	"github.com/bhojpur/dcp/pkg/cloud/secretencrypt/providers"
 	"github.com/bhojpur/host/pkg/generated/controllers/core"
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	apiserverconfigv1 "k8s.io/apiserver/pkg/apis/config/v1"
 	"k8s.io/utils/pointer"
 )
 
-const aescbcKeySize = 32
-
 type EncryptionState struct {
-	Stage        string   `json:"stage"`
-	ActiveKey    string   `json:"activekey"`
-	Enable       *bool    `json:"enable,omitempty"`
-	HashMatch    bool     `json:"hashmatch,omitempty"`
-	HashError    string   `json:"hasherror,omitempty"`
-	InactiveKeys []string `json:"inactivekeys,omitempty"`
+	Stage               string   `json:"stage"`
+	ActiveKey           string   `json:"activekey"`
+	ActiveKeyType       string   `json:"activekeytype,omitempty"`
+	Enable              *bool    `json:"enable,omitempty"`
+	HashMatch           bool     `json:"hashmatch,omitempty"`
+	HashError           string   `json:"hasherror,omitempty"`
+	InactiveKeys        []string `json:"inactivekeys,omitempty"`
+	InactiveKeyTypes    []string `json:"inactivekeytypes,omitempty"`
+	Resources           []string `json:"resources,omitempty"`
+	DecryptingResources []string `json:"decryptingresources,omitempty"`
 }
 
 type EncryptionRequest struct {
-	Stage  *string `json:"stage,omitempty"`
-	Enable *bool   `json:"enable,omitempty"`
-	Force  bool    `json:"force"`
-	Skip   bool    `json:"skip"`
+	Stage     *string  `json:"stage,omitempty"`
+	Enable    *bool    `json:"enable,omitempty"`
+	Force     bool     `json:"force"`
+	Skip      bool     `json:"skip"`
+	Provider  string   `json:"provider,omitempty"`
+	Resources []string `json:"resources,omitempty"`
 }
 
 func getEncryptionRequest(req *http.Request) (EncryptionRequest, error) {
@@ -94,17 +96,15 @@ func encryptionStatusHandler(server *config.Control) http.Handler {
 
 func encryptionStatus(server *config.Control) (EncryptionState, error) {
 	state := EncryptionState{}
-	providers, err := secretsencrypt.GetEncryptionProviders(server.Runtime)
+	encConfig, err := secretsencrypt.GetEncryptionConfig(server.Runtime)
 	if os.IsNotExist(err) {
 		return state, nil
 	} else if err != nil {
 		return state, err
 	}
-	if providers[1].Identity != nil && providers[0].AESCBC != nil {
-		state.Enable = pointer.Bool(true)
-	} else if providers[0].Identity != nil && providers[1].AESCBC != nil || !server.EncryptSecrets {
-		state.Enable = pointer.Bool(false)
-	}
+	state.Enable = pointer.Bool(encConfig.Enabled)
+	state.Resources = encConfig.Resources
+	state.DecryptingResources = encConfig.Decrypting
 
 	if err := verifyEncryptionHashAnnotation(server.Runtime, server.Runtime.Core.Core(), ""); err != nil {
 		state.HashMatch = false
@@ -117,20 +117,14 @@ func encryptionStatus(server *config.Control) (EncryptionState, error) {
 		return state, err
 	}
 	state.Stage = stage
-	active := true
-	for _, p := range providers {
-		if p.AESCBC != nil {
-			for _, aesKey := range p.AESCBC.Keys {
-				if active {
-					active = false
-					state.ActiveKey = aesKey.Name
-				} else {
-					state.InactiveKeys = append(state.InactiveKeys, aesKey.Name)
-				}
-			}
-		}
-		if p.Identity != nil {
-			active = false
+	// The first key is only used for writes while encryption is enabled
+	for i, k := range encConfig.Keys {
+		if i == 0 && encConfig.Enabled {
+			state.ActiveKey = k.Name
+			state.ActiveKeyType = k.Type
+		} else {
+			state.InactiveKeys = append(state.InactiveKeys, k.Name)
+			state.InactiveKeyTypes = append(state.InactiveKeyTypes, k.Type)
 		}
 	}
 
@@ -138,35 +132,24 @@ func encryptionStatus(server *config.Control) (EncryptionState, error) {
 }
 
 func encryptionEnable(ctx context.Context, server *config.Control, enable bool) error {
-	providers, err := secretsencrypt.GetEncryptionProviders(server.Runtime)
-	if err != nil {
-		return err
-	}
-	if len(providers) > 2 {
-		return fmt.Errorf("more than 2 providers (%d) found in secrets encryption", len(providers))
-	}
-	curKeys, err := secretsencrypt.GetEncryptionKeys(server.Runtime)
+	encConfig, err := secretsencrypt.GetEncryptionConfig(server.Runtime)
 	if err != nil {
 		return err
 	}
-	if providers[1].Identity != nil && providers[0].AESCBC != nil && !enable {
+	if encConfig.Enabled && !enable {
 		logrus.Infoln("Disabling secrets encryption")
-		if err := secretsencrypt.WriteEncryptionConfig(server.Runtime, curKeys, enable); err != nil {
-			return err
-		}
 	} else if !enable {
 		logrus.Infoln("Secrets encryption already disabled")
 		return nil
-	} else if providers[0].Identity != nil && providers[1].AESCBC != nil && enable {
+	} else if !encConfig.Enabled {
 		logrus.Infoln("Enabling secrets encryption")
-		if err := secretsencrypt.WriteEncryptionConfig(server.Runtime, curKeys, enable); err != nil {
-			return err
-		}
-	} else if enable {
+	} else {
 		logrus.Infoln("Secrets encryption already enabled")
 		return nil
-	} else {
-		return fmt.Errorf("unable to enable/disable secrets encryption, unknown configuration")
+	}
+	encConfig.Enabled = enable
+	if err := secretsencrypt.WriteEncryptionConfig(server.Runtime, encConfig); err != nil {
+		return err
 	}
 	return cluster.Save(ctx, server, true)
 }
@@ -190,7 +173,7 @@ func encryptionConfigHandler(ctx context.Context, server *config.Control) http.H
 		if encryptReq.Stage != nil {
 			switch *encryptReq.Stage {
 			case secretsencrypt.EncryptionPrepare:
-				err = encryptionPrepare(ctx, server, encryptReq.Force)
+				err = encryptionPrepare(ctx, server, encryptReq)
 			case secretsencrypt.EncryptionRotate:
 				err = encryptionRotate(ctx, server, encryptReq.Force)
 			case secretsencrypt.EncryptionReencryptActive:
@@ -210,23 +193,53 @@ func encryptionConfigHandler(ctx context.Context, server *config.Control) http.H
 	})
 }
 
-func encryptionPrepare(ctx context.Context, server *config.Control, force bool) error {
+// encryptionPrepare adds a new key, which may use a different provider than the
+// current keys, and optionally changes the encrypted resources. The new key
+// only becomes active on rotate, and the resource change takes full effect once
+// reencrypt has rewritten the stored objects.
+func encryptionPrepare(ctx context.Context, server *config.Control, req EncryptionRequest) error {
 	states := secretsencrypt.EncryptionStart + "-" + secretsencrypt.EncryptionReencryptFinished
-	if err := verifyEncryptionHashAnnotation(server.Runtime, server.Runtime.Core.Core(), states); err != nil && !force {
+	if err := verifyEncryptionHashAnnotation(server.Runtime, server.Runtime.Core.Core(), states); err != nil && !req.Force {
 		return err
 	}
 
-	curKeys, err := secretsencrypt.GetEncryptionKeys(server.Runtime)
+	encConfig, err := secretsencrypt.GetEncryptionConfig(server.Runtime)
 	if err != nil {
 		return err
 	}
 
-	if err := AppendNewEncryptionKey(&curKeys); err != nil {
+	providerType := req.Provider
+	if providerType == "" {
+		providerType = server.EncryptProvider
+	}
+	if providerType == "" {
+		providerType = providers.AESCBC
+	}
+	key, err := providers.NewKey(providerType, providers.KMSOptions{
+		Endpoint: server.EncryptKMSEndpoint,
+		Timeout:  server.EncryptKMSTimeout,
+	})
+	if err != nil {
+		return err
+	}
+	if key.KMS != nil {
+		if err := providers.CheckKMSPlugin(ctx, key.KMS); err != nil {
+			return err
+		}
+	}
+	if len(req.Resources) > 0 {
+		if err := encConfig.SetResources(req.Resources); err != nil {
+			return err
+		}
+		logrus.Infof("Encrypting %v, decrypting %v after reencrypt", encConfig.Resources, encConfig.Decrypting)
+	}
+	if err := encConfig.AddKey(key); err != nil {
 		return err
 	}
-	logrus.Infoln("Adding secrets-encryption key: ", curKeys[len(curKeys)-1])
+	logrus.Infof("Adding secrets-encryption %s key: %s", key.Type, key.Name)
 
-	if err := secretsencrypt.WriteEncryptionConfig(server.Runtime, curKeys, true); err != nil {
+	encConfig.Enabled = true
+	if err := secretsencrypt.WriteEncryptionConfig(server.Runtime, encConfig); err != nil {
 		return err
 	}
 	nodeName := os.Getenv("NODE_NAME")
@@ -246,15 +259,14 @@ func encryptionRotate(ctx context.Context, server *config.Control, force bool) e
 		return err
 	}
 
-	curKeys, err := secretsencrypt.GetEncryptionKeys(server.Runtime)
+	encConfig, err := secretsencrypt.GetEncryptionConfig(server.Runtime)
 	if err != nil {
 		return err
 	}
 
-	// Right rotate elements
-	rotatedKeys := append(curKeys[len(curKeys)-1:], curKeys[:len(curKeys)-1]...)
-
-	if err = secretsencrypt.WriteEncryptionConfig(server.Runtime, rotatedKeys, true); err != nil {
+	encConfig.Rotate()
+	encConfig.Enabled = true
+	if err = secretsencrypt.WriteEncryptionConfig(server.Runtime, encConfig); err != nil {
 		return err
 	}
 	logrus.Infoln("Encryption keys right rotated")
@@ -295,25 +307,6 @@ func encryptionReencrypt(ctx context.Context, server *config.Control, force bool
 	return nil
 }
 
-func AppendNewEncryptionKey(keys *[]apiserverconfigv1.Key) error {
-
-	aescbcKey := make([]byte, aescbcKeySize)
-	_, err := rand.Read(aescbcKey)
-	if err != nil {
-		return err
-	}
-	encodedKey := base64.StdEncoding.EncodeToString(aescbcKey)
-
-	newKey := []apiserverconfigv1.Key{
-		{
-			Name:   "aescbckey-" + time.Now().Format(time.RFC3339),
-			Secret: encodedKey,
-		},
-	}
-	*keys = append(*keys, newKey...)
-	return nil
-}
-
 func getEncryptionHashAnnotation(core core.Interface) (string, string, error) {
 	nodeName := os.Getenv("NODE_NAME")
 	node, err := core.V1().Node().Get(nodeName, metav1.GetOptions{})
@@ -36,4 +36,33 @@ type ControllerManagerConfiguration struct {
 	// NodeLifecycleControllerConfiguration holds configuration for
 	// NodeLifecycleController related features.
 	NodeLifecycleController nodelifecycleconfig.NodeLifecycleControllerConfiguration
+
+	// NodePoolPartitionConfiguration holds configuration for the detection
+	// of the network partition of NodePools in NodeLifecycleController.
+	NodePoolPartition NodePoolPartitionConfiguration
+
+	// CSRApproverConfiguration holds configuration for the approver of
+	// the Bhojpur DCP related CSRs.
+	CSRApprover CSRApproverConfiguration
+}
+
+// CSRApproverConfiguration contains elements describing the CSR approver.
+type CSRApproverConfiguration struct {
+	// ApprovalPolicyFile is the path to the approval policy of the CSRs,
+	// all the Bhojpur DCP related CSRs are approved if empty.
+	ApprovalPolicyFile string
+}
+
+// NodePoolPartitionConfiguration contains elements describing how NodeLifecycleController
+// detects that a NodePool is partitioned from the cloud.
+type NodePoolPartitionConfiguration struct {
+	// PoolPartitionThreshold is the fraction of the nodes of a NodePool which need to become
+	// unreachable together for the pool to be treated as partitioned.
+	PoolPartitionThreshold float32
+	// MinPoolPartitionSize is the min number of nodes of a NodePool for the partition detection,
+	// the nodes of smaller pools are evicted one by one.
+	MinPoolPartitionSize int32
+	// PoolPartitionWindow is the max interval between the nodes of a NodePool becoming unreachable
+	// for them to be treated as unreachable together.
+	PoolPartitionWindow metav1.Duration
 }
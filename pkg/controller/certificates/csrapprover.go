@@ -37,7 +37,10 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 
@@ -54,12 +57,19 @@ var (
 	dcpCsr = fmt.Sprintf("%s-csr", strings.TrimRightFunc(projectinfo.GetProjectPrefix(), func(c rune) bool { return c == '-' }))
 )
 
-// DcpCSRApprover is the controller that auto approve all Bhojpur DCP related CSR
+// DcpCSRApprover is the controller that auto approve all Bhojpur DCP related CSR,
+// or approve and deny them by the approval policy if it is set
 type DcpCSRApprover struct {
 	client    kubernetes.Interface
 	workqueue workqueue.RateLimitingInterface
 	getCsr    func(string) (*certificatesv1.CertificateSigningRequest, error)
 	hasSynced func() bool
+
+	policy   *CSRApprovalPolicy
+	getNode  func(string) (*corev1.Node, error)
+	limiter  *requesterRateLimiter
+	recorder record.EventRecorder
+	now      func() time.Time
 }
 
 // Run starts the DcpCSRApprover
@@ -106,7 +116,7 @@ func (yca *DcpCSRApprover) processNextItem() bool {
 		return true
 	}
 
-	if err := approveCSR(yca.client, csr); err != nil {
+	if err := yca.approveCSR(csr); err != nil {
 		runtime.HandleError(err)
 		enqueueObj(yca.workqueue, csr)
 		return true
@@ -148,8 +158,8 @@ func enqueueObj(wq workqueue.RateLimitingInterface, obj interface{}) {
 	klog.V(4).Infof("approved or denied csr, ignore it: %s", key)
 }
 
-// NewCSRApprover creates a new DcpCSRApprover
-func NewCSRApprover(client kubernetes.Interface, sharedInformers informers.SharedInformerFactory) (*DcpCSRApprover, error) {
+// NewCSRApprover creates a new DcpCSRApprover, all Bhojpur DCP related CSRs are approved if policy is nil
+func NewCSRApprover(client kubernetes.Interface, sharedInformers informers.SharedInformerFactory, policy *CSRApprovalPolicy) (*DcpCSRApprover, error) {
 	var hasSynced func() bool
 	var getCsr func(string) (*certificatesv1.CertificateSigningRequest, error)
 
@@ -188,17 +198,34 @@ func NewCSRApprover(client kubernetes.Interface, sharedInformers informers.Share
 		}
 	}
 
-	return &DcpCSRApprover{
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&v1core.EventSinkImpl{Interface: client.CoreV1().Events("")})
+
+	yca := &DcpCSRApprover{
 		client:    client,
 		workqueue: wq,
 		getCsr:    getCsr,
 		hasSynced: hasSynced,
-	}, nil
+		policy:    policy,
+		limiter:   newRequesterRateLimiter(),
+		recorder:  eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "csr-approver"}),
+		now:       time.Now,
+	}
+	if policy != nil {
+		// the nodes are only required by the approval policy
+		nodeInformer := sharedInformers.Core().V1().Nodes()
+		yca.getNode = nodeInformer.Lister().Get
+		nodeSynced := nodeInformer.Informer().HasSynced
+		yca.hasSynced = func() bool {
+			return hasSynced() && nodeSynced()
+		}
+	}
+	return yca, nil
 }
 
 // approveCSR checks the csr status, if it is neither approved nor
-// denied, it will try to approve the csr.
-func approveCSR(client kubernetes.Interface, csr *certificatesv1.CertificateSigningRequest) error {
+// denied, it will approve or deny the csr by the approval policy.
+func (yca *DcpCSRApprover) approveCSR(csr *certificatesv1.CertificateSigningRequest) error {
 	if !isDcpCSR(csr) {
 		klog.Infof("csr(%s) is not %s", csr.GetName(), dcpCsr)
 		return nil
@@ -215,33 +242,69 @@ func approveCSR(client kubernetes.Interface, csr *certificatesv1.CertificateSign
 		return nil
 	}
 
+	d := yca.decide(csr)
+	switch d.action {
+	case actionRetry:
+		klog.Infof("defer the approval of %s(%s), %s", dcpCsr, csr.GetName(), d.message)
+		yca.recorder.Event(csr, corev1.EventTypeNormal, d.reason, d.message)
+		yca.workqueue.AddAfter(csr.GetName(), d.retryAfter)
+		return nil
+	case actionDeny:
+		csr.Status.Conditions = append(csr.Status.Conditions,
+			certificatesv1.CertificateSigningRequestCondition{
+				Type:    certificatesv1.CertificateDenied,
+				Status:  corev1.ConditionTrue,
+				Reason:  d.reason,
+				Message: d.message,
+			})
+		if err := updateApproval(context.Background(), yca.client, csr); err != nil {
+			klog.Errorf("failed to deny %s(%s), %v", dcpCsr, csr.GetName(), err)
+			return err
+		}
+		klog.Infof("successfully deny %s(%s), %s", dcpCsr, csr.GetName(), d.message)
+		yca.recorder.Event(csr, corev1.EventTypeWarning, d.reason, d.message)
+		return nil
+	}
+
 	// approve the Bhojpur DCP related csr
 	csr.Status.Conditions = append(csr.Status.Conditions,
 		certificatesv1.CertificateSigningRequestCondition{
 			Type:    certificatesv1.CertificateApproved,
 			Status:  corev1.ConditionTrue,
-			Reason:  "AutoApproved",
-			Message: fmt.Sprintf("self-approving %s", dcpCsr),
+			Reason:  d.reason,
+			Message: d.message,
 		})
 
-	err := updateApproval(context.Background(), client, csr)
+	err := updateApproval(context.Background(), yca.client, csr)
 	if err != nil {
 		klog.Errorf("failed to approve %s(%s), %v", dcpCsr, csr.GetName(), err)
+		if d.release != nil {
+			d.release()
+		}
 		return err
 	}
 	klog.Infof("successfully approve %s(%s)", dcpCsr, csr.GetName())
+	yca.recorder.Event(csr, corev1.EventTypeNormal, d.reason, d.message)
 	return nil
 }
 
+// decide approves all the Bhojpur DCP related csr if there is no approval policy,
+// otherwise evaluates the csr against the policy.
+func (yca *DcpCSRApprover) decide(csr *certificatesv1.CertificateSigningRequest) approvalDecision {
+	if yca.policy == nil {
+		return approve("AutoApproved", fmt.Sprintf("self-approving %s", dcpCsr))
+	}
+	x509cr, err := parseCSR(csr)
+	if err != nil {
+		return deny("InvalidRequest", "failed to parse the certificate request, %v", err)
+	}
+	return yca.policy.evaluate(csr, x509cr, yca.getNode, yca.limiter, yca.now())
+}
+
 // isDcpCSR checks if given csr is a Bhojpur DCP related csr, i.e.,
 // the organizations' list contains "bhojpur:dcpsvr"
 func isDcpCSR(csr *certificatesv1.CertificateSigningRequest) bool {
-	pemBytes := csr.Spec.Request
-	block, _ := pem.Decode(pemBytes)
-	if block == nil || block.Type != "CERTIFICATE REQUEST" {
-		return false
-	}
-	x509cr, err := x509.ParseCertificateRequest(block.Bytes)
+	x509cr, err := parseCSR(csr)
 	if err != nil {
 		return false
 	}
@@ -253,6 +316,15 @@ func isDcpCSR(csr *certificatesv1.CertificateSigningRequest) bool {
 	return false
 }
 
+// parseCSR decodes the PEM encoded certificate request of the csr
+func parseCSR(csr *certificatesv1.CertificateSigningRequest) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(csr.Spec.Request)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, fmt.Errorf("pem block type must be CERTIFICATE REQUEST")
+	}
+	return x509.ParseCertificateRequest(block.Bytes)
+}
+
 // checkCertApprovalCondition checks if the given csr's status is
 // approved or denied
 func checkCertApprovalCondition(status *certificatesv1.CertificateSigningRequestStatus) (approved bool, denied bool) {
@@ -0,0 +1,391 @@
+package certificates
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// NodeNamePlaceholder in the common names of a rule is replaced with the name of the requesting node
+	NodeNamePlaceholder = "$(NODE_NAME)"
+
+	nodeUserPrefix = "system:node:"
+)
+
+// CSRApprovalPolicy is the policy of the approval of the Bhojpur DCP related CSRs.
+// A CSR is checked against the first rule listing all of its organizations, and
+// is denied if no rule matches or a matching rule does not list all of them.
+type CSRApprovalPolicy struct {
+	Rules []CSRApprovalRule `json:"rules"`
+}
+
+// CSRApprovalRule is the requirements of the CSRs with the organizations.
+type CSRApprovalRule struct {
+	// Name of the rule, used in the events and the messages of the conditions.
+	Name string `json:"name"`
+	// Organizations matched by the rule, a CSR matches if any of its organizations is in the list,
+	// and is only allowed if all of its organizations are.
+	Organizations []string `json:"organizations"`
+	// CommonNames allowed, $(NODE_NAME) is replaced with the name of the requesting node.
+	// Any common name is allowed if empty.
+	CommonNames []string `json:"commonNames,omitempty"`
+	// RequireNode requires the requester to be an existing node, i.e. the user is system:node:<name>.
+	RequireNode bool `json:"requireNode,omitempty"`
+	// AllowNodeAddresses allows the IP and DNS SANs which are the name or the addresses of the requesting node.
+	AllowNodeAddresses bool `json:"allowNodeAddresses,omitempty"`
+	// AllowedCIDRs are the ranges of the IP SANs allowed.
+	AllowedCIDRs []string `json:"allowedCIDRs,omitempty"`
+	// AllowedDNSNames are the DNS SANs allowed, a name prefixed with "*." allows the subdomains.
+	AllowedDNSNames []string `json:"allowedDNSNames,omitempty"`
+	// RateLimit limits the approved CSRs per requester, no limit if nil.
+	RateLimit *CSRRateLimit `json:"rateLimit,omitempty"`
+
+	cidrs []*net.IPNet
+}
+
+// CSRRateLimit is the max number of the CSRs approved for a requester in a period.
+type CSRRateLimit struct {
+	Requests int             `json:"requests"`
+	Period   metav1.Duration `json:"period"`
+}
+
+// LoadCSRApprovalPolicy reads and validates the policy from the file
+func LoadCSRApprovalPolicy(path string) (*CSRApprovalPolicy, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the csr approval policy %s, %v", path, err)
+	}
+	policy := &CSRApprovalPolicy{}
+	if err := yaml.UnmarshalStrict(content, policy); err != nil {
+		return nil, fmt.Errorf("failed to parse the csr approval policy %s, %v", path, err)
+	}
+	if err := policy.complete(); err != nil {
+		return nil, fmt.Errorf("invalid csr approval policy %s, %v", path, err)
+	}
+	return policy, nil
+}
+
+// complete validates the rules and parses the CIDRs
+func (p *CSRApprovalPolicy) complete() error {
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i)
+		}
+		if len(rule.Organizations) == 0 {
+			return fmt.Errorf("rule %s has no organizations", rule.Name)
+		}
+		rule.cidrs = rule.cidrs[:0]
+		for _, cidr := range rule.AllowedCIDRs {
+			_, ipNet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				return fmt.Errorf("rule %s has invalid cidr %s, %v", rule.Name, cidr, err)
+			}
+			rule.cidrs = append(rule.cidrs, ipNet)
+		}
+		if rule.RateLimit != nil && (rule.RateLimit.Requests <= 0 || rule.RateLimit.Period.Duration <= 0) {
+			return fmt.Errorf("rule %s has invalid rate limit, requests and period should be positive", rule.Name)
+		}
+	}
+	return nil
+}
+
+// matchRule returns the first rule listing all the organizations of the csr. If there is
+// none, it returns the first rule listing some of them, which does not allow the csr.
+func (p *CSRApprovalPolicy) matchRule(x509cr *x509.CertificateRequest) (rule *CSRApprovalRule, allowed bool) {
+	for i := range p.Rules {
+		matched, all := false, true
+		for _, csrOrg := range x509cr.Subject.Organization {
+			if p.Rules[i].allowsOrganization(csrOrg) {
+				matched = true
+			} else {
+				all = false
+			}
+		}
+		if matched && all {
+			return &p.Rules[i], true
+		}
+		if matched && rule == nil {
+			rule = &p.Rules[i]
+		}
+	}
+	return rule, false
+}
+
+func (r *CSRApprovalRule) allowsOrganization(org string) bool {
+	for _, allowed := range r.Organizations {
+		if allowed == org {
+			return true
+		}
+	}
+	return false
+}
+
+// restrictsSANs returns true if the rule limits the SANs of the CSRs
+func (r *CSRApprovalRule) restrictsSANs() bool {
+	return r.AllowNodeAddresses || len(r.cidrs) != 0 || len(r.AllowedDNSNames) != 0
+}
+
+type decisionAction int
+
+const (
+	actionApprove decisionAction = iota
+	actionDeny
+	actionRetry
+)
+
+// approvalDecision is the result of the evaluation of a CSR
+type approvalDecision struct {
+	action     decisionAction
+	reason     string
+	message    string
+	retryAfter time.Duration
+	// release gives back the rate limit slot reserved for an approval which is not saved.
+	release func()
+}
+
+func approve(reason, message string) approvalDecision {
+	return approvalDecision{action: actionApprove, reason: reason, message: message}
+}
+
+func deny(reason, format string, args ...interface{}) approvalDecision {
+	return approvalDecision{action: actionDeny, reason: reason, message: fmt.Sprintf(format, args...)}
+}
+
+// evaluate checks the csr against the policy. getNode returns the node of the name.
+func (p *CSRApprovalPolicy) evaluate(csr *certificatesv1.CertificateSigningRequest, x509cr *x509.CertificateRequest,
+	getNode func(string) (*corev1.Node, error), limiter *requesterRateLimiter, now time.Time) approvalDecision {
+	rule, allowed := p.matchRule(x509cr)
+	if rule == nil {
+		return deny("NoMatchingRule", "no rule of the approval policy matches organizations %v", x509cr.Subject.Organization)
+	}
+	if !allowed {
+		var notAllowed []string
+		for _, org := range x509cr.Subject.Organization {
+			if !rule.allowsOrganization(org) {
+				notAllowed = append(notAllowed, org)
+			}
+		}
+		return deny("OrganizationNotAllowed", "organizations %v are not allowed by rule %s", notAllowed, rule.Name)
+	}
+
+	// 1. identify the requesting node
+	var node *corev1.Node
+	nodeName := strings.TrimPrefix(csr.Spec.Username, nodeUserPrefix)
+	if nodeName == csr.Spec.Username {
+		nodeName = ""
+	}
+	if nodeName != "" {
+		n, err := getNode(nodeName)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return approvalDecision{action: actionRetry, reason: "NodeUnknown",
+				message: fmt.Sprintf("failed to get node %s, %v", nodeName, err), retryAfter: time.Second}
+		}
+		node = n
+	}
+	if rule.RequireNode {
+		if nodeName == "" {
+			return deny("NotNode", "rule %s requires the requester to be a node, but it is %s", rule.Name, csr.Spec.Username)
+		}
+		if node == nil {
+			return deny("NodeNotFound", "rule %s requires the requester to be an existing node, but node %s is not found", rule.Name, nodeName)
+		}
+	}
+
+	// 2. check the common name
+	if len(rule.CommonNames) != 0 {
+		allowed := false
+		for _, cn := range rule.CommonNames {
+			if strings.Contains(cn, NodeNamePlaceholder) {
+				if nodeName == "" {
+					continue
+				}
+				cn = strings.Replace(cn, NodeNamePlaceholder, nodeName, -1)
+			}
+			if cn == x509cr.Subject.CommonName {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return deny("CommonNameNotAllowed", "common name %s is not allowed by rule %s", x509cr.Subject.CommonName, rule.Name)
+		}
+	}
+
+	// 3. check the SANs
+	if rule.restrictsSANs() {
+		for _, ip := range x509cr.IPAddresses {
+			if !rule.allowsIP(ip, node) {
+				return deny("IPAddressNotAllowed", "ip address %s is not allowed by rule %s", ip, rule.Name)
+			}
+		}
+		for _, name := range x509cr.DNSNames {
+			if !rule.allowsDNSName(name, node) {
+				return deny("DNSNameNotAllowed", "dns name %s is not allowed by rule %s", name, rule.Name)
+			}
+		}
+	}
+
+	// 4. check the rate limit of the requester
+	if rule.RateLimit != nil && limiter != nil {
+		if retryAfter := limiter.reserve(rule, csr.Spec.Username, now); retryAfter > 0 {
+			return approvalDecision{action: actionRetry, reason: "RateLimited",
+				message: fmt.Sprintf("requester %s exceeds %d approvals per %s of rule %s",
+					csr.Spec.Username, rule.RateLimit.Requests, rule.RateLimit.Period.Duration, rule.Name),
+				retryAfter: retryAfter}
+		}
+		d := approve("PolicyApproved", fmt.Sprintf("approved by rule %s of the approval policy", rule.Name))
+		d.release = func() { limiter.release(rule, csr.Spec.Username, now) }
+		return d
+	}
+
+	return approve("PolicyApproved", fmt.Sprintf("approved by rule %s of the approval policy", rule.Name))
+}
+
+func (r *CSRApprovalRule) allowsIP(ip net.IP, node *corev1.Node) bool {
+	for _, cidr := range r.cidrs {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	if r.AllowNodeAddresses && node != nil {
+		for _, addr := range node.Status.Addresses {
+			if addrIP := net.ParseIP(addr.Address); addrIP != nil && addrIP.Equal(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *CSRApprovalRule) allowsDNSName(name string, node *corev1.Node) bool {
+	for _, allowed := range r.AllowedDNSNames {
+		if allowed == name {
+			return true
+		}
+		if strings.HasPrefix(allowed, "*.") && strings.HasSuffix(name, allowed[1:]) && len(name) > len(allowed)-1 {
+			return true
+		}
+	}
+	if r.AllowNodeAddresses && node != nil {
+		if name == node.Name {
+			return true
+		}
+		for _, addr := range node.Status.Addresses {
+			if (addr.Type == corev1.NodeHostName || addr.Type == corev1.NodeInternalDNS || addr.Type == corev1.NodeExternalDNS) &&
+				addr.Address == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pruneInterval is the min interval between the removals of the idle requesters
+const pruneInterval = time.Minute
+
+// requesterRateLimiter records the approvals of the requesters in the period of the rate limits
+type requesterRateLimiter struct {
+	sync.Mutex
+	approvals map[string]*requesterApprovals
+	lastPrune time.Time
+}
+
+// requesterApprovals is the approvals of a requester by a rule
+type requesterApprovals struct {
+	period time.Duration
+	times  []time.Time
+}
+
+func newRequesterRateLimiter() *requesterRateLimiter {
+	return &requesterRateLimiter{approvals: make(map[string]*requesterApprovals)}
+}
+
+// reserve records an approval for the requester if the rate limit of the rule allows,
+// otherwise returns the time to wait for the next approval.
+func (l *requesterRateLimiter) reserve(rule *CSRApprovalRule, requester string, now time.Time) time.Duration {
+	l.Lock()
+	defer l.Unlock()
+	l.prune(now)
+	key := rule.Name + "/" + requester
+	period := rule.RateLimit.Period.Duration
+	var recent []time.Time
+	if a := l.approvals[key]; a != nil {
+		for _, t := range a.times {
+			if now.Sub(t) < period {
+				recent = append(recent, t)
+			}
+		}
+	}
+	if len(recent) >= rule.RateLimit.Requests {
+		l.approvals[key] = &requesterApprovals{period: period, times: recent}
+		return recent[0].Add(period).Sub(now)
+	}
+	l.approvals[key] = &requesterApprovals{period: period, times: append(recent, now)}
+	return 0
+}
+
+// release removes the approval reserved at the time for the requester.
+func (l *requesterRateLimiter) release(rule *CSRApprovalRule, requester string, reservedAt time.Time) {
+	l.Lock()
+	defer l.Unlock()
+	key := rule.Name + "/" + requester
+	a := l.approvals[key]
+	if a == nil {
+		return
+	}
+	for i := len(a.times) - 1; i >= 0; i-- {
+		if a.times[i].Equal(reservedAt) {
+			a.times = append(a.times[:i], a.times[i+1:]...)
+			break
+		}
+	}
+	if len(a.times) == 0 {
+		delete(l.approvals, key)
+	}
+}
+
+// prune removes the requesters without approval in the period of their rule,
+// so that the requesters which are gone are not kept forever.
+func (l *requesterRateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < pruneInterval {
+		return
+	}
+	l.lastPrune = now
+	for key, a := range l.approvals {
+		if len(a.times) == 0 || now.Sub(a.times[len(a.times)-1]) >= a.period {
+			delete(l.approvals, key)
+		}
+	}
+}
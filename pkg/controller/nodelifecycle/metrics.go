@@ -34,6 +34,11 @@ const (
 	zoneNoUnhealthyNodesKey = "unhealthy_nodes_in_zone"
 	evictionsNumberKey      = "evictions_number"
 	zone                    = "zone"
+
+	poolSizeKey             = "nodepool_size"
+	poolUnreachableNodesKey = "unreachable_nodes_in_nodepool"
+	poolPartitionedKey      = "nodepool_partitioned"
+	pool                    = "nodepool"
 )
 
 var (
@@ -73,6 +78,33 @@ var (
 		},
 		[]string{zone},
 	)
+	poolSize = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      nodeControllerSubsystem,
+			Name:           poolSizeKey,
+			Help:           "Gauge measuring number of registered Nodes per NodePool.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{pool},
+	)
+	unreachableNodesInPool = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      nodeControllerSubsystem,
+			Name:           poolUnreachableNodesKey,
+			Help:           "Gauge measuring number of unreachable Nodes per NodePool.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{pool},
+	)
+	poolPartitioned = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      nodeControllerSubsystem,
+			Name:           poolPartitionedKey,
+			Help:           "Gauge measuring whether the NodePool is treated as partitioned, 1 if partitioned.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{pool},
+	)
 )
 
 var registerMetrics sync.Once
@@ -84,5 +116,8 @@ func Register() {
 		legacyregistry.MustRegister(zoneSize)
 		legacyregistry.MustRegister(unhealthyNodes)
 		legacyregistry.MustRegister(evictionsNumber)
+		legacyregistry.MustRegister(poolSize)
+		legacyregistry.MustRegister(unreachableNodesInPool)
+		legacyregistry.MustRegister(poolPartitioned)
 	})
 }
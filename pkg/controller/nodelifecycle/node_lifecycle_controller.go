@@ -56,6 +56,7 @@ import (
 	utilnode "k8s.io/component-helpers/node/topology"
 	"k8s.io/klog/v2"
 
+	appsclientset "github.com/bhojpur/dcp/pkg/appmanager/client/clientset/versioned"
 	"github.com/bhojpur/dcp/pkg/controller/kubernetes/controller"
 	taintutils "github.com/bhojpur/dcp/pkg/controller/kubernetes/util/taints"
 	"github.com/bhojpur/dcp/pkg/controller/nodelifecycle/scheduler"
@@ -306,6 +307,15 @@ type Controller struct {
 
 	zoneStates map[string]ZoneState
 
+	// poolClient is used to report the PoolPartitioned condition of the NodePools, the condition
+	// is not reported if nil.
+	poolClient appsclientset.Interface
+	// poolPartitionStates is whether the NodePools are partitioned, the pods on the nodes of
+	// partitioned pools are not evicted.
+	poolPartitionStates map[string]bool
+	// reportedPoolPartitions is the PoolPartitioned condition reported for the NodePools.
+	reportedPoolPartitions map[string]bool
+
 	daemonSetStore          appsv1listers.DaemonSetLister
 	daemonSetInformerSynced cache.InformerSynced
 
@@ -355,6 +365,9 @@ type Controller struct {
 	secondaryEvictionLimiterQPS float32
 	largeClusterThreshold       int32
 	unhealthyZoneThreshold      float32
+	poolPartitionThreshold      float32
+	minPoolPartitionSize        int32
+	poolPartitionWindow         time.Duration
 
 	// if set to true Controller will start TaintManager that will evict Pods from
 	// tainted nodes, if they're not tolerated.
@@ -380,6 +393,10 @@ func NewNodeLifecycleController(
 	largeClusterThreshold int32,
 	unhealthyZoneThreshold float32,
 	runTaintManager bool,
+	poolClient appsclientset.Interface,
+	poolPartitionThreshold float32,
+	minPoolPartitionSize int32,
+	poolPartitionWindow time.Duration,
 ) (*Controller, error) {
 
 	if kubeClient == nil {
@@ -414,11 +431,17 @@ func NewNodeLifecycleController(
 		zoneNoExecuteTainter:        make(map[string]*scheduler.RateLimitedTimedQueue),
 		nodesToRetry:                sync.Map{},
 		zoneStates:                  make(map[string]ZoneState),
+		poolClient:                  poolClient,
+		poolPartitionStates:         make(map[string]bool),
+		reportedPoolPartitions:      make(map[string]bool),
 		podEvictionTimeout:          podEvictionTimeout,
 		evictionLimiterQPS:          evictionLimiterQPS,
 		secondaryEvictionLimiterQPS: secondaryEvictionLimiterQPS,
 		largeClusterThreshold:       largeClusterThreshold,
 		unhealthyZoneThreshold:      unhealthyZoneThreshold,
+		poolPartitionThreshold:      poolPartitionThreshold,
+		minPoolPartitionSize:        minPoolPartitionSize,
+		poolPartitionWindow:         poolPartitionWindow,
 		runTaintManager:             runTaintManager,
 		nodeUpdateQueue:             workqueue.NewNamed("node_lifecycle_controller"),
 		podUpdateQueue:              workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "node_lifecycle_controller_pods"),
@@ -801,6 +824,7 @@ func (nc *Controller) monitorNodeHealth() error {
 	}
 
 	zoneToNodeConditions := map[string][]*v1.NodeCondition{}
+	healthResults := make([]nodeHealthResult, 0, len(nodes))
 	for i := range nodes {
 		var gracePeriod time.Duration
 		var observedReadyCondition v1.NodeCondition
@@ -828,7 +852,21 @@ func (nc *Controller) monitorNodeHealth() error {
 		if !isNodeExcludedFromDisruptionChecks(node) {
 			zoneToNodeConditions[utilnode.GetZoneKey(node)] = append(zoneToNodeConditions[utilnode.GetZoneKey(node)], currentReadyCondition)
 		}
+		healthResults = append(healthResults, nodeHealthResult{
+			node:                   node,
+			gracePeriod:            gracePeriod,
+			observedReadyCondition: observedReadyCondition,
+			currentReadyCondition:  currentReadyCondition,
+		})
+	}
 
+	// The partition of the NodePools is detected before the evictions, so that no node
+	// of a pool which loses its network together is tainted or evicted.
+	nc.handlePoolPartition(healthResults)
+
+	for _, r := range healthResults {
+		node := r.node
+		gracePeriod, observedReadyCondition, currentReadyCondition := r.gracePeriod, r.observedReadyCondition, r.currentReadyCondition
 		if currentReadyCondition != nil {
 			pods, err := nc.getPodsAssignedToNode(node.Name)
 			if err != nil {
@@ -841,7 +879,10 @@ func (nc *Controller) monitorNodeHealth() error {
 				}
 				continue
 			}
-			if nc.runTaintManager {
+			partitioned := nc.isNodeInPartitionedPool(node)
+			if partitioned {
+				klog.V(4).Infof("node %s is in partitioned nodepool %s, so skip pods eviction", node.Name, getNodePoolName(node))
+			} else if nc.runTaintManager {
 				nc.processTaintBaseEviction(node, &observedReadyCondition)
 			} else {
 				if err := nc.processNoTaintBaseEviction(node, &observedReadyCondition, gracePeriod, pods); err != nil {
@@ -856,6 +897,10 @@ func (nc *Controller) monitorNodeHealth() error {
 				nodeutil.RecordNodeStatusChange(nc.recorder, node, "NodeNotReady")
 				fallthrough
 			case needsRetry && observedReadyCondition.Status != v1.ConditionTrue:
+				// pods on the nodes of a partitioned pool keep serving inside the pool, same as on autonomous nodes
+				if partitioned {
+					break
+				}
 				if err = nodeutil.MarkPodsNotReady(nc.kubeClient, pods, node.Name, node); err != nil {
 					utilruntime.HandleError(fmt.Errorf("unable to mark all pods NotReady on node %v: %v; queuing for retry", node.Name, err))
 					nc.nodesToRetry.Store(node.Name, struct{}{})
@@ -0,0 +1,215 @@
+package nodelifecycle
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	appsv1alpha1 "github.com/bhojpur/dcp/pkg/appmanager/apis/apps/v1alpha1"
+)
+
+// nodeHealthResult is the health of a node observed in a pass of monitorNodeHealth.
+type nodeHealthResult struct {
+	node                   *v1.Node
+	gracePeriod            time.Duration
+	observedReadyCondition v1.NodeCondition
+	currentReadyCondition  *v1.NodeCondition
+}
+
+// poolPartitionState is the partition state of a NodePool computed in a pass of monitorNodeHealth.
+type poolPartitionState struct {
+	size        int
+	unreachable int
+	partitioned bool
+}
+
+func getNodePoolName(node *v1.Node) string {
+	if node == nil || node.Labels == nil {
+		return ""
+	}
+	return node.Labels[appsv1alpha1.LabelCurrentNodePool]
+}
+
+// isNodeInPartitionedPool returns true if the node belongs to a NodePool treated as partitioned.
+func (nc *Controller) isNodeInPartitionedPool(node *v1.Node) bool {
+	pool := getNodePoolName(node)
+	return pool != "" && nc.poolPartitionStates[pool]
+}
+
+// computePoolPartitionState returns the partition state of a NodePool from the Ready conditions of its nodes.
+// A pool is partitioned if at least nc.poolPartitionThreshold of its nodes became unreachable within
+// nc.poolPartitionWindow of each other, which tells a loss of the network of the pool from the failures
+// of single nodes. A partitioned pool stays partitioned until less than nc.poolPartitionThreshold of its
+// nodes are unreachable.
+func (nc *Controller) computePoolPartitionState(nodeReadyConditions []*v1.NodeCondition, wasPartitioned bool) poolPartitionState {
+	state := poolPartitionState{size: len(nodeReadyConditions)}
+	var newest time.Time
+	for _, c := range nodeReadyConditions {
+		if c != nil && c.Status == v1.ConditionUnknown {
+			state.unreachable++
+			if c.LastTransitionTime.After(newest) {
+				newest = c.LastTransitionTime.Time
+			}
+		}
+	}
+	if state.size < int(nc.minPoolPartitionSize) || state.unreachable == 0 {
+		return state
+	}
+
+	together := state.unreachable
+	if !wasPartitioned {
+		together = 0
+		for _, c := range nodeReadyConditions {
+			if c != nil && c.Status == v1.ConditionUnknown && newest.Sub(c.LastTransitionTime.Time) <= nc.poolPartitionWindow {
+				together++
+			}
+		}
+	}
+	state.partitioned = float32(together)/float32(state.size) >= nc.poolPartitionThreshold
+	return state
+}
+
+// handlePoolPartition computes the partition state of the NodePools, stops the evictions of the nodes of
+// the pools entering the partition and reports the PoolPartitioned condition of the pools.
+func (nc *Controller) handlePoolPartition(results []nodeHealthResult) {
+	poolToResults := map[string][]nodeHealthResult{}
+	for _, r := range results {
+		if pool := getNodePoolName(r.node); pool != "" {
+			poolToResults[pool] = append(poolToResults[pool], r)
+		}
+	}
+
+	newPoolPartitionStates := make(map[string]bool, len(poolToResults))
+	for pool, rs := range poolToResults {
+		conditions := make([]*v1.NodeCondition, 0, len(rs))
+		for _, r := range rs {
+			conditions = append(conditions, r.currentReadyCondition)
+		}
+		wasPartitioned := nc.poolPartitionStates[pool]
+		state := nc.computePoolPartitionState(conditions, wasPartitioned)
+		newPoolPartitionStates[pool] = state.partitioned
+		poolSize.WithLabelValues(pool).Set(float64(state.size))
+		unreachableNodesInPool.WithLabelValues(pool).Set(float64(state.unreachable))
+		if state.partitioned {
+			poolPartitioned.WithLabelValues(pool).Set(1)
+		} else {
+			poolPartitioned.WithLabelValues(pool).Set(0)
+		}
+
+		switch {
+		case state.partitioned && !wasPartitioned:
+			klog.V(0).Infof("Controller detected that %d/%d nodes of NodePool %s are unreachable together. Entering pool partition mode.",
+				state.unreachable, state.size, pool)
+			for _, r := range rs {
+				if nc.runTaintManager {
+					if _, err := nc.markNodeAsReachable(r.node); err != nil {
+						klog.Errorf("Failed to remove taints from Node %v", r.node.Name)
+					}
+				} else {
+					nc.cancelPodEviction(r.node)
+				}
+			}
+		case !state.partitioned && wasPartitioned:
+			klog.V(0).Infof("Controller detected that %d/%d nodes of NodePool %s are reachable. Exiting pool partition mode.",
+				state.size-state.unreachable, state.size, pool)
+			// The nodes still unreachable are evicted after a whole eviction timeout from now.
+			now := nc.now()
+			for _, r := range rs {
+				v := nc.nodeHealthMap.getDeepCopy(r.node.Name)
+				if v == nil {
+					continue
+				}
+				v.probeTimestamp = now
+				v.readyTransitionTimestamp = now
+				nc.nodeHealthMap.set(r.node.Name, v)
+			}
+		}
+
+		// only the pools which have been partitioned once have the condition
+		if reported, ok := nc.reportedPoolPartitions[pool]; (ok && reported != state.partitioned) || (!ok && state.partitioned) {
+			if err := nc.setPoolPartitionedCondition(pool, state); err != nil {
+				klog.Errorf("Failed to set the %s condition of NodePool %s: %v", appsv1alpha1.NodePoolPartitionedCondition, pool, err)
+				continue
+			}
+			nc.reportedPoolPartitions[pool] = state.partitioned
+		}
+	}
+
+	for pool := range nc.poolPartitionStates {
+		if _, ok := newPoolPartitionStates[pool]; !ok {
+			poolSize.DeleteLabelValues(pool)
+			unreachableNodesInPool.DeleteLabelValues(pool)
+			poolPartitioned.DeleteLabelValues(pool)
+			delete(nc.reportedPoolPartitions, pool)
+		}
+	}
+	nc.poolPartitionStates = newPoolPartitionStates
+}
+
+// setPoolPartitionedCondition sets the PoolPartitioned condition of the NodePool.
+func (nc *Controller) setPoolPartitionedCondition(poolName string, state poolPartitionState) error {
+	if nc.poolClient == nil {
+		return nil
+	}
+
+	condition := appsv1alpha1.NodePoolCondition{
+		Type:               appsv1alpha1.NodePoolPartitionedCondition,
+		Status:             v1.ConditionFalse,
+		LastTransitionTime: nc.now(),
+		Reason:             "Reachable",
+		Message:            fmt.Sprintf("%d/%d nodes are unreachable", state.unreachable, state.size),
+	}
+	if state.partitioned {
+		condition.Status = v1.ConditionTrue
+		condition.Reason = "NodesUnreachable"
+	}
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		pool, err := nc.poolClient.AppsV1alpha1().NodePools().Get(context.TODO(), poolName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		var conditions []appsv1alpha1.NodePoolCondition
+		for _, c := range pool.Status.Conditions {
+			if c.Type != condition.Type {
+				conditions = append(conditions, c)
+			} else if c.Status == condition.Status {
+				condition.LastTransitionTime = c.LastTransitionTime
+			}
+		}
+		pool.Status.Conditions = append(conditions, condition)
+		_, err = nc.poolClient.AppsV1alpha1().NodePools().UpdateStatus(context.TODO(), pool, metav1.UpdateOptions{})
+		return err
+	})
+	if apierrors.IsNotFound(err) {
+		klog.V(2).Infof("NodePool %s is not found, skip setting the %s condition", poolName, condition.Type)
+		return nil
+	}
+	return err
+}
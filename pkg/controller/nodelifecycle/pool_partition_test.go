@@ -0,0 +1,130 @@
+package nodelifecycle
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestComputePoolPartitionState(t *testing.T) {
+	nc := &Controller{
+		poolPartitionThreshold: 0.55,
+		minPoolPartitionSize:   2,
+		poolPartitionWindow:    time.Minute,
+	}
+	now := time.Now()
+	ready := &v1.NodeCondition{Type: v1.NodeReady, Status: v1.ConditionTrue}
+	unreachableAt := func(ago time.Duration) *v1.NodeCondition {
+		return &v1.NodeCondition{
+			Type:               v1.NodeReady,
+			Status:             v1.ConditionUnknown,
+			LastTransitionTime: metav1.NewTime(now.Add(-ago)),
+		}
+	}
+
+	tests := []struct {
+		name           string
+		conditions     []*v1.NodeCondition
+		wasPartitioned bool
+		partitioned    bool
+	}{
+		{
+			name:        "healthy pool",
+			conditions:  []*v1.NodeCondition{ready, ready, ready},
+			partitioned: false,
+		},
+		{
+			name:        "single node failure",
+			conditions:  []*v1.NodeCondition{unreachableAt(0), ready, ready},
+			partitioned: false,
+		},
+		{
+			name:        "most nodes unreachable together",
+			conditions:  []*v1.NodeCondition{unreachableAt(0), unreachableAt(10 * time.Second), ready},
+			partitioned: true,
+		},
+		{
+			name:        "most nodes unreachable one by one",
+			conditions:  []*v1.NodeCondition{unreachableAt(0), unreachableAt(time.Hour), ready},
+			partitioned: false,
+		},
+		{
+			name:           "partitioned pool stays partitioned",
+			conditions:     []*v1.NodeCondition{unreachableAt(0), unreachableAt(time.Hour), ready},
+			wasPartitioned: true,
+			partitioned:    true,
+		},
+		{
+			name:           "partitioned pool recovers",
+			conditions:     []*v1.NodeCondition{unreachableAt(time.Hour), ready, ready},
+			wasPartitioned: true,
+			partitioned:    false,
+		},
+		{
+			name:        "pool smaller than the min size",
+			conditions:  []*v1.NodeCondition{unreachableAt(0)},
+			partitioned: false,
+		},
+		{
+			name:        "not ready nodes are not unreachable",
+			conditions:  []*v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse}, {Type: v1.NodeReady, Status: v1.ConditionFalse}},
+			partitioned: false,
+		},
+	}
+
+	for _, tt := range tests {
+		st := tt
+		tf := func(t *testing.T) {
+			t.Parallel()
+			state := nc.computePoolPartitionState(st.conditions, st.wasPartitioned)
+			if state.partitioned != st.partitioned {
+				t.Fatalf("expect partitioned %v, but got %v with %d/%d nodes unreachable",
+					st.partitioned, state.partitioned, state.unreachable, state.size)
+			}
+		}
+		t.Run(st.name, tf)
+	}
+}
+
+func TestIsNodeInPartitionedPool(t *testing.T) {
+	nc := &Controller{poolPartitionStates: map[string]bool{"hangzhou": true, "beijing": false}}
+	nodeInPool := func(pool string) *v1.Node {
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", Labels: map[string]string{}}}
+		if pool != "" {
+			node.Labels["apps.bhojpur.net/nodepool"] = pool
+		}
+		return node
+	}
+
+	if !nc.isNodeInPartitionedPool(nodeInPool("hangzhou")) {
+		t.Fatalf("node in partitioned pool hangzhou is not detected")
+	}
+	if nc.isNodeInPartitionedPool(nodeInPool("beijing")) {
+		t.Fatalf("node in healthy pool beijing is detected as partitioned")
+	}
+	if nc.isNodeInPartitionedPool(nodeInPool("")) {
+		t.Fatalf("node without pool is detected as partitioned")
+	}
+}
@@ -43,6 +43,7 @@ import (
 const (
 	hubHealthzCheckFrequency = 10 * time.Second
 	fileMode                 = 0666
+	engineRestartTimeout     = time.Minute
 )
 
 type engineOperator struct {
@@ -51,57 +52,144 @@ type engineOperator struct {
 	joinToken                string
 	workingMode              util.WorkingMode
 	engineHealthCheckTimeout time.Duration
+	podManifestPath          string
 }
 
 // NewEngineOperator new engineOperator struct
 func NewEngineOperator(apiServerAddr string, engineImage string, joinToken string,
-	workingMode util.WorkingMode, engineHealthCheckTimeout time.Duration) *engineOperator {
+	workingMode util.WorkingMode, engineHealthCheckTimeout time.Duration, podManifestPath string) *engineOperator {
 	return &engineOperator{
 		apiServerAddr:            apiServerAddr,
 		engineImage:              engineImage,
 		joinToken:                joinToken,
 		workingMode:              workingMode,
 		engineHealthCheckTimeout: engineHealthCheckTimeout,
+		podManifestPath:          podManifestPath,
 	}
 }
 
 // Install set Bhojpur DCP server engine yaml to static path to start pod
 func (op *engineOperator) Install() error {
 
-	// 1. put dcpsvr yaml into /etc/kubernetes/manifests
+	// 1. put dcpsvr yaml into the static pod path, /etc/kubernetes/manifests by default
 	klog.Infof("setting up Bhojpur DCP server engine on node")
 
 	// 1-1. replace variables in yaml file
 	klog.Infof("setting up Bhojpur DCP server engine apiServer addr")
-	engineTemplate, err := templates.SubsituteTemplate(enutil.EngineTemplate, map[string]string{
-		"kubernetesServerAddr": op.apiServerAddr,
-		"image":                op.engineImage,
-		"joinToken":            op.joinToken,
-		"workingMode":          string(op.workingMode),
-	})
+	engineTemplate, err := op.renderEngineTemplate()
 	if err != nil {
 		return err
 	}
 
 	// 1-2. create dcpsvr.yaml
-	podManifestPath := enutil.GetPodManifestPath()
-	if err := enutil.EnsureDir(podManifestPath); err != nil {
+	if err := enutil.EnsureDir(op.podManifestPath); err != nil {
 		return err
 	}
-	if err := ioutil.WriteFile(getEngineYaml(podManifestPath), []byte(engineTemplate), fileMode); err != nil {
+	if err := ioutil.WriteFile(getEngineYaml(op.podManifestPath), []byte(engineTemplate), fileMode); err != nil {
 		return err
 	}
-	klog.Infof("create the %s/dcpsvr.yaml", podManifestPath)
+	klog.Infof("create the %s/dcpsvr.yaml", op.podManifestPath)
 
 	// 2. wait Bhojpur DCP server engine pod to be ready
 	return engineHealthcheck(op.engineHealthCheckTimeout)
 }
 
+// Upgrade replaces the Bhojpur DCP server engine static pod with the one rendered for the
+// new image, the current yaml is kept so that the upgrade can be rolled back. The backup is
+// marked with the upgradeID, if any, so that only the upgrade writing it rolls it back.
+func (op *engineOperator) Upgrade(upgradeID string) error {
+	engineYamlPath := getEngineYaml(op.podManifestPath)
+	current, err := ioutil.ReadFile(engineYamlPath)
+	if err != nil {
+		return fmt.Errorf("fail to read %s, the node may not be converted: %v", engineYamlPath, err)
+	}
+
+	engineTemplate, err := op.renderEngineTemplate()
+	if err != nil {
+		return err
+	}
+
+	// the job may be restarted after a failed health check, never overwrite the backup
+	// with the yaml of a previous attempt.
+	if string(current) == engineTemplate {
+		klog.Infof("%s is up to date, skip to replace it", engineYamlPath)
+		return engineHealthcheck(op.engineHealthCheckTimeout)
+	}
+
+	// 1. keep the current dcpsvr.yaml
+	if err := enutil.EnsureDir(getEngineConf()); err != nil {
+		return err
+	}
+	backupPath := getEngineBackupYaml()
+	if err := ioutil.WriteFile(backupPath, current, fileMode); err != nil {
+		return err
+	}
+	if err := writeEngineBackupID(upgradeID); err != nil {
+		return err
+	}
+	klog.Infof("UpgradeEngine: %s is backed up to %s", engineYamlPath, backupPath)
+
+	// 2. replace the dcpsvr.yaml
+	if err := ioutil.WriteFile(engineYamlPath, []byte(engineTemplate), fileMode); err != nil {
+		return err
+	}
+	klog.Infof("UpgradeEngine: %s is replaced with image %s", engineYamlPath, op.engineImage)
+
+	// 3. wait the old Bhojpur DCP server engine pod to be replaced and the new one to be ready
+	return waitEngineRestart(op.engineHealthCheckTimeout)
+}
+
+// Rollback restores the Bhojpur DCP server engine yaml kept by the last upgrade. If upgradeID
+// is set, the yaml is only restored if the backup was written by that upgrade, otherwise the
+// node was not upgraded by it and is left as it is.
+func (op *engineOperator) Rollback(upgradeID string) error {
+	backupPath := getEngineBackupYaml()
+	if upgradeID != "" {
+		id, err := ioutil.ReadFile(getEngineBackupIDFile())
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if string(id) != upgradeID {
+			klog.Infof("RollbackEngine: %s is not backed up by upgrade %s, nothing to roll back", backupPath, upgradeID)
+			return nil
+		}
+	}
+	backup, err := ioutil.ReadFile(backupPath)
+	if err != nil {
+		return fmt.Errorf("fail to read %s, nothing to roll back: %v", backupPath, err)
+	}
+
+	engineYamlPath := getEngineYaml(op.podManifestPath)
+	current, err := ioutil.ReadFile(engineYamlPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if string(current) == string(backup) {
+		klog.Infof("%s is already rolled back", engineYamlPath)
+		return engineHealthcheck(op.engineHealthCheckTimeout)
+	}
+
+	if err := ioutil.WriteFile(engineYamlPath, backup, fileMode); err != nil {
+		return err
+	}
+	klog.Infof("RollbackEngine: %s is restored from %s", engineYamlPath, backupPath)
+
+	return waitEngineRestart(op.engineHealthCheckTimeout)
+}
+
+func (op *engineOperator) renderEngineTemplate() (string, error) {
+	return templates.SubsituteTemplate(enutil.EngineTemplate, map[string]string{
+		"kubernetesServerAddr": op.apiServerAddr,
+		"image":                op.engineImage,
+		"joinToken":            op.joinToken,
+		"workingMode":          string(op.workingMode),
+	})
+}
+
 // UnInstall remove yaml and configs of Bhojpur DCP server engine
 func (op *engineOperator) UnInstall() error {
 	// 1. remove the dcpsvr.yaml to delete the dcpsvr
-	podManifestPath := enutil.GetPodManifestPath()
-	engineYamlPath := getEngineYaml(podManifestPath)
+	engineYamlPath := getEngineYaml(op.podManifestPath)
 	if _, err := enutil.FileExists(engineYamlPath); os.IsNotExist(err) {
 		klog.Infof("UninstallEngine: %s is not exists, skip delete", engineYamlPath)
 	} else {
@@ -145,6 +233,27 @@ func getEngineYaml(podManifestPath string) string {
 	return filepath.Join(podManifestPath, enutil.EngineYamlName)
 }
 
+func getEngineBackupYaml() string {
+	return filepath.Join(getEngineConf(), fmt.Sprintf(enutil.KubeletSvcBackup, enutil.EngineYamlName))
+}
+
+// getEngineBackupIDFile returns the file keeping the id of the upgrade writing the backup yaml
+func getEngineBackupIDFile() string {
+	return getEngineBackupYaml() + ".id"
+}
+
+// writeEngineBackupID marks the backup yaml with the id of the upgrade, an upgrade without
+// id removes the mark of a previous one.
+func writeEngineBackupID(upgradeID string) error {
+	if upgradeID == "" {
+		if err := os.Remove(getEngineBackupIDFile()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return ioutil.WriteFile(getEngineBackupIDFile(), []byte(upgradeID), fileMode)
+}
+
 func getEngineConf() string {
 	return filepath.Join(hubself.EngineRootDir, hubself.EngineName)
 }
@@ -170,6 +279,16 @@ func waitUntilEngineExit(timeout time.Duration, period time.Duration) error {
 	})
 }
 
+// waitEngineRestart waits the running Bhojpur DCP server engine to exit after its yaml is
+// changed, then checks the status of the new one. The kubelet may restart the pod before
+// the exit is noticed, so a timeout of the first phase is not an error.
+func waitEngineRestart(timeout time.Duration) error {
+	if err := waitUntilEngineExit(engineRestartTimeout, time.Second); err != nil {
+		klog.Warningf("the exit of the old Bhojpur DCP server engine is not noticed: %v", err)
+	}
+	return engineHealthcheck(timeout)
+}
+
 // engineHealthcheck will check the status of Bhojpur DCP server engine pod
 func engineHealthcheck(timeout time.Duration) error {
 	serverHealthzURL, err := url.Parse(fmt.Sprintf("http://%s", enutil.ServerHealthzServer))
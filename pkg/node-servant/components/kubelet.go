@@ -26,7 +26,6 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strings"
 
 	"k8s.io/klog/v2"
@@ -43,43 +42,45 @@ const (
 )
 
 type kubeletOperator struct {
-	dcpDir string
+	dcpDir   string
+	provider KubeletProvider
 }
 
 // NewKubeletOperator create kubeletOperator
-func NewKubeletOperator(bhojpurDir string) *kubeletOperator {
+func NewKubeletOperator(bhojpurDir string, provider KubeletProvider) *kubeletOperator {
 	return &kubeletOperator{
-		dcpDir: bhojpurDir,
+		dcpDir:   bhojpurDir,
+		provider: provider,
 	}
 }
 
 // RedirectTrafficToEngine
-// add env config leads kubelet to visit Bhojpur DCP server engine as apiServer
+// point the kubelet at the revised kubeconfig, which leads kubelet to visit Bhojpur DCP server engine as apiServer
 func (op *kubeletOperator) RedirectTrafficToEngine() error {
 	// 1. create a working dir to store revised kubelet.conf
-	_, err := op.writeEngineKubeletConfig()
+	engineKubeletConf, err := op.writeEngineKubeletConfig()
 	if err != nil {
 		return err
 	}
 
-	// 2. append /var/lib/kubelet/kubeadm-flags.env
-	if err := op.appendConfig(); err != nil {
+	// 2. set the kubeconfig of kubelet where the provider keeps the kubelet args
+	if err := op.provider.SetKubeletKubeconfig(engineKubeletConf); err != nil {
 		return err
 	}
 
 	// 3. restart
-	return restartKubeletService()
+	return op.provider.RestartKubelet()
 }
 
 // UndoRedirectTrafficToEngine
 // undo what's done to kubelet and restart
-// to compatible the old convert way for a while , so do renameSvcBk
 func (op *kubeletOperator) UndoRedirectTrafficToEngine() error {
-	if err := op.undoAppendConfig(); err != nil {
+	if err := op.provider.UnsetKubeletKubeconfig(op.getEngineKubeletConf()); err != nil {
 		return err
 	}
+	klog.Info("revertKubelet: unset kubelet kubeconfig finished")
 
-	if err := restartKubeletService(); err != nil {
+	if err := op.provider.RestartKubelet(); err != nil {
 		return err
 	}
 
@@ -114,61 +115,6 @@ func (op *kubeletOperator) undoWriteEngineKubeletConfig() error {
 	return os.Remove(dcpKubeletConf)
 }
 
-func (op *kubeletOperator) appendConfig() error {
-	// set env KUBELET_KUBEADM_ARGS, args set later will override before
-	// ExecStart: kubelet $KUBELET_KUBECONFIG_ARGS $KUBELET_CONFIG_ARGS $KUBELET_KUBEADM_ARGS $KUBELET_EXTRA_ARGS
-	// append setup: " --kubeconfig=$engineKubeletConf -bootstrap-kubeconfig= "
-	kubeConfigSetup := op.getAppendSetting()
-
-	// if wrote, return
-	content, err := ioutil.ReadFile(kubeAdmFlagsEnvFile)
-	if err != nil {
-		return err
-	}
-	args := string(content)
-	if strings.Contains(args, kubeConfigSetup) {
-		klog.Info("kubeConfigSetup has wrote before")
-		return nil
-	}
-
-	// append KUBELET_KUBEADM_ARGS
-	argsRegexp := regexp.MustCompile(`KUBELET_KUBEADM_ARGS="(.+)"`)
-	finding := argsRegexp.FindStringSubmatch(args)
-	if len(finding) != 2 {
-		return fmt.Errorf("kubeadm-flags.env error format. %s", args)
-	}
-
-	r := strings.Replace(args, finding[1], fmt.Sprintf("%s %s", finding[1], kubeConfigSetup), 1)
-	err = ioutil.WriteFile(kubeAdmFlagsEnvFile, []byte(r), fileMode)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (op *kubeletOperator) undoAppendConfig() error {
-	kubeConfigSetup := op.getAppendSetting()
-	contentbyte, err := ioutil.ReadFile(kubeAdmFlagsEnvFile)
-	if err != nil {
-		return err
-	}
-
-	content := strings.ReplaceAll(string(contentbyte), kubeConfigSetup, "")
-	err = ioutil.WriteFile(kubeAdmFlagsEnvFile, []byte(content), fileMode)
-	if err != nil {
-		return err
-	}
-	klog.Info("revertKubelet: undoAppendConfig finished")
-
-	return nil
-}
-
-func (op *kubeletOperator) getAppendSetting() string {
-	configPath := op.getEngineKubeletConf()
-	return fmt.Sprintf(" --kubeconfig=%s --bootstrap-kubeconfig= ", configPath)
-}
-
 func (op *kubeletOperator) getEngineKubeletConf() string {
 	return filepath.Join(op.dcpDir, enutil.KubeletConfName)
 }
@@ -190,10 +136,16 @@ func restartKubeletService() error {
 
 // GetApiServerAddress parse apiServer address from conf file
 func GetApiServerAddress(kubeadmConfPaths []string) (string, error) {
+	return getApiServerAddress("", kubeadmConfPaths)
+}
+
+// getApiServerAddress parse apiServer address from the kubeconfig referred by the
+// first existing kubeadm conf file, all the paths are taken as relative to rootDir.
+func getApiServerAddress(rootDir string, kubeadmConfPaths []string) (string, error) {
 	var kbcfg string
 	for _, path := range kubeadmConfPaths {
-		if exist, _ := enutil.FileExists(path); exist {
-			kbcfg = path
+		if exist, _ := enutil.FileExists(filepath.Join(rootDir, path)); exist {
+			kbcfg = filepath.Join(rootDir, path)
 			break
 		}
 	}
@@ -210,8 +162,12 @@ func GetApiServerAddress(kubeadmConfPaths []string) (string, error) {
 	if len(confArr) != 2 {
 		return "", fmt.Errorf("get kubeletConfPath format err:%s", kubeletConfPath)
 	}
-	kubeletConfPath = confArr[1]
-	apiserverAddr, err := enutil.GetSingleContentFromFile(kubeletConfPath, apiserverAddrRegularExpression)
+	return getServerFromKubeconfig(filepath.Join(rootDir, confArr[1]))
+}
+
+// getServerFromKubeconfig parse the apiServer address from a kubeconfig file
+func getServerFromKubeconfig(kubeconfigPath string) (string, error) {
+	apiserverAddr, err := enutil.GetSingleContentFromFile(kubeconfigPath, apiserverAddrRegularExpression)
 	if err != nil {
 		return "", err
 	}
@@ -220,6 +176,5 @@ func GetApiServerAddress(kubeadmConfPaths []string) (string, error) {
 	if len(addrArr) != 2 {
 		return "", fmt.Errorf("get apiserverAddr format err:%s", apiserverAddr)
 	}
-	apiserverAddr = addrArr[1]
-	return apiserverAddr, nil
+	return addrArr[1], nil
 }
@@ -0,0 +1,311 @@
+package components
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	enutil "github.com/bhojpur/dcp/pkg/client/util/edgenode"
+)
+
+const (
+	// ProviderKubeadm is the provider of the nodes that are set up by kubeadm,
+	// the kubelet args are kept in kubeadm-flags.env
+	ProviderKubeadm = "kubeadm"
+	// ProviderK3s is the provider of the nodes that run the k3s agent
+	ProviderK3s = "k3s"
+	// ProviderRKE2 is the provider of the nodes that run the rke2 agent
+	ProviderRKE2 = "rke2"
+	// ProviderDcp is the provider of the nodes that run the Bhojpur DCP agent
+	ProviderDcp = "dcp"
+
+	// engineDropInName is the name of the config file dropped into <config>.d by node-servant
+	engineDropInName = "99-dcpsvr.yaml"
+)
+
+// KubeletProvider knows where a kubernetes distribution keeps the args and the kubeconfig
+// of kubelet, so that the kubelet can be pointed at Bhojpur DCP server engine and back.
+type KubeletProvider interface {
+	// GetApiServerAddress returns the apiServer address used by kubelet
+	GetApiServerAddress() (string, error)
+	// GetPodManifestPath returns the static pod path watched by kubelet
+	GetPodManifestPath() string
+	// SetKubeletKubeconfig makes kubelet use the kubeconfig at path, it shall be idempotent
+	SetKubeletKubeconfig(path string) error
+	// UnsetKubeletKubeconfig undoes SetKubeletKubeconfig
+	UnsetKubeletKubeconfig(path string) error
+	// RestartKubelet restarts the kubelet, or the agent that runs it
+	RestartKubelet() error
+}
+
+// KubeletProviderOptions has the information that required to create a KubeletProvider
+type KubeletProviderOptions struct {
+	// RootDir is prepended to all the paths on the node, it is empty unless
+	// the node layout is faked.
+	RootDir string
+	// KubeadmConfPaths are the kubelet service confs searched in order by the kubeadm provider
+	KubeadmConfPaths []string
+}
+
+// KubeletProviderFactory creates a KubeletProvider
+type KubeletProviderFactory func(o KubeletProviderOptions) KubeletProvider
+
+var kubeletProviders = map[string]KubeletProviderFactory{}
+
+func init() {
+	kubeadm := func(o KubeletProviderOptions) KubeletProvider {
+		return newKubeadmProvider(o)
+	}
+	// minikube, kind and ack nodes are all set up by kubeadm
+	for _, name := range []string{ProviderKubeadm, "minikube", "kind", "ack"} {
+		RegisterKubeletProvider(name, kubeadm)
+	}
+	RegisterKubeletProvider(ProviderK3s, func(o KubeletProviderOptions) KubeletProvider {
+		return newAgentProvider(o, "/etc/rancher/k3s/config.yaml", "/var/lib/rancher/k3s", "k3s-agent", "k3s")
+	})
+	RegisterKubeletProvider(ProviderRKE2, func(o KubeletProviderOptions) KubeletProvider {
+		return newAgentProvider(o, "/etc/rancher/rke2/config.yaml", "/var/lib/rancher/rke2", "rke2-agent", "rke2-server")
+	})
+	RegisterKubeletProvider(ProviderDcp, func(o KubeletProviderOptions) KubeletProvider {
+		return newAgentProvider(o, "/etc/bhojpur/dcp/config.yaml", "/var/lib/bhojpur/dcp", "dcp-agent", "dcp")
+	})
+}
+
+// RegisterKubeletProvider registers the factory of a KubeletProvider with the provider name,
+// a provider registered later replaces the former one with the same name.
+func RegisterKubeletProvider(name string, factory KubeletProviderFactory) {
+	kubeletProviders[name] = factory
+}
+
+// KubeletProviders returns the sorted names of all the registered providers
+func KubeletProviders() []string {
+	names := make([]string, 0, len(kubeletProviders))
+	for name := range kubeletProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewKubeletProvider creates the KubeletProvider registered with name,
+// the kubeadm provider is used if name is empty.
+func NewKubeletProvider(name string, o KubeletProviderOptions) (KubeletProvider, error) {
+	if name == "" {
+		name = ProviderKubeadm
+	}
+	factory, ok := kubeletProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q, valid providers are: %s",
+			name, strings.Join(KubeletProviders(), ", "))
+	}
+	return factory(o), nil
+}
+
+// kubeadmProvider appends the kubeconfig setting to KUBELET_KUBEADM_ARGS in kubeadm-flags.env
+type kubeadmProvider struct {
+	rootDir          string
+	kubeadmConfPaths []string
+	restart          func() error
+}
+
+func newKubeadmProvider(o KubeletProviderOptions) *kubeadmProvider {
+	kubeadmConfPaths := o.KubeadmConfPaths
+	if len(kubeadmConfPaths) == 0 {
+		kubeadmConfPaths = GetDefaultKubeadmConfPath()
+	}
+	return &kubeadmProvider{
+		rootDir:          o.RootDir,
+		kubeadmConfPaths: kubeadmConfPaths,
+		restart:          restartKubeletService,
+	}
+}
+
+func (p *kubeadmProvider) GetApiServerAddress() (string, error) {
+	return getApiServerAddress(p.rootDir, p.kubeadmConfPaths)
+}
+
+func (p *kubeadmProvider) GetPodManifestPath() string {
+	return filepath.Join(p.rootDir, enutil.GetPodManifestPath())
+}
+
+func (p *kubeadmProvider) SetKubeletKubeconfig(path string) error {
+	// set env KUBELET_KUBEADM_ARGS, args set later will override before
+	// ExecStart: kubelet $KUBELET_KUBECONFIG_ARGS $KUBELET_CONFIG_ARGS $KUBELET_KUBEADM_ARGS $KUBELET_EXTRA_ARGS
+	// append setup: " --kubeconfig=$engineKubeletConf -bootstrap-kubeconfig= "
+	kubeConfigSetup := getKubeadmAppendSetting(path)
+	flagsEnvFile := p.flagsEnvFile()
+
+	// if wrote, return
+	content, err := ioutil.ReadFile(flagsEnvFile)
+	if err != nil {
+		return err
+	}
+	args := string(content)
+	if strings.Contains(args, kubeConfigSetup) {
+		klog.Info("kubeConfigSetup has wrote before")
+		return nil
+	}
+
+	// append KUBELET_KUBEADM_ARGS
+	argsRegexp := regexp.MustCompile(`KUBELET_KUBEADM_ARGS="(.+)"`)
+	finding := argsRegexp.FindStringSubmatch(args)
+	if len(finding) != 2 {
+		return fmt.Errorf("kubeadm-flags.env error format. %s", args)
+	}
+
+	r := strings.Replace(args, finding[1], finding[1]+kubeConfigSetup, 1)
+	return ioutil.WriteFile(flagsEnvFile, []byte(r), fileMode)
+}
+
+func (p *kubeadmProvider) UnsetKubeletKubeconfig(path string) error {
+	flagsEnvFile := p.flagsEnvFile()
+	contentbyte, err := ioutil.ReadFile(flagsEnvFile)
+	if err != nil {
+		return err
+	}
+
+	content := strings.ReplaceAll(string(contentbyte), getKubeadmAppendSetting(path), "")
+	return ioutil.WriteFile(flagsEnvFile, []byte(content), fileMode)
+}
+
+func (p *kubeadmProvider) RestartKubelet() error {
+	return p.restart()
+}
+
+func (p *kubeadmProvider) flagsEnvFile() string {
+	return filepath.Join(p.rootDir, kubeAdmFlagsEnvFile)
+}
+
+func getKubeadmAppendSetting(path string) string {
+	return fmt.Sprintf(" --kubeconfig=%s --bootstrap-kubeconfig= ", path)
+}
+
+// agentProvider serves the distributions whose agent starts kubelet as an embedded
+// process, like k3s, rke2 and the Bhojpur DCP agent. The agent regenerates the kubelet
+// kubeconfig on every start, so the kubeconfig is overridden with a kubelet-arg that
+// is put into a drop-in file of the agent config instead of editing the kubeconfig.
+type agentProvider struct {
+	rootDir    string
+	configFile string
+	dataDir    string
+	services   []string
+	restart    func(service string) error
+	isActive   func(service string) bool
+}
+
+func newAgentProvider(o KubeletProviderOptions, configFile, dataDir string, services ...string) *agentProvider {
+	return &agentProvider{
+		rootDir:    o.RootDir,
+		configFile: configFile,
+		dataDir:    dataDir,
+		services:   services,
+		restart:    restartService,
+		isActive:   isServiceActive,
+	}
+}
+
+func (p *agentProvider) GetApiServerAddress() (string, error) {
+	return getServerFromKubeconfig(filepath.Join(p.getDataDir(), "agent", "kubelet.kubeconfig"))
+}
+
+func (p *agentProvider) GetPodManifestPath() string {
+	return filepath.Join(p.getDataDir(), "agent", "pod-manifests")
+}
+
+func (p *agentProvider) SetKubeletKubeconfig(path string) error {
+	dropIn := p.dropInFile()
+	if err := enutil.EnsureDir(filepath.Dir(dropIn)); err != nil {
+		return err
+	}
+
+	// kubelet-arg+ appends the arg to the ones set in config.yaml, and the latter
+	// kubeconfig arg overrides the one set by the agent.
+	content := fmt.Sprintf("# generated by node-servant, remove it with node-servant revert\nkubelet-arg+:\n- %q\n",
+		"kubeconfig="+path)
+	if err := ioutil.WriteFile(dropIn, []byte(content), fileMode); err != nil {
+		return err
+	}
+	klog.Infof("kubelet kubeconfig is set in %s", dropIn)
+	return nil
+}
+
+func (p *agentProvider) UnsetKubeletKubeconfig(_ string) error {
+	dropIn := p.dropInFile()
+	if err := os.Remove(dropIn); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	klog.Infof("%s has been removed", dropIn)
+	return nil
+}
+
+// RestartKubelet restarts the first active service of the agent, the server runs
+// kubelet too when the control-plane node is also a worker.
+func (p *agentProvider) RestartKubelet() error {
+	for _, svc := range p.services {
+		if p.isActive(svc) {
+			return p.restart(svc)
+		}
+	}
+	return fmt.Errorf("none of services %s is active", strings.Join(p.services, ", "))
+}
+
+func (p *agentProvider) dropInFile() string {
+	return filepath.Join(p.rootDir, p.configFile+".d", engineDropInName)
+}
+
+// getDataDir returns the data-dir set in the agent config, or the default one
+func (p *agentProvider) getDataDir() string {
+	content, err := ioutil.ReadFile(filepath.Join(p.rootDir, p.configFile))
+	if err != nil {
+		return filepath.Join(p.rootDir, p.dataDir)
+	}
+	config := struct {
+		DataDir string `json:"data-dir"`
+	}{}
+	if err := yaml.Unmarshal(content, &config); err != nil || config.DataDir == "" {
+		return filepath.Join(p.rootDir, p.dataDir)
+	}
+	return filepath.Join(p.rootDir, config.DataDir)
+}
+
+func isServiceActive(service string) bool {
+	return exec.Command("systemctl", "is-active", "--quiet", service).Run() == nil
+}
+
+func restartService(service string) error {
+	klog.Infof("restartKubelet: systemctl restart %s", service)
+	cmd := exec.Command("systemctl", "restart", service)
+	if err := enutil.Exec(cmd); err != nil {
+		return err
+	}
+	klog.Infof("restartKubelet: %s has been restarted", service)
+	return nil
+}
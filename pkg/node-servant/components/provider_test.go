@@ -0,0 +1,234 @@
+package components
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testKubeadmConf = `[Service]
+Environment="KUBELET_KUBECONFIG_ARGS=--bootstrap-kubeconfig=/etc/kubernetes/bootstrap-kubelet.conf --kubeconfig=/etc/kubernetes/kubelet.conf"
+`
+	testKubeadmFlagsEnv = `KUBELET_KUBEADM_ARGS="--network-plugin=cni --pod-infra-container-image=k8s.gcr.io/pause:3.5"`
+	testKubeconfig      = `apiVersion: v1
+clusters:
+- cluster:
+    server: https://%s
+  name: default
+`
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), dirMode); err != nil {
+		t.Fatalf("could not create dir for %s: %v", path, err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), fileMode); err != nil {
+		t.Fatalf("could not write %s: %v", path, err)
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read %s: %v", path, err)
+	}
+	return string(content)
+}
+
+// fakeLayout creates the files of a node set up by provider under rootDir
+func fakeLayout(t *testing.T, provider, rootDir string) {
+	switch provider {
+	case ProviderKubeadm:
+		writeTestFile(t, filepath.Join(rootDir, KubelerSvcPathSystemEtc), testKubeadmConf)
+		writeTestFile(t, filepath.Join(rootDir, kubeAdmFlagsEnvFile), testKubeadmFlagsEnv)
+		writeTestFile(t, filepath.Join(rootDir, "/etc/kubernetes/kubelet.conf"),
+			strings.Replace(testKubeconfig, "%s", "1.2.3.4:6443", 1))
+	case ProviderK3s:
+		writeTestFile(t, filepath.Join(rootDir, "/etc/rancher/k3s/config.yaml"), "kubelet-arg:\n- \"max-pods=50\"\n")
+		writeTestFile(t, filepath.Join(rootDir, "/var/lib/rancher/k3s/agent/kubelet.kubeconfig"),
+			strings.Replace(testKubeconfig, "%s", "127.0.0.1:6444", 1))
+	case ProviderRKE2:
+		writeTestFile(t, filepath.Join(rootDir, "/var/lib/rancher/rke2/agent/kubelet.kubeconfig"),
+			strings.Replace(testKubeconfig, "%s", "127.0.0.1:6443", 1))
+	case ProviderDcp:
+		writeTestFile(t, filepath.Join(rootDir, "/etc/bhojpur/dcp/config.yaml"), "data-dir: /data/dcp\n")
+		writeTestFile(t, filepath.Join(rootDir, "/data/dcp/agent/kubelet.kubeconfig"),
+			strings.Replace(testKubeconfig, "%s", "127.0.0.1:6443", 1))
+	}
+}
+
+// stubRestart replaces the restart of kubelet with a counter
+func stubRestart(t *testing.T, provider KubeletProvider, restarts *int) {
+	switch p := provider.(type) {
+	case *kubeadmProvider:
+		p.restart = func() error {
+			*restarts++
+			return nil
+		}
+	case *agentProvider:
+		p.isActive = func(service string) bool {
+			return service == p.services[0]
+		}
+		p.restart = func(service string) error {
+			if service != p.services[0] {
+				t.Errorf("expected service %s to be restarted, got %s", p.services[0], service)
+			}
+			*restarts++
+			return nil
+		}
+	default:
+		t.Fatalf("unexpected provider type %T", provider)
+	}
+}
+
+func TestKubeletProviders(t *testing.T) {
+	tests := []struct {
+		name            string
+		provider        string
+		apiServerAddr   string
+		podManifestPath string
+		kubeletArgsFile string
+	}{
+		{
+			name:            "kubeadm",
+			provider:        ProviderKubeadm,
+			apiServerAddr:   "https://1.2.3.4:6443",
+			podManifestPath: "/etc/kubernetes/manifests",
+			kubeletArgsFile: kubeAdmFlagsEnvFile,
+		},
+		{
+			name:            "k3s",
+			provider:        ProviderK3s,
+			apiServerAddr:   "https://127.0.0.1:6444",
+			podManifestPath: "/var/lib/rancher/k3s/agent/pod-manifests",
+			kubeletArgsFile: "/etc/rancher/k3s/config.yaml.d/" + engineDropInName,
+		},
+		{
+			name:            "rke2 without config",
+			provider:        ProviderRKE2,
+			apiServerAddr:   "https://127.0.0.1:6443",
+			podManifestPath: "/var/lib/rancher/rke2/agent/pod-manifests",
+			kubeletArgsFile: "/etc/rancher/rke2/config.yaml.d/" + engineDropInName,
+		},
+		{
+			name:            "dcp with data-dir",
+			provider:        ProviderDcp,
+			apiServerAddr:   "https://127.0.0.1:6443",
+			podManifestPath: "/data/dcp/agent/pod-manifests",
+			kubeletArgsFile: "/etc/bhojpur/dcp/config.yaml.d/" + engineDropInName,
+		},
+	}
+
+	for _, tt := range tests {
+		st := tt
+		tf := func(t *testing.T) {
+			t.Parallel()
+			rootDir, err := ioutil.TempDir("", "kubelet-provider")
+			if err != nil {
+				t.Fatalf("could not create temp dir: %v", err)
+			}
+			t.Cleanup(func() { os.RemoveAll(rootDir) })
+			fakeLayout(t, st.provider, rootDir)
+
+			provider, err := NewKubeletProvider(st.provider, KubeletProviderOptions{
+				RootDir:          rootDir,
+				KubeadmConfPaths: []string{KubeletSvcPathSystemUsr, KubelerSvcPathSystemEtc},
+			})
+			if err != nil {
+				t.Fatalf("could not create provider %s: %v", st.provider, err)
+			}
+			restarts := 0
+			stubRestart(t, provider, &restarts)
+
+			addr, err := provider.GetApiServerAddress()
+			if err != nil {
+				t.Fatalf("could not get apiserver address: %v", err)
+			}
+			if addr != st.apiServerAddr {
+				t.Errorf("expected apiserver address %s, got %s", st.apiServerAddr, addr)
+			}
+			if got := provider.GetPodManifestPath(); got != filepath.Join(rootDir, st.podManifestPath) {
+				t.Errorf("expected pod manifest path %s, got %s", st.podManifestPath, got)
+			}
+
+			dcpDir := filepath.Join(rootDir, "/var/lib/bhojpur")
+			engineKubeletConf := filepath.Join(dcpDir, "kubelet.conf")
+			kubeletArgsFile := filepath.Join(rootDir, st.kubeletArgsFile)
+			op := NewKubeletOperator(dcpDir, provider)
+
+			// convert twice to make sure it is idempotent
+			for i := 0; i < 2; i++ {
+				if err := op.RedirectTrafficToEngine(); err != nil {
+					t.Fatalf("could not redirect kubelet: %v", err)
+				}
+			}
+			if restarts != 2 {
+				t.Errorf("expected kubelet to be restarted 2 times, got %d", restarts)
+			}
+			if _, err := os.Stat(engineKubeletConf); err != nil {
+				t.Errorf("expected %s to be written: %v", engineKubeletConf, err)
+			}
+			args := readTestFile(t, kubeletArgsFile)
+			if n := strings.Count(args, "kubeconfig="+engineKubeletConf); n != 1 {
+				t.Errorf("expected kubeconfig to be set once in %s, got %d times:\n%s", kubeletArgsFile, n, args)
+			}
+
+			if err := op.UndoRedirectTrafficToEngine(); err != nil {
+				t.Fatalf("could not undo the redirect of kubelet: %v", err)
+			}
+			if restarts != 3 {
+				t.Errorf("expected kubelet to be restarted 3 times, got %d", restarts)
+			}
+			if _, err := os.Stat(engineKubeletConf); !os.IsNotExist(err) {
+				t.Errorf("expected %s to be removed, got %v", engineKubeletConf, err)
+			}
+			if st.provider == ProviderKubeadm {
+				if args := readTestFile(t, kubeletArgsFile); args != testKubeadmFlagsEnv {
+					t.Errorf("expected %s to be restored, got:\n%s", kubeletArgsFile, args)
+				}
+			} else if _, err := os.Stat(kubeletArgsFile); !os.IsNotExist(err) {
+				t.Errorf("expected %s to be removed, got %v", kubeletArgsFile, err)
+			}
+		}
+		t.Run(st.name, tf)
+	}
+}
+
+func TestNewKubeletProvider(t *testing.T) {
+	for _, name := range []string{"", "minikube", "kind", "ack"} {
+		provider, err := NewKubeletProvider(name, KubeletProviderOptions{})
+		if err != nil {
+			t.Fatalf("could not create provider %q: %v", name, err)
+		}
+		if _, ok := provider.(*kubeadmProvider); !ok {
+			t.Errorf("expected provider %q to be served by kubeadm, got %T", name, provider)
+		}
+	}
+
+	if _, err := NewKubeletProvider("unknown", KubeletProviderOptions{}); err == nil {
+		t.Errorf("expected an error for an unknown provider")
+	}
+}
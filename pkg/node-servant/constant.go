@@ -30,6 +30,14 @@ const (
 	//ConvertPreflightJobNameBase is the prefix of the preflight-convert ServantJob name
 	ConvertPreflightJobNameBase = "node-servant-preflight-convert"
 
+	// UpgradeJobNameBase is the prefix of the upgrade ServantJob name
+	UpgradeJobNameBase = "node-servant-upgrade"
+	// UpgradeRollbackJobNameBase is the prefix of the ServantJob name which rolls back an upgrade
+	UpgradeRollbackJobNameBase = "node-servant-upgrade-rollback"
+
+	// DiagnoseJobNameBase is the prefix of the diagnose ServantJob name
+	DiagnoseJobNameBase = "node-servant-diagnose"
+
 	// ConvertServantJobTemplate defines the dcpctl convert servant job in yaml format
 	ConvertServantJobTemplate = `
 apiVersion: batch/v1
@@ -57,7 +65,7 @@ spec:
         - /bin/sh
         - -c
         args:
-        - "/usr/local/bin/entry.sh convert --working-mode {{.working_mode}} --dcpsvr-image {{.dcpsvr_image}} {{if .dcpsvr_healthcheck_timeout}}--dcpsvr-healthcheck-timeout {{.dcpsvr_healthcheck_timeout}} {{end}}--join-token {{.joinToken}}"
+        - "/usr/local/bin/entry.sh convert --working-mode {{.working_mode}} --dcpsvr-image {{.dcpsvr_image}} {{if .dcpsvr_healthcheck_timeout}}--dcpsvr-healthcheck-timeout {{.dcpsvr_healthcheck_timeout}} {{end}}--join-token {{.joinToken}}{{if .provider}} --provider {{.provider}}{{end}}"
         securityContext:
           privileged: true
         volumeMounts:
@@ -100,7 +108,7 @@ spec:
         - /bin/sh
         - -c
         args:
-        - "/usr/local/bin/entry.sh revert"
+        - "/usr/local/bin/entry.sh revert{{if .provider}} --provider {{.provider}}{{end}}"
         securityContext:
           privileged: true
         volumeMounts:
@@ -158,5 +166,94 @@ spec:
         - name: KUBELET_SVC
           value: {{.kubeadm_conf_path}}
           {{end}}
+`
+	// UpgradeServantJobTemplate defines the dcpctl upgrade servant job in yaml format
+	UpgradeServantJobTemplate = `
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: {{.jobName}}
+  namespace: kube-system
+spec:
+  template:
+    spec:
+      hostPID: true
+      hostNetwork: true
+      restartPolicy: OnFailure
+      nodeName: {{.nodeName}}
+      volumes:
+      - name: host-root
+        hostPath:
+          path: /
+          type: Directory
+      containers:
+      - name: node-servant
+        image: {{.node_servant_image}}
+        imagePullPolicy: IfNotPresent
+        command:
+        - /bin/sh
+        - -c
+        args:
+        - "/usr/local/bin/entry.sh upgrade {{if .rollback}}--rollback {{else}}--working-mode {{.working_mode}} --dcpsvr-image {{.dcpsvr_image}} --join-token {{.joinToken}} {{end}}{{if .provider}}--provider {{.provider}} {{end}}{{if .upgrade_id}}--upgrade-id {{.upgrade_id}} {{end}}{{if .dcpsvr_healthcheck_timeout}}--dcpsvr-healthcheck-timeout {{.dcpsvr_healthcheck_timeout}}{{end}}"
+        securityContext:
+          privileged: true
+        volumeMounts:
+        - mountPath: /bhojpur
+          name: host-root
+        env:
+        - name: NODE_NAME
+          valueFrom:
+            fieldRef:
+              fieldPath: spec.nodeName
+          {{if  .kubeadm_conf_path }}
+        - name: KUBELET_SVC
+          value: {{.kubeadm_conf_path}}
+          {{end}}
+`
+	// DiagnoseServantJobTemplate defines the dcpctl diagnose servant job in yaml format
+	DiagnoseServantJobTemplate = `
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: {{.jobName}}
+  namespace: kube-system
+spec:
+  backoffLimit: 0
+  template:
+    spec:
+      hostPID: true
+      hostNetwork: true
+      restartPolicy: Never
+      nodeName: {{.nodeName}}
+      tolerations:
+      - operator: Exists
+      volumes:
+      - name: host-root
+        hostPath:
+          path: /
+          type: Directory
+      containers:
+      - name: node-servant
+        image: {{.node_servant_image}}
+        imagePullPolicy: IfNotPresent
+        command:
+        - /bin/sh
+        - -c
+        args:
+        - "/usr/local/bin/entry.sh diagnose {{if .log_lines}}--log-lines {{.log_lines}} {{end}}"
+        securityContext:
+          privileged: true
+        volumeMounts:
+        - mountPath: /bhojpur
+          name: host-root
+        env:
+        - name: NODE_NAME
+          valueFrom:
+            fieldRef:
+              fieldPath: spec.nodeName
+          {{if  .kubeadm_conf_path }}
+        - name: KUBELET_SVC
+          value: {{.kubeadm_conf_path}}
+          {{end}}
 `
 )
@@ -46,10 +46,17 @@ func (n *nodeConverter) Do() error {
 		return err
 	}
 
-	if err := n.installEngine(); err != nil {
+	provider, err := components.NewKubeletProvider(n.provider, components.KubeletProviderOptions{
+		KubeadmConfPaths: n.kubeadmConfPaths,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := n.installEngine(provider); err != nil {
 		return err
 	}
-	if err := n.convertKubelet(); err != nil {
+	if err := n.convertKubelet(provider); err != nil {
 		return err
 	}
 
@@ -64,8 +71,8 @@ func (n *nodeConverter) validateOptions() error {
 	return nil
 }
 
-func (n *nodeConverter) installEngine() error {
-	apiServerAddress, err := components.GetApiServerAddress(n.kubeadmConfPaths)
+func (n *nodeConverter) installEngine(provider components.KubeletProvider) error {
+	apiServerAddress, err := provider.GetApiServerAddress()
 	if err != nil {
 		return err
 	}
@@ -73,11 +80,11 @@ func (n *nodeConverter) installEngine() error {
 		return fmt.Errorf("get apiServerAddress empty")
 	}
 	op := components.NewEngineOperator(apiServerAddress, n.engineImage, n.joinToken,
-		n.workingMode, n.engineHealthCheckTimeout)
+		n.workingMode, n.engineHealthCheckTimeout, provider.GetPodManifestPath())
 	return op.Install()
 }
 
-func (n *nodeConverter) convertKubelet() error {
-	op := components.NewKubeletOperator(n.bhojpurDir)
+func (n *nodeConverter) convertKubelet(provider components.KubeletProvider) error {
+	op := components.NewKubeletOperator(n.bhojpurDir, provider)
 	return op.RedirectTrafficToEngine()
 }
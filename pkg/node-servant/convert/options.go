@@ -42,6 +42,7 @@ type Options struct {
 	joinToken        string
 	kubeadmConfPaths []string
 	bhojpurDir       string
+	provider         string
 }
 
 // NewConvertOptions creates a new Options
@@ -73,6 +74,12 @@ func (o *Options) Complete(flags *pflag.FlagSet) error {
 		o.kubeadmConfPaths = strings.Split(kubeadmConfPaths, ",")
 	}
 
+	provider, err := flags.GetString("provider")
+	if err != nil {
+		return err
+	}
+	o.provider = provider
+
 	joinToken, err := flags.GetString("join-token")
 	if err != nil {
 		return err
@@ -0,0 +1,157 @@
+package diagnose
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+const (
+	// BundleBegin and BundleEnd enclose the bundle printed by the node-servant diagnose job
+	BundleBegin = "-----BEGIN DIAGNOSE BUNDLE-----"
+	BundleEnd   = "-----END DIAGNOSE BUNDLE-----"
+
+	// ProblemsFileName is the file of the bundle listing the problems detected, one per line
+	ProblemsFileName = "problems.txt"
+
+	bundleLineLength = 76
+	bundleFileMode   = 0644
+)
+
+// File is a file of the diagnose bundle
+type File struct {
+	Name    string
+	Content []byte
+}
+
+// WriteBundle writes the files as a gzipped tarball encoded in base64 between
+// BundleBegin and BundleEnd, so that it can be carried by the logs of the job.
+func WriteBundle(w io.Writer, files []File) error {
+	var buf bytes.Buffer
+	if err := WriteTarball(&buf, "", files, time.Now()); err != nil {
+		return err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(buf.Bytes())
+	var out strings.Builder
+	out.WriteString(BundleBegin + "\n")
+	for len(encoded) > bundleLineLength {
+		out.WriteString(encoded[:bundleLineLength] + "\n")
+		encoded = encoded[bundleLineLength:]
+	}
+	out.WriteString(encoded + "\n")
+	out.WriteString(BundleEnd + "\n")
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// ReadBundle extracts the files of the bundle written by WriteBundle from the logs.
+// Log lines interleaved with the bundle are skipped.
+func ReadBundle(logs string) ([]File, error) {
+	var encoded strings.Builder
+	begun, ended := false, false
+	scanner := bufio.NewScanner(strings.NewReader(logs))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == BundleBegin:
+			begun = true
+			encoded.Reset()
+		case line == BundleEnd && begun:
+			ended = true
+		case begun && !ended && line != "" && !strings.ContainsAny(line, " \t"):
+			encoded.WriteString(line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !begun || !ended {
+		return nil, fmt.Errorf("no diagnose bundle found in the logs")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded.String())
+	if err != nil {
+		return nil, fmt.Errorf("fail to decode the diagnose bundle: %v", err)
+	}
+	return readTarball(bytes.NewReader(data))
+}
+
+// WriteTarball writes the files under the dir prefix as a gzipped tarball.
+func WriteTarball(w io.Writer, prefix string, files []File, modTime time.Time) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	for _, f := range files {
+		name := f.Name
+		if prefix != "" {
+			name = prefix + "/" + name
+		}
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    bundleFileMode,
+			Size:    int64(len(f.Content)),
+			ModTime: modTime,
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(f.Content); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func readTarball(r io.Reader) ([]File, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+
+	var files []File
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: hdr.Name, Content: content})
+	}
+}
@@ -0,0 +1,341 @@
+package diagnose
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bhojpur/dcp/pkg/client/constants"
+	enutil "github.com/bhojpur/dcp/pkg/client/util/edgenode"
+	"github.com/bhojpur/dcp/pkg/engine/certificate/hubself"
+	"github.com/bhojpur/dcp/pkg/engine/storage/disk"
+	"github.com/bhojpur/dcp/pkg/preflight"
+	tunnelconstants "github.com/bhojpur/dcp/pkg/tunnel/constants"
+)
+
+const (
+	podLogsDir          = "/var/log/pods"
+	kubeAdmFlagsEnvFile = "/var/lib/kubelet/kubeadm-flags.env"
+	engineProxyServer   = "http://127.0.0.1:10261"
+
+	maxCacheDirEntries      = 5000
+	httpProbeTimeout        = 5 * time.Second
+	certExpiryWarningPeriod = 30 * 24 * time.Hour
+)
+
+var kubeletKubeconfigRegexp = regexp.MustCompile(`--kubeconfig=(\S+)`)
+
+// report holds the files collected on the node and the problems detected
+type report struct {
+	files    []File
+	problems []string
+}
+
+func (r *report) addFile(name string, content []byte) {
+	r.files = append(r.files, File{Name: name, Content: content})
+}
+
+func (r *report) addProblem(format string, args ...interface{}) {
+	r.problems = append(r.problems, fmt.Sprintf(format, args...))
+}
+
+// collectEngineManifest collects the yaml of the dcpsvr static pod
+func collectEngineManifest(r *report) {
+	engineYamlPath := filepath.Join(enutil.GetPodManifestPath(), enutil.EngineYamlName)
+	content, err := ioutil.ReadFile(engineYamlPath)
+	if err != nil {
+		r.addProblem("dcpsvr static pod %s can not be read, the node may not be converted: %v", engineYamlPath, err)
+		return
+	}
+	r.addFile("dcpsvr.yaml", Redact(content))
+}
+
+// collectEngineLogs collects the last lines of the latest log file of the dcpsvr pod,
+// and checks the healthz of dcpsvr.
+func collectEngineLogs(r *report, lines int) {
+	logFiles, _ := filepath.Glob(filepath.Join(podLogsDir,
+		enutil.EngineNamespace+"_"+enutil.EngineComponentName+"-*", enutil.EngineComponentName, "*.log"))
+	var latest string
+	var latestModTime time.Time
+	for _, f := range logFiles {
+		if info, err := os.Stat(f); err == nil && info.ModTime().After(latestModTime) {
+			latest, latestModTime = f, info.ModTime()
+		}
+	}
+	if latest == "" {
+		r.addProblem("no log of dcpsvr is found in %s", podLogsDir)
+	} else if content, err := tailFile(latest, lines); err != nil {
+		r.addProblem("log of dcpsvr %s can not be read: %v", latest, err)
+	} else {
+		r.addFile("dcpsvr.log", Redact(content))
+	}
+
+	healthzURL := fmt.Sprintf("http://%s%s", enutil.ServerHealthzServer, enutil.ServerHealthzURLPath)
+	if _, err := httpGet(healthzURL); err != nil {
+		r.addProblem("dcpsvr is not healthy, %s: %v", healthzURL, err)
+	}
+}
+
+// collectCacheDir lists the cache of dcpsvr
+func collectCacheDir(r *report) {
+	var buf bytes.Buffer
+	entries := 0
+	err := filepath.Walk(disk.CacheBaseDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if entries >= maxCacheDirEntries {
+			return filepath.SkipDir
+		}
+		entries++
+		fmt.Fprintf(&buf, "%s %10d %s %s\n", info.Mode(), info.Size(),
+			info.ModTime().Format(time.RFC3339), path)
+		return nil
+	})
+	if err != nil {
+		r.addProblem("cache dir %s of dcpsvr can not be listed: %v", disk.CacheBaseDir, err)
+	}
+	if entries >= maxCacheDirEntries {
+		fmt.Fprintf(&buf, "... more than %d entries, the others are not listed\n", maxCacheDirEntries)
+	}
+	r.addFile("cache-dir.txt", buf.Bytes())
+}
+
+// collectKubeletKubeconfig collects the kubeconfig used by the kubelet, and checks that
+// the kubelet visits the apiserver through dcpsvr once the node is converted.
+func collectKubeletKubeconfig(r *report) {
+	kubeconfigPath := enutil.KubeCondfigPath
+	if flags, err := ioutil.ReadFile(kubeAdmFlagsEnvFile); err == nil {
+		// flags set later override the ones before
+		if found := kubeletKubeconfigRegexp.FindAllSubmatch(flags, -1); len(found) != 0 {
+			kubeconfigPath = string(found[len(found)-1][1])
+		}
+	}
+
+	content, err := ioutil.ReadFile(kubeconfigPath)
+	if err != nil {
+		r.addProblem("kubeconfig of kubelet %s can not be read: %v", kubeconfigPath, err)
+		return
+	}
+	var servers []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if line = strings.TrimSpace(line); strings.HasPrefix(line, "server:") {
+			servers = append(servers, strings.TrimSpace(strings.TrimPrefix(line, "server:")))
+		}
+	}
+	header := fmt.Sprintf("# kubeconfig: %s\n# server: %s\n", kubeconfigPath, strings.Join(servers, ", "))
+	r.addFile("kubelet-kubeconfig.yaml", append([]byte(header), Redact(content)...))
+
+	engineYamlPath := filepath.Join(enutil.GetPodManifestPath(), enutil.EngineYamlName)
+	if exist, _ := enutil.FileExists(engineYamlPath); !exist {
+		return
+	}
+	for _, server := range servers {
+		if server != engineProxyServer {
+			r.addProblem("kubelet visits the apiserver through %s instead of dcpsvr(%s)", server, engineProxyServer)
+		}
+	}
+}
+
+// collectTunnelAgentStatus probes the meta server of tunnel-agent, and keeps its tunnel metrics
+func collectTunnelAgentStatus(r *report) {
+	metricsURL := fmt.Sprintf("http://127.0.0.1:%s/metrics", tunnelconstants.TunnelAgentMetaPort)
+	body, err := httpGet(metricsURL)
+	if err != nil {
+		r.addFile("tunnel-agent.txt", []byte(fmt.Sprintf("tunnel-agent is not reachable at %s: %v\n", metricsURL, err)))
+		return
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "tunnel-agent is reachable at %s\n", metricsURL)
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "tunnel") {
+			buf.WriteString(line + "\n")
+		}
+	}
+	r.addFile("tunnel-agent.txt", buf.Bytes())
+}
+
+// collectIptables collects the TUNNEL-PORT chains set by tunnel-server
+func collectIptables(r *report) {
+	out, err := exec.Command("iptables-save", "-t", "nat").CombinedOutput()
+	if err != nil {
+		r.addFile("iptables.txt", []byte(fmt.Sprintf("fail to run iptables-save: %v\n%s", err, out)))
+		return
+	}
+
+	var buf bytes.Buffer
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.Contains(line, "TUNNEL-PORT") {
+			buf.WriteString(line + "\n")
+		}
+	}
+	if buf.Len() == 0 {
+		buf.WriteString("no TUNNEL-PORT chain is found in the nat table\n")
+	}
+	r.addFile("iptables.txt", buf.Bytes())
+}
+
+// collectCertificates lists the expiry dates of the certificates of dcpsvr, kubelet,
+// kubernetes and tunnel-agent. Only the certificates are parsed, the keys are never read out.
+func collectCertificates(r *report, now time.Time) {
+	dirs := []string{
+		filepath.Join(hubself.EngineRootDir, hubself.EngineName, "pki"),
+		preflight.KubeletPkiDir,
+		filepath.Join(preflight.KubernetesDir, "pki"),
+		constants.TunnelAgentWorkdir,
+	}
+
+	var buf bytes.Buffer
+	for _, dir := range dirs {
+		_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return nil
+			}
+			if ext := filepath.Ext(path); ext != ".crt" && ext != ".pem" {
+				return nil
+			}
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+			for _, cert := range parseCertificates(content) {
+				fmt.Fprintf(&buf, "%s %s %q\n", cert.NotAfter.Format(time.RFC3339), path, cert.Subject.String())
+				if problem := checkCertificateExpiry(cert, now); problem != "" {
+					r.addProblem("certificate %s(%s) %s", path, cert.Subject.CommonName, problem)
+				}
+			}
+			return nil
+		})
+	}
+	if buf.Len() == 0 {
+		buf.WriteString("no certificate is found\n")
+	}
+	r.addFile("certificates.txt", buf.Bytes())
+}
+
+// collectPreflight runs the preflight checks of the files and dirs required on the node.
+// The checks of the ports are skipped, the ports are used by the components once converted.
+func collectPreflight(r *report, kubeadmConfPaths []string) {
+	checks := []preflight.Checker{
+		preflight.FileAtLeastOneExistingCheck{Paths: kubeadmConfPaths, Label: "KubeadmConfig"},
+		preflight.FileExistingCheck{Path: kubeAdmFlagsEnvFile, Label: "KubeAdmFlagsEnv"},
+		preflight.DirExistingCheck{Path: preflight.KubernetesDir},
+		preflight.DirExistingCheck{Path: preflight.KubeletPkiDir},
+	}
+
+	var buf bytes.Buffer
+	for _, c := range checks {
+		warnings, errs := c.Check()
+		for _, w := range warnings {
+			fmt.Fprintf(&buf, "[WARNING %s]: %v\n", c.Name(), w)
+		}
+		for _, e := range errs {
+			fmt.Fprintf(&buf, "[ERROR %s]: %v\n", c.Name(), e)
+			r.addProblem("preflight check %s failed: %v", c.Name(), e)
+		}
+		if len(warnings) == 0 && len(errs) == 0 {
+			fmt.Fprintf(&buf, "[OK %s]\n", c.Name())
+		}
+	}
+	r.addFile("preflight.txt", buf.Bytes())
+}
+
+func parseCertificates(content []byte) []*x509.Certificate {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, content = pem.Decode(content)
+		if block == nil {
+			return certs
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+}
+
+// checkCertificateExpiry returns the problem of the certificate if it has expired
+// or expires soon, or an empty string.
+func checkCertificateExpiry(cert *x509.Certificate, now time.Time) string {
+	switch {
+	case now.After(cert.NotAfter):
+		return fmt.Sprintf("expired at %s", cert.NotAfter.Format(time.RFC3339))
+	case now.Add(certExpiryWarningPeriod).After(cert.NotAfter):
+		return fmt.Sprintf("expires soon at %s", cert.NotAfter.Format(time.RFC3339))
+	case now.Before(cert.NotBefore):
+		return fmt.Sprintf("is not valid before %s", cert.NotBefore.Format(time.RFC3339))
+	}
+	return ""
+}
+
+func tailFile(path string, lines int) ([]byte, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	all := strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	if lines > 0 && len(all) > lines {
+		all = all[len(all)-lines:]
+	}
+	return []byte(strings.Join(all, "\n") + "\n"), nil
+}
+
+func httpGet(url string) ([]byte, error) {
+	client := &http.Client{Timeout: httpProbeTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("response status code is %d", resp.StatusCode)
+	}
+	return body, nil
+}
+
+// problemsFile returns the content of ProblemsFileName
+func (r *report) problemsFile() []byte {
+	if len(r.problems) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(r.problems, "\n") + "\n")
+}
@@ -0,0 +1,66 @@
+package diagnose
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"io"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// nodeDiagnoser do the diagnose job
+type nodeDiagnoser struct {
+	Options
+}
+
+// NewDiagnoserWithOptions creates nodeDiagnoser
+func NewDiagnoserWithOptions(o *Options) *nodeDiagnoser {
+	return &nodeDiagnoser{
+		*o,
+	}
+}
+
+// Do collects the diagnostics of the node, and writes them to w as a bundle.
+// It only reads the node, the problems detected are reported in the bundle.
+func (n *nodeDiagnoser) Do(w io.Writer) error {
+	r := &report{}
+
+	klog.Info("collecting the dcpsvr static pod, logs and cache")
+	collectEngineManifest(r)
+	collectEngineLogs(r, n.logLines)
+	collectCacheDir(r)
+
+	klog.Info("collecting the kubeconfig of kubelet")
+	collectKubeletKubeconfig(r)
+
+	klog.Info("collecting the status of tunnel-agent and the TUNNEL-PORT chains")
+	collectTunnelAgentStatus(r)
+	collectIptables(r)
+
+	klog.Info("collecting the certificates and running the preflight checks")
+	collectCertificates(r, time.Now())
+	collectPreflight(r, n.kubeadmConfPaths)
+
+	r.addFile(ProblemsFileName, r.problemsFile())
+	klog.Infof("%d problems are detected", len(r.problems))
+	return WriteBundle(w, r.files)
+}
@@ -0,0 +1,67 @@
+package diagnose
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	"github.com/bhojpur/dcp/pkg/node-servant/components"
+)
+
+const (
+	// DefaultLogLines is the default number of the lines of dcpsvr logs collected
+	DefaultLogLines = 500
+)
+
+// Options has the information that required by diagnose operation
+type Options struct {
+	logLines         int
+	kubeadmConfPaths []string
+}
+
+// NewDiagnoseOptions creates a new Options
+func NewDiagnoseOptions() *Options {
+	return &Options{
+		logLines:         DefaultLogLines,
+		kubeadmConfPaths: components.GetDefaultKubeadmConfPath(),
+	}
+}
+
+// Complete completes all the required options.
+func (o *Options) Complete(flags *pflag.FlagSet) error {
+	logLines, err := flags.GetInt("log-lines")
+	if err != nil {
+		return err
+	}
+	o.logLines = logLines
+
+	kubeadmConfPaths, err := flags.GetString("kubeadm-conf-path")
+	if err != nil {
+		return err
+	}
+	if kubeadmConfPaths != "" {
+		o.kubeadmConfPaths = strings.Split(kubeadmConfPaths, ",")
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package diagnose
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"regexp"
+)
+
+const redacted = "<redacted>"
+
+var (
+	// secretKeyValueRegexp matches the values of keys like token, password or client-key-data
+	// in yaml, json, flags and env files.
+	secretKeyValueRegexp = regexp.MustCompile(
+		`(?i)([\w.-]*(?:token|password|passwd|secret|key-data|certificate-data|authority-data)[\w.-]*["']?\s*[:=]\s*["']?)[^\s"',]+`)
+	// secretFlagRegexp matches the values of flags like --join-token separated by a space.
+	secretFlagRegexp = regexp.MustCompile(`(?i)(--[\w-]*(?:token|password|secret)[\w-]*\s+)[^\s"',-][^\s"',]*`)
+	// bearerRegexp matches bearer tokens in the logs of requests.
+	bearerRegexp = regexp.MustCompile(`(?i)(bearer\s+)[^\s"',]+`)
+)
+
+// Redact replaces the secrets in the content, like tokens, passwords and the
+// data of the certificates and keys, with <redacted>.
+func Redact(content []byte) []byte {
+	for _, re := range []*regexp.Regexp{secretKeyValueRegexp, secretFlagRegexp, bearerRegexp} {
+		content = re.ReplaceAll(content, []byte("${1}"+redacted))
+	}
+	return content
+}
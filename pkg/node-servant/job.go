@@ -49,6 +49,17 @@ func RenderNodeServantJob(action string, tmplCtx map[string]string, nodeName str
 	case "preflight-convert":
 		servantJobTemplate = ConvertPreflightJobTemplate
 		jobBaseName = ConvertPreflightJobNameBase
+	case "upgrade":
+		servantJobTemplate = UpgradeServantJobTemplate
+		jobBaseName = UpgradeJobNameBase
+		delete(tmplCtx, "rollback")
+	case "upgrade-rollback":
+		servantJobTemplate = UpgradeServantJobTemplate
+		jobBaseName = UpgradeRollbackJobNameBase
+		tmplCtx["rollback"] = "true"
+	case "diagnose":
+		servantJobTemplate = DiagnoseServantJobTemplate
+		jobBaseName = DiagnoseJobNameBase
 	}
 
 	tmplCtx["jobName"] = jobBaseName + "-" + nodeName
@@ -95,6 +106,15 @@ func validate(action string, tmplCtx map[string]string, nodeName string) error {
 	case "preflight-convert":
 		keysMustHave := []string{"node_servant_image"}
 		return checkKeys(keysMustHave, tmplCtx)
+	case "upgrade":
+		keysMustHave := []string{"node_servant_image", "dcpsvr_image", "joinToken", "working_mode"}
+		return checkKeys(keysMustHave, tmplCtx)
+	case "upgrade-rollback":
+		keysMustHave := []string{"node_servant_image"}
+		return checkKeys(keysMustHave, tmplCtx)
+	case "diagnose":
+		keysMustHave := []string{"node_servant_image"}
+		return checkKeys(keysMustHave, tmplCtx)
 	default:
 		return fmt.Errorf("action invalied: %s ", action)
 	}
@@ -33,6 +33,7 @@ type Options struct {
 	kubeadmConfPath string
 	dcpDir          string
 	nodeName        string
+	provider        string
 }
 
 // NewRevertOptions creates a new Options
@@ -55,6 +56,12 @@ func (o *Options) Complete(flags *pflag.FlagSet) error {
 	}
 	o.kubeadmConfPath = kubeadmConfPath
 
+	provider, err := flags.GetString("provider")
+	if err != nil {
+		return err
+	}
+	o.provider = provider
+
 	nodeName, err := enutil.GetNodeName(kubeadmConfPath)
 	if err != nil {
 		return err
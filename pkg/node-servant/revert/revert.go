@@ -42,24 +42,30 @@ func NewReverterWithOptions(o *Options) *nodeReverter {
 // Do, do the convert job
 // shall be implemented as idempotent, can execute multiple times with no side-affect.
 func (n *nodeReverter) Do() error {
+	provider, err := components.NewKubeletProvider(n.provider, components.KubeletProviderOptions{
+		KubeadmConfPaths: []string{n.kubeadmConfPath},
+	})
+	if err != nil {
+		return err
+	}
 
-	if err := n.revertKubelet(); err != nil {
+	if err := n.revertKubelet(provider); err != nil {
 		return err
 	}
-	if err := n.uninstallEngine(); err != nil {
+	if err := n.uninstallEngine(provider); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (n *nodeReverter) revertKubelet() error {
-	op := components.NewKubeletOperator(n.dcpDir)
+func (n *nodeReverter) revertKubelet(provider components.KubeletProvider) error {
+	op := components.NewKubeletOperator(n.dcpDir, provider)
 	return op.UndoRedirectTrafficToEngine()
 }
 
-func (n *nodeReverter) uninstallEngine() error {
+func (n *nodeReverter) uninstallEngine(provider components.KubeletProvider) error {
 	op := components.NewEngineOperator("", "", "",
-		util.WorkingModeCloud, time.Duration(1)) // params is not important here
+		util.WorkingModeCloud, time.Duration(1), provider.GetPodManifestPath()) // only the pod manifest path is important here
 	return op.UnInstall()
 }
@@ -0,0 +1,74 @@
+package upgrade
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"fmt"
+
+	"github.com/bhojpur/dcp/pkg/node-servant/components"
+)
+
+// nodeUpgrader do the upgrade job
+type nodeUpgrader struct {
+	Options
+}
+
+// NewUpgraderWithOptions creates nodeUpgrader
+func NewUpgraderWithOptions(o *Options) *nodeUpgrader {
+	return &nodeUpgrader{
+		*o,
+	}
+}
+
+// Do, do the upgrade job, or roll back the last upgrade if required.
+// shall be implemented as idempotent, can execute multiple times with no side-affect.
+func (n *nodeUpgrader) Do() error {
+	provider, err := components.NewKubeletProvider(n.provider, components.KubeletProviderOptions{
+		KubeadmConfPaths: n.kubeadmConfPaths,
+	})
+	if err != nil {
+		return err
+	}
+
+	if n.rollback {
+		return n.rollbackEngine(provider)
+	}
+	return n.upgradeEngine(provider)
+}
+
+func (n *nodeUpgrader) upgradeEngine(provider components.KubeletProvider) error {
+	apiServerAddress, err := provider.GetApiServerAddress()
+	if err != nil {
+		return err
+	}
+	if apiServerAddress == "" {
+		return fmt.Errorf("get apiServerAddress empty")
+	}
+	op := components.NewEngineOperator(apiServerAddress, n.engineImage, n.joinToken,
+		n.workingMode, n.engineHealthCheckTimeout, provider.GetPodManifestPath())
+	return op.Upgrade(n.upgradeID)
+}
+
+func (n *nodeUpgrader) rollbackEngine(provider components.KubeletProvider) error {
+	op := components.NewEngineOperator("", "", "",
+		n.workingMode, n.engineHealthCheckTimeout, provider.GetPodManifestPath()) // only the health check timeout and the pod manifest path are used here
+	return op.Rollback(n.upgradeID)
+}
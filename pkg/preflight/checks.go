@@ -21,7 +21,6 @@ package preflight
 // THE SOFTWARE.
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -163,7 +162,7 @@ func (jc JobExistCheck) Check() (warnings, errorList []error) {
 // After the job is successfully executed, it will be deleted.
 // The failed job will not be deleted, and the user needs to delete it manually.
 type NodeServantJobCheck struct {
-	cliSet *kubernetes.Clientset
+	cliSet kubernetes.Interface
 	jobLst []*batchv1.Job
 
 	waitServantJobTimeout time.Duration
@@ -356,7 +355,7 @@ func (ipc ImagePullCheck) Check() (warnings, errorList []error) {
 }
 
 // RunConvertClusterChecks excutes all cluster-level checks.
-func RunConvertClusterChecks(cliSet *kubernetes.Clientset, ignorePreflightErrors sets.String) error {
+func RunConvertClusterChecks(cliSet kubernetes.Interface, ignorePreflightErrors sets.String) error {
 	nodeLst, err := cliSet.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
 	if err != nil {
 		return err
@@ -379,7 +378,7 @@ func RunConvertClusterChecks(cliSet *kubernetes.Clientset, ignorePreflightErrors
 
 // RunNodeServantJobCheck runs preflight-check job for each node.
 func RunNodeServantJobCheck(
-	cliSet *kubernetes.Clientset, jobLst []*batchv1.Job,
+	cliSet kubernetes.Interface, jobLst []*batchv1.Job,
 	waitServantJobTimeout time.Duration, checkServantJobPeriod time.Duration,
 	ignorePreflightErrors sets.String) error {
 
@@ -437,29 +436,9 @@ func RunPullImagesCheck(o ImageOperator, ignorePreflightErrors sets.String) erro
 // RunChecks runs each check, displays it's warnings/errors, and once all
 // are processed will exit if any errors occurred.
 func RunChecks(checks []Checker, ww io.Writer, ignorePreflightErrors sets.String) error {
-	var errsBuffer bytes.Buffer
-
-	for _, c := range checks {
-		name := c.Name()
-		warnings, errs := c.Check()
-
-		if setHasItemOrAll(ignorePreflightErrors, name) {
-			// Decrease severity of errors to warnings for this check
-			warnings = append(warnings, errs...)
-			errs = []error{}
-		}
-
-		for _, w := range warnings {
-			io.WriteString(ww, fmt.Sprintf("\t[WARNING %s]: %v\n", name, w))
-		}
-		for _, i := range errs {
-			errsBuffer.WriteString(fmt.Sprintf("\t[ERROR %s]: %v\n", name, i.Error()))
-		}
-	}
-	if errsBuffer.Len() > 0 {
-		return &Error{Msg: errsBuffer.String()}
-	}
-	return nil
+	report := RunChecksWithReport(checks, ignorePreflightErrors)
+	report.WriteWarnings(ww)
+	return report.Err()
 }
 
 // setHasItemOrAll is helper function that return true if item is present in the set (case insensitive) or special key 'all' is present
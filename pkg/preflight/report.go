@@ -0,0 +1,191 @@
+package preflight
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+)
+
+// Severity is the severity of the result of a check
+type Severity string
+
+const (
+	SeverityOK      Severity = "ok"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+
+	// OutputText, OutputJSON and OutputYAML are the formats of a Report
+	OutputText = "text"
+	OutputJSON = "json"
+	OutputYAML = "yaml"
+)
+
+// Remediator is implemented by the checks which can tell how to fix what they find.
+type Remediator interface {
+	Remediation() string
+}
+
+// CheckResult is the result of a check
+type CheckResult struct {
+	Name     string   `json:"name"`
+	Severity Severity `json:"severity"`
+	// Ignored is true if the errors of the check are shown as warnings by ignorePreflightErrors
+	Ignored     bool            `json:"ignored,omitempty"`
+	Warnings    []string        `json:"warnings,omitempty"`
+	Errors      []string        `json:"errors,omitempty"`
+	Remediation string          `json:"remediation,omitempty"`
+	Duration    metav1.Duration `json:"duration"`
+}
+
+// Report is the machine-readable result of a run of checks
+type Report struct {
+	StartTime metav1.Time     `json:"startTime"`
+	Duration  metav1.Duration `json:"duration"`
+	Passed    bool            `json:"passed"`
+	Results   []CheckResult   `json:"results"`
+}
+
+// RunChecksWithReport runs each check and returns the report of them, the errors of the
+// checks in ignorePreflightErrors are reported as warnings.
+func RunChecksWithReport(checks []Checker, ignorePreflightErrors sets.String) *Report {
+	start := time.Now()
+	report := &Report{StartTime: metav1.NewTime(start), Passed: true}
+	for _, c := range checks {
+		checkStart := time.Now()
+		warnings, errs := c.Check()
+		result := CheckResult{
+			Name:     c.Name(),
+			Duration: metav1.Duration{Duration: time.Since(checkStart)},
+		}
+
+		if len(errs) != 0 && setHasItemOrAll(ignorePreflightErrors, result.Name) {
+			// Decrease severity of errors to warnings for this check
+			warnings = append(warnings, errs...)
+			errs = nil
+			result.Ignored = true
+		}
+		for _, w := range warnings {
+			result.Warnings = append(result.Warnings, w.Error())
+		}
+		for _, e := range errs {
+			result.Errors = append(result.Errors, e.Error())
+		}
+
+		switch {
+		case len(errs) != 0:
+			result.Severity = SeverityError
+			report.Passed = false
+		case len(warnings) != 0:
+			result.Severity = SeverityWarning
+		default:
+			result.Severity = SeverityOK
+		}
+		if r, ok := c.(Remediator); ok && result.Severity != SeverityOK {
+			result.Remediation = r.Remediation()
+		}
+		report.Results = append(report.Results, result)
+	}
+	report.Duration = metav1.Duration{Duration: time.Since(start)}
+	return report
+}
+
+// Err returns the errors of the checks as an *Error, or nil if all checks passed.
+func (r *Report) Err() error {
+	var errsBuffer bytes.Buffer
+	for _, result := range r.Results {
+		for _, e := range result.Errors {
+			errsBuffer.WriteString(fmt.Sprintf("\t[ERROR %s]: %v\n", result.Name, e))
+		}
+	}
+	if errsBuffer.Len() > 0 {
+		return &Error{Msg: errsBuffer.String()}
+	}
+	return nil
+}
+
+// WriteWarnings writes the warnings of the checks in the format RunChecks always used.
+func (r *Report) WriteWarnings(ww io.Writer) {
+	for _, result := range r.Results {
+		for _, w := range result.Warnings {
+			io.WriteString(ww, fmt.Sprintf("\t[WARNING %s]: %v\n", result.Name, w))
+		}
+	}
+}
+
+// Write writes the report in the output format: text, json or yaml.
+func (r *Report) Write(w io.Writer, output string) error {
+	switch output {
+	case OutputJSON:
+		content, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(content, '\n'))
+		return err
+	case OutputYAML:
+		content, err := yaml.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(content)
+		return err
+	case OutputText, "":
+		return r.writeText(w)
+	default:
+		return fmt.Errorf("unsupported output format %q, valid formats are: %s, %s, %s",
+			output, OutputText, OutputJSON, OutputYAML)
+	}
+}
+
+func (r *Report) writeText(w io.Writer) error {
+	var buf bytes.Buffer
+	for _, result := range r.Results {
+		fmt.Fprintf(&buf, "[%s %s] (%s)\n", map[Severity]string{
+			SeverityOK:      "OK",
+			SeverityWarning: "WARNING",
+			SeverityError:   "ERROR",
+		}[result.Severity], result.Name, result.Duration.Round(time.Millisecond))
+		for _, e := range result.Errors {
+			fmt.Fprintf(&buf, "\t%s\n", e)
+		}
+		for _, warning := range result.Warnings {
+			fmt.Fprintf(&buf, "\t%s\n", warning)
+		}
+		if result.Remediation != "" {
+			fmt.Fprintf(&buf, "\tremediation: %s\n", result.Remediation)
+		}
+	}
+	if r.Passed {
+		buf.WriteString("[preflight] All checks passed\n")
+	} else {
+		buf.WriteString("[preflight] Some checks failed\n")
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,177 @@
+package preflight
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+type fakeCheck struct {
+	name     string
+	warnings []error
+	errors   []error
+}
+
+func (fc fakeCheck) Name() string {
+	return fc.name
+}
+
+func (fc fakeCheck) Check() (warnings, errorList []error) {
+	return fc.warnings, fc.errors
+}
+
+func (fc fakeCheck) Remediation() string {
+	return "fix " + fc.name
+}
+
+func TestRunChecksWithReport(t *testing.T) {
+	checks := []Checker{
+		fakeCheck{name: "Pass"},
+		fakeCheck{name: "Warn", warnings: []error{errors.New("warn")}},
+		fakeCheck{name: "Fail", errors: []error{errors.New("fail")}},
+		fakeCheck{name: "Ignored", errors: []error{errors.New("ignored")}},
+	}
+
+	report := RunChecksWithReport(checks, sets.NewString("ignored"))
+	if report.Passed {
+		t.Fatalf("report should not pass with the error of Fail")
+	}
+	expected := map[string]Severity{
+		"Pass":    SeverityOK,
+		"Warn":    SeverityWarning,
+		"Fail":    SeverityError,
+		"Ignored": SeverityWarning,
+	}
+	if len(report.Results) != len(expected) {
+		t.Fatalf("expect %d results, but got %d", len(expected), len(report.Results))
+	}
+	for _, r := range report.Results {
+		if r.Severity != expected[r.Name] {
+			t.Errorf("expect severity %s for %s, but got %s", expected[r.Name], r.Name, r.Severity)
+		}
+		if r.Severity == SeverityOK && r.Remediation != "" {
+			t.Errorf("expect no remediation for %s, but got %q", r.Name, r.Remediation)
+		}
+		if r.Severity != SeverityOK && r.Remediation != "fix "+r.Name {
+			t.Errorf("expect remediation for %s, but got %q", r.Name, r.Remediation)
+		}
+		if r.Ignored != (r.Name == "Ignored") {
+			t.Errorf("unexpected ignored %v for %s", r.Ignored, r.Name)
+		}
+	}
+	if err := report.Err(); err == nil {
+		t.Fatalf("expect an error from the report")
+	}
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf, OutputJSON); err != nil {
+		t.Fatalf("failed to write the report, %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("failed to decode the json report, %v", err)
+	}
+	if decoded.Passed || len(decoded.Results) != len(expected) || decoded.Results[2].Errors[0] != "fail" {
+		t.Fatalf("unexpected json report %s", buf.String())
+	}
+
+	if err := RunChecksWithReport(checks[:2], nil).Err(); err != nil {
+		t.Fatalf("expect no error from the report, but got %v", err)
+	}
+}
+
+func TestSysctlCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sysctl")
+	if err != nil {
+		t.Fatalf("failed to create the temp dir, %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if err := os.MkdirAll(filepath.Join(dir, "net/ipv4"), 0755); err != nil {
+		t.Fatalf("failed to create the sysctl dir, %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "net/ipv4/ip_forward"), []byte("0\n"), 0644); err != nil {
+		t.Fatalf("failed to write the sysctl, %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		expected map[string]string
+		errors   int
+	}{
+		{"matched", map[string]string{"net.ipv4.ip_forward": "0"}, 0},
+		{"mismatched", map[string]string{"net.ipv4.ip_forward": "1"}, 1},
+		{"missing", map[string]string{"net.ipv4.ip_forward": "0", "net.bridge.bridge-nf-call-iptables": "1"}, 1},
+	}
+
+	for _, tt := range tests {
+		st := tt
+		tf := func(t *testing.T) {
+			t.Parallel()
+			_, errs := SysctlCheck{Expected: st.expected, ProcSysDir: dir}.Check()
+			if len(errs) != st.errors {
+				t.Fatalf("expect %d errors, but got %v", st.errors, errs)
+			}
+		}
+		t.Run(st.name, tf)
+	}
+}
+
+func TestClockSkewCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		offset   time.Duration
+		err      error
+		warnings int
+		errors   int
+	}{
+		{"in sync", 0, nil, 0, 0},
+		{"ahead", time.Minute, nil, 0, 1},
+		{"behind", -time.Minute, nil, 0, 1},
+		{"unreachable", 0, errors.New("connection refused"), 1, 0},
+	}
+
+	for _, tt := range tests {
+		st := tt
+		tf := func(t *testing.T) {
+			t.Parallel()
+			csc := ClockSkewCheck{
+				MaxSkew: 5 * time.Second,
+				ServerTime: func() (time.Time, error) {
+					return time.Now().Add(st.offset), st.err
+				},
+			}
+			warnings, errs := csc.Check()
+			if len(warnings) != st.warnings || len(errs) != st.errors {
+				t.Fatalf("expect %d warnings and %d errors, but got %v and %v", st.warnings, st.errors, warnings, errs)
+			}
+		}
+		t.Run(st.name, tf)
+	}
+}
@@ -0,0 +1,461 @@
+package preflight
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+
+	"github.com/bhojpur/dcp/pkg/engine/storage/disk"
+)
+
+const (
+	defaultProcSysDir  = "/proc/sys"
+	defaultSysModule   = "/sys/module"
+	defaultProcModules = "/proc/modules"
+	defaultCgroupDir   = "/sys/fs/cgroup"
+
+	defaultMaxClockSkew = 5 * time.Second
+	dnsLookupTimeout    = 5 * time.Second
+)
+
+// SystemCheckOptions has the information that required by the system checks
+type SystemCheckOptions struct {
+	// ClientSet is used to check the clock skew against the apiserver, the check is skipped if nil.
+	ClientSet kubernetes.Interface
+	// KernelModules are the kernel modules which must be loaded or built in.
+	KernelModules []string
+	// Sysctls are the kernel parameters and their required values.
+	Sysctls map[string]string
+	// CgroupVersion is the required cgroup version, 0 accepts both v1 and v2.
+	CgroupVersion int
+	// MaxClockSkew is the max clock skew against the apiserver.
+	MaxClockSkew time.Duration
+	// EngineCacheDir and MinEngineCacheFree define the disk space required by the cache of dcpsvr.
+	EngineCacheDir     string
+	MinEngineCacheFree resource.Quantity
+	// TunnelServerAddress is resolved by the DNS check, the check is skipped if empty or an IP.
+	TunnelServerAddress string
+}
+
+// NewSystemCheckOptions creates a new SystemCheckOptions with the default requirements
+func NewSystemCheckOptions() *SystemCheckOptions {
+	return &SystemCheckOptions{
+		KernelModules: []string{"br_netfilter", "overlay"},
+		Sysctls: map[string]string{
+			"net.ipv4.ip_forward":                "1",
+			"net.bridge.bridge-nf-call-iptables": "1",
+		},
+		MaxClockSkew:       defaultMaxClockSkew,
+		EngineCacheDir:     disk.CacheBaseDir,
+		MinEngineCacheFree: resource.MustParse("1Gi"),
+	}
+}
+
+// CheckerFactory returns the check built with the options, or nil if it does not apply.
+type CheckerFactory func(o *SystemCheckOptions) Checker
+
+type namedCheckerFactory struct {
+	name    string
+	factory CheckerFactory
+}
+
+var (
+	systemCheckersLock sync.Mutex
+	systemCheckers     []namedCheckerFactory
+)
+
+// RegisterSystemChecker adds a check to the system checks, a check registered with the name
+// of an existing one replaces it. Checks run in the order they are registered.
+func RegisterSystemChecker(name string, factory CheckerFactory) {
+	systemCheckersLock.Lock()
+	defer systemCheckersLock.Unlock()
+	for i := range systemCheckers {
+		if systemCheckers[i].name == name {
+			systemCheckers[i].factory = factory
+			return
+		}
+	}
+	systemCheckers = append(systemCheckers, namedCheckerFactory{name: name, factory: factory})
+}
+
+// SystemCheckers returns the registered system checks which apply with the options
+func SystemCheckers(o *SystemCheckOptions) []Checker {
+	systemCheckersLock.Lock()
+	defer systemCheckersLock.Unlock()
+	var checks []Checker
+	for _, f := range systemCheckers {
+		if c := f.factory(o); c != nil {
+			checks = append(checks, c)
+		}
+	}
+	return checks
+}
+
+func init() {
+	RegisterSystemChecker("KernelModules", func(o *SystemCheckOptions) Checker {
+		if len(o.KernelModules) == 0 {
+			return nil
+		}
+		return KernelModulesCheck{Modules: o.KernelModules}
+	})
+	RegisterSystemChecker("CgroupVersion", func(o *SystemCheckOptions) Checker {
+		return CgroupVersionCheck{Version: o.CgroupVersion}
+	})
+	RegisterSystemChecker("Sysctls", func(o *SystemCheckOptions) Checker {
+		if len(o.Sysctls) == 0 {
+			return nil
+		}
+		return SysctlCheck{Expected: o.Sysctls}
+	})
+	RegisterSystemChecker("ClockSkew", func(o *SystemCheckOptions) Checker {
+		if o.ClientSet == nil {
+			return nil
+		}
+		return NewClockSkewCheck(o.ClientSet, o.MaxClockSkew)
+	})
+	RegisterSystemChecker("EngineCacheDiskSpace", func(o *SystemCheckOptions) Checker {
+		if o.EngineCacheDir == "" {
+			return nil
+		}
+		return DiskSpaceCheck{Path: o.EngineCacheDir, MinFree: o.MinEngineCacheFree, Label: "EngineCacheDiskSpace"}
+	})
+	RegisterSystemChecker("TunnelServerDNS", func(o *SystemCheckOptions) Checker {
+		if o.TunnelServerAddress == "" {
+			return nil
+		}
+		host, _, err := net.SplitHostPort(o.TunnelServerAddress)
+		if err != nil {
+			host = o.TunnelServerAddress
+		}
+		if net.ParseIP(host) != nil {
+			return nil
+		}
+		return DNSResolutionCheck{Host: host, Label: "TunnelServerDNS"}
+	})
+}
+
+// KernelModulesCheck checks that the kernel modules are loaded or built in.
+type KernelModulesCheck struct {
+	Modules []string
+	// SysModuleDir and ProcModules are /sys/module and /proc/modules if empty
+	SysModuleDir string
+	ProcModules  string
+}
+
+func (KernelModulesCheck) Name() string {
+	return "KernelModules"
+}
+
+func (kmc KernelModulesCheck) Check() (warnings, errorList []error) {
+	klog.V(1).Infof("validating kernel modules %s", kmc.Modules)
+	sysModuleDir, procModules := defaultSysModule, defaultProcModules
+	if kmc.SysModuleDir != "" {
+		sysModuleDir = kmc.SysModuleDir
+	}
+	if kmc.ProcModules != "" {
+		procModules = kmc.ProcModules
+	}
+
+	loaded := make(map[string]bool)
+	if content, err := ioutil.ReadFile(procModules); err == nil {
+		for _, line := range strings.Split(string(content), "\n") {
+			if fields := strings.Fields(line); len(fields) != 0 {
+				loaded[fields[0]] = true
+			}
+		}
+	}
+
+	var missing []string
+	for _, m := range kmc.Modules {
+		if loaded[m] {
+			continue
+		}
+		// built-in modules are not listed in /proc/modules
+		if _, err := os.Stat(filepath.Join(sysModuleDir, m)); err == nil {
+			continue
+		}
+		missing = append(missing, m)
+	}
+	if len(missing) != 0 {
+		return nil, []error{errors.Errorf("kernel modules %s are not loaded", missing)}
+	}
+	return nil, nil
+}
+
+func (kmc KernelModulesCheck) Remediation() string {
+	return fmt.Sprintf("load the modules with 'modprobe <module>', and add them to /etc/modules-load.d/ to load them at boot: %s",
+		strings.Join(kmc.Modules, ", "))
+}
+
+// CgroupVersionCheck checks the version of the cgroup mounted.
+type CgroupVersionCheck struct {
+	// Version is the required version, 0 accepts both v1 and v2.
+	Version int
+	// CgroupDir is /sys/fs/cgroup if empty
+	CgroupDir string
+}
+
+func (CgroupVersionCheck) Name() string {
+	return "CgroupVersion"
+}
+
+func (cvc CgroupVersionCheck) Check() (warnings, errorList []error) {
+	klog.V(1).Infoln("validating cgroup version")
+	cgroupDir := defaultCgroupDir
+	if cvc.CgroupDir != "" {
+		cgroupDir = cvc.CgroupDir
+	}
+
+	version, err := detectCgroupVersion(cgroupDir)
+	if err != nil {
+		return nil, []error{err}
+	}
+	klog.V(1).Infof("cgroup v%d is mounted on %s", version, cgroupDir)
+	if cvc.Version != 0 && version != cvc.Version {
+		return nil, []error{errors.Errorf("cgroup v%d is mounted, but cgroup v%d is required", version, cvc.Version)}
+	}
+	return nil, nil
+}
+
+func (cvc CgroupVersionCheck) Remediation() string {
+	return "mount the required cgroup version, with systemd.unified_cgroup_hierarchy=1 (v2) or 0 (v1) in the kernel command line"
+}
+
+// detectCgroupVersion returns 2 if the unified hierarchy is mounted, or 1 if the
+// controllers are mounted one by one.
+func detectCgroupVersion(cgroupDir string) (int, error) {
+	if _, err := os.Stat(filepath.Join(cgroupDir, "cgroup.controllers")); err == nil {
+		return 2, nil
+	}
+	entries, err := ioutil.ReadDir(cgroupDir)
+	if err != nil {
+		return 0, errors.Wrapf(err, "unable to read %s", cgroupDir)
+	}
+	for _, e := range entries {
+		if e.Name() == "cpu" || e.Name() == "memory" || strings.HasPrefix(e.Name(), "cpu,") {
+			return 1, nil
+		}
+	}
+	return 0, errors.Errorf("no cgroup is mounted on %s", cgroupDir)
+}
+
+// SysctlCheck checks the values of the kernel parameters.
+type SysctlCheck struct {
+	Expected map[string]string
+	// ProcSysDir is /proc/sys if empty
+	ProcSysDir string
+}
+
+func (SysctlCheck) Name() string {
+	return "Sysctls"
+}
+
+func (sc SysctlCheck) Check() (warnings, errorList []error) {
+	procSysDir := defaultProcSysDir
+	if sc.ProcSysDir != "" {
+		procSysDir = sc.ProcSysDir
+	}
+
+	for _, key := range sc.sortedKeys() {
+		klog.V(1).Infof("validating sysctl %s", key)
+		path := filepath.Join(procSysDir, strings.Replace(key, ".", "/", -1))
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			errorList = append(errorList, errors.Errorf("sysctl %s can not be read: %v", key, err))
+			continue
+		}
+		if val := strings.TrimSpace(string(content)); val != sc.Expected[key] {
+			errorList = append(errorList, errors.Errorf("sysctl %s is %s, but %s is required", key, val, sc.Expected[key]))
+		}
+	}
+	return nil, errorList
+}
+
+func (sc SysctlCheck) Remediation() string {
+	var settings []string
+	for _, key := range sc.sortedKeys() {
+		settings = append(settings, fmt.Sprintf("%s=%s", key, sc.Expected[key]))
+	}
+	return fmt.Sprintf("set them with 'sysctl -w', and add them to /etc/sysctl.d/ to keep them at boot: %s",
+		strings.Join(settings, " "))
+}
+
+func (sc SysctlCheck) sortedKeys() []string {
+	keys := make([]string, 0, len(sc.Expected))
+	for key := range sc.Expected {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ClockSkewCheck checks the clock skew of the node against the apiserver, which breaks
+// the validation of the certificates and the leases.
+type ClockSkewCheck struct {
+	MaxSkew time.Duration
+	// ServerTime returns the time of the apiserver
+	ServerTime func() (time.Time, error)
+}
+
+// NewClockSkewCheck creates a ClockSkewCheck reading the time from the Date header
+// of the responses of the apiserver.
+func NewClockSkewCheck(cliSet kubernetes.Interface, maxSkew time.Duration) ClockSkewCheck {
+	return ClockSkewCheck{
+		MaxSkew: maxSkew,
+		ServerTime: func() (time.Time, error) {
+			return getServerTime(cliSet)
+		},
+	}
+}
+
+func (ClockSkewCheck) Name() string {
+	return "ClockSkew"
+}
+
+func (csc ClockSkewCheck) Check() (warnings, errorList []error) {
+	klog.V(1).Infoln("validating clock skew against the apiserver")
+	before := time.Now()
+	serverTime, err := csc.ServerTime()
+	if err != nil {
+		return []error{errors.Wrap(err, "unable to get the time of the apiserver")}, nil
+	}
+	// the Date header is rounded down to the second
+	local := before.Add(time.Since(before) / 2).Truncate(time.Second)
+	skew := local.Sub(serverTime)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > csc.MaxSkew {
+		return nil, []error{errors.Errorf("the clock skew against the apiserver is %s, more than %s", skew, csc.MaxSkew)}
+	}
+	return nil, nil
+}
+
+func (ClockSkewCheck) Remediation() string {
+	return "synchronize the clock of the node with NTP, e.g. enable chronyd or systemd-timesyncd"
+}
+
+func getServerTime(cliSet kubernetes.Interface) (time.Time, error) {
+	restClient, ok := cliSet.Discovery().RESTClient().(*rest.RESTClient)
+	if !ok || restClient == nil || restClient.Client == nil {
+		return time.Time{}, errors.New("the client of the apiserver is not a rest client")
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet,
+		restClient.Get().AbsPath("/version").URL().String(), nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	resp, err := restClient.Client.Do(req)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer resp.Body.Close()
+	date := resp.Header.Get("Date")
+	if date == "" {
+		return time.Time{}, errors.New("no Date header in the response of the apiserver")
+	}
+	return http.ParseTime(date)
+}
+
+// DiskSpaceCheck checks the free space of the filesystem of the path.
+type DiskSpaceCheck struct {
+	Path    string
+	MinFree resource.Quantity
+	Label   string
+}
+
+func (dsc DiskSpaceCheck) Name() string {
+	if dsc.Label != "" {
+		return dsc.Label
+	}
+	return fmt.Sprintf("DiskSpace-%s", strings.Replace(dsc.Path, "/", "-", -1))
+}
+
+func (dsc DiskSpaceCheck) Check() (warnings, errorList []error) {
+	klog.V(1).Infof("validating the free disk space of %s", dsc.Path)
+	// the path may be created later, check the filesystem it will be created on
+	path := filepath.Clean(dsc.Path)
+	for {
+		if _, err := os.Stat(path); err == nil || path == filepath.Dir(path) {
+			break
+		}
+		path = filepath.Dir(path)
+	}
+
+	free, err := freeDiskSpace(path)
+	if err != nil {
+		return []error{errors.Wrapf(err, "unable to get the free disk space of %s", path)}, nil
+	}
+	if free < uint64(dsc.MinFree.Value()) {
+		return nil, []error{errors.Errorf("the free disk space of %s is %s, less than %s",
+			path, resource.NewQuantity(int64(free), resource.BinarySI), dsc.MinFree.String())}
+	}
+	return nil, nil
+}
+
+func (dsc DiskSpaceCheck) Remediation() string {
+	return fmt.Sprintf("free at least %s of disk space on the filesystem of %s", dsc.MinFree.String(), dsc.Path)
+}
+
+// DNSResolutionCheck checks that the host can be resolved.
+type DNSResolutionCheck struct {
+	Host  string
+	Label string
+}
+
+func (drc DNSResolutionCheck) Name() string {
+	if drc.Label != "" {
+		return drc.Label
+	}
+	return fmt.Sprintf("DNSResolution-%s", drc.Host)
+}
+
+func (drc DNSResolutionCheck) Check() (warnings, errorList []error) {
+	klog.V(1).Infof("validating DNS resolution of %s", drc.Host)
+	ctx, cancel := context.WithTimeout(context.Background(), dnsLookupTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, drc.Host)
+	if err != nil {
+		return nil, []error{errors.Wrapf(err, "unable to resolve %s", drc.Host)}
+	}
+	klog.V(1).Infof("%s is resolved to %s", drc.Host, addrs)
+	return nil, nil
+}
+
+func (drc DNSResolutionCheck) Remediation() string {
+	return fmt.Sprintf("make %s resolvable by the DNS servers in /etc/resolv.conf, or add it to /etc/hosts", drc.Host)
+}
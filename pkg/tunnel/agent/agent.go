@@ -22,6 +22,8 @@ package agent
 
 import (
 	"crypto/tls"
+
+	"google.golang.org/grpc/credentials"
 )
 
 // TunnelAgent sets up tunnel to TunnelServer, receive requests
@@ -34,7 +36,7 @@ type TunnelAgent interface {
 func NewTunnelAgent(tlsCfg *tls.Config,
 	tunnelServerAddr, nodeName, agentIdentifiers string) TunnelAgent {
 	ata := anpTunnelAgent{
-		tlsCfg:           tlsCfg,
+		creds:            credentials.NewTLS(tlsCfg),
 		tunnelServerAddr: tunnelServerAddr,
 		nodeName:         nodeName,
 		agentIdentifiers: agentIdentifiers,
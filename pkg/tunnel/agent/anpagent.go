@@ -21,7 +21,6 @@ package agent
 // THE SOFTWARE.
 
 import (
-	"crypto/tls"
 	"time"
 
 	"google.golang.org/grpc"
@@ -35,7 +34,7 @@ import (
 // anpTunnelAgent implements the TunnelAgent using the
 // apiserver-network-proxy package
 type anpTunnelAgent struct {
-	tlsCfg           *tls.Config
+	creds            credentials.TransportCredentials
 	tunnelServerAddr string
 	nodeName         string
 	agentIdentifiers string
@@ -45,7 +44,7 @@ var _ TunnelAgent = &anpTunnelAgent{}
 
 // RunAgent runs the tunnel-agent which will try to connect tunnel-server
 func (ata *anpTunnelAgent) Run(stopChan <-chan struct{}) {
-	dialOption := grpc.WithTransportCredentials(credentials.NewTLS(ata.tlsCfg))
+	dialOption := grpc.WithTransportCredentials(ata.creds)
 	cc := &anpagent.ClientSetConfig{
 		Address:                 ata.tunnelServerAddr,
 		AgentID:                 ata.nodeName,
@@ -0,0 +1,137 @@
+package agent
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc/credentials"
+	"k8s.io/klog/v2"
+)
+
+// rejectionThreshold is the number of consecutive rejections after which
+// the identity of the agent is considered rejected by the tunnel-server
+const rejectionThreshold = 3
+
+// rejectedAlerts are the tls alerts sent by a server that doesn't accept
+// the client certificate
+var rejectedAlerts = []string{
+	"bad certificate",
+	"unsupported certificate",
+	"revoked certificate",
+	"expired certificate",
+	"unknown certificate",
+	"unknown certificate authority",
+	"certificate required",
+	"access denied",
+}
+
+// rejectionTracker counts the consecutive connections whose client
+// certificate is rejected by the tunnel-server
+type rejectionTracker struct {
+	count int32
+}
+
+// observe records the result of a handshake or a read on a connection
+func (rt *rejectionTracker) observe(err error) {
+	if err == nil {
+		atomic.StoreInt32(&rt.count, 0)
+		return
+	}
+	if isIdentityRejected(err) {
+		klog.Warningf("client certificate rejected by the server, %v", err)
+		atomic.AddInt32(&rt.count, 1)
+	}
+}
+
+func (rt *rejectionTracker) rejected() bool {
+	return atomic.LoadInt32(&rt.count) >= rejectionThreshold
+}
+
+func (rt *rejectionTracker) reset() {
+	atomic.StoreInt32(&rt.count, 0)
+}
+
+// isIdentityRejected returns true if err is a tls alert sent by the server
+// because it doesn't accept the client certificate. With TLS 1.3 the alert
+// is received on the first read after the handshake.
+func isIdentityRejected(err error) bool {
+	msg := err.Error()
+	idx := strings.Index(msg, "remote error: tls: ")
+	if idx < 0 {
+		return false
+	}
+	alert := msg[idx+len("remote error: tls: "):]
+	for _, rejected := range rejectedAlerts {
+		if strings.HasPrefix(alert, rejected) {
+			return true
+		}
+	}
+	return false
+}
+
+// trackingCredentials wraps the transport credentials of a session to
+// report the rejections of the client certificate.
+type trackingCredentials struct {
+	credentials.TransportCredentials
+	tracker *rejectionTracker
+}
+
+func newTrackingCredentials(creds credentials.TransportCredentials, tracker *rejectionTracker) credentials.TransportCredentials {
+	return &trackingCredentials{
+		TransportCredentials: creds,
+		tracker:              tracker,
+	}
+}
+
+// ClientHandshake is part of credentials.TransportCredentials
+func (tc *trackingCredentials) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	conn, authInfo, err := tc.TransportCredentials.ClientHandshake(ctx, authority, rawConn)
+	if err != nil {
+		tc.tracker.observe(err)
+		return nil, nil, err
+	}
+	return &trackingConn{Conn: conn, tracker: tc.tracker}, authInfo, nil
+}
+
+// Clone is part of credentials.TransportCredentials
+func (tc *trackingCredentials) Clone() credentials.TransportCredentials {
+	return newTrackingCredentials(tc.TransportCredentials.Clone(), tc.tracker)
+}
+
+// trackingConn reports the result of the first read on a connection, which
+// is where a TLS 1.3 server rejects the client certificate.
+type trackingConn struct {
+	net.Conn
+	tracker  *rejectionTracker
+	observed int32
+}
+
+func (c *trackingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if atomic.CompareAndSwapInt32(&c.observed, 0, 1) {
+		c.tracker.observe(err)
+	}
+	return n, err
+}
@@ -0,0 +1,354 @@
+package agent
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/certificate"
+	"k8s.io/klog/v2"
+
+	"github.com/bhojpur/dcp/pkg/projectinfo"
+	"github.com/bhojpur/dcp/pkg/utils/certmanager"
+)
+
+const (
+	defaultIdentityCheckPeriod = 30 * time.Second
+	defaultSessionDrainPeriod  = 30 * time.Second
+)
+
+// IdentityConfig configures how the tunnel-agent keeps the client identity
+// that it uses to connect to the tunnel-server valid.
+type IdentityConfig struct {
+	NodeName         string
+	TunnelServerAddr string
+	AgentIdentifiers string
+	// CAFile is the CA bundle used to verify the tunnel-server, it's
+	// watched for CA rotation
+	CAFile string
+	// Client is used to request certificates
+	Client kubernetes.Interface
+	// BootstrapClient is used to request a new certificate when the identity
+	// of the agent is rejected by the tunnel-server, e.g. a clientset that
+	// authenticates with a join token. Client is used if it's nil.
+	BootstrapClient kubernetes.Interface
+	// NewCertManager creates a certificate manager that requests
+	// certificates with the given clientset
+	NewCertManager func(client kubernetes.Interface) (certificate.Manager, error)
+	// ResetCert removes the stored certificate, so the next certificate
+	// manager requests a new one
+	ResetCert func() error
+	// CheckPeriod is the period of checking the identity
+	CheckPeriod time.Duration
+	// DrainPeriod is how long the previous session is kept after a new
+	// session is set up, so in flight requests can complete
+	DrainPeriod time.Duration
+}
+
+// identityStatus is the result of checking the certificate of the agent
+type identityStatus string
+
+const (
+	identityValid     identityStatus = "valid"
+	identityMissing   identityStatus = "missing"
+	identityExpired   identityStatus = "expired"
+	identityMismatch  identityStatus = "node mismatch"
+	identityUntrusted identityStatus = "untrusted by the new CA"
+)
+
+// session is a connection of the agent to the tunnel-server
+type session struct {
+	fingerprint [sha256.Size]byte
+	stopCh      chan struct{}
+}
+
+// IdentityManager runs the tunnel-agent and rotates its client identity.
+// A new session is set up whenever the certificate or the CA bundle
+// changes, the previous session is closed after the drain period. The
+// certificate is requested again when the node is renamed or the CA
+// rotates, and requested with the bootstrap client when the tunnel-server
+// keeps rejecting it.
+type IdentityManager struct {
+	cfg        *IdentityConfig
+	certMgr    certificate.Manager
+	caData     []byte
+	session    *session
+	rejections *rejectionTracker
+	// startSession starts a session with creds that runs until stopCh is closed
+	startSession func(creds credentials.TransportCredentials, stopCh <-chan struct{})
+	now          func() time.Time
+	wg           sync.WaitGroup
+}
+
+// NewIdentityManager creates an IdentityManager
+func NewIdentityManager(cfg *IdentityConfig) *IdentityManager {
+	if cfg.CheckPeriod <= 0 {
+		cfg.CheckPeriod = defaultIdentityCheckPeriod
+	}
+	if cfg.DrainPeriod <= 0 {
+		cfg.DrainPeriod = defaultSessionDrainPeriod
+	}
+	im := &IdentityManager{
+		cfg:        cfg,
+		rejections: &rejectionTracker{},
+		now:        time.Now,
+	}
+	im.startSession = func(creds credentials.TransportCredentials, stopCh <-chan struct{}) {
+		ata := &anpTunnelAgent{
+			creds:            creds,
+			tunnelServerAddr: cfg.TunnelServerAddr,
+			nodeName:         cfg.NodeName,
+			agentIdentifiers: cfg.AgentIdentifiers,
+		}
+		ata.Run(stopCh)
+	}
+	return im
+}
+
+// Run waits for the certificate, connects to the tunnel-server and keeps the
+// identity valid until stopCh is closed.
+func (im *IdentityManager) Run(stopCh <-chan struct{}) error {
+	if err := im.startCertManager(im.cfg.Client); err != nil {
+		return err
+	}
+	defer func() {
+		im.certMgr.Stop()
+	}()
+
+	// a certificate for a renamed node may be left in the cert dir
+	_ = wait.PollImmediateUntil(time.Second, func() (bool, error) {
+		switch status := im.checkIdentity(im.certMgr.Current(), nil); status {
+		case identityValid:
+			return true, nil
+		case identityMissing:
+			klog.Infof("certificate %s not signed, waiting...", projectinfo.GetAgentName())
+		default:
+			klog.Infof("certificate %s is %s, request a new one", projectinfo.GetAgentName(), status)
+			if err := im.reissue(im.cfg.Client); err != nil {
+				klog.Errorf("failed to request a new certificate, %v", err)
+			}
+		}
+		return false, nil
+	}, stopCh)
+	klog.Infof("certificate %s ok", projectinfo.GetAgentName())
+
+	caData, err := ioutil.ReadFile(im.cfg.CAFile)
+	if err != nil {
+		return fmt.Errorf("failed to read CA file %s: %v", im.cfg.CAFile, err)
+	}
+	im.caData = caData
+	if err := im.reconnect(stopCh); err != nil {
+		return err
+	}
+
+	wait.Until(func() {
+		im.sync(stopCh)
+	}, im.cfg.CheckPeriod, stopCh)
+
+	if im.session != nil {
+		close(im.session.stopCh)
+	}
+	im.wg.Wait()
+	return nil
+}
+
+// sync checks the identity of the agent, requests a new certificate if it's
+// no longer usable and reconnects when the certificate or the CA changes.
+func (im *IdentityManager) sync(stopCh <-chan struct{}) {
+	if im.rejections.rejected() {
+		client := im.cfg.BootstrapClient
+		if client == nil {
+			client = im.cfg.Client
+		}
+		klog.Warningf("identity of %s is rejected by %s, bootstrap a new certificate",
+			projectinfo.GetAgentName(), projectinfo.GetServerName())
+		if err := im.reissue(client); err != nil {
+			klog.Errorf("failed to bootstrap a new certificate, %v", err)
+			return
+		}
+		im.rejections.reset()
+		return
+	}
+
+	caData, err := ioutil.ReadFile(im.cfg.CAFile)
+	if err != nil {
+		klog.Errorf("failed to read CA file %s, %v", im.cfg.CAFile, err)
+		caData = im.caData
+	}
+	caChanged := len(caData) != 0 && !bytes.Equal(caData, im.caData)
+
+	cert := im.certMgr.Current()
+	var newCA []byte
+	if caChanged {
+		newCA = caData
+	}
+	switch status := im.checkIdentity(cert, newCA); status {
+	case identityMissing:
+		// the certificate manager is requesting a new certificate
+		return
+	case identityValid:
+	default:
+		klog.Infof("certificate %s is %s, request a new one", projectinfo.GetAgentName(), status)
+		client := im.cfg.Client
+		if status == identityExpired && im.cfg.BootstrapClient != nil {
+			// the expired certificate can't be renewed by the agent itself
+			client = im.cfg.BootstrapClient
+		}
+		if err := im.reissue(client); err != nil {
+			klog.Errorf("failed to request a new certificate, %v", err)
+		}
+		return
+	}
+
+	if caChanged {
+		klog.Infof("CA bundle %s changed, reconnect to %s", im.cfg.CAFile, projectinfo.GetServerName())
+		im.caData = caData
+	} else if im.session != nil && im.session.fingerprint == sha256.Sum256(cert.Certificate[0]) {
+		return
+	} else {
+		klog.Infof("certificate %s rotated, reconnect to %s", projectinfo.GetAgentName(), projectinfo.GetServerName())
+	}
+	if err := im.reconnect(stopCh); err != nil {
+		klog.Errorf("failed to reconnect to %s, %v", projectinfo.GetServerName(), err)
+	}
+}
+
+// checkIdentity checks if cert is usable by the agent. If newCA is set, the
+// certificate is untrusted when it's signed by the current CA bundle but not
+// by newCA.
+func (im *IdentityManager) checkIdentity(cert *tls.Certificate, newCA []byte) identityStatus {
+	if cert == nil || len(cert.Certificate) == 0 {
+		return identityMissing
+	}
+	leaf := cert.Leaf
+	if leaf == nil {
+		var err error
+		if leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return identityMissing
+		}
+	}
+
+	now := im.now()
+	if now.After(leaf.NotAfter) {
+		return identityExpired
+	}
+	if !containsString(leaf.DNSNames, im.cfg.NodeName) {
+		return identityMismatch
+	}
+	if len(newCA) != 0 && signedBy(cert, leaf, im.caData, now) && !signedBy(cert, leaf, newCA, now) {
+		return identityUntrusted
+	}
+	return identityValid
+}
+
+// reissue replaces the certificate manager with one that requests a new
+// certificate with client.
+func (im *IdentityManager) reissue(client kubernetes.Interface) error {
+	if err := im.cfg.ResetCert(); err != nil {
+		return err
+	}
+	im.certMgr.Stop()
+	return im.startCertManager(client)
+}
+
+func (im *IdentityManager) startCertManager(client kubernetes.Interface) error {
+	certMgr, err := im.cfg.NewCertManager(client)
+	if err != nil {
+		return err
+	}
+	certMgr.Start()
+	im.certMgr = certMgr
+	return nil
+}
+
+// reconnect sets up a new session with the current certificate and CA, and
+// closes the previous session after the drain period.
+func (im *IdentityManager) reconnect(stopCh <-chan struct{}) error {
+	cert := im.certMgr.Current()
+	if cert == nil {
+		return fmt.Errorf("certificate %s is not ready", projectinfo.GetAgentName())
+	}
+	tlsCfg, err := certmanager.GenTLSConfigUseCertMgrAndCA(im.certMgr,
+		im.cfg.TunnelServerAddr, im.cfg.CAFile)
+	if err != nil {
+		return err
+	}
+
+	current := &session{
+		fingerprint: sha256.Sum256(cert.Certificate[0]),
+		stopCh:      make(chan struct{}),
+	}
+	im.startSession(newTrackingCredentials(credentials.NewTLS(tlsCfg), im.rejections), current.stopCh)
+
+	if previous := im.session; previous != nil {
+		im.wg.Add(1)
+		go func() {
+			defer im.wg.Done()
+			select {
+			case <-time.After(im.cfg.DrainPeriod):
+			case <-stopCh:
+			}
+			close(previous.stopCh)
+		}()
+	}
+	im.session = current
+	return nil
+}
+
+// signedBy returns true if cert can be verified by the CA bundle caData
+func signedBy(cert *tls.Certificate, leaf *x509.Certificate, caData []byte, now time.Time) bool {
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caData) {
+		return false
+	}
+	intermediates := x509.NewCertPool()
+	for _, der := range cert.Certificate[1:] {
+		if c, err := x509.ParseCertificate(der); err == nil {
+			intermediates.AddCert(c)
+		}
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return err == nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,401 @@
+package agent
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/credentials"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/certificate"
+
+	"github.com/bhojpur/dcp/pkg/utils/certmanager"
+)
+
+// fakeSigner approves and signs the csrs created through its clientsets
+type fakeSigner struct {
+	sync.Mutex
+	caCert   *x509.Certificate
+	caKey    crypto.Signer
+	validity time.Duration
+	serial   int64
+}
+
+func newFakeSigner(t *testing.T, validity time.Duration) *fakeSigner {
+	s := &fakeSigner{validity: validity}
+	s.rotateCA(t)
+	return s
+}
+
+// rotateCA replaces the CA that signs the certificates
+func (s *fakeSigner) rotateCA(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := certutil.NewSelfSignedCACert(certutil.Config{CommonName: fmt.Sprintf("fake-ca-%d", time.Now().UnixNano())}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.caCert, s.caKey = ca, key
+}
+
+func (s *fakeSigner) caPEM() []byte {
+	s.Lock()
+	defer s.Unlock()
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.caCert.Raw})
+}
+
+func (s *fakeSigner) sign(csrPEM []byte) ([]byte, error) {
+	block, _ := pem.Decode(csrPEM)
+	if block == nil {
+		return nil, errors.New("invalid csr")
+	}
+	req, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.serial++
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(s.serial),
+		Subject:      pkix.Name{CommonName: req.Subject.CommonName, Organization: req.Subject.Organization},
+		DNSNames:     req.DNSNames,
+		IPAddresses:  req.IPAddresses,
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.Add(s.validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, s.caCert, req.PublicKey, s.caKey)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+// clientset returns a fake clientset whose csrs are signed by the signer.
+// The csrs of legacy signer are created with v1beta1, an approved v1 copy is
+// added for the certificate manager which watches v1 csrs.
+func (s *fakeSigner) clientset() *fake.Clientset {
+	client := fake.NewSimpleClientset()
+	var count int
+	client.PrependReactor("create", "certificatesigningrequests", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		csr, ok := action.(k8stesting.CreateAction).GetObject().(*certificatesv1beta1.CertificateSigningRequest)
+		if !ok {
+			return false, nil, nil
+		}
+		count++
+		csr.Name = fmt.Sprintf("csr-%d", count)
+		certPEM, err := s.sign(csr.Spec.Request)
+		if err != nil {
+			return true, nil, err
+		}
+		approved := certificatesv1.CertificateSigningRequestCondition{
+			Type:   certificatesv1.CertificateApproved,
+			Status: corev1.ConditionTrue,
+		}
+		v1csr := &certificatesv1.CertificateSigningRequest{
+			ObjectMeta: csr.ObjectMeta,
+			Spec: certificatesv1.CertificateSigningRequestSpec{
+				Request:    csr.Spec.Request,
+				SignerName: certificatesv1beta1.LegacyUnknownSignerName,
+			},
+			Status: certificatesv1.CertificateSigningRequestStatus{
+				Certificate: certPEM,
+				Conditions:  []certificatesv1.CertificateSigningRequestCondition{approved},
+			},
+		}
+		// the fake clientset ignores the field selector of the certificate
+		// manager, so only the latest csr is kept
+		v1gvr := certificatesv1.SchemeGroupVersion.WithResource("certificatesigningrequests")
+		for i := 1; i < count; i++ {
+			_ = client.Tracker().Delete(v1gvr, "", fmt.Sprintf("csr-%d", i))
+		}
+		return false, nil, client.Tracker().Add(v1csr)
+	})
+	return client
+}
+
+func csrCreations(client *fake.Clientset) int {
+	var n int
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "create" && action.GetResource().Resource == "certificatesigningrequests" {
+			n++
+		}
+	}
+	return n
+}
+
+// sessionRecorder records the sessions started by the identity manager
+type sessionRecorder struct {
+	sync.Mutex
+	leafs  []*x509.Certificate
+	closed []bool
+}
+
+func (r *sessionRecorder) sessions() ([]*x509.Certificate, []bool) {
+	r.Lock()
+	defer r.Unlock()
+	return append([]*x509.Certificate(nil), r.leafs...), append([]bool(nil), r.closed...)
+}
+
+type identityTest struct {
+	signer    *fakeSigner
+	client    *fake.Clientset
+	bootstrap *fake.Clientset
+	caFile    string
+	im        *IdentityManager
+	recorder  *sessionRecorder
+	stopCh    chan struct{}
+	done      chan error
+}
+
+func newIdentityTest(t *testing.T, nodeName string, validity time.Duration) *identityTest {
+	t.Setenv("POD_IP", "10.0.0.1")
+	t.Setenv("NODE_NAME", nodeName)
+	dir := t.TempDir()
+	certDir := filepath.Join(dir, "pki")
+
+	it := &identityTest{
+		signer:   newFakeSigner(t, validity),
+		caFile:   filepath.Join(dir, "ca.crt"),
+		recorder: &sessionRecorder{},
+		stopCh:   make(chan struct{}),
+		done:     make(chan error, 1),
+	}
+	it.client = it.signer.clientset()
+	it.bootstrap = it.signer.clientset()
+	it.writeCA(t)
+
+	it.im = NewIdentityManager(&IdentityConfig{
+		NodeName:         nodeName,
+		TunnelServerAddr: "127.0.0.1:10262",
+		CAFile:           it.caFile,
+		Client:           it.client,
+		BootstrapClient:  it.bootstrap,
+		NewCertManager: func(client kubernetes.Interface) (certificate.Manager, error) {
+			return certmanager.NewTunnelAgentCertManager(client, certDir)
+		},
+		ResetCert: func() error {
+			return certmanager.ResetTunnelAgentCert(certDir)
+		},
+		CheckPeriod: 50 * time.Millisecond,
+		DrainPeriod: 100 * time.Millisecond,
+	})
+	it.im.startSession = func(_ credentials.TransportCredentials, stopCh <-chan struct{}) {
+		leaf, err := x509.ParseCertificate(it.im.certMgr.Current().Certificate[0])
+		assert.NoError(t, err)
+
+		it.recorder.Lock()
+		idx := len(it.recorder.leafs)
+		it.recorder.leafs = append(it.recorder.leafs, leaf)
+		it.recorder.closed = append(it.recorder.closed, false)
+		it.recorder.Unlock()
+		go func() {
+			<-stopCh
+			it.recorder.Lock()
+			it.recorder.closed[idx] = true
+			it.recorder.Unlock()
+		}()
+	}
+	return it
+}
+
+func (it *identityTest) writeCA(t *testing.T) {
+	if err := ioutil.WriteFile(it.caFile, it.signer.caPEM(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (it *identityTest) run() {
+	go func() {
+		it.done <- it.im.Run(it.stopCh)
+	}()
+}
+
+func (it *identityTest) stop(t *testing.T) {
+	close(it.stopCh)
+	select {
+	case err := <-it.done:
+		assert.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("identity manager didn't stop")
+	}
+}
+
+// waitForSessions waits until n sessions are started and all but the last
+// one are closed, and returns the certificate of the last session
+func (it *identityTest) waitForSessions(t *testing.T, n int) *x509.Certificate {
+	var leaf *x509.Certificate
+	assert.Eventually(t, func() bool {
+		leafs, closed := it.recorder.sessions()
+		if len(leafs) != n {
+			return false
+		}
+		for i := 0; i < n-1; i++ {
+			if !closed[i] {
+				return false
+			}
+		}
+		leaf = leafs[n-1]
+		return !closed[n-1]
+	}, 20*time.Second, 20*time.Millisecond)
+	return leaf
+}
+
+func TestIdentityManagerBootstrap(t *testing.T) {
+	it := newIdentityTest(t, "node-a", time.Hour)
+	it.run()
+	defer it.stop(t)
+
+	leaf := it.waitForSessions(t, 1)
+	if assert.NotNil(t, leaf) {
+		assert.Equal(t, []string{"node-a"}, leaf.DNSNames)
+	}
+	assert.Equal(t, 1, csrCreations(it.client))
+	assert.Equal(t, 0, csrCreations(it.bootstrap))
+}
+
+func TestIdentityManagerRotation(t *testing.T) {
+	// the certificate manager rotates the certificate at 70%-90% of its lifetime
+	it := newIdentityTest(t, "node-a", 3*time.Second)
+	it.run()
+	defer it.stop(t)
+
+	first := it.waitForSessions(t, 1)
+	second := it.waitForSessions(t, 2)
+	if assert.NotNil(t, first) && assert.NotNil(t, second) {
+		assert.NotEqual(t, first.SerialNumber, second.SerialNumber)
+	}
+}
+
+func TestIdentityManagerNodeRenamed(t *testing.T) {
+	it := newIdentityTest(t, "node-a", time.Hour)
+
+	// leave a certificate of the old node name in the cert dir
+	t.Setenv("NODE_NAME", "old-node")
+	old, err := it.im.cfg.NewCertManager(it.client)
+	assert.NoError(t, err)
+	old.Start()
+	assert.Eventually(t, func() bool { return old.Current() != nil }, 10*time.Second, 20*time.Millisecond)
+	old.Stop()
+	t.Setenv("NODE_NAME", "node-a")
+
+	it.run()
+	defer it.stop(t)
+
+	leaf := it.waitForSessions(t, 1)
+	if assert.NotNil(t, leaf) {
+		assert.Equal(t, []string{"node-a"}, leaf.DNSNames)
+	}
+	assert.Equal(t, 2, csrCreations(it.client))
+}
+
+func TestIdentityManagerCARotation(t *testing.T) {
+	it := newIdentityTest(t, "node-a", time.Hour)
+	it.run()
+	defer it.stop(t)
+
+	first := it.waitForSessions(t, 1)
+	oldCA := it.signer.caPEM()
+
+	it.signer.rotateCA(t)
+	it.writeCA(t)
+
+	second := it.waitForSessions(t, 2)
+	if assert.NotNil(t, first) && assert.NotNil(t, second) {
+		assert.NotEqual(t, first.SerialNumber, second.SerialNumber)
+		roots := x509.NewCertPool()
+		roots.AppendCertsFromPEM(it.signer.caPEM())
+		_, err := second.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+		assert.NoError(t, err)
+	}
+	assert.NotEqual(t, oldCA, it.signer.caPEM())
+	assert.Equal(t, 0, csrCreations(it.bootstrap))
+}
+
+func TestIdentityManagerRejected(t *testing.T) {
+	it := newIdentityTest(t, "node-a", time.Hour)
+	it.run()
+	defer it.stop(t)
+
+	first := it.waitForSessions(t, 1)
+	for i := 0; i < rejectionThreshold; i++ {
+		it.im.rejections.observe(errors.New("remote error: tls: bad certificate"))
+	}
+
+	second := it.waitForSessions(t, 2)
+	if assert.NotNil(t, first) && assert.NotNil(t, second) {
+		assert.NotEqual(t, first.SerialNumber, second.SerialNumber)
+	}
+	assert.Equal(t, 1, csrCreations(it.bootstrap))
+}
+
+func TestIsIdentityRejected(t *testing.T) {
+	tests := map[string]bool{
+		"remote error: tls: bad certificate":                               true,
+		"read tcp 10.0.0.1:1234: remote error: tls: revoked certificate":   true,
+		"remote error: tls: unknown certificate authority":                 true,
+		"remote error: tls: handshake failure":                             false,
+		"x509: certificate signed by unknown authority":                    false,
+		"connection error: desc = \"transport: error while dialing: EOF\"": false,
+	}
+	for msg, expected := range tests {
+		assert.Equal(t, expected, isIdentityRejected(errors.New(msg)), msg)
+	}
+
+	tracker := &rejectionTracker{}
+	for i := 0; i < rejectionThreshold; i++ {
+		assert.False(t, tracker.rejected())
+		tracker.observe(errors.New("remote error: tls: bad certificate"))
+	}
+	assert.True(t, tracker.rejected())
+	tracker.observe(nil)
+	assert.False(t, tracker.rejected())
+}
@@ -37,6 +37,10 @@ const (
 	TunnelDNSRecordConfigMapName    = "%s-tunnel-nodes"
 	TunnelDNSRecordNodeDataKey      = "tunnel-nodes"
 
+	// Tunnel server replicas related constants
+	TunnelServerPeerPort              = "10269"
+	TunnelServerReplicasConfigMapName = "%s-tunnel-server-replicas"
+
 	// Tunnel PKI related constants
 	TunnelCSROrg                 = "bhojpur:tunnel"
 	TunnelAgentCSRCN             = "tunnel-agent"
@@ -49,7 +53,9 @@ const (
 	TunnelCSRApproverThreadiness = 2
 
 	// name of the environment variables used in pod
-	TunnelAgentPodIPEnv = "POD_IP"
+	TunnelAgentPodIPEnv    = "POD_IP"
+	TunnelServerPodIPEnv   = "POD_IP"
+	TunnelServerPodNameEnv = "POD_NAME"
 
 	// name of the environment for selecting backend agent used in tunnel-server
 	NodeIPKeyIndex     = "status.internalIP"
@@ -102,3 +102,34 @@ func CreateClientSetApiserverAddr(apiserverAddr string) (*kubernetes.Clientset,
 
 	return kubernetes.NewForConfig(&restConfig)
 }
+
+// CreateClientSetBootstrapToken creates a clientset that authenticates with
+// the given bootstrap (join) token. The apiserver address and CA are taken
+// from the kubeconfig if it's set, otherwise from the apiserverAddr and the
+// serviceaccount's CA.
+func CreateClientSetBootstrapToken(kubeConfig, apiserverAddr, token string) (*kubernetes.Clientset, error) {
+	if token == "" {
+		return nil, errors.New("bootstrap token can't be empty")
+	}
+
+	var restConfig *rest.Config
+	if kubeConfig != "" {
+		cfg, err := clientcmd.BuildConfigFromFlags("", kubeConfig)
+		if err != nil {
+			return nil, fmt.Errorf("fail to load the apiserver address from %s: %v",
+				kubeConfig, err)
+		}
+		restConfig = rest.AnonymousClientConfig(cfg)
+	} else {
+		if apiserverAddr == "" {
+			return nil, errors.New("either kubeconfig or apiserver addr has to be set")
+		}
+		restConfig = &rest.Config{Host: "https://" + apiserverAddr}
+		if _, err := certutil.NewPool(constants.TunnelCAFile); err == nil {
+			restConfig.TLSClientConfig.CAFile = constants.TunnelCAFile
+		}
+	}
+	restConfig.BearerToken = token
+
+	return kubernetes.NewForConfig(restConfig)
+}
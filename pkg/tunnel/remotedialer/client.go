@@ -36,7 +36,14 @@ type ConnectAuthorizer func(proto, address string) bool
 // ClientConnect connect to WS and wait 5 seconds when error
 func ClientConnect(ctx context.Context, wsURL string, headers http.Header, dialer *websocket.Dialer,
 	auth ConnectAuthorizer, onConnect func(context.Context, *Session) error) error {
-	if err := ConnectToProxy(ctx, wsURL, headers, auth, dialer, onConnect); err != nil {
+	return ClientConnectWithPolicy(ctx, wsURL, headers, dialer, auth, nil, onConnect)
+}
+
+// ClientConnectWithPolicy is ClientConnect with the data written to the proxy
+// limited and scheduled by policy
+func ClientConnectWithPolicy(ctx context.Context, wsURL string, headers http.Header, dialer *websocket.Dialer,
+	auth ConnectAuthorizer, policy *TrafficPolicy, onConnect func(context.Context, *Session) error) error {
+	if err := ConnectToProxyWithPolicy(ctx, wsURL, headers, auth, dialer, policy, onConnect); err != nil {
 		logrus.WithError(err).Error("Remotedialer proxy error")
 		time.Sleep(time.Duration(5) * time.Second)
 		return err
@@ -46,6 +53,11 @@ func ClientConnect(ctx context.Context, wsURL string, headers http.Header, diale
 
 // ConnectToProxy connect to websocket server
 func ConnectToProxy(rootCtx context.Context, proxyURL string, headers http.Header, auth ConnectAuthorizer, dialer *websocket.Dialer, onConnect func(context.Context, *Session) error) error {
+	return ConnectToProxyWithPolicy(rootCtx, proxyURL, headers, auth, dialer, nil, onConnect)
+}
+
+// ConnectToProxyWithPolicy connect to websocket server, applying policy to the session
+func ConnectToProxyWithPolicy(rootCtx context.Context, proxyURL string, headers http.Header, auth ConnectAuthorizer, dialer *websocket.Dialer, policy *TrafficPolicy, onConnect func(context.Context, *Session) error) error {
 	logrus.WithField("url", proxyURL).Info("Connecting to proxy")
 
 	if dialer == nil {
@@ -73,6 +85,7 @@ func ConnectToProxy(rootCtx context.Context, proxyURL string, headers http.Heade
 	defer cancel()
 
 	session := NewClientSession(auth, ws)
+	session.setTrafficPolicy(policy)
 	defer session.Close()
 
 	if onConnect != nil {
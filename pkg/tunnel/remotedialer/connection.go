@@ -21,12 +21,14 @@ package remotedialer
 // THE SOFTWARE.
 
 import (
+	"context"
 	"io"
 	"net"
 	"time"
 
 	"github.com/bhojpur/dcp/pkg/tunnel/remotedialer/metrics"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
 )
 
 type connection struct {
@@ -37,6 +39,9 @@ type connection struct {
 	addr          addr
 	session       *Session
 	connID        int64
+	class         TrafficClass
+	written       int64
+	limiters      []*rate.Limiter
 }
 
 func newConnection(connID int64, session *Session, proto, address string) *connection {
@@ -50,6 +55,10 @@ func newConnection(connID int64, session *Session, proto, address string) *conne
 	}
 	c.backPressure = newBackPressure(c)
 	c.buffer = newReadBuffer(connID, c.backPressure)
+	if session.policy != nil {
+		c.class = session.policy.classify(address)
+		c.limiters = session.policy.limitersFor(session.clientKey, address)
+	}
 	metrics.IncSMTotalAddConnectionsForWS(session.clientKey, proto, address)
 	return c
 }
@@ -102,9 +111,42 @@ func (c *connection) Write(b []byte) (int, error) {
 		return 0, io.ErrClosedPipe
 	}
 	c.backPressure.Wait()
+	if err := c.throttle(len(b)); err != nil {
+		return 0, err
+	}
 	msg := newMessage(c.connID, b)
 	metrics.AddSMTotalTransmitBytesOnWS(c.session.clientKey, float64(len(msg.Bytes())))
-	return c.session.writeMessage(c.writeDeadline, msg)
+	return c.session.writeData(c, msg)
+}
+
+// throttle blocks until the node and port limits admit n more bytes.
+func (c *connection) throttle(n int) error {
+	if len(c.limiters) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	if !c.writeDeadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, c.writeDeadline)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := waitLimiters(ctx, c.limiters, n)
+	metrics.AddSMTotalThrottledSecondsForClass(c.session.clientKey, string(c.class), time.Since(start).Seconds())
+	return err
+}
+
+// trafficClass accounts n written bytes and returns the class of the
+// connection, demoting it to bulk once it passes the bulk threshold.
+func (c *connection) trafficClass(n int) TrafficClass {
+	c.written += int64(n)
+	threshold := c.session.policy.bulkThreshold()
+	if c.class != TrafficClassBulk && threshold > 0 && c.written > threshold {
+		c.class = TrafficClassBulk
+	}
+	return c.class
 }
 
 func (c *connection) OnPause() {
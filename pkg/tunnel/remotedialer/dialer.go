@@ -28,12 +28,19 @@ import (
 type Dialer func(ctx context.Context, network, address string) (net.Conn, error)
 
 func (s *Server) HasSession(clientKey string) bool {
+	if s.LocalDialer != nil && s.sessions.hasLocal(clientKey) {
+		return true
+	}
 	_, err := s.sessions.getDialer(clientKey)
 	return err == nil
 }
 
 func (s *Server) Dialer(clientKey string) Dialer {
 	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if s.LocalDialer != nil && s.sessions.hasLocal(clientKey) {
+			return s.LocalDialer(clientKey)(ctx, network, address)
+		}
+
 		d, err := s.sessions.getDialer(clientKey)
 		if err != nil {
 			return nil, err
@@ -42,3 +49,24 @@ func (s *Server) Dialer(clientKey string) Dialer {
 		return d(ctx, network, address)
 	}
 }
+
+// AddLocalClient announces to the peers that clientKey can be reached
+// through the LocalDialer of this server.
+func (s *Server) AddLocalClient(clientKey string) {
+	s.sessions.addLocal(clientKey)
+}
+
+// RemoveLocalClient withdraws a client added by AddLocalClient.
+func (s *Server) RemoveLocalClient(clientKey string) {
+	s.sessions.removeLocal(clientKey)
+}
+
+// HasLocalClient returns true if clientKey was added by AddLocalClient.
+func (s *Server) HasLocalClient(clientKey string) bool {
+	return s.sessions.hasLocal(clientKey)
+}
+
+// LocalClients returns the keys of the clients added by AddLocalClient.
+func (s *Server) LocalClients() []string {
+	return s.sessions.localKeys()
+}
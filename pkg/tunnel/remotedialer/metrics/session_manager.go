@@ -92,6 +92,24 @@ var (
 		[]string{"clientkey"},
 	)
 
+	TotalTransmitBytesForClass = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "session_server",
+			Name:      "total_transmit_bytes_per_class",
+			Help:      "Total bytes transmitted per traffic class",
+		},
+		[]string{"clientkey", "class"},
+	)
+
+	TotalThrottledSecondsForClass = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "session_server",
+			Name:      "total_throttled_seconds_per_class",
+			Help:      "Total seconds writes waited on bandwidth limits per traffic class",
+		},
+		[]string{"clientkey", "class"},
+	)
+
 	TotalAddPeerAttempt = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Subsystem: "session_server",
@@ -132,6 +150,8 @@ func Register() {
 	prometheus.MustRegister(TotalTransmitBytesOnWS)
 	prometheus.MustRegister(TotalTransmitErrorBytesOnWS)
 	prometheus.MustRegister(TotalReceiveBytesOnWS)
+	prometheus.MustRegister(TotalTransmitBytesForClass)
+	prometheus.MustRegister(TotalThrottledSecondsForClass)
 	prometheus.MustRegister(TotalAddPeerAttempt)
 	prometheus.MustRegister(TotalPeerConnected)
 	prometheus.MustRegister(TotalPeerDisConnected)
@@ -202,6 +222,26 @@ func AddSMTotalReceiveBytesOnWS(clientKey string, size float64) {
 	}
 }
 
+func AddSMTotalTransmitBytesForClass(clientKey, class string, size float64) {
+	if prometheusMetrics {
+		TotalTransmitBytesForClass.With(
+			prometheus.Labels{
+				"clientkey": clientKey,
+				"class":     class,
+			}).Add(size)
+	}
+}
+
+func AddSMTotalThrottledSecondsForClass(clientKey, class string, seconds float64) {
+	if prometheusMetrics {
+		TotalThrottledSecondsForClass.With(
+			prometheus.Labels{
+				"clientkey": clientKey,
+				"class":     class,
+			}).Add(seconds)
+	}
+}
+
 func IncSMTotalAddConnectionsForWS(clientKey, proto, addr string) {
 	if prometheusMetrics {
 		TotalAddConnectionsForWS.With(
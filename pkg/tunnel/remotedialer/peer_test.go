@@ -0,0 +1,109 @@
+package remotedialer
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func rejectAll(req *http.Request) (string, bool, error) {
+	return "", false, nil
+}
+
+func newPeerServer(id string) (*Server, *httptest.Server) {
+	server := New(rejectAll, DefaultErrorWriter)
+	server.PeerID = id
+	server.PeerToken = "token"
+	return server, httptest.NewServer(server)
+}
+
+func TestLocalClientThroughPeer(t *testing.T) {
+	// echo server behind the local client of server a
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	a, httpA := newPeerServer("a")
+	defer httpA.Close()
+	a.LocalDialer = func(clientKey string) Dialer {
+		assert.Equal(t, "node-a", clientKey)
+		return func(ctx context.Context, network, address string) (net.Conn, error) {
+			return net.Dial(network, address)
+		}
+	}
+	b, httpB := newPeerServer("b")
+	defer httpB.Close()
+
+	a.AddLocalClient("node-a")
+	a.AddLocalClient("node-a")
+	assert.True(t, a.HasLocalClient("node-a"))
+	assert.Equal(t, []string{"node-a"}, a.LocalClients())
+
+	a.AddPeer("ws"+strings.TrimPrefix(httpB.URL, "http"), "b", "token")
+	b.AddPeer("ws"+strings.TrimPrefix(httpA.URL, "http"), "a", "token")
+	defer a.RemovePeer("b")
+	defer b.RemovePeer("a")
+
+	assert.Eventually(t, func() bool {
+		return b.HasSession("node-a")
+	}, 10*time.Second, 10*time.Millisecond)
+	assert.False(t, b.HasLocalClient("node-a"))
+
+	conn, err := b.Dialer("node-a")(context.Background(), "tcp", listener.Addr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("hello"))
+	assert.NoError(t, err)
+	buf := make([]byte, 5)
+	_, err = io.ReadFull(conn, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(buf))
+
+	// the client is withdrawn once all of its references are removed
+	a.RemoveLocalClient("node-a")
+	assert.True(t, a.HasLocalClient("node-a"))
+	a.RemoveLocalClient("node-a")
+	assert.False(t, a.HasLocalClient("node-a"))
+	assert.Eventually(t, func() bool {
+		return !b.HasSession("node-a")
+	}, 10*time.Second, 10*time.Millisecond)
+}
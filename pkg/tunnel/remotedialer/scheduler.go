@@ -0,0 +1,188 @@
+package remotedialer
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// schedulerQuantum is the number of bytes a class of weight one may send in
+// each scheduling round.
+const schedulerQuantum = MaxRead
+
+var errSchedulerClosed = errors.New("session writer closed")
+
+type scheduledWrite struct {
+	deadline time.Time
+	msg      *message
+	done     chan writeResult
+}
+
+type writeResult struct {
+	n   int
+	err error
+}
+
+// writeScheduler serializes data messages of a session onto its websocket,
+// sharing the link between traffic classes with deficit round robin.
+type writeScheduler struct {
+	sync.Mutex
+	cond     *sync.Cond
+	write    func(deadline time.Time, msg *message) (int, error)
+	weights  []int
+	queues   [][]*scheduledWrite
+	deficit  []int
+	pending  int
+	current  int
+	credited bool
+	closed   bool
+}
+
+func newWriteScheduler(policy *TrafficPolicy, write func(time.Time, *message) (int, error)) *writeScheduler {
+	w := &writeScheduler{
+		write:   write,
+		weights: make([]int, len(trafficClasses)),
+		queues:  make([][]*scheduledWrite, len(trafficClasses)),
+		deficit: make([]int, len(trafficClasses)),
+	}
+	w.cond = sync.NewCond(&w.Mutex)
+	for i, class := range trafficClasses {
+		w.weights[i] = policy.weight(class)
+	}
+	return w
+}
+
+// Write queues msg in its class and blocks until it has been written.
+func (w *writeScheduler) Write(class TrafficClass, deadline time.Time, msg *message) (int, error) {
+	req := &scheduledWrite{
+		deadline: deadline,
+		msg:      msg,
+		done:     make(chan writeResult, 1),
+	}
+
+	w.Lock()
+	if w.closed {
+		w.Unlock()
+		return 0, errSchedulerClosed
+	}
+	idx := classIndex(class)
+	w.queues[idx] = append(w.queues[idx], req)
+	w.pending++
+	w.cond.Signal()
+	w.Unlock()
+
+	result := <-req.done
+	return result.n, result.err
+}
+
+// Run writes queued messages until the scheduler is closed.
+func (w *writeScheduler) Run() {
+	for {
+		req := w.next()
+		if req == nil {
+			return
+		}
+		if !req.deadline.IsZero() && time.Now().After(req.deadline) {
+			req.done <- writeResult{err: fmt.Errorf("i/o timeout")}
+			continue
+		}
+		n, err := w.write(req.deadline, req.msg)
+		req.done <- writeResult{n: n, err: err}
+	}
+}
+
+// Close stops the scheduler and fails all queued writes.
+func (w *writeScheduler) Close() {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.closed {
+		return
+	}
+	w.closed = true
+	for i, queue := range w.queues {
+		for _, req := range queue {
+			req.done <- writeResult{err: errSchedulerClosed}
+		}
+		w.queues[i] = nil
+	}
+	w.pending = 0
+	w.cond.Broadcast()
+}
+
+// next picks the next message to write. Each class visited in turn is
+// credited weight*quantum bytes, and may send while its head message fits
+// in its deficit. Classes with an empty queue forfeit their deficit.
+func (w *writeScheduler) next() *scheduledWrite {
+	w.Lock()
+	defer w.Unlock()
+
+	for {
+		if w.closed {
+			return nil
+		}
+		if w.pending == 0 {
+			w.cond.Wait()
+			continue
+		}
+
+		queue := w.queues[w.current]
+		if len(queue) == 0 {
+			w.deficit[w.current] = 0
+			w.advance()
+			continue
+		}
+
+		if !w.credited {
+			w.deficit[w.current] += w.weights[w.current] * schedulerQuantum
+			w.credited = true
+		}
+
+		head := queue[0]
+		size := len(head.msg.bytes)
+		if size > w.deficit[w.current] {
+			w.advance()
+			continue
+		}
+
+		w.deficit[w.current] -= size
+		w.queues[w.current] = queue[1:]
+		w.pending--
+		return head
+	}
+}
+
+func (w *writeScheduler) advance() {
+	w.current = (w.current + 1) % len(w.queues)
+	w.credited = false
+}
+
+func classIndex(class TrafficClass) int {
+	for i, c := range trafficClasses {
+		if c == class {
+			return i
+		}
+	}
+	return len(trafficClasses) - 1
+}
@@ -47,11 +47,15 @@ type Server struct {
 	PeerID                  string
 	PeerToken               string
 	ClientConnectAuthorizer ConnectAuthorizer
-	authorizer              Authorizer
-	errorWriter             ErrorWriter
-	sessions                *sessionManager
-	peers                   map[string]peer
-	peerLock                sync.Mutex
+	TrafficPolicy           *TrafficPolicy
+	// LocalDialer dials the clients added with AddLocalClient, it is used
+	// for both local connections and connections requested by peers.
+	LocalDialer func(clientKey string) Dialer
+	authorizer  Authorizer
+	errorWriter ErrorWriter
+	sessions    *sessionManager
+	peers       map[string]peer
+	peerLock    sync.Mutex
 }
 
 func New(auth Authorizer, errorWriter ErrorWriter) *Server {
@@ -88,7 +92,7 @@ func (s *Server) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	session := s.sessions.add(clientKey, wsConn, peer)
+	session := s.sessions.add(clientKey, wsConn, peer, s.TrafficPolicy)
 	session.auth = s.ClientConnectAuthorizer
 	defer s.sessions.remove(session)
 
@@ -33,6 +33,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/bhojpur/dcp/pkg/tunnel/remotedialer/metrics"
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
 )
@@ -51,6 +52,8 @@ type Session struct {
 	pingWait         sync.WaitGroup
 	dialer           Dialer
 	client           bool
+	policy           *TrafficPolicy
+	scheduler        *writeScheduler
 }
 
 // PrintTunnelData No tunnel logging by default
@@ -88,6 +91,17 @@ func newSession(sessionKey int64, clientKey string, conn *websocket.Conn) *Sessi
 	}
 }
 
+// setTrafficPolicy enables rate limiting and the weighted writer for the
+// data of this session. It must be called before the session is served.
+func (s *Session) setTrafficPolicy(policy *TrafficPolicy) {
+	if policy == nil {
+		return
+	}
+	s.policy = policy
+	s.scheduler = newWriteScheduler(policy, s.writeMessage)
+	go s.scheduler.Run()
+}
+
 func (s *Session) startPings(rootCtx context.Context) {
 	ctx, cancel := context.WithCancel(rootCtx)
 	s.pingCancel = cancel
@@ -342,11 +356,26 @@ func (s *Session) writeMessage(deadline time.Time, message *message) (int, error
 	return message.WriteTo(deadline, s.conn)
 }
 
+// writeData writes a data message of c, through the scheduler when the
+// session has a traffic policy.
+func (s *Session) writeData(c *connection, msg *message) (int, error) {
+	if s.scheduler == nil {
+		return s.writeMessage(c.writeDeadline, msg)
+	}
+
+	class := c.trafficClass(len(msg.bytes))
+	metrics.AddSMTotalTransmitBytesForClass(s.clientKey, string(class), float64(len(msg.bytes)))
+	return s.scheduler.Write(class, c.writeDeadline, msg)
+}
+
 func (s *Session) Close() {
 	s.Lock()
 	defer s.Unlock()
 
 	s.stopPings()
+	if s.scheduler != nil {
+		s.scheduler.Close()
+	}
 
 	for _, connection := range s.conns {
 		connection.tunnelClose(errors.New("tunnel disconnect"))
@@ -40,13 +40,22 @@ type sessionManager struct {
 	sync.Mutex
 	clients   map[string][]*Session
 	peers     map[string][]*Session
+	locals    map[string]*localClient
 	listeners map[sessionListener]bool
 }
 
+// localClient is a client that is connected to this server through another
+// transport, e.g. a grpc tunnel, and is reached with the Server.LocalDialer.
+type localClient struct {
+	sessionKey int64
+	refs       int
+}
+
 func newSessionManager() *sessionManager {
 	return &sessionManager{
 		clients:   map[string][]*Session{},
 		peers:     map[string][]*Session{},
+		locals:    map[string]*localClient{},
 		listeners: map[sessionListener]bool{},
 	}
 }
@@ -84,6 +93,68 @@ func (sm *sessionManager) addListener(listener sessionListener) {
 			listener.sessionAdded(k, session.sessionKey)
 		}
 	}
+
+	for k, local := range sm.locals {
+		listener.sessionAdded(k, local.sessionKey)
+	}
+}
+
+// addLocal registers a local client and announces it to the listeners, i.e.
+// the peers. A client may be added several times, it is announced once.
+func (sm *sessionManager) addLocal(clientKey string) {
+	sm.Lock()
+	defer sm.Unlock()
+
+	if local, ok := sm.locals[clientKey]; ok {
+		local.refs++
+		return
+	}
+
+	local := &localClient{sessionKey: rand.Int63(), refs: 1}
+	sm.locals[clientKey] = local
+	for l := range sm.listeners {
+		l.sessionAdded(clientKey, local.sessionKey)
+	}
+}
+
+// removeLocal drops a reference of a local client and withdraws the client
+// from the listeners once the last reference is gone.
+func (sm *sessionManager) removeLocal(clientKey string) {
+	sm.Lock()
+	defer sm.Unlock()
+
+	local, ok := sm.locals[clientKey]
+	if !ok {
+		return
+	}
+	local.refs--
+	if local.refs > 0 {
+		return
+	}
+
+	delete(sm.locals, clientKey)
+	for l := range sm.listeners {
+		l.sessionRemoved(clientKey, local.sessionKey)
+	}
+}
+
+func (sm *sessionManager) hasLocal(clientKey string) bool {
+	sm.Lock()
+	defer sm.Unlock()
+
+	_, ok := sm.locals[clientKey]
+	return ok
+}
+
+func (sm *sessionManager) localKeys() []string {
+	sm.Lock()
+	defer sm.Unlock()
+
+	keys := make([]string, 0, len(sm.locals))
+	for k := range sm.locals {
+		keys = append(keys, k)
+	}
+	return keys
 }
 
 func (sm *sessionManager) getDialer(clientKey string) (Dialer, error) {
@@ -109,9 +180,14 @@ func (sm *sessionManager) getDialer(clientKey string) (Dialer, error) {
 	return nil, fmt.Errorf("failed to find Session for client %s", clientKey)
 }
 
-func (sm *sessionManager) add(clientKey string, conn *websocket.Conn, peer bool) *Session {
+func (sm *sessionManager) add(clientKey string, conn *websocket.Conn, peer bool, policy *TrafficPolicy) *Session {
 	sessionKey := rand.Int63()
 	session := newSession(sessionKey, clientKey, conn)
+	if !peer {
+		// peer sessions carry traffic of many nodes, limits apply at the
+		// replica holding the node session
+		session.setTrafficPolicy(policy)
+	}
 
 	sm.Lock()
 	defer sm.Unlock()
@@ -153,6 +229,9 @@ func (sm *sessionManager) remove(s *Session) {
 
 		if len(newSessions) == 0 {
 			delete(store, s.clientKey)
+			if i == 0 && s.policy != nil {
+				s.policy.releaseLimiters(s.clientKey)
+			}
 		} else {
 			store[s.clientKey] = newSessions
 		}
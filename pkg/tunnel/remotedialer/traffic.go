@@ -0,0 +1,259 @@
+package remotedialer
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/time/rate"
+	"sigs.k8s.io/yaml"
+)
+
+// TrafficClass is the priority class of a tunneled connection. The session
+// writer shares the websocket between classes according to their weights.
+type TrafficClass string
+
+const (
+	// TrafficClassInteractive is used for latency sensitive traffic,
+	// e.g. exec, attach and port-forward streams.
+	TrafficClassInteractive TrafficClass = "interactive"
+	// TrafficClassMetrics is used for metrics scraping.
+	TrafficClassMetrics TrafficClass = "metrics"
+	// TrafficClassBulk is used for large transfers, e.g. kubectl cp or log dumps.
+	TrafficClassBulk TrafficClass = "bulk"
+)
+
+// trafficClasses lists the classes in scheduling order.
+var trafficClasses = []TrafficClass{TrafficClassInteractive, TrafficClassMetrics, TrafficClassBulk}
+
+const (
+	defaultInteractiveWeight = 8
+	defaultMetricsWeight     = 4
+	defaultBulkWeight        = 1
+	// defaultBulkThresholdBytes is the number of bytes a connection may write
+	// before it is demoted to the bulk class.
+	defaultBulkThresholdBytes = 4 * 1024 * 1024
+)
+
+// RateLimit is a token-bucket limit in bytes per second.
+type RateLimit struct {
+	// BytesPerSecond is the sustained rate. Zero means unlimited.
+	BytesPerSecond int64 `json:"bytesPerSecond"`
+	// Burst is the bucket size in bytes. Defaults to one second of traffic.
+	Burst int64 `json:"burst,omitempty"`
+}
+
+// TrafficPolicy configures bandwidth limits and priority classes for the
+// connections tunneled through a session.
+type TrafficPolicy struct {
+	// NodeLimit is applied to all traffic written for a node (client key).
+	NodeLimit *RateLimit `json:"nodeLimit,omitempty"`
+	// PortLimits are applied per node to the traffic of a destination port.
+	PortLimits map[string]RateLimit `json:"portLimits,omitempty"`
+	// Weights are the relative shares of each class on the websocket.
+	Weights map[TrafficClass]int `json:"weights,omitempty"`
+	// PortClasses maps a destination port to its traffic class.
+	PortClasses map[string]TrafficClass `json:"portClasses,omitempty"`
+	// DefaultClass is used for ports without an explicit class.
+	DefaultClass TrafficClass `json:"defaultClass,omitempty"`
+	// BulkThresholdBytes demotes a connection to the bulk class once it has
+	// written more than this many bytes. Negative disables demotion.
+	BulkThresholdBytes int64 `json:"bulkThresholdBytes,omitempty"`
+
+	limitersLock sync.Mutex
+	limiters     map[string]*rate.Limiter
+}
+
+// LoadTrafficPolicy reads a TrafficPolicy from a YAML or JSON file. An empty
+// path returns a nil policy, which disables limiting and scheduling.
+func LoadTrafficPolicy(path string) (*TrafficPolicy, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read traffic policy %s: %v", path, err)
+	}
+	policy := &TrafficPolicy{}
+	if err := yaml.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("failed to parse traffic policy %s: %v", path, err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Validate checks the classes, weights and limits of the policy.
+func (p *TrafficPolicy) Validate() error {
+	for class, weight := range p.Weights {
+		if !isValidClass(class) {
+			return fmt.Errorf("unknown traffic class %q", class)
+		}
+		if weight <= 0 {
+			return fmt.Errorf("weight of traffic class %q must be positive", class)
+		}
+	}
+	for port, class := range p.PortClasses {
+		if _, err := strconv.Atoi(port); err != nil {
+			return fmt.Errorf("invalid port %q in portClasses", port)
+		}
+		if !isValidClass(class) {
+			return fmt.Errorf("unknown traffic class %q for port %s", class, port)
+		}
+	}
+	if p.DefaultClass != "" && !isValidClass(p.DefaultClass) {
+		return fmt.Errorf("unknown default traffic class %q", p.DefaultClass)
+	}
+	if p.NodeLimit != nil && p.NodeLimit.BytesPerSecond < 0 {
+		return fmt.Errorf("nodeLimit must not be negative")
+	}
+	for port, limit := range p.PortLimits {
+		if _, err := strconv.Atoi(port); err != nil {
+			return fmt.Errorf("invalid port %q in portLimits", port)
+		}
+		if limit.BytesPerSecond < 0 {
+			return fmt.Errorf("limit for port %s must not be negative", port)
+		}
+	}
+	return nil
+}
+
+func isValidClass(class TrafficClass) bool {
+	for _, c := range trafficClasses {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// weight returns the configured weight of a class or its default.
+func (p *TrafficPolicy) weight(class TrafficClass) int {
+	if w, ok := p.Weights[class]; ok && w > 0 {
+		return w
+	}
+	switch class {
+	case TrafficClassInteractive:
+		return defaultInteractiveWeight
+	case TrafficClassMetrics:
+		return defaultMetricsWeight
+	default:
+		return defaultBulkWeight
+	}
+}
+
+// classify returns the initial class of a connection to address.
+func (p *TrafficPolicy) classify(address string) TrafficClass {
+	if class, ok := p.PortClasses[portOf(address)]; ok {
+		return class
+	}
+	if p.DefaultClass != "" {
+		return p.DefaultClass
+	}
+	return TrafficClassInteractive
+}
+
+func (p *TrafficPolicy) bulkThreshold() int64 {
+	if p.BulkThresholdBytes == 0 {
+		return defaultBulkThresholdBytes
+	}
+	return p.BulkThresholdBytes
+}
+
+// limitersFor returns the node and port limiters that apply to a connection
+// of clientKey to address. Limiters are shared between all sessions of a node.
+func (p *TrafficPolicy) limitersFor(clientKey, address string) []*rate.Limiter {
+	var limiters []*rate.Limiter
+	if p.NodeLimit != nil && p.NodeLimit.BytesPerSecond > 0 {
+		limiters = append(limiters, p.limiter(clientKey, *p.NodeLimit))
+	}
+	port := portOf(address)
+	if limit, ok := p.PortLimits[port]; ok && limit.BytesPerSecond > 0 {
+		limiters = append(limiters, p.limiter(clientKey+"/"+port, limit))
+	}
+	return limiters
+}
+
+func (p *TrafficPolicy) limiter(key string, limit RateLimit) *rate.Limiter {
+	p.limitersLock.Lock()
+	defer p.limitersLock.Unlock()
+
+	if p.limiters == nil {
+		p.limiters = map[string]*rate.Limiter{}
+	}
+	l, ok := p.limiters[key]
+	if !ok {
+		burst := limit.Burst
+		if burst <= 0 {
+			burst = limit.BytesPerSecond
+		}
+		l = rate.NewLimiter(rate.Limit(limit.BytesPerSecond), int(burst))
+		p.limiters[key] = l
+	}
+	return l
+}
+
+// releaseLimiters drops the node and port limiters of clientKey, it is called
+// once the last session of the node is removed.
+func (p *TrafficPolicy) releaseLimiters(clientKey string) {
+	p.limitersLock.Lock()
+	defer p.limitersLock.Unlock()
+
+	for key := range p.limiters {
+		if key == clientKey || strings.HasPrefix(key, clientKey+"/") {
+			delete(p.limiters, key)
+		}
+	}
+}
+
+// waitLimiters blocks until n bytes may be written under every limiter.
+// Writes larger than a bucket are admitted in bucket-sized chunks.
+func waitLimiters(ctx context.Context, limiters []*rate.Limiter, n int) error {
+	for _, l := range limiters {
+		remaining := n
+		for remaining > 0 {
+			chunk := remaining
+			if burst := l.Burst(); chunk > burst {
+				chunk = burst
+			}
+			if err := l.WaitN(ctx, chunk); err != nil {
+				return err
+			}
+			remaining -= chunk
+		}
+	}
+	return nil
+}
+
+func portOf(address string) string {
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return ""
+	}
+	return port
+}
@@ -0,0 +1,170 @@
+package remotedialer
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrafficPolicyClassify(t *testing.T) {
+	policy := &TrafficPolicy{
+		PortClasses: map[string]TrafficClass{
+			"9100": TrafficClassMetrics,
+		},
+		BulkThresholdBytes: 100,
+	}
+	assert.NoError(t, policy.Validate())
+
+	assert.Equal(t, TrafficClassMetrics, policy.classify("127.0.0.1:9100"))
+	assert.Equal(t, TrafficClassInteractive, policy.classify("127.0.0.1:10250"))
+
+	policy.DefaultClass = TrafficClassBulk
+	assert.Equal(t, TrafficClassBulk, policy.classify("127.0.0.1:10250"))
+
+	session := &Session{clientKey: "node1", policy: policy}
+	c := &connection{session: session, class: TrafficClassInteractive}
+	assert.Equal(t, TrafficClassInteractive, c.trafficClass(60))
+	assert.Equal(t, TrafficClassBulk, c.trafficClass(60))
+}
+
+func TestTrafficPolicyValidate(t *testing.T) {
+	tests := map[string]*TrafficPolicy{
+		"unknown class weight": {Weights: map[TrafficClass]int{"video": 1}},
+		"zero weight":          {Weights: map[TrafficClass]int{TrafficClassBulk: 0}},
+		"invalid port class":   {PortClasses: map[string]TrafficClass{"http": TrafficClassBulk}},
+		"negative port limit":  {PortLimits: map[string]RateLimit{"10250": {BytesPerSecond: -1}}},
+	}
+	for name, policy := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, policy.Validate())
+		})
+	}
+}
+
+func TestTrafficPolicyLimiters(t *testing.T) {
+	policy := &TrafficPolicy{
+		NodeLimit: &RateLimit{BytesPerSecond: 1024},
+		PortLimits: map[string]RateLimit{
+			"10250": {BytesPerSecond: 512, Burst: 256},
+		},
+	}
+
+	limiters := policy.limitersFor("node1", "127.0.0.1:10250")
+	assert.Len(t, limiters, 2)
+	assert.Equal(t, 1024, limiters[0].Burst())
+	assert.Equal(t, 256, limiters[1].Burst())
+
+	// limiters of a node are shared between its connections
+	again := policy.limitersFor("node1", "127.0.0.1:10250")
+	assert.Same(t, limiters[0], again[0])
+	assert.Same(t, limiters[1], again[1])
+
+	assert.Len(t, policy.limitersFor("node1", "127.0.0.1:9100"), 1)
+	assert.NotSame(t, limiters[0], policy.limitersFor("node2", "127.0.0.1:9100")[0])
+}
+
+func TestTrafficPolicyReleaseLimiters(t *testing.T) {
+	policy := &TrafficPolicy{
+		NodeLimit: &RateLimit{BytesPerSecond: 1024},
+		PortLimits: map[string]RateLimit{
+			"10250": {BytesPerSecond: 512},
+		},
+	}
+	s1 := &Session{clientKey: "node1", sessionKey: 1, policy: policy}
+	s2 := &Session{clientKey: "node1", sessionKey: 2, policy: policy}
+	sm := newSessionManager()
+	sm.clients["node1"] = []*Session{s1, s2}
+	limiters := policy.limitersFor("node1", "127.0.0.1:10250")
+	policy.limitersFor("node2", "127.0.0.1:10250")
+
+	// limiters are kept while the node has sessions left
+	sm.remove(s1)
+	assert.Len(t, policy.limiters, 4)
+
+	sm.remove(s2)
+	assert.Len(t, policy.limiters, 2)
+	assert.NotSame(t, limiters[0], policy.limitersFor("node1", "127.0.0.1:10250")[0])
+}
+
+func TestWriteSchedulerWeightedShares(t *testing.T) {
+	var (
+		lock  sync.Mutex
+		order []string
+		start = make(chan struct{})
+	)
+	write := func(_ time.Time, msg *message) (int, error) {
+		<-start
+		lock.Lock()
+		defer lock.Unlock()
+		order = append(order, string(msg.bytes[:1]))
+		return len(msg.bytes), nil
+	}
+
+	policy := &TrafficPolicy{
+		Weights: map[TrafficClass]int{
+			TrafficClassInteractive: 2,
+			TrafficClassBulk:        1,
+		},
+	}
+	scheduler := newWriteScheduler(policy, write)
+
+	// queue everything before the writer starts
+	wg := sync.WaitGroup{}
+	enqueue := func(class TrafficClass, prefix string, count int) {
+		for i := 0; i < count; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := scheduler.Write(class, time.Time{}, newMessage(1, append([]byte(prefix), make([]byte, schedulerQuantum-1)...)))
+				assert.NoError(t, err)
+			}()
+		}
+	}
+	enqueue(TrafficClassBulk, "b", 6)
+	enqueue(TrafficClassInteractive, "i", 6)
+	assert.Eventually(t, func() bool {
+		scheduler.Lock()
+		defer scheduler.Unlock()
+		return scheduler.pending == 12
+	}, time.Second, time.Millisecond)
+
+	go scheduler.Run()
+	close(start)
+	wg.Wait()
+	scheduler.Close()
+
+	// each round sends two interactive messages for every bulk message
+	assert.Equal(t, []string{"i", "i", "b", "i", "i", "b", "i", "i", "b", "b", "b", "b"}, order)
+}
+
+func TestWriteSchedulerClose(t *testing.T) {
+	scheduler := newWriteScheduler(&TrafficPolicy{}, func(time.Time, *message) (int, error) {
+		return 0, nil
+	})
+	scheduler.Close()
+
+	_, err := scheduler.Write(TrafficClassBulk, time.Time{}, newMessage(1, []byte("data")))
+	assert.Equal(t, errSchedulerClosed, err)
+}
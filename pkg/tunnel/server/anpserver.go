@@ -53,6 +53,10 @@ type anpTunnelServer struct {
 	tlsCfg                   *tls.Config
 	wrappers                 hw.HandlerWrappers
 	proxyStrategy            string
+	// peerCfg enables forwarding requests between tunnel-server replicas,
+	// it's nil if there is only one replica
+	peerCfg *PeerConfig
+	stopCh  <-chan struct{}
 }
 
 var _ TunnelServer = &anpTunnelServer{}
@@ -63,6 +67,12 @@ func (ats *anpTunnelServer) Run() error {
 		[]anpserver.ProxyStrategy{anpserver.ProxyStrategy(ats.proxyStrategy)},
 		ats.serverCount,
 		&anpserver.AgentTokenAuthenticationOptions{})
+
+	var peers *peerForwarder
+	if ats.peerCfg != nil {
+		peers = newPeerForwarder(ats.peerCfg, ats.interceptorServerUDSFile)
+	}
+
 	// 1. start the proxier
 	proxierErr := runProxier(
 		&anpserver.Tunnel{Server: proxyServer},
@@ -73,8 +83,12 @@ func (ats *anpTunnelServer) Run() error {
 		return fmt.Errorf("fail to run the proxier: %s", proxierErr)
 	}
 
+	interceptor := NewRequestInterceptor(ats.interceptorServerUDSFile, ats.tlsCfg)
+	if interceptor != nil {
+		interceptor.peers = peers
+	}
 	wrappedHandler, err := wh.WrapHandler(
-		NewRequestInterceptor(ats.interceptorServerUDSFile, ats.tlsCfg),
+		interceptor,
 		ats.wrappers,
 	)
 	if err != nil {
@@ -93,11 +107,20 @@ func (ats *anpTunnelServer) Run() error {
 	}
 
 	// 3. start the agent server
-	agentServerErr := runAgentServer(ats.tlsCfg, ats.serverAgentAddr, proxyServer)
+	var agentService anpagent.AgentServiceServer = proxyServer
+	if peers != nil {
+		agentService = &agentServer{AgentServiceServer: proxyServer, peers: peers}
+	}
+	agentServerErr := runAgentServer(ats.tlsCfg, ats.serverAgentAddr, agentService)
 	if agentServerErr != nil {
 		return fmt.Errorf("fail to run agent server: %s", agentServerErr)
 	}
 
+	// 4. start forwarding requests between replicas
+	if peers != nil {
+		peers.run(ats.tlsCfg, ats.stopCh)
+	}
+
 	return nil
 }
 
@@ -178,7 +201,7 @@ func runMasterServer(handler http.Handler,
 // to corresponding tunnel-agent
 func runAgentServer(tlsCfg *tls.Config,
 	agentServerAddr string,
-	proxyServer *anpserver.ProxyServer) error {
+	agentService anpagent.AgentServiceServer) error {
 	serverOption := grpc.Creds(credentials.NewTLS(tlsCfg))
 
 	ka := keepalive.ServerParameters{
@@ -193,7 +216,7 @@ func runAgentServer(tlsCfg *tls.Config,
 	grpcServer := grpc.NewServer(serverOption,
 		grpc.KeepaliveParams(ka))
 
-	anpagent.RegisterAgentServiceServer(grpcServer, proxyServer)
+	anpagent.RegisterAgentServiceServer(grpcServer, agentService)
 	listener, err := net.Listen("tcp", agentServerAddr)
 	klog.Info("start handling connection from agents")
 	if err != nil {
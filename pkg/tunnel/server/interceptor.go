@@ -69,6 +69,9 @@ func putBufioReader(br *bufio.Reader) {
 // through the tunnel and sends responses back to the master
 type RequestInterceptor struct {
 	contextDialer func(addr string, header http.Header, isTLS bool) (net.Conn, error)
+	// peers forwards the connections of agents connected to other
+	// tunnel-server replicas, it's nil if peer forwarding is disabled.
+	peers *peerForwarder
 }
 
 // NewRequestInterceptor creates a interceptor object that intercept request from kube-apiserver
@@ -77,34 +80,22 @@ func NewRequestInterceptor(udsSockFile string, cfg *tls.Config) *RequestIntercep
 		return nil
 	}
 
+	ri := &RequestInterceptor{}
 	cfg.InsecureSkipVerify = true
-	contextDialer := func(addr string, header http.Header, isTLS bool) (net.Conn, error) {
+	ri.contextDialer = func(addr string, header http.Header, isTLS bool) (net.Conn, error) {
 		klog.V(4).Infof("Sending request to %q.", addr)
-		proxyConn, err := net.Dial("unix", udsSockFile)
-		if err != nil {
-			return nil, fmt.Errorf("dialing proxy %q failed: %v", udsSockFile, err)
+		var proxyConn net.Conn
+		var err error
+		if agent := agentKey(addr, header); ri.peers != nil && ri.peers.isRemote(agent) {
+			proxyConn, err = ri.peers.dial(agent, addr)
+		} else {
+			proxyConn, err = dialProxier(udsSockFile, addr, header)
 		}
-
-		var connectHeaders string
-		for _, h := range supportedHeaders {
-			if v := header.Get(h); len(v) != 0 {
-				connectHeaders = fmt.Sprintf("%s\r\n%s: %s", connectHeaders, h, v)
-			}
-		}
-
-		fmt.Fprintf(proxyConn, "CONNECT %s HTTP/1.1\r\nHost: %s%s\r\n\r\n", addr, "127.0.0.1", connectHeaders)
-		br := newBufioReader(proxyConn)
-		defer putBufioReader(br)
-		res, err := http.ReadResponse(br, nil)
 		if err != nil {
-			proxyConn.Close()
-			return nil, fmt.Errorf("reading HTTP response from CONNECT to %s via proxy %s failed: %v", addr, udsSockFile, err)
-		}
-		if res.StatusCode != 200 {
-			proxyConn.Close()
-			return nil, fmt.Errorf("proxy error from %s while dialing %s, code %d: %v", udsSockFile, addr, res.StatusCode, res.Status)
+			return nil, err
 		}
 
+		connectHeaders := formatConnectHeaders(header)
 		// if the request scheme is https, setup a tls connection over the
 		// proxy tunnel (i.e. interceptor <--tls--> kubelet)
 		if isTLS {
@@ -120,9 +111,42 @@ func NewRequestInterceptor(udsSockFile string, cfg *tls.Config) *RequestIntercep
 		return proxyConn, nil
 	}
 
-	return &RequestInterceptor{
-		contextDialer: contextDialer,
+	return ri
+}
+
+// dialProxier sets up a tunnel to addr through the proxier listening on
+// udsSockFile, the proxier selects the agent by addr and the
+// X-Tunnel-Proxy-Host header.
+func dialProxier(udsSockFile, addr string, header http.Header) (net.Conn, error) {
+	proxyConn, err := net.Dial("unix", udsSockFile)
+	if err != nil {
+		return nil, fmt.Errorf("dialing proxy %q failed: %v", udsSockFile, err)
+	}
+
+	fmt.Fprintf(proxyConn, "CONNECT %s HTTP/1.1\r\nHost: %s%s\r\n\r\n", addr, "127.0.0.1", formatConnectHeaders(header))
+	br := newBufioReader(proxyConn)
+	defer putBufioReader(br)
+	res, err := http.ReadResponse(br, nil)
+	if err != nil {
+		proxyConn.Close()
+		return nil, fmt.Errorf("reading HTTP response from CONNECT to %s via proxy %s failed: %v", addr, udsSockFile, err)
+	}
+	if res.StatusCode != 200 {
+		proxyConn.Close()
+		return nil, fmt.Errorf("proxy error from %s while dialing %s, code %d: %v", udsSockFile, addr, res.StatusCode, res.Status)
+	}
+	return proxyConn, nil
+}
+
+// formatConnectHeaders formats the supported headers for the CONNECT request
+func formatConnectHeaders(header http.Header) string {
+	var connectHeaders string
+	for _, h := range supportedHeaders {
+		if v := header.Get(h); len(v) != 0 {
+			connectHeaders = fmt.Sprintf("%s\r\n%s: %s", connectHeaders, h, v)
+		}
 	}
+	return connectHeaders
 }
 
 // copyHeader copy header from src to dst
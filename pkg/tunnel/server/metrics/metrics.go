@@ -42,6 +42,7 @@ type TunnelServerMetrics struct {
 	proxyingRequestsCollector *prometheus.GaugeVec
 	proxyingRequestsGauge     prometheus.Gauge
 	cloudNodeGauge            prometheus.Gauge
+	peerForwardedCounter      prometheus.Counter
 }
 
 func newTunnelServerMetrics() *TunnelServerMetrics {
@@ -67,14 +68,23 @@ func newTunnelServerMetrics() *TunnelServerMetrics {
 			Name:      "cloud_nodes_counter",
 			Help:      "counter of cloud nodes that do not run tunnel agent",
 		})
+	peerForwardedCounter := prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "peer_forwarded_connections",
+			Help:      "counter of connections forwarded to agents through other tunnel server replicas",
+		})
 
 	prometheus.MustRegister(proxyingRequestsCollector)
 	prometheus.MustRegister(proxyingRequestsGauge)
 	prometheus.MustRegister(cloudNodeGauge)
+	prometheus.MustRegister(peerForwardedCounter)
 	return &TunnelServerMetrics{
 		proxyingRequestsCollector: proxyingRequestsCollector,
 		proxyingRequestsGauge:     proxyingRequestsGauge,
 		cloudNodeGauge:            cloudNodeGauge,
+		peerForwardedCounter:      peerForwardedCounter,
 	}
 }
 
@@ -97,3 +107,7 @@ func (tsm *TunnelServerMetrics) DecInFlightRequests(verb, path string) {
 func (tsm *TunnelServerMetrics) ObserveCloudNodes(cnt int) {
 	tsm.cloudNodeGauge.Set(float64(cnt))
 }
+
+func (tsm *TunnelServerMetrics) IncPeerForwardedConnections() {
+	tsm.peerForwardedCounter.Inc()
+}
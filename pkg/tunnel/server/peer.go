@@ -0,0 +1,209 @@
+package server
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	pkgagent "sigs.k8s.io/apiserver-network-proxy/pkg/agent"
+	anpagent "sigs.k8s.io/apiserver-network-proxy/proto/agent"
+	"sigs.k8s.io/apiserver-network-proxy/proto/header"
+
+	"github.com/bhojpur/dcp/pkg/tunnel/constants"
+	"github.com/bhojpur/dcp/pkg/tunnel/remotedialer"
+	"github.com/bhojpur/dcp/pkg/tunnel/server/metrics"
+)
+
+const (
+	// peerDialTimeout is the timeout of setting up a connection through a peer
+	peerDialTimeout = 10 * time.Second
+	// defaultReplicaSyncPeriod is the default period of renewing the record
+	// of a replica in the replica registry
+	defaultReplicaSyncPeriod = 10 * time.Second
+)
+
+// PeerConfig configures the forwarding of requests between tunnel-server
+// replicas. Every replica records the agents connected to it in the replica
+// registry and connects to the other replicas as a remotedialer peer, so a
+// request landing on any replica reaches the replica holding the agent.
+type PeerConfig struct {
+	// ReplicaID identifies the replica, e.g. the pod name
+	ReplicaID string
+	// ListenAddr is the address on which to serve connections from peers
+	ListenAddr string
+	// AdvertiseAddr is the address that peers use to connect to the replica
+	AdvertiseAddr string
+	// Token is shared by all replicas to authenticate peer connections
+	Token string
+	// Client is used to maintain the replica registry
+	Client kubernetes.Interface
+	// SyncPeriod is the period of renewing the record in the replica registry
+	SyncPeriod time.Duration
+}
+
+// peerForwarder forwards connections between tunnel-server replicas by
+// using the remotedialer peer sessions. Agents connected to the replica are
+// announced to the peers as local clients, which are dialed through the
+// proxier of the replica.
+type peerForwarder struct {
+	cfg    *PeerConfig
+	server *remotedialer.Server
+}
+
+func newPeerForwarder(cfg *PeerConfig, udsSockFile string) *peerForwarder {
+	server := remotedialer.New(rejectClients, remotedialer.DefaultErrorWriter)
+	server.PeerID = cfg.ReplicaID
+	server.PeerToken = cfg.Token
+	server.LocalDialer = func(agent string) remotedialer.Dialer {
+		return func(_ context.Context, _, address string) (net.Conn, error) {
+			header := http.Header{}
+			if host, _, err := net.SplitHostPort(address); err != nil || host != agent {
+				header.Set(constants.ProxyHostHeaderKey, agent)
+			}
+			return dialProxier(udsSockFile, address, header)
+		}
+	}
+
+	return &peerForwarder{
+		cfg:    cfg,
+		server: server,
+	}
+}
+
+// rejectClients rejects all of non-peer connections, agents connect to
+// the tunnel-server through the agent server.
+func rejectClients(req *http.Request) (string, bool, error) {
+	return "", false, nil
+}
+
+// isRemote returns true if the agent isn't connected to this replica but
+// is announced by one of the peers.
+func (pf *peerForwarder) isRemote(agent string) bool {
+	return !pf.server.HasLocalClient(agent) && pf.server.HasSession(agent)
+}
+
+// dial sets up a tunnel to addr through the peer holding the agent
+func (pf *peerForwarder) dial(agent, addr string) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), peerDialTimeout)
+	defer cancel()
+
+	conn, err := pf.server.Dialer(agent)(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("dialing %s through peer of agent %s failed: %v", addr, agent, err)
+	}
+	metrics.Metrics.IncPeerForwardedConnections()
+	klog.V(4).Infof("forward connection to %q of agent %s through peer", addr, agent)
+	return conn, nil
+}
+
+// run serves the connections from peers and keeps the replica registry
+// and the peers in sync.
+func (pf *peerForwarder) run(tlsCfg *tls.Config, stopCh <-chan struct{}) {
+	go func() {
+		klog.Infof("start handling connection from peers at %s", pf.cfg.ListenAddr)
+		server := http.Server{
+			Addr:         pf.cfg.ListenAddr,
+			Handler:      pf.server,
+			TLSConfig:    tlsCfg,
+			TLSNextProto: make(map[string]func(*http.Server, *tls.Conn, http.Handler)),
+		}
+		if err := server.ListenAndServeTLS("", ""); err != nil {
+			klog.Errorf("failed to serve connection from peers: %v", err)
+		}
+	}()
+
+	registry := newReplicaRegistry(pf.cfg, pf.server)
+	go registry.Run(stopCh)
+}
+
+// agentServer wraps the AgentServiceServer of the proxy server to announce
+// the connected agents to the peers.
+type agentServer struct {
+	anpagent.AgentServiceServer
+	peers *peerForwarder
+}
+
+// Connect is called when an agent connects to the replica, it returns when
+// the agent disconnects.
+func (as *agentServer) Connect(stream anpagent.AgentService_ConnectServer) error {
+	agents := agentKeys(stream.Context())
+	for _, agent := range agents {
+		as.peers.server.AddLocalClient(agent)
+	}
+	defer func() {
+		for _, agent := range agents {
+			as.peers.server.RemoveLocalClient(agent)
+		}
+	}()
+	return as.AgentServiceServer.Connect(stream)
+}
+
+// agentKeys returns the agent ID and identifiers of the agent connecting
+// with ctx, they are the keys that the proxier selects the agent by.
+func agentKeys(ctx context.Context) []string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	var keys []string
+	seen := map[string]bool{}
+	add := func(values ...string) {
+		for _, v := range values {
+			if len(v) != 0 && !seen[v] {
+				seen[v] = true
+				keys = append(keys, v)
+			}
+		}
+	}
+	add(md.Get(header.AgentID)...)
+	for _, ids := range md.Get(header.AgentIdentifiers) {
+		identifiers, err := pkgagent.GenAgentIdentifiers(ids)
+		if err != nil {
+			klog.Errorf("failed to parse agent identifiers %q, %v", ids, err)
+			continue
+		}
+		add(identifiers.Host...)
+		add(identifiers.IPv4...)
+		add(identifiers.IPv6...)
+	}
+	return keys
+}
+
+// agentKey returns the key of the agent that serves a request to addr
+func agentKey(addr string, header http.Header) string {
+	if host := header.Get(constants.ProxyHostHeaderKey); len(host) != 0 {
+		return host
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
@@ -0,0 +1,233 @@
+package server
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	"github.com/bhojpur/dcp/pkg/projectinfo"
+	"github.com/bhojpur/dcp/pkg/tunnel/constants"
+)
+
+// GetTunnelServerReplicasConfigMapName returns the name of the configmap
+// used as the replica registry
+func GetTunnelServerReplicasConfigMapName() string {
+	return fmt.Sprintf(constants.TunnelServerReplicasConfigMapName,
+		strings.TrimRightFunc(projectinfo.GetProjectPrefix(), func(c rune) bool { return c == '-' }))
+}
+
+// replicaRecord is the record of a replica in the replica registry
+type replicaRecord struct {
+	// PeerURL is the url that peers use to connect to the replica
+	PeerURL string `json:"peerURL"`
+	// Agents are the keys of the agents connected to the replica
+	Agents []string `json:"agents,omitempty"`
+	// RenewTime is the last time the replica renewed the record
+	RenewTime metav1.Time `json:"renewTime"`
+}
+
+// peerManager manages the remotedialer peers of a replica
+type peerManager interface {
+	AddPeer(url, id, token string)
+	RemovePeer(id string)
+	LocalClients() []string
+}
+
+// replicaRegistry records which replica holds which agent sessions in a
+// configmap, each replica owns the data key of its ID. The records are also
+// used to discover the peers of the replica, a record that has not been
+// renewed for three sync periods is considered expired and removed.
+type replicaRegistry struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	id        string
+	peerURL   string
+	token     string
+	period    time.Duration
+	peers     peerManager
+	// known are the peers that have been added, keyed by the replica ID
+	known map[string]string
+	now   func() time.Time
+}
+
+func newReplicaRegistry(cfg *PeerConfig, peers peerManager) *replicaRegistry {
+	period := cfg.SyncPeriod
+	if period <= 0 {
+		period = defaultReplicaSyncPeriod
+	}
+	return &replicaRegistry{
+		client:    cfg.Client,
+		namespace: constants.TunnelServerServiceNs,
+		name:      GetTunnelServerReplicasConfigMapName(),
+		id:        cfg.ReplicaID,
+		peerURL:   fmt.Sprintf("wss://%s/v1/peer", cfg.AdvertiseAddr),
+		token:     cfg.Token,
+		period:    period,
+		peers:     peers,
+		known:     map[string]string{},
+		now:       time.Now,
+	}
+}
+
+// Run renews the record of the replica and syncs the peers periodically,
+// the record is removed when stopCh is closed.
+func (r *replicaRegistry) Run(stopCh <-chan struct{}) {
+	klog.Infof("starting replica registry for replica %s", r.id)
+	wait.Until(func() {
+		if err := r.sync(); err != nil {
+			klog.Errorf("failed to sync replica registry, %v", err)
+		}
+	}, r.period, stopCh)
+
+	if err := r.remove(); err != nil {
+		klog.Errorf("failed to remove replica %s from registry, %v", r.id, err)
+	}
+}
+
+// sync renews the record of the replica, removes the expired records and
+// updates the peers according to the remaining records.
+func (r *replicaRegistry) sync() error {
+	var records map[string]replicaRecord
+	err := r.update(func(current map[string]replicaRecord) {
+		now := r.now()
+		agents := r.peers.LocalClients()
+		sort.Strings(agents)
+		current[r.id] = replicaRecord{
+			PeerURL:   r.peerURL,
+			Agents:    agents,
+			RenewTime: metav1.NewTime(now),
+		}
+		for id, record := range current {
+			if id != r.id && now.Sub(record.RenewTime.Time) > 3*r.period {
+				klog.Infof("remove expired replica %s from registry", id)
+				delete(current, id)
+			}
+		}
+		records = current
+	})
+	if err != nil {
+		return err
+	}
+
+	r.syncPeers(records)
+	return nil
+}
+
+// syncPeers adds the replicas in records as peers, and removes the peers
+// that are not in records any more.
+func (r *replicaRegistry) syncPeers(records map[string]replicaRecord) {
+	for id, record := range records {
+		if id == r.id {
+			continue
+		}
+		if url, ok := r.known[id]; !ok || url != record.PeerURL {
+			r.peers.AddPeer(record.PeerURL, id, r.token)
+			r.known[id] = record.PeerURL
+		}
+	}
+	for id := range r.known {
+		if _, ok := records[id]; !ok {
+			r.peers.RemovePeer(id)
+			delete(r.known, id)
+		}
+	}
+}
+
+// remove deletes the record of the replica from the registry
+func (r *replicaRegistry) remove() error {
+	return r.update(func(current map[string]replicaRecord) {
+		delete(current, r.id)
+	})
+}
+
+// update applies mutate to the records in the registry, the configmap is
+// created if it doesn't exist.
+func (r *replicaRegistry) update(mutate func(map[string]replicaRecord)) error {
+	return retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}, func() error {
+		cm, err := r.client.CoreV1().ConfigMaps(r.namespace).Get(context.Background(), r.name, metav1.GetOptions{})
+		create := false
+		if apierrors.IsNotFound(err) {
+			create = true
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      r.name,
+					Namespace: r.namespace,
+				},
+			}
+		} else if err != nil {
+			return err
+		}
+
+		records := decodeReplicaRecords(cm.Data)
+		mutate(records)
+		if cm.Data, err = encodeReplicaRecords(records); err != nil {
+			return err
+		}
+
+		if create {
+			_, err = r.client.CoreV1().ConfigMaps(r.namespace).Create(context.Background(), cm, metav1.CreateOptions{})
+		} else {
+			_, err = r.client.CoreV1().ConfigMaps(r.namespace).Update(context.Background(), cm, metav1.UpdateOptions{})
+		}
+		return err
+	})
+}
+
+func decodeReplicaRecords(data map[string]string) map[string]replicaRecord {
+	records := make(map[string]replicaRecord, len(data))
+	for id, raw := range data {
+		var record replicaRecord
+		if err := json.Unmarshal([]byte(raw), &record); err != nil {
+			klog.Warningf("skip invalid record of replica %s, %v", id, err)
+			continue
+		}
+		records[id] = record
+	}
+	return records
+}
+
+func encodeReplicaRecords(records map[string]replicaRecord) (map[string]string, error) {
+	data := make(map[string]string, len(records))
+	for id, record := range records {
+		raw, err := json.Marshal(record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode record of replica %s, %v", id, err)
+		}
+		data[id] = string(raw)
+	}
+	return data, nil
+}
@@ -0,0 +1,154 @@
+package server
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/bhojpur/dcp/pkg/tunnel/constants"
+)
+
+type fakePeerManager struct {
+	agents []string
+	peers  map[string]string
+}
+
+func (f *fakePeerManager) AddPeer(url, id, token string) {
+	f.peers[id] = url
+}
+
+func (f *fakePeerManager) RemovePeer(id string) {
+	delete(f.peers, id)
+}
+
+func (f *fakePeerManager) LocalClients() []string {
+	return f.agents
+}
+
+func newTestRegistry(client *fake.Clientset, id, addr string, agents []string, now time.Time) (*replicaRegistry, *fakePeerManager) {
+	peers := &fakePeerManager{agents: agents, peers: map[string]string{}}
+	r := newReplicaRegistry(&PeerConfig{
+		ReplicaID:     id,
+		AdvertiseAddr: addr,
+		Token:         "token",
+		Client:        client,
+		SyncPeriod:    10 * time.Second,
+	}, peers)
+	r.now = func() time.Time { return now }
+	return r, peers
+}
+
+func TestReplicaRegistrySync(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	now := time.Now()
+
+	r1, peers1 := newTestRegistry(client, "server-1", "10.0.0.1:10269", []string{"node-b", "node-a"}, now)
+	r2, peers2 := newTestRegistry(client, "server-2", "10.0.0.2:10269", []string{"node-c"}, now)
+
+	if err := r1.sync(); err != nil {
+		t.Fatalf("failed to sync registry, %v", err)
+	}
+	if len(peers1.peers) != 0 {
+		t.Errorf("expected no peers, got %v", peers1.peers)
+	}
+	if err := r2.sync(); err != nil {
+		t.Fatalf("failed to sync registry, %v", err)
+	}
+	if err := r1.sync(); err != nil {
+		t.Fatalf("failed to sync registry, %v", err)
+	}
+
+	if !reflect.DeepEqual(peers1.peers, map[string]string{"server-2": "wss://10.0.0.2:10269/v1/peer"}) {
+		t.Errorf("unexpected peers of server-1, %v", peers1.peers)
+	}
+	if !reflect.DeepEqual(peers2.peers, map[string]string{"server-1": "wss://10.0.0.1:10269/v1/peer"}) {
+		t.Errorf("unexpected peers of server-2, %v", peers2.peers)
+	}
+
+	cm, err := client.CoreV1().ConfigMaps(constants.TunnelServerServiceNs).Get(context.Background(), GetTunnelServerReplicasConfigMapName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get registry, %v", err)
+	}
+	records := decodeReplicaRecords(cm.Data)
+	if !reflect.DeepEqual(records["server-1"].Agents, []string{"node-a", "node-b"}) {
+		t.Errorf("unexpected agents of server-1, %v", records["server-1"].Agents)
+	}
+	if !reflect.DeepEqual(records["server-2"].Agents, []string{"node-c"}) {
+		t.Errorf("unexpected agents of server-2, %v", records["server-2"].Agents)
+	}
+
+	// server-2 stops renewing its record
+	r1.now = func() time.Time { return now.Add(time.Minute) }
+	if err := r1.sync(); err != nil {
+		t.Fatalf("failed to sync registry, %v", err)
+	}
+	if len(peers1.peers) != 0 {
+		t.Errorf("expected expired peer to be removed, got %v", peers1.peers)
+	}
+
+	if err := r1.remove(); err != nil {
+		t.Fatalf("failed to remove replica, %v", err)
+	}
+	cm, err = client.CoreV1().ConfigMaps(constants.TunnelServerServiceNs).Get(context.Background(), GetTunnelServerReplicasConfigMapName(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get registry, %v", err)
+	}
+	if len(cm.Data) != 0 {
+		t.Errorf("expected empty registry, got %v", cm.Data)
+	}
+}
+
+func TestAgentKeys(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"agentID", "node-a",
+		"agentIdentifiers", "host=node-a&ipv4=192.168.0.10&ipv4=192.168.0.11",
+	))
+	keys := agentKeys(ctx)
+	sort.Strings(keys)
+	expected := []string{"192.168.0.10", "192.168.0.11", "node-a"}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected agent keys %v, got %v", expected, keys)
+	}
+
+	if keys := agentKeys(context.Background()); len(keys) != 0 {
+		t.Errorf("expected no agent keys, got %v", keys)
+	}
+}
+
+func TestAgentKey(t *testing.T) {
+	header := http.Header{}
+	if key := agentKey("192.168.0.10:10250", header); key != "192.168.0.10" {
+		t.Errorf("expected agent key 192.168.0.10, got %s", key)
+	}
+	header.Set(constants.ProxyHostHeaderKey, "node-a")
+	if key := agentKey("127.0.0.1:10255", header); key != "node-a" {
+		t.Errorf("expected agent key node-a, got %s", key)
+	}
+}
@@ -42,7 +42,9 @@ func NewTunnelServer(
 	serverCount int,
 	tlsCfg *tls.Config,
 	wrappers hw.HandlerWrappers,
-	proxyStrategy string) TunnelServer {
+	proxyStrategy string,
+	peerCfg *PeerConfig,
+	stopCh <-chan struct{}) TunnelServer {
 	ats := anpTunnelServer{
 		egressSelectorEnabled:    egressSelectorEnabled,
 		interceptorServerUDSFile: interceptorServerUDSFile,
@@ -53,6 +55,8 @@ func NewTunnelServer(
 		tlsCfg:                   tlsCfg,
 		wrappers:                 wrappers,
 		proxyStrategy:            proxyStrategy,
+		peerCfg:                  peerCfg,
+		stopCh:                   stopCh,
 	}
 	return &ats
 }
@@ -89,14 +89,18 @@ type coreDNSRecordController struct {
 	syncPeriod           int
 	listenInsecureAddr   string
 	listenSecureAddr     string
+	records              *recordSet
+	dnsServer            *dnsServer
 }
 
-// NewCoreDNSRecordController create a CoreDNSRecordController that synchronizes node dns records with CoreDNS configuration
+// NewCoreDNSRecordController create a CoreDNSRecordController that synchronizes node dns records with CoreDNS configuration.
+// If dnsServerAddr is not empty, the records are also served by an embedded dns server listening on that address.
 func NewCoreDNSRecordController(client clientset.Interface,
 	informerFactory informers.SharedInformerFactory,
 	listenInsecureAddr string,
 	listenSecureAddr string,
-	syncPeriod int) (DNSRecordController, error) {
+	syncPeriod int,
+	dnsServerAddr string) (DNSRecordController, error) {
 	dnsctl := &coreDNSRecordController{
 		kubeClient:           client,
 		syncPeriod:           syncPeriod,
@@ -104,6 +108,10 @@ func NewCoreDNSRecordController(client clientset.Interface,
 		listenSecureAddr:     listenSecureAddr,
 		sharedInformerFactor: informerFactory,
 		queue:                workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "tunnel-dns"),
+		records:              newRecordSet(),
+	}
+	if len(dnsServerAddr) != 0 {
+		dnsctl.dnsServer = newDNSServer(dnsServerAddr, dnsctl.records)
 	}
 
 	nodeInformer := informerFactory.Core().V1().Nodes()
@@ -140,6 +148,16 @@ func NewCoreDNSRecordController(client clientset.Interface,
 }
 
 func (dnsctl *coreDNSRecordController) Run(stopCh <-chan struct{}) {
+	// the embedded dns server runs on every replica, replicas that are not
+	// the leader learn the records from the dns record ConfigMap
+	if dnsctl.dnsServer != nil {
+		go func() {
+			if err := dnsctl.dnsServer.Run(stopCh); err != nil {
+				klog.Fatalf("failed to run embedded dns server, %v", err)
+			}
+		}()
+	}
+
 	electionChecker := leaderelection.NewLeaderHealthzAdaptor(time.Second * 20)
 	id, err := os.Hostname()
 	if err != nil {
@@ -367,6 +385,9 @@ func (dnsctl *coreDNSRecordController) getTunnelServerIP(useCache bool) (string,
 func (dnsctl *coreDNSRecordController) updateDNSRecords(records []string) error {
 	// keep sorted
 	sort.Strings(records)
+	// the embedded DNS server answers from memory, so it stays current even
+	// when persisting the ConfigMap fails and is retried on the next sync
+	dnsctl.records.Replace(records)
 
 	cm, err := dnsctl.kubeClient.CoreV1().ConfigMaps(constants.TunnelServerServiceNs).
 		Get(context.Background(), dcptunnelDNSRecordConfigMapName, metav1.GetOptions{})
@@ -27,8 +27,24 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
+func TestUpdateDNSRecordsWithoutConfigMap(t *testing.T) {
+	dnsctl := &coreDNSRecordController{
+		kubeClient: fake.NewSimpleClientset(),
+		records:    newRecordSet(),
+	}
+
+	// the ConfigMap does not exist, the records are still served
+	if err := dnsctl.updateDNSRecords([]string{formatDNSRecord("10.96.0.100", "edge-node")}); err == nil {
+		t.Fatalf("expected an error updating a missing ConfigMap")
+	}
+	if ip, ok := dnsctl.records.Lookup("edge-node"); !ok || ip.String() != "10.96.0.100" {
+		t.Errorf("expected edge-node to resolve to 10.96.0.100, got %v", ip)
+	}
+}
+
 func TestResolveServicePorts(t *testing.T) {
 	testcases := map[string]struct {
 		service             *corev1.Service
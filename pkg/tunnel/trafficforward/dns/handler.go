@@ -94,6 +94,7 @@ func (dnsctl *coreDNSRecordController) addConfigMap(obj interface{}) {
 		dnsctl.deleteConfigMap(cm)
 		return
 	}
+	dnsctl.refreshRecords(cm)
 	klog.V(2).Infof("enqueue configmap add event for %v/%v", cm.Namespace, cm.Name)
 	dnsctl.enqueue(cm, ConfigMapAdd)
 }
@@ -111,6 +112,7 @@ func (dnsctl *coreDNSRecordController) updateConfigMap(oldObj, newObj interface{
 	if reflect.DeepEqual(oldConfigMap.Data, newConfigMap.Data) {
 		return
 	}
+	dnsctl.refreshRecords(newConfigMap)
 
 	klog.V(2).Infof("enqueue configmap update event for %v/%v, will sync tunnel server svc", newConfigMap.Namespace, newConfigMap.Name)
 	dnsctl.enqueue(newConfigMap, ConfigMapUpdate)
@@ -134,6 +136,16 @@ func (dnsctl *coreDNSRecordController) deleteConfigMap(obj interface{}) {
 	dnsctl.enqueue(cm, ConfigMapDelete)
 }
 
+// refreshRecords loads the in-memory records served by the embedded dns
+// server from the dns record ConfigMap, so that replicas which are not the
+// leader serve the same records as the leader.
+func (dnsctl *coreDNSRecordController) refreshRecords(cm *corev1.ConfigMap) {
+	if cm.Namespace != constants.TunnelServerServiceNs || cm.Name != dcptunnelDNSRecordConfigMapName {
+		return
+	}
+	dnsctl.records.Replace(strings.Split(cm.Data[constants.TunnelDNSRecordNodeDataKey], "\n"))
+}
+
 func (dnsctl *coreDNSRecordController) addService(obj interface{}) {
 	svc, ok := obj.(*corev1.Service)
 	if !ok {
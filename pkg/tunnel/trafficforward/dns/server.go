@@ -0,0 +1,379 @@
+package dns
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// defaultRecordTTL is the ttl of answers. Records change when nodes join
+	// or leave the cluster, so a short ttl keeps resolvers up to date.
+	defaultRecordTTL = 5
+	// maxUDPMessageSize is the largest response sent over udp, larger
+	// responses are truncated so that the client retries over tcp.
+	maxUDPMessageSize = 512
+	tcpIdleTimeout    = 10 * time.Second
+)
+
+// recordSet is the in-memory set of node dns records, it maps node
+// hostnames to the address that should be used to reach the node.
+type recordSet struct {
+	sync.RWMutex
+	hosts map[string]net.IP
+	names map[string][]string
+}
+
+func newRecordSet() *recordSet {
+	return &recordSet{
+		hosts: map[string]net.IP{},
+		names: map[string][]string{},
+	}
+}
+
+// Replace replaces all records with records in the "ip\thostname" format
+// that is used in the dns record ConfigMap.
+func (rs *recordSet) Replace(records []string) {
+	hosts := make(map[string]net.IP, len(records))
+	names := make(map[string][]string, len(records))
+	for _, record := range records {
+		if len(record) == 0 {
+			continue
+		}
+		arr := strings.Split(record, "\t")
+		if len(arr) != 2 {
+			klog.Warningf("skip invalid dns record %q", record)
+			continue
+		}
+		ip := net.ParseIP(arr[0])
+		if ip == nil {
+			klog.Warningf("skip dns record %q with invalid ip", record)
+			continue
+		}
+		host := canonicalName(arr[1])
+		hosts[host] = ip
+		names[ip.String()] = append(names[ip.String()], host)
+	}
+	for _, hostnames := range names {
+		sort.Strings(hostnames)
+	}
+
+	rs.Lock()
+	defer rs.Unlock()
+	rs.hosts = hosts
+	rs.names = names
+}
+
+// Lookup returns the address of the specified hostname.
+func (rs *recordSet) Lookup(host string) (net.IP, bool) {
+	rs.RLock()
+	defer rs.RUnlock()
+	ip, ok := rs.hosts[canonicalName(host)]
+	return ip, ok
+}
+
+// ReverseLookup returns the hostnames that resolve to the specified ip.
+func (rs *recordSet) ReverseLookup(ip net.IP) []string {
+	rs.RLock()
+	defer rs.RUnlock()
+	return rs.names[ip.String()]
+}
+
+func canonicalName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// dnsServer is a minimal authoritative dns server that answers A, AAAA and
+// PTR queries for node hostnames from a recordSet. It can be used as the
+// target of the CoreDNS forward plugin instead of the hosts plugin.
+type dnsServer struct {
+	addr    string
+	records *recordSet
+	ttl     uint32
+}
+
+func newDNSServer(addr string, records *recordSet) *dnsServer {
+	return &dnsServer{
+		addr:    addr,
+		records: records,
+		ttl:     defaultRecordTTL,
+	}
+}
+
+// Run serves dns queries over udp and tcp until stopCh is closed.
+func (s *dnsServer) Run(stopCh <-chan struct{}) error {
+	pc, err := net.ListenPacket("udp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on udp %s, %v", s.addr, err)
+	}
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		pc.Close()
+		return fmt.Errorf("failed to listen on tcp %s, %v", s.addr, err)
+	}
+	klog.Infof("embedded dns server is listening on %s", s.addr)
+
+	go s.serveUDP(pc)
+	go s.serveTCP(ln)
+
+	<-stopCh
+	pc.Close()
+	ln.Close()
+	return nil
+}
+
+func (s *dnsServer) serveUDP(pc net.PacketConn) {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			if isClosedConnError(err) {
+				return
+			}
+			klog.Errorf("failed to read dns query, %v", err)
+			continue
+		}
+		resp, err := s.handle(buf[:n], maxUDPMessageSize)
+		if err != nil {
+			klog.V(4).Infof("failed to handle dns query from %s, %v", addr, err)
+			continue
+		}
+		if _, err := pc.WriteTo(resp, addr); err != nil {
+			klog.Errorf("failed to write dns response to %s, %v", addr, err)
+		}
+	}
+}
+
+func (s *dnsServer) serveTCP(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if isClosedConnError(err) {
+				return
+			}
+			klog.Errorf("failed to accept dns connection, %v", err)
+			continue
+		}
+		go s.serveTCPConn(conn)
+	}
+}
+
+// serveTCPConn serves length prefixed dns messages as described in RFC 1035 4.2.2
+func (s *dnsServer) serveTCPConn(conn net.Conn) {
+	defer conn.Close()
+	for {
+		conn.SetDeadline(time.Now().Add(tcpIdleTimeout))
+		var length uint16
+		if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+			return
+		}
+		req := make([]byte, length)
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+		resp, err := s.handle(req, 0)
+		if err != nil {
+			klog.V(4).Infof("failed to handle dns query from %s, %v", conn.RemoteAddr(), err)
+			return
+		}
+		out := make([]byte, 2+len(resp))
+		binary.BigEndian.PutUint16(out, uint16(len(resp)))
+		copy(out[2:], resp)
+		if _, err := conn.Write(out); err != nil {
+			return
+		}
+	}
+}
+
+// handle answers the query in req. If maxSize is positive and the response
+// is larger, the answers are dropped and the response is marked truncated.
+func (s *dnsServer) handle(req []byte, maxSize int) ([]byte, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(req)
+	if err != nil {
+		return nil, err
+	}
+	question, err := parser.Question()
+	if err != nil && err != dnsmessage.ErrSectionDone {
+		return nil, err
+	}
+
+	respHeader := dnsmessage.Header{
+		ID:                 header.ID,
+		Response:           true,
+		OpCode:             header.OpCode,
+		Authoritative:      true,
+		RecursionDesired:   header.RecursionDesired,
+		RecursionAvailable: false,
+	}
+	if err == dnsmessage.ErrSectionDone || header.OpCode != 0 {
+		respHeader.RCode = dnsmessage.RCodeFormatError
+		if header.OpCode != 0 {
+			respHeader.RCode = dnsmessage.RCodeNotImplemented
+		}
+		return buildResponse(respHeader, nil, nil)
+	}
+
+	answers, found := s.answer(question)
+	if !found {
+		respHeader.RCode = dnsmessage.RCodeNameError
+	}
+	resp, err := buildResponse(respHeader, &question, answers)
+	if err != nil {
+		return nil, err
+	}
+	if maxSize > 0 && len(resp) > maxSize {
+		respHeader.Truncated = true
+		return buildResponse(respHeader, &question, nil)
+	}
+	return resp, nil
+}
+
+// answer returns the resource records for question, and false if the
+// queried name is unknown.
+func (s *dnsServer) answer(q dnsmessage.Question) ([]dnsmessage.Resource, bool) {
+	rrHeader := dnsmessage.ResourceHeader{
+		Name:  q.Name,
+		Type:  q.Type,
+		Class: dnsmessage.ClassINET,
+		TTL:   s.ttl,
+	}
+
+	if q.Type == dnsmessage.TypePTR {
+		ip := parseReverseName(q.Name.String())
+		if ip == nil {
+			return nil, false
+		}
+		hostnames := s.records.ReverseLookup(ip)
+		if len(hostnames) == 0 {
+			return nil, false
+		}
+		answers := make([]dnsmessage.Resource, 0, len(hostnames))
+		for _, host := range hostnames {
+			name, err := dnsmessage.NewName(host + ".")
+			if err != nil {
+				continue
+			}
+			answers = append(answers, dnsmessage.Resource{
+				Header: rrHeader,
+				Body:   &dnsmessage.PTRResource{PTR: name},
+			})
+		}
+		return answers, true
+	}
+
+	ip, ok := s.records.Lookup(q.Name.String())
+	if !ok {
+		return nil, false
+	}
+	// a known name without a record of the queried type is answered
+	// with an empty NOERROR response
+	switch {
+	case q.Type == dnsmessage.TypeA && ip.To4() != nil:
+		var a [4]byte
+		copy(a[:], ip.To4())
+		return []dnsmessage.Resource{{Header: rrHeader, Body: &dnsmessage.AResource{A: a}}}, true
+	case q.Type == dnsmessage.TypeAAAA && ip.To4() == nil:
+		var aaaa [16]byte
+		copy(aaaa[:], ip.To16())
+		return []dnsmessage.Resource{{Header: rrHeader, Body: &dnsmessage.AAAAResource{AAAA: aaaa}}}, true
+	}
+	return nil, true
+}
+
+func buildResponse(header dnsmessage.Header, question *dnsmessage.Question, answers []dnsmessage.Resource) ([]byte, error) {
+	builder := dnsmessage.NewBuilder(nil, header)
+	builder.EnableCompression()
+	if question != nil {
+		if err := builder.StartQuestions(); err != nil {
+			return nil, err
+		}
+		if err := builder.Question(*question); err != nil {
+			return nil, err
+		}
+	}
+	if err := builder.StartAnswers(); err != nil {
+		return nil, err
+	}
+	for _, rr := range answers {
+		var err error
+		switch body := rr.Body.(type) {
+		case *dnsmessage.AResource:
+			err = builder.AResource(rr.Header, *body)
+		case *dnsmessage.AAAAResource:
+			err = builder.AAAAResource(rr.Header, *body)
+		case *dnsmessage.PTRResource:
+			err = builder.PTRResource(rr.Header, *body)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return builder.Finish()
+}
+
+// parseReverseName parses names like 4.3.2.1.in-addr.arpa. and the nibble
+// format of ip6.arpa.
+func parseReverseName(name string) net.IP {
+	name = canonicalName(name)
+	switch {
+	case strings.HasSuffix(name, ".in-addr.arpa"):
+		labels := strings.Split(strings.TrimSuffix(name, ".in-addr.arpa"), ".")
+		if len(labels) != 4 {
+			return nil
+		}
+		for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+			labels[i], labels[j] = labels[j], labels[i]
+		}
+		return net.ParseIP(strings.Join(labels, ".")).To4()
+	case strings.HasSuffix(name, ".ip6.arpa"):
+		labels := strings.Split(strings.TrimSuffix(name, ".ip6.arpa"), ".")
+		if len(labels) != 32 {
+			return nil
+		}
+		var b strings.Builder
+		for i := len(labels) - 1; i >= 0; i-- {
+			if len(labels[i]) != 1 {
+				return nil
+			}
+			b.WriteString(labels[i])
+			if i%4 == 0 && i != 0 {
+				b.WriteString(":")
+			}
+		}
+		return net.ParseIP(b.String())
+	}
+	return nil
+}
+
+func isClosedConnError(err error) bool {
+	return strings.Contains(err.Error(), "use of closed network connection")
+}
@@ -0,0 +1,263 @@
+package dns
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func newQuery(t *testing.T, name string, qtype dnsmessage.Type) []byte {
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: 42, RecursionDesired: true})
+	if err := builder.StartQuestions(); err != nil {
+		t.Fatal(err)
+	}
+	if err := builder.Question(dnsmessage.Question{
+		Name:  dnsmessage.MustNewName(name),
+		Type:  qtype,
+		Class: dnsmessage.ClassINET,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	query, err := builder.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return query
+}
+
+func parseResponse(t *testing.T, resp []byte) dnsmessage.Message {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(resp); err != nil {
+		t.Fatalf("failed to unpack response, %v", err)
+	}
+	return msg
+}
+
+func TestDNSServerHandle(t *testing.T) {
+	records := newRecordSet()
+	records.Replace([]string{
+		formatDNSRecord("10.96.0.100", "edge-node-1"),
+		formatDNSRecord("10.96.0.100", "edge-node-2"),
+		formatDNSRecord("192.168.0.10", "Cloud-Node"),
+		formatDNSRecord("fd00::10", "cloud-node-v6"),
+		"invalid record",
+		"",
+	})
+	server := newDNSServer("", records)
+
+	tests := map[string]struct {
+		name     string
+		qtype    dnsmessage.Type
+		rcode    dnsmessage.RCode
+		expected []string
+	}{
+		"A record of edge node": {
+			name:     "edge-node-1.",
+			qtype:    dnsmessage.TypeA,
+			rcode:    dnsmessage.RCodeSuccess,
+			expected: []string{"10.96.0.100"},
+		},
+		"A record is case insensitive": {
+			name:     "cloud-node.",
+			qtype:    dnsmessage.TypeA,
+			rcode:    dnsmessage.RCodeSuccess,
+			expected: []string{"192.168.0.10"},
+		},
+		"AAAA record": {
+			name:     "cloud-node-v6.",
+			qtype:    dnsmessage.TypeAAAA,
+			rcode:    dnsmessage.RCodeSuccess,
+			expected: []string{"fd00::10"},
+		},
+		"AAAA query of ipv4 node": {
+			name:  "edge-node-1.",
+			qtype: dnsmessage.TypeAAAA,
+			rcode: dnsmessage.RCodeSuccess,
+		},
+		"unknown node": {
+			name:  "unknown-node.",
+			qtype: dnsmessage.TypeA,
+			rcode: dnsmessage.RCodeNameError,
+		},
+		"PTR record of tunnel server": {
+			name:     "100.0.96.10.in-addr.arpa.",
+			qtype:    dnsmessage.TypePTR,
+			rcode:    dnsmessage.RCodeSuccess,
+			expected: []string{"edge-node-1.", "edge-node-2."},
+		},
+		"PTR record of ipv6 node": {
+			name:     "0.1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.d.f.ip6.arpa.",
+			qtype:    dnsmessage.TypePTR,
+			rcode:    dnsmessage.RCodeSuccess,
+			expected: []string{"cloud-node-v6."},
+		},
+		"PTR record of unknown ip": {
+			name:  "1.0.168.192.in-addr.arpa.",
+			qtype: dnsmessage.TypePTR,
+			rcode: dnsmessage.RCodeNameError,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp, err := server.handle(newQuery(t, tt.name, tt.qtype), maxUDPMessageSize)
+			if err != nil {
+				t.Fatalf("failed to handle query, %v", err)
+			}
+			msg := parseResponse(t, resp)
+			if msg.Header.ID != 42 || !msg.Header.Response || !msg.Header.Authoritative {
+				t.Errorf("unexpected response header %v", msg.Header)
+			}
+			if msg.Header.RCode != tt.rcode {
+				t.Errorf("expected rcode %v, got %v", tt.rcode, msg.Header.RCode)
+			}
+			var answers []string
+			for _, rr := range msg.Answers {
+				switch body := rr.Body.(type) {
+				case *dnsmessage.AResource:
+					answers = append(answers, net.IP(body.A[:]).String())
+				case *dnsmessage.AAAAResource:
+					answers = append(answers, net.IP(body.AAAA[:]).String())
+				case *dnsmessage.PTRResource:
+					answers = append(answers, body.PTR.String())
+				}
+			}
+			if len(answers) != len(tt.expected) {
+				t.Fatalf("expected answers %v, got %v", tt.expected, answers)
+			}
+			for i := range answers {
+				if answers[i] != tt.expected[i] {
+					t.Errorf("expected answers %v, got %v", tt.expected, answers)
+				}
+			}
+		})
+	}
+}
+
+func TestDNSServerTruncate(t *testing.T) {
+	records := make([]string, 0, 50)
+	for i := 0; i < 50; i++ {
+		records = append(records, formatDNSRecord("10.96.0.100", "a-long-edge-node-hostname-"+string(rune('a'+i%26))+string(rune('a'+i/26))))
+	}
+	rs := newRecordSet()
+	rs.Replace(records)
+	server := newDNSServer("", rs)
+
+	query := newQuery(t, "100.0.96.10.in-addr.arpa.", dnsmessage.TypePTR)
+	msg := parseResponse(t, mustHandle(t, server, query, maxUDPMessageSize))
+	if !msg.Header.Truncated || len(msg.Answers) != 0 {
+		t.Errorf("expected truncated udp response without answers, got %v with %d answers", msg.Header, len(msg.Answers))
+	}
+
+	msg = parseResponse(t, mustHandle(t, server, query, 0))
+	if msg.Header.Truncated || len(msg.Answers) != 50 {
+		t.Errorf("expected full tcp response, got %v with %d answers", msg.Header, len(msg.Answers))
+	}
+}
+
+func mustHandle(t *testing.T, server *dnsServer, query []byte, maxSize int) []byte {
+	resp, err := server.handle(query, maxSize)
+	if err != nil {
+		t.Fatalf("failed to handle query, %v", err)
+	}
+	return resp
+}
+
+func TestDNSServerRun(t *testing.T) {
+	rs := newRecordSet()
+	rs.Replace([]string{formatDNSRecord("10.96.0.100", "edge-node-1")})
+
+	// pick a free port for both udp and tcp
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	server := newDNSServer(addr, rs)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	errCh := make(chan error, 1)
+	go func() { errCh <- server.Run(stopCh) }()
+
+	query := newQuery(t, "edge-node-1.", dnsmessage.TypeA)
+
+	// udp
+	var resp []byte
+	for i := 0; i < 50; i++ {
+		select {
+		case err := <-errCh:
+			t.Fatalf("dns server exited, %v", err)
+		default:
+		}
+		conn, err := net.Dial("udp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.SetDeadline(time.Now().Add(100 * time.Millisecond))
+		conn.Write(query)
+		buf := make([]byte, 512)
+		n, err := conn.Read(buf)
+		conn.Close()
+		if err == nil {
+			resp = buf[:n]
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if resp == nil {
+		t.Fatalf("no dns response over udp")
+	}
+	if msg := parseResponse(t, resp); len(msg.Answers) != 1 {
+		t.Errorf("expected 1 answer over udp, got %d", len(msg.Answers))
+	}
+
+	// tcp
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Second))
+	req := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(req, uint16(len(query)))
+	copy(req[2:], query)
+	if _, err := conn.Write(req); err != nil {
+		t.Fatal(err)
+	}
+	var length uint16
+	if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+		t.Fatal(err)
+	}
+	resp = make([]byte, length)
+	if _, err := conn.Read(resp); err != nil {
+		t.Fatal(err)
+	}
+	if msg := parseResponse(t, resp); len(msg.Answers) != 1 {
+		t.Errorf("expected 1 answer over tcp, got %d", len(msg.Answers))
+	}
+}
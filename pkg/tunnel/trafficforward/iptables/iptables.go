@@ -228,11 +228,11 @@ func (im *iptablesManager) deleteJumpChains(jumpChains []iptablesJumpChain) erro
 
 // getIPOfNodesWithoutAgent returns the ip addresses of all nodes that
 // are not running tunnel-agent
-func (im *iptablesManager) getIPOfNodesWithoutAgent() []string {
+func getIPOfNodesWithoutAgent(nodeInformer coreinformer.NodeInformer) []string {
 	var nodesIP []string
-	nodes, err := im.nodeInformer.Lister().List(labels.Everything())
+	nodes, err := nodeInformer.Lister().List(labels.Everything())
 	if err != nil {
-		klog.Errorf("failed to list nodes for iptables: %v", err)
+		klog.Errorf("failed to list nodes for traffic forwarding rules: %v", err)
 		return nodesIP
 	}
 
@@ -348,14 +348,7 @@ func (im *iptablesManager) ensurePortIptables(port string, currentIPs, deletedIP
 	}
 
 	// decide the proxy destination based on the port number
-	proxyDest := im.insecureDnatDest
-	if port == util.KubeletHTTPSPort {
-		proxyDest = im.secureDnatDest
-	} else if port == util.KubeletHTTPPort {
-		proxyDest = im.insecureDnatDest
-	} else if dst, ok := portMappings[port]; ok {
-		proxyDest = dst
-	}
+	proxyDest := getProxyDest(port, im.secureDnatDest, im.insecureDnatDest, portMappings)
 
 	// do not proxy packets, those destination node doesn't has agent running
 	for _, ip := range currentIPs {
@@ -416,6 +409,20 @@ func (im *iptablesManager) ensureJumpChains(jumpChains []iptablesJumpChain) erro
 	return nil
 }
 
+// getProxyDest returns the tunnel server address that requests to port
+// should be redirected to
+func getProxyDest(port, secureDnatDest, insecureDnatDest string, portMappings map[string]string) string {
+	proxyDest := insecureDnatDest
+	if port == util.KubeletHTTPSPort {
+		proxyDest = secureDnatDest
+	} else if port == util.KubeletHTTPPort {
+		proxyDest = insecureDnatDest
+	} else if dst, ok := portMappings[port]; ok {
+		proxyDest = dst
+	}
+	return proxyDest
+}
+
 func dnatIptablesArgs(msg, destPort, proxyDest string) []string {
 	args := iptablesCommonArgs(msg, destPort, nil)
 	args = append(args, "-j", "DNAT", "--to-destination", proxyDest)
@@ -456,14 +463,14 @@ func toCIDR(ip net.IP) string {
 	return fmt.Sprintf("%s/%d", ip.String(), size)
 }
 
-func (im *iptablesManager) clearConnTrackEntries(ips, ports []string) error {
-	if len(im.conntrackPath) == 0 {
+func clearConnTrackEntries(execer exec.Interface, conntrackPath string, ips, ports []string) error {
+	if len(conntrackPath) == 0 {
 		return nil
 	}
 	klog.Infof("clear conntrack entries for ports %q and nodes %q", ports, ips)
 	for _, port := range ports {
 		for _, ip := range ips {
-			if err := im.clearConnTrackEntriesForIPPort(ip, port); err != nil {
+			if err := clearConnTrackEntriesForIPPort(execer, conntrackPath, ip, port); err != nil {
 				return err
 			}
 		}
@@ -471,13 +478,13 @@ func (im *iptablesManager) clearConnTrackEntries(ips, ports []string) error {
 	return nil
 }
 
-func (im *iptablesManager) clearConnTrackEntriesForIPPort(ip, port string) error {
+func clearConnTrackEntriesForIPPort(execer exec.Interface, conntrackPath, ip, port string) error {
 	parameters := parametersWithFamily(utilnet.IsIPv6String(ip),
 		"-D", "--orig-dst",
 		ip, "-p",
 		"tcp", "--dport", port)
-	output, err := im.execer.
-		Command(im.conntrackPath, parameters...).
+	output, err := execer.
+		Command(conntrackPath, parameters...).
 		CombinedOutput()
 
 	if err != nil && !strings.Contains(err.Error(), NoConnectionToDelete) {
@@ -506,12 +513,12 @@ func (im *iptablesManager) syncIptableSetting() {
 		klog.Errorf("failed to sync iptables rules, %v", err)
 		return
 	}
-	portsChanged, deletedDnatPorts := im.getDeletedPorts(dnatPorts)
+	portsChanged, deletedDnatPorts := getDeletedPorts(im.lastDnatPorts, dnatPorts)
 	currentDnatPorts := append(dnatPorts, util.KubeletHTTPSPort, util.KubeletHTTPPort)
 
 	// check if there are new nodes
-	nodesIP := im.getIPOfNodesWithoutAgent()
-	nodesChanged, addedNodesIP, deletedNodesIP := im.getAddedAndDeletedNodes(nodesIP)
+	nodesIP := getIPOfNodesWithoutAgent(im.nodeInformer)
+	nodesChanged, addedNodesIP, deletedNodesIP := getAddedAndDeletedNodes(im.lastNodesIP, nodesIP)
 	currentNodesIP := append(nodesIP, loopbackAddr)
 
 	// update the iptables setting if necessary
@@ -525,28 +532,28 @@ func (im *iptablesManager) syncIptableSetting() {
 		im.lastDnatPorts = dnatPorts
 		// we don't need to clear conntrack entries for newly added dnat ports,
 		if len(deletedDnatPorts) != 0 {
-			im.clearConnTrackEntries(currentNodesIP, deletedDnatPorts)
+			clearConnTrackEntries(im.execer, im.conntrackPath, currentNodesIP, deletedDnatPorts)
 		}
 		klog.Infof("dnat ports changed, %v", dnatPorts)
 	}
 
 	if nodesChanged {
 		im.lastNodesIP = nodesIP
-		im.clearConnTrackEntries(append(addedNodesIP, deletedNodesIP...), currentDnatPorts)
+		clearConnTrackEntries(im.execer, im.conntrackPath, append(addedNodesIP, deletedNodesIP...), currentDnatPorts)
 		klog.Infof("directly access nodes changed, %v for ports %v", nodesIP, currentDnatPorts)
 	}
 }
 
-func (im *iptablesManager) getAddedAndDeletedNodes(currentNodesIP []string) (bool, []string, []string) {
+func getAddedAndDeletedNodes(lastNodesIP, currentNodesIP []string) (bool, []string, []string) {
 	changed := false
-	if len(im.lastNodesIP) != len(currentNodesIP) {
+	if len(lastNodesIP) != len(currentNodesIP) {
 		changed = true
 	}
 	addedNodesIP := make([]string, 0)
 	for i := range currentNodesIP {
 		found := false
-		for j := range im.lastNodesIP {
-			if currentNodesIP[i] == im.lastNodesIP[j] {
+		for j := range lastNodesIP {
+			if currentNodesIP[i] == lastNodesIP[j] {
 				found = true
 				break
 			}
@@ -559,17 +566,17 @@ func (im *iptablesManager) getAddedAndDeletedNodes(currentNodesIP []string) (boo
 	}
 
 	deletedNodesIP := make([]string, 0)
-	for i := range im.lastNodesIP {
+	for i := range lastNodesIP {
 		found := false
 		for j := range currentNodesIP {
-			if im.lastNodesIP[i] == currentNodesIP[j] {
+			if lastNodesIP[i] == currentNodesIP[j] {
 				found = true
 				break
 			}
 		}
 
 		if !found {
-			deletedNodesIP = append(deletedNodesIP, im.lastNodesIP[i])
+			deletedNodesIP = append(deletedNodesIP, lastNodesIP[i])
 			changed = true
 		}
 	}
@@ -577,23 +584,23 @@ func (im *iptablesManager) getAddedAndDeletedNodes(currentNodesIP []string) (boo
 	return changed, addedNodesIP, deletedNodesIP
 }
 
-func (im *iptablesManager) getDeletedPorts(currentPorts []string) (bool, []string) {
+func getDeletedPorts(lastDnatPorts, currentPorts []string) (bool, []string) {
 	changed := false
-	if len(im.lastDnatPorts) != len(currentPorts) {
+	if len(lastDnatPorts) != len(currentPorts) {
 		changed = true
 	}
 	var deletedPorts []string
-	for i := range im.lastDnatPorts {
+	for i := range lastDnatPorts {
 		found := false
 		for j := range currentPorts {
-			if im.lastDnatPorts[i] == currentPorts[j] {
+			if lastDnatPorts[i] == currentPorts[j] {
 				found = true
 				break
 			}
 		}
 
 		if !found {
-			deletedPorts = append(deletedPorts, im.lastDnatPorts[i])
+			deletedPorts = append(deletedPorts, lastDnatPorts[i])
 			changed = true
 		}
 	}
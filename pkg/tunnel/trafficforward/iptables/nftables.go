@@ -0,0 +1,316 @@
+package iptables
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	coreinformer "k8s.io/client-go/informers/core/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/exec"
+
+	"github.com/bhojpur/dcp/pkg/tunnel/util"
+	"github.com/bhojpur/dcp/pkg/utils/nftables"
+)
+
+const (
+	// nftablesTable is the table that holds all forwarding rules of the
+	// tunnel server. It is replaced as a whole on every change.
+	nftablesTable = "dcp-tunnel"
+	// nftablesOutputChain is the base chain hooked into nat output
+	nftablesOutputChain = "OUTPUT"
+)
+
+// nftablesManager implements the IptablesManager with native nftables
+// rules. The chains mirror the ones created by the iptablesManager.
+type nftablesManager struct {
+	kubeClient       clientset.Interface
+	nodeInformer     coreinformer.NodeInformer
+	nftables         nftables.Interface
+	execer           exec.Interface
+	conntrackPath    string
+	secureDnatDest   string
+	insecureDnatDest string
+	lastNodesIP      []string
+	lastDnatPorts    []string
+	lastRuleset      []byte
+	syncPeriod       int
+}
+
+// NewNftablesManager creates an IptablesManager that maintains the dnat
+// rules with nft instead of iptables.
+func NewNftablesManager(client clientset.Interface,
+	nodeInformer coreinformer.NodeInformer,
+	listenAddr string,
+	listenInsecureAddr string,
+	syncPeriod int) IptablesManager {
+	execer := exec.New()
+	return newNftablesManager(client, nodeInformer, listenAddr, listenInsecureAddr, syncPeriod,
+		nftables.New(execer), execer)
+}
+
+func newNftablesManager(client clientset.Interface,
+	nodeInformer coreinformer.NodeInformer,
+	listenAddr string,
+	listenInsecureAddr string,
+	syncPeriod int,
+	nft nftables.Interface,
+	execer exec.Interface) *nftablesManager {
+
+	if syncPeriod < defaultSyncPeriod {
+		syncPeriod = defaultSyncPeriod
+	}
+
+	nm := &nftablesManager{
+		kubeClient:       client,
+		nodeInformer:     nodeInformer,
+		nftables:         nft,
+		execer:           execer,
+		secureDnatDest:   listenAddr,
+		insecureDnatDest: listenInsecureAddr,
+		lastNodesIP:      make([]string, 0),
+		lastDnatPorts:    make([]string, 0),
+		syncPeriod:       syncPeriod,
+	}
+
+	// conntrack setting
+	conntrackPath, err := execer.LookPath("conntrack")
+	if err != nil {
+		klog.Errorf("error looking for path of conntrack: %v", err)
+	} else {
+		nm.conntrackPath = conntrackPath
+	}
+
+	return nm
+}
+
+// Run starts the nftablesManager that will updates dnat rules periodically
+func (nm *nftablesManager) Run(stopCh <-chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	// wait the nodeInformer has synced
+	if !cache.WaitForCacheSync(stopCh,
+		nm.nodeInformer.Informer().HasSynced) {
+		klog.Error("sync node cache timeout")
+		return
+	}
+	// sync nftables setting when tunnel server startup
+	nm.syncNftablesSetting()
+
+	ticker := time.NewTicker(time.Duration(nm.syncPeriod) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			klog.Info("stop the nftablesManager")
+			nm.cleanupNftablesSetting()
+			return
+		case <-ticker.C:
+			nm.syncNftablesSetting()
+		}
+	}
+}
+
+func (nm *nftablesManager) cleanupNftablesSetting() {
+	if err := nm.nftables.DeleteTable(nftables.FamilyIPv4, nftablesTable); err != nil {
+		klog.Errorf("failed to delete nftables table %s: %v", nftablesTable, err)
+		return
+	}
+	nm.lastRuleset = nil
+	klog.Info("Complete cleanup nftables rules")
+}
+
+// syncNftablesSetting renders the whole forwarding table and applies it
+// when it differs from the last applied ruleset or has been removed from
+// the host.
+func (nm *nftablesManager) syncNftablesSetting() {
+	dnatPorts, portMappings, err := util.GetConfiguredProxyPortsAndMappings(nm.kubeClient, nm.insecureDnatDest, nm.secureDnatDest)
+	if err != nil {
+		klog.Errorf("failed to sync nftables rules, %v", err)
+		return
+	}
+	portsChanged, deletedDnatPorts := getDeletedPorts(nm.lastDnatPorts, dnatPorts)
+	currentDnatPorts := append(dnatPorts, util.KubeletHTTPSPort, util.KubeletHTTPPort)
+
+	nodesIP := getIPOfNodesWithoutAgent(nm.nodeInformer)
+	nodesChanged, addedNodesIP, deletedNodesIP := getAddedAndDeletedNodes(nm.lastNodesIP, nodesIP)
+	currentNodesIP := append(nodesIP, loopbackAddr)
+
+	ruleset := renderNftablesRuleset(currentDnatPorts, currentNodesIP, func(port string) string {
+		return getProxyDest(port, nm.secureDnatDest, nm.insecureDnatDest, portMappings)
+	})
+	if !bytes.Equal(ruleset, nm.lastRuleset) || !nm.tableExists() {
+		if err := nm.nftables.Apply(ruleset); err != nil {
+			klog.Errorf("failed to apply nftables rules: %v", err)
+			return
+		}
+		nm.lastRuleset = ruleset
+	}
+
+	if portsChanged {
+		nm.lastDnatPorts = dnatPorts
+		if len(deletedDnatPorts) != 0 {
+			clearConnTrackEntries(nm.execer, nm.conntrackPath, currentNodesIP, deletedDnatPorts)
+		}
+		klog.Infof("dnat ports changed, %v", dnatPorts)
+	}
+
+	if nodesChanged {
+		nm.lastNodesIP = nodesIP
+		clearConnTrackEntries(nm.execer, nm.conntrackPath, append(addedNodesIP, deletedNodesIP...), currentDnatPorts)
+		klog.Infof("directly access nodes changed, %v for ports %v", nodesIP, currentDnatPorts)
+	}
+}
+
+func (nm *nftablesManager) tableExists() bool {
+	_, err := nm.nftables.ListTable(nftables.FamilyIPv4, nftablesTable)
+	if err != nil && !nftables.IsNotFoundError(err) {
+		klog.Errorf("failed to list nftables table %s: %v", nftablesTable, err)
+	}
+	return err == nil
+}
+
+// renderNftablesRuleset generates an nft script that atomically replaces the
+// forwarding table. For each port, requests to the given node ips return
+// directly, and the rest are redirected to the address returned by proxyDest.
+func renderNftablesRuleset(ports, nodesIP []string, proxyDest func(port string) string) []byte {
+	ports = sortedUnique(ports)
+	buf := &bytes.Buffer{}
+
+	// the table only sees ipv4 packets, requests to the other addresses are
+	// never forwarded to the tunnel server
+	var destIPs, skipped []string
+	for _, ip := range nodesIP {
+		destIP := net.ParseIP(ip)
+		if destIP == nil || destIP.To4() == nil {
+			skipped = append(skipped, ip)
+			continue
+		}
+		destIPs = append(destIPs, destIP.String())
+	}
+	if len(skipped) != 0 {
+		klog.Warningf("skip non-ipv4 node addresses %q in nftables rules, requests to them are not forwarded through the tunnel", skipped)
+	}
+
+	// adding the table first makes the delete succeed if it does not exist
+	fmt.Fprintf(buf, "add table %s %s\n", nftables.FamilyIPv4, nftablesTable)
+	fmt.Fprintf(buf, "delete table %s %s\n", nftables.FamilyIPv4, nftablesTable)
+	fmt.Fprintf(buf, "table %s %s {\n", nftables.FamilyIPv4, nftablesTable)
+
+	fmt.Fprintf(buf, "\tchain %s {\n", nftablesOutputChain)
+	buf.WriteString("\t\ttype nat hook output priority -100; policy accept;\n")
+	fmt.Fprintf(buf, "\t\tmeta l4proto tcp jump %s comment %s\n",
+		dcptunnelServerPortChain, nftables.Quote(iptablesJumpChains[0].comment))
+	buf.WriteString("\t}\n")
+
+	fmt.Fprintf(buf, "\tchain %s {\n", dcptunnelServerPortChain)
+	for _, port := range ports {
+		fmt.Fprintf(buf, "\t\ttcp dport %s jump %s%s comment %s\n",
+			port, dcptunnelPortChainPrefix, port, nftables.Quote(fmt.Sprintf("jump to port %s", port)))
+	}
+	buf.WriteString("\t}\n")
+
+	for _, port := range ports {
+		fmt.Fprintf(buf, "\tchain %s%s {\n", dcptunnelPortChainPrefix, port)
+		for _, ip := range destIPs {
+			fmt.Fprintf(buf, "\t\tip daddr %s tcp dport %s return comment %s\n",
+				ip, port, nftables.Quote(reqReturnComment))
+		}
+		fmt.Fprintf(buf, "\t\ttcp dport %s dnat to %s comment %s\n",
+			port, proxyDest(port), nftables.Quote(dnatToTunnelComment))
+		buf.WriteString("\t}\n")
+	}
+
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+func sortedUnique(items []string) []string {
+	seen := make(map[string]bool, len(items))
+	var result []string
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			result = append(result, item)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Backend is the implementation used to maintain the forwarding rules
+type Backend string
+
+const (
+	// BackendAuto picks nftables on hosts whose iptables is missing or is
+	// the nf_tables shim, and iptables otherwise
+	BackendAuto Backend = "auto"
+	// BackendIptables maintains the rules with iptables
+	BackendIptables Backend = "iptables"
+	// BackendNftables maintains the rules with nft
+	BackendNftables Backend = "nftables"
+)
+
+// NewManager creates the IptablesManager of the specified backend
+func NewManager(backend Backend,
+	client clientset.Interface,
+	nodeInformer coreinformer.NodeInformer,
+	listenAddr string,
+	listenInsecureAddr string,
+	syncPeriod int) (IptablesManager, error) {
+	if backend == BackendAuto {
+		backend = detectBackend(exec.New())
+		klog.Infof("use %s to maintain traffic forwarding rules", backend)
+	}
+
+	switch backend {
+	case BackendIptables:
+		return NewIptablesManager(client, nodeInformer, listenAddr, listenInsecureAddr, syncPeriod), nil
+	case BackendNftables:
+		return NewNftablesManager(client, nodeInformer, listenAddr, listenInsecureAddr, syncPeriod), nil
+	default:
+		return nil, fmt.Errorf("unknown traffic forwarding backend %q", backend)
+	}
+}
+
+// detectBackend prefers nftables when nft is installed and iptables is
+// either missing or only the nf_tables compatibility shim.
+func detectBackend(execer exec.Interface) Backend {
+	if !nftables.New(execer).Present() {
+		return BackendIptables
+	}
+	if _, err := execer.LookPath("iptables"); err != nil {
+		return BackendNftables
+	}
+	out, err := execer.Command("iptables", "--version").CombinedOutput()
+	if err == nil && strings.Contains(string(out), "nf_tables") {
+		return BackendNftables
+	}
+	return BackendIptables
+}
@@ -0,0 +1,197 @@
+package iptables
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/exec"
+	fakeexec "k8s.io/utils/exec/testing"
+
+	"github.com/bhojpur/dcp/pkg/projectinfo"
+	"github.com/bhojpur/dcp/pkg/tunnel/util"
+	"github.com/bhojpur/dcp/pkg/utils/nftables"
+	nfttest "github.com/bhojpur/dcp/pkg/utils/nftables/testing"
+)
+
+func newTestNode(name, ip string, edge bool) *corev1.Node {
+	edgeWorker := "false"
+	if edge {
+		edgeWorker = "true"
+	}
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				projectinfo.GetEdgeWorkerLabelKey(): edgeWorker,
+			},
+		},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+			},
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: ip},
+			},
+		},
+	}
+}
+
+func TestSyncNftablesSetting(t *testing.T) {
+	configmap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      util.TunnelServerDnatConfigMapName,
+			Namespace: util.TunnelServerDnatConfigMapNs,
+		},
+		Data: map[string]string{
+			"http-proxy-ports": "9100",
+		},
+	}
+	fakeClient := fake.NewSimpleClientset(configmap)
+	fakeInformerFactory := informers.NewSharedInformerFactory(fakeClient, 0*time.Second)
+	nodeStore := fakeInformerFactory.Core().V1().Nodes().Informer().GetStore()
+	nodeStore.Add(newTestNode("cloud-node", "192.168.0.10", false))
+	nodeStore.Add(newTestNode("edge-node", "192.168.0.20", true))
+
+	fexec := &fakeexec.FakeExec{
+		LookPathFunc: func(cmd string) (string, error) { return "", exec.ErrExecutableNotFound },
+	}
+	fakeNft := nfttest.NewFake()
+	nm := newNftablesManager(fakeClient,
+		fakeInformerFactory.Core().V1().Nodes(),
+		ListenAddrForMaster,
+		ListenInsecureAddrForMaster,
+		IptablesSyncPeriod,
+		fakeNft,
+		fexec)
+
+	// 1. the first sync creates the table
+	nm.syncNftablesSetting()
+	if len(fakeNft.Scripts) != 1 {
+		t.Fatalf("expected 1 applied script, got %d", len(fakeNft.Scripts))
+	}
+	script := fakeNft.LastScript()
+	for _, expected := range []string{
+		"delete table ip " + nftablesTable,
+		"type nat hook output priority -100; policy accept;",
+		"tcp dport 10250 jump TUNNEL-PORT-10250",
+		"tcp dport 9100 jump TUNNEL-PORT-9100",
+		"ip daddr 192.168.0.10 tcp dport 10250 return",
+		"ip daddr 127.0.0.1 tcp dport 10250 return",
+		"tcp dport 10250 dnat to " + ListenAddrForMaster,
+		"tcp dport 10255 dnat to " + ListenInsecureAddrForMaster,
+		"tcp dport 9100 dnat to " + ListenInsecureAddrForMaster,
+	} {
+		if !strings.Contains(script, expected) {
+			t.Errorf("expected ruleset to contain %q, got:\n%s", expected, script)
+		}
+	}
+	if strings.Contains(script, "192.168.0.20") {
+		t.Errorf("edge node should be forwarded to the tunnel, got:\n%s", script)
+	}
+
+	// 2. nothing changed, nothing is applied
+	nm.syncNftablesSetting()
+	if len(fakeNft.Scripts) != 1 {
+		t.Errorf("expected no new script when nothing changed, got %d scripts", len(fakeNft.Scripts))
+	}
+
+	// 3. the table is restored if removed from the host
+	fakeNft.DeleteTable(nftables.FamilyIPv4, nftablesTable)
+	nm.syncNftablesSetting()
+	if len(fakeNft.Scripts) != 2 {
+		t.Errorf("expected the table to be restored, got %d scripts", len(fakeNft.Scripts))
+	}
+
+	// 4. cleanup removes the table
+	nm.cleanupNftablesSetting()
+	if _, err := fakeNft.ListTable(nftables.FamilyIPv4, nftablesTable); !nftables.IsNotFoundError(err) {
+		t.Errorf("expected table to be deleted, got %v", err)
+	}
+}
+
+func TestDetectBackend(t *testing.T) {
+	tests := map[string]struct {
+		paths    map[string]bool
+		version  string
+		expected Backend
+	}{
+		"nft missing": {
+			paths:    map[string]bool{"iptables": true},
+			expected: BackendIptables,
+		},
+		"iptables missing": {
+			paths:    map[string]bool{"nft": true},
+			expected: BackendNftables,
+		},
+		"iptables legacy": {
+			paths:    map[string]bool{"nft": true, "iptables": true},
+			version:  "iptables v1.8.4 (legacy)",
+			expected: BackendIptables,
+		},
+		"iptables nf_tables shim": {
+			paths:    map[string]bool{"nft": true, "iptables": true},
+			version:  "iptables v1.8.7 (nf_tables)",
+			expected: BackendNftables,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			fcmd := fakeexec.FakeCmd{
+				CombinedOutputScript: []fakeexec.FakeAction{
+					func() ([]byte, []byte, error) { return []byte(tt.version), nil, nil },
+				},
+			}
+			fexec := &fakeexec.FakeExec{
+				CommandScript: []fakeexec.FakeCommandAction{
+					func(cmd string, args ...string) exec.Cmd { return fakeexec.InitFakeCmd(&fcmd, cmd, args...) },
+				},
+				LookPathFunc: func(cmd string) (string, error) {
+					if tt.paths[cmd] {
+						return "/usr/sbin/" + cmd, nil
+					}
+					return "", exec.ErrExecutableNotFound
+				},
+			}
+			if backend := detectBackend(fexec); backend != tt.expected {
+				t.Errorf("expected backend %s, got %s", tt.expected, backend)
+			}
+		})
+	}
+}
+
+func TestRenderNftablesRulesetSkipsNonIPv4(t *testing.T) {
+	script := string(renderNftablesRuleset([]string{"10250"}, []string{"192.168.0.10", "fd00::10", "invalid"},
+		func(port string) string { return ListenAddrForMaster }))
+	if !strings.Contains(script, "ip daddr 192.168.0.10 tcp dport 10250 return") {
+		t.Errorf("expected ruleset to return for the ipv4 node, got:\n%s", script)
+	}
+	if strings.Contains(script, "fd00::10") || strings.Contains(script, "invalid") {
+		t.Errorf("expected non-ipv4 addresses to be skipped, got:\n%s", script)
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"path/filepath"
 	"time"
 
 	certificatesv1 "k8s.io/api/certificates/v1"
@@ -148,6 +149,17 @@ func NewTunnelAgentCertManager(
 		nil)
 }
 
+// ResetTunnelAgentCert removes the current certificate of the tunnel-agent
+// from certDir, so a new certificate manager will request a new certificate
+// instead of loading the stored one.
+func ResetTunnelAgentCert(certDir string) error {
+	current := filepath.Join(certDir, fmt.Sprintf("%s-current.pem", projectinfo.GetAgentName()))
+	if err := os.Remove(current); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove the current certificate %s: %v", current, err)
+	}
+	return nil
+}
+
 // NewEngineServerCertManager creates a certificate manager for
 // the dcpsvr-server
 func NewEngineServerCertManager(
@@ -0,0 +1,136 @@
+package nftables
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+	utilexec "k8s.io/utils/exec"
+)
+
+// Interface is an injectable interface for running nft commands.  Implementations must be goroutine-safe.
+type Interface interface {
+	// Present returns true if the nft binary can be found.
+	Present() bool
+	// Apply runs `nft -f -` passing the script through stdin. nft applies
+	// the whole script as a single atomic transaction.
+	Apply(script []byte) error
+	// ListTable returns the ruleset of the specified table. If the table
+	// does not exist, return an error that satisfies IsNotFoundError.
+	ListTable(family Family, table string) ([]byte, error)
+	// DeleteTable deletes the specified table. A missing table is not an error.
+	DeleteTable(family Family, table string) error
+}
+
+// Family represents the nftables address family of a table
+type Family string
+
+const (
+	// FamilyIPv4 represents the ip family
+	FamilyIPv4 Family = "ip"
+	// FamilyIPv6 represents the ip6 family
+	FamilyIPv6 Family = "ip6"
+	// FamilyInet represents the inet family, which matches both ipv4 and ipv6
+	FamilyInet Family = "inet"
+)
+
+const cmdNft string = "nft"
+
+// runner implements Interface in terms of exec("nft").
+type runner struct {
+	mu   sync.Mutex
+	exec utilexec.Interface
+}
+
+// New returns a new Interface which will exec nft.
+func New(exec utilexec.Interface) Interface {
+	return &runner{exec: exec}
+}
+
+// Present is part of Interface.
+func (runner *runner) Present() bool {
+	_, err := runner.exec.LookPath(cmdNft)
+	return err == nil
+}
+
+// Apply is part of Interface.
+func (runner *runner) Apply(script []byte) error {
+	runner.mu.Lock()
+	defer runner.mu.Unlock()
+
+	klog.V(4).Infof("running %s -f -", cmdNft)
+	cmd := runner.exec.Command(cmdNft, "-f", "-")
+	cmd.SetStdin(bytes.NewBuffer(script))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v (%s)", err, out)
+	}
+	return nil
+}
+
+// ListTable is part of Interface.
+func (runner *runner) ListTable(family Family, table string) ([]byte, error) {
+	runner.mu.Lock()
+	defer runner.mu.Unlock()
+
+	out, err := runner.exec.Command(cmdNft, "list", "table", string(family), table).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("error listing table %s %s: %v: %s", family, table, err, out)
+	}
+	return out, nil
+}
+
+// DeleteTable is part of Interface.
+func (runner *runner) DeleteTable(family Family, table string) error {
+	runner.mu.Lock()
+	defer runner.mu.Unlock()
+
+	out, err := runner.exec.Command(cmdNft, "delete", "table", string(family), table).CombinedOutput()
+	if err != nil && !isMissing(string(out)) {
+		return fmt.Errorf("error deleting table %s %s: %v: %s", family, table, err, out)
+	}
+	return nil
+}
+
+// IsNotFoundError returns true if the error indicates "not found".  It parses
+// the error string looking for known values, which is imperfect; beware using
+// this function for anything beyond deciding between logging or ignoring an
+// error.
+func IsNotFoundError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return isMissing(err.Error())
+}
+
+func isMissing(msg string) bool {
+	return strings.Contains(msg, "No such file or directory") ||
+		strings.Contains(msg, "does not exist")
+}
+
+// Quote returns s as an nft string literal.
+func Quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `'`) + `"`
+}
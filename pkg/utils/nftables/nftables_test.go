@@ -0,0 +1,113 @@
+package nftables
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"k8s.io/utils/exec"
+	fakeexec "k8s.io/utils/exec/testing"
+)
+
+func TestApply(t *testing.T) {
+	fcmd := fakeexec.FakeCmd{
+		CombinedOutputScript: []fakeexec.FakeAction{
+			func() ([]byte, []byte, error) { return []byte{}, nil, nil },
+			func() ([]byte, []byte, error) {
+				return []byte("Error: syntax error"), nil, &fakeexec.FakeExitError{Status: 1}
+			},
+		},
+	}
+	fexec := fakeexec.FakeExec{
+		CommandScript: []fakeexec.FakeCommandAction{
+			func(cmd string, args ...string) exec.Cmd { return fakeexec.InitFakeCmd(&fcmd, cmd, args...) },
+			func(cmd string, args ...string) exec.Cmd { return fakeexec.InitFakeCmd(&fcmd, cmd, args...) },
+		},
+	}
+
+	runner := New(&fexec)
+	script := "table ip test {\n}\n"
+	if err := runner.Apply([]byte(script)); err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if fcmd.CombinedOutputCalls != 1 {
+		t.Errorf("expected 1 CombinedOutput() call, got %d", fcmd.CombinedOutputCalls)
+	}
+	if got := fcmd.CombinedOutputLog[0]; len(got) != 3 || got[0] != "nft" || got[1] != "-f" || got[2] != "-" {
+		t.Errorf("wrong command: %v", got)
+	}
+	if data, _ := ioutil.ReadAll(fcmd.Stdin); string(data) != script {
+		t.Errorf("wrong script passed to nft: %q", data)
+	}
+
+	if err := runner.Apply([]byte("bogus")); err == nil {
+		t.Errorf("expected failure")
+	}
+}
+
+func TestDeleteTable(t *testing.T) {
+	fcmd := fakeexec.FakeCmd{
+		CombinedOutputScript: []fakeexec.FakeAction{
+			// table does not exist
+			func() ([]byte, []byte, error) {
+				return []byte("Error: No such file or directory; did you mean table 'filter' in family ip?"), nil, &fakeexec.FakeExitError{Status: 1}
+			},
+			// permission denied
+			func() ([]byte, []byte, error) {
+				return []byte("Error: Operation not permitted"), nil, &fakeexec.FakeExitError{Status: 1}
+			},
+		},
+	}
+	fexec := fakeexec.FakeExec{
+		CommandScript: []fakeexec.FakeCommandAction{
+			func(cmd string, args ...string) exec.Cmd { return fakeexec.InitFakeCmd(&fcmd, cmd, args...) },
+			func(cmd string, args ...string) exec.Cmd { return fakeexec.InitFakeCmd(&fcmd, cmd, args...) },
+		},
+	}
+
+	runner := New(&fexec)
+	if err := runner.DeleteTable(FamilyIPv4, "test"); err != nil {
+		t.Errorf("expected missing table to be ignored, got %v", err)
+	}
+	if err := runner.DeleteTable(FamilyIPv4, "test"); err == nil {
+		t.Errorf("expected failure")
+	}
+	if got := fcmd.CombinedOutputLog[0]; len(got) != 5 || got[1] != "delete" || got[3] != "ip" || got[4] != "test" {
+		t.Errorf("wrong command: %v", got)
+	}
+}
+
+func TestIsNotFoundError(t *testing.T) {
+	if IsNotFoundError(nil) {
+		t.Errorf("nil is not a not found error")
+	}
+	if !IsNotFoundError(errorString("Error: No such file or directory")) {
+		t.Errorf("expected not found error")
+	}
+	if IsNotFoundError(errorString("Error: Operation not permitted")) {
+		t.Errorf("unexpected not found error")
+	}
+}
+
+type errorString string
+
+func (e errorString) Error() string { return string(e) }
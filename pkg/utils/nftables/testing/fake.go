@@ -0,0 +1,108 @@
+package testing
+
+// Copyright (c) 2018 Bhojpur Consulting Private Limited, India. All rights reserved.
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bhojpur/dcp/pkg/utils/nftables"
+)
+
+// FakeNFTables is an in-memory implementation of nftables Interface. It keeps
+// the scripts that were applied and the tables they define.
+type FakeNFTables struct {
+	mu sync.Mutex
+	// Missing makes Present report that nft is not installed.
+	Missing bool
+	// ApplyError, if set, is returned by Apply without changing any table.
+	ApplyError error
+	// Scripts holds every script passed to Apply, in order.
+	Scripts []string
+	// Tables maps "family name" to the script that last defined the table.
+	Tables map[string]string
+}
+
+// NewFake returns a FakeNFTables without any table
+func NewFake() *FakeNFTables {
+	return &FakeNFTables{Tables: map[string]string{}}
+}
+
+var _ nftables.Interface = &FakeNFTables{}
+
+// Present is part of nftables.Interface
+func (f *FakeNFTables) Present() bool {
+	return !f.Missing
+}
+
+// Apply is part of nftables.Interface
+func (f *FakeNFTables) Apply(script []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.ApplyError != nil {
+		return f.ApplyError
+	}
+	f.Scripts = append(f.Scripts, string(script))
+	for _, line := range strings.Split(string(script), "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) >= 4 && fields[0] == "delete" && fields[1] == "table":
+			delete(f.Tables, fields[2]+" "+fields[3])
+		case len(fields) >= 3 && fields[0] == "table":
+			f.Tables[fields[1]+" "+fields[2]] = string(script)
+		}
+	}
+	return nil
+}
+
+// ListTable is part of nftables.Interface
+func (f *FakeNFTables) ListTable(family nftables.Family, table string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	script, ok := f.Tables[string(family)+" "+table]
+	if !ok {
+		return nil, fmt.Errorf("Error: No such file or directory; list table %s %s", family, table)
+	}
+	return []byte(script), nil
+}
+
+// DeleteTable is part of nftables.Interface
+func (f *FakeNFTables) DeleteTable(family nftables.Family, table string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.Tables, string(family)+" "+table)
+	return nil
+}
+
+// LastScript returns the most recently applied script
+func (f *FakeNFTables) LastScript() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.Scripts) == 0 {
+		return ""
+	}
+	return f.Scripts[len(f.Scripts)-1]
+}